@@ -0,0 +1,488 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// archiveManifestName is the manifest stored at the root of an export archive
+const archiveManifestName = "manifest.yaml"
+
+// archiveFilesDir holds the exported files inside an archive
+const archiveFilesDir = "files/"
+
+// archiveSkipped are never exported: they hold machine-local state or, for
+// the default profile, the other profiles
+var archiveSkipped = map[string]bool{"locks": true, "cache": true, "index.json": true, "bin": true, "profiles": true, "current_profile": true, "berga.db-wal": true, "berga.db-shm": true}
+
+// archiveAliases map short selection names to files in the berga home
+var archiveAliases = map[string]string{
+	"config":    "config.yaml",
+	"bookmarks": "bookmarks.yaml",
+	"tags":      "tags.yaml",
+	"trust":     "trust.yaml",
+}
+
+// ArchiveManifest describes the contents of an export archive
+type ArchiveManifest struct {
+	Version int           `yaml:"version"`
+	Created time.Time     `yaml:"created"`
+	Host    string        `yaml:"host,omitempty"`
+	Files   []ArchiveFile `yaml:"files"`
+}
+
+// ArchiveFile is one exported file, relative to the berga home
+type ArchiveFile struct {
+	Path   string      `yaml:"path"`
+	Mode   os.FileMode `yaml:"mode"`
+	Size   int64       `yaml:"size"`
+	SHA256 string      `yaml:"sha256"`
+}
+
+var (
+	exportOutput     string
+	importOnConflict string
+	importDryRun     bool
+)
+
+// exportCmd writes berga's files to an archive
+var exportCmd = &cobra.Command{
+	Use:   "export [item...]",
+	Short: "Export scripts, templates, and config to an archive",
+	Long: `Write the berga home directory, or selected parts of it, to a single .tar.gz
+or .zip archive with a manifest, for moving to another machine.
+
+Items are paths relative to ~/.berga, or one of config, bookmarks, tags, trust:
+
+  berga export                               # everything
+  berga export scripts templates/gitignore.tmpl config -o setup.zip
+
+Secrets stored in the OS keychain are not exported.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportArchive(args, exportOutput)
+	},
+}
+
+// importCmd restores files from an archive
+var importCmd = &cobra.Command{
+	Use:   "import [archive]",
+	Short: "Import an archive created with 'berga export'",
+	Long: `Unpack an archive created with 'berga export' into ~/.berga. Files that
+already exist with different content are handled by --on-conflict:
+
+  skip       keep the existing file (default)
+  overwrite  replace it with the archived file
+  rename     import the archived file under a new name`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLocks([]string{"config", "bookmarks", "tags", "trust", "dotfiles"}, func() error {
+			return importArchive(args[0], importOnConflict, importDryRun)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	// Flags
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Archive to write; .zip or .tar.gz (default berga-export-<date>.tar.gz)")
+	importCmd.Flags().StringVar(&importOnConflict, "on-conflict", "skip", "How to handle existing files: skip, overwrite, or rename")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without writing anything")
+}
+
+// safeArchivePath cleans a relative archive path, rejecting ones that escape
+// the berga home
+func safeArchivePath(p string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(p))
+	if clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid path in archive: %s", p)
+	}
+	return clean, nil
+}
+
+// archiveFilePath maps a path in an archive, relative to the berga home, to
+// where it lives on this machine: scripts and templates in the first search
+// path, other content in the data directory, and the rest in the config
+// directory
+func archiveFilePath(rel string) string {
+	first, rest, _ := strings.Cut(rel, "/")
+	switch {
+	case first == "scripts":
+		return filepath.Join(GetScriptsDir(), filepath.FromSlash(rest))
+	case first == "templates":
+		return filepath.Join(GetTemplatesDir(), filepath.FromSlash(rest))
+	case dataDirNames[first]:
+		return filepath.Join(GetDataDir(), filepath.FromSlash(rel))
+	}
+	return filepath.Join(GetConfigDir(), filepath.FromSlash(rel))
+}
+
+// defaultExportItems returns everything in the config directory plus the
+// content directories, which may live elsewhere
+func defaultExportItems() []string {
+	seen := make(map[string]bool)
+	var items []string
+	if entries, err := os.ReadDir(GetConfigDir()); err == nil {
+		for _, entry := range entries {
+			seen[entry.Name()] = true
+			items = append(items, entry.Name())
+		}
+	}
+	for name := range dataDirNames {
+		if !seen[name] {
+			items = append(items, name)
+		}
+	}
+	sort.Strings(items)
+	return items
+}
+
+// collectExportFiles lists the files selected by items, as paths relative to
+// the berga home
+func collectExportFiles(items []string) ([]string, error) {
+	explicit := len(items) > 0 && !(len(items) == 1 && items[0] == ".")
+	if !explicit {
+		items = defaultExportItems()
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, item := range items {
+		if alias, ok := archiveAliases[item]; ok {
+			item = alias
+		}
+		item = path.Clean(filepath.ToSlash(item))
+		root := archiveFilePath(item)
+		if _, err := os.Stat(root); err != nil {
+			if !explicit {
+				continue
+			}
+			return nil, fmt.Errorf("'%s' not found in %s", item, GetConfigDir())
+		}
+
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			rel = path.Join(item, filepath.ToSlash(rel))
+			if archiveSkipped[rel] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if !info.Mode().IsRegular() || seen[rel] {
+				return nil
+			}
+			seen[rel] = true
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// archiveWriter adds files to a tar.gz or zip archive
+type archiveWriter interface {
+	add(name string, mode os.FileMode, data []byte) error
+	Close() error
+}
+
+type tarArchive struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (a *tarArchive) add(name string, mode os.FileMode, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: int64(mode.Perm()), Size: int64(len(data)), ModTime: time.Now()}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) add(name string, mode os.FileMode, data []byte) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	hdr.SetMode(mode.Perm())
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+func isZipArchive(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
+
+func exportArchive(items []string, output string) error {
+	if output == "" {
+		output = fmt.Sprintf("berga-export-%s.tar.gz", time.Now().Format("20060102"))
+	}
+
+	files, err := collectExportFiles(items)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to export in %s", GetConfigDir())
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	var archive archiveWriter
+	if isZipArchive(output) {
+		archive = &zipArchive{zw: zip.NewWriter(out)}
+	} else {
+		gz := gzip.NewWriter(out)
+		archive = &tarArchive{gz: gz, tw: tar.NewWriter(gz)}
+	}
+
+	host, _ := os.Hostname()
+	manifest := ArchiveManifest{Version: 1, Created: time.Now().UTC(), Host: host}
+	for _, rel := range files {
+		p := archiveFilePath(rel)
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, ArchiveFile{
+			Path:   rel,
+			Mode:   info.Mode().Perm(),
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		if err := archive.add(archiveFilesDir+rel, info.Mode(), data); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := archive.add(archiveManifestName, 0644, data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	fmt.Printf("Exported %d file(s) to %s\n", len(files), output)
+	return nil
+}
+
+// readArchive loads every entry of a tar.gz or zip archive into memory
+func readArchive(name string) (map[string][]byte, error) {
+	entries := make(map[string][]byte)
+
+	if isZipArchive(name) {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+			entries[f.Name] = data
+		}
+		return entries, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		entries[hdr.Name] = data
+	}
+	return entries, nil
+}
+
+// renamedPath returns a free name for an imported file, e.g.
+// scripts/deploy.imported.sh
+func renamedPath(rel string) string {
+	ext := path.Ext(rel)
+	base := strings.TrimSuffix(rel, ext)
+	for i := 1; ; i++ {
+		candidate := base + ".imported" + ext
+		if i > 1 {
+			candidate = fmt.Sprintf("%s.imported-%d%s", base, i, ext)
+		}
+		if _, err := os.Stat(archiveFilePath(candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+func importArchive(name, onConflict string, dryRun bool) error {
+	switch onConflict {
+	case "skip", "overwrite", "rename":
+	default:
+		return fmt.Errorf("invalid --on-conflict '%s' (expected skip, overwrite, or rename)", onConflict)
+	}
+
+	entries, err := readArchive(name)
+	if err != nil {
+		return err
+	}
+	raw, ok := entries[archiveManifestName]
+	if !ok {
+		return fmt.Errorf("%s is not a berga archive (no %s)", name, archiveManifestName)
+	}
+	var manifest ArchiveManifest
+	if err := yaml.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, file := range manifest.Files {
+		rel, err := safeArchivePath(file.Path)
+		if err != nil {
+			return err
+		}
+		data, ok := entries[archiveFilesDir+rel]
+		if !ok {
+			return fmt.Errorf("archive is missing %s", rel)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != file.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s; the archive may be corrupt", rel)
+		}
+
+		action := "imported"
+		target := archiveFilePath(rel)
+		if existing, err := os.ReadFile(target); err == nil {
+			switch {
+			case bytes.Equal(existing, data):
+				counts["unchanged"]++
+				continue
+			case onConflict == "skip":
+				fmt.Printf("  skipped     %s (exists)\n", rel)
+				counts["skipped"]++
+				continue
+			case onConflict == "rename":
+				renamed := renamedPath(rel)
+				target = archiveFilePath(renamed)
+				action = "renamed"
+				rel = rel + " -> " + renamed
+			default:
+				action = "overwrote"
+			}
+		}
+
+		fmt.Printf("  %-11s %s\n", action, rel)
+		counts[action]++
+		if dryRun {
+			continue
+		}
+
+		mode := file.Mode.Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		write := func() error { return writeFileAtomic(target, data, mode) }
+		switch {
+		case strings.HasPrefix(rel, "scripts/"):
+			err = trackChange("script", target, revisionImport, write)
+		case strings.HasPrefix(rel, "templates/"):
+			err = trackChange("template", target, revisionImport, write)
+		default:
+			err = write()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+	}
+
+	summary := fmt.Sprintf("%d imported, %d overwritten, %d renamed, %d skipped, %d unchanged",
+		counts["imported"], counts["overwrote"], counts["renamed"], counts["skipped"], counts["unchanged"])
+	if dryRun {
+		summary += " (dry run)"
+	}
+	fmt.Println(summary)
+	return nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeHomeFile(t *testing.T, rel, content string) {
+	t.Helper()
+	path := archiveFilePath(rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readHomeFile(t *testing.T, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(archiveFilePath(rel))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", rel, err)
+	}
+	return string(data)
+}
+
+func TestCollectExportFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeHomeFile(t, "config.yaml", "a: 1\n")
+	writeHomeFile(t, "scripts/deploy.sh", "#!/bin/sh\n")
+	writeHomeFile(t, "templates/x.tmpl", "x")
+	writeHomeFile(t, "locks/config.lock", "1\n")
+	writeHomeFile(t, "cache/templates/abc/y.tmpl", "y")
+
+	files, err := collectExportFiles(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"config.yaml", "scripts/deploy.sh", "templates/x.tmpl"}
+	if len(files) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, files)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, files)
+		}
+	}
+
+	files, err = collectExportFiles([]string{"config", "scripts"})
+	if err != nil || len(files) != 2 {
+		t.Errorf("Expected config and scripts only, got %v (err %v)", files, err)
+	}
+
+	if _, err := collectExportFiles([]string{"missing"}); err == nil {
+		t.Error("Expected an error for a missing item")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "backup"+ext)
+
+			t.Setenv("HOME", t.TempDir())
+			writeHomeFile(t, "scripts/deploy.sh", "new deploy\n")
+			writeHomeFile(t, "scripts/build.sh", "build\n")
+			writeHomeFile(t, "templates/x.tmpl", "x")
+			if err := exportArchive(nil, archive); err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+
+			// A second machine with one conflicting and one identical file
+			t.Setenv("HOME", t.TempDir())
+			writeHomeFile(t, "scripts/deploy.sh", "old deploy\n")
+			writeHomeFile(t, "scripts/build.sh", "build\n")
+
+			if err := importArchive(archive, "skip", false); err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if got := readHomeFile(t, "scripts/deploy.sh"); got != "old deploy\n" {
+				t.Errorf("skip should keep the existing file, got %q", got)
+			}
+			if got := readHomeFile(t, "templates/x.tmpl"); got != "x" {
+				t.Errorf("Expected new template to be imported, got %q", got)
+			}
+
+			if err := importArchive(archive, "rename", false); err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if got := readHomeFile(t, "scripts/deploy.imported.sh"); got != "new deploy\n" {
+				t.Errorf("rename should import under a new name, got %q", got)
+			}
+
+			if err := importArchive(archive, "overwrite", false); err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if got := readHomeFile(t, "scripts/deploy.sh"); got != "new deploy\n" {
+				t.Errorf("overwrite should replace the file, got %q", got)
+			}
+		})
+	}
+}
+
+func TestImportRejectsBadInput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := importArchive("x.tar.gz", "merge", false); err == nil {
+		t.Error("Expected an error for an unknown conflict policy")
+	}
+
+	for _, p := range []string{"../evil", "/etc/passwd", "."} {
+		if _, err := safeArchivePath(p); err == nil {
+			t.Errorf("Expected %q to be rejected", p)
+		}
+	}
+	if got, err := safeArchivePath("scripts/./a.sh"); err != nil || got != "scripts/a.sh" {
+		t.Errorf("Expected cleaned path, got %q (err %v)", got, err)
+	}
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+	"berga/pkg/config"
+	"berga/pkg/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// maskedAuditValue replaces sensitive values in the audit log
+const maskedAuditValue = "********"
+
+// auditedCommands are the commands that change state or run code. Each run
+// of one is recorded with its arguments and result.
+var auditedCommands = map[string]bool{
+	"bookmark add": true, "bookmark remove": true,
+	"config init": true, "config migrate": true, "config set": true, "config unset": true,
+	"dotfiles add": true, "dotfiles link": true, "dotfiles restore": true,
+	"env exec": true, "export": true, "get": true, "import": true, "lock": true, "unlock": true,
+	"http edit": true, "http run": true, "jobs clean": true, "jobs stop": true, "history clear": true, "history fzf": true, "history rerun": true, "share script": true, "registry add": true, "registry remove": true, "registry update": true, "script install": true, "template install": true,
+	"profile create": true, "profile use": true, "run": true,
+	"script archive": true, "script copy": true, "script edit": true, "script encrypt": true, "script pin": true, "script protect": true, "script publish": true,
+	"script rename": true, "script rollback": true, "script run": true, "script run-group": true,
+	"script test": true, "script trust": true, "script unarchive": true, "script unprotect": true, "script unpin": true, "script untrust": true,
+	"tag add": true, "tag rm": true, "task run": true,
+	"template apply": true, "template copy": true, "template edit": true, "template export-builtin": true,
+	"template new": true, "template rename": true, "template rollback": true,
+}
+
+// AuditEntry is one entry of the audit log
+type AuditEntry = store.AuditEntry
+
+var (
+	auditSince  string
+	auditJSON   bool
+	auditAction string
+)
+
+// auditCmd groups the audit log commands
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Review the log of changes made with berga",
+	Long: `Every command that changes something or runs code is recorded in an
+append-only audit log: script runs, template applies, config changes, imports,
+and reads of secrets, with their arguments and result. Set audit.enabled to
+false to stop recording.`,
+}
+
+// auditShowCmd prints the audit log
+var auditShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the audit log",
+	Long: `Show audit log entries, oldest first. --since takes a duration such as 12h
+or 7d, or a date (2006-01-02); --action keeps entries whose action starts with
+the given text, such as "script" or "config set".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return showAudit(auditSince, auditAction, auditJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditShowCmd)
+
+	// Flags
+	auditShowCmd.Flags().StringVar(&auditSince, "since", "", "Only show entries newer than a duration (7d, 12h) or date")
+	auditShowCmd.Flags().StringVar(&auditAction, "action", "", "Only show actions starting with this text")
+	auditShowCmd.Flags().BoolVar(&auditJSON, "json", false, "Print entries as JSON")
+}
+
+// auditEnabled reports whether the audit log is being written
+func auditEnabled() bool {
+	return !viper.IsSet("audit.enabled") || viper.GetBool("audit.enabled")
+}
+
+// auditUser names the user running berga
+func auditUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// recordAudit appends an entry to the audit log. Like usage tracking it is
+// best-effort: failures are only reported in verbose mode.
+func recordAudit(action, target string, params map[string]string, result error) {
+	recordTimedAudit(action, target, params, result, 0)
+}
+
+// recordTimedAudit is recordAudit for actions that took a while, such as
+// script runs, keeping how long they took
+func recordTimedAudit(action, target string, params map[string]string, result error, elapsed time.Duration) {
+	if !auditEnabled() {
+		return
+	}
+	entry := AuditEntry{
+		Time:       time.Now().UTC(),
+		User:       auditUser(),
+		Action:     action,
+		Target:     target,
+		Params:     params,
+		DurationMS: elapsed.Milliseconds(),
+	}
+	if result != nil {
+		entry.Error = result.Error()
+	}
+
+	err := appendAuditEntry(entry)
+	if err != nil && viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+func appendAuditEntry(entry AuditEntry) error {
+	return withStore(func(s store.Store) error {
+		return s.AddAudit(entry)
+	})
+}
+
+// auditSecretAccess records that a secret was read to be used or shown
+func auditSecretAccess(key string) {
+	recordAudit("secret access", key, nil, nil)
+}
+
+// auditCommand records a finished command if it is one that is audited
+func auditCommand(cmd *cobra.Command, result error, elapsed time.Duration) {
+	if cmd == nil {
+		return
+	}
+	action := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	if !auditedCommands[action] {
+		return
+	}
+
+	var target string
+	args := cmd.Flags().Args()
+	if len(args) > 0 {
+		target, args = args[0], args[1:]
+	}
+	params := make(map[string]string)
+	if len(args) > 0 {
+		if action == "config set" {
+			if k, _ := config.LookupKey(target); k.Sensitive {
+				args = []string{maskedAuditValue}
+			}
+		}
+		params["args"] = strings.Join(args, " ")
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		params["--"+f.Name] = f.Value.String()
+	})
+	if len(params) == 0 {
+		params = nil
+	}
+	recordTimedAudit(action, target, params, result, elapsed)
+}
+
+// parseSince turns a --since value into the earliest time to show. Besides
+// Go durations it accepts whole days (7d) and dates.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since '%s' (expected a duration such as 7d or 12h, or a date)", s)
+}
+
+// loadAuditLog reads the whole audit log, oldest first
+func loadAuditLog() ([]AuditEntry, error) {
+	return queryAuditLog(time.Time{}, "")
+}
+
+// queryAuditLog reads the entries at or after since whose action starts with
+// action, oldest first
+func queryAuditLog(since time.Time, action string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := withStore(func(s store.Store) error {
+		var err error
+		entries, err = s.Audit(since, action)
+		return err
+	})
+	return entries, err
+}
+
+func showAudit(since, action string, asJSON bool) error {
+	var from time.Time
+	if since != "" {
+		var err error
+		if from, err = parseSince(since, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	entries, err := queryAuditLog(from, action)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if entries == nil {
+			entries = []AuditEntry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println(i18n.T("list.no_audit_entries"))
+		return nil
+	}
+	listHeader(i18n.T("header.audit_log"))
+	for _, entry := range entries {
+		status := ui.Green(ui.Icon("✅", "ok"))
+		if entry.Error != "" {
+			status = ui.Red(ui.Icon("❌", "x"))
+		}
+		line := fmt.Sprintf("  %s %s %s %s", status, ui.Dim(entry.Time.Local().Format("2006-01-02 15:04:05")), entry.User, ui.Bold(entry.Action))
+		if entry.Target != "" {
+			line += " " + entry.Target
+		}
+		if args := entry.Params["args"]; args != "" {
+			line += " " + args
+		}
+		keys := make([]string, 0, len(entry.Params))
+		for k := range entry.Params {
+			if k != "args" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			line += ui.Dim(fmt.Sprintf(" %s=%s", k, entry.Params[k]))
+		}
+		fmt.Println(line)
+		if entry.Error != "" {
+			fmt.Println("      " + ui.Red(entry.Error))
+		}
+	}
+	return nil
+}
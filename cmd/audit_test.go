@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestRecordAudit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	recordAudit("script run", "deploy.sh", map[string]string{"args": "prod"}, nil)
+	recordAudit("config set", "editor", nil, errors.New("boom"))
+
+	entries, err := loadAuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Action != "script run" || entries[0].Target != "deploy.sh" || entries[0].Params["args"] != "prod" {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+	if entries[1].Error != "boom" {
+		t.Errorf("Expected the failure to be recorded, got %+v", entries[1])
+	}
+	if info, err := os.Stat(GetStoreFile()); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("Expected a private audit log, got %v, %v", info, err)
+	}
+
+	viper.Set("audit.enabled", false)
+	defer viper.Set("audit.enabled", nil)
+	recordAudit("script run", "other.sh", nil, nil)
+	if entries, _ := loadAuditLog(); len(entries) != 2 {
+		t.Errorf("Expected nothing recorded with audit.enabled false, got %d entries", len(entries))
+	}
+}
+
+func TestAuditCommandMasksSecrets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cmd := &cobra.Command{Use: "set"}
+	configCmd.AddCommand(cmd)
+	defer configCmd.RemoveCommand(cmd)
+	cmd.Flags().Bool("plain", false, "")
+	if err := cmd.ParseFlags([]string{"secrets.token", "hunter2", "--plain"}); err != nil {
+		t.Fatal(err)
+	}
+	auditCommand(cmd, nil, 1500*time.Millisecond)
+
+	// Commands that change nothing are not recorded
+	auditCommand(scriptListCmd, nil, 0)
+
+	entries, _ := loadAuditLog()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %v", entries)
+	}
+	entry := entries[0]
+	if entry.Action != "config set" || entry.Target != "secrets.token" || entry.Params["--plain"] != "true" || entry.DurationMS != 1500 {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if strings.Contains(entry.Params["args"], "hunter2") {
+		t.Errorf("Expected the secret value to be masked, got %q", entry.Params["args"])
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"7d":  now.AddDate(0, 0, -7),
+		"12h": now.Add(-12 * time.Hour),
+		"0d":  now,
+	}
+	for in, want := range tests {
+		got, err := parseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if got, err := parseSince("2024-03-01", now); err != nil || got.Day() != 1 {
+		t.Errorf("Expected a date to parse, got %v, %v", got, err)
+	}
+	if _, err := parseSince("last week", now); err == nil {
+		t.Error("Expected an invalid value to be an error")
+	}
+}
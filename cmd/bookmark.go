@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	bookmarkTags      []string
+	bookmarkFilterTag string
+)
+
+// Bookmark is a named URL or filesystem path
+type Bookmark struct {
+	Name   string   `yaml:"name"`
+	Target string   `yaml:"target"`
+	Tags   []string `yaml:"tags,omitempty"`
+}
+
+// IsURL reports whether the bookmark points to a URL rather than a path
+func (b Bookmark) IsURL() bool {
+	return strings.Contains(b.Target, "://")
+}
+
+// bookmarkCmd represents the bookmark command
+var bookmarkCmd = &cobra.Command{
+	Use:     "bookmark",
+	Short:   "Manage bookmarked URLs and paths",
+	Long:    `Store named URLs and filesystem paths with tags, and open or jump to them quickly.`,
+	Aliases: []string{"bm"},
+}
+
+// bookmarkAddCmd adds a bookmark
+var bookmarkAddCmd = &cobra.Command{
+	Use:   "add [name] [url-or-path]",
+	Short: "Add a bookmark",
+	Long:  `Add a named bookmark for a URL or filesystem path. Relative paths are stored as absolute paths.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("bookmarks", func() error {
+			return addBookmark(args[0], args[1], bookmarkTags)
+		})
+	},
+}
+
+// bookmarkListCmd lists bookmarks
+var bookmarkListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List bookmarks",
+	Long:    `Display all bookmarks, optionally filtered by tag.`,
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listBookmarks(bookmarkFilterTag)
+	},
+}
+
+// bookmarkOpenCmd opens a bookmark
+var bookmarkOpenCmd = &cobra.Command{
+	Use:   "open [name]",
+	Short: "Open a bookmark",
+	Long:  `Open a bookmarked URL in the browser or a bookmarked path in the file manager.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return openBookmark(args[0])
+	},
+}
+
+// bookmarkCdCmd prints a bookmarked path
+var bookmarkCdCmd = &cobra.Command{
+	Use:   "cd [name]",
+	Short: "Print a bookmarked path for shell functions",
+	Long: `Print the path of a bookmark so a shell function can change into it, e.g.:
+
+  bcd() { cd "$(berga bookmark cd "$1")"; }`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printBookmarkPath(args[0])
+	},
+}
+
+// bookmarkSearchCmd searches bookmarks
+var bookmarkSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search bookmarks",
+	Long:  `Search bookmarks by name, target, or tag.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return searchBookmarks(args[0])
+	},
+}
+
+// bookmarkRemoveCmd removes a bookmark
+var bookmarkRemoveCmd = &cobra.Command{
+	Use:     "remove [name]",
+	Short:   "Remove a bookmark",
+	Long:    `Remove a bookmark by name.`,
+	Aliases: []string{"rm"},
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("bookmarks", func() error {
+			return removeBookmark(args[0])
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkCmd)
+	bookmarkCmd.AddCommand(bookmarkAddCmd)
+	bookmarkCmd.AddCommand(bookmarkListCmd)
+	bookmarkCmd.AddCommand(bookmarkOpenCmd)
+	bookmarkCmd.AddCommand(bookmarkCdCmd)
+	bookmarkCmd.AddCommand(bookmarkSearchCmd)
+	bookmarkCmd.AddCommand(bookmarkRemoveCmd)
+
+	// Flags
+	bookmarkAddCmd.Flags().StringSliceVarP(&bookmarkTags, "tag", "t", nil, "Tag for the bookmark (repeatable)")
+	bookmarkListCmd.Flags().StringVarP(&bookmarkFilterTag, "tag", "t", "", "Only show bookmarks with this tag")
+}
+
+func loadBookmarks() ([]Bookmark, error) {
+	data, err := os.ReadFile(GetBookmarksFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+
+	var bookmarks []Bookmark
+	if err := yaml.Unmarshal(data, &bookmarks); err != nil {
+		return nil, fmt.Errorf("failed to parse bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+func saveBookmarks(bookmarks []Bookmark) error {
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].Name < bookmarks[j].Name
+	})
+
+	data, err := yaml.Marshal(bookmarks)
+	if err != nil {
+		return fmt.Errorf("failed to encode bookmarks: %w", err)
+	}
+
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetBookmarksFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bookmarks: %w", err)
+	}
+	return nil
+}
+
+func findBookmark(bookmarks []Bookmark, name string) (Bookmark, error) {
+	for _, b := range bookmarks {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Bookmark{}, fmt.Errorf("bookmark '%s' not found", name)
+}
+
+func addBookmark(name, target string, tags []string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	if _, err := findBookmark(bookmarks, name); err == nil {
+		return fmt.Errorf("bookmark '%s' already exists", name)
+	}
+
+	bookmark := Bookmark{Name: name, Target: target, Tags: tags}
+	if !bookmark.IsURL() {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		bookmark.Target = abs
+	}
+
+	if err := saveBookmarks(append(bookmarks, bookmark)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Bookmark '%s' added: %s\n", name, bookmark.Target)
+	return nil
+}
+
+func printBookmarks(bookmarks []Bookmark) {
+	for _, b := range bookmarks {
+		icon := ui.Icon("📁", "d")
+		if b.IsURL() {
+			icon = ui.Icon("🔗", "@")
+		}
+		line := fmt.Sprintf("  %s %s -> %s", icon, ui.Bold(b.Name), b.Target)
+		if len(b.Tags) > 0 {
+			line += " " + ui.Cyan(fmt.Sprintf("[%s]", strings.Join(b.Tags, ", ")))
+		}
+		fmt.Println(line)
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func listBookmarks(tag string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	if tag != "" {
+		var filtered []Bookmark
+		for _, b := range bookmarks {
+			if hasTag(b.Tags, tag) {
+				filtered = append(filtered, b)
+			}
+		}
+		bookmarks = filtered
+	}
+
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks found.")
+		fmt.Println("Add one with: berga bookmark add <name> <url-or-path>")
+		return nil
+	}
+
+	ui.Header("Bookmarks")
+	printBookmarks(bookmarks)
+	return nil
+}
+
+func searchBookmarks(query string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	query = strings.ToLower(query)
+	var matches []Bookmark
+	for _, b := range bookmarks {
+		if strings.Contains(strings.ToLower(b.Name), query) ||
+			strings.Contains(strings.ToLower(b.Target), query) ||
+			hasTag(b.Tags, query) {
+			matches = append(matches, b)
+		}
+	}
+
+	if len(matches) == 0 {
+		fmt.Printf("No bookmarks matching '%s'.\n", query)
+		return nil
+	}
+
+	printBookmarks(matches)
+	return nil
+}
+
+func openBookmark(name string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	bookmark, err := findBookmark(bookmarks, name)
+	if err != nil {
+		return err
+	}
+
+	return openWithSystem(bookmark.Target)
+}
+
+func printBookmarkPath(name string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	bookmark, err := findBookmark(bookmarks, name)
+	if err != nil {
+		return err
+	}
+	if bookmark.IsURL() {
+		return fmt.Errorf("bookmark '%s' is a URL, not a path", name)
+	}
+
+	fmt.Println(bookmark.Target)
+	return nil
+}
+
+func removeBookmark(name string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+
+	for i, b := range bookmarks {
+		if b.Name == name {
+			if err := saveBookmarks(append(bookmarks[:i], bookmarks[i+1:]...)); err != nil {
+				return err
+			}
+			fmt.Printf("Bookmark '%s' removed.\n", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("bookmark '%s' not found", name)
+}
+
+// openWithSystem opens a URL or path with the platform's default handler
+func openWithSystem(target string) error {
+	cmd := systemOpenCommand(runtime.GOOS, target)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	return nil
+}
+
+// systemOpenCommand is the command opening target on goos. On Windows the
+// target goes to the URL handler directly rather than through cmd /C start,
+// where & and | in a URL would start other commands.
+func systemOpenCommand(goos, target string) *exec.Cmd {
+	switch goos {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	case "darwin":
+		return exec.Command("open", target)
+	default:
+		return exec.Command("xdg-open", target)
+	}
+}
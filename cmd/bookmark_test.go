@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBookmarks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	adds := []struct {
+		name, target string
+		tags         []string
+		wantErr      bool
+	}{
+		{"docs", "https://example.com/docs", []string{"work"}, false},
+		{"api", dir, nil, false},
+		{"rel", ".", nil, false},
+		{"docs", "https://example.org", nil, true},
+	}
+	for _, tt := range adds {
+		err := addBookmark(tt.name, tt.target, tt.tags)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("addBookmark(%s, %s) error = %v, want error %v", tt.name, tt.target, err, tt.wantErr)
+		}
+	}
+
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, b := range bookmarks {
+		names = append(names, b.Name)
+	}
+	if strings.Join(names, ",") != "api,docs,rel" {
+		t.Errorf("Expected bookmarks sorted by name, got %v", names)
+	}
+
+	wd, _ := os.Getwd()
+	resolves := []struct {
+		name, target string
+		isURL        bool
+	}{
+		{"docs", "https://example.com/docs", true},
+		{"api", dir, false},
+		{"rel", wd, false},
+	}
+	for _, tt := range resolves {
+		b, err := findBookmark(bookmarks, tt.name)
+		if err != nil || b.Target != tt.target || b.IsURL() != tt.isURL {
+			t.Errorf("findBookmark(%s) = %+v, %v; want target %s, URL %v", tt.name, b, err, tt.target, tt.isURL)
+		}
+	}
+	if _, err := findBookmark(bookmarks, "missing"); err == nil {
+		t.Error("Expected a missing bookmark to be an error")
+	}
+	if err := printBookmarkPath("docs"); err == nil {
+		t.Error("Expected a URL bookmark to have no path")
+	}
+
+	if err := removeBookmark("api"); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeBookmark("api"); err == nil {
+		t.Error("Expected removing a missing bookmark to be an error")
+	}
+	bookmarks, _ = loadBookmarks()
+	if len(bookmarks) != 2 || !hasTag(bookmarks[0].Tags, "WORK") {
+		t.Errorf("Unexpected bookmarks after removal: %+v", bookmarks)
+	}
+}
+
+func TestSystemOpenCommand(t *testing.T) {
+	target := "https://example.com/?a=1&b=2|calc"
+	tests := []struct {
+		goos string
+		want []string
+	}{
+		{"windows", []string{"rundll32", "url.dll,FileProtocolHandler", target}},
+		{"darwin", []string{"open", target}},
+		{"linux", []string{"xdg-open", target}},
+	}
+	for _, tt := range tests {
+		if got := systemOpenCommand(tt.goos, target).Args; strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+			t.Errorf("systemOpenCommand(%s) = %q, want %q", tt.goos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// cheatExt is the extension of cheatsheet files
+const cheatExt = ".md"
+
+var cheatEdit bool
+
+// cheatCmd shows cheatsheets
+var cheatCmd = &cobra.Command{
+	Use:   "cheat [topic]",
+	Short: "Show a cheatsheet",
+	Long: `Show a command reference sheet from ~/.berga/cheats. Each topic is a Markdown
+file, and subdirectories group topics: cheats/git/rebase.md is "git/rebase".
+Headings, tables, inline code, and fenced code blocks are rendered with colors.
+
+The topic is matched fuzzily, so a few letters are enough:
+
+  berga cheat              # list the topics
+  berga cheat tar
+  berga cheat gre          # git/rebase
+  berga cheat --edit tar   # create or edit cheats/tar.md`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if len(args) == 0 {
+			if cheatEdit {
+				return fmt.Errorf("--edit needs a topic")
+			}
+			return listCheats()
+		}
+		if cheatEdit {
+			return editCheat(args[0])
+		}
+		return showCheat(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cheatCmd)
+	cheatCmd.ValidArgsFunction = completeCheatTopics
+
+	// Flags
+	cheatCmd.Flags().BoolVarP(&cheatEdit, "edit", "e", false, "Create or edit the topic's cheatsheet in your editor")
+	cheatCmd.Flags().BoolVar(&showNoPager, "no-pager", false, "Print the cheatsheet instead of opening it in a pager")
+}
+
+// cheatTopics returns the topics in the cheats directory, sorted
+func cheatTopics() ([]string, error) {
+	dir := GetCheatsDir()
+	var topics []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(d.Name()), cheatExt) {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		topics = append(topics, filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cheats directory: %w", err)
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// cheatPath returns the file of a topic
+func cheatPath(topic string) string {
+	return filepath.Join(GetCheatsDir(), filepath.FromSlash(topic)+cheatExt)
+}
+
+// findCheat picks the topic a query means: a topic or the last part of one
+// with that name, or else the best fuzzy match, preferring shorter topics
+func findCheat(query string, topics []string) (string, bool) {
+	for _, topic := range topics {
+		if strings.EqualFold(topic, query) {
+			return topic, true
+		}
+	}
+	for _, topic := range topics {
+		if strings.EqualFold(pathBase(topic), query) {
+			return topic, true
+		}
+	}
+
+	best, bestScore := "", -1
+	for _, topic := range topics {
+		score, ok := fuzzyScore(query, topic)
+		if !ok {
+			continue
+		}
+		if score > bestScore || score == bestScore && len(topic) < len(best) {
+			best, bestScore = topic, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// pathBase returns the last part of a slash-separated topic
+func pathBase(topic string) string {
+	return topic[strings.LastIndex(topic, "/")+1:]
+}
+
+func listCheats() error {
+	topics, err := cheatTopics()
+	if err != nil {
+		return err
+	}
+	if len(topics) == 0 {
+		fmt.Println(i18n.T("list.no_cheats"))
+		fmt.Println(i18n.T("list.add_cheats", GetCheatsDir()))
+		return nil
+	}
+	width := 0
+	for _, topic := range topics {
+		width = max(width, len(topic))
+	}
+	listHeader(i18n.T("header.cheats"))
+	for _, topic := range topics {
+		fmt.Printf("  %-*s  %s\n", width, topic, ui.Dim(cheatTitle(cheatPath(topic))))
+	}
+	return nil
+}
+
+// cheatTitle returns the first heading of a cheatsheet
+func cheatTitle(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+	}
+	return ""
+}
+
+func showCheat(query string) error {
+	topics, err := cheatTopics()
+	if err != nil {
+		return err
+	}
+	topic, ok := findCheat(query, topics)
+	if !ok {
+		return fmt.Errorf("no cheatsheet matches '%s'; see 'berga cheat' for the topics, or create it with 'berga cheat --edit %s'", query, query)
+	}
+	content, err := os.ReadFile(cheatPath(topic))
+	if err != nil {
+		return fmt.Errorf("failed to read cheatsheet: %w", err)
+	}
+	return pageOutput(renderMarkdown(string(content)), showNoPager)
+}
+
+// editCheat opens a topic's cheatsheet in the editor, creating it with a
+// heading when it does not exist. The topic is taken as given, never fuzzily.
+func editCheat(topic string) error {
+	topic = strings.TrimSuffix(filepath.ToSlash(topic), cheatExt)
+	if topic == "" || strings.HasPrefix(topic, "/") || strings.Contains("/"+topic+"/", "/../") {
+		return fmt.Errorf("invalid topic '%s'", topic)
+	}
+	path := cheatPath(topic)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create cheats directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte("# "+pathBase(topic)+"\n\n"), 0644); err != nil {
+			return fmt.Errorf("failed to create cheatsheet: %w", err)
+		}
+	}
+	editor := preferredEditor()
+	fmt.Printf("Opening %s with %s...\n", path, editor)
+	return editFile(editor, path)
+}
+
+// completeCheatTopics completes topic names
+func completeCheatTopics(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	topics, _ := cheatTopics()
+	var names []string
+	for _, topic := range topics {
+		if strings.HasPrefix(topic, toComplete) {
+			names = append(names, topic)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+var (
+	// markdownCode matches `inline code`
+	markdownCode = regexp.MustCompile("`([^`]+)`")
+	// markdownBold matches **bold** text
+	markdownBold = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	// markdownTableRule matches the line under a table's header, e.g. |---|:--:|
+	markdownTableRule = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// fenceLanguages maps code fence languages to a file name highlight
+// recognizes
+var fenceLanguages = map[string]string{
+	"bash": ".sh", "sh": ".sh", "shell": ".sh", "zsh": ".sh", "console": ".sh",
+	"python": ".py", "py": ".py",
+	"go":         ".go",
+	"javascript": ".js", "js": ".js", "typescript": ".ts", "ts": ".ts",
+	"ruby": ".rb", "rb": ".rb",
+	"powershell": ".ps1", "ps1": ".ps1", "pwsh": ".ps1",
+	"yaml": ".yaml", "yml": ".yaml",
+}
+
+// renderMarkdown renders a cheatsheet for the terminal: headings in color,
+// tables aligned, code blocks indented and highlighted, inline code in color
+func renderMarkdown(content string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			lang := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(trimmed, "```")))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			if len(code) == 0 {
+				continue
+			}
+			rendered := highlight("code"+fenceLanguages[lang], strings.Join(code, "\n")+"\n")
+			for _, codeLine := range strings.Split(strings.TrimSuffix(rendered, "\n"), "\n") {
+				b.WriteString("    " + codeLine + "\n")
+			}
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && markdownTableRule.MatchString(strings.TrimSpace(lines[i+1])):
+			rows := [][]string{tableCells(trimmed)}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, tableCells(strings.TrimSpace(lines[i])))
+			}
+			i--
+			renderTable(&b, rows)
+		case strings.HasPrefix(trimmed, "#"):
+			heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			if strings.HasPrefix(trimmed, "# ") {
+				heading = strings.ToUpper(heading)
+			}
+			b.WriteString(ui.Bold(ui.Cyan(heading)) + "\n")
+		default:
+			b.WriteString(renderInline(line) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// renderInline styles inline code and bold text
+func renderInline(text string) string {
+	text = markdownCode.ReplaceAllStringFunc(text, func(m string) string {
+		return ui.Cyan(markdownCode.FindStringSubmatch(m)[1])
+	})
+	return markdownBold.ReplaceAllStringFunc(text, func(m string) string {
+		return ui.Bold(markdownBold.FindStringSubmatch(m)[1])
+	})
+}
+
+// plainInline is text as renderInline shows it, without the styling, for
+// measuring columns
+func plainInline(text string) string {
+	return markdownBold.ReplaceAllString(markdownCode.ReplaceAllString(text, "$1"), "$1")
+}
+
+// tableCells splits a Markdown table row into its cells
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+// renderTable writes rows as aligned columns, the first as the header
+func renderTable(b *strings.Builder, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(plainInline(cell)))
+		}
+	}
+	for r, row := range rows {
+		b.WriteString("  ")
+		for i, cell := range row {
+			text := renderInline(cell)
+			if r == 0 {
+				text = ui.Bold(plainInline(cell))
+			}
+			b.WriteString(text)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(plainInline(cell))+2))
+			}
+		}
+		b.WriteString("\n")
+		if r == 0 {
+			var rule []string
+			for _, width := range widths {
+				rule = append(rule, strings.Repeat(ui.Icon("─", "-"), width))
+			}
+			b.WriteString("  " + ui.Dim(strings.Join(rule, "  ")) + "\n")
+		}
+	}
+}
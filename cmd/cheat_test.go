@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"berga/internal/ui"
+)
+
+func TestFindCheat(t *testing.T) {
+	topics := []string{"docker", "git/rebase", "git/stash", "rebase-notes", "tar"}
+	tests := map[string]string{
+		"tar":        "tar",
+		"TAR":        "tar",
+		"rebase":     "git/rebase",
+		"git/stash":  "git/stash",
+		"gstsh":      "git/stash",
+		"dkr":        "docker",
+		"rebase-not": "rebase-notes",
+	}
+	for query, want := range tests {
+		if got, ok := findCheat(query, topics); !ok || got != want {
+			t.Errorf("findCheat(%q) = %q, %v, want %q", query, got, ok, want)
+		}
+	}
+	if got, ok := findCheat("kubectl", topics); ok {
+		t.Errorf("Expected no match for kubectl, got %q", got)
+	}
+}
+
+func TestCheatTopics(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"tar.md", "git/rebase.md", "git/notes.txt", ".drafts/wip.md"} {
+		path := filepath.Join(GetCheatsDir(), filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("# Title\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	topics, err := cheatTopics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(topics, ",") != "git/rebase,tar" {
+		t.Errorf("Unexpected topics %v", topics)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	content := "# tar\n\n| Flag | Meaning |\n|------|:-------:|\n| `-x` | extract |\n| `-czf` | **create** gzip |\n\n```bash\ntar -xzf a.tgz\n```\n"
+	want := "TAR\n\n" +
+		"  Flag  Meaning\n" +
+		"  " + strings.Repeat(ui.Icon("─", "-"), 4) + "  " + strings.Repeat(ui.Icon("─", "-"), 11) + "\n" +
+		"  -x    extract\n" +
+		"  -czf  create gzip\n" +
+		"\n" +
+		"    tar -xzf a.tgz\n"
+	if got := renderMarkdown(content); got != want {
+		t.Errorf("renderMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"berga/internal/ui"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -20,9 +23,23 @@ var configCmd = &cobra.Command{
 var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize berga configuration",
-	Long:  `Create the initial berga configuration directory and files.`,
+	Long: `Create the initial berga configuration directory and files.
+
+With --interactive, berga asks for the scripts and templates directories,
+your editor, the author and email for templates, and whether to keep an
+audit log, then offers to add completion to your shell. The same questions
+are asked the first time berga runs in a terminal without a config.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return initializeBergaConfig()
+		if configInitInteractive {
+			cmd.SilenceUsage = true
+			if promptsDisabled() {
+				return fmt.Errorf("--interactive needs a terminal to ask questions")
+			}
+			return withLock("config", func() error {
+				return runSetupWizard(true)
+			})
+		}
+		return withLock("config", initializeBergaConfig)
 	},
 }
 
@@ -51,6 +68,9 @@ func init() {
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configPathCmd)
+
+	// Flags
+	configInitCmd.Flags().BoolVarP(&configInitInteractive, "interactive", "i", false, "Ask for the basic settings instead of writing defaults")
 }
 
 func initializeBergaConfig() error {
@@ -67,12 +87,25 @@ func initializeBergaConfig() error {
 		fmt.Printf("Created directory: %s\n", dir)
 	}
 
+	// Keep editor and OS clutter out of listings
+	for _, dir := range []string{scriptsDir, templatesDir} {
+		ignoreFile := filepath.Join(dir, ignoreFileName)
+		if _, err := os.Stat(ignoreFile); os.IsNotExist(err) {
+			if err := os.WriteFile(ignoreFile, []byte(defaultIgnoreRules), 0644); err != nil {
+				return fmt.Errorf("failed to create %s: %w", ignoreFile, err)
+			}
+		}
+	}
+
 	// Create default config file
 	configFile := filepath.Join(configDir, "config.yaml")
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		defaultConfig := `# Berga Configuration File
 # This file stores your personal berga settings
 
+# Config layout version, upgraded by 'berga config migrate'
+version: 1
+
 # Default editor for editing scripts and configs
 editor: ""
 
@@ -81,8 +114,9 @@ shell: ""
 
 # Script execution settings
 scripts:
-  timeout: 300  # seconds
+  timeout: 300  # seconds, or a duration such as 5m; 0 for no timeout
   verbose: false
+  require_trust: false  # refuse scripts not approved with 'berga script trust'
 
 # Template settings
 templates:
@@ -153,14 +187,17 @@ Thumbs.db
 }
 
 func showConfiguration() error {
-	fmt.Println("Berga Configuration:")
-	fmt.Println("===================")
+	ui.Header("Berga Configuration")
 	
+	printActiveProfile()
 	if viper.ConfigFileUsed() != "" {
 		fmt.Printf("Config file: %s\n", viper.ConfigFileUsed())
 	} else {
 		fmt.Println("Config file: Not found")
 	}
+	if project != nil {
+		fmt.Printf("Project file: %s\n", project.File)
+	}
 	
 	fmt.Printf("Verbose: %v\n", viper.GetBool("verbose"))
 	
@@ -177,11 +214,17 @@ func showConfiguration() error {
 }
 
 func showPaths() error {
-	fmt.Println("Berga Paths:")
-	fmt.Println("============")
+	ui.Header("Berga Paths")
+	printActiveProfile()
 	fmt.Printf("Config directory: %s\n", GetConfigDir())
-	fmt.Printf("Scripts directory: %s\n", GetScriptsDir())
-	fmt.Printf("Templates directory: %s\n", GetTemplatesDir())
+	if GetDataDir() != GetConfigDir() {
+		fmt.Printf("Data directory: %s\n", GetDataDir())
+	}
+	fmt.Printf("Scripts directory: %s\n", strings.Join(GetScriptsDirs(), string(os.PathListSeparator)))
+	fmt.Printf("Templates directory: %s\n", strings.Join(GetTemplatesDirs(), string(os.PathListSeparator)))
+	if dir := GetProjectScriptsDir(); dir != "" {
+		fmt.Printf("Project scripts directory: %s\n", dir)
+	}
 
 	// Check if directories exist
 	paths := map[string]string{
@@ -193,11 +236,15 @@ func showPaths() error {
 	fmt.Println("\nDirectory Status:")
 	for name, path := range paths {
 		if _, err := os.Stat(path); os.IsNotExist(err) {
-			fmt.Printf("  %s: ❌ Not found\n", name)
+			fmt.Printf("  %s: %s\n", name, ui.Red(ui.Icon("❌", "x")+" Not found"))
 		} else {
-			fmt.Printf("  %s: ✅ Exists\n", name)
+			fmt.Printf("  %s: %s\n", name, ui.Green(ui.Icon("✅", "ok")+" Exists"))
 		}
 	}
+	
+	if configRoot, _ := platformRoots(); usingLegacyRoot() && configRoot != legacyBergaRoot() {
+		fmt.Printf("\n%s is the legacy location; move to %s with 'berga config migrate'.\n", legacyBergaRoot(), configRoot)
+	}
 
 	return nil
 }
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configCheckCmd validates the config files in use against the schema
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the config files for unknown keys and invalid values",
+	Long: `Compare the config file and the project .berga.yaml in use with the keys berga
+knows, and report each unknown key and each value of the wrong type with its
+line. A misspelled key is otherwise ignored, so a setting like
+
+  scripts:
+    timout: 10m
+
+silently has no effect. Every command also prints these problems as warnings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		found := 0
+		for _, path := range checkedConfigFiles() {
+			problems, err := checkConfigFile(path)
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Printf("%s:%d: %s\n", path, problem.Line, problem.Message)
+			}
+			found += len(problems)
+		}
+		if found > 0 {
+			return fmt.Errorf("found %d problem(s) in the config", found)
+		}
+		fmt.Println(ui.Green("✓") + " Config is valid")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configCheckCmd)
+}
+
+// checkedConfigFiles returns the global config file and project file in use
+func checkedConfigFiles() []string {
+	var paths []string
+	if used := viper.ConfigFileUsed(); used != "" {
+		paths = append(paths, used)
+	}
+	if project != nil {
+		paths = append(paths, project.File)
+	}
+	return paths
+}
+
+// checkConfigFile reports the problems in a config file
+func checkConfigFile(path string) ([]config.Problem, error) {
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Check(doc, flagConfigKeys...), nil
+}
+
+// warnConfigProblems prints the problems in the config files in use as
+// warnings. Unreadable files are left to the commands that read them.
+func warnConfigProblems() {
+	for _, path := range checkedConfigFiles() {
+		problems, err := checkConfigFile(path)
+		if err != nil {
+			continue
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %s:%d: %s", path, problem.Line, problem.Message)))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var configSetPlain bool
+
+// configGetCmd prints a configuration value
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Get a configuration value",
+	Long:  `Print the effective value of a configuration key, using dot-paths for nested keys (e.g. scripts.timeout).`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return getConfigValue(args[0])
+	},
+}
+
+// configSetCmd sets a configuration value
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set a configuration value",
+	Long: `Set a configuration key in your config file. Values are validated against the
+known keys and their types before the file is written, and existing comments
+are preserved.
+
+Sensitive keys (serve.token, secrets.*) are stored in the OS keychain (macOS
+Keychain, Windows Credential Manager, or libsecret) and the config file only
+records a reference to them. Use --plain to write them to the file instead.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("config", func() error {
+			return setConfigValue(args[0], args[1])
+		})
+	},
+}
+
+// configUnsetCmd removes a configuration value
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset [key]",
+	Short: "Remove a configuration value",
+	Long:  `Remove a key from your config file so its default applies again.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("config", func() error {
+			return unsetConfigValue(args[0])
+		})
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+
+	// Flags
+	configSetCmd.Flags().BoolVar(&configSetPlain, "plain", false, "Store sensitive values in the config file instead of the OS keychain")
+}
+
+// configFilePath returns the config file that is read, or the default location
+func configFilePath() string {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used
+	}
+	return filepath.Join(GetConfigDir(), "config.yaml")
+}
+
+// loadConfigDocument parses a YAML file into a document node, creating an
+// empty mapping when the file does not exist
+func loadConfigDocument(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	doc, err := config.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func saveConfigDocument(path string, doc *yaml.Node) error {
+	data, err := config.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	// Write through a symlinked config and keep its permissions, which are
+	// often 0600 when it holds secrets
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+func getConfigValue(key string) error {
+	if !viper.IsSet(key) {
+		return fmt.Errorf("config key '%s' is not set", key)
+	}
+
+	if k, _ := config.LookupKey(key); k.Sensitive {
+		auditSecretAccess(key)
+	}
+
+	value := viper.Get(key)
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		out, err := yaml.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+		fmt.Print(string(out))
+	default:
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func setConfigValue(key, raw string) error {
+	value, tag, err := config.Validate(key, raw)
+	if err != nil {
+		return err
+	}
+
+	shown := value
+	if k, _ := config.LookupKey(key); k.Sensitive {
+		// The keychain reference is safe to show; a plain secret is not
+		shown = maskedSecret
+		if !configSetPlain {
+			if value, err = storeSecret(key, value); err != nil {
+				return err
+			}
+			shown = value
+		}
+	}
+
+	path := configFilePath()
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	if err := config.Set(doc.Content[0], key, value, tag); err != nil {
+		return err
+	}
+	if err := saveConfigDocument(path, doc); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s = %s in %s\n", key, shown, path)
+	return nil
+}
+
+func unsetConfigValue(key string) error {
+	path := configFilePath()
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	// Drop the keychain entry the file refers to, if any
+	if account, ok := keychainRef(config.Value(doc.Content[0], key)); ok {
+		if err := deleteSecret(account); err != nil {
+			return err
+		}
+	}
+	if !config.Remove(doc.Content[0], key) {
+		return fmt.Errorf("config key '%s' is not set in %s", key, path)
+	}
+	if err := saveConfigDocument(path, doc); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unset %s in %s\n", key, path)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSaveConfigDocumentKeepsFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks and modes differ on Windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "berga.yaml")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	if err := os.WriteFile(target, []byte("editor: vim\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := loadConfigDocument(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveConfigDocument(link, doc); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Expected the config to stay a symlink")
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the config to keep mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestSetConfigValuePlainSecretMasked(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configSetPlain = true
+	defer func() { configSetPlain = false }()
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	err := setConfigValue("secrets.api_token", "s3cr3t-value")
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "s3cr3t-value") || !strings.Contains(string(out), maskedSecret) {
+		t.Errorf("Expected the secret to be masked, got %q", out)
+	}
+	data, _ := os.ReadFile(configFilePath())
+	if !strings.Contains(string(data), "s3cr3t-value") {
+		t.Errorf("Expected --plain to store the secret in the file, got:\n%s", data)
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// envPrefix starts the name of every environment variable berga reads config
+// from, e.g. BERGA_SCRIPTS_TIMEOUT for scripts.timeout
+const envPrefix = "BERGA"
+
+// envKeyReplacer maps the separators in config keys to underscores
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// legacyEnvNames are unprefixed variables read before the BERGA_ prefix was
+// introduced. They still apply, but only as defaults below the config file.
+var legacyEnvNames = map[string]string{"editor": "EDITOR", "pager": "PAGER", "verbose": "VERBOSE"}
+
+// flagConfigKeys are config keys that come from global flags rather than the
+// schema
+var flagConfigKeys = []string{"no-color", "verbose"}
+
+// configEnvCmd lists the environment variables berga reads config from
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the environment variables that override config keys",
+	Long: `Print every environment variable berga recognizes, the config key it sets,
+and its current value. A variable overrides the config file, and a flag
+overrides the variable:
+
+  BERGA_SCRIPTS_TIMEOUT=60 berga script run backup.sh
+
+Names are BERGA_ followed by the key in upper case with dots and dashes
+turned into underscores. Keys such as secrets.<name> take any name:
+BERGA_SECRETS_API_TOKEN sets secrets.api_token. Sensitive values are masked.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showConfigEnv()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEnvCmd)
+}
+
+// setupConfigEnv makes every config key readable from its BERGA_ variable,
+// with the legacy unprefixed names as defaults
+func setupConfigEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
+	viper.AutomaticEnv()
+	for key, name := range legacyEnvNames {
+		if value, ok := os.LookupEnv(name); ok {
+			viper.SetDefault(key, value)
+		}
+	}
+}
+
+// configEnvName returns the environment variable for a config key
+func configEnvName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// configEnvVar is one row of 'config env'
+type configEnvVar struct {
+	Name, Key, Value string
+}
+
+// configEnvVars lists the recognized variables: one per fixed key, and one
+// per variable set below a wildcard key such as secrets.*
+func configEnvVars() []configEnvVar {
+	keys := append(config.KnownKeys(), flagConfigKeys...)
+	sort.Strings(keys)
+
+	var vars []configEnvVar
+	for _, key := range keys {
+		if prefix, ok := strings.CutSuffix(key, ".*"); ok {
+			namePrefix := configEnvName(prefix) + "_"
+			vars = append(vars, configEnvVar{Name: namePrefix + "<NAME>", Key: prefix + ".<name>"})
+			for _, entry := range os.Environ() {
+				name, value, _ := strings.Cut(entry, "=")
+				if rest, ok := strings.CutPrefix(name, namePrefix); ok && rest != "" {
+					vars = append(vars, configEnvVar{Name: name, Key: prefix + "." + strings.ToLower(rest), Value: value})
+				}
+			}
+			continue
+		}
+		name := configEnvName(key)
+		vars = append(vars, configEnvVar{Name: name, Key: key, Value: os.Getenv(name)})
+	}
+	return vars
+}
+
+func showConfigEnv() error {
+	vars := configEnvVars()
+	width := 0
+	for _, v := range vars {
+		width = max(width, len(v.Name))
+	}
+
+	for _, v := range vars {
+		value := v.Value
+		if k, _ := config.LookupKey(v.Key); k.Sensitive && value != "" {
+			value = maskedAuditValue
+		}
+		line := fmt.Sprintf("%-*s  %s", width, v.Name, v.Key)
+		if legacy, ok := legacyEnvNames[v.Key]; ok {
+			line += ui.Dim(fmt.Sprintf(" (or %s as a default)", legacy))
+		}
+		if value != "" {
+			line += " = " + value
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"scripts.timeout": "BERGA_SCRIPTS_TIMEOUT",
+		"no-color":        "BERGA_NO_COLOR",
+		"secrets.api":     "BERGA_SECRETS_API",
+	} {
+		if got := configEnvName(key); got != want {
+			t.Errorf("configEnvName(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestSetupConfigEnv(t *testing.T) {
+	t.Setenv("BERGA_SCRIPTS_TIMEOUT", "60")
+	t.Setenv("SCRIPTS_TIMEOUT", "1")
+	t.Setenv("EDITOR", "legacy-editor")
+	setupConfigEnv()
+	defer viper.SetDefault("editor", nil)
+
+	if got := scriptRunTimeout(); got != time.Minute {
+		t.Errorf("Expected BERGA_SCRIPTS_TIMEOUT to set the timeout, got %v", got)
+	}
+	if got := viper.GetString("editor"); got != "legacy-editor" {
+		t.Errorf("Expected the legacy EDITOR to apply as a default, got %q", got)
+	}
+	viper.Set("editor", "configured")
+	defer viper.Set("editor", nil)
+	if got := viper.GetString("editor"); got != "configured" {
+		t.Errorf("Expected config to win over the legacy variable, got %q", got)
+	}
+}
+
+func TestConfigEnvVars(t *testing.T) {
+	t.Setenv("BERGA_SECRETS_API_TOKEN", "abc")
+	t.Setenv("BERGA_SHELL", "bash")
+
+	found := make(map[string]configEnvVar)
+	for _, v := range configEnvVars() {
+		found[v.Name] = v
+	}
+	if v := found["BERGA_SHELL"]; v.Key != "shell" || v.Value != "bash" {
+		t.Errorf("Unexpected entry for BERGA_SHELL: %+v", v)
+	}
+	if v := found["BERGA_SECRETS_API_TOKEN"]; v.Key != "secrets.api_token" {
+		t.Errorf("Expected a variable below a wildcard key to be listed, got %+v", v)
+	}
+	if _, ok := found["BERGA_SECRETS_<NAME>"]; !ok {
+		t.Error("Expected the wildcard pattern to be listed")
+	}
+	if _, ok := found["BERGA_VERBOSE"]; !ok {
+		t.Error("Expected global flag keys to be listed")
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"berga/pkg/config"
+)
+
+// backupConfigFile copies path aside before a migration rewrites it
+func backupConfigFile(path string, version int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := writeFileAtomic(backup, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to back up config file: %w", err)
+	}
+	return backup, nil
+}
+
+// migrateConfigFile brings the config file at path up to the current schema
+// version, backing it up first. With dryRun it only prints the changes.
+func migrateConfigFile(path string, dryRun bool) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("No config file to upgrade")
+		return nil
+	}
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	from, changes, err := config.Migrate(doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if from == config.SchemaVersion {
+		fmt.Printf("%s is already at config version %d\n", path, config.SchemaVersion)
+		return nil
+	}
+
+	fmt.Printf("Upgrading %s from config version %d to %d:\n", path, from, config.SchemaVersion)
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	fmt.Printf("  set version to %d\n", config.SchemaVersion)
+	if dryRun {
+		fmt.Println("(dry run)")
+		return nil
+	}
+
+	backup, err := backupConfigFile(path, from)
+	if err != nil {
+		return err
+	}
+	if err := saveConfigDocument(path, doc); err != nil {
+		return err
+	}
+	fmt.Printf("Backup saved to %s\n", backup)
+	return nil
+}
+
+// configNeedsUpgrade reports whether the config file at path has an older
+// layout that upgradeConfigOnStartup would change. It lets startup skip the
+// config lock when there is nothing to do.
+func configNeedsUpgrade(path string) bool {
+	if path == "" {
+		return false
+	}
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		// Let upgradeConfigOnStartup report it
+		return true
+	}
+	_, changes, err := config.Migrate(doc)
+	return err != nil || len(changes) > 0
+}
+
+// upgradeConfigOnStartup migrates the config file in use when an older layout
+// needs changes, and reports whether it rewrote the file. Files whose only
+// difference is the missing version key are left alone until 'config
+// migrate' runs. The caller holds the config lock.
+func upgradeConfigOnStartup(path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return false, err
+	}
+	from, changes, err := config.Migrate(doc)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	backup, err := backupConfigFile(path, from)
+	if err != nil {
+		return false, err
+	}
+	if err := saveConfigDocument(path, doc); err != nil {
+		return false, err
+	}
+	fmt.Fprintf(os.Stderr, "Upgraded %s to config version %d (backup: %s):\n", path, config.SchemaVersion, backup)
+	for _, change := range changes {
+		fmt.Fprintf(os.Stderr, "  %s\n", change)
+	}
+	return true, nil
+}
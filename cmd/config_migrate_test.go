@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeConfigOnStartup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".berga.yaml")
+	original := "author: Ada\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !configNeedsUpgrade(path) {
+		t.Error("Expected an old layout to need upgrading")
+	}
+
+	upgraded, err := upgradeConfigOnStartup(path)
+	if err != nil || !upgraded {
+		t.Fatalf("Expected the config to be upgraded, got %v, %v", upgraded, err)
+	}
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != original {
+		t.Errorf("Expected a backup of the original, got %q, %v", backup, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "version: 1") || !strings.Contains(string(data), "author: Ada") {
+		t.Errorf("Unexpected upgraded config:\n%s", data)
+	}
+
+	// A second berga that waited for the lock finds nothing left to do and
+	// keeps the first one's backup
+	if configNeedsUpgrade(path) {
+		t.Error("Expected an upgraded config not to need upgrading")
+	}
+	if upgraded, err := upgradeConfigOnStartup(path); upgraded || err != nil {
+		t.Errorf("Expected the upgrade not to run twice, got %v, %v", upgraded, err)
+	}
+	if backup, _ := os.ReadFile(path + ".v0.bak"); string(backup) != original {
+		t.Errorf("Expected the backup to be kept, got %q", backup)
+	}
+
+	// Only the version is missing: leave the file for 'config migrate'
+	current := filepath.Join(dir, "current.yaml")
+	os.WriteFile(current, []byte("editor: vim\n"), 0644)
+	if upgraded, err := upgradeConfigOnStartup(current); upgraded || err != nil {
+		t.Errorf("Expected a current layout to be left alone, got %v, %v", upgraded, err)
+	}
+	if configNeedsUpgrade(current) || configNeedsUpgrade("") {
+		t.Error("Expected startup to skip the config lock when nothing needs upgrading")
+	}
+}
+
+func TestMigrateConfigFileDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("scripts_dir: ~/s\n"), 0644)
+
+	if err := migrateConfigFile(path, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "scripts_dir: ~/s\n" {
+		t.Errorf("Expected --dry-run to leave the file alone, got %q", data)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); !os.IsNotExist(err) {
+		t.Error("Expected no backup from a dry run")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configInitInteractive bool
+
+// wizardSkipped are commands that never start the first-run wizard: ones
+// that set up berga themselves, and ones run from scripts or shell startup
+var wizardSkipped = map[string]bool{
+	"config": true, "profile": true, "import": true, "help": true, "completion": true,
+	"shell-init": true, "cd": true, "serve": true, "listen": true,
+	cobra.ShellCompRequestCmd: true, cobra.ShellCompNoDescRequestCmd: true,
+}
+
+// needsFirstRun reports whether cmd should start the setup wizard: berga
+// has no config yet and someone is at the terminal to answer
+func needsFirstRun(cmd *cobra.Command) bool {
+	if cfgFile != "" || viper.ConfigFileUsed() != "" || promptsDisabled() || !ui.IsTerminal(os.Stdout) {
+		return false
+	}
+	if name, _ := activeProfile(); name != defaultProfile {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(GetConfigDir(), "config.yaml")); err == nil {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if wizardSkipped[c.Name()] {
+			return false
+		}
+	}
+	return true
+}
+
+// gitConfigValue returns a value from the user's git config, or ""
+func gitConfigValue(key string) string {
+	out, err := exec.Command("git", "config", "--global", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// configSetting is one answer the wizard writes to the config file
+type configSetting struct {
+	Key, Value string
+}
+
+// runSetupWizard asks for the basic settings, creates berga's directories
+// and config file with them, and offers to set up the shell. Without
+// confirmed set, it first asks whether to set up berga at all.
+func runSetupWizard(confirmed bool) error {
+	if !confirmed {
+		fmt.Fprintln(promptOut, "berga has no configuration yet.")
+		if !confirm(promptOut, "Set up berga now?", true) {
+			fmt.Fprintln(promptOut, ui.Dim("Skipped. Run 'berga config init --interactive' to set it up later."))
+			return nil
+		}
+	}
+
+	questions := []struct{ label, key, def string }{
+		{"Scripts directory", "paths.scripts", GetScriptsDir()},
+		{"Templates directory", "paths.templates", GetTemplatesDir()},
+		{"Editor", "editor", preferredEditor()},
+		{"Author for templates", "templates.author", gitConfigValue("user.name")},
+		{"Email for templates", "templates.email", gitConfigValue("user.email")},
+	}
+	var settings []configSetting
+	for _, q := range questions {
+		value, err := promptLine(q.label, q.def, false)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimSpace(value)
+		// Directories are only written to the config when moved elsewhere
+		if strings.HasPrefix(q.key, "paths.") && value == q.def {
+			continue
+		}
+		settings = append(settings, configSetting{q.key, value})
+	}
+	audit := confirm(promptOut, "Keep an audit log of script runs and changes?", true)
+	settings = append(settings, configSetting{"audit.enabled", fmt.Sprint(audit)})
+
+	// The directories are created where the answers point
+	for _, s := range settings {
+		if strings.HasPrefix(s.Key, "paths.") {
+			viper.Set(s.Key, s.Value)
+		}
+	}
+	if err := initializeBergaConfig(); err != nil {
+		return err
+	}
+	if err := writeConfigSettings(filepath.Join(GetConfigDir(), "config.yaml"), settings); err != nil {
+		return err
+	}
+
+	shell, err := initShell(nil)
+	if err != nil {
+		return nil
+	}
+	if confirm(promptOut, fmt.Sprintf("Add completion and the bcd/alias integration to your %s config?", shell), false) {
+		if err := installShellCompletion(shell); err != nil {
+			return err
+		}
+		return installShellInit(shell)
+	}
+	return nil
+}
+
+// writeConfigSettings validates settings and sets them in the config file,
+// keeping its comments
+func writeConfigSettings(path string, settings []configSetting) error {
+	doc, err := loadConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		value, tag, err := config.Validate(s.Key, s.Value)
+		if err != nil {
+			return err
+		}
+		if err := config.Set(doc.Content[0], s.Key, value, tag); err != nil {
+			return err
+		}
+	}
+	return saveConfigDocument(path, doc)
+}
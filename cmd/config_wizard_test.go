@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestRunSetupWizard(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	promptOut = io.Discard
+	defer viper.Set("paths.scripts", nil)
+
+	scriptsDir := filepath.Join(home, "my-scripts")
+	answers := []string{"y", scriptsDir, "", "vim", "Ada", "ada@example.com", "n", "y"}
+	stdinReader = bufio.NewReader(strings.NewReader(strings.Join(answers, "\n") + "\n"))
+
+	if err := runSetupWizard(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(scriptsDir, "hello.sh")); err != nil {
+		t.Error("Expected the example script in the chosen scripts directory")
+	}
+
+	data, err := os.ReadFile(filepath.Join(GetConfigDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(data)
+	for _, want := range []string{"editor: vim", "author: Ada", "email: ada@example.com", "enabled: false", "scripts: " + scriptsDir} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected %q in the config:\n%s", want, config)
+		}
+	}
+	if strings.Contains(config, "templates: ") {
+		t.Error("Expected an unchanged templates directory to be left out of the config")
+	}
+
+	rc, err := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if err != nil || !strings.Contains(string(rc), "berga completion bash") || !strings.Contains(string(rc), "berga shell-init bash") {
+		t.Errorf("Expected completion and the integration in .bashrc, got %q, %v", rc, err)
+	}
+}
+
+func TestRunSetupWizardDeclined(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	promptOut = io.Discard
+	stdinReader = bufio.NewReader(strings.NewReader("n\n"))
+
+	if err := runSetupWizard(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(GetConfigDir()); err == nil {
+		t.Error("Expected nothing to be created when setup is declined")
+	}
+}
+
+func TestNeedsFirstRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	if needsFirstRun(scriptListCmd) {
+		t.Error("Expected no wizard without a terminal")
+	}
+	setTerminal(t, true)
+	if needsFirstRun(configInitCmd) || needsFirstRun(shellInitCmd) {
+		t.Error("Expected config and shell-init commands to never start the wizard")
+	}
+}
@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// scriptCopyCmd duplicates a script
+var scriptCopyCmd = &cobra.Command{
+	Use:     "copy [script] [new-name]",
+	Aliases: []string{"cp"},
+	Short:   "Copy a script under a new name",
+	Long: `Copy a script to a new name in the same directory. The copy keeps the
+original's permissions, so an executable script stays executable, and its test
+spec is copied along with it. An encrypted script stays encrypted.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return copyItem("script", args[0], args[1])
+	},
+}
+
+// scriptRenameCmd renames a script
+var scriptRenameCmd = &cobra.Command{
+	Use:     "rename [script] [new-name]",
+	Aliases: []string{"mv"},
+	Short:   "Rename a script",
+	Long: `Rename a script in place. Its test spec, version history, tags, usage,
+pin, trust, and protection move with it, and aliases and groups in your config file
+that name it are updated to the new name.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return renameItem("script", args[0], args[1])
+	},
+}
+
+// templateCopyCmd duplicates a template
+var templateCopyCmd = &cobra.Command{
+	Use:     "copy [template] [new-name]",
+	Aliases: []string{"cp"},
+	Short:   "Copy a template under a new name",
+	Long: `Copy a template to a new name in the same directory, along with its
+variable schema. The .tmpl extension is added when the original has one.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return copyItem("template", args[0], args[1])
+	},
+}
+
+// templateRenameCmd renames a template
+var templateRenameCmd = &cobra.Command{
+	Use:     "rename [template] [new-name]",
+	Aliases: []string{"mv"},
+	Short:   "Rename a template",
+	Long: `Rename a template in place. Its variable schema, version history, tags,
+and usage move with it, and aliases in your config file that name it are
+updated to the new name.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return renameItem("template", args[0], args[1])
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptCopyCmd, scriptRenameCmd)
+	templateCmd.AddCommand(templateCopyCmd, templateRenameCmd)
+
+	for _, c := range []*cobra.Command{scriptCopyCmd, scriptRenameCmd} {
+		c.ValidArgsFunction = completeScriptNames
+	}
+	for _, c := range []*cobra.Command{templateCopyCmd, templateRenameCmd} {
+		c.ValidArgsFunction = completeTemplateNames
+	}
+}
+
+// copyTarget resolves the file to copy or rename and where it goes. The new
+// name stays in the same directory and keeps the .age or .tmpl extension of
+// the original.
+func copyTarget(kind, name, newName string) (string, string, error) {
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+		return "", "", fmt.Errorf("invalid %s name '%s'", kind, newName)
+	}
+
+	var src, ext string
+	if kind == "script" {
+		src = resolveScriptPath(name)
+		if _, err := os.Stat(src); err != nil {
+			return "", "", fmt.Errorf("script '%s' not found", name)
+		}
+		ext = encryptedExt
+	} else {
+		path, ok := findLocalTemplate(name)
+		if !ok {
+			return "", "", fmt.Errorf("template '%s' not found", name)
+		}
+		src = path
+		ext = ".tmpl"
+	}
+	if strings.HasSuffix(src, ext) && !strings.HasSuffix(newName, ext) {
+		newName += ext
+	}
+
+	dst := filepath.Join(filepath.Dir(src), newName)
+	if _, err := os.Lstat(dst); err == nil {
+		return "", "", fmt.Errorf("%s '%s' already exists", kind, newName)
+	}
+	return src, dst, nil
+}
+
+// companionPath returns the file kept next to a script or template: a
+// script's test spec or a template's variable schema
+func companionPath(kind, path string) string {
+	if kind == "script" {
+		return specPathFor(path)
+	}
+	return schemaPathFor(path)
+}
+
+// itemNames returns the names stores may key an item under: its file name and,
+// for encrypted scripts and templates, the name without the extension
+func itemNames(kind, path string) []string {
+	base := filepath.Base(path)
+	trimmed := strings.TrimSuffix(base, ".tmpl")
+	if kind == "script" {
+		trimmed = strings.TrimSuffix(base, encryptedExt)
+	}
+	if trimmed == base {
+		return []string{base}
+	}
+	return []string{trimmed, base}
+}
+
+// copyItem copies a script or template and its companion file
+func copyItem(kind, name, newName string) error {
+	src, dst, err := copyTarget(kind, name, newName)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	err = trackChange(kind, dst, revisionCreate, func() error {
+		return writeFileAtomic(dst, data, info.Mode().Perm())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", kind, err)
+	}
+
+	if companion, err := os.ReadFile(companionPath(kind, src)); err == nil {
+		if err := writeFileAtomic(companionPath(kind, dst), companion, 0644); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", filepath.Base(companionPath(kind, src)), err)
+		}
+	}
+
+	fmt.Printf("Copied %s '%s' to %s\n", kind, name, dst)
+	return nil
+}
+
+// renameItem renames a script or template and moves everything recorded under
+// its old name
+func renameItem(kind, name, newName string) error {
+	src, dst, err := copyTarget(kind, name, newName)
+	if err != nil {
+		return err
+	}
+
+	oldLog := revisionLogPath(kind, revisionKey(kind, src))
+	newLog := revisionLogPath(kind, revisionKey(kind, dst))
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", kind, err)
+	}
+	if _, err := os.Stat(companionPath(kind, src)); err == nil {
+		if err := os.Rename(companionPath(kind, src), companionPath(kind, dst)); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", filepath.Base(companionPath(kind, src)), err)
+		}
+	}
+	if _, err := os.Stat(oldLog); err == nil {
+		if err := os.Rename(oldLog, newLog); err != nil {
+			return fmt.Errorf("failed to move version history: %w", err)
+		}
+	}
+	fmt.Printf("Renamed %s '%s' to %s\n", kind, name, dst)
+
+	if err := renameRecords(kind, src, dst); err != nil {
+		return err
+	}
+
+	updated, err := renameConfigReferences(kind, itemNames(kind, src), itemNames(kind, dst))
+	if err != nil {
+		return err
+	}
+	for _, ref := range updated {
+		fmt.Printf("Updated %s\n", ref)
+	}
+	return nil
+}
+
+// renameRecords moves an item's tags, usage, and for scripts its pin, trust,
+// and protection to the new name
+func renameRecords(kind, src, dst string) error {
+	oldNames, newNames := itemNames(kind, src), itemNames(kind, dst)
+
+	err := withLock("tags", func() error {
+		index, err := loadTagIndex()
+		if err != nil {
+			return err
+		}
+		entries, err := index.itemTags(kind)
+		if err != nil {
+			return err
+		}
+		if moveKeys(entries, oldNames, newNames) {
+			return saveTagIndex(index)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = withLock("usage", func() error {
+		stats, err := loadUsage()
+		if err != nil {
+			return err
+		}
+		if moveKeys(stats.entries(kind), oldNames, newNames) {
+			return saveUsage(stats)
+		}
+		return nil
+	})
+	if err != nil || kind != "script" {
+		return err
+	}
+
+	err = withLock("pins", func() error {
+		return renamePin(filepath.Base(src), filepath.Base(dst))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Trust and protection are keyed by path outside the scripts directory
+	oldKeys := []string{scriptTrustKey(oldNames[0], src), scriptTrustKey(filepath.Base(src), src)}
+	newKeys := []string{scriptTrustKey(newNames[0], dst), scriptTrustKey(filepath.Base(dst), dst)}
+
+	trust, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+	if moveKeys(trust, oldKeys, newKeys) {
+		if err := saveTrustStore(trust); err != nil {
+			return err
+		}
+	}
+
+	protected, err := loadProtectedStore()
+	if err != nil {
+		return err
+	}
+	if moveKeys(protected, oldKeys, newKeys) {
+		return saveProtectedStore(protected)
+	}
+	return nil
+}
+
+// moveKeys moves the entry under each old key to the matching new key and
+// reports whether anything moved
+func moveKeys[V any](m map[string]V, oldKeys, newKeys []string) bool {
+	moved := false
+	for i, key := range oldKeys {
+		value, ok := m[key]
+		if !ok || i >= len(newKeys) {
+			continue
+		}
+		delete(m, key)
+		m[newKeys[i]] = value
+		moved = true
+	}
+	return moved
+}
+
+// renameConfigReferences rewrites aliases, and for scripts group members, in
+// the config file that name the item. It returns a description of each
+// reference updated.
+func renameConfigReferences(kind string, oldNames, newNames []string) ([]string, error) {
+	path := configFilePath()
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+
+	// Only aliases for the item's own command group can name it
+	parent := scriptCmd
+	if kind == "template" {
+		parent = templateCmd
+	}
+
+	var updated []string
+	err := withLock("config", func() error {
+		doc, err := loadConfigDocument(path)
+		if err != nil {
+			return err
+		}
+		mapping := doc.Content[0]
+
+		if aliases := config.Lookup(mapping, "aliases"); aliases != nil && aliases.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(aliases.Content); i += 2 {
+				value := aliases.Content[i+1]
+				if value.Kind != yaml.ScalarNode {
+					continue
+				}
+				fields := strings.Fields(value.Value)
+				if len(fields) > 0 && (fields[0] == parent.Name() || parent.HasAlias(fields[0])) && renameFields(fields[1:], oldNames, newNames) {
+					value.Value = strings.Join(fields, " ")
+					updated = append(updated, fmt.Sprintf("alias '%s'", aliases.Content[i].Value))
+				}
+			}
+		}
+
+		if groups := config.Lookup(mapping, "groups"); kind == "script" && groups != nil && groups.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(groups.Content); i += 2 {
+				if renameGroupMembers(groups.Content[i+1], oldNames, newNames) {
+					updated = append(updated, fmt.Sprintf("group '%s'", groups.Content[i].Value))
+				}
+			}
+		}
+
+		if len(updated) == 0 {
+			return nil
+		}
+		return saveConfigDocument(path, doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update config references: %w", err)
+	}
+	return updated, nil
+}
+
+// renameFields replaces every field equal to an old name with the matching
+// new name
+func renameFields(fields, oldNames, newNames []string) bool {
+	changed := false
+	for i, field := range fields {
+		for j, old := range oldNames {
+			if field == old {
+				fields[i] = newNames[min(j, len(newNames)-1)]
+				changed = true
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// renameGroupMembers renames members of a group given as a list or as one
+// comma-separated string
+func renameGroupMembers(node *yaml.Node, oldNames, newNames []string) bool {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		changed := false
+		for _, item := range node.Content {
+			members := []string{strings.TrimSpace(item.Value)}
+			if item.Kind == yaml.ScalarNode && renameFields(members, oldNames, newNames) {
+				item.Value = members[0]
+				changed = true
+			}
+		}
+		return changed
+	case yaml.ScalarNode:
+		members := strings.Split(node.Value, ",")
+		for i := range members {
+			members[i] = strings.TrimSpace(members[i])
+		}
+		if renameFields(members, oldNames, newNames) {
+			node.Value = strings.Join(members, ", ")
+			return true
+		}
+	}
+	return false
+}
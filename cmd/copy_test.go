@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyScriptKeepsModeAndSpec(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := writeTestScript(t, "deploy.sh", "#!/bin/sh\necho deploy\n")
+	os.WriteFile(specPathFor(src), []byte("cases: []\n"), 0644)
+
+	if err := copyItem("script", "deploy.sh", "deploy-staging.sh"); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(GetScriptsDir(), "deploy-staging.sh")
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		t.Errorf("Expected the copy to stay executable, got %v", info.Mode())
+	}
+	if _, err := os.Stat(specPathFor(dst)); err != nil {
+		t.Errorf("Expected the test spec to be copied: %v", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("Expected the original to remain: %v", err)
+	}
+
+	if err := copyItem("script", "deploy.sh", "deploy-staging.sh"); err == nil {
+		t.Error("Expected an error copying onto an existing script")
+	}
+	if err := copyItem("script", "deploy.sh", "../escape.sh"); err == nil {
+		t.Error("Expected an error for a name with a path")
+	}
+}
+
+func TestCopyTargetKeepsExtension(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "secret.sh"+encryptedExt, "age")
+
+	_, dst, err := copyTarget("script", "secret.sh", "other.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dst) != "other.sh"+encryptedExt {
+		t.Errorf("Expected the copy to stay encrypted, got %s", dst)
+	}
+}
+
+func TestRenameScriptUpdatesReferences(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "build.sh", "#!/bin/sh\n")
+	addTags("script", "build.sh", []string{"ci"})
+	recordUsage("script", "build.sh")
+
+	path := configFilePath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`aliases:
+  b: script run build.sh --fast
+  sb: s show build.sh
+  t: template apply build.sh
+groups:
+  ci: [lint.sh, build.sh]
+  release: "build.sh, deploy.sh"
+`), 0644)
+
+	if err := renameItem("script", "build.sh", "compile.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(GetScriptsDir(), "compile.sh")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(GetScriptsDir(), "build.sh")); !os.IsNotExist(err) {
+		t.Error("Expected the old script to be gone")
+	}
+
+	index, _ := loadTagIndex()
+	if _, ok := index.Scripts["build.sh"]; ok || len(index.Scripts["compile.sh"]) != 1 {
+		t.Errorf("Expected tags to move to the new name, got %v", index.Scripts)
+	}
+	stats, _ := loadUsage()
+	if stats.Scripts["compile.sh"].Count != 1 {
+		t.Errorf("Expected usage to move to the new name, got %v", stats.Scripts)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"b: script run compile.sh --fast", "[lint.sh, compile.sh]", "sb: s show compile.sh", `release: "compile.sh, deploy.sh"`, "t: template apply build.sh"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the config, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestRenameTemplateMovesSchema(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetTemplatesDir(), 0755)
+	src := filepath.Join(GetTemplatesDir(), "readme.tmpl")
+	os.WriteFile(src, []byte("# {{.name}}\n"), 0644)
+	os.WriteFile(schemaPathFor(src), []byte("name: {}\n"), 0644)
+	recordUsage("template", "readme.tmpl")
+
+	if err := renameItem("template", "readme", "intro"); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(GetTemplatesDir(), "intro.tmpl")
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatalf("Expected the .tmpl extension to be kept: %v", err)
+	}
+	if _, err := os.Stat(schemaPathFor(dst)); err != nil {
+		t.Errorf("Expected the schema to move: %v", err)
+	}
+	stats, _ := loadUsage()
+	if stats.Templates["intro"].Count != 1 {
+		t.Errorf("Expected usage to move to the new name, got %v", stats.Templates)
+	}
+}
@@ -0,0 +1,665 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"berga/internal/ui"
+	"berga/pkg/store"
+
+	"github.com/spf13/cobra"
+)
+
+// dashboardHistoryLimit is how many recent runs the history pane lists
+const dashboardHistoryLimit = 200
+
+// dashboardItem is one file or recorded run listed in a dashboard pane
+type dashboardItem struct {
+	Name string
+	Path string
+	// RunID is the recorded run a history item shows
+	RunID int
+}
+
+// dashboardPane lists the files of one kind
+type dashboardPane struct {
+	Title string
+	Kind  string
+	Items []dashboardItem
+}
+
+// dashboardAction is what the event loop should do after a key press
+type dashboardAction int
+
+const (
+	actionNone dashboardAction = iota
+	actionQuit
+	actionRun
+	actionStop
+)
+
+// dashboard holds the state of the interactive UI. It is kept free of I/O so
+// key handling and rendering can be tested directly.
+type dashboard struct {
+	panes     []dashboardPane
+	pane      int
+	cursor    int
+	query     string
+	searching bool
+	output    []string
+	running   string
+	status    string
+}
+
+// uiCmd opens the interactive dashboard
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and run scripts in an interactive terminal UI",
+	Long: `Open a full-screen dashboard listing scripts, templates, snippets, notes, and
+the run history. Running berga with no arguments in a terminal opens it too.
+
+  ←/→, tab    switch pane          /      fuzzy search
+  ↑/↓, j/k    move                 esc    clear search or output
+  enter       run the selected script, showing its output live
+  q, ctrl+c   quit (ctrl+c stops a running script first)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runDashboard()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+// newDashboard loads the files shown in each pane, and the recent runs
+func newDashboard() *dashboard {
+	d := &dashboard{}
+	refreshListings(indexedSourceDirs())
+	for _, src := range searchSources() {
+		pane := dashboardPane{Title: strings.ToUpper(src.Type[:1]) + src.Type[1:] + "s", Kind: src.Type}
+		// Later directories shadow earlier ones, so go from the last search
+		// path to the first
+		var dirs []string
+		for i := len(src.Dirs) - 1; i >= 0; i-- {
+			dirs = append(dirs, src.Dirs[i])
+		}
+		if src.Type == "script" && GetProjectScriptsDir() != "" {
+			dirs = append(dirs, GetProjectScriptsDir())
+		}
+		seen := make(map[string]int)
+		for _, dir := range dirs {
+			files, err := readListing(dir)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				if file.IsDir() || isSchemaFile(file.Name()) || src.Type == "script" && isScriptSpecFile(file.Name()) {
+					continue
+				}
+				item := dashboardItem{Name: file.Name(), Path: filepath.Join(dir, file.Name())}
+				// Project scripts and earlier search paths shadow the rest
+				if i, ok := seen[item.Name]; ok {
+					pane.Items[i] = item
+					continue
+				}
+				seen[item.Name] = len(pane.Items)
+				pane.Items = append(pane.Items, item)
+			}
+		}
+		d.panes = append(d.panes, pane)
+	}
+	d.panes = append(d.panes, historyPane())
+	return d
+}
+
+// historyPane lists the most recent recorded runs, newest first
+func historyPane() dashboardPane {
+	pane := dashboardPane{Title: "History", Kind: "history"}
+	entries, _ := queryHistory(store.RunFilter{Limit: dashboardHistoryLimit})
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		pane.Items = append(pane.Items, dashboardItem{Name: fmt.Sprintf("%d %s", entry.ID, entry.command()), RunID: entry.ID})
+	}
+	return pane
+}
+
+// reloadHistory lists the runs recorded since the dashboard opened
+func (d *dashboard) reloadHistory() {
+	for i, pane := range d.panes {
+		if pane.Kind == "history" {
+			d.panes[i] = historyPane()
+		}
+	}
+}
+
+// fuzzyScore matches query as a case-insensitive subsequence of s. Matches
+// at word starts and runs of consecutive characters score higher.
+func fuzzyScore(query, s string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	score, qi := 0, 0
+	prevMatch := false
+	var prev rune
+	for i, r := range strings.ToLower(s) {
+		if qi < len(q) && r == q[qi] {
+			score++
+			if prevMatch {
+				score += 3
+			}
+			if i == 0 || !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 2
+			}
+			qi++
+			prevMatch = true
+		} else {
+			prevMatch = false
+		}
+		prev = r
+	}
+	return score, qi == len(q)
+}
+
+// visible returns the current pane's items matching the search query, best
+// matches first
+func (d *dashboard) visible() []dashboardItem {
+	if len(d.panes) == 0 {
+		return nil
+	}
+	type scored struct {
+		item  dashboardItem
+		score int
+	}
+	var matches []scored
+	for _, item := range d.panes[d.pane].Items {
+		if score, ok := fuzzyScore(d.query, item.Name); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	items := make([]dashboardItem, len(matches))
+	for i, m := range matches {
+		items[i] = m.item
+	}
+	return items
+}
+
+// selected returns the item under the cursor
+func (d *dashboard) selected() (dashboardItem, bool) {
+	items := d.visible()
+	if d.cursor < 0 || d.cursor >= len(items) {
+		return dashboardItem{}, false
+	}
+	return items[d.cursor], true
+}
+
+// clearOutput drops the output of a finished run so the preview shows again
+func (d *dashboard) clearOutput() {
+	if d.running == "" {
+		d.output = nil
+		d.status = ""
+	}
+}
+
+func (d *dashboard) switchPane(delta int) {
+	d.pane = (d.pane + delta + len(d.panes)) % len(d.panes)
+	d.cursor = 0
+	d.clearOutput()
+}
+
+func (d *dashboard) moveCursor(delta int) {
+	n := len(d.visible())
+	d.cursor += delta
+	if d.cursor >= n {
+		d.cursor = n - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+	d.clearOutput()
+}
+
+// handleKey updates the dashboard for one key press
+func (d *dashboard) handleKey(key string) dashboardAction {
+	if d.running != "" {
+		if key == "ctrl+c" || key == "esc" {
+			return actionStop
+		}
+		return actionNone
+	}
+
+	if d.searching {
+		switch key {
+		case "ctrl+c":
+			return actionQuit
+		case "enter":
+			d.searching = false
+		case "esc":
+			d.searching = false
+			d.query = ""
+		case "backspace":
+			if d.query != "" {
+				_, size := utf8.DecodeLastRuneInString(d.query)
+				d.query = d.query[:len(d.query)-size]
+			}
+		case "up", "down", "left", "right", "tab", "backtab":
+			d.searching = false
+			return d.handleKey(key)
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				d.query += key
+			}
+		}
+		d.cursor = 0
+		d.clearOutput()
+		return actionNone
+	}
+
+	switch key {
+	case "q", "ctrl+c":
+		return actionQuit
+	case "/":
+		d.searching = true
+	case "esc":
+		d.query = ""
+		d.cursor = 0
+		d.clearOutput()
+	case "tab", "right", "l":
+		d.switchPane(1)
+	case "backtab", "left", "h":
+		d.switchPane(-1)
+	case "down", "j":
+		d.moveCursor(1)
+	case "up", "k":
+		d.moveCursor(-1)
+	case "enter":
+		if _, ok := d.selected(); ok && d.panes[d.pane].Kind == "script" {
+			return actionRun
+		}
+	}
+	return actionNone
+}
+
+// decodeKeys turns raw terminal input into key names. Printable characters
+// are returned as themselves. The prompt line editor shares it.
+func decodeKeys(b []byte) []string {
+	sequences := map[string]string{
+		"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+		"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+		"\x1b[Z": "backtab",
+		"\x1b[H": "home", "\x1b[F": "end", "\x1bOH": "home", "\x1bOF": "end",
+	}
+	controls := map[byte]string{
+		0x01: "ctrl+a", 0x04: "ctrl+d", 0x05: "ctrl+e", 0x0b: "ctrl+k",
+		0x0e: "ctrl+n", 0x10: "ctrl+p", 0x15: "ctrl+u", 0x17: "ctrl+w",
+	}
+
+	var keys []string
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) >= 4 && string(b[:4]) == "\x1b[3~" {
+				keys = append(keys, "delete")
+				b = b[4:]
+				continue
+			}
+			if len(b) >= 3 {
+				if key, ok := sequences[string(b[:3])]; ok {
+					keys = append(keys, key)
+					b = b[3:]
+					continue
+				}
+			}
+			keys = append(keys, "esc")
+			b = b[1:]
+			continue
+		}
+		switch b[0] {
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		case '\t':
+			keys = append(keys, "tab")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		default:
+			if key, ok := controls[b[0]]; ok {
+				keys = append(keys, key)
+				break
+			}
+			r, size := utf8.DecodeRune(b)
+			if unicode.IsPrint(r) {
+				keys = append(keys, string(r))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// ansiSequence matches terminal escape sequences in script output
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// fitWidth strips control characters and pads or truncates s to width columns
+func fitWidth(s string, width int) string {
+	s = ansiSequence.ReplaceAllString(strings.ReplaceAll(s, "\t", "    "), "")
+	runes := make([]rune, 0, width)
+	for _, r := range s {
+		if len(runes) == width {
+			break
+		}
+		if unicode.IsPrint(r) {
+			runes = append(runes, r)
+		}
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// previewItem returns the first lines shown for the selected item: the
+// details and output of a recorded run, or the start of a file
+func previewItem(item dashboardItem, max int) []string {
+	if item.RunID == 0 {
+		return previewLines(item.Path, max)
+	}
+	entry, err := findHistoryEntry(strconv.Itoa(item.RunID))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var buf bytes.Buffer
+	showHistoryEntry(&buf, entry)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) > max {
+		lines = lines[:max]
+	}
+	return lines
+}
+
+// previewLines returns the first lines of a file for the preview pane
+func previewLines(path string, max int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for len(lines) < max && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// render draws the whole screen for a terminal of the given size
+func (d *dashboard) render(width, height int) string {
+	if width < 40 {
+		width = 40
+	}
+	if height < 6 {
+		height = 6
+	}
+	bodyHeight := height - 3
+	listWidth := width / 3
+	if listWidth > 32 {
+		listWidth = 32
+	}
+	rightWidth := width - listWidth - 3
+
+	var lines []string
+
+	// Pane tabs
+	var tabs []string
+	for i, pane := range d.panes {
+		tab := fmt.Sprintf(" %s (%d) ", pane.Title, len(pane.Items))
+		if i == d.pane {
+			tab = ui.Reverse(ui.Bold(tab))
+		}
+		tabs = append(tabs, tab)
+	}
+	lines = append(lines, strings.Join(tabs, " "))
+
+	// Search line
+	switch {
+	case d.searching:
+		lines = append(lines, "/"+d.query+"▏")
+	case d.query != "":
+		lines = append(lines, ui.Dim("/"+d.query))
+	default:
+		lines = append(lines, "")
+	}
+
+	// Item list on the left
+	items := d.visible()
+	offset := 0
+	if d.cursor >= bodyHeight {
+		offset = d.cursor - bodyHeight + 1
+	}
+	left := make([]string, bodyHeight)
+	for i := range left {
+		n := offset + i
+		switch {
+		case n < len(items) && n == d.cursor:
+			left[i] = ui.Reverse(fitWidth("> "+items[n].Name, listWidth))
+		case n < len(items):
+			left[i] = fitWidth("  "+items[n].Name, listWidth)
+		case n == 0:
+			left[i] = ui.Dim(fitWidth("  (nothing here)", listWidth))
+		default:
+			left[i] = strings.Repeat(" ", listWidth)
+		}
+	}
+
+	// Output of the last run, or a preview of the selected item, on the right
+	var right []string
+	if d.output != nil {
+		right = d.output
+		if len(right) > bodyHeight {
+			right = right[len(right)-bodyHeight:]
+		}
+	} else if item, ok := d.selected(); ok {
+		right = previewItem(item, bodyHeight)
+	}
+	for i := 0; i < bodyHeight; i++ {
+		text := ""
+		if i < len(right) {
+			text = right[i]
+		}
+		lines = append(lines, left[i]+" "+ui.Dim("│")+" "+strings.TrimRight(fitWidth(text, rightWidth), " "))
+	}
+
+	// Status or key help
+	footer := d.status
+	switch {
+	case d.running != "":
+		footer = fmt.Sprintf("Running %s... (ctrl+c to stop)", d.running)
+	case footer == "":
+		footer = "←/→ pane  ↑/↓ move  / search  enter run  esc clear  q quit"
+	}
+	lines = append(lines, ui.Dim(fitWidth(footer, width)))
+
+	return strings.Join(lines, "\r\n")
+}
+
+// dashboardRunAllowed applies the checks a script passes before the dashboard
+// runs it. The dashboard can't ask for confirmation, so scripts that need it,
+// through their danger level or a policy rule, are left to 'script run'.
+func dashboardRunAllowed(name, scriptPath string) error {
+	if level := scriptDangerLevel(name, scriptPath); level != dangerLow {
+		return fmt.Errorf("marked danger: %s; run it with 'berga script run' to confirm", level)
+	}
+	rule, ctx, err := checkScriptPolicy(name)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		return fmt.Errorf("script %s; run it with 'berga script run' to confirm", rule.requirement(ctx))
+	}
+	return requireKubeContext(name, scriptPath)
+}
+
+// startRun runs the selected script, sending its output lines to lines and
+// its result to done, and records it in the history. It returns a function
+// that stops the script.
+func (d *dashboard) startRun(lines chan<- string, done chan<- error) context.CancelFunc {
+	item, _ := d.selected()
+	d.running = item.Name
+	d.output = []string{}
+	d.status = ""
+
+	ctx, cancel := runContext(context.Background(), scriptRunTimeout())
+	go func() {
+		storedPath, err := locateScript(item.Name)
+		if err != nil {
+			done <- err
+			return
+		}
+		scriptPath, cleanup, err := plainScriptPath(storedPath)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer cleanup()
+		if err := dashboardRunAllowed(item.Name, scriptPath); err != nil {
+			done <- err
+			return
+		}
+		recordUsage("script", item.Name)
+
+		cmd := scriptCommand(ctx, scriptPath, nil)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			done <- err
+			return
+		}
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			done <- err
+			return
+		}
+		started := time.Now()
+		if err := cmd.Start(); err != nil {
+			done <- fmt.Errorf("failed to start script: %w", err)
+			return
+		}
+
+		output := newHistoryOutput()
+		send := func(line string) {
+			if output != nil {
+				output.Write([]byte(line + "\n"))
+			}
+			lines <- line
+		}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		for _, rd := range []io.Reader{stdout, stderr} {
+			go func(rd io.Reader) {
+				defer wg.Done()
+				streamLines(rd, send)
+			}(rd)
+		}
+		wg.Wait()
+		err = cmd.Wait()
+		elapsed := time.Since(started)
+		recordTimedAudit("script run", item.Name, map[string]string{"via": "ui"}, err, elapsed)
+		recordRunOutput(item.Name, nil, err, elapsed, output)
+		done <- err
+	}()
+	return cancel
+}
+
+// finishRun records the result of a script run
+func (d *dashboard) finishRun(err error) {
+	switch {
+	case err == nil:
+		d.status = fmt.Sprintf("%s finished successfully", d.running)
+	default:
+		d.status = fmt.Sprintf("%s failed: %v", d.running, err)
+	}
+	d.running = ""
+}
+
+func runDashboard() error {
+	if !ui.IsTerminal(os.Stdin) || !ui.IsTerminal(os.Stdout) {
+		return fmt.Errorf("berga ui needs an interactive terminal")
+	}
+
+	d := newDashboard()
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Use the alternate screen and hide the cursor while the UI is open
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	keys := make(chan []string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- decodeKeys(buf[:n])
+		}
+	}()
+
+	lines := make(chan string, 256)
+	done := make(chan error, 1)
+	stop := context.CancelFunc(func() {})
+	defer func() { stop() }()
+
+	for {
+		width, height := terminalSize(os.Stdin)
+		fmt.Print("\033[H\033[2J" + d.render(width, height))
+
+		select {
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range batch {
+				switch d.handleKey(key) {
+				case actionQuit:
+					return nil
+				case actionRun:
+					stop = d.startRun(lines, done)
+				case actionStop:
+					stop()
+				}
+			}
+		case line := <-lines:
+			d.output = append(d.output, line)
+		case err := <-done:
+			// Every line was sent before the result, so collect any left over
+			for len(lines) > 0 {
+				d.output = append(d.output, <-lines)
+			}
+			d.finishRun(err)
+			d.reloadHistory()
+			stop()
+		}
+	}
+}
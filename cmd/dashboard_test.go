@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("dpy", "deploy.sh"); !ok {
+		t.Error("Expected subsequence to match")
+	}
+	if _, ok := fuzzyScore("xyz", "deploy.sh"); ok {
+		t.Error("Expected non-subsequence not to match")
+	}
+	if _, ok := fuzzyScore("DEP", "deploy.sh"); !ok {
+		t.Error("Expected matching to ignore case")
+	}
+
+	prefix, _ := fuzzyScore("dep", "deploy.sh")
+	scattered, _ := fuzzyScore("dep", "docker-compose-up.sh")
+	if prefix <= scattered {
+		t.Errorf("Expected consecutive match to score higher (%d vs %d)", prefix, scattered)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("\x1b[A\x1b[Bj\r\x7f\x1b\x03é\t\x1b[Z\x1b[3~\x1b[H\x01\x17"))
+	want := []string{"up", "down", "j", "enter", "backspace", "esc", "ctrl+c", "é", "tab", "backtab", "delete", "home", "ctrl+a", "ctrl+w"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func testDashboard() *dashboard {
+	return &dashboard{panes: []dashboardPane{
+		{Title: "Scripts", Kind: "script", Items: []dashboardItem{
+			{Name: "backup.sh"}, {Name: "deploy.sh"}, {Name: "docker-clean.sh"},
+		}},
+		{Title: "Templates", Kind: "template", Items: []dashboardItem{{Name: "gitignore.tmpl"}}},
+	}}
+}
+
+func TestDashboardNavigation(t *testing.T) {
+	d := testDashboard()
+
+	d.handleKey("down")
+	d.handleKey("down")
+	d.handleKey("down")
+	if item, _ := d.selected(); item.Name != "docker-clean.sh" {
+		t.Errorf("Expected cursor to stop at the last item, got %s", item.Name)
+	}
+
+	d.handleKey("tab")
+	if d.pane != 1 || d.cursor != 0 {
+		t.Errorf("Expected tab to switch pane and reset cursor, got pane %d cursor %d", d.pane, d.cursor)
+	}
+	if d.handleKey("enter") != actionNone {
+		t.Error("Expected enter on a template not to run anything")
+	}
+
+	d.handleKey("left")
+	if d.handleKey("enter") != actionRun {
+		t.Error("Expected enter on a script to run it")
+	}
+	if d.handleKey("q") != actionQuit {
+		t.Error("Expected q to quit")
+	}
+}
+
+func TestDashboardSearch(t *testing.T) {
+	d := testDashboard()
+
+	d.handleKey("/")
+	for _, key := range []string{"d", "c"} {
+		d.handleKey(key)
+	}
+	if d.handleKey("q") != actionNone {
+		t.Error("Expected q to be typed into the search, not quit")
+	}
+	d.handleKey("backspace")
+
+	items := d.visible()
+	if len(items) != 1 || items[0].Name != "docker-clean.sh" {
+		t.Errorf("Expected only docker-clean.sh to match, got %v", items)
+	}
+
+	d.handleKey("esc")
+	if d.query != "" || len(d.visible()) != 3 {
+		t.Errorf("Expected esc to clear the search, got %q", d.query)
+	}
+}
+
+func TestDashboardRunningKeys(t *testing.T) {
+	d := testDashboard()
+	d.running = "deploy.sh"
+	d.output = []string{"working"}
+
+	if d.handleKey("q") != actionNone {
+		t.Error("Expected keys to be ignored while a script runs")
+	}
+	if d.handleKey("ctrl+c") != actionStop {
+		t.Error("Expected ctrl+c to stop the running script")
+	}
+
+	d.finishRun(nil)
+	if d.running != "" || !strings.Contains(d.status, "deploy.sh") {
+		t.Errorf("Unexpected state after run: running %q status %q", d.running, d.status)
+	}
+	d.handleKey("down")
+	if d.output != nil {
+		t.Error("Expected moving the cursor to clear finished output")
+	}
+}
+
+func TestDashboardRender(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho deploying\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := testDashboard()
+	d.panes[0].Items[0].Path = path
+
+	screen := d.render(80, 10)
+	lines := strings.Split(screen, "\r\n")
+	if len(lines) != 10 {
+		t.Errorf("Expected 10 lines, got %d", len(lines))
+	}
+	for _, want := range []string{"Scripts (3)", "Templates (1)", "backup.sh", "echo deploying"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Expected screen to contain %q:\n%s", want, screen)
+		}
+	}
+
+	d.output = []string{"\x1b[32mdone\x1b[0m"}
+	if screen := d.render(80, 10); !strings.Contains(screen, "done") || strings.Contains(screen, "echo deploying") {
+		t.Errorf("Expected run output instead of the preview:\n%s", screen)
+	}
+}
+
+func TestFitWidth(t *testing.T) {
+	if got := fitWidth("ab\tc", 8); got != "ab    c " {
+		t.Errorf("Unexpected padding %q", got)
+	}
+	if got := fitWidth("\x1b[1mlong text\x1b[0m", 4); got != "long" {
+		t.Errorf("Unexpected truncation %q", got)
+	}
+}
+
+func TestDashboardRunAllowedPolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\n")
+
+	if err := dashboardRunAllowed("deploy.sh", path); err != nil {
+		t.Fatalf("Expected the script to be allowed without a policy, got %v", err)
+	}
+	writeTestPolicy(t, "rules:\n  - scripts: [deploy.sh]\n    action: deny\n    message: use the pipeline\n")
+	if err := dashboardRunAllowed("deploy.sh", path); err == nil || !strings.Contains(err.Error(), "use the pipeline") {
+		t.Errorf("Expected a deny rule to stop the run, got %v", err)
+	}
+	writeTestPolicy(t, "rules:\n  - scripts: [deploy.sh]\n    action: confirm\n")
+	if err := dashboardRunAllowed("deploy.sh", path); err == nil || !strings.Contains(err.Error(), "requires confirmation") {
+		t.Errorf("Expected a confirm rule to be left to 'script run', got %v", err)
+	}
+}
+
+func TestDashboardHistoryPane(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("history.output", true)
+	defer viper.Set("history.output", nil)
+
+	recordRun("backup.sh", nil, nil, time.Second)
+	startHistoryRecording()
+	activeHistoryOutput.Write([]byte("deployed to prod\n"))
+	recordRun("deploy.sh", []string{"prod"}, &ExitError{Code: 2}, time.Second)
+
+	pane := historyPane()
+	if len(pane.Items) != 2 || pane.Items[0].Name != "2 deploy.sh prod" || pane.Items[1].RunID != 1 {
+		t.Fatalf("Expected the newest run first, got %+v", pane.Items)
+	}
+	d := &dashboard{panes: []dashboardPane{pane}}
+	if d.handleKey("enter") != actionNone {
+		t.Error("Expected enter on a recorded run not to run anything")
+	}
+	screen := d.render(80, 12)
+	for _, want := range []string{"History (2)", "exit 2", "deployed to prod"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Expected screen to contain %q:\n%s", want, screen)
+		}
+	}
+}
+
+func TestDashboardRunLongLines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	writeTestScript(t, "long.sh", "#!/bin/sh\nhead -c 100000 /dev/zero | tr '\\0' x\necho\necho done\n")
+	d := &dashboard{panes: []dashboardPane{{Title: "Scripts", Kind: "script", Items: []dashboardItem{{Name: "long.sh"}}}}}
+
+	lines := make(chan string, 256)
+	done := make(chan error, 1)
+	stop := d.startRun(lines, done)
+	defer stop()
+	var got []string
+	var err error
+	for running := true; running; {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case err = <-done:
+			running = false
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(lines) > 0 {
+		got = append(got, <-lines)
+	}
+	if len(got) != 2 || len(got[0]) != 100000 || got[1] != "done" {
+		t.Errorf("Expected the long line whole and the line after it, got %d lines", len(got))
+	}
+
+	d.finishRun(nil)
+	d.reloadHistory()
+	entries, err := loadHistory()
+	if err != nil || len(entries) != 1 || entries[0].Script != "long.sh" {
+		t.Errorf("Expected the run to be recorded, got %+v, %v", entries, err)
+	}
+}
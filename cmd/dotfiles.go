@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	dotfilesCopy  bool
+	dotfilesForce bool
+)
+
+// dotfilesCmd represents the dotfiles command
+var dotfilesCmd = &cobra.Command{
+	Use:   "dotfiles",
+	Short: "Manage dotfiles",
+	Long: `Track home-directory files under ~/.berga/dotfiles and link or copy them into place.
+
+Per-OS and per-host overrides are stored next to the base file using a
+"##" suffix, e.g. ".gitconfig##os.darwin" or ".bashrc##host.laptop".
+A host override wins over an OS override, which wins over the base file.`,
+	Aliases: []string{"dot"},
+}
+
+// dotfilesAddCmd starts tracking a file
+var dotfilesAddCmd = &cobra.Command{
+	Use:   "add [path...]",
+	Short: "Start tracking dotfiles",
+	Long:  `Move files from your home directory into ~/.berga/dotfiles and link them back into place.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("dotfiles", func() error {
+			for _, path := range args {
+				if err := addDotfile(path); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+}
+
+// dotfilesLinkCmd links tracked files into place
+var dotfilesLinkCmd = &cobra.Command{
+	Use:   "link",
+	Short: "Link tracked dotfiles into your home directory",
+	Long:  `Create symlinks (or copies with --copy) in your home directory for every tracked dotfile. Existing files are backed up with a .berga-backup suffix.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("dotfiles", linkDotfiles)
+	},
+}
+
+// dotfilesStatusCmd shows the state of tracked files
+var dotfilesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of tracked dotfiles",
+	Long:  `Show whether each tracked dotfile is linked, copied, modified, missing, or in conflict.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dotfilesStatus()
+	},
+}
+
+// dotfilesRestoreCmd replaces links with regular files
+var dotfilesRestoreCmd = &cobra.Command{
+	Use:   "restore [path...]",
+	Short: "Restore dotfiles as regular files",
+	Long:  `Replace links in your home directory with regular copies of the tracked files. With no arguments, every tracked file is restored.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("dotfiles", func() error {
+			return restoreDotfiles(args)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dotfilesCmd)
+	dotfilesCmd.AddCommand(dotfilesAddCmd)
+	dotfilesCmd.AddCommand(dotfilesLinkCmd)
+	dotfilesCmd.AddCommand(dotfilesStatusCmd)
+	dotfilesCmd.AddCommand(dotfilesRestoreCmd)
+
+	// Flags
+	dotfilesCmd.PersistentFlags().BoolVar(&dotfilesCopy, "copy", false, "Copy files instead of creating symlinks")
+	dotfilesLinkCmd.Flags().BoolVarP(&dotfilesForce, "force", "f", false, "Replace existing files without keeping a backup")
+}
+
+// dotfile is a tracked file and the variant selected for this machine
+type dotfile struct {
+	RelPath string // path relative to the home directory
+	Source  string // selected file inside the dotfiles directory
+}
+
+// useCopies reports whether dotfiles should be copied rather than symlinked
+func useCopies() bool {
+	if dotfilesCopy || viper.GetString("dotfiles.mode") == "copy" {
+		return true
+	}
+	// Symlinks need elevated privileges on most Windows setups
+	return runtime.GOOS == "windows" && viper.GetString("dotfiles.mode") != "link"
+}
+
+// splitVariant splits "name##os.linux" into the base name and its qualifier
+func splitVariant(name string) (string, string) {
+	if i := strings.Index(name, "##"); i >= 0 {
+		return name[:i], name[i+2:]
+	}
+	return name, ""
+}
+
+// variantScore ranks a qualifier for the current machine; -1 means it does not apply
+func variantScore(qualifier, goos, host string) int {
+	switch {
+	case qualifier == "":
+		return 0
+	case qualifier == "os."+goos:
+		return 1
+	case strings.EqualFold(qualifier, "host."+host):
+		return 2
+	default:
+		return -1
+	}
+}
+
+// selectDotfiles groups the files in dir by base path and picks the best variant of each
+func selectDotfiles(dir, goos, host string) ([]dotfile, error) {
+	best := make(map[string]string)
+	scores := make(map[string]int)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		base, qualifier := splitVariant(rel)
+		score := variantScore(qualifier, goos, host)
+		if score < 0 {
+			return nil
+		}
+		if current, ok := scores[base]; !ok || score > current {
+			scores[base] = score
+			best[base] = path
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotfiles directory: %w", err)
+	}
+
+	files := make([]dotfile, 0, len(best))
+	for rel, source := range best {
+		files = append(files, dotfile{RelPath: rel, Source: source})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].RelPath < files[j].RelPath })
+	return files, nil
+}
+
+func trackedDotfiles() ([]dotfile, error) {
+	host, _ := os.Hostname()
+	return selectDotfiles(GetDotfilesDir(), runtime.GOOS, host)
+}
+
+func homeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return home, nil
+}
+
+func addDotfile(path string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	rel, err := filepath.Rel(home, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is not inside your home directory", path)
+	}
+
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory; add individual files instead", path)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is already a symlink", path)
+	}
+
+	dest := filepath.Join(GetDotfilesDir(), rel)
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s is already tracked", rel)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create dotfiles directory: %w", err)
+	}
+	if err := copyFile(abs, dest); err != nil {
+		return err
+	}
+	if err := placeDotfile(dest, abs); err != nil {
+		return err
+	}
+
+	fmt.Printf("Tracking %s\n", rel)
+	return nil
+}
+
+// placeDotfile puts source at target as a symlink or a copy
+func placeDotfile(source, target string) error {
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	if useCopies() {
+		return copyFile(source, target)
+	}
+	if err := os.Symlink(source, target); err != nil {
+		return fmt.Errorf("failed to link %s: %w", target, err)
+	}
+	return nil
+}
+
+func linkDotfiles() error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	files, err := trackedDotfiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No dotfiles tracked.")
+		fmt.Println("Start with: berga dotfiles add ~/.bashrc")
+		return nil
+	}
+
+	for _, f := range files {
+		target := filepath.Join(home, f.RelPath)
+		state := dotfileState(f, target)
+		if state == "linked" || (state == "copied" && useCopies()) {
+			continue
+		}
+
+		if state != "missing" && !dotfilesForce {
+			backup := target + ".berga-backup"
+			if err := os.Rename(target, backup); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", target, err)
+			}
+			fmt.Printf("  %s backed up to %s\n", f.RelPath, backup)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", target, err)
+		}
+		if err := placeDotfile(f.Source, target); err != nil {
+			return err
+		}
+		fmt.Printf("  %s %s\n", ui.Green(ui.Icon("✅", "ok")), f.RelPath)
+	}
+	return nil
+}
+
+// dotfileState describes how target relates to the tracked source
+func dotfileState(f dotfile, target string) string {
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return "missing"
+	}
+	if err != nil {
+		return "conflict"
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if dest, err := os.Readlink(target); err == nil && dest == f.Source {
+			return "linked"
+		}
+		return "conflict"
+	}
+
+	same, err := sameContent(f.Source, target)
+	if err != nil {
+		return "conflict"
+	}
+	if same {
+		return "copied"
+	}
+	return "modified"
+}
+
+func dotfilesStatus() error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	files, err := trackedDotfiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No dotfiles tracked.")
+		return nil
+	}
+
+	ui.Header("Dotfiles")
+	for _, f := range files {
+		state := dotfileState(f, filepath.Join(home, f.RelPath))
+		label := fmt.Sprintf("%-10s", state)
+		switch state {
+		case "linked", "copied":
+			label = ui.Green(label)
+		case "modified", "missing":
+			label = ui.Yellow(label)
+		case "conflict":
+			label = ui.Red(label)
+		}
+
+		source, _ := filepath.Rel(GetDotfilesDir(), f.Source)
+		line := fmt.Sprintf("  %s %s", label, f.RelPath)
+		if source != f.RelPath {
+			line += ui.Dim(" (" + source + ")")
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\nDotfiles directory: %s\n", GetDotfilesDir())
+	return nil
+}
+
+func restoreDotfiles(paths []string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	files, err := trackedDotfiles()
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		if rel, err := filepath.Rel(home, abs); err == nil {
+			wanted[rel] = true
+		}
+	}
+
+	restored := 0
+	for _, f := range files {
+		if len(wanted) > 0 && !wanted[f.RelPath] {
+			continue
+		}
+		target := filepath.Join(home, f.RelPath)
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", target, err)
+		}
+		if err := copyFile(f.Source, target); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s\n", f.RelPath)
+		restored++
+	}
+
+	if restored == 0 {
+		fmt.Println("No matching dotfiles to restore.")
+	}
+	return nil
+}
+
+// copyFile copies src to dst, preserving the file mode
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+func sameContent(a, b string) (bool, error) {
+	da, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(da, db), nil
+}
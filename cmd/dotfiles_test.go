@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitVariant(t *testing.T) {
+	base, qualifier := splitVariant(".bashrc##os.linux")
+	if base != ".bashrc" || qualifier != "os.linux" {
+		t.Errorf("Expected .bashrc/os.linux, got %s/%s", base, qualifier)
+	}
+
+	base, qualifier = splitVariant(".bashrc")
+	if base != ".bashrc" || qualifier != "" {
+		t.Errorf("Expected .bashrc with no qualifier, got %s/%s", base, qualifier)
+	}
+}
+
+func TestSelectDotfiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".bashrc", ".bashrc##os.linux", ".bashrc##host.box", ".vimrc", ".vimrc##os.darwin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := selectDotfiles(dir, "linux", "other")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 dotfiles, got %d", len(files))
+	}
+	if filepath.Base(files[0].Source) != ".bashrc##os.linux" {
+		t.Errorf("Expected OS override for .bashrc, got %s", files[0].Source)
+	}
+	if filepath.Base(files[1].Source) != ".vimrc" {
+		t.Errorf("Expected base .vimrc, got %s", files[1].Source)
+	}
+
+	files, err = selectDotfiles(dir, "linux", "box")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Base(files[0].Source) != ".bashrc##host.box" {
+		t.Errorf("Expected host override for .bashrc, got %s", files[0].Source)
+	}
+}
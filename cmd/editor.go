@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"berga/pkg/scripts"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// editorFlag is the --editor override shared by the commands that open an
+// editor
+var editorFlag string
+
+var editWorkspace bool
+
+// editCmd opens a script, a template, or the whole workspace
+var editCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit a script or template, or open the berga workspace",
+	Long: `Open a script or template by name in your editor, looking for a script
+first. With --workspace, open the whole berga directory instead, the same as
+'berga open'.
+
+The editor comes from --editor, the editor setting, $EDITOR, or $VISUAL, and
+may include arguments, e.g. --editor "code -w".`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if editWorkspace {
+			return cobra.NoArgs(cmd, args)
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("expected a script or template name, or --workspace")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if editWorkspace {
+			return openWorkspace("")
+		}
+		return editByName(args[0])
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 || editWorkspace {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names, _ := completeScriptNames(cmd, nil, toComplete)
+		templates, _ := completeTemplateNames(cmd, nil, toComplete)
+		return append(names, templates...), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// openCmd opens the berga workspace in the editor
+var openCmd = &cobra.Command{
+	Use:   "open [part]",
+	Short: "Open the berga workspace in your editor",
+	Long: `Open the berga directory, with scripts, templates, notes, and the rest, in
+your editor. Editors that take a directory get it directly; VS Code and
+similar editors open it in a new window.
+
+Give a part to open only that: scripts, templates, snippets, notes, dotfiles,
+requests, or config (the config file).`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: workspaceParts,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		part := ""
+		if len(args) > 0 {
+			part = args[0]
+		}
+		return openWorkspace(part)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd, openCmd)
+
+	// Flags
+	editCmd.Flags().BoolVarP(&editWorkspace, "workspace", "w", false, "Open the whole berga directory")
+	for _, cmd := range []*cobra.Command{editCmd, openCmd, scriptEditCmd, templateEditCmd, httpEditCmd} {
+		cmd.Flags().StringVar(&editorFlag, "editor", "", "Editor to use instead of the configured one")
+	}
+}
+
+// editorCommand returns the command that opens paths in editor. The editor
+// may carry its own arguments, e.g. "code -w".
+func editorCommand(editor string, paths ...string) *exec.Cmd {
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		fields = []string{editor}
+	}
+	cmd := exec.Command(fields[0], append(fields[1:], paths...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd
+}
+
+// reviewEdit shows the changes an edit made to name and, with edit.review
+// set, asks whether to keep them. It reports whether they are kept.
+func reviewEdit(name string, before, after []byte) bool {
+	fmt.Print(colorDiff(unifiedDiff(name, name+" (edited)", string(before), string(after))))
+	if !viper.GetBool("edit.review") {
+		return true
+	}
+	return confirm(promptOut, "Keep these changes?", true)
+}
+
+// editFile opens path in editor, then reviews what changed. Rejected changes
+// are undone: the original content is written back, or a new file removed.
+func editFile(editor, path string) error {
+	before, err := os.ReadFile(path)
+	existed := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := editorCommand(editor, path).Run(); err != nil {
+		return err
+	}
+
+	after, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Nothing was saved
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if bytes.Equal(before, after) {
+		fmt.Println("No changes.")
+		return nil
+	}
+	if reviewEdit(filepath.Base(path), before, after) {
+		return nil
+	}
+
+	if existed {
+		err = os.WriteFile(path, before, 0)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to undo the changes to %s: %w", path, err)
+	}
+	fmt.Printf("Discarded the changes to %s\n", filepath.Base(path))
+	return nil
+}
+
+// editorName returns an editor's program name without path or extension
+func editorName(editor string) string {
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(fields[0]))
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// workspaceEditor adapts an editor to opening directories. GUI editors that
+// reuse a window get their new-window flag; editors that only edit files are
+// an error.
+func workspaceEditor(editor string) (string, error) {
+	name := editorName(editor)
+	switch name {
+	case "code", "code-insiders", "codium", "cursor", "subl":
+		if len(strings.Fields(editor)) == 1 {
+			return editor + " -n", nil
+		}
+	case "nano", "pico", "notepad", "ed":
+		return "", fmt.Errorf("%s cannot open a directory; pass --editor with one that can, such as code, vim, or emacs", name)
+	}
+	return editor, nil
+}
+
+// workspaceParts are the names 'berga open' accepts
+var workspaceParts = []string{"config", "dotfiles", "notes", "requests", "scripts", "snippets", "templates"}
+
+// workspacePartPaths maps each of workspaceParts to its path
+func workspacePartPaths() map[string]string {
+	return map[string]string{
+		"scripts":   GetScriptsDir(),
+		"templates": GetTemplatesDir(),
+		"snippets":  GetSnippetsDir(),
+		"notes":     GetNotesDir(),
+		"dotfiles":  GetDotfilesDir(),
+		"requests":  GetRequestsDir(),
+		"config":    configFilePath(),
+	}
+}
+
+// workspaceDirs returns the directories that make up the workspace: the data
+// directory, and the config directory where it is separate
+func workspaceDirs() []string {
+	dirs := []string{GetDataDir()}
+	if filepath.Clean(GetConfigDir()) != filepath.Clean(GetDataDir()) {
+		dirs = append(dirs, GetConfigDir())
+	}
+	return dirs
+}
+
+// openWorkspace opens the berga directories, or one part of them, in the
+// editor
+func openWorkspace(part string) error {
+	editor := preferredEditor()
+	paths := workspaceDirs()
+	if part != "" {
+		path, ok := workspacePartPaths()[part]
+		if !ok {
+			return fmt.Errorf("unknown part '%s' (expected one of: %s)", part, strings.Join(workspaceParts, ", "))
+		}
+		paths = []string{path}
+	}
+
+	if part != "config" {
+		var err error
+		if editor, err = workspaceEditor(editor); err != nil {
+			return err
+		}
+		for _, dir := range paths {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+		}
+	}
+
+	fmt.Printf("Opening %s with %s...\n", strings.Join(paths, ", "), editor)
+	return editorCommand(editor, paths...).Run()
+}
+
+// editByName edits the script or, failing that, the template with a name
+func editByName(name string) error {
+	if _, ok := (scripts.DirStore{Dirs: GetScriptsDirs()}).Find(name); ok {
+		return editScript(name)
+	}
+	if _, ok := findLocalTemplate(name); ok {
+		return editTemplate(name)
+	}
+	return fmt.Errorf("no script or template named '%s' (create one with 'berga script edit' or 'berga template edit')", name)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEditorCommand(t *testing.T) {
+	cmd := editorCommand("code -w", "/tmp/a.sh")
+	if got := strings.Join(cmd.Args, " "); got != "code -w /tmp/a.sh" {
+		t.Errorf("Expected the editor's arguments to be kept, got %q", got)
+	}
+}
+
+func TestWorkspaceEditor(t *testing.T) {
+	tests := map[string]string{
+		"code":                "code -n",
+		"/usr/bin/subl":       "/usr/bin/subl -n",
+		"code --reuse-window": "code --reuse-window",
+		"vim":                 "vim",
+		"emacs -nw":           "emacs -nw",
+	}
+	for editor, want := range tests {
+		if got, err := workspaceEditor(editor); err != nil || got != want {
+			t.Errorf("workspaceEditor(%q) = %q, %v; want %q", editor, got, err, want)
+		}
+	}
+	if _, err := workspaceEditor("nano"); err == nil {
+		t.Error("Expected an editor that cannot open directories to be an error")
+	}
+}
+
+func TestPreferredEditorFlag(t *testing.T) {
+	viper.Set("editor", "vim")
+	defer viper.Set("editor", nil)
+	if got := preferredEditor(); got != "vim" {
+		t.Errorf("Expected the configured editor, got %q", got)
+	}
+	editorFlag = "code -w"
+	defer func() { editorFlag = "" }()
+	if got := preferredEditor(); got != "code -w" {
+		t.Errorf("Expected --editor to win, got %q", got)
+	}
+}
+
+func TestOpenWorkspace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+	t.Setenv("HOME", t.TempDir())
+	log := filepath.Join(t.TempDir(), "args")
+	editor := filepath.Join(t.TempDir(), "fake-editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho \"$@\" > "+log+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	editorFlag = editor
+	defer func() { editorFlag = "" }()
+
+	if err := openWorkspace(""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(log)
+	if !strings.Contains(string(data), GetDataDir()) {
+		t.Errorf("Expected the data directory to be opened, got %q", data)
+	}
+
+	if err := openWorkspace("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(log); strings.TrimSpace(string(data)) != GetNotesDir() {
+		t.Errorf("Expected only the notes directory, got %q", data)
+	}
+	if info, err := os.Stat(GetNotesDir()); err != nil || !info.IsDir() {
+		t.Error("Expected a missing part to be created")
+	}
+	if err := openWorkspace("nope"); err == nil {
+		t.Error("Expected an unknown part to be an error")
+	}
+}
+
+func TestEditFileReview(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor.sh")
+	os.WriteFile(editor, []byte("#!/bin/sh\necho changed >> \"$1\"\n"), 0755)
+	file := filepath.Join(dir, "deploy.sh")
+	os.WriteFile(file, []byte("original\n"), 0644)
+
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	promptOut = io.Discard
+	viper.Set("edit.review", true)
+	defer viper.Set("edit.review", nil)
+
+	stdinReader = bufio.NewReader(strings.NewReader("n\n"))
+	if err := editFile(editor, file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "original\n" {
+		t.Errorf("Expected rejected changes to be undone, got %q", data)
+	}
+
+	stdinReader = bufio.NewReader(strings.NewReader("y\n"))
+	if err := editFile(editor, file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "original\nchanged\n" {
+		t.Errorf("Expected accepted changes to be kept, got %q", data)
+	}
+
+	created := filepath.Join(dir, "new.sh")
+	stdinReader = bufio.NewReader(strings.NewReader("n\n"))
+	if err := editFile(editor, created); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Error("Expected a rejected new file to be removed")
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var envExecSecrets []string
+
+// envCmd groups commands that work with env profiles
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Use environment profiles outside of scripts",
+	Long: `Work with the environment profiles defined under env.profiles in the global
+config or a project .berga.yaml.`,
+}
+
+// envExecCmd runs an arbitrary command with an env profile
+var envExecCmd = &cobra.Command{
+	Use:   "exec [profile] -- [command...]",
+	Short: "Run a command with an env profile and secrets",
+	Long: `Run any command with the variables of an env profile layered over the
+current environment, the same way 'script run --env-profile' does.
+
+Secrets stored with 'berga config set secrets.<key>' are injected with
+--secret NAME=key, or --secret key to export secrets.<key> as KEY:
+
+  berga env exec prod -- terraform plan
+  berga env exec dev --secret GITHUB_TOKEN=gh_token -- gh pr list
+
+The command's exit status is passed through.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if dash := cmd.ArgsLenAtDash(); dash == 0 || dash > 1 {
+			return fmt.Errorf("expected one profile before '--', got %d arguments", dash)
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("usage: berga env exec <profile> -- <command...>")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return envExec(args[0], args[1:], envExecSecrets)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envExecCmd)
+
+	// Flags
+	envExecCmd.Flags().StringArrayVar(&envExecSecrets, "secret", nil, "Export a secret as NAME=key (secrets.<key>), or key as KEY; repeatable")
+}
+
+// secretEnv resolves --secret values to environment variables
+func secretEnv(specs []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, spec := range specs {
+		name, key, found := strings.Cut(spec, "=")
+		if !found {
+			key = name
+			name = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+		}
+		if name == "" || key == "" {
+			return nil, fmt.Errorf("invalid --secret '%s' (expected NAME=key or key)", spec)
+		}
+		if !viper.IsSet("secrets." + key) {
+			return nil, fmt.Errorf("secret '%s' is not set (store it with 'berga config set secrets.%s')", key, key)
+		}
+		env[name] = viper.GetString("secrets." + key)
+		auditSecretAccess("secrets." + key)
+	}
+	return env, nil
+}
+
+// layerEnviron appends variables to an environment. Later maps win.
+func layerEnviron(environ []string, layers ...map[string]string) []string {
+	for _, layer := range layers {
+		keys := make([]string, 0, len(layer))
+		for k := range layer {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			environ = append(environ, k+"="+layer[k])
+		}
+	}
+	return environ
+}
+
+func envExec(profile string, command []string, secrets []string) error {
+	env, err := resolveEnvProfile(profile)
+	if err != nil {
+		return err
+	}
+	secretVars, err := secretEnv(secrets)
+	if err != nil {
+		return err
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("command '%s' not found: %w", command[0], err)
+	}
+
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "Profile: %s (%d variables, %d secrets)\n", profile, len(env), len(secretVars))
+		fmt.Fprintf(os.Stderr, "Executing: %s\n", strings.Join(command, " "))
+	}
+
+	cmd := exec.Command(path, command[1:]...)
+	cmd.Env = layerEnviron(os.Environ(), env, secretVars)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSecretEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("secrets.gh_token", "ghp_123")
+	viper.Set("secrets.api-key", "k")
+	defer viper.Set("secrets", nil)
+
+	env, err := secretEnv([]string{"GITHUB_TOKEN=gh_token", "api-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["GITHUB_TOKEN"] != "ghp_123" || env["API_KEY"] != "k" {
+		t.Errorf("Unexpected secret env %v", env)
+	}
+
+	if _, err := secretEnv([]string{"missing"}); err == nil {
+		t.Error("Expected an unset secret to be an error")
+	}
+	if _, err := secretEnv([]string{"NAME="}); err == nil {
+		t.Error("Expected an empty key to be an error")
+	}
+}
+
+func TestEnvExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	defer func(p *ProjectConfig) { project = p }(project)
+
+	project = &ProjectConfig{}
+	project.Env.Profiles = map[string]map[string]string{
+		"dev": {"API_URL": "http://localhost"},
+	}
+
+	if err := envExec("dev", []string{"sh", "-c", `test "$API_URL" = http://localhost`}, nil); err != nil {
+		t.Errorf("Expected the profile's variables in the environment, got %v", err)
+	}
+
+	err := envExec("dev", []string{"sh", "-c", "exit 3"}, nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("Expected exit status 3 to be passed through, got %v", err)
+	}
+
+	if err := envExec("staging", []string{"true"}, nil); err == nil {
+		t.Error("Expected an unknown profile to be an error")
+	}
+}
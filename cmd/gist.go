@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// gistTokenKey is the secret holding the GitHub token used to publish gists
+const gistTokenKey = "secrets.gist_token"
+
+// gistPrefix selects a gist as the source for 'berga get'
+const gistPrefix = "gist:"
+
+// gistAPI is the GitHub API base URL. Tests replace it.
+var gistAPI = "https://api.github.com"
+
+var (
+	publishPublic      bool
+	publishDescription string
+	getForce           bool
+)
+
+// Gist is the part of a GitHub gist berga reads and writes
+type Gist struct {
+	ID          string              `json:"id,omitempty"`
+	HTMLURL     string              `json:"html_url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Public      bool                `json:"public"`
+	Files       map[string]GistFile `json:"files"`
+}
+
+// GistFile is one file in a gist
+type GistFile struct {
+	Content   string `json:"content"`
+	RawURL    string `json:"raw_url,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// scriptPublishCmd shares a script as a gist
+var scriptPublishCmd = &cobra.Command{
+	Use:   "publish [script-name]",
+	Short: "Publish a script as a GitHub gist",
+	Long: `Create a GitHub gist holding a script, or update the gist it was published
+to before. The token is read from secrets.gist_token and needs the gist scope:
+
+  berga config set secrets.gist_token ghp_...
+  berga script publish cleanup.sh --public
+
+Gists are secret (unlisted) unless --public is given. Others can import the
+script with 'berga get gist:<id>'. Encrypted scripts cannot be published.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("gists", func() error {
+			return publishScript(args[0])
+		})
+	},
+}
+
+// getCmd imports scripts and templates from a remote source
+var getCmd = &cobra.Command{
+	Use:   "get [source]",
+	Short: "Import scripts and templates from a gist",
+	Long: `Download the files of a GitHub gist into berga: .tmpl files become templates,
+everything else becomes a script.
+
+  berga get gist:8a3f0c2d9e
+  berga get gist:https://gist.github.com/someone/8a3f0c2d9e
+
+Existing files are left alone unless --force is given. Review imported
+scripts before running them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		id, ok := strings.CutPrefix(args[0], gistPrefix)
+		if !ok {
+			return fmt.Errorf("unsupported source '%s' (expected gist:<id>)", args[0])
+		}
+		return getGist(gistID(id), getForce)
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptPublishCmd)
+	rootCmd.AddCommand(getCmd)
+
+	// Flags
+	scriptPublishCmd.Flags().BoolVar(&publishPublic, "public", false, "Create a public gist instead of a secret one")
+	scriptPublishCmd.Flags().StringVarP(&publishDescription, "description", "d", "", "Gist description (default: the script's berga:description)")
+	getCmd.Flags().BoolVarP(&getForce, "force", "f", false, "Overwrite existing scripts and templates")
+}
+
+// gistID accepts a gist id or a gist URL
+func gistID(ref string) string {
+	ref = strings.TrimSuffix(strings.TrimSpace(ref), "/")
+	if strings.Contains(ref, "/") {
+		return path.Base(ref)
+	}
+	return ref
+}
+
+// gistRequest calls the gists API, decoding the response into out
+func gistRequest(method, endpoint string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, gistAPI+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := strings.TrimSpace(viper.GetString(gistTokenKey)); token != "" {
+		auditSecretAccess(gistTokenKey)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func loadGistStore() (map[string]string, error) {
+	store := make(map[string]string)
+
+	data, err := os.ReadFile(GetGistsFile())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gist store: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse gist store: %w", err)
+	}
+	return store, nil
+}
+
+func saveGistStore(store map[string]string) error {
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return fmt.Errorf("failed to encode gist store: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetGistsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write gist store: %w", err)
+	}
+	return nil
+}
+
+func publishScript(scriptName string) error {
+	scriptPath := resolveScriptPath(scriptName)
+	if isEncryptedScript(scriptPath) {
+		return fmt.Errorf("script '%s' is encrypted and cannot be published", scriptName)
+	}
+	content, err := os.ReadFile(scriptPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("script '%s' not found in %s", scriptName, GetScriptsDir())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+	if strings.TrimSpace(viper.GetString(gistTokenKey)) == "" {
+		return fmt.Errorf("no GitHub token; store one with the gist scope using 'berga config set %s ghp_...'", gistTokenKey)
+	}
+
+	store, err := loadGistStore()
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(scriptPath)
+	description := publishDescription
+	if description == "" {
+		description = readMetadata(scriptPath)["description"]
+	}
+	body := Gist{Description: description, Public: publishPublic, Files: map[string]GistFile{name: {Content: string(content)}}}
+
+	var result Gist
+	id, published := store[name]
+	if published {
+		err = gistRequest(http.MethodPatch, "/gists/"+id, body, &result)
+	} else {
+		err = gistRequest(http.MethodPost, "/gists", body, &result)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish '%s': %w", scriptName, err)
+	}
+
+	store[name] = result.ID
+	if err := saveGistStore(store); err != nil {
+		return err
+	}
+	if published {
+		fmt.Printf("Updated gist %s\n", result.HTMLURL)
+	} else {
+		fmt.Printf("Published '%s' to %s\n", scriptName, result.HTMLURL)
+	}
+	fmt.Println(ui.Dim(fmt.Sprintf("Import it with: berga get %s%s", gistPrefix, result.ID)))
+	return nil
+}
+
+// gistFileContent returns a file's content, fetching it when the API
+// truncated it
+func gistFileContent(file GistFile) (string, error) {
+	if !file.Truncated || file.RawURL == "" {
+		return file.Content, nil
+	}
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Get(file.RawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func getGist(id string, force bool) error {
+	if id == "" {
+		return fmt.Errorf("missing gist id")
+	}
+	var g Gist
+	if err := gistRequest(http.MethodGet, "/gists/"+id, nil, &g); err != nil {
+		return fmt.Errorf("failed to fetch gist %s: %w", id, err)
+	}
+	if len(g.Files) == 0 {
+		return fmt.Errorf("gist %s has no files", id)
+	}
+
+	names := make([]string, 0, len(g.Files))
+	for name := range g.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	imported := 0
+	for _, name := range names {
+		file := g.Files[name]
+		if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: skipping '%s': not a plain file name", name)))
+			continue
+		}
+		dest, kind, perm := filepath.Join(GetScriptsDir(), name), "script", os.FileMode(0755)
+		if strings.HasSuffix(name, ".tmpl") {
+			dest, kind, perm = filepath.Join(GetTemplatesDir(), name), "template", 0644
+		}
+		if _, err := os.Stat(dest); err == nil && !force {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %s '%s' already exists; skipped (use --force to overwrite)", kind, name)))
+			continue
+		}
+
+		content, err := gistFileContent(file)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", kind, err)
+		}
+		if err := writeFileAtomic(dest, []byte(content), perm); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		fmt.Printf("Imported %s '%s'\n", kind, name)
+		imported++
+	}
+
+	if imported == 0 {
+		return fmt.Errorf("nothing imported from gist %s", id)
+	}
+	return nil
+}
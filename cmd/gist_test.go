@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeGistAPI serves the gists endpoints berga uses from memory
+func fakeGistAPI(t *testing.T, gists map[string]*Gist) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := filepath.Base(r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			g, ok := gists[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+				return
+			}
+			json.NewEncoder(w).Encode(g)
+		case http.MethodPost, http.MethodPatch:
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var g Gist
+			json.NewDecoder(r.Body).Decode(&g)
+			if r.Method == http.MethodPost {
+				id = "g1"
+				w.WriteHeader(http.StatusCreated)
+			}
+			g.ID, g.HTMLURL = id, "https://gist.example/"+id
+			gists[id] = &g
+			json.NewEncoder(w).Encode(g)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	orig := gistAPI
+	gistAPI = server.URL
+	t.Cleanup(func() { gistAPI = orig })
+}
+
+func TestPublishScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	gists := make(map[string]*Gist)
+	fakeGistAPI(t, gists)
+	writeTestScript(t, "cleanup.sh", "#!/bin/sh\n# berga:description: Remove build output\nrm -rf build\n")
+
+	if err := publishScript("cleanup.sh"); err == nil {
+		t.Error("Expected publishing without a token to fail")
+	}
+
+	viper.Set(gistTokenKey, "test-token")
+	defer viper.Set(gistTokenKey, nil)
+	if err := publishScript("cleanup.sh"); err != nil {
+		t.Fatal(err)
+	}
+	g := gists["g1"]
+	if g == nil || g.Description != "Remove build output" || g.Files["cleanup.sh"].Content == "" {
+		t.Fatalf("Unexpected gist %+v", g)
+	}
+
+	// Publishing again updates the same gist
+	writeTestScript(t, "cleanup.sh", "#!/bin/sh\nrm -rf dist\n")
+	delete(gists, "g1")
+	if err := publishScript("cleanup.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if g := gists["g1"]; g == nil || g.Files["cleanup.sh"].Content != "#!/bin/sh\nrm -rf dist\n" {
+		t.Errorf("Expected the recorded gist to be updated, got %+v", g)
+	}
+}
+
+func TestGetGist(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fakeGistAPI(t, map[string]*Gist{
+		"abc": {ID: "abc", Files: map[string]GistFile{
+			"hello.sh":        {Content: "#!/bin/sh\necho hi\n"},
+			"README.md.tmpl":  {Content: "# {{.Name}}\n"},
+			"../escape.sh":    {Content: "nope"},
+			".hidden-setting": {Content: "nope"},
+		}},
+	})
+
+	if err := getGist(gistID("https://gist.github.com/someone/abc"), false); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(GetScriptsDir(), "hello.sh")); err != nil || string(data) != "#!/bin/sh\necho hi\n" {
+		t.Errorf("Expected the script to be imported, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(GetTemplatesDir(), "README.md.tmpl")); err != nil {
+		t.Error("Expected the .tmpl file to be imported as a template")
+	}
+	if _, err := os.Stat(filepath.Join(GetScriptsDir(), ".hidden-setting")); err == nil {
+		t.Error("Expected hidden file names to be skipped")
+	}
+
+	writeTestScript(t, "hello.sh", "mine")
+	if err := getGist("abc", false); err == nil {
+		t.Error("Expected nothing to be imported over existing files")
+	}
+	if data, _ := os.ReadFile(filepath.Join(GetScriptsDir(), "hello.sh")); string(data) != "mine" {
+		t.Error("Expected the existing script to be kept without --force")
+	}
+	if err := getGist("missing", false); err == nil {
+		t.Error("Expected an unknown gist to be an error")
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// lastCommandEnv is set by the shell integration before each prompt to the
+// command that just ran
+const lastCommandEnv = "BERGA_LAST_COMMAND"
+
+var (
+	grabDescription string
+	grabSnippet     bool
+	grabForce       bool
+)
+
+// grabCmd saves a one-liner as a script or snippet
+var grabCmd = &cobra.Command{
+	Use:   "grab [name]",
+	Short: "Save the last shell command as a script",
+	Long: `Save the command you just ran as a new script, so a useful one-liner is not
+lost in your shell history. The command comes from standard input when it is
+piped in, and otherwise from the shell integration ('berga shell-init'), which
+keeps the last command in $BERGA_LAST_COMMAND before each prompt.
+
+  docker system prune -af --volumes
+  berga grab docker-clean -d "Free all Docker disk space"
+
+  echo 'git log --oneline --graph --all' | berga grab graph.sh --snippet
+
+A name without an extension gets the one of your shell (.sh, .fish, or .ps1).
+Scripts start with a shebang for your shell and are made executable; with
+--snippet the command is saved to the snippets directory instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		command, err := grabbedCommand()
+		if err != nil {
+			return err
+		}
+		return grab(args[0], command)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grabCmd)
+
+	// Flags
+	grabCmd.Flags().StringVarP(&grabDescription, "description", "d", "", "What the command does, saved as berga:description")
+	grabCmd.Flags().BoolVar(&grabSnippet, "snippet", false, "Save to the snippets directory instead of the scripts directory")
+	grabCmd.Flags().BoolVarP(&grabForce, "force", "f", false, "Replace an existing script or snippet of the same name")
+}
+
+// grabbedCommand returns the command to save: standard input when something
+// is piped in, or the last command the shell integration recorded
+func grabbedCommand() (string, error) {
+	if !stdinIsTerminal() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read standard input: %w", err)
+		}
+		if command := strings.TrimSpace(string(data)); command != "" {
+			return command, nil
+		}
+	}
+	command := strings.TrimSpace(os.Getenv(lastCommandEnv))
+	if command == "" {
+		return "", fmt.Errorf("no command to grab: pipe one in, or load the shell integration with 'berga shell-init --install' so berga can see your last command")
+	}
+	return command, nil
+}
+
+// grabShell is the shell a grabbed command is written for, from $SHELL
+func grabShell() string {
+	shell, err := initShell(nil)
+	if err != nil {
+		return "sh"
+	}
+	return shell
+}
+
+// grabContent is the script or snippet saving command, for shell
+func grabContent(command, description, shell string) string {
+	var b strings.Builder
+	switch shell {
+	case "powershell":
+	case "sh":
+		b.WriteString("#!/bin/sh\n")
+	default:
+		fmt.Fprintf(&b, "#!/usr/bin/env %s\n", shell)
+	}
+	if description != "" {
+		fmt.Fprintf(&b, "# berga:description: %s\n", strings.Join(strings.Fields(description), " "))
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(command)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// grabFileName adds the shell's extension to a name without one
+func grabFileName(name, shell string) string {
+	if filepath.Ext(name) != "" {
+		return name
+	}
+	switch shell {
+	case "fish":
+		return name + ".fish"
+	case "powershell":
+		return name + ".ps1"
+	}
+	return name + ".sh"
+}
+
+// grab saves command as the script or snippet name
+func grab(name, command string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid name '%s'", name)
+	}
+	shell := grabShell()
+	if runtime.GOOS == "windows" {
+		shell = "powershell"
+	}
+	name = grabFileName(name, shell)
+	content := []byte(grabContent(command, grabDescription, shell))
+
+	kind, dir := "script", GetScriptsDir()
+	if grabSnippet {
+		kind, dir = "snippet", GetSnippetsDir()
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Lstat(path); err == nil && !grabForce {
+		return fmt.Errorf("%s '%s' already exists; use --force to replace it", kind, name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", kind, err)
+	}
+
+	if grabSnippet {
+		if err := writeFileAtomic(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to save snippet: %w", err)
+		}
+	} else {
+		err := trackChange("script", path, revisionCreate, func() error {
+			return writeFileAtomic(path, content, 0755)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save script: %w", err)
+		}
+	}
+
+	fmt.Printf("Saved %s '%s' to %s:\n  %s\n", kind, name, path, strings.ReplaceAll(command, "\n", "\n  "))
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGrabContent(t *testing.T) {
+	got := grabContent("docker system prune -af", "Free  disk\nspace", "bash")
+	want := "#!/usr/bin/env bash\n# berga:description: Free disk space\n\ndocker system prune -af\n"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := grabContent("Get-Process", "", "powershell"); got != "Get-Process\n" {
+		t.Errorf("Expected no shebang for PowerShell, got %q", got)
+	}
+
+	for name, want := range map[string]string{"clean": "clean.sh", "clean.py": "clean.py"} {
+		if got := grabFileName(name, "zsh"); got != want {
+			t.Errorf("grabFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := grabFileName("clean", "fish"); got != "clean.fish" {
+		t.Errorf("Expected a fish extension, got %q", got)
+	}
+}
+
+func TestGrab(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("grabbed scripts are PowerShell on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv(lastCommandEnv, "  du -sh * | sort -h\n")
+	setTerminal(t, true)
+	grabDescription = "Largest entries here"
+	defer func() { grabDescription, grabForce = "", false }()
+
+	command, err := grabbedCommand()
+	if err != nil || command != "du -sh * | sort -h" {
+		t.Fatalf("Expected the last command, got %q, %v", command, err)
+	}
+	if err := grab("sizes", command); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(GetScriptsDir(), "sizes.sh")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# berga:description: Largest entries here\n\ndu -sh * | sort -h\n") {
+		t.Errorf("Unexpected script:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm()&0100 == 0 {
+		t.Error("Expected the script to be executable")
+	}
+
+	if err := grab("sizes", "ls"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected grabbing over a script to fail, got %v", err)
+	}
+	grabForce = true
+	if err := grab("sizes", "ls"); err != nil {
+		t.Errorf("Expected --force to replace the script, got %v", err)
+	}
+	if err := grab("../sizes", "ls"); err == nil {
+		t.Error("Expected a name with a path to be refused")
+	}
+}
+
+func TestGrabWithoutCommand(t *testing.T) {
+	setTerminal(t, true)
+	t.Setenv(lastCommandEnv, "")
+	if _, err := grabbedCommand(); err == nil || !strings.Contains(err.Error(), "shell-init") {
+		t.Errorf("Expected a hint about the shell integration, got %v", err)
+	}
+}
@@ -0,0 +1,545 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/internal/ui"
+	"berga/pkg/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// defaultHistoryLimit is how many runs the history keeps by default
+const defaultHistoryLimit = 1000
+
+// historyOutputLimit caps the recorded output of a run, keeping the end
+const historyOutputLimit = 64 * 1024
+
+// historyPickerLimit is how many matches the picker offers without fzf
+const historyPickerLimit = 10
+
+// HistoryEntry is one recorded script run
+type HistoryEntry store.Run
+
+// command is the run as it would be typed after 'berga script run'
+func (e HistoryEntry) command() string {
+	parts := make([]string, 0, len(e.Args)+1)
+	for _, part := range append([]string{e.Script}, e.Args...) {
+		parts = append(parts, shellQuote(part))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for a POSIX shell when it needs quoting
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// status describes how the run ended, padded to width
+func (e HistoryEntry) status(width int) string {
+	if e.ExitCode == 0 {
+		return ui.Green(fmt.Sprintf("%-*s", width, "ok"))
+	}
+	return ui.Red(fmt.Sprintf("%-*s", width, fmt.Sprintf("exit %d", e.ExitCode)))
+}
+
+var (
+	historyLimit  int
+	historyScript string
+	historyFailed bool
+	historySince  string
+	historyQuery  string
+	// scriptHistoryFlags are the flags of the current 'script run', recorded
+	// to run it again with; see historyFlagArgs
+	scriptHistoryFlags []string
+)
+
+// historyCmd lists previous script runs
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Search and rerun previous script runs",
+	Long: `Every 'berga script run' is recorded with its arguments, flags, directory,
+exit code, and duration. Without a subcommand the most recent runs are listed.
+
+With history.output set, the last 64KB of each run's output is kept too, for
+'history show' and the preview in 'history fzf'. Scripts then write to a pipe
+rather than straight to the terminal, which some programs notice.`,
+	Args: cobra.NoArgs,
+	RunE: runListHistory,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List previous script runs",
+	Long: `List the most recent runs, oldest first. --since takes a duration such as 12h
+or 30d, or a date (2006-01-02):
+
+  berga history list --script deploy.sh --failed --since 30d`,
+	Args: cobra.NoArgs,
+	RunE: runListHistory,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:               "show [id]",
+	Short:             "Show a recorded run and its output",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHistoryIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		entry, err := findHistoryEntry(args[0])
+		if err != nil {
+			return err
+		}
+		return showHistoryEntry(os.Stdout, entry)
+	},
+}
+
+var historyRerunCmd = &cobra.Command{
+	Use:               "rerun [id]",
+	Short:             "Run a recorded script run again",
+	Long:              `Run the script again with the same arguments and flags, from the directory it was run in.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHistoryIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		entry, err := findHistoryEntry(args[0])
+		if err != nil {
+			return err
+		}
+		return rerunHistoryEntry(entry)
+	},
+}
+
+var historyFzfCmd = &cobra.Command{
+	Use:   "fzf",
+	Short: "Fuzzy search previous runs and rerun one",
+	Long: `Search previous runs by script name and arguments, newest first, and run the
+chosen one again, like Ctrl-R in a shell. When fzf is installed it is used,
+with each run's details and recorded output in the preview window. Otherwise
+berga asks for a search, offers the best matches, and shows the chosen run
+before asking whether to run it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if promptsDisabled() {
+			return fmt.Errorf("history fzf needs an interactive terminal")
+		}
+		return searchHistory(historyQuery)
+	},
+}
+
+var historyClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the recorded runs and their output",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return clearHistory()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyRerunCmd)
+	historyCmd.AddCommand(historyFzfCmd)
+	historyCmd.AddCommand(historyClearCmd)
+
+	// Flags
+	for _, cmd := range []*cobra.Command{historyCmd, historyListCmd} {
+		cmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Show at most this many runs (0 for all)")
+		cmd.Flags().StringVar(&historyScript, "script", "", "Only show runs of this script")
+		cmd.Flags().BoolVar(&historyFailed, "failed", false, "Only show runs that exited with an error")
+		cmd.Flags().StringVar(&historySince, "since", "", "Only show runs newer than a duration (30d, 12h) or date")
+	}
+	historyFzfCmd.Flags().StringVarP(&historyQuery, "query", "q", "", "Start with this search")
+}
+
+// historyEnabled reports whether script runs are recorded
+func historyEnabled() bool {
+	if !viper.IsSet("history.enabled") {
+		return true
+	}
+	return viper.GetBool("history.enabled")
+}
+
+// maxHistory returns how many runs the history keeps
+func maxHistory() int {
+	if viper.IsSet("history.limit") {
+		return viper.GetInt("history.limit")
+	}
+	return defaultHistoryLimit
+}
+
+// historyFlagArgs returns the flags given to this 'script run' for a rerun.
+// Arguments chosen from menus are recorded with the run, and protected
+// scripts ask for confirmation again.
+func historyFlagArgs(flags *pflag.FlagSet) []string {
+	return changedFlagArgs(flags, "background", "interactive-select-args", "confirm")
+}
+
+// tailBuffer keeps the last limit bytes written to it. Writes may come from
+// the stdout and stderr copiers at once.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf
+}
+
+// activeHistoryOutput collects the output of the current run for the
+// history when history.output is set; read by executeScript
+var activeHistoryOutput *tailBuffer
+
+// startHistoryRecording prepares to record the output of a run
+func startHistoryRecording() {
+	activeHistoryOutput = newHistoryOutput()
+}
+
+// newHistoryOutput returns a buffer for the output of a run when
+// history.output is on, and nil otherwise
+func newHistoryOutput() *tailBuffer {
+	if historyEnabled() && viper.GetBool("history.output") {
+		return &tailBuffer{limit: historyOutputLimit}
+	}
+	return nil
+}
+
+// recordRun adds a finished run to the history. Failing to record never
+// fails the run.
+func recordRun(scriptName string, args []string, runErr error, elapsed time.Duration) {
+	output := activeHistoryOutput
+	activeHistoryOutput = nil
+	recordRunOutput(scriptName, args, runErr, elapsed, output)
+}
+
+// recordRunOutput is recordRun for runs that collect their own output, such
+// as those started from the HTTP API or the dashboard
+func recordRunOutput(scriptName string, args []string, runErr error, elapsed time.Duration, output *tailBuffer) {
+	if !historyEnabled() {
+		return
+	}
+	dir, _ := os.Getwd()
+	entry := HistoryEntry{
+		Time:       time.Now().Add(-elapsed),
+		Script:     scriptName,
+		Args:       args,
+		Flags:      scriptHistoryFlags,
+		Dir:        dir,
+		ExitCode:   ExitCode(runErr),
+		DurationMS: elapsed.Milliseconds(),
+	}
+	if output != nil {
+		entry.Output = append([]byte{}, output.Bytes()...)
+	}
+	err := withStore(func(s store.Store) error {
+		_, err := s.AddRun(store.Run(entry), maxHistory())
+		return err
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: failed to record the run in the history: "+err.Error()))
+	}
+}
+
+// loadHistory reads every recorded run, oldest first
+func loadHistory() ([]HistoryEntry, error) {
+	return queryHistory(store.RunFilter{})
+}
+
+// queryHistory reads the recorded runs filter selects, oldest first
+func queryHistory(filter store.RunFilter) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	err := withStore(func(s store.Store) error {
+		runs, err := s.Runs(filter)
+		for _, run := range runs {
+			entries = append(entries, HistoryEntry(run))
+		}
+		return err
+	})
+	return entries, err
+}
+
+// findHistoryEntry looks up a run by its ID, with its recorded output
+func findHistoryEntry(id string) (HistoryEntry, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("invalid run ID '%s'", id)
+	}
+	var run store.Run
+	var found bool
+	err = withStore(func(s store.Store) error {
+		run, found, err = s.FindRun(n)
+		return err
+	})
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	if !found {
+		return HistoryEntry{}, fmt.Errorf("run %d not found (see 'berga history')", n)
+	}
+	return HistoryEntry(run), nil
+}
+
+// historyLine is how a run is listed: ID, time, status, and command
+func historyLine(entry HistoryEntry) string {
+	return fmt.Sprintf("%5d  %s  %s  %s", entry.ID, entry.Time.Local().Format("2006-01-02 15:04"), entry.status(8), entry.command())
+}
+
+func runListHistory(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	filter := store.RunFilter{Script: historyScript, Failed: historyFailed, Limit: historyLimit}
+	if historySince != "" {
+		var err error
+		if filter.Since, err = parseSince(historySince, time.Now()); err != nil {
+			return err
+		}
+	}
+	return listHistory(filter)
+}
+
+func listHistory(filter store.RunFilter) error {
+	shown, err := queryHistory(filter)
+	if err != nil {
+		return err
+	}
+	if len(shown) == 0 {
+		if recorded, err := queryHistory(store.RunFilter{Limit: 1}); err == nil && len(recorded) > 0 {
+			fmt.Println("No recorded runs match.")
+			return nil
+		}
+		fmt.Println("No runs recorded yet. Scripts you run with 'berga script run' show up here.")
+		return nil
+	}
+	for _, entry := range shown {
+		fmt.Println("  " + historyLine(entry))
+	}
+	return nil
+}
+
+func showHistoryEntry(w io.Writer, entry HistoryEntry) error {
+	fmt.Fprintf(w, "%s %s\n", ui.Bold("Run:"), entry.command())
+	fmt.Fprintf(w, "%s %s\n", ui.Bold("Started:"), entry.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "%s %s in %s\n", ui.Bold("Result:"), entry.status(0), formatDuration(float64(entry.DurationMS)/1000))
+	if entry.Dir != "" {
+		fmt.Fprintf(w, "%s %s\n", ui.Bold("Directory:"), entry.Dir)
+	}
+	if len(entry.Flags) > 0 {
+		fmt.Fprintf(w, "%s %s\n", ui.Bold("Flags:"), strings.Join(entry.Flags, " "))
+	}
+	if !entry.HasOutput {
+		fmt.Fprintln(w, ui.Dim("\nNo output recorded (set history.output to keep it)"))
+		return nil
+	}
+	output := entry.Output
+	fmt.Fprintln(w, ui.Bold("\nOutput:"))
+	w.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// rerunHistoryEntry runs a recorded run again through a new berga process,
+// so it goes through the same checks, hooks, and recording as the original
+func rerunHistoryEntry(entry HistoryEntry) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the berga executable: %w", err)
+	}
+	args := append([]string{"script", "run"}, entry.Flags...)
+	args = append(append(args, entry.Script, "--"), entry.Args...)
+
+	fmt.Fprintln(os.Stderr, ui.ErrDim("Running: berga script run "+entry.command()))
+	rerun := exec.Command(self, args...)
+	rerun.Stdin, rerun.Stdout, rerun.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if info, err := os.Stat(entry.Dir); err == nil && info.IsDir() {
+		rerun.Dir = entry.Dir
+	} else if entry.Dir != "" {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %s no longer exists; running in the current directory", entry.Dir)))
+	}
+	err = rerun.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// searchHistory picks a run by fuzzy search and runs it again
+func searchHistory(query string) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No runs recorded yet. Scripts you run with 'berga script run' show up here.")
+		return nil
+	}
+	// Newest first, like shell history search
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	var entry HistoryEntry
+	var ok bool
+	if fzf, lookErr := exec.LookPath("fzf"); lookErr == nil {
+		entry, ok, err = pickWithFzf(fzf, entries, query)
+	} else {
+		entry, ok, err = pickHistoryEntry(entries, query)
+	}
+	if err != nil || !ok {
+		return err
+	}
+	return rerunHistoryEntry(entry)
+}
+
+// pickWithFzf lets fzf choose a run, previewing each with 'history show'
+func pickWithFzf(fzf string, entries []HistoryEntry, query string) (HistoryEntry, bool, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("failed to find the berga executable: %w", err)
+	}
+	var input bytes.Buffer
+	for _, entry := range entries {
+		fmt.Fprintf(&input, "%d\t%s\n", entry.ID, ui.Dim(entry.Time.Local().Format("2006-01-02 15:04"))+"  "+entry.command())
+	}
+
+	preview := shellQuote(self)
+	if runtime.GOOS == "windows" {
+		// fzf runs the preview with cmd.exe there
+		preview = `"` + self + `"`
+	}
+	picker := exec.Command(fzf, "--ansi", "--no-sort", "--delimiter=\t", "--with-nth=2..", "--query="+query,
+		"--prompt=berga history> ", "--preview="+preview+" history show {1}", "--preview-window=down:60%")
+	picker.Stdin = &input
+	picker.Stderr = os.Stderr
+	out, err := picker.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// 1 is no match and 130 is Esc or Ctrl-C
+		if code := exitErr.ExitCode(); code == 1 || code == 130 {
+			return HistoryEntry{}, false, nil
+		}
+	}
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("fzf failed: %w", err)
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	entry, err := findHistoryEntry(id)
+	return entry, err == nil, err
+}
+
+// pickHistoryEntry is the picker for when fzf is not installed: search, pick
+// one of the best matches, look at it, and confirm
+func pickHistoryEntry(entries []HistoryEntry, query string) (HistoryEntry, bool, error) {
+	if query == "" {
+		var err error
+		if query, err = promptLine("Search runs", "", false); err != nil {
+			return HistoryEntry{}, false, err
+		}
+	}
+	matches := matchHistory(entries, query)
+	if len(matches) == 0 {
+		fmt.Fprintf(promptOut, "No runs match '%s'\n", query)
+		return HistoryEntry{}, false, nil
+	}
+	if len(matches) > historyPickerLimit {
+		matches = matches[:historyPickerLimit]
+	}
+
+	options := make([]string, len(matches))
+	for i, entry := range matches {
+		options[i] = historyLine(entry)
+	}
+	choice, err := promptSelect("Run", options, options[0])
+	if err != nil {
+		return HistoryEntry{}, false, err
+	}
+	for i, option := range options {
+		if option == choice {
+			fmt.Fprintln(promptOut)
+			showHistoryEntry(promptOut, matches[i])
+			fmt.Fprintln(promptOut)
+			return matches[i], confirm(promptOut, "Run it again?", true), nil
+		}
+	}
+	return HistoryEntry{}, false, fmt.Errorf("no run '%s'", choice)
+}
+
+// matchHistory returns the runs whose command matches query, best first and
+// newest first among equal matches
+func matchHistory(entries []HistoryEntry, query string) []HistoryEntry {
+	type match struct {
+		entry HistoryEntry
+		score int
+	}
+	var matches []match
+	for _, entry := range entries {
+		if score, ok := fuzzyScore(query, entry.command()); ok {
+			matches = append(matches, match{entry, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	result := make([]HistoryEntry, len(matches))
+	for i, m := range matches {
+		result[i] = m.entry
+	}
+	return result
+}
+
+func clearHistory() error {
+	err := withStore(func(s store.Store) error {
+		return s.ClearRuns()
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Cleared the run history")
+	return nil
+}
+
+// completeHistoryIDs completes run IDs, newest first, described by their
+// commands
+func completeHistoryIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, _ := loadHistory()
+	var ids []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		ids = append(ids, fmt.Sprintf("%d\t%s", entries[i].ID, entries[i].command()))
+	}
+	return ids, cobra.ShellCompDirectiveKeepOrder | cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRecordRunAndLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("history.output", true)
+	viper.Set("history.limit", 2)
+	defer viper.Set("history.output", nil)
+	defer viper.Set("history.limit", nil)
+
+	for i, args := range [][]string{{"one"}, {"two words"}, {"three"}} {
+		startHistoryRecording()
+		activeHistoryOutput.Write([]byte("output " + args[0] + "\n"))
+		var err error
+		if i == 1 {
+			err = &ExitError{Code: 2}
+		}
+		recordRun("backup.sh", args, err, time.Second)
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ID != 2 || entries[1].ID != 3 {
+		t.Fatalf("Expected runs 2 and 3 to be kept, got %+v", entries)
+	}
+	if entries[0].ExitCode != 2 || entries[0].command() != "backup.sh 'two words'" {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+
+	entry, err := findHistoryEntry("3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	showHistoryEntry(&out, entry)
+	if !strings.Contains(out.String(), "output three") {
+		t.Errorf("Expected the recorded output, got:\n%s", out.String())
+	}
+	if _, err := findHistoryEntry("1"); err == nil {
+		t.Error("Expected a dropped run not to be found")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{limit: 5}
+	b.Write([]byte("abc"))
+	b.Write([]byte("defg"))
+	if got := string(b.Bytes()); got != "cdefg" {
+		t.Errorf("Expected the last 5 bytes, got %q", got)
+	}
+}
+
+func TestPickHistoryEntry(t *testing.T) {
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	var out bytes.Buffer
+	promptOut = &out
+
+	entries := []HistoryEntry{
+		{ID: 3, Script: "deploy.sh", Args: []string{"--prod"}},
+		{ID: 2, Script: "backup.sh"},
+		{ID: 1, Script: "deploy.sh", Args: []string{"--staging"}},
+	}
+	if got := matchHistory(entries, "dep"); len(got) != 2 || got[0].ID != 3 {
+		t.Errorf("Expected the newest deploy first, got %+v", got)
+	}
+
+	// Search, take the second match, and confirm
+	stdinReader = bufio.NewReader(strings.NewReader("deploy\n2\ny\n"))
+	entry, ok, err := pickHistoryEntry(entries, "")
+	if err != nil || !ok || entry.ID != 1 {
+		t.Errorf("Expected run 1 to be picked, got %+v %v %v", entry, ok, err)
+	}
+
+	stdinReader = bufio.NewReader(strings.NewReader("nothing\n"))
+	if _, ok, err := pickHistoryEntry(entries, ""); ok || err != nil {
+		t.Errorf("Expected no pick without a match, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// lockedBundleName is the encrypted bundle 'berga lock' leaves in the data
+// directory
+const lockedBundleName = "locked.tar.gz.age"
+
+var lockPassphrase bool
+
+// lockCmd encrypts berga's content directories into a single bundle
+var lockCmd = &cobra.Command{
+	Use:   "lock [dir...]",
+	Short: "Encrypt scripts, templates, and other content into one bundle",
+	Long: `Pack berga's content directories (scripts, templates, snippets, notes,
+dotfiles, requests, archived scripts and versions), or just the ones named,
+into a single age-encrypted bundle and remove the plain files, so berga can
+live on a shared or backed-up machine. 'berga unlock' restores them.
+
+The bundle is encrypted to the age identity in secrets.age_identity, which
+can live in the OS keychain, or with --passphrase to a passphrase that age
+asks for:
+
+  berga lock
+  berga lock scripts notes --passphrase
+  berga unlock
+
+Config files are not locked. Dotfiles linked from your home directory point
+at nothing while the dotfiles directory is locked. Requires age 1.1 or later.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return lockHome(args, lockPassphrase)
+	},
+}
+
+// unlockCmd restores the content encrypted by 'berga lock'
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Decrypt the bundle written by 'berga lock'",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return unlockHome()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(unlockCmd)
+
+	// Flags
+	lockCmd.Flags().BoolVar(&lockPassphrase, "passphrase", false, "Encrypt with a passphrase instead of the age identity")
+}
+
+// lockedBundlePath returns where the encrypted bundle is kept
+func lockedBundlePath() string {
+	return filepath.Join(GetDataDir(), lockedBundleName)
+}
+
+// lockableDirs returns the content directories under the data directory
+// that exist and are selected by names (all of them when names is empty)
+func lockableDirs(names []string) ([]string, error) {
+	known := map[string]bool{"archive": true}
+	for name := range dataDirNames {
+		known[name] = true
+	}
+
+	selected := names
+	if len(selected) == 0 {
+		for name := range known {
+			selected = append(selected, name)
+		}
+	}
+
+	var dirs []string
+	for _, name := range selected {
+		name = strings.TrimSuffix(filepath.ToSlash(name), "/")
+		if !known[name] {
+			return nil, fmt.Errorf("cannot lock '%s' (expected one of: %s)", name, strings.Join(sortedKeys(known), ", "))
+		}
+		info, err := os.Stat(filepath.Join(GetDataDir(), name))
+		if err != nil || !info.IsDir() {
+			if len(names) > 0 {
+				return nil, fmt.Errorf("'%s' not found in %s", name, GetDataDir())
+			}
+			continue
+		}
+		dirs = append(dirs, name)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// packDirs writes dirs, relative to root, to a tar.gz archive. Only
+// directories and regular files can be packed.
+func packDirs(root string, dirs []string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, dir := range dirs {
+		err := filepath.Walk(filepath.Join(root, dir), func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			rel = filepath.ToSlash(rel)
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				return fmt.Errorf("cannot lock %s: not a regular file", rel)
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			if info.IsDir() {
+				hdr.Name += "/"
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bundleDirs returns the top-level directories in a packed archive
+func bundleDirs(data []byte) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	err := walkBundle(data, func(hdr *tar.Header, rel string, r io.Reader) error {
+		first, _, _ := strings.Cut(rel, "/")
+		if !seen[first] {
+			seen[first] = true
+			dirs = append(dirs, first)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// walkBundle calls fn for each entry of a packed archive, with its cleaned
+// relative path
+func walkBundle(data []byte, fn func(hdr *tar.Header, rel string, r io.Reader) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		rel, err := safeArchivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, rel, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// unpackDirs extracts a packed archive under root. Nothing is written if any
+// of its directories already exists.
+func unpackDirs(data []byte, root string) ([]string, error) {
+	dirs, err := bundleDirs(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if _, err := os.Lstat(filepath.Join(root, dir)); err == nil {
+			return nil, fmt.Errorf("%s already exists; move it aside before unlocking", filepath.Join(root, dir))
+		}
+	}
+
+	err = walkBundle(data, func(hdr *tar.Header, rel string, r io.Reader) error {
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(target, mode|0700)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, r); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack bundle: %w", err)
+	}
+	return dirs, nil
+}
+
+// runAgeTerminal runs age with its passphrase prompt on the terminal
+func runAgeTerminal(stdin []byte, args ...string) ([]byte, error) {
+	agePath, err := exec.LookPath("age")
+	if err != nil {
+		return nil, fmt.Errorf("age is not installed (needed for 'berga lock', see https://age-encryption.org)")
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(agePath, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run age: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// isPassphraseBundle reports whether an age file was encrypted with a
+// passphrase, which age records as an scrypt stanza in the header
+func isPassphraseBundle(ciphertext []byte) bool {
+	header := ciphertext
+	if i := bytes.Index(header, []byte("\n---")); i >= 0 {
+		header = header[:i]
+	}
+	return bytes.Contains(header, []byte("\n-> scrypt "))
+}
+
+func lockHome(names []string, passphrase bool) error {
+	bundle := lockedBundlePath()
+	if _, err := os.Stat(bundle); err == nil {
+		return fmt.Errorf("berga is already locked (%s); run 'berga unlock' first", bundle)
+	}
+
+	dirs, err := lockableDirs(names)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("nothing to lock in %s", GetDataDir())
+	}
+
+	plain, err := packDirs(GetDataDir(), dirs)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %w", strings.Join(dirs, ", "), err)
+	}
+
+	var ciphertext []byte
+	if passphrase {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("--passphrase needs a terminal to ask for the passphrase")
+		}
+		ciphertext, err = runAgeTerminal(plain, "--encrypt", "--passphrase")
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+	} else {
+		identity, err := ageIdentity()
+		if err != nil {
+			return err
+		}
+		recipient, err := runAge("age-keygen", []byte(identity+"\n"), "-y")
+		if err != nil {
+			return fmt.Errorf("failed to read the age identity: %w", err)
+		}
+		ciphertext, err = runAge("age", plain, "--encrypt", "--recipient", strings.TrimSpace(string(recipient)))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		// Make sure the bundle can be opened again before removing anything
+		check, err := decryptBundleWith(identity, ciphertext)
+		if err != nil || !bytes.Equal(check, plain) {
+			return fmt.Errorf("failed to verify the encrypted bundle; nothing was removed")
+		}
+	}
+
+	if err := writeFileAtomic(bundle, ciphertext, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", bundle, err)
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(filepath.Join(GetDataDir(), dir)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	fmt.Printf("Locked %s into %s\n", strings.Join(dirs, ", "), bundle)
+	for _, dir := range dirs {
+		if dir == "dotfiles" {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: linked dotfiles are unavailable until 'berga unlock'"))
+		}
+	}
+	return nil
+}
+
+// decryptBundleWith decrypts a bundle with an age identity, passed on stdin
+// so it never touches the disk
+func decryptBundleWith(identity string, ciphertext []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "berga-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(ciphertext); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	tmp.Close()
+	return runAge("age", []byte(identity+"\n"), "--decrypt", "--identity", "-", tmp.Name())
+}
+
+func unlockHome() error {
+	bundle := lockedBundlePath()
+	ciphertext, err := os.ReadFile(bundle)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("berga is not locked (no %s)", bundle)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", bundle, err)
+	}
+
+	var plain []byte
+	if isPassphraseBundle(ciphertext) {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("the bundle is passphrase-protected; run 'berga unlock' in a terminal")
+		}
+		plain, err = runAgeTerminal(nil, "--decrypt", bundle)
+	} else {
+		identity, idErr := ageIdentity()
+		if idErr != nil {
+			return idErr
+		}
+		plain, err = decryptBundleWith(identity, ciphertext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", bundle, err)
+	}
+
+	dirs, err := unpackDirs(plain, GetDataDir())
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(bundle); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", bundle, err)
+	}
+	fmt.Printf("Unlocked %s\n", strings.Join(dirs, ", "))
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockableDirs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "deploy.sh", "#!/bin/sh\n")
+	if err := os.MkdirAll(GetSnippetsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := lockableDirs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(dirs, ",") != "scripts,snippets" {
+		t.Errorf("Expected only existing content directories, got %v", dirs)
+	}
+	if _, err := lockableDirs([]string{"notes"}); err == nil {
+		t.Error("Expected a missing directory to be an error when named")
+	}
+	if _, err := lockableDirs([]string{"profiles"}); err == nil {
+		t.Error("Expected a directory that is not content to be rejected")
+	}
+}
+
+func TestPackUnpackDirs(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "scripts", "ops"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "scripts", "ops", "deploy.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := packDirs(src, []string{"notes", "scripts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	dirs, err := unpackDirs(data, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(dirs, ",") != "notes,scripts" {
+		t.Errorf("Unexpected directories %v", dirs)
+	}
+	info, err := os.Stat(filepath.Join(dst, "scripts", "ops", "deploy.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the script to be restored with its mode, got %v, %v", info, err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "notes")); err != nil || !info.IsDir() {
+		t.Error("Expected the empty notes directory to be restored")
+	}
+
+	if _, err := unpackDirs(data, dst); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected existing directories to stop the unpack, got %v", err)
+	}
+}
+
+func TestUnpackDirsRejectsEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "../evil.sh", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hi"))
+	tw.Close()
+	gz.Close()
+
+	dst := t.TempDir()
+	if _, err := unpackDirs(buf.Bytes(), filepath.Join(dst, "data")); err == nil {
+		t.Error("Expected a path outside the data directory to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "evil.sh")); err == nil {
+		t.Error("Expected nothing to be written outside the data directory")
+	}
+}
+
+func TestIsPassphraseBundle(t *testing.T) {
+	passphrase := []byte("age-encryption.org/v1\n-> scrypt abc 18\nxyz\n--- mac\nbody")
+	recipient := []byte("age-encryption.org/v1\n-> X25519 abc\nxyz\n--- mac\n-> scrypt in the body")
+	if !isPassphraseBundle(passphrase) || isPassphraseBundle(recipient) {
+		t.Error("Expected only an scrypt stanza in the header to mean a passphrase")
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Events that configured hooks attach to
+const (
+	hookPreScriptRun      = "pre_script_run"
+	hookPostScriptRun     = "post_script_run"
+	hookPreTemplateApply  = "pre_template_apply"
+	hookPostTemplateApply = "post_template_apply"
+)
+
+// hookDepthEnv marks commands started by a hook, so a hook that runs berga
+// does not set off the same hooks again
+const hookDepthEnv = "BERGA_IN_HOOK"
+
+// hookCommands reads a hook setting, which is one command or a list of them
+func hookCommands(value interface{}) []string {
+	var commands []string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		commands = []string{v}
+	default:
+		commands = cast.ToStringSlice(v)
+	}
+	var nonEmpty []string
+	for _, command := range commands {
+		if strings.TrimSpace(command) != "" {
+			nonEmpty = append(nonEmpty, command)
+		}
+	}
+	return nonEmpty
+}
+
+// configuredHooks returns the commands for an event: the global ones under
+// hooks.<event>, then those of the item under hooks.scripts.<name> or
+// hooks.templates.<name>. An item matches by any of names, ignoring case.
+func configuredHooks(event, kind string, names ...string) []string {
+	commands := hookCommands(viper.Get("hooks." + event))
+	for key, value := range viper.GetStringMap("hooks." + kind + "s") {
+		for _, name := range names {
+			if name != "" && strings.EqualFold(key, name) {
+				commands = append(commands, hookCommands(cast.ToStringMap(value)[event])...)
+				break
+			}
+		}
+	}
+	return commands
+}
+
+// runConfiguredHooks runs the hooks for an event with context in env, in
+// order. BERGA_EVENT names the event. The first failing hook stops the rest
+// and is returned; hooks started from inside a hook are not run.
+func runConfiguredHooks(event, kind string, names []string, env []string) error {
+	if os.Getenv(hookDepthEnv) != "" {
+		return nil
+	}
+	commands := configuredHooks(event, kind, names...)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	env = append(append(os.Environ(), env...), "BERGA_EVENT="+event, hookDepthEnv+"=1")
+	for _, command := range commands {
+		if viper.GetBool("verbose") {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", ui.ErrDim(ui.Icon("🪝", ">")), event, command)
+		}
+		cmd := hookCommand(command)
+		cmd.Env = env
+		cmd.Stdin = os.Stdin
+		// Keep stdout for the script or rendered template
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %w", event, command, err)
+		}
+	}
+	return nil
+}
+
+// warnHookFailure reports a failed post hook without changing the result of
+// what it followed
+func warnHookFailure(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %v", err)))
+	}
+}
+
+// scriptHookNames are the names a script's hooks may be configured under:
+// its file name and the name without extension
+func scriptHookNames(scriptPath string) []string {
+	base := strings.TrimSuffix(filepath.Base(scriptPath), encryptedExt)
+	return []string{base, strings.TrimSuffix(base, filepath.Ext(base))}
+}
+
+// scriptHookEnv is the context passed to script hooks
+func scriptHookEnv(scriptPath string, args []string) []string {
+	return []string{
+		"BERGA_SCRIPT=" + filepath.Base(scriptPath),
+		"BERGA_SCRIPT_PATH=" + scriptPath,
+		"BERGA_ARGS=" + strings.Join(args, " "),
+	}
+}
+
+// scriptResultEnv adds a finished run's outcome to the hook context
+func scriptResultEnv(env []string, err error, elapsed time.Duration) []string {
+	env = append(env,
+		"BERGA_EXIT_CODE="+strconv.Itoa(ExitCode(err)),
+		"BERGA_DURATION_MS="+strconv.FormatInt(elapsed.Milliseconds(), 10),
+	)
+	if err != nil {
+		env = append(env, "BERGA_ERROR="+err.Error())
+	}
+	return env
+}
+
+// templateHookNames are the names a template's hooks may be configured under
+func templateHookNames(templateName string) []string {
+	return []string{templateName, strings.TrimSuffix(templateName, ".tmpl")}
+}
+
+// templateHookEnv is the context passed to template hooks. The output is an
+// absolute path, or "-" for stdout.
+func templateHookEnv(templateName, templatePath, outputFile string) []string {
+	output := outputFile
+	if output != "-" {
+		if abs, err := filepath.Abs(outputFile); err == nil {
+			output = abs
+		}
+	}
+	return []string{
+		"BERGA_TEMPLATE=" + templateName,
+		"BERGA_TEMPLATE_PATH=" + templatePath,
+		"BERGA_OUTPUT=" + output,
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfiguredHooks(t *testing.T) {
+	viper.Set("hooks", map[string]interface{}{
+		"pre_script_run": "echo global",
+		"scripts": map[string]interface{}{
+			"Deploy": map[string]interface{}{
+				"pre_script_run":  []interface{}{"./check-branch.sh", ""},
+				"post_script_run": "notify",
+			},
+		},
+	})
+	defer viper.Set("hooks", nil)
+
+	got := configuredHooks(hookPreScriptRun, "script", scriptHookNames("/s/deploy.sh")...)
+	if strings.Join(got, "|") != "echo global|./check-branch.sh" {
+		t.Errorf("Expected the global hook, then the script's, got %q", got)
+	}
+	if got := configuredHooks(hookPreScriptRun, "script", scriptHookNames("/s/lint.sh")...); len(got) != 1 {
+		t.Errorf("Expected only the global hook for another script, got %q", got)
+	}
+	if got := configuredHooks(hookPostTemplateApply, "template", "deploy"); len(got) != 0 {
+		t.Errorf("Expected script hooks not to apply to templates, got %q", got)
+	}
+}
+
+func TestRunConfiguredHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	log := filepath.Join(t.TempDir(), "log")
+	viper.Set("hooks", map[string]interface{}{
+		"post_script_run": []interface{}{
+			`echo "$BERGA_EVENT $BERGA_SCRIPT $BERGA_EXIT_CODE" >> ` + log,
+			"exit 3",
+			"echo never >> " + log,
+		},
+	})
+	defer viper.Set("hooks", nil)
+
+	env := scriptResultEnv(scriptHookEnv("/s/deploy.sh", nil), &ExitError{Code: 2}, 0)
+	err := runConfiguredHooks(hookPostScriptRun, "script", scriptHookNames("/s/deploy.sh"), env)
+	if err == nil || !strings.Contains(err.Error(), "post_script_run hook 'exit 3' failed") {
+		t.Errorf("Expected the failing hook to be reported, got %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	if string(data) != "post_script_run deploy.sh 2\n" {
+		t.Errorf("Expected one hook run with context, got %q", data)
+	}
+
+	// Hooks do not fire for commands started by a hook
+	t.Setenv(hookDepthEnv, "1")
+	if err := runConfiguredHooks(hookPostScriptRun, "script", nil, env); err != nil {
+		t.Errorf("Expected hooks to be skipped inside a hook, got %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+	"berga/pkg/templates"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultHTTPTimeout bounds requests that do not set their own timeout
+const defaultHTTPTimeout = 30 * time.Second
+
+// maskedSecret stands in for secrets when a request is only printed
+const maskedSecret = "********"
+
+var (
+	httpVars    []string
+	httpInclude bool
+	httpDryRun  bool
+)
+
+// HTTPRequest is a saved request in the requests directory. Every field is a
+// template rendered with the template variables, --var values, and the
+// secret function: {{secret "api_token"}} reads secrets.api_token.
+type HTTPRequest struct {
+	Method  string            `yaml:"method,omitempty"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Timeout string            `yaml:"timeout,omitempty"`
+}
+
+// httpRequestSkeleton is the starting point for 'http edit' on a new request
+const httpRequestSkeleton = `# berga http request. Fields are templates: {{.Author}}, {{.Var}} from
+# --var Var=value, and {{secret "name"}} for secrets.name.
+method: GET
+url: https://api.example.com/
+headers:
+  Accept: application/json
+#  Authorization: Bearer {{secret "api_token"}}
+#body: |
+#  {"name": "{{.Name}}"}
+#timeout: 30s
+`
+
+// httpCmd represents the http command
+var httpCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Run saved HTTP requests",
+	Long: `Save HTTP requests by name and run them from the command line. Requests are
+YAML files in the requests directory:
+
+  method: POST
+  url: https://auth.example.com/token
+  headers:
+    Content-Type: application/json
+  body: |
+    {"client_id": "{{.ClientID}}", "secret": "{{secret "client_secret"}}"}
+
+Each field is a template with the usual template variables, values given with
+--var, and {{secret "name"}} for secrets stored with 'berga config set'.`,
+}
+
+var httpRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Send a saved HTTP request",
+	Long: `Send a saved request and print the response body. The command fails when the
+response status is not 2xx. --include prints the status line and headers
+first; --dry-run prints the rendered request, with secrets masked, instead of
+sending it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runHTTPRequest(args[0], httpVars, os.Stdout)
+	},
+}
+
+var httpListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List saved HTTP requests",
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listHTTPRequests()
+	},
+}
+
+var httpEditCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Create or edit a saved HTTP request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editHTTPRequest(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(httpCmd)
+	httpCmd.AddCommand(httpRunCmd)
+	httpCmd.AddCommand(httpListCmd)
+	httpCmd.AddCommand(httpEditCmd)
+
+	// Flags
+	httpRunCmd.Flags().StringArrayVar(&httpVars, "var", nil, "Set a template variable as NAME=VALUE (repeatable)")
+	httpRunCmd.Flags().BoolVarP(&httpInclude, "include", "i", false, "Print the response status line and headers")
+	httpRunCmd.Flags().BoolVar(&httpDryRun, "dry-run", false, "Print the rendered request instead of sending it")
+}
+
+// httpRequestPath returns the file of a saved request
+func httpRequestPath(name string) string {
+	return filepath.Join(GetRequestsDir(), strings.TrimSuffix(name, ".yaml")+".yaml")
+}
+
+// loadHTTPRequest reads a saved request
+func loadHTTPRequest(name string) (*HTTPRequest, error) {
+	data, err := os.ReadFile(httpRequestPath(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("request '%s' not found; create it with 'berga http edit %s'", name, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	var req HTTPRequest
+	if err := yaml.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request '%s': %w", name, err)
+	}
+	if req.URL == "" {
+		return nil, fmt.Errorf("request '%s' has no url", name)
+	}
+	if req.Timeout != "" {
+		if _, err := time.ParseDuration(req.Timeout); err != nil {
+			return nil, fmt.Errorf("request '%s' has an invalid timeout '%s'", name, req.Timeout)
+		}
+	}
+	return &req, nil
+}
+
+// httpTemplateVars returns the variables for rendering a request: the
+// template defaults and config variables, then --var values
+func httpTemplateVars(assignments []string) (map[string]interface{}, error) {
+	vars, err := collectTemplateVars(nil, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assignments {
+		key, value, found := strings.Cut(assignment, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --var '%s' (expected NAME=VALUE)", assignment)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// renderHTTPRequest renders every field of a request. With mask set, secrets
+// are replaced by a placeholder so the result is safe to print.
+func renderHTTPRequest(name string, req *HTTPRequest, vars map[string]interface{}, mask bool) (*HTTPRequest, error) {
+	funcs := template.FuncMap{
+		"secret": func(key string) (string, error) {
+			if !viper.IsSet("secrets." + key) {
+				return "", fmt.Errorf("secret '%s' is not set (store it with 'berga config set secrets.%s')", key, key)
+			}
+			if mask {
+				return maskedSecret, nil
+			}
+			auditSecretAccess("secrets." + key)
+			return viper.GetString("secrets." + key), nil
+		},
+	}
+	for name, fn := range hostTemplatePlatform().funcs() {
+		funcs[name] = fn
+	}
+	render := func(field, text string) (string, error) {
+		tmpl, err := templates.TextRenderer{Funcs: funcs, Strict: true}.Parse(name+" "+field, text)
+		if err != nil {
+			return "", fmt.Errorf("invalid template in %s: %w", field, err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, vars); err != nil {
+			return "", fmt.Errorf("failed to render %s: %w", field, err)
+		}
+		return out.String(), nil
+	}
+
+	rendered := &HTTPRequest{Headers: make(map[string]string), Timeout: req.Timeout}
+	var err error
+	if rendered.Method, err = render("method", req.Method); err != nil {
+		return nil, err
+	}
+	rendered.Method = strings.ToUpper(strings.TrimSpace(rendered.Method))
+	if rendered.Method == "" {
+		rendered.Method = http.MethodGet
+	}
+	if rendered.URL, err = render("url", req.URL); err != nil {
+		return nil, err
+	}
+	rendered.URL = strings.TrimSpace(rendered.URL)
+	for key, value := range req.Headers {
+		if rendered.Headers[key], err = render("header "+key, value); err != nil {
+			return nil, err
+		}
+	}
+	if rendered.Body, err = render("body", req.Body); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// printHTTPRequest writes a rendered request in HTTP message form
+func printHTTPRequest(w io.Writer, req *HTTPRequest) {
+	fmt.Fprintf(w, "%s %s\n", req.Method, req.URL)
+	keys := make([]string, 0, len(req.Headers))
+	for k := range req.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s: %s\n", k, req.Headers[k])
+	}
+	if req.Body != "" {
+		fmt.Fprintf(w, "\n%s", req.Body)
+		if !strings.HasSuffix(req.Body, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// sendHTTPRequest sends a rendered request and writes the response to out
+func sendHTTPRequest(req *HTTPRequest, include bool, out io.Writer) error {
+	timeout := defaultHTTPTimeout
+	if req.Timeout != "" {
+		timeout, _ = time.ParseDuration(req.Timeout)
+	}
+
+	var body io.Reader
+	if req.Body != "" {
+		body = strings.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", "berga/"+rootCmd.Version)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if include {
+		fmt.Fprintf(out, "%s %s\n", resp.Proto, resp.Status)
+		if err := resp.Header.Write(out); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
+
+func runHTTPRequest(name string, assignments []string, out io.Writer) error {
+	req, err := loadHTTPRequest(name)
+	if err != nil {
+		return err
+	}
+	vars, err := httpTemplateVars(assignments)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderHTTPRequest(name, req, vars, httpDryRun)
+	if err != nil {
+		return err
+	}
+
+	if httpDryRun {
+		printHTTPRequest(out, rendered)
+		return nil
+	}
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "%s %s\n", rendered.Method, rendered.URL)
+	}
+	return sendHTTPRequest(rendered, httpInclude, out)
+}
+
+// httpRequestNames returns the names of the saved requests, sorted
+func httpRequestNames() ([]string, error) {
+	entries, err := os.ReadDir(GetRequestsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read requests directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func listHTTPRequests() error {
+	names, err := httpRequestNames()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("No saved requests found.")
+		fmt.Println("Create one with: berga http edit <name>")
+		return nil
+	}
+
+	listHeader(i18n.T("header.http_requests"))
+	for _, name := range names {
+		req, err := loadHTTPRequest(name)
+		if err != nil {
+			fmt.Printf("  %s %s\n", ui.Bold(name), ui.Red(err.Error()))
+			continue
+		}
+		method := req.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		fmt.Printf("  %s %s %s\n", ui.Bold(name), ui.Cyan(strings.ToUpper(method)), req.URL)
+	}
+	return nil
+}
+
+// editHTTPRequest opens a saved request in the editor, starting new ones
+// from a skeleton, and checks that the result still loads
+func editHTTPRequest(name string) error {
+	path := httpRequestPath(name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create requests directory: %w", err)
+		}
+		if err := writeFileAtomic(path, []byte(httpRequestSkeleton), 0600); err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+	}
+
+	editor := preferredEditor()
+	fmt.Printf("Opening %s with %s...\n", path, editor)
+	if err := editorCommand(editor, path).Run(); err != nil {
+		return err
+	}
+
+	if _, err := loadHTTPRequest(name); err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %v", err)))
+	}
+	return nil
+}
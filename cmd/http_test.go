@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeHTTPRequest(t *testing.T, name, content string) {
+	t.Helper()
+	path := httpRequestPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderHTTPRequest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("secrets.api_token", "s3cret")
+	defer viper.Set("secrets", nil)
+
+	req := &HTTPRequest{
+		Method:  "post",
+		URL:     "https://api.example.com/users/{{.User}}",
+		Headers: map[string]string{"Authorization": `Bearer {{secret "api_token"}}`},
+		Body:    `{"name": "{{.User}}"}`,
+	}
+	vars := map[string]interface{}{"User": "ada"}
+
+	got, err := renderHTTPRequest("create-user", req, vars, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != "POST" || got.URL != "https://api.example.com/users/ada" || got.Body != `{"name": "ada"}` {
+		t.Errorf("Unexpected rendered request: %+v", got)
+	}
+	if got.Headers["Authorization"] != "Bearer s3cret" {
+		t.Errorf("Expected the secret in the header, got %q", got.Headers["Authorization"])
+	}
+
+	masked, err := renderHTTPRequest("create-user", req, vars, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.Headers["Authorization"] != "Bearer "+maskedSecret {
+		t.Errorf("Expected the secret to be masked, got %q", masked.Headers["Authorization"])
+	}
+
+	if _, err := renderHTTPRequest("x", &HTTPRequest{URL: "{{.Missing}}"}, vars, false); err == nil {
+		t.Error("Expected an error for an undefined variable")
+	}
+	if _, err := renderHTTPRequest("x", &HTTPRequest{URL: `{{secret "nope"}}`}, vars, false); err == nil {
+		t.Error("Expected an error for a missing secret")
+	}
+}
+
+func TestRunHTTPRequest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Test", "yes")
+		io.WriteString(w, r.Method+" "+r.Header.Get("X-Id")+" "+string(body))
+	}))
+	defer server.Close()
+
+	writeHTTPRequest(t, "echo", "method: PUT\nurl: "+server.URL+"/echo\nheaders:\n  X-Id: \"{{.Id}}\"\nbody: hello\n")
+	writeHTTPRequest(t, "missing", "url: "+server.URL+"/missing\n")
+
+	var out bytes.Buffer
+	if err := runHTTPRequest("echo", []string{"Id=42"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "PUT 42 hello" {
+		t.Errorf("Unexpected response body %q", out.String())
+	}
+
+	httpInclude = true
+	out.Reset()
+	err := runHTTPRequest("missing", nil, &out)
+	httpInclude = false
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a 404 error, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "HTTP/1.1 404 Not Found\n") {
+		t.Errorf("Expected the status line with --include, got %q", out.String())
+	}
+
+	if err := runHTTPRequest("echo", []string{"Id"}, &out); err == nil {
+		t.Error("Expected an error for a --var without a value")
+	}
+	if err := runHTTPRequest("nope", nil, &out); err == nil {
+		t.Error("Expected an error for an unknown request")
+	}
+}
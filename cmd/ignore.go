@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ignoreFileName is the file in a scripts or templates directory listing
+// entries to hide from listings and completion, in gitignore syntax
+const ignoreFileName = ".bergaignore"
+
+// defaultIgnoreRules are written to new scripts and templates directories by
+// 'config init'
+const defaultIgnoreRules = `# Hidden from 'berga script list', 'template list', and completion
+.DS_Store
+Thumbs.db
+*~
+*.swp
+*.swo
+.#*
+`
+
+// ignoreRule is one line of an ignore file
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnoreRules reads gitignore-style rules. Listings are flat, so a
+// pattern only has to match an entry's name: a leading "/" or "**/" is
+// dropped, and patterns that still contain a slash never match.
+func parseIgnoreRules(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		line = strings.TrimPrefix(strings.TrimPrefix(line, "/"), "**/")
+		if line == "" || strings.Contains(line, "/") {
+			continue
+		}
+		rule.pattern = strings.ReplaceAll(line, "**", "*")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether rules hide an entry. As in gitignore, the last
+// matching rule wins.
+func ignored(rules []ignoreRule, name string, isDir bool) bool {
+	hidden := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if ok, _ := filepath.Match(rule.pattern, name); ok {
+			hidden = !rule.negate
+		}
+	}
+	return hidden
+}
+
+// loadIgnoreRules reads the ignore file in dir. A missing or unreadable file
+// hides nothing.
+func loadIgnoreRules(dir string) []ignoreRule {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		return nil
+	}
+	return parseIgnoreRules(data)
+}
+
+// readListing reads dir like os.ReadDir, leaving out the ignore file and the
+// entries it matches. Unchanged directories are read from the index.
+func readListing(dir string) ([]os.DirEntry, error) {
+	entries, rules, err := listDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name() == ignoreFileName || ignored(rules, entry.Name(), entry.IsDir()) {
+			continue
+		}
+		visible = append(visible, entry)
+	}
+	return visible, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{scriptRunCmd, scriptEditCmd, scriptShowCmd} {
+		c.ValidArgsFunction = completeScriptNames
+	}
+	for _, c := range []*cobra.Command{templateApplyCmd, templateEditCmd, templateShowCmd} {
+		c.ValidArgsFunction = completeTemplateNames
+	}
+}
+
+// completeScriptNames completes the script argument of a command. Later
+// arguments, passed to the script, complete as files.
+func completeScriptNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	dirs := GetScriptsDirs()
+	if dir := GetProjectScriptsDir(); dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+	names := completeNames(dirs, toComplete, func(name string) (string, bool) {
+		if isScriptSpecFile(name) {
+			return "", false
+		}
+		return name, true
+	})
+	// Pinned scripts are offered first
+	pinned := pinnedScripts()
+	var first, rest []string
+	for _, name := range names {
+		if pinned[name] {
+			first = append(first, name)
+		} else {
+			rest = append(rest, name)
+		}
+	}
+	return append(first, rest...), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeTemplateNames completes the template argument of a command. Later
+// arguments, such as the output file, complete as files.
+func completeTemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeNames(GetTemplatesDirs(), toComplete, func(name string) (string, bool) {
+		if isSchemaFile(name) {
+			return "", false
+		}
+		return strings.TrimSuffix(name, ".tmpl"), true
+	}), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNames lists the names in dirs starting with prefix, once each.
+// display maps a file name to the name to offer, or rejects it.
+func completeNames(dirs []string, prefix string, display func(string) (string, bool)) []string {
+	refreshListings(dirs)
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range dirs {
+		files, err := readListing(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name, ok := display(file.Name())
+			if !ok || file.IsDir() || seen[name] || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]byte("# editor files\n*.swp\n.DS_Store\n/_*\n!_keep.sh\nhelpers/\nsub/dir.sh\n\\#notes\n"))
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"deploy.sh.swp", false, true},
+		{".DS_Store", false, true},
+		{"_internal.sh", false, true},
+		{"_keep.sh", false, false},
+		{"helpers", true, true},
+		{"helpers", false, false},
+		{"dir.sh", false, false},
+		{"#notes", false, true},
+		{"deploy.sh", false, false},
+	}
+	for _, tt := range tests {
+		if got := ignored(rules, tt.name, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestReadListing(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"build.sh", "build.sh.swp", ".DS_Store", ignoreFileName} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.WriteFile(filepath.Join(dir, ignoreFileName), []byte(defaultIgnoreRules), 0644)
+
+	entries, err := readListing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"build.sh"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("readListing() = %v, want %v", names, want)
+	}
+}
+
+func TestCompleteTemplateNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"gitignore.tmpl", "go.mod.tmpl", "go.mod.vars.yaml", "draft.tmpl"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.WriteFile(filepath.Join(dir, ignoreFileName), []byte("draft*\n"), 0644)
+
+	viper.Set("paths.templates", dir)
+	defer viper.Set("paths.templates", nil)
+
+	names, _ := completeTemplateNames(nil, nil, "g")
+	if want := []string{"gitignore", "go.mod"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("completeTemplateNames() = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// indexVersion is bumped when the layout of index.json changes; an index
+// of another version is rebuilt
+const indexVersion = 1
+
+// indexRacyWindow is how close to its indexing a directory may have changed
+// and still be trusted. A directory changed again within the same mtime tick
+// keeps its mtime, so listings that recent are read again.
+const indexRacyWindow = 2 * time.Second
+
+// listingIndex caches the entries of the scripts, templates, snippets, and
+// notes directories, so listings and completion don't read every directory
+// on each run. A directory's entries are reused while its mtime, which
+// changes when entries are added, removed, or renamed, and its ignore file
+// are unchanged.
+type listingIndex struct {
+	Version int                    `json:"version"`
+	Dirs    map[string]*indexedDir `json:"dirs"`
+}
+
+// indexedDir is the cached listing of one directory
+type indexedDir struct {
+	ModTime       time.Time      `json:"mtime"`
+	IgnoreModTime time.Time      `json:"ignore_mtime,omitempty"`
+	Ignore        string         `json:"ignore,omitempty"`
+	IndexedAt     time.Time      `json:"indexed_at"`
+	Entries       []indexedEntry `json:"entries"`
+}
+
+// indexedEntry is one directory entry, with its type bits only; size and
+// times change without touching the directory, so Info reads them fresh
+type indexedEntry struct {
+	Name string      `json:"name"`
+	Mode fs.FileMode `json:"mode"`
+}
+
+// indexDirEntry serves an indexedEntry as an os.DirEntry
+type indexDirEntry struct {
+	dir   string
+	entry indexedEntry
+}
+
+func (e indexDirEntry) Name() string      { return e.entry.Name }
+func (e indexDirEntry) IsDir() bool       { return e.entry.Mode.IsDir() }
+func (e indexDirEntry) Type() fs.FileMode { return e.entry.Mode.Type() }
+func (e indexDirEntry) Info() (fs.FileInfo, error) {
+	return os.Lstat(filepath.Join(e.dir, e.entry.Name))
+}
+func (e indexDirEntry) String() string { return fs.FormatDirEntry(e) }
+
+var (
+	indexMu sync.Mutex
+	// loadedIndex is the index read from indexPath in this run
+	loadedIndex *listingIndex
+	indexPath   string
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Show the cached index of scripts and templates",
+	Long: `Show the directories in the listing index and whether each is up to date.
+
+berga keeps the entries of the scripts, templates, snippets, and notes
+directories in ~/.berga/index.json, so 'list', 'search', the dashboard, and tab
+completion don't read every search path on each run. A directory is read again
+when its modification time or its .bergaignore changes. Set index.enabled to
+false to always read the directories.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return showIndex()
+	},
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Read every indexed directory again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		os.Remove(GetIndexFile())
+		indexMu.Lock()
+		loadedIndex = nil
+		indexMu.Unlock()
+
+		dirs := indexedSourceDirs()
+		refreshListings(dirs)
+		fmt.Printf("Indexed %d directories.\n", len(dirs))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+}
+
+// indexEnabled reports whether listings go through the index
+func indexEnabled() bool {
+	if !viper.IsSet("index.enabled") {
+		return true
+	}
+	return viper.GetBool("index.enabled")
+}
+
+// indexedSourceDirs returns the directories listings are read from
+func indexedSourceDirs() []string {
+	var dirs []string
+	if dir := GetProjectScriptsDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	for _, src := range searchSources() {
+		dirs = append(dirs, src.Dirs...)
+	}
+	return dirs
+}
+
+// currentIndex returns the index of this profile, reading it on first use.
+// A missing, unreadable, or outdated index starts empty. Callers hold
+// indexMu.
+func currentIndex() *listingIndex {
+	path := GetIndexFile()
+	if loadedIndex != nil && indexPath == path {
+		return loadedIndex
+	}
+	index := &listingIndex{Version: indexVersion, Dirs: make(map[string]*indexedDir)}
+	if data, err := os.ReadFile(path); err == nil {
+		var stored listingIndex
+		if json.Unmarshal(data, &stored) == nil && stored.Version == indexVersion && stored.Dirs != nil {
+			index = &stored
+		}
+	}
+	loadedIndex, indexPath = index, path
+	return index
+}
+
+// saveIndex writes the index back. It is only a cache, so failing to write
+// it, say before 'berga config init', is not an error.
+func saveIndex(index *listingIndex) {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return
+	}
+	writeFileAtomic(GetIndexFile(), data, 0644)
+}
+
+// indexedListing returns the cached listing of dir if it is still current
+func indexedListing(index *listingIndex, dir string) (*indexedDir, bool) {
+	cached := index.Dirs[dir]
+	if cached == nil {
+		return nil, false
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.ModTime().Equal(cached.ModTime) {
+		return nil, false
+	}
+	if cached.IndexedAt.Sub(cached.ModTime) < indexRacyWindow {
+		return nil, false
+	}
+	var ignoreTime time.Time
+	if info, err := os.Stat(filepath.Join(dir, ignoreFileName)); err == nil {
+		ignoreTime = info.ModTime()
+	}
+	return cached, ignoreTime.Equal(cached.IgnoreModTime)
+}
+
+// scanDir reads dir and its ignore file into an indexedDir
+func scanDir(dir string) (*indexedDir, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Stamp the time before reading, so a change during the read keeps the
+	// listing from looking settled
+	scanned := &indexedDir{ModTime: info.ModTime(), IndexedAt: time.Now()}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		scanned.Entries = append(scanned.Entries, indexedEntry{Name: entry.Name(), Mode: entry.Type()})
+	}
+	if info, err := os.Stat(filepath.Join(dir, ignoreFileName)); err == nil {
+		scanned.IgnoreModTime = info.ModTime()
+		if data, err := os.ReadFile(filepath.Join(dir, ignoreFileName)); err == nil {
+			scanned.Ignore = string(data)
+		}
+	}
+	return scanned, nil
+}
+
+// listDir returns every entry of dir, from the index when it is current,
+// along with the rules of its ignore file
+func listDir(dir string) ([]os.DirEntry, []ignoreRule, error) {
+	if !indexEnabled() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return entries, loadIgnoreRules(dir), nil
+	}
+
+	dir = filepath.Clean(dir)
+	indexMu.Lock()
+	index := currentIndex()
+	cached, ok := indexedListing(index, dir)
+	indexMu.Unlock()
+
+	if !ok {
+		scanned, err := scanDir(dir)
+		indexMu.Lock()
+		if err != nil {
+			if index.Dirs[dir] != nil {
+				delete(index.Dirs, dir)
+				saveIndex(index)
+			}
+			indexMu.Unlock()
+			return nil, nil, err
+		}
+		index.Dirs[dir] = scanned
+		saveIndex(index)
+		indexMu.Unlock()
+		cached = scanned
+	}
+
+	entries := make([]os.DirEntry, len(cached.Entries))
+	for i, entry := range cached.Entries {
+		entries[i] = indexDirEntry{dir: dir, entry: entry}
+	}
+	return entries, parseIgnoreRules([]byte(cached.Ignore)), nil
+}
+
+// refreshListings brings the index up to date for dirs, reading the changed
+// ones concurrently, so that listing them afterwards costs a stat each. It
+// saves the index once rather than after each directory.
+func refreshListings(dirs []string) {
+	if !indexEnabled() {
+		return
+	}
+	indexMu.Lock()
+	index := currentIndex()
+	var stale []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, ok := indexedListing(index, dir); !ok {
+			stale = append(stale, dir)
+		}
+	}
+	indexMu.Unlock()
+	if len(stale) == 0 {
+		return
+	}
+
+	scanned := make([]*indexedDir, len(stale))
+	var wg sync.WaitGroup
+	for i, dir := range stale {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			scanned[i], _ = scanDir(dir)
+		}(i, dir)
+	}
+	wg.Wait()
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	for i, dir := range stale {
+		if scanned[i] == nil {
+			delete(index.Dirs, dir)
+		} else {
+			index.Dirs[dir] = scanned[i]
+		}
+	}
+	saveIndex(index)
+}
+
+// walkListing calls fn for every file under dir, like filepath.Walk but
+// listing each directory through the index. Ignore files don't apply.
+func walkListing(dir string, fn func(path string)) {
+	entries, _, err := listDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			walkListing(path, fn)
+		} else {
+			fn(path)
+		}
+	}
+}
+
+// showIndex prints each directory in the index and whether it is current
+func showIndex() error {
+	if !indexEnabled() {
+		fmt.Println("The index is turned off (index.enabled: false).")
+		return nil
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index := currentIndex()
+	if len(index.Dirs) == 0 {
+		fmt.Println("The index is empty; it fills as directories are listed.")
+		return nil
+	}
+
+	dirs := make([]string, 0, len(index.Dirs))
+	for dir := range index.Dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		state := ui.Green("current")
+		if _, ok := indexedListing(index, dir); !ok {
+			state = ui.Yellow("stale")
+		}
+		fmt.Printf("%s %s\n", dir, ui.Dim(fmt.Sprintf("(%d entries, %s)", len(index.Dirs[dir].Entries), state)))
+	}
+	fmt.Printf("\nIndex: %s\n", GetIndexFile())
+	return nil
+}
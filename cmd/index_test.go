@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func listingNames(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := readListing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+func TestReadListingIndex(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetConfigDir(), 0755)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.sh"), []byte("#!/bin/sh\n"), 0755)
+
+	// An hour-old directory is past the racy window, so its listing is kept
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(dir, old, old)
+	if names := listingNames(t, dir); len(names) != 1 || names[0] != "a.sh" {
+		t.Fatalf("Expected a.sh, got %v", names)
+	}
+
+	// A change that keeps the mtime goes unseen, showing the index is used
+	os.WriteFile(filepath.Join(dir, "b.sh"), []byte("#!/bin/sh\n"), 0755)
+	os.Chtimes(dir, old, old)
+	loadedIndex = nil
+	if names := listingNames(t, dir); len(names) != 1 {
+		t.Fatalf("Expected the indexed listing, got %v", names)
+	}
+
+	// A new mtime reads the directory again
+	newer := old.Add(time.Minute)
+	os.Chtimes(dir, newer, newer)
+	if names := listingNames(t, dir); len(names) != 2 {
+		t.Fatalf("Expected both scripts, got %v", names)
+	}
+
+	// Editing the ignore file in place applies too
+	os.WriteFile(filepath.Join(dir, ignoreFileName), []byte("b.sh\n"), 0644)
+	os.Chtimes(dir, newer, newer)
+	if names := listingNames(t, dir); len(names) != 1 || names[0] != "a.sh" {
+		t.Fatalf("Expected b.sh to be ignored, got %v", names)
+	}
+
+	files, _ := readListing(dir)
+	if info, err := files[0].Info(); err != nil || info.Size() != 10 {
+		t.Errorf("Expected fresh file info, got %v %v", info, err)
+	}
+}
+
+func TestRefreshListings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetConfigDir(), 0755)
+	var dirs []string
+	for _, name := range []string{"one", "two", "three"} {
+		dir := filepath.Join(t.TempDir(), name)
+		os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+		os.WriteFile(filepath.Join(dir, "sub", name+".txt"), nil, 0644)
+		dirs = append(dirs, dir)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	refreshListings(append(dirs, missing))
+
+	loadedIndex = nil
+	index := currentIndex()
+	if len(index.Dirs) != 3 || index.Dirs[missing] != nil {
+		t.Errorf("Expected the three directories in the saved index, got %d", len(index.Dirs))
+	}
+
+	var walked []string
+	walkListing(dirs[0], func(path string) { walked = append(walked, path) })
+	if len(walked) != 1 || walked[0] != filepath.Join(dirs[0], "sub", "one.txt") {
+		t.Errorf("Expected the nested file, got %v", walked)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+)
+
+// stdinIsTerminal reports whether someone can answer prompts. Tests replace it.
+var stdinIsTerminal = func() bool {
+	if !ui.IsTerminal(os.Stdin) {
+		return false
+	}
+	// /dev/null is a character device as well, and it is what CI runners
+	// usually attach to stdin
+	stdin, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(stdin, null)
+}
+
+// promptsDisabled reports whether berga must not wait for input: with
+// --no-input or --assume-yes, or when stdin is not a terminal, as in CI.
+// Callers then fall back to defaults or fail with an error saying which flag
+// to pass.
+func promptsDisabled() bool {
+	return templateNoInput || assumeYes || !stdinIsTerminal()
+}
+
+// confirm asks a yes/no question on out. --assume-yes answers yes; when
+// prompts are disabled otherwise, def is returned without asking.
+func confirm(out io.Writer, question string, def bool) bool {
+	if assumeYes {
+		return true
+	}
+	if answer, ok := replayedConfirmation(question); ok {
+		recordConfirmation(question, answer)
+		return answer
+	}
+	if promptsDisabled() {
+		return def
+	}
+
+	hint := i18n.T("prompt.hint_default_no")
+	if def {
+		hint = i18n.T("prompt.hint_default_yes")
+	}
+	fmt.Fprintf(out, "%s %s: ", question, hint)
+	response, _ := readLine()
+	answer := i18n.IsYes(response)
+	if strings.TrimSpace(response) == "" {
+		answer = def
+	}
+	recordConfirmation(question, answer)
+	return answer
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setTerminal pretends stdin is or is not a terminal for the rest of the test
+func setTerminal(t *testing.T, terminal bool) {
+	t.Helper()
+	orig, origEditing := stdinIsTerminal, lineEditing
+	stdinIsTerminal = func() bool { return terminal }
+	// Prompts read the test's input, never the real terminal
+	lineEditing = func() bool { return false }
+	t.Cleanup(func() { stdinIsTerminal, lineEditing = orig, origEditing })
+}
+
+func TestPromptsDisabled(t *testing.T) {
+	setTerminal(t, true)
+	if promptsDisabled() {
+		t.Error("Expected prompts in a terminal")
+	}
+
+	assumeYes = true
+	if !promptsDisabled() {
+		t.Error("Expected --assume-yes to disable prompts")
+	}
+	assumeYes = false
+
+	setTerminal(t, false)
+	if !promptsDisabled() {
+		t.Error("Expected prompts to be disabled without a terminal")
+	}
+}
+
+func TestConfirmWithoutTerminal(t *testing.T) {
+	setTerminal(t, false)
+	if confirm(io.Discard, "Proceed?", false) || !confirm(io.Discard, "Proceed?", true) {
+		t.Error("Expected the default answer without a terminal")
+	}
+
+	assumeYes = true
+	defer func() { assumeYes = false }()
+	if !confirm(io.Discard, "Proceed?", false) {
+		t.Error("Expected --assume-yes to answer yes")
+	}
+}
+
+func TestConfirmOverwriteNonInteractive(t *testing.T) {
+	setTerminal(t, false)
+	output := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(output, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := confirmOverwrite(output); err == nil || !strings.Contains(err.Error(), "--assume-yes") {
+		t.Errorf("Expected an error suggesting --assume-yes, got %v", err)
+	}
+
+	assumeYes = true
+	defer func() { assumeYes = false }()
+	if ok, err := confirmOverwrite(output); !ok || err != nil {
+		t.Errorf("Expected --assume-yes to overwrite, got %v, %v", ok, err)
+	}
+}
+
+func TestAssumeYesDangerLevels(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	medium := writeTestScript(t, "prune.sh", "#!/bin/sh\n# berga:danger: medium\n")
+	high := writeTestScript(t, "wipe.sh", "#!/bin/sh\n# berga:danger: high\n")
+
+	assumeYes = true
+	defer func() { assumeYes = false }()
+	if err := confirmDangerousScript("prune.sh", medium); err != nil {
+		t.Errorf("Expected --assume-yes to confirm a medium script, got %v", err)
+	}
+	if err := confirmDangerousScript("wipe.sh", high); err == nil || !strings.Contains(err.Error(), "--confirm") {
+		t.Errorf("Expected a high script to still need --confirm, got %v", err)
+	}
+}
@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// jobIDEnv is set for the berga process running a background job, to the
+// job's ID
+const jobIDEnv = "BERGA_JOB_ID"
+
+// jobPollInterval is how often a followed job log is checked for output
+const jobPollInterval = 200 * time.Millisecond
+
+// jobStopGrace is how long 'jobs stop' waits for a job to exit
+const jobStopGrace = 5 * time.Second
+
+// Job is a script run started with 'script run --background'
+type Job struct {
+	ID       int       `yaml:"id"`
+	Script   string    `yaml:"script"`
+	Args     []string  `yaml:"args,omitempty"`
+	PID      int       `yaml:"pid"`
+	Log      string    `yaml:"log"`
+	Started  time.Time `yaml:"started"`
+	Ended    time.Time `yaml:"ended,omitempty"`
+	ExitCode int       `yaml:"exit_code,omitempty"`
+	Stopped  bool      `yaml:"stopped,omitempty"`
+}
+
+// running reports whether the job's process is still alive
+func (j Job) running() bool {
+	return j.Ended.IsZero() && processAlive(j.PID)
+}
+
+// status describes the state of a job for 'jobs list'
+func (j Job) status() string {
+	switch {
+	case j.Stopped:
+		return ui.Yellow("stopped")
+	case !j.Ended.IsZero() && j.ExitCode == 0:
+		return ui.Green("done")
+	case !j.Ended.IsZero():
+		return ui.Red(fmt.Sprintf("exit %d", j.ExitCode))
+	case processAlive(j.PID):
+		return ui.Cyan("running")
+	}
+	// Gone without recording how it ended, e.g. killed or after a reboot
+	return ui.Dim("lost")
+}
+
+var (
+	scriptBackground bool
+	// scriptJobFlags are the flags a background job runs with; see jobFlagArgs
+	scriptJobFlags []string
+	jobsFollow     bool
+	jobsForce      bool
+)
+
+// jobsCmd manages background jobs
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Manage scripts running in the background",
+	Long: `Scripts started with 'berga script run --background' run detached from the
+terminal, with their output written to a log file. Without a subcommand the
+jobs are listed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listJobs()
+	},
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List background jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listJobs()
+	},
+}
+
+var jobsLogsCmd = &cobra.Command{
+	Use:               "logs [id]",
+	Short:             "Print a job's output",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return jobLogs(args[0], jobsFollow, -1)
+	},
+}
+
+var jobsAttachCmd = &cobra.Command{
+	Use:   "attach [id]",
+	Short: "Follow a job's output until it ends",
+	Long: `Show the last lines of a job's output and keep printing new output until the
+job ends. Ctrl-C detaches without stopping the job.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return jobLogs(args[0], true, 10)
+	},
+}
+
+var jobsStopCmd = &cobra.Command{
+	Use:   "stop [id]",
+	Short: "Stop a background job",
+	Long: `Stop a job and the processes it started. They are asked to terminate first;
+--force kills them outright.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return stopJob(args[0], jobsForce)
+	},
+}
+
+var jobsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove finished jobs and their logs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("jobs", cleanJobs)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsLogsCmd)
+	jobsCmd.AddCommand(jobsAttachCmd)
+	jobsCmd.AddCommand(jobsStopCmd)
+	jobsCmd.AddCommand(jobsCleanCmd)
+
+	// Flags
+	scriptRunCmd.Flags().BoolVar(&scriptBackground, "background", false, "Run the script detached, as a job managed with 'berga jobs'")
+	jobsLogsCmd.Flags().BoolVarP(&jobsFollow, "follow", "f", false, "Keep printing new output until the job ends")
+	jobsStopCmd.Flags().BoolVar(&jobsForce, "force", false, "Kill the job instead of asking it to terminate")
+}
+
+func loadJobs() ([]Job, error) {
+	data, err := os.ReadFile(GetJobsFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+
+	var jobs []Job
+	if err := yaml.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func saveJobs(jobs []Job) error {
+	data, err := yaml.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetJobsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	return nil
+}
+
+// findJob returns the index of the job with the given ID
+func findJob(jobs []Job, id string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "%"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid job ID '%s'", id)
+	}
+	for i, job := range jobs {
+		if job.ID == n {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("job %d not found (see 'berga jobs list')", n)
+}
+
+// jobFlagArgs returns the flags given to this 'script run' for the job to
+// run with, leaving out the ones already dealt with in the foreground.
+// Jobs have no timeout unless --timeout is given.
+func jobFlagArgs(flags *pflag.FlagSet) []string {
+	args := changedFlagArgs(flags, "background", "interactive-select-args", "confirm")
+	if !flags.Changed("timeout") {
+		args = append(args, "--timeout=0")
+	}
+	return args
+}
+
+// changedFlagArgs returns the flags set on the command line as arguments
+// that set them again, leaving out skip
+func changedFlagArgs(flags *pflag.FlagSet, skip ...string) []string {
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	var args []string
+	flags.Visit(func(f *pflag.Flag) {
+		if skipped[f.Name] {
+			return
+		}
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range values.GetSlice() {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
+
+// startJob starts a script as a background job: berga runs it again in a
+// detached process whose output goes to the job's log
+func startJob(scriptName string, args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the berga executable: %w", err)
+	}
+	// Protected scripts were confirmed before getting here
+	childArgs := append([]string{"script", "run", scriptName, "--confirm=" + scriptName}, scriptJobFlags...)
+	childArgs = append(append(childArgs, "--"), args...)
+
+	var job Job
+	err = withLock("jobs", func() error {
+		jobs, err := loadJobs()
+		if err != nil {
+			return err
+		}
+		job = Job{ID: 1, Script: scriptName, Args: args, Started: time.Now()}
+		for _, j := range jobs {
+			job.ID = max(job.ID, j.ID+1)
+		}
+		if err := os.MkdirAll(GetJobsDir(), 0755); err != nil {
+			return fmt.Errorf("failed to create jobs directory: %w", err)
+		}
+		job.Log = filepath.Join(GetJobsDir(), fmt.Sprintf("%d.log", job.ID))
+		logFile, err := os.OpenFile(job.Log, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create job log: %w", err)
+		}
+		defer logFile.Close()
+
+		cmd := exec.Command(self, childArgs...)
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", jobIDEnv, job.ID))
+		detachProcess(cmd)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to start job: %w", err)
+		}
+		job.PID = cmd.Process.Pid
+		cmd.Process.Release()
+		return saveJobs(append(jobs, job))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Started job %d (pid %d): %s\n", job.ID, job.PID, scriptName)
+	fmt.Println(ui.Dim(fmt.Sprintf("Follow it with 'berga jobs attach %d', stop it with 'berga jobs stop %d'", job.ID, job.ID)))
+	return nil
+}
+
+// takeJobID returns the ID of the job this process runs, if any, and clears
+// it from the environment so the script's own berga commands don't see it
+func takeJobID() string {
+	id := os.Getenv(jobIDEnv)
+	os.Unsetenv(jobIDEnv)
+	return id
+}
+
+// finishJob records how a job's run ended
+func finishJob(id string, runErr error) {
+	// The job ends either way; wait for the registry rather than lose the result
+	defer func(wait time.Duration) { waitLock = wait }(waitLock)
+	waitLock = max(waitLock, 30*time.Second)
+	err := withLock("jobs", func() error {
+		jobs, err := loadJobs()
+		if err != nil {
+			return err
+		}
+		i, err := findJob(jobs, id)
+		if err != nil || jobs[i].Stopped {
+			return err
+		}
+		jobs[i].Ended = time.Now()
+		jobs[i].ExitCode = ExitCode(runErr)
+		return saveJobs(jobs)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: failed to record the end of job %s: %v", id, err)))
+	}
+}
+
+func listJobs() error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs. Start one with 'berga script run <name> --background'.")
+		return nil
+	}
+
+	for _, job := range jobs {
+		command := strings.Join(append([]string{job.Script}, job.Args...), " ")
+		fmt.Printf("  %3d  %-18s %s  %s %s\n", job.ID, job.status(),
+			job.Started.Local().Format("2006-01-02 15:04"), ui.Bold(command), ui.Dim(fmt.Sprintf("(pid %d)", job.PID)))
+	}
+	return nil
+}
+
+// jobLogs prints a job's log, or its last tail lines when tail is not
+// negative. With follow set it keeps printing new output until the job ends.
+func jobLogs(id string, follow bool, tail int) error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	i, err := findJob(jobs, id)
+	if err != nil {
+		return err
+	}
+	job := jobs[i]
+
+	f, err := os.Open(job.Log)
+	if err != nil {
+		return fmt.Errorf("failed to open job log: %w", err)
+	}
+	defer f.Close()
+
+	if tail >= 0 {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("failed to read job log: %w", err)
+		}
+		lines := strings.SplitAfter(string(data), "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		fmt.Print(strings.Join(lines[max(len(lines)-tail, 0):], ""))
+	} else if _, err := io.Copy(os.Stdout, f); err != nil {
+		return fmt.Errorf("failed to read job log: %w", err)
+	}
+	if !follow {
+		return nil
+	}
+
+	for job.running() {
+		time.Sleep(jobPollInterval)
+		if _, err := io.Copy(os.Stdout, f); err != nil {
+			return fmt.Errorf("failed to read job log: %w", err)
+		}
+		if jobs, err := loadJobs(); err == nil {
+			if i, err := findJob(jobs, id); err == nil {
+				job = jobs[i]
+			}
+		}
+	}
+	io.Copy(os.Stdout, f)
+	fmt.Fprintln(os.Stderr, ui.ErrDim(fmt.Sprintf("Job %d: %s", job.ID, job.status())))
+	return nil
+}
+
+func stopJob(id string, force bool) error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	i, err := findJob(jobs, id)
+	if err != nil {
+		return err
+	}
+	job := jobs[i]
+	if !job.running() {
+		return fmt.Errorf("job %d is not running", job.ID)
+	}
+
+	if err := stopProcessGroup(job.PID, force); err != nil {
+		return fmt.Errorf("failed to stop job %d: %w", job.ID, err)
+	}
+	deadline := time.Now().Add(jobStopGrace)
+	for processAlive(job.PID) && time.Now().Before(deadline) {
+		time.Sleep(jobPollInterval)
+	}
+	if processAlive(job.PID) {
+		return fmt.Errorf("job %d is still running; use --force to kill it", job.ID)
+	}
+
+	err = withLock("jobs", func() error {
+		jobs, err := loadJobs()
+		if err != nil {
+			return err
+		}
+		i, err := findJob(jobs, id)
+		if err != nil {
+			return err
+		}
+		if jobs[i].Ended.IsZero() {
+			jobs[i].Ended = time.Now()
+			jobs[i].Stopped = true
+		}
+		return saveJobs(jobs)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stopped job %d\n", job.ID)
+	return nil
+}
+
+// cleanJobs removes jobs that are no longer running, with their logs
+func cleanJobs() error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	var kept []Job
+	removed := 0
+	for _, job := range jobs {
+		if job.running() {
+			kept = append(kept, job)
+			continue
+		}
+		os.Remove(job.Log)
+		removed++
+	}
+	if err := saveJobs(kept); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d finished job(s)\n", removed)
+	return nil
+}
+
+// completeJobIDs completes job IDs, described by their scripts
+func completeJobIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	jobs, _ := loadJobs()
+	var ids []string
+	for _, job := range jobs {
+		ids = append(ids, fmt.Sprintf("%d\t%s", job.ID, job.Script))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts cmd in a session of its own, so it outlives the
+// terminal and its process group can be stopped as a whole
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// stopProcessGroup signals a job and every process in its group
+func stopProcessGroup(pid int, force bool) error {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	return syscall.Kill(-pid, sig)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestJobFlagArgs(t *testing.T) {
+	flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	var background, selectArgs bool
+	var envVars []string
+	var cwd string
+	timeout := timeoutValue(time.Minute)
+	flags.BoolVar(&background, "background", false, "")
+	flags.BoolVar(&selectArgs, "interactive-select-args", false, "")
+	flags.StringArrayVar(&envVars, "env", nil, "")
+	flags.StringVar(&cwd, "cwd", "", "")
+	flags.Var(&timeout, "timeout", "")
+
+	if err := flags.Parse([]string{"--background", "--env", "A=1", "--env", "B=2", "--cwd", "/tmp"}); err != nil {
+		t.Fatal(err)
+	}
+	got := jobFlagArgs(flags)
+	want := []string{"--cwd=/tmp", "--env=A=1", "--env=B=2", "--timeout=0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jobFlagArgs() = %v, want %v", got, want)
+	}
+
+	if err := flags.Parse([]string{"--timeout", "90s"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobFlagArgs(flags); got[len(got)-1] != "--timeout=1m30s" {
+		t.Errorf("Expected an explicit timeout to be kept, got %v", got)
+	}
+}
+
+func TestFinishAndCleanJobs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetJobsDir(), 0755)
+	logPath := GetJobsDir() + "/1.log"
+	os.WriteFile(logPath, []byte("output\n"), 0600)
+	running := Job{ID: 2, Script: "watch.sh", PID: os.Getpid(), Started: time.Now()}
+	if err := saveJobs([]Job{{ID: 1, Script: "backup.sh", PID: os.Getpid(), Log: logPath, Started: time.Now()}, running}); err != nil {
+		t.Fatal(err)
+	}
+
+	finishJob("1", &ExitError{Code: 3})
+	jobs, err := loadJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs[0].Ended.IsZero() || jobs[0].ExitCode != 3 || jobs[0].running() {
+		t.Errorf("Expected job 1 to be recorded as ended with status 3, got %+v", jobs[0])
+	}
+	if !jobs[1].running() {
+		t.Error("Expected job 2 to still be running")
+	}
+
+	if err := cleanJobs(); err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ = loadJobs()
+	if len(jobs) != 1 || jobs[0].ID != 2 {
+		t.Errorf("Expected only the running job to be kept, got %+v", jobs)
+	}
+	if _, err := os.Stat(logPath); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the finished job's log to be removed")
+	}
+	if _, err := findJob(jobs, "%2"); err != nil {
+		t.Errorf("Expected %%2 to find job 2, got %v", err)
+	}
+	if _, err := findJob(jobs, "7"); err == nil {
+		t.Error("Expected an unknown job to fail")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// detachProcess starts cmd without a console and in a process group of its
+// own, so it outlives the terminal
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
+
+// stopProcessGroup ends a job and the processes it started. Windows has no
+// polite equivalent of SIGTERM for console-less processes, so both ways kill
+// the process tree.
+func stopProcessGroup(pid int, force bool) error {
+	out, err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
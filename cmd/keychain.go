@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// keychainService is the service name berga's secrets are stored under
+const keychainService = "berga"
+
+// keychainRefPrefix marks a config value that lives in the OS keychain,
+// e.g. "serve.token: keychain:serve.token"
+const keychainRefPrefix = "keychain:"
+
+// errKeychainUnavailable is returned when no supported keychain is present
+var errKeychainUnavailable = errors.New("no OS keychain available")
+
+// errSecretNotFound is returned when the keychain has no entry for a key
+var errSecretNotFound = errors.New("secret not found in keychain")
+
+// keychain stores secrets in the operating system's credential store
+type keychain interface {
+	Name() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// openKeychain returns the keychain for this platform; a variable so tests
+// can replace it
+var openKeychain = openSystemKeychain
+
+// openSystemKeychain returns the keychain for this platform: macOS Keychain,
+// Windows Credential Manager, or libsecret through secret-tool elsewhere
+func openSystemKeychain() (keychain, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return windowsKeychain()
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, errKeychainUnavailable
+		}
+		return macKeychain{}, nil
+	default:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, fmt.Errorf("%w (install libsecret's secret-tool)", errKeychainUnavailable)
+		}
+		return secretToolKeychain{}, nil
+	}
+}
+
+// macKeychain uses the macOS security tool
+type macKeychain struct{}
+
+func (macKeychain) Name() string { return "macOS Keychain" }
+
+func (macKeychain) Get(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", key, "-w").Output()
+	if err != nil {
+		return "", errSecretNotFound
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (macKeychain) Set(key, value string) error {
+	// With -w last and no value, security asks for the secret twice on stdin,
+	// so it never appears in the process list
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", key, "-w")
+	cmd.Stdin = strings.NewReader(value + "\n" + value + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store secret: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (macKeychain) Delete(key string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", key).Run(); err != nil {
+		return errSecretNotFound
+	}
+	return nil
+}
+
+// secretToolKeychain uses libsecret's secret-tool
+type secretToolKeychain struct{}
+
+func (secretToolKeychain) Name() string { return "Secret Service" }
+
+func (secretToolKeychain) Get(key string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keychainService, "account", key).Output()
+	if err != nil || len(out) == 0 {
+		return "", errSecretNotFound
+	}
+	return string(out), nil
+}
+
+func (secretToolKeychain) Set(key, value string) error {
+	// The secret is read from stdin so it never appears in the process list
+	cmd := exec.Command("secret-tool", "store", "--label", "berga "+key, "service", keychainService, "account", key)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store secret: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretToolKeychain) Delete(key string) error {
+	if err := exec.Command("secret-tool", "clear", "service", keychainService, "account", key).Run(); err != nil {
+		return errSecretNotFound
+	}
+	return nil
+}
+
+// keychainRef returns the account name a config value refers to, if any
+func keychainRef(value interface{}) (string, bool) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, keychainRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(s, keychainRefPrefix), true
+}
+
+// resolveKeychainValues replaces keychain references in the loaded config with
+// the secrets they point to, so viper getters return the real values. A
+// reference that can't be resolved is cleared rather than left in place, where
+// the reference itself would pass for the secret.
+func resolveKeychainValues() {
+	var kc keychain
+	var kcErr error
+	for _, key := range viper.AllKeys() {
+		account, ok := keychainRef(viper.Get(key))
+		if !ok {
+			continue
+		}
+		if kc == nil && kcErr == nil {
+			kc, kcErr = openKeychain()
+		}
+		if kcErr != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: cannot read '%s': %v", key, kcErr)))
+			viper.Set(key, "")
+			continue
+		}
+		secret, err := kc.Get(account)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: cannot read '%s' from %s: %v", key, kc.Name(), err)))
+			viper.Set(key, "")
+			continue
+		}
+		viper.Set(key, secret)
+	}
+}
+
+// requireSecret returns a secret config value, or an error when it is empty
+// or is a keychain reference that could not be resolved. Servers use it so
+// they never start guarded by a blank or placeholder secret.
+func requireSecret(key string) (string, error) {
+	value := viper.GetString(key)
+	if _, ok := keychainRef(value); ok || value == "" {
+		return "", fmt.Errorf("'%s' is empty or could not be read from the keychain", key)
+	}
+	return value, nil
+}
+
+// storeSecret saves a sensitive config value in the keychain and returns the
+// reference to write to the config file instead
+func storeSecret(key, value string) (string, error) {
+	kc, err := openKeychain()
+	if err != nil {
+		return "", fmt.Errorf("%w; use --plain to store '%s' in the config file", err, key)
+	}
+	// Profiles other than the default get their own keychain entries
+	account := key
+	if name, _ := activeProfile(); name != defaultProfile {
+		account = name + "/" + key
+	}
+	if err := kc.Set(account, value); err != nil {
+		return "", err
+	}
+	fmt.Printf("Stored %s in %s\n", key, kc.Name())
+	return keychainRefPrefix + account, nil
+}
+
+// deleteSecret removes a keychain entry, ignoring missing entries
+func deleteSecret(account string) error {
+	kc, err := openKeychain()
+	if err != nil {
+		return err
+	}
+	if err := kc.Delete(account); err != nil && !errors.Is(err, errSecretNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cmd
+
+// windowsKeychain is only available on Windows
+func windowsKeychain() (keychain, error) {
+	return nil, errKeychainUnavailable
+}
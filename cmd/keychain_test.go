@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeKeychain is an in-memory keychain for tests
+type fakeKeychain map[string]string
+
+func (fakeKeychain) Name() string { return "fake keychain" }
+
+func (k fakeKeychain) Get(key string) (string, error) {
+	if value, ok := k[key]; ok {
+		return value, nil
+	}
+	return "", errSecretNotFound
+}
+
+func (k fakeKeychain) Set(key, value string) error {
+	k[key] = value
+	return nil
+}
+
+func (k fakeKeychain) Delete(key string) error {
+	delete(k, key)
+	return nil
+}
+
+func TestKeychainRef(t *testing.T) {
+	if account, ok := keychainRef("keychain:serve.token"); !ok || account != "serve.token" {
+		t.Errorf("keychainRef() = %q, %v; want serve.token, true", account, ok)
+	}
+	if _, ok := keychainRef("plain-value"); ok {
+		t.Error("Expected plain values not to be keychain references")
+	}
+	if _, ok := keychainRef(42); ok {
+		t.Error("Expected non-strings not to be keychain references")
+	}
+}
+
+func TestResolveKeychainValues(t *testing.T) {
+	orig := openKeychain
+	defer func() { openKeychain = orig }()
+	defer viper.Set("serve.token", nil)
+	defer viper.Set("secrets.hook", nil)
+
+	openKeychain = func() (keychain, error) { return fakeKeychain{"serve.token": "s3cret"}, nil }
+	viper.Set("serve.token", "keychain:serve.token")
+	viper.Set("secrets.hook", "keychain:missing")
+	resolveKeychainValues()
+	if got, err := requireSecret("serve.token"); err != nil || got != "s3cret" {
+		t.Errorf("Expected the secret from the keychain, got %q, %v", got, err)
+	}
+	if got := viper.GetString("secrets.hook"); got != "" {
+		t.Errorf("Expected a missing entry to be cleared, got %q", got)
+	}
+	if _, err := requireSecret("secrets.hook"); err == nil {
+		t.Error("Expected an unresolved secret to be refused")
+	}
+
+	openKeychain = func() (keychain, error) { return nil, errKeychainUnavailable }
+	viper.Set("serve.token", "keychain:serve.token")
+	resolveKeychainValues()
+	if got := viper.GetString("serve.token"); got != "" {
+		t.Errorf("Expected the reference to be cleared without a keychain, got %q", got)
+	}
+}
+
+func TestServeRefusesUnresolvedToken(t *testing.T) {
+	defer viper.Set("serve.token", nil)
+	for _, value := range []string{"", "keychain:serve.token"} {
+		viper.Set("serve.token", value)
+		if err := serve("127.0.0.1:0", ""); err == nil || !strings.Contains(err.Error(), "refusing to start") {
+			t.Errorf("Expected serve to refuse serve.token %q, got %v", value, err)
+		}
+	}
+	if err := serve("127.0.0.1:0", "keychain:serve.token"); err == nil {
+		t.Error("Expected a keychain reference as --token to be refused")
+	}
+}
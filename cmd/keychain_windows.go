@@ -0,0 +1,117 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = 1168
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential mirrors the Win32 CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialManager uses the Windows Credential Manager
+type credentialManager struct{}
+
+func windowsKeychain() (keychain, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, errKeychainUnavailable
+	}
+	return credentialManager{}, nil
+}
+
+func credentialTarget(key string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(keychainService + ":" + key)
+}
+
+func (credentialManager) Name() string { return "Windows Credential Manager" }
+
+func (credentialManager) Get(key string) (string, error) {
+	target, err := credentialTarget(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	r, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errno, ok := callErr.(syscall.Errno); ok && errno == errorNotFound {
+			return "", errSecretNotFound
+		}
+		return "", fmt.Errorf("failed to read credential: %w", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return string(blob), nil
+}
+
+func (credentialManager) Set(key, value string) error {
+	target, err := credentialTarget(key)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		UserName:           user,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	if r, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("failed to store credential: %w", callErr)
+	}
+	return nil
+}
+
+func (credentialManager) Delete(key string) error {
+	target, err := credentialTarget(key)
+	if err != nil {
+		return err
+	}
+	if r, _, callErr := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		if errno, ok := callErr.(syscall.Errno); ok && errno == errorNotFound {
+			return errSecretNotFound
+		}
+		return fmt.Errorf("failed to delete credential: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultListenAddr is where 'berga listen' accepts webhooks by default
+const defaultListenAddr = "127.0.0.1:8788"
+
+// maxHookPayload caps the size of a webhook body
+const maxHookPayload = 1 << 20
+
+var listenAddr string
+
+// listenCmd represents the listen command
+var listenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Run scripts when webhooks arrive",
+	Long: `Listen for webhooks and run the script configured for each one, so a git push
+or CI event can trigger local automation.
+
+Hooks are defined under listen.hooks in the config file:
+
+  listen:
+    addr: 127.0.0.1:8788
+    allow: [127.0.0.1, 10.0.0.0/8]
+    hooks:
+      deploy:
+        script: deploy.sh
+        secret: deploy_hook      # the secret is secrets.deploy_hook
+        args: [--prod]
+        events: [push]           # optional, matched against X-GitHub-Event
+                                 # or X-Gitlab-Event
+
+Each hook is served at POST /hooks/<name>. A request must prove it knows the
+hook's secret with a GitHub X-Hub-Signature-256 signature, an X-Gitlab-Token
+header, "Authorization: Bearer <secret>", or a ?token= query parameter. When
+listen.allow is set, only those addresses and networks may connect.
+
+The script runs in the background with the request body on stdin and these
+environment variables:
+
+  BERGA_HOOK               hook name
+  BERGA_HOOK_EVENT         event name from the GitHub or GitLab header
+  BERGA_HOOK_DELIVERY      delivery ID, when the sender provides one
+  BERGA_HOOK_CONTENT_TYPE  Content-Type of the request
+  BERGA_HOOK_PAYLOAD       path to a file holding the request body
+
+Runs of the same hook never overlap, and scripts marked dangerous are refused.
+Every run is recorded in the audit log.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return listen(listenAddr)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listenCmd)
+
+	// Flags
+	listenCmd.Flags().StringVar(&listenAddr, "addr", "", "Address to listen on (default: listen.addr from config, or "+defaultListenAddr+")")
+}
+
+// webhook is a configured listen.hooks entry
+type webhook struct {
+	Name   string
+	Script string
+	Secret string
+	Args   []string
+	Events []string
+}
+
+// hookPayload is what a script learns about the request that triggered it
+type hookPayload struct {
+	Event       string
+	Delivery    string
+	ContentType string
+	Body        []byte
+}
+
+// hookRunner runs the script of a webhook
+type hookRunner func(hook webhook, payload hookPayload)
+
+// loadWebhooks reads listen.hooks from config and resolves each hook's secret
+func loadWebhooks() (map[string]webhook, error) {
+	hooks := make(map[string]webhook)
+	for name, value := range viper.GetStringMap("listen.hooks") {
+		settings := cast.ToStringMap(value)
+		hook := webhook{
+			Name:   name,
+			Script: cast.ToString(settings["script"]),
+			Args:   cast.ToStringSlice(settings["args"]),
+			Events: listSetting(settings["events"]),
+		}
+		if hook.Script == "" {
+			return nil, fmt.Errorf("hook '%s' has no script", name)
+		}
+		secretKey := cast.ToString(settings["secret"])
+		if secretKey == "" {
+			return nil, fmt.Errorf("hook '%s' has no secret; set listen.hooks.%s.secret to the name of a secrets entry", name, name)
+		}
+		if !viper.IsSet("secrets." + secretKey) {
+			return nil, fmt.Errorf("secret '%s' for hook '%s' is not set (store it with 'berga config set secrets.%s')", secretKey, name, secretKey)
+		}
+		secret, err := requireSecret("secrets." + secretKey)
+		if err != nil {
+			return nil, fmt.Errorf("secret '%s' for hook '%s': %w", secretKey, name, err)
+		}
+		hook.Secret = secret
+		auditSecretAccess("secrets." + secretKey)
+		hooks[name] = hook
+	}
+	return hooks, nil
+}
+
+// listSetting reads a setting given as a list or one comma-separated string
+func listSetting(value interface{}) []string {
+	var items []string
+	for _, v := range cast.ToStringSlice(value) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseAllowList turns listen.allow entries into networks. A bare address
+// allows just that address.
+func parseAllowList(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid listen.allow entry '%s' (expected an IP address or CIDR network)", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen.allow entry '%s' (expected an IP address or CIDR network)", entry)
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
+
+// remoteAllowed reports whether a request's remote address is in the allow
+// list. An empty list allows everyone.
+func remoteAllowed(remoteAddr string, allow []*net.IPNet) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hookAuthorized checks that a request knows the hook's secret. A GitHub
+// signature must match the body; otherwise the secret itself must be sent.
+func hookAuthorized(r *http.Request, body []byte, secret string) bool {
+	// Anyone can sign with an empty key
+	if secret == "" {
+		return false
+	}
+	if signature := r.Header.Get("X-Hub-Signature-256"); signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+
+	given := r.Header.Get("X-Gitlab-Token")
+	if given == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			given = strings.TrimPrefix(auth, "Bearer ")
+		}
+	}
+	if given == "" {
+		given = r.URL.Query().Get("token")
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(secret)) == 1
+}
+
+// hookEvent returns the event name sent by GitHub, GitLab, or Gitea
+func hookEvent(r *http.Request) string {
+	for _, header := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Gitea-Event"} {
+		if event := r.Header.Get(header); event != "" {
+			return event
+		}
+	}
+	return ""
+}
+
+// hookDelivery returns the sender's ID for the request, if it has one
+func hookDelivery(r *http.Request) string {
+	for _, header := range []string{"X-GitHub-Delivery", "X-Gitlab-Event-UUID", "X-Gitea-Delivery"} {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// newHookHandler returns the webhook routes. Accepted requests are handed to
+// run, which is expected to return quickly.
+func newHookHandler(hooks map[string]webhook, allow []*net.IPNet, run hookRunner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !remoteAllowed(r.RemoteAddr, allow) {
+			writeJSONError(w, http.StatusForbidden, "address not allowed")
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, "/hooks/")
+		// Config keys are case-insensitive, so hook names are stored lowercased
+		hook, found := hooks[strings.ToLower(name)]
+		if !ok || !found {
+			writeJSONError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookPayload))
+		if err != nil {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "payload too large")
+			return
+		}
+		if !hookAuthorized(r, body, hook.Secret) {
+			writeJSONError(w, http.StatusUnauthorized, "invalid or missing signature")
+			return
+		}
+
+		payload := hookPayload{
+			Event:       hookEvent(r),
+			Delivery:    hookDelivery(r),
+			ContentType: r.Header.Get("Content-Type"),
+			Body:        body,
+		}
+		if len(hook.Events) > 0 && !containsFold(hook.Events, payload.Event) {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "event": payload.Event})
+			return
+		}
+
+		run(hook, payload)
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted", "hook": hook.Name})
+	})
+}
+
+// containsFold reports whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hookEnviron returns the environment for a hook's script, on top of base
+// from scriptCommand
+func hookEnviron(base []string, hook webhook, payload hookPayload, payloadFile string) []string {
+	if base == nil {
+		base = os.Environ()
+	}
+	return layerEnviron(base, map[string]string{
+		"BERGA_HOOK":              hook.Name,
+		"BERGA_HOOK_EVENT":        payload.Event,
+		"BERGA_HOOK_DELIVERY":     payload.Delivery,
+		"BERGA_HOOK_CONTENT_TYPE": payload.ContentType,
+		"BERGA_HOOK_PAYLOAD":      payloadFile,
+	})
+}
+
+// runHook runs a hook's script to completion, writing its output with the hook
+// name in front of each line
+func runHook(ctx context.Context, hook webhook, payload hookPayload, stdout, stderr io.Writer) error {
+	storedPath, err := locateScript(hook.Script)
+	if err != nil {
+		return err
+	}
+	scriptPath, cleanup, err := plainScriptPath(storedPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if level := scriptDangerLevel(hook.Script, scriptPath); level != dangerLow {
+		return fmt.Errorf("script '%s' is marked danger: %s and cannot be run from a webhook", hook.Script, level)
+	}
+	if rule, ctx, err := checkScriptPolicy(hook.Script); err != nil {
+		return err
+	} else if rule != nil {
+		return fmt.Errorf("script '%s' %s and cannot be run from a webhook", hook.Script, rule.requirement(ctx))
+	}
+	if err := requireKubeContext(hook.Script, scriptPath); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "berga-hook-*")
+	if err != nil {
+		return fmt.Errorf("failed to save payload: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(payload.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save payload: %w", err)
+	}
+
+	ctx, cancel := runContext(ctx, scriptRunTimeout())
+	defer cancel()
+
+	mu := &sync.Mutex{}
+	out := &lineWriter{mu: mu, out: stdout, label: ui.Cyan("[" + hook.Name + "]"), now: time.Now}
+	errOut := &lineWriter{mu: mu, out: stderr, label: ui.ErrCyan("[" + hook.Name + "]"), now: time.Now}
+	defer out.Flush()
+	defer errOut.Flush()
+
+	cmd := scriptCommand(ctx, scriptPath, hook.Args)
+	cmd.Env = hookEnviron(cmd.Env, hook, payload, file.Name())
+	cmd.Stdin = bytes.NewReader(payload.Body)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return cmd.Run()
+}
+
+// hookQueue runs hooks in the background, one run at a time per hook
+type hookQueue struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (q *hookQueue) lock(name string) *sync.Mutex {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.locks == nil {
+		q.locks = make(map[string]*sync.Mutex)
+	}
+	if q.locks[name] == nil {
+		q.locks[name] = &sync.Mutex{}
+	}
+	return q.locks[name]
+}
+
+func (q *hookQueue) run(hook webhook, payload hookPayload) {
+	go func() {
+		lock := q.lock(hook.Name)
+		lock.Lock()
+		defer lock.Unlock()
+
+		fmt.Printf("%s %s %s\n", ui.Dim(time.Now().Format("15:04:05")), ui.Bold(hook.Name), hookDescription(hook, payload))
+		started := time.Now()
+		err := runHook(context.Background(), hook, payload, os.Stdout, os.Stderr)
+
+		params := map[string]string{"via": "webhook", "script": hook.Script}
+		if payload.Event != "" {
+			params["event"] = payload.Event
+		}
+		if payload.Delivery != "" {
+			params["delivery"] = payload.Delivery
+		}
+		recordTimedAudit("hook run", hook.Name, params, err, time.Since(started))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("Hook '%s' failed: %v", hook.Name, err)))
+		}
+	}()
+}
+
+// hookDescription summarizes a hook run for the log
+func hookDescription(hook webhook, payload hookPayload) string {
+	desc := "running " + hook.Script
+	if payload.Event != "" {
+		desc += " for " + payload.Event
+	}
+	return desc
+}
+
+func listen(addr string) error {
+	if addr == "" {
+		addr = viper.GetString("listen.addr")
+	}
+	if addr == "" {
+		addr = defaultListenAddr
+	}
+
+	hooks, err := loadWebhooks()
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return fmt.Errorf("no webhooks configured; add them under listen.hooks with 'berga open config' (see 'berga listen --help')")
+	}
+	allow, err := parseAllowList(listSetting(viper.Get("listen.allow")))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("berga listening for webhooks on http://%s\n", addr)
+	for _, name := range names {
+		fmt.Printf("  POST /hooks/%s -> %s\n", name, hooks[name].Script)
+	}
+	if len(allow) == 0 && !isLoopbackAddr(addr) {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: listening beyond localhost without listen.allow; any address can reach the hooks"))
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newHookHandler(hooks, allow, (&hookQueue{}).run),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local
+// connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
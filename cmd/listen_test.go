@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestHookHandler(t *testing.T) {
+	hooks := map[string]webhook{
+		"deploy": {Name: "deploy", Script: "deploy.sh", Secret: "s3cret", Events: []string{"push"}},
+	}
+	allow, err := parseAllowList([]string{"192.0.2.10", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ran []hookPayload
+	handler := newHookHandler(hooks, allow, func(hook webhook, payload hookPayload) {
+		ran = append(ran, payload)
+	})
+
+	body := `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name    string
+		path    string
+		remote  string
+		headers map[string]string
+		want    int
+	}{
+		{"github signature", "/hooks/deploy", "192.0.2.10:4000", map[string]string{"X-Hub-Signature-256": signature, "X-GitHub-Event": "push"}, http.StatusAccepted},
+		{"bad signature", "/hooks/deploy", "192.0.2.10:4000", map[string]string{"X-Hub-Signature-256": "sha256=00", "X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"gitlab token", "/hooks/deploy", "10.1.2.3:4000", map[string]string{"X-Gitlab-Token": "s3cret", "X-Gitlab-Event": "Push"}, http.StatusAccepted},
+		{"query token", "/hooks/deploy?token=s3cret", "10.1.2.3:4000", map[string]string{"X-GitHub-Event": "push"}, http.StatusAccepted},
+		{"no secret", "/hooks/deploy", "10.1.2.3:4000", map[string]string{"X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"other event", "/hooks/deploy", "10.1.2.3:4000", map[string]string{"Authorization": "Bearer s3cret", "X-GitHub-Event": "issues"}, http.StatusOK},
+		{"address not allowed", "/hooks/deploy", "198.51.100.1:4000", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusForbidden},
+		{"unknown hook", "/hooks/other", "10.1.2.3:4000", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if len(ran) != 3 {
+		t.Fatalf("Expected 3 runs, got %d", len(ran))
+	}
+	if ran[0].Event != "push" || string(ran[0].Body) != body {
+		t.Errorf("Unexpected payload %+v", ran[0])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/hooks/deploy?token=s3cret", nil)
+	req.RemoteAddr = "10.1.2.3:4000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be refused, got %d", rec.Code)
+	}
+}
+
+func TestParseAllowList(t *testing.T) {
+	allow, err := parseAllowList([]string{"127.0.0.1", "::1", "172.16.0.0/12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"127.0.0.1:80":   true,
+		"[::1]:80":       true,
+		"172.20.1.1:80":  true,
+		"127.0.0.2:80":   false,
+		"192.168.0.1:80": false,
+	} {
+		if got := remoteAllowed(addr, allow); got != want {
+			t.Errorf("remoteAllowed(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if !remoteAllowed("203.0.113.5:80", nil) {
+		t.Error("Expected an empty allow list to allow everyone")
+	}
+	if _, err := parseAllowList([]string{"example.com"}); err == nil {
+		t.Error("Expected an invalid entry to be an error")
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("listen.hooks", map[string]interface{}{
+		"deploy": map[string]interface{}{"script": "deploy.sh", "secret": "deploy_hook", "events": "push, release"},
+	})
+	viper.Set("secrets.deploy_hook", "s3cret")
+	defer viper.Set("listen.hooks", nil)
+	defer viper.Set("secrets.deploy_hook", nil)
+
+	hooks, err := loadWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := hooks["deploy"]
+	if hook.Secret != "s3cret" || len(hook.Events) != 2 || hook.Events[1] != "release" {
+		t.Errorf("Unexpected hook %+v", hook)
+	}
+
+	viper.Set("secrets.deploy_hook", nil)
+	if _, err := loadWebhooks(); err == nil {
+		t.Error("Expected a hook whose secret is not set to be an error")
+	}
+	for _, value := range []string{"", "keychain:secrets.deploy_hook"} {
+		viper.Set("secrets.deploy_hook", value)
+		if _, err := loadWebhooks(); err == nil {
+			t.Errorf("Expected secret %q to be refused", value)
+		}
+	}
+}
+
+func TestHookAuthorizedEmptySecret(t *testing.T) {
+	body := []byte(`{"ref": "main"}`)
+	mac := hmac.New(sha256.New, nil)
+	mac.Write(body)
+	req := httptest.NewRequest(http.MethodPost, "/hooks/deploy", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	if hookAuthorized(req, body, "") {
+		t.Error("Expected a body signed with the empty key to be refused")
+	}
+}
+
+func TestRunHookExposesPayload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "echo.sh", "#!/bin/sh\necho \"$BERGA_HOOK $BERGA_HOOK_EVENT\"\ncat\ncat \"$BERGA_HOOK_PAYLOAD\"\n")
+
+	hook := webhook{Name: "deploy", Script: "echo.sh"}
+	payload := hookPayload{Event: "push", Body: []byte("payload\n")}
+	var stdout, stderr bytes.Buffer
+	if err := runHook(context.Background(), hook, payload, &stdout, &stderr); err != nil {
+		t.Fatalf("runHook failed: %v (%s)", err, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "deploy push") || strings.Count(out, "payload") != 2 {
+		t.Errorf("Expected the event and body on stdin and in the payload file, got %q", out)
+	}
+
+	writeTestScript(t, "wipe.sh", "#!/bin/sh\n# berga:danger: high\n")
+	if err := runHook(context.Background(), webhook{Name: "wipe", Script: "wipe.sh"}, payload, &stdout, &stderr); err == nil {
+		t.Error("Expected a dangerous script to be refused")
+	}
+}
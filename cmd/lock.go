@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// staleLockAge is how old a lock may get before it is assumed abandoned
+const staleLockAge = 10 * time.Minute
+
+// lockPollInterval is how often a held lock is re-checked while waiting
+const lockPollInterval = 100 * time.Millisecond
+
+// defaultLockWait is how long a lock is waited for without --wait-lock. Most
+// locks are held for milliseconds, so a short wait rides out another berga
+// process recording its history or usage instead of failing.
+const defaultLockWait = 5 * time.Second
+
+// malformedLockAge is how old a lock file that can't be parsed may get
+// before it is taken over. A new lock is empty for the moment between its
+// creation and its owner writing to it.
+const malformedLockAge = 5 * time.Second
+
+// takeoverGuardAge is how old a takeover guard may get before it is assumed
+// to be left by a crashed process
+const takeoverGuardAge = 10 * time.Second
+
+// lockInfo is the owner recorded in a lock file
+type lockInfo struct {
+	PID      int
+	Host     string
+	Acquired time.Time
+}
+
+// acquireLock takes the named lock under ~/.berga/locks, waiting up to
+// --wait-lock (default defaultLockWait) for another berga process to release
+// it. Locks left behind by dead processes, older than staleLockAge, or
+// unreadable past malformedLockAge are taken over.
+func acquireLock(name string) (func(), error) {
+	if err := os.MkdirAll(GetLocksDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create locks directory: %w", err)
+	}
+	path := filepath.Join(GetLocksDir(), name+".lock")
+	deadline := time.Now().Add(waitLock)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			host, _ := os.Hostname()
+			owner := []byte(fmt.Sprintf("%d\n%s\n%s\n", os.Getpid(), host, time.Now().Format(time.RFC3339)))
+			f.Write(owner)
+			f.Close()
+			return func() {
+				// Held past staleLockAge, the lock may have been taken over
+				if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, owner) {
+					os.Remove(path)
+				}
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+
+		data, info, readErr := readLockFile(path)
+		if os.IsNotExist(readErr) {
+			continue
+		}
+		stale := readErr == nil && lockIsStale(info) || readErr != nil && malformedLockExpired(path)
+		if stale && takeOverLock(path, data) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			if readErr != nil {
+				return nil, fmt.Errorf("%s is locked by another berga process (use --wait-lock to wait longer)", name)
+			}
+			return nil, fmt.Errorf("%s is locked by process %d on %s since %s (use --wait-lock to wait longer)",
+				name, info.PID, info.Host, info.Acquired.Format("15:04:05"))
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// withLock runs fn while holding the named lock
+func withLock(name string, fn func() error) error {
+	unlock, err := acquireLock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
+
+// withLocks runs fn while holding several store locks, taken in the given order
+func withLocks(names []string, fn func() error) error {
+	if len(names) == 0 {
+		return fn()
+	}
+	return withLock(names[0], func() error {
+		return withLocks(names[1:], fn)
+	})
+}
+
+// readLockFile reads a lock file and parses its owner
+func readLockFile(path string) ([]byte, lockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, lockInfo{}, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 {
+		return data, lockInfo{}, fmt.Errorf("malformed lock file")
+	}
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return data, lockInfo{}, fmt.Errorf("malformed lock file")
+	}
+	acquired, err := time.Parse(time.RFC3339, lines[2])
+	if err != nil {
+		return data, lockInfo{}, fmt.Errorf("malformed lock file")
+	}
+	return data, lockInfo{PID: pid, Host: lines[1], Acquired: acquired}, nil
+}
+
+// malformedLockExpired reports whether a lock file that can't be parsed is
+// old enough that its owner is not about to write to it
+func malformedLockExpired(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > malformedLockAge
+}
+
+// takeOverLock removes a stale lock whose content was stale. Two processes
+// finding the same stale lock must not both remove it: the second would
+// remove the lock the first has just taken. So removal happens under a
+// takeover guard, and only if the lock still holds the stale content. It
+// reports whether the lock may be free now; false means another process is
+// taking it over.
+func takeOverLock(path string, stale []byte) bool {
+	guard := path + ".takeover"
+	f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if info, err := os.Stat(guard); err == nil && time.Since(info.ModTime()) > takeoverGuardAge {
+			os.Remove(guard)
+			return true
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(guard)
+
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, stale) {
+		os.Remove(path)
+	}
+	return true
+}
+
+// lockIsStale reports whether a lock's owner is gone or has held it too long
+func lockIsStale(info lockInfo) bool {
+	if time.Since(info.Acquired) > staleLockAge {
+		return true
+	}
+	host, _ := os.Hostname()
+	if info.Host != host {
+		// A process on another machine sharing the directory can't be checked
+		return false
+	}
+	return !processAlive(info.PID)
+}
+
+// processAlive reports whether a process with the given pid is running
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// FindProcess only succeeds for live processes on Windows
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return streamFileAtomic(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// streamFileAtomic is writeFileAtomic for output produced by write, which
+// goes through a buffered writer straight to the temporary file. If write
+// fails the file at path is left as it was.
+func streamFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	buf := bufio.NewWriterSize(tmp, 64*1024)
+	if err := write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
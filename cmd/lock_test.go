@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setLockWait sets --wait-lock for a test
+func setLockWait(t *testing.T, wait time.Duration) {
+	t.Helper()
+	orig := waitLock
+	waitLock = wait
+	t.Cleanup(func() { waitLock = orig })
+}
+
+func TestAcquireLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setLockWait(t, 0)
+
+	unlock, err := acquireLock("test")
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	if _, err := acquireLock("test"); err == nil {
+		t.Fatal("Expected a held lock to be refused")
+	}
+
+	unlock()
+	unlock, err = acquireLock("test")
+	if err != nil {
+		t.Fatalf("Failed to re-acquire released lock: %v", err)
+	}
+	unlock()
+}
+
+func TestAcquireLockStale(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(GetLocksDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	host, _ := os.Hostname()
+	old := time.Now().Add(-2 * staleLockAge).Format(time.RFC3339)
+	path := filepath.Join(GetLocksDir(), "test.lock")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n%s\n%s\n", os.Getpid(), host, old)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := acquireLock("test")
+	if err != nil {
+		t.Fatalf("Expected stale lock to be taken over: %v", err)
+	}
+	unlock()
+}
+
+func TestAcquireLockMalformed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setLockWait(t, 0)
+	os.MkdirAll(GetLocksDir(), 0755)
+	path := filepath.Join(GetLocksDir(), "test.lock")
+
+	// A lock its owner has not written to yet is left alone
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireLock("test"); err == nil {
+		t.Fatal("Expected a new empty lock to be respected")
+	}
+
+	old := time.Now().Add(-2 * malformedLockAge)
+	os.Chtimes(path, old, old)
+	unlock, err := acquireLock("test")
+	if err != nil {
+		t.Fatalf("Expected an old malformed lock to be taken over: %v", err)
+	}
+	unlock()
+}
+
+func TestTakeOverLockRechecks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.lock")
+	os.WriteFile(path, []byte("2\nhost\nnew\n"), 0644)
+
+	// Another process replaced the stale lock after it was read
+	if !takeOverLock(path, []byte("1\nhost\nold\n")) {
+		t.Error("Expected the takeover to go ahead")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Expected the new owner's lock to be kept")
+	}
+
+	// A takeover in progress elsewhere
+	os.WriteFile(path+".takeover", nil, 0644)
+	if takeOverLock(path, []byte("2\nhost\nnew\n")) {
+		t.Error("Expected to wait for the other takeover")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Expected the lock to be left to the other takeover")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+
+	if err := writeFileAtomic(path, []byte("a: 1\n"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "a: 1\n" {
+		t.Errorf("Unexpected content %q (err %v)", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, found %d entries", len(entries))
+	}
+}
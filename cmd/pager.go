@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// showNoPager disables the pager for the show commands
+var showNoPager bool
+
+// ansiPattern matches the escape sequences added by syntax highlighting
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// displayLines counts the terminal rows text takes up when long lines
+// soft-wrap at width
+func displayLines(text string, width int) int {
+	if width <= 0 {
+		width = 80
+	}
+	rows := 0
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		cols := 0
+		for _, r := range ansiPattern.ReplaceAllString(line, "") {
+			if r == '\t' {
+				cols += 8 - cols%8
+			} else {
+				cols++
+			}
+		}
+		rows += 1 + max(cols-1, 0)/width
+	}
+	return rows
+}
+
+// pagerCommand returns the pager to use: the pager setting, then $PAGER,
+// then less if it is installed. "" or "cat" means no pager.
+func pagerCommand() string {
+	if pager := viper.GetString("pager"); pager != "" {
+		return pager
+	}
+	if pager, ok := os.LookupEnv("PAGER"); ok {
+		return pager
+	}
+	if _, err := exec.LookPath("less"); err == nil {
+		return "less"
+	}
+	return ""
+}
+
+// pageOutput writes text to stdout, through a pager when stdout is a
+// terminal and the text does not fit on one screen
+func pageOutput(text string, noPager bool) error {
+	pager := strings.TrimSpace(pagerCommand())
+	if noPager || pager == "" || pager == "cat" || !ui.IsTerminal(os.Stdout) {
+		_, err := io.WriteString(os.Stdout, text)
+		return err
+	}
+	width, height := terminalSize(os.Stdout)
+	if displayLines(text, width) < height {
+		_, err := io.WriteString(os.Stdout, text)
+		return err
+	}
+
+	cmd := hookCommand(pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git: quit if it fits, pass colors through, keep the screen
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+
+	if err := cmd.Start(); err != nil {
+		// A missing or broken pager should not hide the output
+		_, err := io.WriteString(os.Stdout, text)
+		return err
+	}
+	cmd.Wait()
+	return nil
+}
+
+// syntax describes how to highlight one language
+type syntax struct {
+	Comment  string
+	Keywords map[string]bool
+	Keys     bool // highlight "key:" at the start of lines, as in YAML
+}
+
+func keywords(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// syntaxes are the languages the show commands highlight
+var syntaxes = map[string]*syntax{
+	"shell":      {Comment: "#", Keywords: keywords("if then else elif fi for while until do done case esac function in return local export set shift exit")},
+	"python":     {Comment: "#", Keywords: keywords("def class if elif else for while return import from as with try except finally raise pass break continue in not and or is lambda yield None True False")},
+	"go":         {Comment: "//", Keywords: keywords("package import func return if else for range switch case default type struct interface var const go defer map chan nil true false")},
+	"js":         {Comment: "//", Keywords: keywords("function return if else for while const let var class new import export from async await try catch throw null undefined true false")},
+	"ruby":       {Comment: "#", Keywords: keywords("def end class module if elsif else unless while do return require yield nil true false")},
+	"powershell": {Comment: "#", Keywords: keywords("function param if elseif else foreach for while return try catch finally throw")},
+	"yaml":       {Comment: "#", Keys: true},
+	"text":       {},
+}
+
+// extensionSyntax maps file extensions to languages
+var extensionSyntax = map[string]string{
+	".sh":   "shell",
+	".bash": "shell",
+	".zsh":  "shell",
+	".py":   "python",
+	".go":   "go",
+	".js":   "js",
+	".mjs":  "js",
+	".ts":   "js",
+	".rb":   "ruby",
+	".ps1":  "powershell",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// shebangSyntax maps interpreters named in a shebang to languages
+var shebangSyntax = map[string]string{
+	"sh": "shell", "bash": "shell", "zsh": "shell", "dash": "shell", "ksh": "shell",
+	"python": "python", "python3": "python",
+	"node": "js",
+	"ruby": "ruby",
+	"pwsh": "powershell",
+}
+
+// detectSyntax picks a language from a file's extension, falling back to its
+// shebang. Names ending in .tmpl are detected from the name without it.
+func detectSyntax(name string, content string) *syntax {
+	name = strings.TrimSuffix(name, ".tmpl")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if lang, ok := extensionSyntax[strings.ToLower(name[i:])]; ok {
+			return syntaxes[lang]
+		}
+	}
+	if strings.HasPrefix(content, "#!") {
+		if interpreter := shebangInterpreter(strings.SplitN(content, "\n", 2)[0]); interpreter != "" {
+			if lang, ok := shebangSyntax[interpreter]; ok {
+				return syntaxes[lang]
+			}
+		}
+	}
+	return syntaxes["text"]
+}
+
+// shebangInterpreter returns the interpreter a shebang line runs, e.g.
+// "python3" for "#!/usr/bin/env python3"
+func shebangInterpreter(shebang string) string {
+	fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := fields[0][strings.LastIndex(fields[0], "/")+1:]
+	if name == "env" && len(fields) > 1 {
+		name = fields[1]
+	}
+	return name
+}
+
+// highlight colors comments, strings, and keywords for display. Template
+// actions in .tmpl files are highlighted on top of the underlying language.
+func highlight(name, content string) string {
+	if !ui.ColorEnabled() {
+		return content
+	}
+	return highlightText(name, content)
+}
+
+// highlightText styles content line by line
+func highlightText(name, content string) string {
+	syn := detectSyntax(name, content)
+	isTemplate := strings.HasSuffix(name, ".tmpl")
+
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		newline := line[len(body):]
+		if i == 0 && strings.HasPrefix(body, "#!") {
+			lines[i] = ui.Dim(body) + newline
+			continue
+		}
+		if isTemplate {
+			lines[i] = highlightTemplateLine(body, syn) + newline
+		} else {
+			lines[i] = highlightLine(body, syn) + newline
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// highlightTemplateLine highlights {{ ... }} actions and the text around them
+func highlightTemplateLine(line string, syn *syntax) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(line, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(line[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+		b.WriteString(highlightLine(line[:start], syn))
+		b.WriteString(ui.Yellow(line[start:end]))
+		line = line[end:]
+	}
+	b.WriteString(highlightLine(line, syn))
+	return b.String()
+}
+
+// highlightLine highlights one line of code
+func highlightLine(line string, syn *syntax) string {
+	if line == "" {
+		return line
+	}
+
+	var b strings.Builder
+	i := 0
+	if syn.Keys {
+		trimmed := strings.TrimLeft(line, " -")
+		if colon := strings.Index(trimmed, ":"); colon > 0 && !strings.ContainsAny(trimmed[:colon], " \"'#") {
+			i = len(line) - len(trimmed) + colon
+			b.WriteString(line[:len(line)-len(trimmed)])
+			b.WriteString(ui.Bold(trimmed[:colon]))
+		}
+	}
+
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case syn.Comment != "" && strings.HasPrefix(line[i:], syn.Comment) && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			b.WriteString(ui.Dim(line[i:]))
+			return b.String()
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' && c != '\'' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			b.WriteString(ui.Green(line[i:end]))
+			i = end
+		case isWordStart(c):
+			end := i + 1
+			for end < len(line) && (isWordStart(line[end]) || line[end] >= '0' && line[end] <= '9') {
+				end++
+			}
+			word := line[i:end]
+			if syn.Keywords[word] && (i == 0 || line[i-1] != '$' && line[i-1] != '.') {
+				word = ui.Cyan(word)
+			}
+			b.WriteString(word)
+			i = end
+		default:
+			_, size := utf8.DecodeRuneInString(line[i:])
+			b.WriteString(line[i : i+size])
+			i += size
+		}
+	}
+	return b.String()
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayLines(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  int
+	}{
+		{"a\nb\n", 80, 2},
+		{"", 80, 1},
+		{strings.Repeat("x", 80) + "\n", 80, 1},
+		{strings.Repeat("x", 81) + "\n", 80, 2},
+		{"\t\tx\n", 10, 2},
+		{"\x1b[32m" + strings.Repeat("x", 10) + "\x1b[0m\n", 10, 1},
+	}
+	for _, tt := range tests {
+		if got := displayLines(tt.text, tt.width); got != tt.want {
+			t.Errorf("displayLines(%q, %d) = %d, want %d", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDetectSyntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"deploy.sh", "", "shell"},
+		{"job", "#!/usr/bin/env python3\nprint(1)\n", "python"},
+		{"run", "#!/bin/bash\n", "shell"},
+		{"config.yaml.tmpl", "", "yaml"},
+		{"notes.txt", "", "text"},
+	}
+	for _, tt := range tests {
+		if got := detectSyntax(tt.name, tt.content); got != syntaxes[tt.want] {
+			t.Errorf("detectSyntax(%q) did not pick %s", tt.name, tt.want)
+		}
+	}
+}
+
+func TestHighlightKeepsText(t *testing.T) {
+	// Whatever the styling, stripping it must give back the original text
+	for name, content := range map[string]string{
+		"deploy.sh":        "#!/bin/sh\nif [ \"$x\" ]; then # check\n  echo ${#list} 'it''s'\nfi\n",
+		"config.yaml.tmpl": "name: {{.Name}}\n- item: \"quoted # not a comment\"\n",
+		"main.go":          "func main() { // start\n\tfmt.Println(`raw \\`)\n}\n",
+		"unterminated.py":  "print(\"oops\n",
+	} {
+		got := ansiPattern.ReplaceAllString(highlightText(name, content), "")
+		if got != content {
+			t.Errorf("highlight(%q) changed the text: %q", name, got)
+		}
+	}
+}
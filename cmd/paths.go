@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dataDirNames are the directories holding your own content. On Linux they
+// live under XDG_DATA_HOME rather than next to the config files.
+var dataDirNames = map[string]bool{"scripts": true, "templates": true, "snippets": true, "notes": true, "cheats": true, "dotfiles": true, "requests": true, ".versions": true}
+
+var migrateDryRun bool
+
+// configMigrateCmd moves ~/.berga to the platform's standard locations
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move ~/.berga to the standard directories and upgrade the config file",
+	Long: `Move berga's files from the legacy ~/.berga directory to the platform's
+standard locations: $XDG_CONFIG_HOME/berga and $XDG_DATA_HOME/berga on Linux,
+%APPDATA%\berga on Windows. On macOS ~/.berga is already the standard location.
+
+While ~/.berga exists it keeps being used, so nothing changes until you migrate.
+Run this while no other berga command is running.
+
+The config file is then upgraded to the current config version: settings from
+older layouts move to their current keys and a version key is recorded. The
+original is kept next to it as <file>.v<old version>.bak. Other commands apply
+the same upgrade automatically when an old layout needs changes; use --dry-run
+here to preview it first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := migrateLegacyRoot(migrateDryRun); err != nil {
+			return err
+		}
+
+		// The config file may have just moved out of ~/.berga
+		path := configFilePath()
+		if _, err := os.Stat(path); err != nil {
+			path = filepath.Join(GetConfigDir(), "config.yaml")
+		}
+		return withLock("config", func() error {
+			return migrateConfigFile(path, migrateDryRun)
+		})
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+
+	// Flags
+	configMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would be moved or changed without changing anything")
+}
+
+// legacyBergaRoot returns ~/.berga, where berga kept everything before it
+// followed platform conventions
+func legacyBergaRoot() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".berga")
+}
+
+// usingLegacyRoot reports whether ~/.berga exists and so is still in use
+func usingLegacyRoot() bool {
+	info, err := os.Stat(legacyBergaRoot())
+	return err == nil && info.IsDir()
+}
+
+// platformRoots returns the standard config and data directories for berga:
+// XDG_CONFIG_HOME and XDG_DATA_HOME on Linux and other Unixes, %APPDATA% on
+// Windows, and ~/.berga on macOS
+func platformRoots() (string, string) {
+	legacy := legacyBergaRoot()
+	switch runtime.GOOS {
+	case "darwin":
+		return legacy, legacy
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			dir := filepath.Join(appData, "berga")
+			return dir, dir
+		}
+		return legacy, legacy
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return legacy, legacy
+	}
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), "berga"),
+		filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(home, ".local", "share")), "berga")
+}
+
+// xdgDir reads an XDG base directory variable. Relative paths are invalid
+// per the spec and ignored.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return fallback
+}
+
+// expandHome expands a leading ~ and environment variables in a configured path
+func expandHome(p string) string {
+	p = os.ExpandEnv(p)
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// configuredPaths returns the directories set under the first of keys that
+// has any. The value may be a single path, a list, or paths joined with the
+// OS list separator.
+func configuredPaths(keys ...string) []string {
+	for _, key := range keys {
+		if dirs := configuredPathList(key); len(dirs) > 0 {
+			return dirs
+		}
+	}
+	return nil
+}
+
+// configuredPathList returns the directories set under one key
+func configuredPathList(key string) []string {
+	var raw []string
+	switch v := viper.Get(key).(type) {
+	case string:
+		raw = filepath.SplitList(v)
+	case []string:
+		raw = v
+	case []interface{}:
+		for _, item := range v {
+			raw = append(raw, fmt.Sprint(item))
+		}
+	}
+
+	var dirs []string
+	for _, p := range raw {
+		if p = strings.TrimSpace(p); p != "" {
+			dirs = append(dirs, filepath.Clean(expandHome(p)))
+		}
+	}
+	return dirs
+}
+
+// GetScriptsDirs returns every directory scripts are looked up in, in order,
+// from paths.scripts or scripts.paths. The first one is where new scripts go.
+func GetScriptsDirs() []string {
+	if dirs := configuredPaths("paths.scripts", "scripts.paths"); len(dirs) > 0 {
+		return dirs
+	}
+	return []string{filepath.Join(GetDataDir(), "scripts")}
+}
+
+// GetTemplatesDirs returns every directory templates are looked up in, in
+// order, from paths.templates or templates.paths. The first one is where new
+// templates go.
+func GetTemplatesDirs() []string {
+	if dirs := configuredPaths("paths.templates", "templates.paths"); len(dirs) > 0 {
+		return dirs
+	}
+	return []string{filepath.Join(GetDataDir(), "templates")}
+}
+
+// pathMove is one entry moved by 'config migrate'
+type pathMove struct {
+	From string
+	To   string
+}
+
+// legacyMoves plans where each entry of the legacy directory goes. Content
+// directories go to the data root, everything else to the config root; other
+// profiles are split the same way.
+func legacyMoves(legacy, configRoot, dataRoot string) ([]pathMove, error) {
+	entries, err := os.ReadDir(legacy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", legacy, err)
+	}
+
+	var moves []pathMove
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case dataDirNames[name]:
+			moves = append(moves, pathMove{filepath.Join(legacy, name), filepath.Join(dataRoot, name)})
+		case name == "profiles" && entry.IsDir():
+			profiles, err := os.ReadDir(filepath.Join(legacy, name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read profiles: %w", err)
+			}
+			for _, profile := range profiles {
+				if !profile.IsDir() {
+					moves = append(moves, pathMove{filepath.Join(legacy, name, profile.Name()), filepath.Join(configRoot, name, profile.Name())})
+					continue
+				}
+				sub, err := legacyMoves(filepath.Join(legacy, name, profile.Name()),
+					filepath.Join(configRoot, name, profile.Name()), filepath.Join(dataRoot, name, profile.Name()))
+				if err != nil {
+					return nil, err
+				}
+				moves = append(moves, sub...)
+			}
+		default:
+			moves = append(moves, pathMove{filepath.Join(legacy, name), filepath.Join(configRoot, name)})
+		}
+	}
+	return moves, nil
+}
+
+// movePath renames src to dst, copying across filesystems when a rename is
+// not possible
+func movePath(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	var linkErr *os.LinkError
+	if err == nil || !errors.As(err, &linkErr) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file or directory, keeping permissions and symlinks
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// removeEmptyDirs removes dir and any directories below it that are empty
+func removeEmptyDirs(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := removeEmptyDirs(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Remove(dir)
+}
+
+func migrateLegacyRoot(dryRun bool) error {
+	legacy := legacyBergaRoot()
+	if !usingLegacyRoot() {
+		fmt.Printf("No files to move: %s does not exist\n", legacy)
+		return nil
+	}
+	configRoot, dataRoot := platformRoots()
+	if configRoot == legacy {
+		fmt.Printf("%s is already the standard location on this platform\n", legacy)
+		return nil
+	}
+
+	moves, err := legacyMoves(legacy, configRoot, dataRoot)
+	if err != nil {
+		return err
+	}
+	for _, move := range moves {
+		if _, err := os.Lstat(move.To); err == nil {
+			return fmt.Errorf("%s already exists; move it aside and run 'berga config migrate' again", move.To)
+		}
+	}
+
+	for _, move := range moves {
+		fmt.Printf("  %s -> %s\n", move.From, move.To)
+		if dryRun {
+			continue
+		}
+		if err := movePath(move.From, move.To); err != nil {
+			return fmt.Errorf("failed to move %s: %w", move.From, err)
+		}
+	}
+	if dryRun {
+		fmt.Println("(dry run)")
+		return nil
+	}
+
+	if err := removeEmptyDirs(legacy); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", legacy, err)
+	}
+	fmt.Printf("\nMoved %d item(s). Config: %s, data: %s\n", len(moves), configRoot, dataRoot)
+	if _, err := os.Stat(filepath.Join(dataRoot, "dotfiles")); err == nil {
+		fmt.Println("Dotfiles linked from the old location need relinking: run 'berga dotfiles link'.")
+	}
+	return nil
+}
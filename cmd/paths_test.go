@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPlatformRoots(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG directories are only used on Linux and other Unixes")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("BERGA_PROFILE", "")
+
+	if got := GetConfigDir(); got != filepath.Join(home, ".config", "berga") {
+		t.Errorf("Expected ~/.config/berga without ~/.berga, got %s", got)
+	}
+	if got := GetScriptsDir(); got != filepath.Join(home, ".local", "share", "berga", "scripts") {
+		t.Errorf("Expected scripts under ~/.local/share/berga, got %s", got)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_DATA_HOME", "relative")
+	config, data := platformRoots()
+	if config != filepath.Join(home, "cfg", "berga") {
+		t.Errorf("Expected XDG_CONFIG_HOME to be honored, got %s", config)
+	}
+	if data != filepath.Join(home, ".local", "share", "berga") {
+		t.Errorf("Expected a relative XDG_DATA_HOME to be ignored, got %s", data)
+	}
+
+	if err := os.MkdirAll(filepath.Join(home, ".berga"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetScriptsDir(); got != filepath.Join(home, ".berga", "scripts") {
+		t.Errorf("Expected an existing ~/.berga to stay in use, got %s", got)
+	}
+}
+
+func TestConfiguredSearchPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("BERGA_PROFILE", "")
+	defer viper.Set("paths.scripts", nil)
+
+	first := filepath.Join(home, "first")
+	second := filepath.Join(home, "second")
+	viper.Set("paths.scripts", []interface{}{"~/first", second})
+	dirs := GetScriptsDirs()
+	if len(dirs) != 2 || dirs[0] != first || dirs[1] != second {
+		t.Fatalf("Unexpected search paths %v", dirs)
+	}
+
+	viper.Set("paths.scripts", first+string(os.PathListSeparator)+second)
+	if dirs := GetScriptsDirs(); len(dirs) != 2 || dirs[1] != second {
+		t.Fatalf("Expected a path list to be split, got %v", dirs)
+	}
+
+	for _, dir := range []string{first, second} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(second, "only.sh"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolveScriptPath("only.sh"); got != filepath.Join(second, "only.sh") {
+		t.Errorf("Expected a script from the second search path, got %s", got)
+	}
+	if got := resolveScriptPath("new.sh"); got != filepath.Join(first, "new.sh") {
+		t.Errorf("Expected a missing script to resolve to the first path, got %s", got)
+	}
+}
+
+func TestSearchPathAliases(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("BERGA_PROFILE", "")
+	defer viper.Set("templates.paths", nil)
+	defer viper.Set("paths.templates", nil)
+
+	viper.Set("templates.paths", []interface{}{"~/work", "~/shared"})
+	if dirs := GetTemplatesDirs(); len(dirs) != 2 || dirs[0] != filepath.Join(home, "work") {
+		t.Fatalf("Expected templates.paths to be used, got %v", dirs)
+	}
+	viper.Set("paths.templates", "~/mine")
+	if dirs := GetTemplatesDirs(); len(dirs) != 1 || dirs[0] != filepath.Join(home, "mine") {
+		t.Errorf("Expected paths.templates to win, got %v", dirs)
+	}
+}
+
+func TestMigrateLegacyRoot(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("migration moves to XDG directories on Linux")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("BERGA_PROFILE", "")
+
+	legacy := filepath.Join(home, ".berga")
+	for rel, content := range map[string]string{
+		"config.yaml":                    "editor: vim\n",
+		"scripts/deploy.sh":              "#!/bin/sh\n",
+		"profiles/work/config.yaml":      "shell: zsh\n",
+		"profiles/work/templates/a.tmpl": "a",
+		"current_profile":                "work\n",
+	} {
+		path := filepath.Join(legacy, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateLegacyRoot(true); err != nil {
+		t.Fatal(err)
+	}
+	if !usingLegacyRoot() {
+		t.Fatal("Expected --dry-run to leave ~/.berga in place")
+	}
+
+	if err := migrateLegacyRoot(false); err != nil {
+		t.Fatal(err)
+	}
+	if usingLegacyRoot() {
+		t.Error("Expected ~/.berga to be removed")
+	}
+	for _, path := range []string{
+		filepath.Join(home, ".config", "berga", "config.yaml"),
+		filepath.Join(home, ".config", "berga", "current_profile"),
+		filepath.Join(home, ".config", "berga", "profiles", "work", "config.yaml"),
+		filepath.Join(home, ".local", "share", "berga", "scripts", "deploy.sh"),
+		filepath.Join(home, ".local", "share", "berga", "profiles", "work", "templates", "a.tmpl"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s after migration: %v", path, err)
+		}
+	}
+}
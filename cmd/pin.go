@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Pin is a pinned script, optionally with a short name for 'berga run'
+type Pin struct {
+	Script string `yaml:"script"`
+	Name   string `yaml:"name,omitempty"`
+}
+
+var pinName string
+
+// scriptPinCmd pins a script, or lists the pins
+var scriptPinCmd = &cobra.Command{
+	Use:   "pin [script-name]",
+	Short: "Pin a script so it is listed first and runs with 'berga run'",
+	Long: `Pin a script. Pinned scripts are listed and completed before the others, and
+--as gives them a short name for 'berga run':
+
+  berga script pin deploy-production.sh --as dp
+  berga run dp
+
+Pinning a script again changes its short name. Without arguments, the pins
+are listed in order.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeScriptNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if len(args) == 0 {
+			return listPins()
+		}
+		return withLock("pins", func() error {
+			return pinScript(args[0], pinName)
+		})
+	},
+}
+
+// scriptUnpinCmd removes a pin
+var scriptUnpinCmd = &cobra.Command{
+	Use:   "unpin [script-or-pin-name]",
+	Short: "Unpin a script",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		pins, _ := loadPins()
+		var names []string
+		for _, pin := range pins {
+			if strings.HasPrefix(pin.Script, toComplete) {
+				names = append(names, pin.Script)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("pins", func() error {
+			return unpinScript(args[0])
+		})
+	},
+}
+
+// runCmd runs a pin, alias, or script by name
+var runCmd = &cobra.Command{
+	Use:   "run [name] [args...]",
+	Short: "Run a pinned script, alias, or script by name",
+	Long: `Run something with as little typing as possible. The name is looked up as
+the short name of a pinned script, then as an alias from the config, and
+then as a script name. Everything after the name is passed on, including
+flags:
+
+  berga run dp --timeout 10m          # pinned as dp
+  berga run ll                        # aliases.ll: "script list"
+  berga run backup.sh -- --full       # same as 'berga script run backup.sh -- --full'`,
+	DisableFlagParsing: true,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return runNames(toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+			return cmd.Help()
+		}
+		cmd.SilenceUsage = true
+		target, err := resolveRunTarget(args[0])
+		if err != nil {
+			return err
+		}
+		return dispatch(append(target, args[1:]...))
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptPinCmd)
+	scriptCmd.AddCommand(scriptUnpinCmd)
+	rootCmd.AddCommand(runCmd)
+
+	// Flags
+	scriptPinCmd.Flags().StringVar(&pinName, "as", "", "Short name to run the script by with 'berga run'")
+}
+
+func loadPins() ([]Pin, error) {
+	data, err := os.ReadFile(GetPinsFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pins: %w", err)
+	}
+
+	var pins []Pin
+	if err := yaml.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pins: %w", err)
+	}
+	return pins, nil
+}
+
+func savePins(pins []Pin) error {
+	data, err := yaml.Marshal(pins)
+	if err != nil {
+		return fmt.Errorf("failed to encode pins: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetPinsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write pins: %w", err)
+	}
+	return nil
+}
+
+// pinnedScripts returns the set of pinned script names
+func pinnedScripts() map[string]bool {
+	pins, _ := loadPins()
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		pinned[pin.Script] = true
+	}
+	return pinned
+}
+
+// pinsFirst moves the entries of pinned scripts to the front, keeping the
+// order within both parts
+func pinsFirst(files []os.DirEntry, pinned map[string]bool) []os.DirEntry {
+	if len(pinned) == 0 {
+		return files
+	}
+	var first, rest []os.DirEntry
+	for _, file := range files {
+		if pinned[file.Name()] {
+			first = append(first, file)
+		} else {
+			rest = append(rest, file)
+		}
+	}
+	return append(first, rest...)
+}
+
+func pinScript(scriptName, name string) error {
+	scriptPath := resolveScriptPath(scriptName)
+	if _, err := os.Stat(scriptPath); err != nil {
+		return fmt.Errorf("script '%s' not found in %s", scriptName, GetScriptsDir())
+	}
+	script := filepath.Base(scriptPath)
+
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	found := false
+	for i, pin := range pins {
+		if name != "" && pin.Name == name && pin.Script != script {
+			return fmt.Errorf("'%s' is already the short name of %s", name, pin.Script)
+		}
+		if pin.Script == script {
+			pins[i].Name = name
+			found = true
+		}
+	}
+	if !found {
+		pins = append(pins, Pin{Script: script, Name: name})
+	}
+	if err := savePins(pins); err != nil {
+		return err
+	}
+
+	if name != "" {
+		fmt.Printf("Pinned '%s' as '%s'\n", script, name)
+	} else {
+		fmt.Printf("Pinned '%s'\n", script)
+	}
+	return nil
+}
+
+func unpinScript(nameOrScript string) error {
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	kept := pins[:0]
+	var removed string
+	for _, pin := range pins {
+		if pin.Script == nameOrScript || pin.Name == nameOrScript || strings.TrimSuffix(pin.Script, filepath.Ext(pin.Script)) == nameOrScript {
+			removed = pin.Script
+			continue
+		}
+		kept = append(kept, pin)
+	}
+	if removed == "" {
+		return fmt.Errorf("'%s' is not pinned", nameOrScript)
+	}
+	if err := savePins(kept); err != nil {
+		return err
+	}
+	fmt.Printf("Unpinned '%s'\n", removed)
+	return nil
+}
+
+// renamePin keeps a renamed script pinned
+func renamePin(oldScript, newScript string) error {
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	for i, pin := range pins {
+		if pin.Script == oldScript {
+			pins[i].Script = newScript
+			return savePins(pins)
+		}
+	}
+	return nil
+}
+
+func listPins() error {
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	if len(pins) == 0 {
+		fmt.Println("No pinned scripts. Pin one with 'berga script pin <name>'.")
+		return nil
+	}
+	for _, pin := range pins {
+		line := "  " + ui.Icon("📌", "^") + " " + ui.Bold(pin.Script)
+		if pin.Name != "" {
+			line += ui.Dim(" (berga run " + pin.Name + ")")
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// resolveRunTarget turns a name given to 'berga run' into the berga command
+// line it stands for: a pin's script, an alias, or a script
+func resolveRunTarget(name string) ([]string, error) {
+	pins, err := loadPins()
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range pins {
+		if pin.Name == name {
+			return []string{"script", "run", pin.Script}, nil
+		}
+	}
+	if alias := strings.TrimSpace(viper.GetString("aliases." + name)); alias != "" {
+		return strings.Fields(alias), nil
+	}
+	if _, err := os.Stat(resolveScriptPath(name)); err == nil {
+		return []string{"script", "run", name}, nil
+	}
+	return nil, fmt.Errorf("'%s' is not a pinned script, an alias, or a script in %s", name, GetScriptsDir())
+}
+
+// runNames lists the names 'berga run' accepts starting with prefix: pin
+// names, then aliases, then scripts with pinned ones first
+func runNames(prefix string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if name != "" && !seen[name] && strings.HasPrefix(name, prefix) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	pins, _ := loadPins()
+	for _, pin := range pins {
+		add(pin.Name)
+	}
+	aliases := viper.GetStringMapString("aliases")
+	aliasNames := make([]string, 0, len(aliases))
+	for name := range aliases {
+		aliasNames = append(aliasNames, name)
+	}
+	sort.Strings(aliasNames)
+	for _, name := range aliasNames {
+		add(name)
+	}
+	scripts, _ := completeScriptNames(nil, nil, prefix)
+	for _, name := range scripts {
+		add(name)
+	}
+	return names
+}
+
+// dispatch runs the berga command given by args, as if they had been typed
+// after 'berga'
+func dispatch(args []string) error {
+	c, rest, err := rootCmd.Find(args)
+	if err != nil {
+		return err
+	}
+	// 'berga run' itself is not a target, so an alias cannot loop
+	if c == rootCmd || c.Name() == "run" && c.Parent() == rootCmd {
+		return fmt.Errorf("'%s' is not a berga command", strings.Join(args, " "))
+	}
+	if err := c.ParseFlags(rest); err != nil {
+		return err
+	}
+	positional := c.Flags().Args()
+	if err := c.ValidateArgs(positional); err != nil {
+		return err
+	}
+	switch {
+	case c.RunE != nil:
+		return c.RunE(c, positional)
+	case c.Run != nil:
+		c.Run(c, positional)
+		return nil
+	}
+	return c.Help()
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPinScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "deploy-production.sh", "#!/bin/sh\n")
+	writeTestScript(t, "backup.sh", "#!/bin/sh\n")
+
+	if err := pinScript("deploy-production.sh", "dp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinScript("backup.sh", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinScript("backup.sh", "dp"); err == nil {
+		t.Error("Expected a short name in use by another pin to be rejected")
+	}
+	if err := pinScript("missing.sh", ""); err == nil {
+		t.Error("Expected pinning a missing script to fail")
+	}
+
+	pins, err := loadPins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pin{{Script: "deploy-production.sh", Name: "dp"}, {Script: "backup.sh"}}
+	if !reflect.DeepEqual(pins, want) {
+		t.Errorf("Expected %+v, got %+v", want, pins)
+	}
+
+	if err := renamePin("backup.sh", "nightly.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if !pinnedScripts()["nightly.sh"] {
+		t.Error("Expected a renamed script to stay pinned")
+	}
+
+	if err := unpinScript("dp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unpinScript("dp"); err == nil {
+		t.Error("Expected unpinning twice to fail")
+	}
+	if pinned := pinnedScripts(); pinned["deploy-production.sh"] || len(pinned) != 1 {
+		t.Errorf("Unexpected pins after unpinning: %v", pinned)
+	}
+}
+
+func TestPinsFirst(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.sh", "b.sh", "c.sh", "d.sh"} {
+		os.WriteFile(dir+"/"+name, nil, 0644)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, file := range pinsFirst(files, map[string]bool{"d.sh": true, "b.sh": true}) {
+		got = append(got, file.Name())
+	}
+	if want := []string{"b.sh", "d.sh", "a.sh", "c.sh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestResolveRunTarget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "deploy.sh", "#!/bin/sh\n")
+	writeTestScript(t, "ll", "#!/bin/sh\n")
+	if err := pinScript("deploy.sh", "dp"); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("aliases", map[string]interface{}{"ll": "script list --long", "dp": "script list"})
+	defer viper.Set("aliases", nil)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"dp", []string{"script", "run", "deploy.sh"}},
+		{"ll", []string{"script", "list", "--long"}},
+		{"deploy.sh", []string{"script", "run", "deploy.sh"}},
+	}
+	for _, tt := range tests {
+		got, err := resolveRunTarget(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveRunTarget(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := resolveRunTarget("nothing"); err == nil {
+		t.Error("Expected an unknown name to fail")
+	}
+
+	if got := runNames("d"); !reflect.DeepEqual(got, []string{"dp", "deploy.sh"}) {
+		t.Errorf("Expected the pin name before the scripts, got %v", got)
+	}
+}
+
+func TestDispatchRejectsRun(t *testing.T) {
+	if err := dispatch([]string{"run", "x"}); err == nil {
+		t.Error("Expected 'run' to be rejected as a target")
+	}
+	if err := dispatch([]string{"no-such-command"}); err == nil {
+		t.Error("Expected an unknown command to be rejected")
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultProfile is the profile rooted directly at ~/.berga
+const defaultProfile = "default"
+
+// profileNamePattern restricts profile names to safe directory names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage separate berga profiles",
+	Long: `Keep separate sets of config, scripts, and templates, e.g. for work and
+personal use. The active profile is chosen by --profile, then the
+BERGA_PROFILE environment variable, then 'berga profile use'.
+
+The default profile lives in ~/.berga; others live in ~/.berga/profiles/<name>.`,
+}
+
+// profileListCmd lists profiles
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List profiles",
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listProfiles()
+	},
+}
+
+// profileCreateCmd creates a profile
+var profileCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return createProfile(args[0])
+	},
+}
+
+// profileUseCmd switches the active profile
+var profileUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Make a profile active for future commands",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return useProfile(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+}
+
+// getCurrentProfileFile returns the file recording 'berga profile use'
+func getCurrentProfileFile() string {
+	return filepath.Join(GetBergaRoot(), "current_profile")
+}
+
+// activeProfile returns the selected profile and what selected it
+func activeProfile() (string, string) {
+	if profileFlag != "" {
+		return profileFlag, "--profile"
+	}
+	if env := os.Getenv("BERGA_PROFILE"); env != "" {
+		return env, "BERGA_PROFILE"
+	}
+	if data, err := os.ReadFile(getCurrentProfileFile()); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name, "berga profile use"
+		}
+	}
+	return defaultProfile, ""
+}
+
+// profileDir returns the root directory of a profile
+func profileDir(name string) string {
+	if name == defaultProfile {
+		return GetBergaRoot()
+	}
+	return filepath.Join(GetProfilesDir(), name)
+}
+
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s' (use letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+// checkActiveProfile fails early when the selected profile does not exist.
+// The profile commands themselves are exempt so a bad selection can be fixed.
+func checkActiveProfile(cmd *cobra.Command) error {
+	if cmd.HasParent() && cmd.Parent() == profileCmd {
+		return nil
+	}
+	name, source := activeProfile()
+	if name == defaultProfile {
+		return nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return fmt.Errorf("%s (from %s)", err, source)
+	}
+	if _, err := os.Stat(profileDir(name)); err != nil {
+		return fmt.Errorf("profile '%s' (from %s) does not exist; create it with 'berga profile create %s'", name, source, name)
+	}
+	return nil
+}
+
+// listHeader prints a listing header, naming the active profile unless it is
+// the default one
+func listHeader(title string) {
+	if name, _ := activeProfile(); name != defaultProfile {
+		title = i18n.T("header.profile", title, name)
+	}
+	ui.Header(title)
+}
+
+// printActiveProfile prints the active profile for the config commands
+func printActiveProfile() {
+	name, source := activeProfile()
+	if source != "" {
+		name += " (from " + source + ")"
+	}
+	fmt.Printf("Profile: %s\n", name)
+}
+
+// profileNames returns every profile, default first
+func profileNames() []string {
+	names := []string{defaultProfile}
+	entries, err := os.ReadDir(GetProfilesDir())
+	if err != nil {
+		return names
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != defaultProfile && profileNamePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func listProfiles() error {
+	active, source := activeProfile()
+
+	ui.Header("Profiles")
+	for _, name := range profileNames() {
+		marker := " "
+		label := ui.Bold(name)
+		if name == active {
+			marker = ui.Green("*")
+			if source != "" {
+				label += " " + ui.Dim("(active, from "+source+")")
+			} else {
+				label += " " + ui.Dim("(active)")
+			}
+		}
+		fmt.Printf("  %s %s %s\n", marker, label, ui.Dim(profileDir(name)))
+	}
+	return nil
+}
+
+func createProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	if name == defaultProfile {
+		return fmt.Errorf("the default profile always exists")
+	}
+	if _, err := os.Stat(profileDir(name)); err == nil {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+
+	// Initialize the new profile's directories with the profile selected
+	previous := profileFlag
+	profileFlag = name
+	defer func() { profileFlag = previous }()
+	if err := withLock("config", initializeBergaConfig); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nProfile '%s' created. Switch to it with 'berga profile use %s'.\n", name, name)
+	return nil
+}
+
+func useProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(profileDir(name)); err != nil {
+		return fmt.Errorf("profile '%s' does not exist; create it with 'berga profile create %s'", name, name)
+	}
+
+	if name == defaultProfile {
+		if err := os.Remove(getCurrentProfileFile()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset profile: %w", err)
+		}
+	} else {
+		if err := os.MkdirAll(GetBergaRoot(), 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := writeFileAtomic(getCurrentProfileFile(), []byte(name+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to save profile: %w", err)
+		}
+	}
+
+	fmt.Printf("Now using profile '%s'\n", name)
+	if env := os.Getenv("BERGA_PROFILE"); env != "" && env != name {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: BERGA_PROFILE=%s overrides this in the current shell", env)))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestActiveProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("BERGA_PROFILE", "")
+
+	// An existing ~/.berga keeps the legacy layout on every platform
+	if err := os.MkdirAll(filepath.Join(home, ".berga"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := activeProfile(); name != defaultProfile {
+		t.Errorf("Expected default profile, got %s", name)
+	}
+	if got := GetConfigDir(); got != filepath.Join(home, ".berga") {
+		t.Errorf("Expected default profile at ~/.berga, got %s", got)
+	}
+
+	if err := os.MkdirAll(GetBergaRoot(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getCurrentProfileFile(), []byte("work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name, source := activeProfile(); name != "work" || source != "berga profile use" {
+		t.Errorf("Expected work from 'profile use', got %s from %s", name, source)
+	}
+	if got := GetScriptsDir(); got != filepath.Join(home, ".berga", "profiles", "work", "scripts") {
+		t.Errorf("Unexpected scripts dir %s", got)
+	}
+
+	t.Setenv("BERGA_PROFILE", "personal")
+	if name, _ := activeProfile(); name != "personal" {
+		t.Errorf("Expected BERGA_PROFILE to win over 'profile use', got %s", name)
+	}
+
+	profileFlag = "other"
+	defer func() { profileFlag = "" }()
+	if name, source := activeProfile(); name != "other" || source != "--profile" {
+		t.Errorf("Expected --profile to win, got %s from %s", name, source)
+	}
+}
+
+func TestCreateAndUseProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BERGA_PROFILE", "")
+
+	if err := createProfile("work"); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(GetDataRoot(), "profiles", "work", "scripts")); err != nil {
+		t.Errorf("Expected profile scripts directory: %v", err)
+	}
+	if err := createProfile("work"); err == nil {
+		t.Error("Expected creating an existing profile to fail")
+	}
+	if err := createProfile("../evil"); err == nil {
+		t.Error("Expected an invalid profile name to be rejected")
+	}
+
+	if err := useProfile("missing"); err == nil {
+		t.Error("Expected using a missing profile to fail")
+	}
+	if err := useProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := activeProfile(); name != "work" {
+		t.Errorf("Expected work to be active, got %s", name)
+	}
+	if got := profileNames(); len(got) != 2 || got[1] != "work" {
+		t.Errorf("Unexpected profiles %v", got)
+	}
+
+	if err := useProfile(defaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := activeProfile(); name != defaultProfile {
+		t.Errorf("Expected default to be active again, got %s", name)
+	}
+}
+
+func TestCheckActiveProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BERGA_PROFILE", "ghost")
+
+	err := checkActiveProfile(scriptListCmd)
+	if err == nil || !strings.Contains(err.Error(), "BERGA_PROFILE") {
+		t.Errorf("Expected a missing profile error naming its source, got %v", err)
+	}
+	if err := checkActiveProfile(profileUseCmd); err != nil {
+		t.Errorf("Expected profile commands to be exempt, got %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/scripts"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// projectFileName is the per-project config file discovered from the current directory
+const projectFileName = ".berga.yaml"
+
+// bergaFileSettings are the parts of a config file that viper can't carry
+// because they are case-sensitive (template variables, environment variables)
+// or relative to the file's location (scripts directory)
+type bergaFileSettings struct {
+	Scripts struct {
+		Dir string `yaml:"dir"`
+	} `yaml:"scripts"`
+	Templates struct {
+		Vars map[string]interface{} `yaml:"vars"`
+	} `yaml:"templates"`
+	Env struct {
+		Default  string                       `yaml:"default"`
+		Profiles map[string]map[string]string `yaml:"profiles"`
+	} `yaml:"env"`
+}
+
+// projectKeys are the settings a project file may set. A repository can be
+// cloned from anywhere, so its .berga.yaml is limited to settings about the
+// project; the rest, such as editor, secrets, or scripts.require_trust, only
+// come from your own config files.
+var projectKeys = []string{
+	"scripts.timeout",
+	"scripts.grace",
+	"scripts.retries",
+	"scripts.retry_delay",
+	"scripts.overrides",
+	"templates.vars",
+	"groups",
+}
+
+// projectTrustedKeys run commands or decide what runs: env can set PATH or
+// LD_PRELOAD for every script, and scripts.dir can shadow your scripts. A
+// project file's values are only used once the file is trusted with 'berga
+// project trust'.
+var projectTrustedKeys = []string{"hooks", "aliases", "env", "scripts.dir"}
+
+// ProjectConfig is the project file found for the current directory
+type ProjectConfig struct {
+	Root string
+	File string
+	bergaFileSettings
+}
+
+// project is the active project, or nil outside a project
+var project *ProjectConfig
+
+// globalSettings holds the case-sensitive settings of the global config file
+var globalSettings bergaFileSettings
+
+// findProjectFile walks up from dir looking for a project file. The home
+// directory is not searched since ~/.berga.yaml is the global config.
+func findProjectFile(dir string) string {
+	home, _ := os.UserHomeDir()
+	for {
+		if dir == home {
+			return ""
+		}
+		candidate := filepath.Join(dir, projectFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadFileSettings reads the case-sensitive settings from a config file
+func loadFileSettings(path string) (bergaFileSettings, map[string]interface{}, error) {
+	var settings bergaFileSettings
+	raw := make(map[string]interface{})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings, nil, err
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return settings, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return settings, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return settings, raw, nil
+}
+
+// loadProjectConfig discovers the project file for the current directory and
+// merges it over the global config
+func loadProjectConfig() error {
+	if used := viper.ConfigFileUsed(); used != "" {
+		if settings, _, err := loadFileSettings(used); err == nil {
+			globalSettings = settings
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	path := findProjectFile(cwd)
+	if path == "" {
+		return nil
+	}
+	if abs, err := filepath.Abs(viper.ConfigFileUsed()); err == nil && abs == path {
+		return nil
+	}
+
+	settings, raw, err := loadFileSettings(path)
+	if err != nil {
+		return err
+	}
+	allowed := projectKeys
+	trusted := projectFileTrusted(path)
+	if trusted {
+		allowed = append(append([]string(nil), projectKeys...), projectTrustedKeys...)
+	}
+	if !trusted {
+		settings.Scripts.Dir = ""
+		settings.Env = bergaFileSettings{}.Env
+	}
+	raw, ignored := filterProjectConfig(raw, "", allowed)
+	for _, key := range ignored {
+		if !trusted && matchesKeyPrefix(projectTrustedKeys, key) {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: ignoring %s in %s until you review the file and run 'berga project trust'", key, path)))
+		} else if viper.GetBool("verbose") {
+			fmt.Fprintf(os.Stderr, "Ignoring %s in %s; it can only be set in your own config\n", key, path)
+		}
+	}
+	if err := viper.MergeConfigMap(raw); err != nil {
+		return fmt.Errorf("failed to merge project config: %w", err)
+	}
+
+	project = &ProjectConfig{Root: filepath.Dir(path), File: path, bergaFileSettings: settings}
+	if project.Scripts.Dir != "" && !filepath.IsAbs(project.Scripts.Dir) {
+		project.Scripts.Dir = filepath.Join(project.Root, project.Scripts.Dir)
+	}
+	return nil
+}
+
+// matchesKeyPrefix reports whether key is one of keys or below one of them
+func matchesKeyPrefix(keys []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterProjectConfig keeps the settings of a project file that are allowed,
+// or lie below an allowed key, and returns the dotted paths of the rest
+func filterProjectConfig(raw map[string]interface{}, prefix string, allowed []string) (map[string]interface{}, []string) {
+	kept := make(map[string]interface{})
+	var ignored []string
+	for name, value := range raw {
+		key := strings.ToLower(prefix + name)
+		if matchesKeyPrefix(allowed, key) {
+			kept[name] = value
+			continue
+		}
+		nested, isMap := value.(map[string]interface{})
+		below := false
+		for _, k := range allowed {
+			below = below || strings.HasPrefix(k, key+".")
+		}
+		if !isMap || !below {
+			ignored = append(ignored, key)
+			continue
+		}
+		sub, subIgnored := filterProjectConfig(nested, key+".", allowed)
+		if len(sub) > 0 {
+			kept[name] = sub
+		}
+		ignored = append(ignored, subIgnored...)
+	}
+	sort.Strings(ignored)
+	return kept, ignored
+}
+
+// projectTrustKey is the trust store key of a project file
+func projectTrustKey(path string) string {
+	return "project:" + path
+}
+
+// projectFileTrusted reports whether a project file is unchanged since it
+// was trusted
+func projectFileTrusted(path string) bool {
+	store, err := loadTrustStore()
+	if err != nil {
+		return false
+	}
+	trusted, ok := store[projectTrustKey(path)]
+	if !ok {
+		return false
+	}
+	sum, err := fileChecksum(path)
+	return err == nil && sum == trusted
+}
+
+// GetProjectScriptsDir returns the active project's scripts directory, or ""
+func GetProjectScriptsDir() string {
+	if project == nil {
+		return ""
+	}
+	return project.Scripts.Dir
+}
+
+// resolveScriptPath returns where a script lives, preferring the project's
+// scripts directory and then each of the global search paths in order. An
+// encrypted script is found by its plain name as well. A script that does not
+// exist yet resolves to the first global directory.
+func resolveScriptPath(scriptName string) string {
+	if path, ok := (scripts.DirStore{Dirs: scriptSearchDirs()}).Find(scriptName); ok {
+		return path
+	}
+	return filepath.Join(GetScriptsDir(), scriptName)
+}
+
+// scriptSearchDirs returns the directories scripts are looked up in: the
+// project's scripts directory, if any, then the global search paths
+func scriptSearchDirs() []string {
+	dirs := GetScriptsDirs()
+	if dir := GetProjectScriptsDir(); dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return dirs
+}
+
+// scriptTrustKey returns the trust store key for a script. Project scripts are
+// keyed by path so same-named scripts in different projects stay distinct.
+func scriptTrustKey(scriptName, scriptPath string) string {
+	scriptPath = storedScriptPath(scriptPath)
+	if filepath.Dir(scriptPath) == GetScriptsDir() {
+		return scriptName
+	}
+	return scriptPath
+}
+
+// projectTemplateVars returns template variables from the global and project
+// config files, with the project taking precedence
+func projectTemplateVars() map[string]interface{} {
+	vars := make(map[string]interface{})
+	for k, v := range globalSettings.Templates.Vars {
+		vars[k] = v
+	}
+	if project != nil {
+		for k, v := range project.Templates.Vars {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// envProfiles returns the environment profiles from the global and project
+// config files; a project profile replaces a global one of the same name
+func envProfiles() map[string]map[string]string {
+	profiles := make(map[string]map[string]string)
+	for name, env := range globalSettings.Env.Profiles {
+		profiles[name] = env
+	}
+	if project != nil {
+		for name, env := range project.Env.Profiles {
+			profiles[name] = env
+		}
+	}
+	return profiles
+}
+
+// envProfileNames returns the known profile names in sorted order
+func envProfileNames() []string {
+	var names []string
+	for name := range envProfiles() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envProfileName returns name, or the configured default env profile when
+// name is empty
+func envProfileName(name string) string {
+	if name == "" {
+		name = globalSettings.Env.Default
+		if project != nil && project.Env.Default != "" {
+			name = project.Env.Default
+		}
+	}
+	return name
+}
+
+// resolveEnvProfile returns the variables of the named profile, or of the
+// configured default when name is empty
+func resolveEnvProfile(name string) (map[string]string, error) {
+	if name = envProfileName(name); name == "" {
+		return nil, nil
+	}
+
+	env, ok := envProfiles()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown env profile '%s' (known profiles: %v)", name, envProfileNames())
+	}
+	return env, nil
+}
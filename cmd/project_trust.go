@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// projectCmd groups the commands about the project file in use
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the project .berga.yaml in use",
+}
+
+// projectTrustCmd records the checksum of the project file
+var projectTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Allow the project file's hooks, aliases, env profiles, and scripts.dir",
+	Long: `A project .berga.yaml can only set project settings: script timeouts and
+retries, template variables, and groups. Hooks, aliases, env profiles, and
+scripts.dir decide what runs and with which environment, so they are ignored
+until you review the file and trust it. Trust is tied to the file's SHA-256
+checksum: after the file changes, those settings are ignored again until it
+is trusted again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("trust", trustProjectFile)
+	},
+}
+
+var projectUntrustCmd = &cobra.Command{
+	Use:   "untrust",
+	Short: "Ignore the project file's hooks, aliases, env profiles, and scripts.dir again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("trust", untrustProjectFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+	projectCmd.AddCommand(projectTrustCmd)
+	projectCmd.AddCommand(projectUntrustCmd)
+}
+
+// currentProjectFile returns the project file for the current directory
+func currentProjectFile() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	path := findProjectFile(cwd)
+	if path == "" {
+		return "", fmt.Errorf("no %s found in this directory or above it", projectFileName)
+	}
+	return path, nil
+}
+
+func trustProjectFile() error {
+	path, err := currentProjectFile()
+	if err != nil {
+		return err
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return fmt.Errorf("failed to checksum project file: %w", err)
+	}
+	store, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+	store[projectTrustKey(path)] = sum
+	if err := saveTrustStore(store); err != nil {
+		return err
+	}
+	fmt.Printf("Trusted %s (sha256 %s)\n", path, sum[:12])
+	return nil
+}
+
+func untrustProjectFile() error {
+	path, err := currentProjectFile()
+	if err != nil {
+		return err
+	}
+	store, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+	if _, ok := store[projectTrustKey(path)]; !ok {
+		return fmt.Errorf("%s is not trusted", path)
+	}
+	delete(store, projectTrustKey(path))
+	if err := saveTrustStore(store); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s from the trust store\n", path)
+	return nil
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"berga/internal/ui"
+)
+
+// errPromptInterrupted is returned when a prompt is cancelled with Ctrl+C
+var errPromptInterrupted = errors.New("interrupted")
+
+// lineEditing reports whether prompts can switch the terminal to raw mode for
+// line editing. Otherwise they read plain lines from stdin. Tests replace it.
+var lineEditing = func() bool {
+	return ui.IsTerminal(os.Stdin) && os.Getenv("TERM") != "dumb"
+}
+
+// editResult is what a prompt should do after a key press
+type editResult int
+
+const (
+	editContinue editResult = iota
+	editDone
+	editCancel
+	editEOF
+)
+
+// lineEditor is the text of a prompt being edited and the cursor in it
+type lineEditor struct {
+	buf []rune
+	pos int
+}
+
+// handleKey applies one key from decodeKeys. The usual readline keys work:
+// arrows, Home/End, Ctrl+A/E to move, Ctrl+U/K/W to delete, and Ctrl+D to
+// end input on an empty line.
+func (e *lineEditor) handleKey(key string) editResult {
+	switch key {
+	case "enter":
+		return editDone
+	case "ctrl+c":
+		return editCancel
+	case "ctrl+d":
+		if len(e.buf) == 0 {
+			return editEOF
+		}
+		e.deleteRange(e.pos, e.pos+1)
+	case "left":
+		e.pos = max(e.pos-1, 0)
+	case "right":
+		e.pos = min(e.pos+1, len(e.buf))
+	case "home", "ctrl+a":
+		e.pos = 0
+	case "end", "ctrl+e":
+		e.pos = len(e.buf)
+	case "backspace":
+		e.deleteRange(e.pos-1, e.pos)
+	case "delete":
+		e.deleteRange(e.pos, e.pos+1)
+	case "ctrl+u":
+		e.deleteRange(0, e.pos)
+	case "ctrl+k":
+		e.deleteRange(e.pos, len(e.buf))
+	case "ctrl+w":
+		start := e.pos
+		for start > 0 && unicode.IsSpace(e.buf[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(e.buf[start-1]) {
+			start--
+		}
+		e.deleteRange(start, e.pos)
+	case "tab":
+		e.insert(' ')
+	default:
+		if r, size := utf8.DecodeRuneInString(key); size == len(key) && unicode.IsPrint(r) {
+			e.insert(r)
+		}
+	}
+	return editContinue
+}
+
+func (e *lineEditor) insert(r rune) {
+	e.buf = append(e.buf[:e.pos], append([]rune{r}, e.buf[e.pos:]...)...)
+	e.pos++
+}
+
+// deleteRange removes buf[from:to], clamped to the text
+func (e *lineEditor) deleteRange(from, to int) {
+	from, to = max(from, 0), min(to, len(e.buf))
+	if from >= to {
+		return
+	}
+	e.buf = append(e.buf[:from], e.buf[to:]...)
+	if e.pos > to {
+		e.pos -= to - from
+	} else if e.pos > from {
+		e.pos = from
+	}
+}
+
+// draw redraws the prompt line with the cursor in place. Secret input is not
+// echoed.
+func (e *lineEditor) draw(w io.Writer, prompt string, secret bool) {
+	if secret {
+		fmt.Fprintf(w, "\r\033[K%s", prompt)
+		return
+	}
+	fmt.Fprintf(w, "\r\033[K%s%s", prompt, string(e.buf))
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(w, "\033[%dD", back)
+	}
+}
+
+// promptText formats a prompt label with its default in brackets. Secret
+// defaults are not shown.
+func promptText(label, def string, secret bool) string {
+	if def != "" && !secret {
+		return fmt.Sprintf("%s [%s]: ", label, def)
+	}
+	return label + ": "
+}
+
+// promptLine asks for a line of input on promptOut. An empty answer gives def.
+// With secret set the input is not echoed. In a terminal the line can be
+// edited; otherwise a plain line is read from stdin.
+func promptLine(label, def string, secret bool) (string, error) {
+	prompt := promptText(label, def, secret)
+
+	var restore func()
+	if lineEditing() {
+		restore, _ = makeRaw(os.Stdin)
+	}
+	if restore == nil {
+		fmt.Fprint(promptOut, prompt)
+		input, err := readLine()
+		if err != nil {
+			return input, err
+		}
+		if input == "" {
+			input = def
+		}
+		return input, nil
+	}
+	defer restore()
+
+	e := &lineEditor{}
+	buf := make([]byte, 64)
+	for {
+		e.draw(promptOut, prompt, secret)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			fmt.Fprint(promptOut, "\r\n")
+			return string(e.buf), err
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			switch e.handleKey(key) {
+			case editDone:
+				fmt.Fprint(promptOut, "\r\n")
+				if len(e.buf) == 0 {
+					return def, nil
+				}
+				return string(e.buf), nil
+			case editCancel:
+				fmt.Fprint(promptOut, "\r\n")
+				return "", errPromptInterrupted
+			case editEOF:
+				fmt.Fprint(promptOut, "\r\n")
+				return "", io.EOF
+			}
+		}
+	}
+}
+
+// promptSelect asks for one of options. In a terminal the options are a list
+// to move through with the arrow keys; otherwise they are numbered and either
+// the number or the option itself can be typed. An empty answer gives def.
+func promptSelect(label string, options []string, def string) (string, error) {
+	var restore func()
+	if lineEditing() {
+		restore, _ = makeRaw(os.Stdin)
+	}
+	if restore == nil {
+		fmt.Fprintf(promptOut, "%s:\n", label)
+		for i, option := range options {
+			fmt.Fprintf(promptOut, "  %d) %s\n", i+1, option)
+		}
+		input, err := promptLine("Choose", def, false)
+		if err != nil {
+			return input, err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(input)); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1], nil
+		}
+		return input, nil
+	}
+	defer restore()
+
+	cursor := 0
+	for i, option := range options {
+		if option == def {
+			cursor = i
+		}
+	}
+
+	fmt.Fprint(promptOut, "\033[?25l")
+	defer fmt.Fprint(promptOut, "\033[?25h")
+	buf := make([]byte, 64)
+	for drawn := false; ; drawn = true {
+		if drawn {
+			fmt.Fprintf(promptOut, "\033[%dA", len(options)+1)
+		}
+		fmt.Fprintf(promptOut, "\r\033[K%s: %s\r\n", label, ui.Dim("(↑/↓ to move, enter to choose)"))
+		for i, option := range options {
+			line := "  " + option
+			if i == cursor {
+				line = ui.Cyan("> " + option)
+			}
+			fmt.Fprintf(promptOut, "\r\033[K%s\r\n", line)
+		}
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			switch key {
+			case "up", "ctrl+p", "k":
+				cursor = (cursor - 1 + len(options)) % len(options)
+			case "down", "ctrl+n", "j", "tab":
+				cursor = (cursor + 1) % len(options)
+			case "enter":
+				// Collapse the list into the answer
+				fmt.Fprintf(promptOut, "\033[%dA\r\033[J%s: %s\r\n", len(options)+1, label, options[cursor])
+				return options[cursor], nil
+			case "ctrl+c", "esc":
+				return "", errPromptInterrupted
+			default:
+				if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(options) {
+					cursor = n - 1
+				}
+			}
+		}
+	}
+}
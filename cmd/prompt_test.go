@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want string
+		pos  int
+	}{
+		{"typing", []string{"h", "i", " ", "y", "o", "u"}, "hi you", 6},
+		{"insert in the middle", []string{"a", "c", "left", "b"}, "abc", 2},
+		{"home and end", []string{"b", "home", "a", "end", "c"}, "abc", 3},
+		{"backspace and delete", []string{"a", "b", "c", "backspace", "home", "delete"}, "b", 0},
+		{"kill to start", []string{"a", "b", "left", "ctrl+u"}, "b", 0},
+		{"kill to end", []string{"a", "b", "left", "ctrl+k"}, "a", 1},
+		{"delete word", []string{"o", "n", "e", " ", "t", "w", "o", " ", "ctrl+w"}, "one ", 4},
+		{"cursor stays in bounds", []string{"left", "a", "right", "right"}, "a", 1},
+		{"control keys are not inserted", []string{"a", "backtab", "up"}, "a", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &lineEditor{}
+			for _, key := range tt.keys {
+				if result := e.handleKey(key); result != editContinue {
+					t.Fatalf("Unexpected result %v for %q", result, key)
+				}
+			}
+			if string(e.buf) != tt.want || e.pos != tt.pos {
+				t.Errorf("Expected %q at %d, got %q at %d", tt.want, tt.pos, string(e.buf), e.pos)
+			}
+		})
+	}
+
+	e := &lineEditor{}
+	if e.handleKey("ctrl+d") != editEOF || e.handleKey("ctrl+c") != editCancel || e.handleKey("enter") != editDone {
+		t.Error("Expected Ctrl+D on an empty line, Ctrl+C, and Enter to end the prompt")
+	}
+}
+
+func TestPromptText(t *testing.T) {
+	if got := promptText("Port", "8080", false); got != "Port [8080]: " {
+		t.Errorf("Unexpected prompt %q", got)
+	}
+	if got := promptText("Token", "abc", true); got != "Token: " {
+		t.Errorf("Expected a secret default to be hidden, got %q", got)
+	}
+}
+
+func TestPromptFallback(t *testing.T) {
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	promptOut = io.Discard
+
+	stdinReader = bufio.NewReader(strings.NewReader("New York City\n\n2\nprod\n\n"))
+	if got, err := promptLine("City", "Oslo", false); err != nil || got != "New York City" {
+		t.Errorf("Expected the whole line, got %q, %v", got, err)
+	}
+	if got, _ := promptLine("City", "Oslo", false); got != "Oslo" {
+		t.Errorf("Expected the default for an empty answer, got %q", got)
+	}
+
+	options := []string{"dev", "staging", "prod"}
+	if got, _ := promptSelect("Environment", options, "dev"); got != "staging" {
+		t.Errorf("Expected option 2 to be chosen by number, got %q", got)
+	}
+	if got, _ := promptSelect("Environment", options, "dev"); got != "prod" {
+		t.Errorf("Expected an option to be chosen by name, got %q", got)
+	}
+	if got, _ := promptSelect("Environment", options, "dev"); got != "dev" {
+		t.Errorf("Expected the default for an empty answer, got %q", got)
+	}
+}
+
+func TestSecretVarsAreNotRecorded(t *testing.T) {
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	stdinReader = bufio.NewReader(strings.NewReader("s3cret\n"))
+	promptOut = io.Discard
+
+	recordedAnswers = &AnswerFile{Vars: make(map[string]string), Confirmations: make(map[string]bool)}
+	defer func() { recordedAnswers = nil }()
+
+	schema := &TemplateSchema{Variables: []TemplateVar{{Name: "Token", Secret: true, Required: true}}}
+	vars := make(map[string]interface{})
+	if err := collectSchemaVars(schema, vars, false); err != nil {
+		t.Fatal(err)
+	}
+	if vars["Token"] != "s3cret" {
+		t.Errorf("Expected the secret to be collected, got %v", vars["Token"])
+	}
+	if _, ok := recordedAnswers.Vars["Token"]; ok {
+		t.Error("Expected the secret to stay out of the answer file")
+	}
+}
@@ -0,0 +1,629 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// registryNamePattern limits registry names to what reads well before the
+// slash in 'template install <registry>/<name>'
+var registryNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+var installForce bool
+
+// Install records a template or script installed from a registry
+type Install struct {
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+	Registry string `yaml:"registry"`
+	Path     string `yaml:"path"`
+	Version  string `yaml:"version,omitempty"`
+	Commit   string `yaml:"commit"`
+	Checksum string `yaml:"checksum"`
+}
+
+// registryCmd manages template and script registries
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage registries of shared templates and scripts",
+	Long: `A registry is a git repository of templates and scripts, added under a short
+name and kept up to date with 'registry update'. Templates live in its
+templates/ directory and scripts in scripts/:
+
+  berga registry add company git@github.com:acme/berga-pack.git
+  berga template install company/k8s-deploy
+  berga script install company/deploy.sh@v1.2.0
+
+Registries are stored as registries.<name> in the config file.`,
+}
+
+var registryAddCmd = &cobra.Command{
+	Use:   "add [name] [url]",
+	Short: "Add a registry and clone it",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("registries", func() error {
+			return addRegistry(args[0], args[1])
+		})
+	},
+}
+
+var registryRemoveCmd = &cobra.Command{
+	Use:               "remove [name]",
+	Short:             "Remove a registry; installed templates and scripts are kept",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRegistryNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("registries", func() error {
+			return removeRegistry(args[0])
+		})
+	},
+}
+
+var registryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registries with their version and contents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return listRegistries()
+	},
+}
+
+var registryUpdateCmd = &cobra.Command{
+	Use:   "update [name...]",
+	Short: "Fetch registries and update what was installed from them",
+	Long: `Fetch the latest version of each registry (or the named ones) and update the
+templates and scripts installed from them. Installs pinned to a version with
+@version stay at it, and files changed since they were installed are left
+alone with a warning.`,
+	ValidArgsFunction: completeRegistryNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("registries", func() error {
+			return updateRegistries(args)
+		})
+	},
+}
+
+// installCommand returns the 'install' subcommand for templates or scripts
+func installCommand(kind string) *cobra.Command {
+	example := "company/k8s-deploy"
+	if kind == "script" {
+		example = "company/deploy.sh"
+	}
+	return &cobra.Command{
+		Use:   "install [registry/name[@version]]",
+		Short: fmt.Sprintf("Install a %s from a registry", kind),
+		Long: fmt.Sprintf(`Copy a %[1]s from a registry added with 'berga registry add'. A version
+(a tag, branch, or commit) pins the %[1]s to it; without one, 'registry
+update' keeps it at the latest version.
+
+  berga %[1]s install %[2]s
+  berga %[1]s install %[2]s@v1.2.0
+
+Existing files are only replaced with --force.`, kind, example),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return withLock("registries", func() error {
+				return installFromRegistry(kind, args[0], installForce)
+			})
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.AddCommand(registryAddCmd, registryRemoveCmd, registryListCmd, registryUpdateCmd)
+
+	templateInstallCmd, scriptInstallCmd := installCommand("template"), installCommand("script")
+	templateCmd.AddCommand(templateInstallCmd)
+	scriptCmd.AddCommand(scriptInstallCmd)
+
+	// Flags
+	templateInstallCmd.Flags().BoolVarP(&installForce, "force", "f", false, "Replace an existing template")
+	scriptInstallCmd.Flags().BoolVarP(&installForce, "force", "f", false, "Replace an existing script")
+}
+
+// registries returns the configured registries by name
+func registries() map[string]string {
+	regs := make(map[string]string)
+	for name, url := range viper.GetStringMapString("registries") {
+		regs[name] = url
+	}
+	return regs
+}
+
+// registryNames returns the configured registry names, sorted
+func registryNames() []string {
+	regs := registries()
+	names := make([]string, 0, len(regs))
+	for name := range regs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func completeRegistryNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return registryNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// registryDir returns where a registry is cloned
+func registryDir(name string) string {
+	return filepath.Join(GetRegistriesDir(), name)
+}
+
+// registryGit runs git in a registry's clone and returns its trimmed output
+func registryGit(name string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", registryDir(name)}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// cloneRegistry clones a registry unless it is already cloned. The whole
+// history is kept so installs can name any version.
+func cloneRegistry(name, url string) error {
+	if _, err := os.Stat(filepath.Join(registryDir(name), ".git")); err == nil {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is required for registries")
+	}
+	if err := os.MkdirAll(GetRegistriesDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create registries directory: %w", err)
+	}
+	os.RemoveAll(registryDir(name))
+	if out, err := exec.Command("git", "clone", "--quiet", "--", url, registryDir(name)).CombinedOutput(); err != nil {
+		os.RemoveAll(registryDir(name))
+		return fmt.Errorf("failed to clone %s: %s", url, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// registryShow returns a file's content in a registry at a version
+func registryShow(name, rev, file string) ([]byte, error) {
+	if !validRegistryRev(rev) {
+		return nil, fmt.Errorf("invalid version '%s'", rev)
+	}
+	out, err := exec.Command("git", "-C", registryDir(name), "show", rev+":"+file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s not found at %s", file, rev)
+	}
+	return out, nil
+}
+
+// registryVersion describes the checked-out version of a registry: the
+// nearest tag, or the commit
+func registryVersion(name string) string {
+	version, err := registryGit(name, "describe", "--tags", "--always")
+	if err != nil {
+		return "-"
+	}
+	return version
+}
+
+// registryFiles lists the templates or scripts in a registry at a version.
+// Variable schemas are not counted as templates.
+func registryFiles(name, rev, kind string) []string {
+	if !validRegistryRev(rev) {
+		return nil
+	}
+	out, err := registryGit(name, "ls-tree", "--name-only", rev+":"+kind+"s")
+	if err != nil || out == "" {
+		return nil
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\n") {
+		if kind == "template" && isSchemaFile(file) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// setRegistryConfig sets registries.<name> in the config file, or removes it
+// when url is empty. It takes the config lock, so callers must not hold it.
+func setRegistryConfig(name, url string) error {
+	key := "registries." + name
+	err := withLock("config", func() error {
+		path := configFilePath()
+		doc, err := loadConfigDocument(path)
+		if err != nil {
+			return err
+		}
+		if url == "" {
+			config.Remove(doc.Content[0], key)
+		} else {
+			value, tag, err := config.Validate(key, url)
+			if err != nil {
+				return err
+			}
+			if err := config.Set(doc.Content[0], key, value, tag); err != nil {
+				return err
+			}
+		}
+		return saveConfigDocument(path, doc)
+	})
+	if err != nil {
+		return err
+	}
+	if url == "" {
+		regs := registries()
+		delete(regs, name)
+		viper.Set("registries", regs)
+	} else {
+		viper.Set(key, url)
+	}
+	return nil
+}
+
+func addRegistry(name, url string) error {
+	if !registryNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid registry name '%s' (use letters, digits, '.', '_', and '-')", name)
+	}
+	if existing, ok := registries()[name]; ok {
+		return fmt.Errorf("registry '%s' already exists (%s); remove it first", name, existing)
+	}
+	if err := cloneRegistry(name, url); err != nil {
+		return err
+	}
+	if err := setRegistryConfig(name, url); err != nil {
+		return err
+	}
+
+	templates := len(registryFiles(name, "HEAD", "template"))
+	scripts := len(registryFiles(name, "HEAD", "script"))
+	fmt.Printf("%s Added registry '%s' at %s (%d templates, %d scripts)\n", ui.Green("✓"), name, registryVersion(name), templates, scripts)
+	return nil
+}
+
+func removeRegistry(name string) error {
+	if _, ok := registries()[name]; !ok {
+		return fmt.Errorf("registry '%s' not found", name)
+	}
+	if err := setRegistryConfig(name, ""); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(registryDir(name)); err != nil {
+		return fmt.Errorf("failed to remove registry clone: %w", err)
+	}
+	fmt.Printf("Removed registry '%s'\n", name)
+	return nil
+}
+
+func listRegistries() error {
+	names := registryNames()
+	if len(names) == 0 {
+		fmt.Println("No registries. Add one with 'berga registry add <name> <git-url>'")
+		return nil
+	}
+
+	regs := registries()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tTEMPLATES\tSCRIPTS\tURL")
+	for _, name := range names {
+		version := ui.Dim("not cloned")
+		templates, scripts := 0, 0
+		if _, err := os.Stat(registryDir(name)); err == nil {
+			version = registryVersion(name)
+			templates = len(registryFiles(name, "HEAD", "template"))
+			scripts = len(registryFiles(name, "HEAD", "script"))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", name, version, templates, scripts, regs[name])
+	}
+	return w.Flush()
+}
+
+// validRegistryRev reports whether a version can be given to git. One
+// starting with - would be read as an option.
+func validRegistryRev(rev string) bool {
+	return rev != "" && !strings.HasPrefix(rev, "-")
+}
+
+// parseInstallRef splits "registry/name@version"
+func parseInstallRef(ref string) (string, string, string, error) {
+	registry, name, ok := strings.Cut(ref, "/")
+	if !ok || registry == "" || name == "" {
+		return "", "", "", fmt.Errorf("expected <registry>/<name>[@version], got '%s'", ref)
+	}
+	version := ""
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name, version = name[:i], name[i+1:]
+		if !validRegistryRev(version) {
+			return "", "", "", fmt.Errorf("invalid version '%s' in '%s'", version, ref)
+		}
+	}
+	return registry, name, version, nil
+}
+
+// findRegistryFile looks up a template or script in a registry at a version
+// and returns its path in the repository. Templates may be named without
+// .tmpl, and scripts without their extension when that is unambiguous.
+func findRegistryFile(registry, kind, name, rev string) (string, error) {
+	dir := kind + "s"
+	files := registryFiles(registry, rev, kind)
+	var matches []string
+	for _, file := range files {
+		switch {
+		case file == name:
+			return path.Join(dir, file), nil
+		case kind == "template" && file == name+".tmpl":
+			return path.Join(dir, file), nil
+		case kind == "script" && strings.TrimSuffix(file, path.Ext(file)) == name:
+			matches = append(matches, file)
+		}
+	}
+	if len(matches) == 1 {
+		return path.Join(dir, matches[0]), nil
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("'%s' is ambiguous in registry '%s': %s", name, registry, strings.Join(matches, ", "))
+	}
+	return "", fmt.Errorf("%s '%s' not found in registry '%s' at %s", kind, name, registry, rev)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadInstalls() ([]Install, error) {
+	data, err := os.ReadFile(GetInstallsFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installs: %w", err)
+	}
+
+	var installs []Install
+	if err := yaml.Unmarshal(data, &installs); err != nil {
+		return nil, fmt.Errorf("failed to parse installs: %w", err)
+	}
+	return installs, nil
+}
+
+func saveInstalls(installs []Install) error {
+	data, err := yaml.Marshal(installs)
+	if err != nil {
+		return fmt.Errorf("failed to encode installs: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetInstallsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write installs: %w", err)
+	}
+	return nil
+}
+
+// installDest returns where an installed file goes
+func installDest(kind, name string) string {
+	if kind == "template" {
+		return filepath.Join(GetTemplatesDir(), name)
+	}
+	return filepath.Join(GetScriptsDir(), name)
+}
+
+// writeInstall writes a registry file at a version to its destination, with
+// a template's variable schema alongside it, and returns the install record
+func writeInstall(inst Install, rev string) (Install, error) {
+	content, err := registryShow(inst.Registry, rev, inst.Path)
+	if err != nil {
+		return inst, fmt.Errorf("failed to read from registry '%s': %w", inst.Registry, err)
+	}
+	commit, err := registryGit(inst.Registry, "rev-parse", "--short", rev+"^{commit}")
+	if err != nil {
+		return inst, err
+	}
+
+	dest := installDest(inst.Kind, inst.Name)
+	perm := os.FileMode(0644)
+	if inst.Kind == "script" {
+		perm = 0755
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return inst, fmt.Errorf("failed to create %ss directory: %w", inst.Kind, err)
+	}
+	err = trackChange(inst.Kind, dest, revisionImport, func() error {
+		return writeFileAtomic(dest, content, perm)
+	})
+	if err != nil {
+		return inst, fmt.Errorf("failed to write %s: %w", inst.Name, err)
+	}
+
+	if inst.Kind == "template" {
+		schemaPath := strings.TrimSuffix(inst.Path, ".tmpl") + ".vars.yaml"
+		if schema, err := registryShow(inst.Registry, rev, schemaPath); err == nil {
+			if err := writeFileAtomic(schemaPathFor(dest), schema, 0644); err != nil {
+				return inst, fmt.Errorf("failed to write variable schema: %w", err)
+			}
+		}
+	}
+
+	inst.Commit = commit
+	inst.Checksum = checksum(content)
+	return inst, nil
+}
+
+func installFromRegistry(kind, ref string, force bool) error {
+	registry, name, version, err := parseInstallRef(ref)
+	if err != nil {
+		return err
+	}
+	url, ok := registries()[registry]
+	if !ok {
+		return fmt.Errorf("registry '%s' not found (see 'berga registry list')", registry)
+	}
+	if err := cloneRegistry(registry, url); err != nil {
+		return err
+	}
+
+	rev := "HEAD"
+	if version != "" {
+		rev = version
+		if _, err := registryGit(registry, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+			return fmt.Errorf("version '%s' not found in registry '%s'; run 'berga registry update %s' for new versions", version, registry, registry)
+		}
+	}
+	repoPath, err := findRegistryFile(registry, kind, name, rev)
+	if err != nil {
+		return err
+	}
+
+	installs, err := loadInstalls()
+	if err != nil {
+		return err
+	}
+	inst := Install{Kind: kind, Name: path.Base(repoPath), Registry: registry, Path: repoPath, Version: version}
+	index := -1
+	for i, existing := range installs {
+		if existing.Kind == kind && existing.Name == inst.Name {
+			index = i
+		}
+	}
+	dest := installDest(kind, inst.Name)
+	if _, err := os.Stat(dest); err == nil && !force {
+		// Reinstalling from the same place needs no --force
+		if index < 0 || installs[index].Registry != registry || installs[index].Path != repoPath {
+			return fmt.Errorf("%s '%s' already exists; use --force to replace it", kind, inst.Name)
+		}
+	}
+
+	if inst, err = writeInstall(inst, rev); err != nil {
+		return err
+	}
+	if index >= 0 {
+		installs[index] = inst
+	} else {
+		installs = append(installs, inst)
+	}
+	if err := saveInstalls(installs); err != nil {
+		return err
+	}
+
+	at := inst.Commit
+	if version != "" {
+		at = version + " (" + inst.Commit + ")"
+	}
+	fmt.Printf("%s Installed %s '%s' from %s at %s\n", ui.Green("✓"), kind, inst.Name, registry, at)
+	if kind == "script" {
+		fmt.Println(ui.Dim("Review it before running: berga script show " + inst.Name))
+	}
+	return nil
+}
+
+func updateRegistries(names []string) error {
+	regs := registries()
+	if len(names) == 0 {
+		names = registryNames()
+		if len(names) == 0 {
+			fmt.Println("No registries. Add one with 'berga registry add <name> <git-url>'")
+			return nil
+		}
+	}
+	for _, name := range names {
+		if _, ok := regs[name]; !ok {
+			return fmt.Errorf("registry '%s' not found", name)
+		}
+	}
+
+	installs, err := loadInstalls()
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, name := range names {
+		before := registryVersion(name)
+		if err := cloneRegistry(name, regs[name]); err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("✗ %s: %v", name, err)))
+			failed = append(failed, name)
+			continue
+		}
+		_, err := registryGit(name, "fetch", "--quiet", "--tags", "--force", "origin")
+		if err == nil {
+			_, err = registryGit(name, "reset", "--quiet", "--hard", "@{upstream}")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("✗ %s: %v", name, err)))
+			failed = append(failed, name)
+			continue
+		}
+		after := registryVersion(name)
+		if before == after || before == "-" {
+			fmt.Printf("%s is at %s\n", name, after)
+		} else {
+			fmt.Printf("%s updated %s → %s\n", name, before, after)
+		}
+
+		for i, inst := range installs {
+			if inst.Registry != name || inst.Version != "" {
+				continue
+			}
+			updated, err := updateInstall(inst)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %s '%s' not updated: %v", inst.Kind, inst.Name, err)))
+				continue
+			}
+			if updated.Checksum != inst.Checksum {
+				fmt.Printf("  %s Updated %s '%s' (%s → %s)\n", ui.Green("✓"), inst.Kind, inst.Name, inst.Commit, updated.Commit)
+			}
+			installs[i] = updated
+		}
+	}
+	if err := saveInstalls(installs); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to update %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// updateInstall brings an installed file to the registry's latest version,
+// unless it was changed since it was installed
+func updateInstall(inst Install) (Install, error) {
+	current, err := os.ReadFile(installDest(inst.Kind, inst.Name))
+	missing := os.IsNotExist(err)
+	if err != nil && !missing {
+		return inst, err
+	}
+	if !missing && checksum(current) != inst.Checksum {
+		return inst, fmt.Errorf("it was changed since it was installed; reinstall with --force to replace it")
+	}
+	latest, err := registryGit(inst.Registry, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return inst, err
+	}
+	if latest == inst.Commit && !missing {
+		return inst, nil
+	}
+	if _, err := registryGit(inst.Registry, "cat-file", "-e", "HEAD:"+inst.Path); err != nil {
+		return inst, fmt.Errorf("%s was removed from the registry", inst.Path)
+	}
+	return writeInstall(inst, "HEAD")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseInstallRef(t *testing.T) {
+	registry, name, version, err := parseInstallRef("company/k8s-deploy@v1.2.0")
+	if err != nil || registry != "company" || name != "k8s-deploy" || version != "v1.2.0" {
+		t.Errorf("Unexpected parse: %q %q %q %v", registry, name, version, err)
+	}
+	if _, _, _, err := parseInstallRef("k8s-deploy"); err == nil {
+		t.Error("Expected a name without a registry to fail")
+	}
+	for _, ref := range []string{"company/k8s-deploy@", "company/k8s-deploy@--output=/tmp/x"} {
+		if _, _, _, err := parseInstallRef(ref); err == nil {
+			t.Errorf("Expected %s to be refused", ref)
+		}
+	}
+	if _, err := registryShow("company", "--output=/tmp/x", "scripts/a.sh"); err == nil {
+		t.Error("Expected registryShow to refuse an option as the version")
+	}
+}
+
+func TestRegistryInstallAndUpdate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("HOME", t.TempDir())
+	defer viper.Set("registries", nil)
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		os.MkdirAll(filepath.Join(repo, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("config", "user.name", "Ada Lovelace")
+	git("config", "user.email", "ada@example.com")
+	write("templates/k8s-deploy.tmpl", "v1 {{.Name}}\n")
+	write("templates/k8s-deploy.vars.yaml", "variables:\n  Name:\n    default: app\n")
+	write("scripts/deploy.sh", "#!/bin/sh\necho v1\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+
+	if err := addRegistry("company", repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := installFromRegistry("template", "company/k8s-deploy", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := installFromRegistry("script", "company/deploy@v1", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(GetTemplatesDir(), "k8s-deploy.vars.yaml")); err != nil {
+		t.Error("Expected the variable schema to be installed with the template")
+	}
+	if err := installFromRegistry("template", "company/missing", false); err == nil {
+		t.Error("Expected a missing template to fail")
+	}
+
+	write("templates/k8s-deploy.tmpl", "v2 {{.Name}}\n")
+	write("scripts/deploy.sh", "#!/bin/sh\necho v2\n")
+	git("commit", "-q", "-am", "v2")
+	if err := updateRegistries(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	template, _ := os.ReadFile(filepath.Join(GetTemplatesDir(), "k8s-deploy.tmpl"))
+	if string(template) != "v2 {{.Name}}\n" {
+		t.Errorf("Expected the template to follow the registry, got %q", template)
+	}
+	script, _ := os.ReadFile(filepath.Join(GetScriptsDir(), "deploy.sh"))
+	if string(script) != "#!/bin/sh\necho v1\n" {
+		t.Errorf("Expected the pinned script to stay at v1, got %q", script)
+	}
+
+	// A local change is kept on update
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "k8s-deploy.tmpl"), []byte("mine\n"), 0644)
+	write("templates/k8s-deploy.tmpl", "v3 {{.Name}}\n")
+	git("commit", "-q", "-am", "v3")
+	if err := updateRegistries([]string{"company"}); err != nil {
+		t.Fatal(err)
+	}
+	template, _ = os.ReadFile(filepath.Join(GetTemplatesDir(), "k8s-deploy.tmpl"))
+	if string(template) != "mine\n" {
+		t.Errorf("Expected a changed template to be left alone, got %q", template)
+	}
+}
+
+func TestSetRegistryConfigTakesConfigLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setLockWait(t, 0)
+	defer viper.Set("registries", nil)
+
+	err := withLock("config", func() error {
+		return setRegistryConfig("company", "https://example.com/registry.git")
+	})
+	if err == nil || !strings.Contains(err.Error(), "config is locked") {
+		t.Errorf("Expected the config lock to be taken, got %v", err)
+	}
+
+	if err := setRegistryConfig("company", "https://example.com/registry.git"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configFilePath())
+	if !strings.Contains(string(data), "https://example.com/registry.git") {
+		t.Errorf("Expected the registry in the config file, got %q", data)
+	}
+}
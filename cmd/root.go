@@ -1,17 +1,27 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile   string
+	verbose   bool
+	noColor   bool
+	waitLock  time.Duration
+	assumeYes bool
+
+	profileFlag string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -23,12 +33,83 @@ and execute your personal scripts, configurations, and automation tasks.
 
 Use berga to organize your development workflow, store configuration templates,
 and quickly access your most-used scripts across different environments.`,
-	Version: "1.0.0",
+	Version:       "1.0.0",
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkActiveProfile(cmd); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		// Set up a missing config before the command needs it
+		if needsFirstRun(cmd) {
+			if err := withLock("config", func() error { return runSetupWizard(false) }); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			initConfig()
+			fmt.Println()
+		}
+		// 'config migrate' upgrades the file itself, and can preview it first
+		if cmd != configMigrateCmd && cmd.Name() != cobra.ShellCompRequestCmd && configNeedsUpgrade(viper.ConfigFileUsed()) {
+			// Another berga starting at the same time may be upgrading it too
+			var upgraded bool
+			err := withLock("config", func() (err error) {
+				upgraded, err = upgradeConfigOnStartup(viper.ConfigFileUsed())
+				return err
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: failed to upgrade config: "+err.Error()))
+			} else if upgraded {
+				initConfig()
+			}
+		}
+		// Typos in key names are otherwise silently ignored
+		if cmd != configCheckCmd && cmd != configMigrateCmd && cmd.Name() != cobra.ShellCompRequestCmd {
+			warnConfigProblems()
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Open the dashboard when started bare in a terminal
+		if ui.IsTerminal(os.Stdin) && ui.IsTerminal(os.Stdout) {
+			cmd.SilenceUsage = true
+			return runDashboard()
+		}
+		return cmd.Help()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	return rootCmd.Execute()
+	started := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	auditCommand(cmd, err, time.Since(started))
+	var exitErr *ExitError
+	if err != nil && (!errors.As(err, &exitErr) || viper.GetBool("verbose")) {
+		ui.Error(os.Stderr, err)
+	}
+	return err
+}
+
+// ExitError reports that a child process exited with a non-zero status
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("script exited with status %d", e.Code)
+}
+
+// ExitCode returns the process exit code berga should use for err
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
 }
 
 func init() {
@@ -37,9 +118,14 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.berga.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "profile to use (default is $BERGA_PROFILE or the one set with 'berga profile use')")
+	rootCmd.PersistentFlags().DurationVar(&waitLock, "wait-lock", defaultLockWait, "wait up to this long for another berga process to release a lock")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "assume-yes", "y", false, "answer yes to confirmations and use defaults instead of prompting")
 
 	// Bind flags to viper
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("no-color", rootCmd.PersistentFlags().Lookup("no-color"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -47,6 +133,10 @@ func initConfig() {
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
+	} else if name, _ := activeProfile(); name != defaultProfile {
+		// Other profiles keep their own config; ~/.berga.yaml belongs to the
+		// default profile
+		viper.SetConfigFile(filepath.Join(GetConfigDir(), "config.yaml"))
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
@@ -54,34 +144,239 @@ func initConfig() {
 
 		// Search config in home directory with name ".berga" (without extension).
 		viper.AddConfigPath(home)
-		viper.AddConfigPath(".")
 		viper.SetConfigType("yaml")
 		viper.SetConfigName(".berga")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	setupConfigEnv() // read in BERGA_ environment variables that match
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil && verbose {
+	// If a config file is found, read it in. Fall back to the file created
+	// by 'berga config init' when no .berga.yaml exists.
+	err := viper.ReadInConfig()
+	if _, notFound := err.(viper.ConfigFileNotFoundError); notFound {
+		defaultConfig := filepath.Join(GetConfigDir(), "config.yaml")
+		if _, statErr := os.Stat(defaultConfig); statErr == nil {
+			viper.SetConfigFile(defaultConfig)
+			err = viper.ReadInConfig()
+		}
+	}
+	if err == nil && verbose {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+
+	// Merge the nearest project .berga.yaml over the global config
+	if err := loadProjectConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: "+err.Error()))
+	}
+	if project != nil && verbose {
+		fmt.Fprintln(os.Stderr, "Using project file:", project.File)
+	}
+
+	// Swap keychain references for the secrets they point to
+	resolveKeychainValues()
+
+	ui.Configure(viper.GetBool("no-color"))
+	i18n.SetLocale(i18n.Detect(viper.GetString("locale")))
 }
 
-// GetConfigDir returns the berga configuration directory
+// GetConfigDir returns the berga configuration directory of the active profile
 func GetConfigDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
+	name, _ := activeProfile()
+	return profileDir(name)
+}
+
+// GetBergaRoot returns the config directory of the default profile, which
+// also holds every other profile. The legacy ~/.berga is used while it
+// exists; otherwise the platform's standard location is.
+func GetBergaRoot() string {
+	if usingLegacyRoot() {
+		return legacyBergaRoot()
+	}
+	configRoot, _ := platformRoots()
+	return configRoot
+}
+
+// GetDataRoot returns the directory holding the default profile's scripts,
+// templates, and other content. It is the same as GetBergaRoot except on
+// Linux, where it follows XDG_DATA_HOME.
+func GetDataRoot() string {
+	if usingLegacyRoot() {
+		return legacyBergaRoot()
+	}
+	_, dataRoot := platformRoots()
+	return dataRoot
+}
+
+// GetDataDir returns the content directory of the active profile
+func GetDataDir() string {
+	name, _ := activeProfile()
+	if name == defaultProfile {
+		return GetDataRoot()
 	}
-	return filepath.Join(home, ".berga")
+	return filepath.Join(GetDataRoot(), "profiles", name)
+}
+
+// GetProfilesDir returns the directory holding non-default profiles
+func GetProfilesDir() string {
+	return filepath.Join(GetBergaRoot(), "profiles")
 }
 
-// GetScriptsDir returns the berga scripts directory
+// GetScriptsDir returns the berga scripts directory, the first of
+// paths.scripts when set
 func GetScriptsDir() string {
-	return filepath.Join(GetConfigDir(), "scripts")
+	return GetScriptsDirs()[0]
 }
 
-// GetTemplatesDir returns the berga templates directory
+// GetTemplatesDir returns the berga templates directory, the first of
+// paths.templates when set
 func GetTemplatesDir() string {
-	return filepath.Join(GetConfigDir(), "templates")
+	return GetTemplatesDirs()[0]
+}
+
+// GetSnippetsDir returns the berga snippets directory
+func GetSnippetsDir() string {
+	return filepath.Join(GetDataDir(), "snippets")
+}
+
+// GetCheatsDir returns the berga cheatsheets directory
+func GetCheatsDir() string {
+	return filepath.Join(GetDataDir(), "cheats")
+}
+
+// GetNotesDir returns the berga notes directory
+func GetNotesDir() string {
+	return filepath.Join(GetDataDir(), "notes")
+}
+
+// GetDotfilesDir returns the berga dotfiles directory
+func GetDotfilesDir() string {
+	return filepath.Join(GetDataDir(), "dotfiles")
+}
+
+// GetRequestsDir returns the directory holding saved HTTP requests
+func GetRequestsDir() string {
+	return filepath.Join(GetDataDir(), "requests")
+}
+
+// GetArchivedScriptsDir returns the directory holding archived scripts
+func GetArchivedScriptsDir() string {
+	return filepath.Join(GetDataDir(), "archive", "scripts")
+}
+
+// GetVersionsDir returns the directory holding saved revisions of scripts
+// and templates
+func GetVersionsDir() string {
+	return filepath.Join(GetDataDir(), ".versions")
+}
+
+// GetBookmarksFile returns the path of the berga bookmarks file
+func GetBookmarksFile() string {
+	return filepath.Join(GetConfigDir(), "bookmarks.yaml")
+}
+
+// GetTrustFile returns the path of the script trust store
+func GetTrustFile() string {
+	return filepath.Join(GetConfigDir(), "trust.yaml")
+}
+
+// GetCacheDir returns the directory holding downloaded remote templates
+func GetCacheDir() string {
+	return filepath.Join(GetConfigDir(), "cache")
+}
+
+// GetLocksDir returns the directory holding berga's lock files
+func GetLocksDir() string {
+	return filepath.Join(GetConfigDir(), "locks")
+}
+
+// GetProtectedFile returns the path of the store of scripts marked dangerous
+func GetProtectedFile() string {
+	return filepath.Join(GetConfigDir(), "protected.yaml")
+}
+
+// GetShimsDir returns the directory 'berga shims install' writes shims to
+func GetShimsDir() string {
+	return filepath.Join(GetConfigDir(), "bin")
+}
+
+// GetPolicyFile returns the path of the rules restricting when scripts run
+func GetPolicyFile() string {
+	return filepath.Join(GetConfigDir(), "policy.yaml")
+}
+
+// GetStoreFile returns the path of the database holding the run history, the
+// audit log, and usage counts
+func GetStoreFile() string {
+	return filepath.Join(GetConfigDir(), "berga.db")
+}
+
+// GetUsageFile returns the path usage counts were kept at before the store
+// database; see importLegacyRecords
+func GetUsageFile() string {
+	return filepath.Join(GetConfigDir(), "usage.yaml")
+}
+
+// GetTagsFile returns the path of the script and template tag index
+func GetTagsFile() string {
+	return filepath.Join(GetConfigDir(), "tags.yaml")
+}
+
+// GetAuditFile returns the path the audit log was kept at before the store
+// database
+func GetAuditFile() string {
+	return filepath.Join(GetConfigDir(), "audit.log")
+}
+
+// GetTasksFile returns the path of the task definitions
+func GetTasksFile() string {
+	return filepath.Join(GetConfigDir(), "tasks.yaml")
+}
+
+// GetPinsFile returns the path of the pinned scripts
+func GetPinsFile() string {
+	return filepath.Join(GetConfigDir(), "pins.yaml")
+}
+
+// GetJobsFile returns the path of the background job registry
+func GetJobsFile() string {
+	return filepath.Join(GetConfigDir(), "jobs.yaml")
+}
+
+// GetJobsDir returns the directory holding background job logs
+func GetJobsDir() string {
+	return filepath.Join(GetConfigDir(), "jobs")
+}
+
+// GetHistoryFile returns the path the run history was kept at before the
+// store database
+func GetHistoryFile() string {
+	return filepath.Join(GetConfigDir(), "history.log")
+}
+
+// GetHistoryDir returns the directory the recorded output of runs was kept
+// in before the store database
+func GetHistoryDir() string {
+	return filepath.Join(GetConfigDir(), "history")
+}
+
+// GetIndexFile returns the path of the cached listing index
+func GetIndexFile() string {
+	return filepath.Join(GetConfigDir(), "index.json")
+}
+
+// GetInstallsFile returns the path of the record of templates and scripts
+// installed from registries
+func GetInstallsFile() string {
+	return filepath.Join(GetConfigDir(), "installs.yaml")
+}
+
+// GetRegistriesDir returns the directory holding registry clones
+func GetRegistriesDir() string {
+	return filepath.Join(GetDataDir(), "registries")
+}
+
+// GetGistsFile returns the file recording which gist each script was
+// published to
+func GetGistsFile() string {
+	return filepath.Join(GetConfigDir(), "gists.yaml")
 }
@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	// Tests point HOME at temporary directories; keep the XDG variables of
+	// the environment running them from redirecting berga elsewhere
+	os.Unsetenv("XDG_CONFIG_HOME")
+	os.Unsetenv("XDG_DATA_HOME")
+	os.Exit(m.Run())
+}
+
 func TestRootCommand(t *testing.T) {
 	// Test that root command initializes without error
 	if rootCmd == nil {
@@ -38,3 +49,18 @@ func TestGetTemplatesDir(t *testing.T) {
 		t.Error("GetTemplatesDir should return a non-empty string")
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("Expected 0 for nil error, got %d", code)
+	}
+
+	if code := ExitCode(errors.New("boom")); code != 1 {
+		t.Errorf("Expected 1 for generic error, got %d", code)
+	}
+
+	wrapped := fmt.Errorf("run failed: %w", &ExitError{Code: 3})
+	if code := ExitCode(wrapped); code != 3 {
+		t.Errorf("Expected 3 for wrapped ExitError, got %d", code)
+	}
+}
@@ -1,21 +1,52 @@
 package cmd
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
+	"berga/internal/i18n"
+	"berga/internal/ui"
+	"berga/pkg/scripts"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	scriptTimeout int
+	scriptTimeout    = timeoutValue(defaultScriptTimeout)
+	scriptGrace      time.Duration
+	scriptInputFile  string
+	scriptWatch      []string
+	scriptWatchDelay time.Duration
+	scriptWatchClear bool
+	scriptListTag    string
+	scriptListSort   string
+	scriptRetries    int
+	scriptRetryDelay time.Duration
+	scriptEnvProfile string
+	scriptTimestamps bool
+	scriptPrefix     bool
+	scriptContainer  string
+	scriptMountCwd   bool
+	scriptSkipChecks bool
+	scriptCleanEnv   bool
+	scriptEnvVars    []string
+	scriptCwd        string
+
+	// Whether retry flags were given explicitly, so they win over config
+	scriptRetriesSet    bool
+	scriptRetryDelaySet bool
+	scriptReplayStdin   bool
 )
 
 // scriptCmd represents the script command
@@ -30,10 +61,18 @@ var scriptCmd = &cobra.Command{
 var scriptListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available scripts",
-	Long:  `Display all available scripts in your berga scripts directory.`,
+	Long: `Display all available scripts in your berga scripts directory.
+
+Scripts in groups defined in config (groups.deploy: [deploy.sh, rollback.sh])
+are listed under their group; --group shows only one group. Archived scripts
+are left out; --archived lists them instead.`,
 	Aliases: []string{"ls"},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listScripts()
+		cmd.SilenceUsage = true
+		if scriptListArchived {
+			return listArchivedScripts(scriptListTag)
+		}
+		return listScripts(scriptListTag, scriptListGroup, scriptListSort)
 	},
 }
 
@@ -41,11 +80,71 @@ var scriptListCmd = &cobra.Command{
 var scriptRunCmd = &cobra.Command{
 	Use:   "run [script-name] [args...]",
 	Short: "Execute a script",
-	Long:  `Execute a script from your berga scripts directory with optional arguments.`,
+	Long: `Execute a script from your berga scripts directory with optional arguments.
+
+Standard input is passed through to the script, so data can be piped in:
+
+  cat data.txt | berga script run transform.sh
+
+Use --input-file to feed a file as the script's standard input instead.
+
+Arguments after "--" are passed to the script untouched, even if they look
+like berga flags:
+
+  berga script run deploy.sh -- --verbose --dry-run
+
+Binaries declared in the script header with "berga:requires: kubectl, jq>=1.6"
+are checked before the script starts; --skip-checks runs it regardless.
+
+Positional arguments declared with "berga:arg: environment: dev, stage, prod"
+are asked for with --interactive-select-args, from a menu of the choices.
+
+"berga:run_in: git-root" runs the script from the root of the current git
+repository, and "berga:run_in: config-dir" from the berga config directory,
+wherever berga is invoked; --cwd overrides it.
+
+"berga:kube_context: prod-*" checks the current kubectl context before the
+script runs, and asks or fails when it does not match.
+
+"berga:input: order.schema.json" and "berga:output: json" declare the JSON the
+script reads and writes; --validate-io checks both against them.
+
+"berga:venv: auto" activates the nearest .venv for Python scripts, and
+"berga:node_modules: auto" the nearest package.json's node_modules for Node
+scripts; either can also be a path relative to the script.
+
+With --hosts the script runs over ssh on each host instead, up to --parallel
+at a time, with every output line labelled by host and a status table at the
+end. Hosts are comma-separated, and "@web" expands to the hosts.web list from
+config:
+
+  berga script run uptime.sh --hosts web1,web2,db1
+  berga script run deploy.sh --hosts @web --parallel 2 --fail-fast
+
+With --result-json the script's output is captured instead of shown, and a
+JSON object with the exit code, duration, whether it timed out, stdout,
+stderr, and the command line is printed when it finishes:
+
+  berga script run backup.sh --result-json | jq .exit_code
+
+The script's exit status becomes berga's exit status.`,
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Failures past this point are about the script, not command usage
+		cmd.SilenceUsage = true
+		scriptRetriesSet = cmd.Flags().Changed("retries")
+		scriptRetryDelaySet = cmd.Flags().Changed("retry-delay")
+		if scriptBackground {
+			scriptJobFlags = jobFlagArgs(cmd.Flags())
+		}
+		scriptHistoryFlags = historyFlagArgs(cmd.Flags())
 		scriptName := args[0]
 		scriptArgs := args[1:]
+		if id := takeJobID(); id != "" {
+			err := runScript(scriptName, scriptArgs)
+			finishJob(id, err)
+			return err
+		}
 		return runScript(scriptName, scriptArgs)
 	},
 }
@@ -65,7 +164,9 @@ var scriptEditCmd = &cobra.Command{
 var scriptShowCmd = &cobra.Command{
 	Use:   "show [script-name]",
 	Short: "Show script content",
-	Long:  `Display the content of a script.`,
+	Long: `Display the content of a script, with syntax highlighting on a terminal.
+Output taller than the terminal opens in the pager from the pager setting or
+$PAGER (default less); --no-pager prints it directly.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return showScript(args[0])
@@ -80,39 +181,196 @@ func init() {
 	scriptCmd.AddCommand(scriptShowCmd)
 
 	// Flags
-	scriptRunCmd.Flags().IntVar(&scriptTimeout, "timeout", 300, "Script execution timeout in seconds")
+	scriptListCmd.Flags().StringVarP(&scriptListTag, "tag", "t", "", "Only show scripts with this tag")
+	scriptListCmd.Flags().StringVarP(&scriptListGroup, "group", "g", "", "Only show scripts in this group (groups.<name> in config)")
+	scriptListCmd.Flags().StringVar(&scriptListSort, "sort", sortName, "Sort order: name, frecency, mtime, or size")
+	scriptShowCmd.Flags().BoolVar(&showNoPager, "no-pager", false, "Print the script instead of opening it in a pager")
+	scriptRunCmd.Flags().Var(&scriptTimeout, "timeout", "Script execution timeout, e.g. 90s, 2m30s, or 1h (a bare number is seconds; 0 for none)")
+	scriptRunCmd.Flags().DurationVar(&scriptGrace, "grace", defaultGracePeriod, "After the timeout, wait this long for the script to exit on SIGTERM before killing it")
+	scriptRunCmd.Flags().StringVar(&scriptInputFile, "input-file", "", "File to feed to the script as standard input")
+	scriptRunCmd.Flags().BoolVar(&scriptTimestamps, "timestamps", false, "Prefix each output line with an RFC3339 timestamp")
+	scriptRunCmd.Flags().BoolVar(&scriptPrefix, "prefix", false, "Label each output line with its stream (OUT/ERR)")
+	scriptRunCmd.Flags().StringVar(&scriptContainer, "container", "", "Run the script inside a Docker/Podman container from this image")
+	scriptRunCmd.Flags().BoolVar(&scriptMountCwd, "mount-cwd", false, "Mount the current directory into the container as its working directory")
+	scriptRunCmd.Flags().StringVar(&scriptConfirm, "confirm", "", "Confirm running a protected script by passing its name")
+	scriptRunCmd.Flags().BoolVar(&scriptSkipChecks, "skip-checks", false, "Run even if the script's declared dependencies are missing")
+	scriptRunCmd.Flags().StringVar(&scriptEnvProfile, "env-profile", "", "Environment profile to run the script with (default: env.default)")
+	scriptRunCmd.Flags().BoolVar(&scriptCleanEnv, "clean-env", false, "Start the script with a minimal environment (PATH, HOME, USER, TERM, ...)")
+	scriptRunCmd.Flags().StringArrayVar(&scriptEnvVars, "env", nil, "Set an environment variable for the script as KEY=VALUE (repeatable)")
+	scriptRunCmd.Flags().StringVar(&scriptCwd, "cwd", "", "Run the script in this directory")
+	scriptRunCmd.Flags().StringArrayVar(&scriptWatch, "watch", nil, "Re-run the script when files matching this glob change (repeatable, supports **)")
+	scriptRunCmd.Flags().DurationVar(&scriptWatchDelay, "debounce", 300*time.Millisecond, "Wait this long after the last change before re-running")
+	scriptRunCmd.Flags().BoolVar(&scriptWatchClear, "clear", false, "Clear the screen before each re-run in watch mode")
+	scriptRunCmd.Flags().IntVar(&scriptRetries, "retries", 0, "Retry a failing script up to this many times")
+	scriptRunCmd.Flags().DurationVar(&scriptRetryDelay, "retry-delay", time.Second, "Delay before the first retry; doubles on each further retry")
+	scriptRunCmd.Flags().BoolVar(&scriptReplayStdin, "replay-stdin", false, "Save piped input so every retry gets it again (up to 64 MB)")
+	scriptRunCmd.Flags().StringSliceVar(&scriptHosts, "hosts", nil, "Run the script over ssh on these hosts (comma-separated; @name for the hosts.<name> group)")
+	scriptRunCmd.Flags().IntVar(&scriptParallel, "parallel", defaultHostParallel, "With --hosts, run on at most this many hosts at once")
+	scriptRunCmd.Flags().BoolVar(&scriptFailFast, "fail-fast", false, "With --hosts, stop all hosts after the first failure")
+	scriptRunCmd.Flags().BoolVar(&scriptNotify, "notify", false, "Send a desktop notification (and scripts.notify_webhook) when the script finishes")
+	scriptRunCmd.Flags().BoolVar(&scriptSelectArgs, "interactive-select-args", false, "Choose the script's declared arguments (berga:arg:) from menus before running")
+	scriptRunCmd.Flags().BoolVar(&scriptResultJSON, "result-json", false, "Capture the script's output and print a JSON result with exit code, duration, and output when it finishes")
+	scriptRunCmd.Flags().IntVar(&scriptResultLimit, "result-limit", defaultResultLimit, "With --result-json, keep at most this many bytes of stdout and of stderr (0 for no limit)")
+	scriptRunCmd.Flags().BoolVar(&scriptValidateIO, "validate-io", false, "Check the JSON on stdin and stdout against the script's berga:input and berga:output contracts and pretty-print the output")
+	scriptRunCmd.Flags().StringVar(&scriptMaxMem, "max-mem", "", "Limit the script's memory, e.g. 512M or 2G (default: scripts.max_mem)")
+	scriptRunCmd.Flags().IntVar(&scriptNice, "nice", 0, "Run the script at this niceness, -20 to 19 (default: scripts.nice)")
+
+	viper.BindPFlag("scripts.timeout", scriptRunCmd.Flags().Lookup("timeout"))
+	viper.BindPFlag("scripts.grace", scriptRunCmd.Flags().Lookup("grace"))
+	viper.BindPFlag("scripts.notify", scriptRunCmd.Flags().Lookup("notify"))
+	viper.BindPFlag("scripts.max_mem", scriptRunCmd.Flags().Lookup("max-mem"))
+	viper.BindPFlag("scripts.nice", scriptRunCmd.Flags().Lookup("nice"))
 }
 
-func listScripts() error {
+func listScripts(tag, group, order string) error {
+	if err := validateSortOrder(order); err != nil {
+		return err
+	}
+	if group != "" {
+		if _, err := scriptGroupMembers(group); err != nil {
+			return err
+		}
+	}
+	
 	scriptsDir := GetScriptsDir()
+	projectDir := GetProjectScriptsDir()
+	
+	index, err := loadTagIndex()
+	if err != nil {
+		return err
+	}
+	pinned := pinnedScripts()
+	dirs := GetScriptsDirs()
+	if projectDir != "" {
+		dirs = append([]string{projectDir}, dirs...)
+	}
+	refreshListings(dirs)
+	
+	// Project scripts shadow global scripts of the same name. shadowed maps
+	// each name listed so far to its directory.
+	shadowed := make(map[string]string)
+	if projectDir != "" {
+		if files, err := readListing(projectDir); err == nil && len(files) > 0 {
+			if err := sortListing(files, "script", order); err != nil {
+				return err
+			}
+			files = pinsFirst(files, pinned)
+			listHeader(i18n.T("header.project_scripts"))
+			for _, name := range printScripts(projectDir, files, index, tag, group) {
+				shadowed[name] = projectDir
+			}
+			fmt.Printf("\n%s\n\n", i18n.T("list.project_scripts_dir", projectDir))
+		}
+	}
 	
 	if _, err := os.Stat(scriptsDir); os.IsNotExist(err) {
-		fmt.Printf("Scripts directory does not exist: %s\n", scriptsDir)
-		fmt.Println("Run 'berga config init' to initialize your configuration.")
+		fmt.Println(i18n.T("list.scripts_dir_missing", scriptsDir))
+		fmt.Println(i18n.T("list.run_config_init"))
 		return nil
 	}
 
-	files, err := os.ReadDir(scriptsDir)
+	files, err := readListing(scriptsDir)
 	if err != nil {
 		return fmt.Errorf("failed to read scripts directory: %w", err)
 	}
 
 	if len(files) == 0 {
-		fmt.Println("No scripts found.")
-		fmt.Printf("Add scripts to: %s\n", scriptsDir)
+		fmt.Println(i18n.T("list.no_scripts"))
+		fmt.Println(i18n.T("list.add_scripts", scriptsDir))
 		return nil
 	}
+	
+	if err := sortListing(files, "script", order); err != nil {
+		return err
+	}
+	files = pinsFirst(files, pinned)
+	
+	listHeader(i18n.T("header.available_scripts"))
+	
+	visible, hidden := unshadowedEntries(files, shadowed)
+	for _, name := range printScripts(scriptsDir, visible, index, tag, group) {
+		shadowed[name] = scriptsDir
+	}
+	if tag == "" && group == "" {
+		printShadowed(hidden, shadowed)
+	}
+	
+	fmt.Printf("\n%s\n", i18n.T("list.scripts_dir", scriptsDir))
+	
+	// Further search paths from paths.scripts
+	for _, dir := range GetScriptsDirs()[1:] {
+		files, err := readListing(dir)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		if err := sortListing(files, "script", order); err != nil {
+			return err
+		}
+		files = pinsFirst(files, pinned)
+		visible, hidden := unshadowedEntries(files, shadowed)
+		fmt.Println()
+		listHeader(i18n.T("header.scripts_in", dir))
+		for _, name := range printScripts(dir, visible, index, tag, group) {
+			shadowed[name] = dir
+		}
+		if tag == "" && group == "" {
+			printShadowed(hidden, shadowed)
+		}
+	}
+	return nil
+}
 
-	fmt.Println("Available Scripts:")
-	fmt.Println("==================")
+// printScripts lists the scripts in dir matching tag and group and returns
+// their names. Without a group filter, scripts in configured groups are
+// listed under their group, followed by the rest.
+func printScripts(dir string, files []os.DirEntry, index *TagIndex, tag, group string) []string {
+	groups := scriptGroups()
+	if group != "" {
+		return printScriptEntries(dir, groupEntries(files, groups[group]), index, tag, "  ", "")
+	}
+	if len(groups) == 0 {
+		return printScriptEntries(dir, files, index, tag, "  ", "")
+	}
 	
+	var names []string
+	grouped := make(map[string]bool)
+	for _, name := range sortedGroupNames(groups) {
+		members := groupEntries(files, groups[name])
+		for _, member := range printScriptEntries(dir, members, index, tag, "    ", "  "+ui.Cyan(name)) {
+			grouped[member] = true
+			names = append(names, member)
+		}
+	}
+	var rest []os.DirEntry
+	for _, file := range files {
+		if !grouped[file.Name()] {
+			rest = append(rest, file)
+		}
+	}
+	heading := ""
+	if len(grouped) > 0 {
+		heading = "  " + ui.Cyan("other")
+	}
+	return append(names, printScriptEntries(dir, rest, index, tag, "    ", heading)...)
+}
+
+// printScriptEntries prints one line per script, preceded by heading if any
+// script is shown, and returns the names of the scripts shown
+func printScriptEntries(dir string, files []os.DirEntry, index *TagIndex, tag, indent, heading string) []string {
+	var names []string
+	pinned := pinnedScripts()
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || isScriptSpecFile(file.Name()) {
 			continue
 		}
 		
 		name := file.Name()
-		path := filepath.Join(scriptsDir, name)
+		path := filepath.Join(dir, name)
+		
+		tags := tagsFor(index, "script", name, path)
+		if tag != "" && !hasTag(tags, tag) {
+			continue
+		}
 		
 		// Get file info
 		info, err := file.Info()
@@ -121,91 +379,228 @@ func listScripts() error {
 		}
 		
 		// Check if executable
-		executable := "📄"
-		if isExecutable(path) {
-			executable = "🚀"
+		executable := ui.Icon("📄", "-")
+		if isEncryptedScript(name) {
+			executable = ui.Icon("🔒", "#")
+		} else if isExecutable(path) {
+			executable = ui.Icon("🚀", "*")
 		}
 		
-		fmt.Printf("  %s %s (%s, %s)\n", 
+		if heading != "" && len(names) == 0 {
+			fmt.Println(heading)
+		}
+		pin := ""
+		if pinned[name] {
+			pin = " " + ui.Icon("📌", "^")
+		}
+		fmt.Printf("%s%s %s%s %s%s%s\n", 
+			indent,
 			executable, 
-			name, 
-			humanizeSize(info.Size()), 
-			info.ModTime().Format("2006-01-02 15:04"))
+			ui.Bold(name), 
+			pin,
+			ui.Dim(fmt.Sprintf("(%s, %s)", humanizeSize(info.Size()), info.ModTime().Format("2006-01-02 15:04"))),
+			formatTags(tags),
+			dangerLabel(scriptDangerLevel(name, path)))
+		names = append(names, name)
 	}
-	
-	fmt.Printf("\nScripts directory: %s\n", scriptsDir)
-	return nil
+	return names
 }
 
 func runScript(scriptName string, args []string) error {
-	scriptsDir := GetScriptsDir()
-	scriptPath := filepath.Join(scriptsDir, scriptName)
-	
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("script '%s' not found in %s", scriptName, scriptsDir)
+	storedPath, err := locateScript(scriptName)
+	if err != nil {
+		return err
+	}
+	// Encrypted scripts run from a private decrypted copy
+	scriptPath, cleanup, err := plainScriptPath(storedPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if _, err := resolveEnvProfile(scriptEnvProfile); err != nil {
+		return err
+	}
+	if err := checkRunEnvironment(); err != nil {
+		return err
+	}
+	if len(scriptHosts) == 0 {
+		if scriptCwd == "" {
+			if _, err := runInDir(scriptPath); err != nil {
+				return err
+			}
+		}
+		if verbose {
+			dir := scriptWorkDir(scriptPath)
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			fmt.Fprintln(os.Stderr, "Running in", dir)
+		}
+	}
+	if err := checkResultFlags(); err != nil {
+		return err
+	}
+	if err := checkIOFlags(); err != nil {
+		return err
+	}
+	var contract *ioContract
+	if scriptValidateIO {
+		if contract, err = loadIOContract(scriptPath, filepath.Dir(storedPath)); err != nil {
+			return fmt.Errorf("script '%s': %w", scriptName, err)
+		}
+		if contract.Input == nil && contract.Output == nil {
+			return fmt.Errorf("script '%s' declares no berga:input or berga:output contract to validate", scriptName)
+		}
+	}
+	if err := checkLimitFlags(); err != nil {
+		return err
+	}
+	var hosts []string
+	if len(scriptHosts) > 0 {
+		if hosts, err = expandHosts(scriptHosts); err != nil {
+			return err
+		}
+		if err := checkHostFlags(); err != nil {
+			return err
+		}
+	}
+	image := containerImage(scriptPath)
+	if image != "" {
+		if _, err := containerRuntime(); err != nil {
+			return err
+		}
 	}
 	
-	// Get timeout from config or flag
-	timeout := time.Duration(scriptTimeout) * time.Second
-	if configTimeout := viper.GetInt("scripts.timeout"); configTimeout > 0 {
-		timeout = time.Duration(configTimeout) * time.Second
+	if image == "" && len(hosts) == 0 {
+		envs, err := resolveScriptEnvs(scriptPath)
+		if err != nil {
+			return err
+		}
+		if verbose && envs.Venv != "" {
+			fmt.Fprintln(os.Stderr, "Using virtualenv", envs.Venv)
+		}
+		if verbose && envs.NodeDir != "" {
+			fmt.Fprintln(os.Stderr, "Using node_modules in", envs.NodeDir)
+		}
+	}
+
+	// Declared dependencies live inside the image for container runs, and on
+	// the remote machines for --hosts
+	if !scriptSkipChecks && image == "" && len(hosts) == 0 {
+		if err := checkScriptRequirements(scriptName, scriptPath); err != nil {
+			return err
+		}
+	}
+	// The context is kubectl's on this machine, not on the --hosts machines
+	if len(hosts) == 0 {
+		if err := checkKubeContext(scriptName, scriptPath); err != nil {
+			return err
+		}
+	}
+	if err := confirmDangerousScript(scriptName, scriptPath); err != nil {
+		return err
+	}
+	if scriptSelectArgs {
+		specs, err := scriptArgSpecs(scriptPath)
+		if err != nil {
+			return err
+		}
+		if args, err = selectScriptArgs(scriptName, specs, args); err != nil {
+			return err
+		}
+	}
+	if scriptBackground {
+		return startJob(scriptName, args)
 	}
+	hookNames, hookEnv := scriptHookNames(storedPath), scriptHookEnv(storedPath, args)
+	if err := runConfiguredHooks(hookPreScriptRun, "script", hookNames, hookEnv); err != nil {
+		return err
+	}
+	recordUsage("script", filepath.Base(storedPath))
 	
-	verbose := viper.GetBool("verbose") || viper.GetBool("scripts.verbose")
+	timeout := scriptRunTimeout()
+	// Keep stdout for the result alone with --result-json
+	verbose := (viper.GetBool("verbose") || viper.GetBool("scripts.verbose")) && !scriptResultJSON
 	
 	if verbose {
-		fmt.Printf("Executing: %s %s\n", scriptPath, strings.Join(args, " "))
-		fmt.Printf("Timeout: %v\n", timeout)
+		buildRunPlan(scriptName, scriptPath, args, image, hosts, timeout).print(os.Stdout)
 		fmt.Println("--- Output ---")
 	}
 	
-	// Determine how to execute the script
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		// On Windows, try to execute directly first
-		if strings.HasSuffix(strings.ToLower(scriptName), ".ps1") {
-			cmd = exec.Command("powershell", append([]string{"-File", scriptPath}, args...)...)
-		} else if strings.HasSuffix(strings.ToLower(scriptName), ".bat") || strings.HasSuffix(strings.ToLower(scriptName), ".cmd") {
-			cmd = exec.Command("cmd", append([]string{"/C", scriptPath}, args...)...)
-		} else {
-			// Try to execute directly
-			cmd = exec.Command(scriptPath, args...)
+	if len(scriptWatch) > 0 {
+		return watchScript(scriptPath, args, scriptWatch, timeout)
+	}
+	
+	started := time.Now()
+	if len(hosts) > 0 {
+		err = runScriptOnHosts(scriptName, scriptPath, args, hosts, timeout)
+		elapsed := time.Since(started)
+		recordRun(scriptName, args, err, elapsed)
+		notifyCompletion(scriptName, err, elapsed)
+		warnHookFailure(runConfiguredHooks(hookPostScriptRun, "script", hookNames, scriptResultEnv(hookEnv, err, elapsed)))
+		return err
+	}
+	if scriptResultJSON {
+		activeResult = newResultCapture(scriptResultLimit)
+		defer func() { activeResult = nil }()
+	}
+	// The input is checked once, before the first attempt, and fed to each
+	var input []byte
+	if contract != nil && contract.Input != nil {
+		if input, err = readValidatedInput(contract); err != nil {
+			return fmt.Errorf("script '%s': %w", scriptName, err)
 		}
-	} else {
-		// On Unix-like systems, check if it's executable
-		if isExecutable(scriptPath) {
-			cmd = exec.Command(scriptPath, args...)
-		} else {
-			// Try to determine the interpreter from the shebang
-			interpreter := getInterpreter(scriptPath)
-			if interpreter != "" {
-				cmd = exec.Command(interpreter, append([]string{scriptPath}, args...)...)
-			} else {
-				cmd = exec.Command("sh", append([]string{scriptPath}, args...)...)
+	}
+	if contract != nil && contract.Output != nil {
+		activeOutputCheck = &bytes.Buffer{}
+		defer func() { activeOutputCheck = nil }()
+	}
+	startHistoryRecording()
+	policy := scriptRetryPolicy(scriptName, scriptRetriesSet, scriptRetryDelaySet)
+	inputFile := scriptInputFile
+	if input == nil {
+		spooled, err := spoolRetryStdin(policy)
+		if err != nil {
+			return err
+		}
+		if spooled != "" {
+			defer os.Remove(spooled)
+			inputFile = spooled
+		}
+	}
+	err = runWithRetries(policy, func() error {
+		// Feed the script from a file or pass our own stdin straight through
+		var stdin io.Reader = os.Stdin
+		if input != nil {
+			stdin = bytes.NewReader(input)
+		} else if inputFile != "" {
+			f, err := os.Open(inputFile)
+			if err != nil {
+				return fmt.Errorf("failed to open input file: %w", err)
 			}
+			defer f.Close()
+			stdin = f
+		}
+		return executeScript(context.Background(), scriptPath, args, stdin, timeout)
+	})
+	elapsed := time.Since(started)
+	recordRun(scriptName, args, err, elapsed)
+	notifyCompletion(scriptName, err, elapsed)
+	warnHookFailure(runConfiguredHooks(hookPostScriptRun, "script", hookNames, scriptResultEnv(hookEnv, err, elapsed)))
+	if scriptResultJSON {
+		if printErr := printScriptResult(activeResult.result(scriptName, err, elapsed)); printErr != nil {
+			return printErr
 		}
 	}
-	
-	// Set up the command
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	
-	// Execute with timeout
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Run()
-	}()
-	
-	select {
-	case err := <-done:
+	if activeOutputCheck != nil {
 		if err != nil {
-			return fmt.Errorf("script execution failed: %w", err)
+			os.Stdout.Write(activeOutputCheck.Bytes())
+		} else if err := writeValidatedOutput(contract, activeOutputCheck.Bytes()); err != nil {
+			return fmt.Errorf("script '%s': %w", scriptName, err)
 		}
-	case <-time.After(timeout):
-		cmd.Process.Kill()
-		return fmt.Errorf("script execution timed out after %v", timeout)
+	}
+	if err != nil {
+		return err
 	}
 	
 	if verbose {
@@ -215,11 +610,130 @@ func runScript(scriptName string, args []string) error {
 	return nil
 }
 
-func editScript(scriptName string) error {
-	scriptsDir := GetScriptsDir()
-	scriptPath := filepath.Join(scriptsDir, scriptName)
+// locateScript returns the path of a script after checking that it exists and is trusted
+func locateScript(scriptName string) (string, error) {
+	scriptPath := resolveScriptPath(scriptName)
+	
+	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+		return "", errors.New(i18n.T("error.script_not_found", scriptName, GetScriptsDir()))
+	}
+	
+	if err := verifyScriptTrust(scriptName, scriptPath); err != nil {
+		return "", err
+	}
+	return scriptPath, nil
+}
+
+// executeScript runs a script to completion. Cancelling parent stops the
+// script gracefully. Hitting the timeout sends it SIGTERM and kills it if it
+// is still running after the grace period. A zero timeout never expires.
+func executeScript(parent context.Context, scriptPath string, args []string, stdin io.Reader, timeout time.Duration) error {
+	ctx, cancel := runContext(parent, timeout)
+	defer cancel()
+	if viper.GetBool("verbose") || viper.GetBool("scripts.verbose") {
+		go reportTimeLeft(ctx, os.Stderr, timeoutNoticeInterval)
+	}
+	
+	stdout, stderr, flush := scriptOutputWriters()
+	defer flush()
+	
+	cmd := scriptCommand(ctx, scriptPath, args)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = stdin
+	if activeResult != nil {
+		cmd.Stdout, cmd.Stderr = activeResult.attempt(cmd.Args)
+	}
+	if activeOutputCheck != nil {
+		activeOutputCheck.Reset()
+		cmd.Stdout = activeOutputCheck
+	}
+	if activeHistoryOutput != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, activeHistoryOutput)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, activeHistoryOutput)
+	}
+	grace := scriptGracePeriod()
+	// Containers are removed by the runtime instead of signalled
+	hardStop := grace == 0 || containerImage(scriptPath) != ""
+	forceStop := cmd.Cancel
+	timedOut := false
+	cmd.Cancel = func() error {
+		if parent.Err() != nil {
+			return terminateProcess(cmd.Process)
+		}
+		timedOut = true
+		if hardStop {
+			return forceStop()
+		}
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: timed out after %v; sending SIGTERM and killing the script if it is still running in %v", timeout, grace)))
+		return terminateProcess(cmd.Process)
+	}
+	// Go kills the process once this passes after Cancel
+	cmd.WaitDelay = grace
+	if hardStop {
+		cmd.WaitDelay = 5 * time.Second
+	}
+	limits, err := scriptResourceLimits()
+	if err != nil {
+		return err
+	}
+	
+	err = runLimited(cmd, scriptPath, limits)
+	if timedOut {
+		how := "stopped by SIGTERM"
+		if hardStop {
+			how = "killed"
+		} else if killedBySignal(cmd.ProcessState) {
+			how = fmt.Sprintf("killed after a %v grace period", grace)
+		}
+		return fmt.Errorf("%w after %v (%s)", errScriptTimedOut, timeout, how)
+	}
+	if err != nil {
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("script execution failed: %w", err)
+	}
+	return nil
+}
+
+// terminateProcess asks a process to stop, falling back to a kill where
+// signals are not supported
+func terminateProcess(p *os.Process) error {
+	if runtime.GOOS == "windows" {
+		return p.Kill()
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+// scriptCommand builds the command used to execute a script, choosing an
+// interpreter based on the platform, extension, and shebang
+func scriptCommand(ctx context.Context, scriptPath string, args []string) *exec.Cmd {
+	if image := containerImage(scriptPath); image != "" {
+		cli, err := containerRuntime()
+		if err != nil {
+			cli = "docker"
+		}
+		return containerCommand(ctx, cli, image, scriptPath, args)
+	}
 	
-	// Get editor from config
+	// Layer the env profile and --env over the inherited or clean environment
+	// Errors in berga:venv: and berga:node_modules: were reported before running
+	envs, _ := resolveScriptEnvs(scriptPath)
+	runner := scripts.LocalRunner{Env: envs.activate(scriptEnviron()), Dir: scriptWorkDir(scriptPath)}
+	return runner.Command(ctx, scriptPath, args)
+}
+
+// preferredEditor returns the editor from --editor, config, $EDITOR, or
+// $VISUAL, with a platform default
+func preferredEditor() string {
+	if editorFlag != "" {
+		return editorFlag
+	}
 	editor := viper.GetString("editor")
 	if editor == "" {
 		// Try environment variables
@@ -236,74 +750,53 @@ func editScript(scriptName string) error {
 			}
 		}
 	}
+	return editor
+}
+
+func editScript(scriptName string) error {
+	scriptPath := resolveScriptPath(scriptName)
+	editor := preferredEditor()
 	
-	fmt.Printf("Opening %s with %s...\n", scriptPath, editor)
+	return trackChange("script", scriptPath, revisionEdit, func() error {
+		if isEncryptedScript(scriptPath) {
+			return editEncryptedScript(editor, scriptPath)
+		}
 	
-	cmd := exec.Command(editor, scriptPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+		fmt.Printf("Opening %s with %s...\n", scriptPath, editor)
 	
-	return cmd.Run()
+		return editFile(editor, scriptPath)
+	})
 }
 
 func showScript(scriptName string) error {
-	scriptsDir := GetScriptsDir()
-	scriptPath := filepath.Join(scriptsDir, scriptName)
+	scriptPath := resolveScriptPath(scriptName)
 	
 	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("script '%s' not found in %s", scriptName, scriptsDir)
+		return errors.New(i18n.T("error.script_not_found", scriptName, GetScriptsDir()))
 	}
 	
-	content, err := os.ReadFile(scriptPath)
+	var content []byte
+	var err error
+	if isEncryptedScript(scriptPath) {
+		content, err = decryptScript(scriptPath)
+	} else {
+		content, err = os.ReadFile(scriptPath)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read script: %w", err)
 	}
 	
-	fmt.Printf("Script: %s\n", scriptPath)
-	fmt.Println("=" + strings.Repeat("=", len(scriptPath)+8))
-	fmt.Print(string(content))
+	var out strings.Builder
+	fmt.Fprintf(&out, "Script: %s\n", scriptPath)
+	fmt.Fprintln(&out, "="+strings.Repeat("=", len(scriptPath)+8))
+	out.WriteString(highlight(strings.TrimSuffix(filepath.Base(scriptPath), encryptedExt), string(content)))
 	
-	return nil
+	return pageOutput(out.String(), showNoPager)
 }
 
+// isExecutable reports whether a script can be run directly on this platform
 func isExecutable(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-	
-	if runtime.GOOS == "windows" {
-		// On Windows, check file extension
-		ext := strings.ToLower(filepath.Ext(path))
-		return ext == ".exe" || ext == ".bat" || ext == ".cmd" || ext == ".ps1"
-	}
-	
-	// On Unix-like systems, check execute permission
-	return info.Mode()&0111 != 0
-}
-
-func getInterpreter(scriptPath string) string {
-	file, err := os.Open(scriptPath)
-	if err != nil {
-		return ""
-	}
-	defer file.Close()
-	
-	scanner := bufio.NewScanner(file)
-	if scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "#!") {
-			shebang := strings.TrimSpace(line[2:])
-			// Extract just the interpreter name
-			parts := strings.Fields(shebang)
-			if len(parts) > 0 {
-				return parts[0]
-			}
-		}
-	}
-	
-	return ""
+	return scripts.Host.IsExecutable(path)
 }
 
 func humanizeSize(size int64) string {
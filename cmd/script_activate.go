@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// venvNames are the directory names berga:venv: auto looks for
+var venvNames = []string{".venv", "venv"}
+
+// scriptEnvs are the language environments a script declares with
+// berga:venv: and berga:node_modules:, resolved to directories
+type scriptEnvs struct {
+	Venv    string // a Python virtualenv
+	NodeDir string // a directory with package.json and node_modules
+}
+
+// resolveScriptEnvs finds the environments a script declares. A path is
+// relative to the script's directory; "auto" looks in the script's
+// directory, then in the directory the script runs in and its parents.
+func resolveScriptEnvs(scriptPath string) (scriptEnvs, error) {
+	var envs scriptEnvs
+	meta := readMetadata(scriptPath)
+
+	if value := meta["venv"]; value != "" {
+		dir, err := resolveScriptEnvDir(scriptPath, value, func(dir string) string {
+			for _, name := range venvNames {
+				if isVenv(filepath.Join(dir, name)) {
+					return filepath.Join(dir, name)
+				}
+			}
+			return ""
+		})
+		if err != nil {
+			return envs, err
+		}
+		if dir == "" {
+			return envs, fmt.Errorf("no virtualenv found for berga:venv: auto; create one with 'python3 -m venv .venv' next to the script or in the project")
+		}
+		if !isVenv(dir) {
+			return envs, fmt.Errorf("berga:venv: %s is not a virtualenv (no pyvenv.cfg in %s)", value, dir)
+		}
+		envs.Venv = dir
+	}
+
+	if value := meta["node_modules"]; value != "" {
+		dir, err := resolveScriptEnvDir(scriptPath, value, func(dir string) string {
+			if fileExists(filepath.Join(dir, "package.json")) {
+				return dir
+			}
+			return ""
+		})
+		if err != nil {
+			return envs, err
+		}
+		if dir == "" {
+			return envs, fmt.Errorf("no package.json found for berga:node_modules: auto")
+		}
+		if info, err := os.Stat(filepath.Join(dir, "node_modules")); err != nil || !info.IsDir() {
+			return envs, fmt.Errorf("%s has no node_modules; run 'npm install' there first", dir)
+		}
+		envs.NodeDir = dir
+	}
+	return envs, nil
+}
+
+// resolveScriptEnvDir turns a berga:venv: or berga:node_modules: value into
+// a directory. For "auto" it returns the first directory match accepts, or ""
+// when there is none.
+func resolveScriptEnvDir(scriptPath, value string, match func(dir string) string) (string, error) {
+	scriptDir := filepath.Dir(storedScriptPath(scriptPath))
+	if value != "auto" {
+		dir := expandHome(value)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(scriptDir, dir)
+		}
+		return filepath.Clean(dir), nil
+	}
+
+	if found := match(scriptDir); found != "" {
+		return found, nil
+	}
+	dir := scriptWorkDir(scriptPath)
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return "", nil
+		}
+	}
+	for {
+		if found := match(dir); found != "" {
+			return found, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// isVenv reports whether dir is a Python virtualenv
+func isVenv(dir string) bool {
+	return fileExists(filepath.Join(dir, "pyvenv.cfg"))
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// activate returns environ with the environments activated the way their
+// own activation scripts would: the virtualenv's and node_modules' bin
+// directories first on PATH, VIRTUAL_ENV and NODE_PATH set, and PYTHONHOME
+// cleared. A nil environ stands for berga's own environment.
+func (e scriptEnvs) activate(environ []string) []string {
+	if e.Venv == "" && e.NodeDir == "" {
+		return environ
+	}
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	var bins []string
+	vars := make(map[string]string)
+	if e.Venv != "" {
+		bin := filepath.Join(e.Venv, "bin")
+		if runtime.GOOS == "windows" {
+			bin = filepath.Join(e.Venv, "Scripts")
+		}
+		bins = append(bins, bin)
+		vars["VIRTUAL_ENV"] = e.Venv
+		environ = withoutEnv(environ, "PYTHONHOME")
+	}
+	if e.NodeDir != "" {
+		modules := filepath.Join(e.NodeDir, "node_modules")
+		bins = append(bins, filepath.Join(modules, ".bin"))
+		vars["NODE_PATH"] = joinPathList(modules, lookupEnv(environ, "NODE_PATH"))
+	}
+
+	pathKey := "PATH"
+	if runtime.GOOS == "windows" {
+		pathKey = "Path"
+	}
+	vars[pathKey] = joinPathList(append(bins, lookupEnv(environ, "PATH"))...)
+	return layerEnviron(withoutEnv(environ, "PATH"), vars)
+}
+
+// joinPathList joins the non-empty directories with the OS path separator
+func joinPathList(dirs ...string) string {
+	var kept []string
+	for _, dir := range dirs {
+		if dir != "" {
+			kept = append(kept, dir)
+		}
+	}
+	return strings.Join(kept, string(os.PathListSeparator))
+}
+
+// envKeyMatches compares variable names, ignoring case on Windows
+func envKeyMatches(a, b string) bool {
+	return a == b || runtime.GOOS == "windows" && strings.EqualFold(a, b)
+}
+
+// lookupEnv returns the value environ gives key; the last one wins
+func lookupEnv(environ []string, key string) string {
+	value := ""
+	for _, kv := range environ {
+		if k, v, _ := strings.Cut(kv, "="); envKeyMatches(k, key) {
+			value = v
+		}
+	}
+	return value
+}
+
+// withoutEnv returns environ without key
+func withoutEnv(environ []string, key string) []string {
+	kept := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if k, _, _ := strings.Cut(kv, "="); !envKeyMatches(k, key) {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolveScriptEnvs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	defer func() { scriptCwd = "" }()
+	scriptCwd = filepath.Join(project, "sub")
+	os.MkdirAll(scriptCwd, 0755)
+
+	path := writeTestScript(t, "tool.py", "#!/usr/bin/env python3\n# berga:venv: auto\n")
+	if _, err := resolveScriptEnvs(path); err == nil {
+		t.Error("Expected an error without a virtualenv")
+	}
+
+	// Found above the directory the script runs in
+	venv := filepath.Join(project, ".venv")
+	os.MkdirAll(venv, 0755)
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte("home = /usr/bin\n"), 0644)
+	envs, err := resolveScriptEnvs(path)
+	if err != nil || envs.Venv != venv {
+		t.Errorf("Expected %s, got %+v %v", venv, envs, err)
+	}
+
+	// A path is relative to the script and must hold node_modules
+	path = writeTestScript(t, "build.js", "#!/usr/bin/env node\n// berga:node_modules: ../js\n")
+	nodeDir := filepath.Join(filepath.Dir(GetScriptsDir()), "js")
+	os.MkdirAll(nodeDir, 0755)
+	if _, err := resolveScriptEnvs(path); err == nil || !strings.Contains(err.Error(), "npm install") {
+		t.Errorf("Expected a missing node_modules to be reported, got %v", err)
+	}
+	os.MkdirAll(filepath.Join(nodeDir, "node_modules"), 0755)
+	if envs, err := resolveScriptEnvs(path); err != nil || envs.NodeDir != nodeDir {
+		t.Errorf("Expected %s, got %+v %v", nodeDir, envs, err)
+	}
+
+	path = writeTestScript(t, "plain.sh", "#!/bin/sh\n")
+	if envs, err := resolveScriptEnvs(path); err != nil || envs != (scriptEnvs{}) {
+		t.Errorf("Expected no environments, got %+v %v", envs, err)
+	}
+}
+
+func TestActivateScriptEnvs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks Unix paths")
+	}
+	if env := (scriptEnvs{}).activate(nil); env != nil {
+		t.Error("Expected the environment to be inherited without environments")
+	}
+
+	envs := scriptEnvs{Venv: "/p/.venv", NodeDir: "/p/js"}
+	env := envs.activate([]string{"PATH=/usr/bin", "PYTHONHOME=/opt/py", "NODE_PATH=/lib/node", "HOME=/h"})
+	if got := lookupEnv(env, "PATH"); got != "/p/.venv/bin:/p/js/node_modules/.bin:/usr/bin" {
+		t.Errorf("Unexpected PATH %q", got)
+	}
+	if got := lookupEnv(env, "VIRTUAL_ENV"); got != "/p/.venv" {
+		t.Errorf("Unexpected VIRTUAL_ENV %q", got)
+	}
+	if got := lookupEnv(env, "NODE_PATH"); got != "/p/js/node_modules:/lib/node" {
+		t.Errorf("Unexpected NODE_PATH %q", got)
+	}
+	if got := lookupEnv(env, "PYTHONHOME"); got != "" {
+		t.Errorf("Expected PYTHONHOME to be cleared, got %q", got)
+	}
+	if got := lookupEnv(env, "HOME"); got != "/h" {
+		t.Errorf("Expected other variables to be kept, got HOME=%q", got)
+	}
+}
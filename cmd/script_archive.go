@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"berga/internal/i18n"
+	"berga/pkg/scripts"
+
+	"github.com/spf13/cobra"
+)
+
+var scriptListArchived bool
+
+// scriptArchiveCmd moves scripts out of the active list
+var scriptArchiveCmd = &cobra.Command{
+	Use:   "archive [script...]",
+	Short: "Archive scripts instead of deleting them",
+	Long: `Move scripts to the archive directory (~/.berga/archive/scripts), along with
+their test specs. Archived scripts no longer appear in 'script list' or tab
+completion and cannot be run by name, but their tags, usage, and version
+history are kept. 'script list --archived' shows them and 'script unarchive'
+brings them back.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		for _, name := range args {
+			if err := archiveScript(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, directive := completeScriptNames(cmd, nil, toComplete)
+		return names, directive
+	},
+}
+
+// scriptUnarchiveCmd restores archived scripts
+var scriptUnarchiveCmd = &cobra.Command{
+	Use:   "unarchive [script...]",
+	Short: "Restore archived scripts",
+	Long: `Move archived scripts back into the scripts directory, the first of
+paths.scripts when several are configured.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		for _, name := range args {
+			if err := unarchiveScript(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeNames([]string{GetArchivedScriptsDir()}, toComplete, func(name string) (string, bool) {
+			return name, !isScriptSpecFile(name)
+		}), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptArchiveCmd, scriptUnarchiveCmd)
+
+	// Flags
+	scriptListCmd.Flags().BoolVar(&scriptListArchived, "archived", false, "List archived scripts instead")
+}
+
+// moveScript moves a script and its test spec from src to the directory dir
+func moveScript(src, dir string) (string, error) {
+	dst := filepath.Join(dir, filepath.Base(src))
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", dst)
+	}
+	if err := movePath(src, dst); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(specPathFor(src)); err == nil {
+		if err := movePath(specPathFor(src), specPathFor(dst)); err != nil {
+			return "", err
+		}
+	}
+	return dst, nil
+}
+
+// archiveScript moves a script from the scripts directories to the archive.
+// Project scripts belong to their repository and are not archived.
+func archiveScript(name string) error {
+	src, ok := scripts.DirStore{Dirs: GetScriptsDirs()}.Find(name)
+	if !ok {
+		return fmt.Errorf("script '%s' not found in %s", name, strings.Join(GetScriptsDirs(), ", "))
+	}
+	if _, err := moveScript(src, GetArchivedScriptsDir()); err != nil {
+		return fmt.Errorf("failed to archive script '%s': %w", name, err)
+	}
+	fmt.Printf("Archived script '%s' (restore it with 'berga script unarchive %s')\n", name, name)
+	return nil
+}
+
+// unarchiveScript moves an archived script back into the scripts directory
+func unarchiveScript(name string) error {
+	src, ok := scripts.DirStore{Dirs: []string{GetArchivedScriptsDir()}}.Find(name)
+	if !ok {
+		return fmt.Errorf("no archived script '%s' (see 'berga script list --archived')", name)
+	}
+	dst, err := moveScript(src, GetScriptsDir())
+	if err != nil {
+		return fmt.Errorf("failed to restore script '%s': %w", name, err)
+	}
+	fmt.Printf("Restored script '%s' to %s\n", name, dst)
+	return nil
+}
+
+// listArchivedScripts prints the archived scripts matching tag
+func listArchivedScripts(tag string) error {
+	dir := GetArchivedScriptsDir()
+	files, err := readListing(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	index, err := loadTagIndex()
+	if err != nil {
+		return err
+	}
+	listHeader(i18n.T("header.archived_scripts"))
+	if len(printScriptEntries(dir, files, index, tag, "  ", "")) == 0 {
+		fmt.Println(i18n.T("list.no_archived_scripts"))
+		return nil
+	}
+	fmt.Printf("\nArchive directory: %s\n", dir)
+	return nil
+}
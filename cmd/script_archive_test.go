@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveAndUnarchiveScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := writeTestScript(t, "old.sh", "#!/bin/sh\n")
+	os.WriteFile(specPathFor(src), []byte("cases: []\n"), 0644)
+
+	if err := archiveScript("old.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("Expected the script to leave the scripts directory")
+	}
+	archived := filepath.Join(GetArchivedScriptsDir(), "old.sh")
+	if _, err := os.Stat(specPathFor(archived)); err != nil {
+		t.Errorf("Expected the test spec to be archived too: %v", err)
+	}
+	names := completeNames([]string{GetScriptsDir()}, "", func(name string) (string, bool) { return name, true })
+	for _, name := range names {
+		if name == "old.sh" {
+			t.Error("Expected an archived script to be left out of completion")
+		}
+	}
+
+	// A new script may take the name; restoring must not overwrite it
+	writeTestScript(t, "old.sh", "#!/bin/sh\necho new\n")
+	if err := unarchiveScript("old.sh"); err == nil {
+		t.Error("Expected restoring over an existing script to fail")
+	}
+	os.Remove(src)
+
+	if err := unarchiveScript("old.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(src); err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Errorf("Expected the script back with its permissions, got %v, %v", info, err)
+	}
+	if err := unarchiveScript("old.sh"); err == nil {
+		t.Error("Expected an error for a script that is not archived")
+	}
+}
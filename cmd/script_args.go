@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var scriptSelectArgs bool
+
+// scriptArg is a positional argument declared in a script header with
+// "berga:arg:", e.g. "# berga:arg: environment: dev, stage, prod". An
+// argument without choices is asked for as free text.
+type scriptArg struct {
+	Name    string
+	Choices []string
+}
+
+// parseScriptArg parses a "berga:arg:" value: a name, optionally followed by
+// a colon and comma-separated choices
+func parseScriptArg(value string) (scriptArg, error) {
+	name, choices, _ := strings.Cut(value, ":")
+	arg := scriptArg{Name: strings.TrimSpace(name)}
+	if arg.Name == "" {
+		return arg, fmt.Errorf("invalid argument declaration '%s' (expected 'name: choice, choice')", value)
+	}
+	for _, choice := range strings.Split(choices, ",") {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			arg.Choices = append(arg.Choices, choice)
+		}
+	}
+	return arg, nil
+}
+
+// allows reports whether value is acceptable: any value without choices,
+// otherwise one of them
+func (a scriptArg) allows(value string) bool {
+	if len(a.Choices) == 0 {
+		return true
+	}
+	for _, choice := range a.Choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptArgSpecs returns the positional arguments a script declares, in order
+func scriptArgSpecs(scriptPath string) ([]scriptArg, error) {
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	defer f.Close()
+
+	var specs []scriptArg
+	for _, entry := range parseMetadata(f) {
+		if entry.Key != "arg" {
+			continue
+		}
+		arg, err := parseScriptArg(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, arg)
+	}
+	return specs, nil
+}
+
+// selectScriptArgs asks for each declared argument that args does not
+// already give: from a menu when it has choices, as text otherwise. Given
+// arguments must be one of their choices. Without prompts the first choice is
+// used, and a free-text argument is an error.
+func selectScriptArgs(scriptName string, specs []scriptArg, args []string) ([]string, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("script '%s' declares no arguments (add '# berga:arg: name: choice, choice' lines to its header)", scriptName)
+	}
+	for i, value := range args {
+		if i < len(specs) && !specs[i].allows(value) {
+			return nil, fmt.Errorf("'%s' is not a valid %s (expected one of: %s)", value, specs[i].Name, strings.Join(specs[i].Choices, ", "))
+		}
+	}
+
+	selected := append([]string(nil), args...)
+	for _, spec := range specs[min(len(args), len(specs)):] {
+		value, err := selectScriptArg(spec)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, value)
+	}
+	return selected, nil
+}
+
+// selectScriptArg asks for one argument, repeating until it gets a value
+func selectScriptArg(spec scriptArg) (string, error) {
+	if promptsDisabled() {
+		if len(spec.Choices) == 0 {
+			return "", fmt.Errorf("missing argument '%s' (pass it after the script name, or run interactively)", spec.Name)
+		}
+		return spec.Choices[0], nil
+	}
+	for {
+		var value string
+		var err error
+		if len(spec.Choices) > 0 {
+			value, err = promptSelect(spec.Name, spec.Choices, spec.Choices[0])
+		} else {
+			value, err = promptLine(spec.Name, "", false)
+		}
+		if err != nil && value == "" {
+			return "", fmt.Errorf("failed to read '%s': %w", spec.Name, err)
+		}
+		switch {
+		case value == "":
+			fmt.Fprintf(promptOut, "  '%s' is required\n", spec.Name)
+		case !spec.allows(value):
+			fmt.Fprintf(promptOut, "  choose one of: %s\n", strings.Join(spec.Choices, ", "))
+		default:
+			return value, nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScriptArgSpecs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "deploy.sh", "#!/bin/sh\n# berga:arg: environment: dev, stage, prod\n# berga:arg: version\necho $1 $2\n")
+
+	specs, err := scriptArgSpecs(filepath.Join(GetScriptsDir(), "deploy.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []scriptArg{{Name: "environment", Choices: []string{"dev", "stage", "prod"}}, {Name: "version"}}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("scriptArgSpecs() = %+v, want %+v", specs, want)
+	}
+	if _, err := parseScriptArg(": a, b"); err == nil {
+		t.Error("Expected an argument without a name to be an error")
+	}
+}
+
+func TestSelectScriptArgs(t *testing.T) {
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	promptOut = io.Discard
+
+	specs := []scriptArg{
+		{Name: "environment", Choices: []string{"dev", "stage", "prod"}},
+		{Name: "region", Choices: []string{"eu", "us"}},
+		{Name: "version"},
+	}
+
+	// An invalid choice is asked again; an empty answer takes the first choice
+	stdinReader = bufio.NewReader(strings.NewReader("3\nasia\n\n\n1.2\n"))
+	got, err := selectScriptArgs("deploy.sh", specs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "prod eu 1.2" {
+		t.Errorf("Unexpected arguments %q", got)
+	}
+
+	// Given arguments fill the first positions and are checked
+	stdinReader = bufio.NewReader(strings.NewReader("2\n1.3\n"))
+	if got, _ := selectScriptArgs("deploy.sh", specs, []string{"stage"}); strings.Join(got, " ") != "stage us 1.3" {
+		t.Errorf("Expected only the missing arguments to be asked for, got %q", got)
+	}
+	if _, err := selectScriptArgs("deploy.sh", specs, []string{"qa"}); err == nil || !strings.Contains(err.Error(), "not a valid environment") {
+		t.Errorf("Expected an invalid given argument to be an error, got %v", err)
+	}
+
+	setTerminal(t, false)
+	if _, err := selectScriptArgs("deploy.sh", specs, nil); err == nil || !strings.Contains(err.Error(), "missing argument 'version'") {
+		t.Errorf("Expected a free-text argument to need a terminal, got %v", err)
+	}
+	if got, _ := selectScriptArgs("deploy.sh", specs, []string{"dev", "us", "2.0", "--force"}); strings.Join(got, " ") != "dev us 2.0 --force" {
+		t.Errorf("Expected complete arguments to pass through, got %q", got)
+	}
+	if _, err := selectScriptArgs("deploy.sh", nil, nil); err == nil {
+		t.Error("Expected a script without declared arguments to be an error")
+	}
+}
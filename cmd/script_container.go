@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// containerScriptDir is where the script is mounted inside the container
+const containerScriptDir = "/berga"
+
+// containerWorkDir is where --mount-cwd mounts the current directory
+const containerWorkDir = "/work"
+
+// containerRuns numbers the containers started by this process
+var containerRuns int64
+
+// containerImage returns the image a script should run in: the --container
+// flag, or a "berga:container:" declaration in the script header
+func containerImage(scriptPath string) string {
+	if scriptContainer != "" {
+		return scriptContainer
+	}
+	return readMetadata(scriptPath)["container"]
+}
+
+// containerRuntime returns the container CLI to use: scripts.container_runtime,
+// or docker, falling back to podman
+func containerRuntime() (string, error) {
+	if cli := viper.GetString("scripts.container_runtime"); cli != "" {
+		if _, err := exec.LookPath(cli); err != nil {
+			return "", fmt.Errorf("container runtime '%s' not found in PATH", cli)
+		}
+		return cli, nil
+	}
+	for _, cli := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(cli); err == nil {
+			return cli, nil
+		}
+	}
+	return "", fmt.Errorf("--container needs docker or podman in PATH")
+}
+
+// shebangCommand returns the interpreter and arguments from a script's shebang
+func shebangCommand(scriptPath string) []string {
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() && strings.HasPrefix(scanner.Text(), "#!") {
+		return strings.Fields(scanner.Text()[2:])
+	}
+	return nil
+}
+
+// containerArgs builds the "run" arguments for executing a script in image
+func containerArgs(name, image, scriptPath string, args []string, env map[string]string, cwd string) []string {
+	inside := containerScriptDir + "/" + filepath.Base(scriptPath)
+
+	runArgs := []string{"run", "--rm", "-i", "--name", name,
+		"-v", scriptPath + ":" + inside + ":ro"}
+	if cwd != "" {
+		runArgs = append(runArgs, "-v", cwd+":"+containerWorkDir, "-w", containerWorkDir)
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		runArgs = append(runArgs, "-e", k+"="+env[k])
+	}
+	runArgs = append(runArgs, image)
+
+	// Run through the interpreter so the mount's permissions don't matter
+	interpreter := shebangCommand(scriptPath)
+	if len(interpreter) == 0 {
+		interpreter = []string{"sh"}
+	}
+	runArgs = append(runArgs, interpreter...)
+	runArgs = append(runArgs, inside)
+	return append(runArgs, args...)
+}
+
+// containerEnv returns the variables passed into a container: the env
+// profile, then --env
+func containerEnv() map[string]string {
+	env := make(map[string]string)
+	profile, _ := resolveEnvProfile(scriptEnvProfile)
+	overrides, _ := parseEnvAssignments(scriptEnvVars)
+	for _, layer := range []map[string]string{profile, overrides} {
+		for k, v := range layer {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// containerCommand builds the command that runs a script inside a container.
+// Cancelling it removes the container too, since killing the client alone
+// would leave the container running.
+func containerCommand(ctx context.Context, cli, image, scriptPath string, args []string) *exec.Cmd {
+	name := fmt.Sprintf("berga-%d-%d", os.Getpid(), atomic.AddInt64(&containerRuns, 1))
+
+	// --cwd or berga:run_in: mounts that directory as the working directory
+	// instead
+	cwd := scriptWorkDir(scriptPath)
+	if cwd == "" && scriptMountCwd {
+		cwd, _ = os.Getwd()
+	}
+	cmd := exec.CommandContext(ctx, cli, containerArgs(name, image, scriptPath, args, containerEnv(), cwd)...)
+	cmd.Cancel = func() error {
+		exec.Command(cli, "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
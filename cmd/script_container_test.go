@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContainerArgs(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "job.py")
+	if err := os.WriteFile(script, []byte("#!/usr/bin/env python3\n# berga:container: python:3.12\nprint('hi')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readMetadata(script)["container"]; got != "python:3.12" {
+		t.Errorf("Expected container metadata python:3.12, got %q", got)
+	}
+
+	got := containerArgs("berga-1-1", "python:3.12", script, []string{"--fast"}, map[string]string{"B": "2", "A": "1"}, "/src")
+	want := []string{
+		"run", "--rm", "-i", "--name", "berga-1-1",
+		"-v", script + ":/berga/job.py:ro",
+		"-v", "/src:/work", "-w", "/work",
+		"-e", "A=1", "-e", "B=2",
+		"python:3.12",
+		"/usr/bin/env", "python3", "/berga/job.py", "--fast",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("containerArgs() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"berga/pkg/scripts"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// encryptedExt marks a script stored encrypted with age
+const encryptedExt = scripts.EncryptedExt
+
+// ageIdentityKey is the secret holding the age identity for encrypted scripts
+const ageIdentityKey = "secrets.age_identity"
+
+// scriptEncryptCmd encrypts stored scripts in place
+var scriptEncryptCmd = &cobra.Command{
+	Use:   "encrypt [script-name...]",
+	Short: "Encrypt scripts with the age identity from secrets",
+	Long: `Encrypt scripts with age so they can be kept in a synced or shared scripts
+directory. Each script is replaced by <name>.age, encrypted to the recipient
+of the age identity stored in secrets.age_identity:
+
+  age-keygen | grep AGE-SECRET-KEY | xargs berga config set secrets.age_identity
+  berga script encrypt deploy.sh
+
+Encrypted scripts keep working under their plain name: 'script run deploy.sh'
+decrypts to a private temporary file for the run, and 'script edit' decrypts
+for the editor and encrypts the result again. Requires age 1.1 or later.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		for _, name := range args {
+			if err := encryptStoredScript(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptEncryptCmd)
+}
+
+// isEncryptedScript reports whether a script is stored encrypted
+func isEncryptedScript(path string) bool {
+	return strings.HasSuffix(path, encryptedExt)
+}
+
+// ageIdentity returns the age identity used for encrypted scripts
+func ageIdentity() (string, error) {
+	identity := strings.TrimSpace(viper.GetString(ageIdentityKey))
+	if identity == "" {
+		return "", fmt.Errorf("no key for encrypted scripts; store an age identity with 'berga config set %s AGE-SECRET-KEY-...'", ageIdentityKey)
+	}
+	auditSecretAccess(ageIdentityKey)
+	return identity, nil
+}
+
+// runAge runs an age tool with stdin, returning its output
+func runAge(tool string, stdin []byte, args ...string) ([]byte, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not installed (needed for encrypted scripts, see https://age-encryption.org)", tool)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", tool, msg)
+		}
+		return nil, fmt.Errorf("failed to run %s: %w", tool, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// decryptScript returns the plaintext of an encrypted script. The identity is
+// passed on stdin so it never touches the disk.
+func decryptScript(path string) ([]byte, error) {
+	identity, err := ageIdentity()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := runAge("age", []byte(identity+"\n"), "--decrypt", "--identity", "-", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", filepath.Base(path), err)
+	}
+	return plain, nil
+}
+
+// encryptScript encrypts plaintext to the recipient of the stored identity and
+// atomically writes it to path
+func encryptScript(plain []byte, path string) error {
+	identity, err := ageIdentity()
+	if err != nil {
+		return err
+	}
+	recipient, err := runAge("age-keygen", []byte(identity+"\n"), "-y")
+	if err != nil {
+		return fmt.Errorf("failed to read the age identity: %w", err)
+	}
+	ciphertext, err := runAge("age", plain, "--encrypt", "--recipient", strings.TrimSpace(string(recipient)))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", filepath.Base(path), err)
+	}
+	return writeFileAtomic(path, ciphertext, 0600)
+}
+
+// decryptToTemp decrypts a script into a private temporary directory under its
+// plain name, so the interpreter is still picked by extension and shebang. The
+// returned function removes it again.
+func decryptToTemp(path string) (string, func(), error) {
+	plain, err := decryptScript(path)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "berga-script-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	tmp := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), encryptedExt))
+	cleanup := func() {
+		decryptedScripts.Delete(tmp)
+		os.RemoveAll(dir)
+	}
+	decryptedScripts.Store(tmp, path)
+
+	if err := os.WriteFile(tmp, plain, 0700); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write decrypted script: %w", err)
+	}
+	return tmp, cleanup, nil
+}
+
+// decryptedScripts maps each decrypted temporary copy to the encrypted script
+// it was made from
+var decryptedScripts sync.Map
+
+// storedScriptPath returns the script a decrypted temporary copy was made
+// from, or path itself. Lookups relative to the script, such as berga:venv:,
+// and anything keyed by its location use it, so they never point into the
+// temporary directory.
+func storedScriptPath(path string) string {
+	if stored, ok := decryptedScripts.Load(path); ok {
+		return stored.(string)
+	}
+	return path
+}
+
+// plainScriptPath returns a path a script can be run from: the script itself,
+// or a decrypted temporary copy if it is encrypted. cleanup is never nil.
+func plainScriptPath(path string) (string, func(), error) {
+	if !isEncryptedScript(path) {
+		return path, func() {}, nil
+	}
+	return decryptToTemp(path)
+}
+
+// editEncryptedScript decrypts a script for the editor and encrypts the result
+// again if it changed
+func editEncryptedScript(editor, path string) error {
+	tmp, cleanup, err := decryptToTemp(path)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	before, err := os.ReadFile(tmp)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Opening %s (decrypted) with %s...\n", filepath.Base(path), editor)
+	if err := editorCommand(editor, tmp).Run(); err != nil {
+		return err
+	}
+
+	after, err := os.ReadFile(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to read edited script: %w", err)
+	}
+	if bytes.Equal(before, after) {
+		fmt.Println("No changes.")
+		return nil
+	}
+	if !reviewEdit(filepath.Base(path), before, after) {
+		fmt.Printf("Discarded the changes to %s\n", filepath.Base(path))
+		return nil
+	}
+	if err := encryptScript(after, path); err != nil {
+		return fmt.Errorf("%w; the script was not changed", err)
+	}
+	fmt.Printf("Encrypted %s\n", filepath.Base(path))
+	return nil
+}
+
+// encryptStoredScript replaces a plaintext script with its encrypted version
+func encryptStoredScript(name string) error {
+	path := resolveScriptPath(name)
+	if isEncryptedScript(path) {
+		return fmt.Errorf("script '%s' is already encrypted", name)
+	}
+	plain, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("script '%s' not found in %s", name, GetScriptsDir())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	if err := encryptScript(plain, path+encryptedExt); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove plaintext script: %w", err)
+	}
+	fmt.Printf("Encrypted %s -> %s\n", name, filepath.Base(path)+encryptedExt)
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResolveEncryptedScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "deploy.sh.age", "ciphertext")
+
+	if got := resolveScriptPath("deploy.sh"); got != path {
+		t.Errorf("resolveScriptPath(deploy.sh) = %s, want %s", got, path)
+	}
+	if got := resolveScriptPath("deploy.sh.age"); got != path {
+		t.Errorf("resolveScriptPath(deploy.sh.age) = %s, want %s", got, path)
+	}
+	if got := specPathFor(path); filepath.Base(got) != "deploy.sh.test.yaml" {
+		t.Errorf("specPathFor(%s) = %s", path, got)
+	}
+}
+
+func TestPlainScriptPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	plain := writeTestScript(t, "hello.sh", "#!/bin/sh\necho hi\n")
+	got, cleanup, err := plainScriptPath(plain)
+	if err != nil || got != plain {
+		t.Errorf("plainScriptPath(%s) = %s, %v", plain, got, err)
+	}
+	cleanup()
+
+	encrypted := writeTestScript(t, "deploy.sh.age", "ciphertext")
+	viper.Set(ageIdentityKey, "")
+	defer viper.Set(ageIdentityKey, nil)
+	if _, _, err := plainScriptPath(encrypted); err == nil || !strings.Contains(err.Error(), ageIdentityKey) {
+		t.Errorf("Expected an error naming %s, got %v", ageIdentityKey, err)
+	}
+}
+
+func TestEncryptedScriptRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("age"); err != nil {
+		t.Skip("age is not installed")
+	}
+	out, err := exec.Command("age-keygen").Output()
+	if err != nil {
+		t.Skip("age-keygen is not available")
+	}
+	var identity string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "AGE-SECRET-KEY-") {
+			identity = line
+		}
+	}
+	viper.Set(ageIdentityKey, identity)
+	defer viper.Set(ageIdentityKey, nil)
+
+	t.Setenv("HOME", t.TempDir())
+	content := "#!/bin/sh\necho secret\n"
+	writeTestScript(t, "deploy.sh", content)
+	if err := encryptStoredScript("deploy.sh"); err != nil {
+		t.Fatal(err)
+	}
+	path := resolveScriptPath("deploy.sh")
+	if !isEncryptedScript(path) {
+		t.Fatalf("Expected the plaintext to be replaced, resolved %s", path)
+	}
+
+	tmp, cleanup, err := plainScriptPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(tmp)
+	if string(data) != content || filepath.Base(tmp) != "deploy.sh" {
+		t.Errorf("Decrypted %s to %q", tmp, data)
+	}
+	if got := storedScriptPath(tmp); got != path {
+		t.Errorf("storedScriptPath(%s) = %s, want %s", tmp, got, path)
+	}
+	cleanup()
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Error("Expected the decrypted copy to be removed")
+	}
+	if got := storedScriptPath(tmp); got != tmp {
+		t.Errorf("Expected the removed copy to be forgotten, got %s", got)
+	}
+}
+
+func TestDecryptedScriptLookups(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stored := writeTestScript(t, "tool.py.age", "ciphertext")
+	// Stand in for decryptToTemp, which needs age
+	tmp := filepath.Join(t.TempDir(), "tool.py")
+	os.WriteFile(tmp, []byte("#!/usr/bin/env python3\n# berga:venv: ../venv\n"), 0700)
+	decryptedScripts.Store(tmp, stored)
+	defer decryptedScripts.Delete(tmp)
+
+	// Relative paths resolve next to the stored script, not the copy
+	venv := filepath.Join(filepath.Dir(GetScriptsDir()), "venv")
+	os.MkdirAll(venv, 0755)
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte("home = /usr/bin\n"), 0644)
+	if envs, err := resolveScriptEnvs(tmp); err != nil || envs.Venv != venv {
+		t.Errorf("Expected %s, got %+v %v", venv, envs, err)
+	}
+	if got := scriptTrustKey("tool.py", tmp); got != "tool.py" {
+		t.Errorf("Expected the copy to share the stored script's trust key, got %s", got)
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Danger levels for scripts. Medium asks for a yes/no confirmation before
+// running; high requires typing the script's name.
+const (
+	dangerLow    = "low"
+	dangerMedium = "medium"
+	dangerHigh   = "high"
+)
+
+var (
+	protectLevel  string
+	scriptConfirm string
+)
+
+// scriptProtectCmd marks scripts as dangerous
+var scriptProtectCmd = &cobra.Command{
+	Use:   "protect [script-name...]",
+	Short: "Require confirmation before a script runs",
+	Long: `Mark scripts as dangerous. Running a script with danger level "high" requires
+typing its name; "medium" asks for a yes/no confirmation. Scripts can also
+declare their level in the header:
+
+  # berga:danger: high
+
+Use --confirm <name> with 'script run' to confirm non-interactively.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("protected", func() error {
+			return protectScripts(args, protectLevel)
+		})
+	},
+}
+
+// scriptUnprotectCmd removes a script's danger level
+var scriptUnprotectCmd = &cobra.Command{
+	Use:   "unprotect [script-name]",
+	Short: "Remove the danger level set with 'script protect'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("protected", func() error {
+			return unprotectScript(args[0])
+		})
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptProtectCmd)
+	scriptCmd.AddCommand(scriptUnprotectCmd)
+
+	// Flags
+	scriptProtectCmd.Flags().StringVar(&protectLevel, "level", dangerHigh, "Danger level: low, medium, or high")
+}
+
+func loadProtectedStore() (map[string]string, error) {
+	store := make(map[string]string)
+
+	data, err := os.ReadFile(GetProtectedFile())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read protected scripts: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse protected scripts: %w", err)
+	}
+	return store, nil
+}
+
+func saveProtectedStore(store map[string]string) error {
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return fmt.Errorf("failed to encode protected scripts: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetProtectedFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write protected scripts: %w", err)
+	}
+	return nil
+}
+
+// normalizeDangerLevel validates a danger level. Unknown levels are treated
+// as high so a typo never weakens protection.
+func normalizeDangerLevel(level string) string {
+	switch level = strings.ToLower(strings.TrimSpace(level)); level {
+	case "", "none", dangerLow:
+		return dangerLow
+	case dangerMedium, dangerHigh:
+		return level
+	}
+	return dangerHigh
+}
+
+// scriptDangerLevel returns a script's danger level from 'script protect' or
+// its "berga:danger:" header, whichever is higher
+func scriptDangerLevel(scriptName, scriptPath string) string {
+	level := normalizeDangerLevel(readMetadata(scriptPath)["danger"])
+	if store, err := loadProtectedStore(); err == nil {
+		if stored, ok := store[scriptTrustKey(scriptName, scriptPath)]; ok {
+			stored = normalizeDangerLevel(stored)
+			if stored == dangerHigh || level == dangerLow {
+				level = stored
+			}
+		}
+	}
+	return level
+}
+
+// dangerLabel flags dangerous scripts in listings
+func dangerLabel(level string) string {
+	switch level {
+	case dangerHigh:
+		return " " + ui.Red(ui.Icon("⚠️ ", "!")+"danger: high")
+	case dangerMedium:
+		return " " + ui.Yellow(ui.Icon("⚠️ ", "!")+"danger: medium")
+	}
+	return ""
+}
+
+// confirmDangerousScript asks before running a protected script, or one a
+// confirm rule in policy.yaml covers, and refuses scripts a deny rule covers.
+// --confirm with the script's name confirms without a prompt; --assume-yes is
+// enough for medium but not for high.
+func confirmDangerousScript(scriptName, scriptPath string) error {
+	rule, ctx, err := checkScriptPolicy(scriptName)
+	if err != nil {
+		return err
+	}
+	level := scriptDangerLevel(scriptName, scriptPath)
+	reason := i18n.T("danger.marked", level)
+	if rule != nil {
+		level, reason = dangerHigh, rule.requirement(ctx)
+	}
+	if level == dangerLow {
+		return nil
+	}
+
+	base := filepath.Base(scriptPath)
+	if scriptConfirm != "" {
+		if scriptConfirm == scriptName || scriptConfirm == base {
+			return nil
+		}
+		return errors.New(i18n.T("error.confirm_mismatch", scriptConfirm, scriptName))
+	}
+	if level == dangerMedium && assumeYes {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return errors.New(i18n.T("error.confirm_required", scriptName, reason, scriptName))
+	}
+
+	fmt.Fprintln(os.Stderr, ui.ErrRed(ui.Icon("⚠️ ", "!")+" "+i18n.T("danger.warning", scriptName, reason)))
+	if level == dangerMedium {
+		fmt.Fprintf(os.Stderr, "%s %s: ", i18n.T("prompt.run_it"), i18n.T("prompt.hint_default_no"))
+		response, _ := readLine()
+		if !i18n.IsYes(response) {
+			return errors.New(i18n.T("error.cancelled"))
+		}
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, i18n.T("prompt.type_script_name", base))
+	response, _ := readLine()
+	response = strings.TrimSpace(response)
+	if response != scriptName && response != base {
+		return errors.New(i18n.T("error.confirmation_mismatch", scriptName))
+	}
+	return nil
+}
+
+func protectScripts(names []string, level string) error {
+	switch level {
+	case dangerLow, dangerMedium, dangerHigh:
+	default:
+		return fmt.Errorf("invalid level '%s' (expected low, medium, or high)", level)
+	}
+
+	store, err := loadProtectedStore()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		path := resolveScriptPath(name)
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("script '%s' not found in %s", name, GetScriptsDir())
+		}
+		store[scriptTrustKey(name, path)] = level
+		fmt.Printf("Script '%s' is now danger: %s\n", name, level)
+	}
+	return saveProtectedStore(store)
+}
+
+func unprotectScript(name string) error {
+	store, err := loadProtectedStore()
+	if err != nil {
+		return err
+	}
+	key := scriptTrustKey(name, resolveScriptPath(name))
+	if _, ok := store[key]; !ok {
+		return fmt.Errorf("script '%s' is not protected", name)
+	}
+	delete(store, key)
+	if err := saveProtectedStore(store); err != nil {
+		return err
+	}
+	fmt.Printf("Script '%s' is no longer protected\n", name)
+	if level := normalizeDangerLevel(readMetadata(resolveScriptPath(name))["danger"]); level != dangerLow {
+		fmt.Printf("Its header still declares danger: %s\n", level)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestScript(t *testing.T, name, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(GetScriptsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(GetScriptsDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNormalizeDangerLevel(t *testing.T) {
+	tests := map[string]string{
+		"":        dangerLow,
+		"none":    dangerLow,
+		"Medium":  dangerMedium,
+		" high ":  dangerHigh,
+		"extreme": dangerHigh,
+	}
+	for in, want := range tests {
+		if got := normalizeDangerLevel(in); got != want {
+			t.Errorf("normalizeDangerLevel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScriptDangerLevel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	header := writeTestScript(t, "wipe.sh", "#!/bin/sh\n# berga:danger: medium\n")
+	plain := writeTestScript(t, "list.sh", "#!/bin/sh\n")
+
+	if got := scriptDangerLevel("wipe.sh", header); got != dangerMedium {
+		t.Errorf("Expected header level medium, got %s", got)
+	}
+	if got := scriptDangerLevel("list.sh", plain); got != dangerLow {
+		t.Errorf("Expected unmarked script to be low, got %s", got)
+	}
+
+	if err := protectScripts([]string{"wipe.sh", "list.sh"}, dangerHigh); err != nil {
+		t.Fatal(err)
+	}
+	if got := scriptDangerLevel("wipe.sh", header); got != dangerHigh {
+		t.Errorf("Expected protect to raise the level to high, got %s", got)
+	}
+
+	if err := protectScripts([]string{"wipe.sh"}, dangerLow); err != nil {
+		t.Fatal(err)
+	}
+	if got := scriptDangerLevel("wipe.sh", header); got != dangerMedium {
+		t.Errorf("Expected the header level to remain in force, got %s", got)
+	}
+
+	if err := unprotectScript("list.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if got := scriptDangerLevel("list.sh", plain); got != dangerLow {
+		t.Errorf("Expected unprotect to clear the level, got %s", got)
+	}
+	if err := protectScripts([]string{"list.sh"}, "severe"); err == nil {
+		t.Error("Expected an invalid level to be rejected")
+	}
+}
+
+func TestConfirmDangerousScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "wipe.sh", "#!/bin/sh\n# berga:danger: high\n")
+
+	defer func() { scriptConfirm = "" }()
+
+	scriptConfirm = "wipe.sh"
+	if err := confirmDangerousScript("wipe.sh", path); err != nil {
+		t.Errorf("Expected --confirm with the name to pass, got %v", err)
+	}
+	scriptConfirm = "other.sh"
+	if err := confirmDangerousScript("wipe.sh", path); err == nil {
+		t.Error("Expected a mismatched --confirm to fail")
+	}
+}
+
+func TestAPIRefusesProtectedScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "wipe.sh", "#!/bin/sh\n# berga:danger: high\necho wiped\n")
+
+	handler := newAPIHandler("secret")
+	req := httptest.NewRequest(http.MethodPost, "/api/scripts/wipe.sh/run", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a protected script, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// cleanEnvKeys are the variables --clean-env keeps, so the script can still
+// find programs, its home directory, and the terminal
+var cleanEnvKeys = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "LANG", "LC_ALL", "TMPDIR", "TZ"}
+
+// cleanEnvKeysWindows are the variables --clean-env keeps on Windows, where
+// programs fail in odd ways without them
+var cleanEnvKeysWindows = []string{"PATH", "PATHEXT", "SYSTEMROOT", "SYSTEMDRIVE", "COMSPEC", "WINDIR",
+	"USERPROFILE", "USERNAME", "APPDATA", "LOCALAPPDATA", "TEMP", "TMP", "HOMEDRIVE", "HOMEPATH"}
+
+// minimalEnviron returns the subset of the current environment kept by
+// --clean-env
+func minimalEnviron() []string {
+	keep := cleanEnvKeys
+	if runtime.GOOS == "windows" {
+		keep = cleanEnvKeysWindows
+	}
+
+	var environ []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		for _, k := range keep {
+			// Windows variable names are case-insensitive
+			if key == k || runtime.GOOS == "windows" && strings.EqualFold(key, k) {
+				environ = append(environ, kv)
+				break
+			}
+		}
+	}
+	return environ
+}
+
+// parseEnvAssignments parses repeated --env KEY=VAL values
+func parseEnvAssignments(values []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, value := range values {
+		key, val, found := strings.Cut(value, "=")
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid --env '%s' (expected KEY=VALUE)", value)
+		}
+		env[key] = val
+	}
+	return env, nil
+}
+
+// checkRunEnvironment validates --env and --cwd before a script starts
+func checkRunEnvironment() error {
+	if _, err := parseEnvAssignments(scriptEnvVars); err != nil {
+		return err
+	}
+	// With --hosts, --cwd names a directory on the remote hosts
+	if scriptCwd != "" && len(scriptHosts) == 0 {
+		info, err := os.Stat(scriptCwd)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("--cwd %s is not a directory", scriptCwd)
+		}
+	}
+	return nil
+}
+
+// runInDir returns the directory a script's "berga:run_in:" declaration runs
+// it in: the root of the current git repository (git-root), the berga config
+// directory (config-dir), or "" for the current directory (cwd, the default)
+func runInDir(scriptPath string) (string, error) {
+	switch value := readMetadata(scriptPath)["run_in"]; value {
+	case "", "cwd":
+		return "", nil
+	case "git-root":
+		out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+		if err != nil {
+			return "", fmt.Errorf("the script runs in the git root (berga:run_in: git-root), but the current directory is not in a git repository; use --cwd to choose a directory")
+		}
+		return filepath.FromSlash(strings.TrimSpace(string(out))), nil
+	case "config-dir":
+		return GetConfigDir(), nil
+	default:
+		return "", fmt.Errorf("invalid berga:run_in: %s (expected git-root, config-dir, or cwd)", value)
+	}
+}
+
+// scriptWorkDir returns the absolute --cwd, else the directory from the
+// script's berga:run_in:, or "" to run in the current directory
+func scriptWorkDir(scriptPath string) string {
+	if scriptCwd == "" {
+		dir, _ := runInDir(scriptPath)
+		return dir
+	}
+	if abs, err := filepath.Abs(scriptCwd); err == nil {
+		return abs
+	}
+	return scriptCwd
+}
+
+// scriptEnviron returns the environment for a script run: the current or,
+// with --clean-env, a minimal environment, then the env profile, then --env.
+// It returns nil when the script should simply inherit berga's environment.
+func scriptEnviron() []string {
+	profile, _ := resolveEnvProfile(scriptEnvProfile)
+	overrides, _ := parseEnvAssignments(scriptEnvVars)
+	if !scriptCleanEnv && len(profile) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	base := os.Environ()
+	if scriptCleanEnv {
+		base = minimalEnviron()
+	}
+	return layerEnviron(base, profile, overrides)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvAssignments(t *testing.T) {
+	env, err := parseEnvAssignments([]string{"A=1", "B=x=y", "EMPTY="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["A"] != "1" || env["B"] != "x=y" || env["EMPTY"] != "" {
+		t.Errorf("Unexpected assignments %v", env)
+	}
+	for _, bad := range []string{"NOVALUE", "=x", "A B=1"} {
+		if _, err := parseEnvAssignments([]string{bad}); err == nil {
+			t.Errorf("Expected '%s' to be rejected", bad)
+		}
+	}
+}
+
+func TestScriptEnviron(t *testing.T) {
+	defer func() {
+		scriptCleanEnv = false
+		scriptEnvVars = nil
+	}()
+	t.Setenv("BERGA_TEST_SECRET", "leak")
+
+	if env := scriptEnviron(); env != nil {
+		t.Errorf("Expected the environment to be inherited, got %d variables", len(env))
+	}
+
+	scriptCleanEnv = true
+	scriptEnvVars = []string{"MODE=test"}
+	env := strings.Join(scriptEnviron(), "\n")
+	if strings.Contains(env, "BERGA_TEST_SECRET") {
+		t.Error("Expected --clean-env to drop other variables")
+	}
+	if !strings.Contains(env, "MODE=test") {
+		t.Error("Expected --env to be set")
+	}
+	if os.Getenv("PATH") != "" && !strings.Contains(env, "PATH=") {
+		t.Error("Expected --clean-env to keep PATH")
+	}
+}
+
+func TestScriptCommandCwd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "pwd.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\npwd\necho \"$MODE\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	work := t.TempDir()
+
+	scriptCwd = work
+	scriptEnvVars = []string{"MODE=sandboxed"}
+	defer func() {
+		scriptCwd = ""
+		scriptEnvVars = nil
+	}()
+	if err := checkRunEnvironment(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := scriptCommand(context.Background(), script, nil).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	resolved, _ := filepath.EvalSymlinks(work)
+	if len(lines) != 2 || (lines[0] != work && lines[0] != resolved) || lines[1] != "sandboxed" {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	scriptCwd = filepath.Join(work, "missing")
+	if err := checkRunEnvironment(); err == nil {
+		t.Error("Expected a missing --cwd to be rejected")
+	}
+}
+
+func TestRunInDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	write := func(runIn string) string {
+		return writeTestScript(t, "where.sh", "#!/bin/sh\n# berga:run_in: "+runIn+"\npwd\n")
+	}
+
+	if dir, err := runInDir(write("config-dir")); err != nil || dir != GetConfigDir() {
+		t.Errorf("Expected the config directory, got %q, %v", dir, err)
+	}
+	if dir, err := runInDir(write("cwd")); err != nil || dir != "" {
+		t.Errorf("Expected the current directory, got %q, %v", dir, err)
+	}
+	if _, err := runInDir(write("home")); err == nil {
+		t.Error("Expected an unknown run_in to be rejected")
+	}
+
+	scriptCwd = t.TempDir()
+	defer func() { scriptCwd = "" }()
+	if dir := scriptWorkDir(write("config-dir")); dir != scriptCwd {
+		t.Errorf("Expected --cwd to win over run_in, got %q", dir)
+	}
+}
+
+func TestRunInGitRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("HOME", t.TempDir())
+	script := writeTestScript(t, "build.sh", "#!/bin/sh\n# berga:run_in: git-root\n")
+
+	repo, _ := filepath.EvalSymlinks(t.TempDir())
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	sub := filepath.Join(repo, "src", "app")
+	os.MkdirAll(sub, 0755)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := runInDir(script); err != nil || dir != repo {
+		t.Errorf("Expected the repository root %q, got %q, %v", repo, dir, err)
+	}
+
+	outside, _ := filepath.EvalSymlinks(t.TempDir())
+	os.Chdir(outside)
+	if _, err := runInDir(script); err == nil {
+		t.Error("Expected git-root outside a repository to fail")
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	scriptListGroup      string
+	scriptGroupKeepGoing bool
+)
+
+// scriptRunGroupCmd runs every script in a group
+var scriptRunGroupCmd = &cobra.Command{
+	Use:   "run-group [group] [args...]",
+	Short: "Run the scripts of a group one after another",
+	Long: `Run every script in a group from config, in the order listed, passing the
+same arguments to each:
+
+  groups:
+    deploy: [build.sh, deploy.sh, smoke-test.sh]
+
+The run stops at the first script that fails unless --keep-going is set; the
+exit status is that of the first failure.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runScriptGroup(args[0], args[1:])
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptRunGroupCmd)
+
+	// Flags
+	scriptRunGroupCmd.Flags().BoolVar(&scriptGroupKeepGoing, "keep-going", false, "Run the remaining scripts after one fails")
+}
+
+// scriptGroups returns the script groups from config. Members may be given as
+// a list or as one comma-separated string.
+func scriptGroups() map[string][]string {
+	groups := make(map[string][]string)
+	for name := range viper.GetStringMap("groups") {
+		var members []string
+		for _, value := range viper.GetStringSlice("groups." + name) {
+			for _, member := range strings.Split(value, ",") {
+				if member = strings.TrimSpace(member); member != "" {
+					members = append(members, member)
+				}
+			}
+		}
+		groups[name] = members
+	}
+	return groups
+}
+
+// scriptGroupMembers returns the scripts of a group, in config order
+func scriptGroupMembers(group string) ([]string, error) {
+	groups := scriptGroups()
+	members, ok := groups[group]
+	if !ok {
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("script group '%s' not found; define groups in config, e.g. 'berga config set groups.%s \"build.sh,deploy.sh\"'", group, group)
+		}
+		return nil, fmt.Errorf("script group '%s' not found (groups: %s)", group, strings.Join(sortedGroupNames(groups), ", "))
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("script group '%s' is empty", group)
+	}
+	return members, nil
+}
+
+// sortedGroupNames returns the group names in alphabetical order
+func sortedGroupNames(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupMemberMatches reports whether a group member names the script file.
+// Encrypted scripts are named without their .age suffix, as on the command line.
+func groupMemberMatches(member, file string) bool {
+	return member == file || member == strings.TrimSuffix(file, encryptedExt)
+}
+
+// groupEntries returns the files that belong to a group, in listing order
+func groupEntries(files []os.DirEntry, members []string) []os.DirEntry {
+	var entries []os.DirEntry
+	for _, file := range files {
+		for _, member := range members {
+			if groupMemberMatches(member, file.Name()) {
+				entries = append(entries, file)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// runScriptGroup runs a group's scripts in order with the same arguments
+func runScriptGroup(group string, args []string) error {
+	members, err := scriptGroupMembers(group)
+	if err != nil {
+		return err
+	}
+	// Catch typos before anything runs
+	for _, member := range members {
+		if _, err := os.Stat(resolveScriptPath(member)); err != nil {
+			return fmt.Errorf("script '%s' in group '%s' not found in %s", member, group, GetScriptsDir())
+		}
+	}
+
+	var firstErr error
+	var failed []string
+	for i, member := range members {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(ui.Bold(fmt.Sprintf("==> %s (%d/%d)", member, i+1, len(members))))
+		if err := runScript(member, args); err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("%s failed: %v", member, err)))
+			failed = append(failed, member)
+			if firstErr == nil {
+				firstErr = err
+			}
+			if !scriptGroupKeepGoing {
+				break
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("group '%s' failed (%s): %w", group, strings.Join(failed, ", "), firstErr)
+	}
+	fmt.Printf("\nGroup '%s': all %d scripts succeeded\n", group, len(members))
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestScriptGroups(t *testing.T) {
+	viper.Set("groups.deploy", []interface{}{"build.sh", "deploy.sh"})
+	viper.Set("groups.ops", "backup.sh, prune.sh")
+	defer viper.Set("groups", nil)
+
+	groups := scriptGroups()
+	if !reflect.DeepEqual(groups["deploy"], []string{"build.sh", "deploy.sh"}) {
+		t.Errorf("Unexpected deploy group %v", groups["deploy"])
+	}
+	if !reflect.DeepEqual(groups["ops"], []string{"backup.sh", "prune.sh"}) {
+		t.Errorf("Unexpected ops group %v", groups["ops"])
+	}
+
+	if _, err := scriptGroupMembers("missing"); err == nil {
+		t.Error("Expected an error for an unknown group")
+	}
+}
+
+func TestGroupEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.sh", "b.sh", "secret.sh.age"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, entry := range groupEntries(files, []string{"secret.sh", "a.sh", "a"}) {
+		got = append(got, entry.Name())
+	}
+	if want := []string{"a.sh", "secret.sh.age"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("groupEntries() = %v, want %v", got, want)
+	}
+}
+
+func TestRunScriptGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	log := filepath.Join(t.TempDir(), "log")
+	writeTestScript(t, "first.sh", "#!/bin/sh\necho first >> \"$1\"\n")
+	writeTestScript(t, "fails.sh", "#!/bin/sh\nexit 2\n")
+	writeTestScript(t, "last.sh", "#!/bin/sh\necho last >> \"$1\"\n")
+	viper.Set("groups.release", "first.sh,fails.sh,last.sh")
+	viper.Set("groups.typo", "first.sh,nope.sh")
+	defer viper.Set("groups", nil)
+
+	err := runScriptGroup("release", []string{log})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Fatalf("Expected the failing script's exit status, got %v", err)
+	}
+	if data, _ := os.ReadFile(log); string(data) != "first\n" {
+		t.Errorf("Expected the group to stop after the failure, got %q", data)
+	}
+
+	os.Remove(log)
+	scriptGroupKeepGoing = true
+	defer func() { scriptGroupKeepGoing = false }()
+	runScriptGroup("release", []string{log})
+	if data, _ := os.ReadFile(log); string(data) != "first\nlast\n" {
+		t.Errorf("Expected --keep-going to run the rest, got %q", data)
+	}
+
+	if err := runScriptGroup("typo", nil); err == nil {
+		t.Error("Expected an error for a missing group member")
+	}
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// defaultHostParallel is how many hosts --hosts runs on at once by default
+const defaultHostParallel = 4
+
+var (
+	scriptHosts    []string
+	scriptParallel int
+	scriptFailFast bool
+)
+
+// Host run states shown in the summary table
+const (
+	hostOK      = "ok"
+	hostFailed  = "failed"
+	hostSkipped = "skipped"
+)
+
+// hostResult is the outcome of running a script on one host
+type hostResult struct {
+	Host     string
+	Status   string
+	ExitCode int
+	Err      error
+	Duration time.Duration
+}
+
+// expandHosts turns --hosts values into a list of hosts. Each value may hold
+// several hosts separated by commas; "@name" expands to the hosts.<name> list
+// from config. Duplicates are dropped, keeping the first occurrence.
+func expandHosts(values []string) ([]string, error) {
+	var hosts []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if host = strings.TrimSpace(host); host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			group, isGroup := strings.CutPrefix(item, "@")
+			if !isGroup {
+				add(item)
+				continue
+			}
+			members := viper.GetStringSlice("hosts." + group)
+			if len(members) == 0 {
+				return nil, fmt.Errorf("host group '%s' not found; define it with 'berga config set hosts.%s \"web1,web2\"'", group, group)
+			}
+			for _, member := range members {
+				for _, host := range strings.Split(member, ",") {
+					add(host)
+				}
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("--hosts needs at least one host")
+	}
+	return hosts, nil
+}
+
+// checkHostFlags rejects run options that have no meaning on a remote host
+func checkHostFlags() error {
+	switch {
+	case scriptContainer != "":
+		return fmt.Errorf("--hosts cannot be combined with --container")
+	case len(scriptWatch) > 0:
+		return fmt.Errorf("--hosts cannot be combined with --watch")
+	case scriptInputFile != "":
+		return fmt.Errorf("--hosts cannot be combined with --input-file; the script itself is sent on standard input")
+	case scriptCleanEnv:
+		return fmt.Errorf("--hosts cannot be combined with --clean-env")
+	case scriptParallel < 1:
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	return nil
+}
+
+// remoteCommand builds the shell command run on each host. The script arrives
+// on standard input and is saved to a temporary file, so its own shebang picks
+// the interpreter just like a local run.
+func remoteCommand(scriptPath string, args []string) string {
+	var run []string
+	env := make(map[string]string)
+	profile, _ := resolveEnvProfile(scriptEnvProfile)
+	overrides, _ := parseEnvAssignments(scriptEnvVars)
+	for _, layer := range []map[string]string{profile, overrides} {
+		for k, v := range layer {
+			env[k] = v
+		}
+	}
+	if len(env) > 0 {
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		run = append(run, "env")
+		for _, k := range keys {
+			run = append(run, posixQuote(k+"="+env[k]))
+		}
+	}
+	if len(shebangCommand(scriptPath)) == 0 {
+		run = append(run, "sh")
+	}
+	run = append(run, `"$f"`)
+	for _, arg := range args {
+		run = append(run, posixQuote(arg))
+	}
+
+	cmd := `f=$(mktemp) && cat > "$f" && chmod 700 "$f" && `
+	if scriptCwd != "" {
+		cmd += "cd " + posixQuote(scriptCwd) + " && "
+	}
+	return cmd + strings.Join(run, " ") + `; rc=$?; rm -f "$f"; exit $rc`
+}
+
+// runOnHost runs a script on one host over ssh, labelling each output line
+// with the host name
+func runOnHost(ctx context.Context, host string, script []byte, command string, timeout time.Duration, stdout, stderr io.Writer) hostResult {
+	started := time.Now()
+	ctx, cancel := runContext(ctx, timeout)
+	defer cancel()
+
+	// BatchMode fails instead of prompting for a password no one can answer
+	cmd := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", "--", host, command)
+	cmd.Stdin = bytes.NewReader(script)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+
+	result := hostResult{Host: host, Status: hostOK, Duration: time.Since(started)}
+	if err == nil {
+		return result
+	}
+	result.Status = hostFailed
+	result.ExitCode = -1
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Err = fmt.Errorf("timed out after %v", timeout)
+	case ctx.Err() != nil:
+		result.Status = hostSkipped
+		result.Err = fmt.Errorf("stopped by --fail-fast")
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		// ssh itself exits 255 when it cannot connect
+		if result.ExitCode == 255 {
+			result.Err = fmt.Errorf("ssh connection failed")
+		}
+	default:
+		result.Err = err
+	}
+	return result
+}
+
+// runScriptOnHosts runs a script on every host with at most --parallel
+// running at once. With --fail-fast the first failure stops the hosts still
+// running and skips those not yet started.
+func runScriptOnHosts(scriptName, scriptPath string, args, hosts []string, timeout time.Duration) error {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return fmt.Errorf("--hosts needs ssh in PATH")
+	}
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+	command := remoteCommand(scriptPath, args)
+
+	width := 0
+	for _, host := range hosts {
+		width = max(width, len(host))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mu := &sync.Mutex{}
+	results := make([]hostResult, len(hosts))
+	slots := make(chan struct{}, scriptParallel)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			results[i] = hostResult{Host: host, Status: hostSkipped, ExitCode: -1}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			label := ui.Cyan(fmt.Sprintf("%-*s", width, host)) + " |"
+			stdout := &lineWriter{mu: mu, out: os.Stdout, label: label, stamps: scriptTimestamps, now: time.Now}
+			stderr := &lineWriter{mu: mu, out: os.Stderr, label: label, stamps: scriptTimestamps, now: time.Now}
+			results[i] = runOnHost(ctx, host, script, command, timeout, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+			if results[i].Status == hostFailed && scriptFailFast {
+				cancel()
+			}
+		}(i, host)
+	}
+	wg.Wait()
+
+	printHostResults(os.Stdout, results)
+	failed := 0
+	for _, r := range results {
+		if r.Status != hostOK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed or skipped on %d of %d hosts", scriptName, failed, len(hosts))
+	}
+	return nil
+}
+
+// printHostResults prints the per-host status table
+func printHostResults(w io.Writer, results []hostResult) {
+	width := len("HOST")
+	for _, r := range results {
+		width = max(width, len(r.Host))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-*s  %-8s %5s  %8s\n", width, "HOST", "STATUS", "EXIT", "TIME")
+	for _, r := range results {
+		status := fmt.Sprintf("%-8s", r.Status)
+		switch r.Status {
+		case hostOK:
+			status = ui.Green(status)
+		case hostFailed:
+			status = ui.Red(status)
+		default:
+			status = ui.Dim(status)
+		}
+		exit := "-"
+		if r.ExitCode >= 0 {
+			exit = fmt.Sprint(r.ExitCode)
+		}
+		elapsed := "-"
+		if r.Duration > 0 {
+			elapsed = r.Duration.Round(100 * time.Millisecond).String()
+		}
+		line := fmt.Sprintf("%-*s  %s %5s  %8s", width, r.Host, status, exit, elapsed)
+		if r.Err != nil {
+			line += "  " + ui.Dim(r.Err.Error())
+		}
+		fmt.Fprintln(w, line)
+	}
+}
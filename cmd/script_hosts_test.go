@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestExpandHosts(t *testing.T) {
+	viper.Set("hosts.web", "web1, web2")
+	viper.Set("hosts.db", []interface{}{"db1", "db2"})
+	defer viper.Set("hosts", nil)
+
+	got, err := expandHosts([]string{"@web,lb", "web2", "@db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"web1", "web2", "lb", "db1", "db2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandHosts() = %v, want %v", got, want)
+	}
+
+	if _, err := expandHosts([]string{"@missing"}); err == nil {
+		t.Error("Expected an error for an unknown host group")
+	}
+	if _, err := expandHosts([]string{" , "}); err == nil {
+		t.Error("Expected an error for an empty host list")
+	}
+}
+
+func TestRemoteCommand(t *testing.T) {
+	dir := t.TempDir()
+	withShebang := filepath.Join(dir, "a.sh")
+	plain := filepath.Join(dir, "b.sh")
+	os.WriteFile(withShebang, []byte("#!/bin/bash\necho hi\n"), 0644)
+	os.WriteFile(plain, []byte("echo hi\n"), 0644)
+
+	scriptEnvVars = []string{"B=2", "A=it's"}
+	scriptCwd = "/srv/app"
+	defer func() { scriptEnvVars, scriptCwd = nil, "" }()
+
+	got := remoteCommand(withShebang, []string{"x y"})
+	want := `f=$(mktemp) && cat > "$f" && chmod 700 "$f" && cd '/srv/app' && env 'A=it'\''s' 'B=2' "$f" 'x y'; rc=$?; rm -f "$f"; exit $rc`
+	if got != want {
+		t.Errorf("remoteCommand() =\n%s\nwant\n%s", got, want)
+	}
+	if got := remoteCommand(plain, nil); !strings.Contains(got, `'B=2' sh "$f"; rc=$?`) {
+		t.Errorf("Expected a script without a shebang to run through sh, got %s", got)
+	}
+}
+
+func TestRunScriptOnHosts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// A stand-in ssh that runs the remote command locally and fails for "bad"
+	bin := t.TempDir()
+	fake := "#!/bin/sh\nshift 3\nhost=$1\nshift\n[ \"$host\" = bad ] && exit 4\nexec sh -c \"$1\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	script := filepath.Join(t.TempDir(), "hi.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"hi $1\"\n"), 0644)
+
+	scriptParallel = 1
+	defer func() { scriptParallel, scriptFailFast = defaultHostParallel, false }()
+
+	if err := runScriptOnHosts("hi.sh", script, []string{"there"}, []string{"h1", "h2"}, time.Minute); err != nil {
+		t.Errorf("Expected all hosts to succeed, got %v", err)
+	}
+
+	scriptFailFast = true
+	err := runScriptOnHosts("hi.sh", script, nil, []string{"bad", "h1", "h2"}, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "3 of 3 hosts") {
+		t.Errorf("Expected --fail-fast to skip the remaining hosts, got %v", err)
+	}
+}
+
+func TestPrintHostResults(t *testing.T) {
+	var out bytes.Buffer
+	printHostResults(&out, []hostResult{
+		{Host: "web1", Status: hostOK, Duration: time.Second},
+		{Host: "database", Status: hostFailed, ExitCode: 2, Duration: 1500 * time.Millisecond},
+		{Host: "web2", Status: hostSkipped, ExitCode: -1},
+	})
+	want := `
+HOST      STATUS    EXIT      TIME
+web1      ok           0        1s
+database  failed       2      1.5s
+web2      skipped      -         -
+`
+	if out.String() != want {
+		t.Errorf("Unexpected table:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
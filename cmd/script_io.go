@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaExt marks the JSON schemas scripts declare contracts with, which
+// are kept out of script listings
+const schemaExt = ".schema.json"
+
+// scriptValidateIO checks the JSON a script reads and writes against its
+// declared contracts
+var scriptValidateIO bool
+
+// jsonSchema is the part of JSON Schema that script contracts are checked
+// against: types, object properties, array items, enums, and the usual
+// bounds. Other keywords are ignored.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *additionalProperties  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is "type", given as one name or a list of names
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("type must be a name or a list of names")
+	}
+	*t = names
+	return nil
+}
+
+// additionalProperties is false, true, or the schema extra properties must
+// match
+type additionalProperties struct {
+	Allowed bool
+	Schema  *jsonSchema
+}
+
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// compile checks the schema and prepares its patterns
+func (s *jsonSchema) compile() error {
+	for _, name := range s.Type {
+		switch name {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("unknown type '%s'", name)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	children := []*jsonSchema{s.Items}
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.Schema)
+	}
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonType names the JSON type of a value decoded with UseNumber
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+// validate appends a problem for every way value breaks the schema, each
+// prefixed with where in the document it is
+func (s *jsonSchema) validate(value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	kind := jsonType(value)
+	if len(s.Type) > 0 {
+		ok := false
+		for _, name := range s.Type {
+			ok = ok || name == kind || (name == "number" && kind == "integer")
+		}
+		if !ok {
+			report("expected %s, got %s", strings.Join(s.Type, " or "), kind)
+			return
+		}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, option := range s.Enum {
+			found = found || jsonEqual(option, value)
+		}
+		if !found {
+			report("%s is not one of the allowed values", compactJSON(value))
+		}
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			report("%s is less than the minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			report("%s is more than the maximum %v", v, *s.Maximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			report("is shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("is longer than %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("%q does not match %s", v, s.Pattern)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("has %d items, fewer than %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("has %d items, more than %d", len(v), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("missing required property '%s'", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(v[name], path+"."+name, problems)
+				continue
+			}
+			if extra := s.AdditionalProperties; extra != nil {
+				if !extra.Allowed {
+					report("unexpected property '%s'", name)
+				} else if extra.Schema != nil {
+					extra.Schema.validate(v[name], path+"."+name, problems)
+				}
+			}
+		}
+	}
+}
+
+// jsonEqual compares two decoded JSON values, numbers by value
+func jsonEqual(a, b interface{}) bool {
+	if x, ok := a.(json.Number); ok {
+		if y, ok := b.(json.Number); ok {
+			fx, _ := x.Float64()
+			fy, _ := y.Float64()
+			return fx == fy
+		}
+		return false
+	}
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// decodeJSON parses a single JSON document, keeping numbers exact
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("no JSON found")
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("more than one JSON value")
+	}
+	return value, nil
+}
+
+// ioContract is what a script declares about its standard input and output
+// with "berga:input:" and "berga:output:"
+type ioContract struct {
+	Input, Output         *jsonSchema // nil when not declared
+	InputName, OutputName string      // the declarations, for messages
+}
+
+// loadIOContract reads the contracts of the script at scriptPath, stored in
+// dir. A declaration is "json" for any JSON document, or the path of a JSON
+// schema relative to dir.
+func loadIOContract(scriptPath, dir string) (*ioContract, error) {
+	meta := readMetadata(scriptPath)
+	contract := &ioContract{InputName: meta["input"], OutputName: meta["output"]}
+	var err error
+	if contract.Input, err = loadContractSchema(dir, contract.InputName); err != nil {
+		return nil, fmt.Errorf("invalid berga:input: %w", err)
+	}
+	if contract.Output, err = loadContractSchema(dir, contract.OutputName); err != nil {
+		return nil, fmt.Errorf("invalid berga:output: %w", err)
+	}
+	return contract, nil
+}
+
+func loadContractSchema(dir, value string) (*jsonSchema, error) {
+	switch value {
+	case "":
+		return nil, nil
+	case "json":
+		return &jsonSchema{}, nil
+	}
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	schema := &jsonSchema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", value, err)
+	}
+	if err := schema.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", value, err)
+	}
+	return schema, nil
+}
+
+// checkDocument validates data against schema, naming the stream and the
+// declaration in the error
+func checkDocument(schema *jsonSchema, data []byte, stream, declared string) error {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", stream, err)
+	}
+	var problems []string
+	schema.validate(value, "$", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s does not match %s:\n  - %s", stream, declared, strings.Join(problems, "\n  - "))
+}
+
+// checkIOFlags rejects flags that cannot be combined with --validate-io
+func checkIOFlags() error {
+	if !scriptValidateIO {
+		return nil
+	}
+	switch {
+	case len(scriptWatch) > 0:
+		return fmt.Errorf("--validate-io cannot be used with --watch")
+	case len(scriptHosts) > 0:
+		return fmt.Errorf("--validate-io cannot be used with --hosts")
+	case scriptResultJSON:
+		return fmt.Errorf("--validate-io cannot be used with --result-json")
+	case scriptBackground:
+		return fmt.Errorf("--validate-io cannot be used with --background")
+	}
+	return nil
+}
+
+// activeOutputCheck collects the script's stdout while --validate-io checks
+// an output contract; read by executeScript
+var activeOutputCheck *bytes.Buffer
+
+// readValidatedInput reads the script's standard input, from --input-file or
+// our own stdin, and checks it against the input contract
+func readValidatedInput(contract *ioContract) ([]byte, error) {
+	var in io.Reader = os.Stdin
+	if scriptInputFile != "" {
+		f, err := os.Open(scriptInputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if err := checkDocument(contract.Input, data, "input", contract.InputName); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeValidatedOutput checks what the script wrote against the output
+// contract and prints it indented. Output that breaks the contract goes to
+// stderr as it was, so that nothing invalid flows down a pipeline.
+func writeValidatedOutput(contract *ioContract, data []byte) error {
+	if err := checkDocument(contract.Output, data, "output", contract.OutputName); err != nil {
+		os.Stderr.Write(data)
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(data), "", "  "); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	_, err := os.Stdout.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOrderSchema = `{
+  "type": "object",
+  "required": ["id", "items"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "status": {"enum": ["open", "paid"]},
+    "items": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["sku"],
+        "properties": {
+          "sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+          "qty": {"type": ["integer", "null"]}
+        }
+      }
+    }
+  }
+}`
+
+func TestIOContract(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "order"+schemaExt), []byte(testOrderSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "total.sh")
+	content := "#!/bin/sh\n# berga:input: order.schema.json\n# berga:output: json\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	contract, err := loadIOContract(script, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `{"id": 5, "status": "paid", "items": [{"sku": "ABC-1", "qty": 2}, {"sku": "XYZ-9", "qty": null}]}`
+	if err := checkDocument(contract.Input, []byte(valid), "input", contract.InputName); err != nil {
+		t.Errorf("Expected a valid order, got %v", err)
+	}
+
+	invalid := `{"id": 0, "status": "lost", "items": [{"sku": "abc", "qty": 2.5}], "note": "x"}`
+	err = checkDocument(contract.Input, []byte(invalid), "input", contract.InputName)
+	if err == nil {
+		t.Fatal("Expected an invalid order to fail")
+	}
+	for _, want := range []string{
+		"input does not match order.schema.json",
+		"$.id: 0 is less than the minimum 1",
+		`$.status: "lost" is not one of the allowed values`,
+		`$.items[0].sku: "abc" does not match`,
+		"$.items[0].qty: expected integer or null, got number",
+		"$: unexpected property 'note'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+
+	if err := checkDocument(contract.Output, []byte(`{"total": 3}`), "output", contract.OutputName); err != nil {
+		t.Errorf("Expected any JSON to satisfy berga:output: json, got %v", err)
+	}
+	for _, data := range []string{"", "{} {}", "not json"} {
+		if err := checkDocument(contract.Output, []byte(data), "output", "json"); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestLoadIOContractInvalid(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "x.sh")
+	for _, schema := range []string{`{"type": "text"}`, `{"pattern": "["}`, `{"type": 1}`} {
+		os.WriteFile(filepath.Join(dir, "x"+schemaExt), []byte(schema), 0644)
+		os.WriteFile(script, []byte("#!/bin/sh\n# berga:output: x.schema.json\n"), 0755)
+		if _, err := loadIOContract(script, dir); err == nil {
+			t.Errorf("Expected schema %s to be rejected", schema)
+		}
+	}
+	os.WriteFile(script, []byte("#!/bin/sh\n# berga:input: missing.schema.json\n"), 0755)
+	if _, err := loadIOContract(script, dir); err == nil || !strings.Contains(err.Error(), "berga:input") {
+		t.Errorf("Expected a missing schema to be an error, got %v", err)
+	}
+}
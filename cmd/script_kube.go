@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+)
+
+// currentKubeContext returns the context kubectl would use now. Tests replace
+// it.
+var currentKubeContext = func() (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("kubectl", "config", "current-context")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// scriptKubeContexts returns the context patterns a script declares with
+// "berga:kube_context:", e.g. "prod-*, prod-eu"
+func scriptKubeContexts(scriptPath string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(readMetadata(scriptPath)["kube_context"], ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid berga:kube_context pattern '%s'", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// kubeContextMismatch reports whether the current kubectl context is not one
+// the script declares with "berga:kube_context:", and if so, says so along
+// with the current context. Scripts without the declaration always fit.
+func kubeContextMismatch(scriptName, scriptPath string) (string, string, error) {
+	patterns, err := scriptKubeContexts(scriptPath)
+	if err != nil {
+		return "", "", fmt.Errorf("script '%s': %w", scriptName, err)
+	}
+	if len(patterns) == 0 {
+		return "", "", nil
+	}
+	expected := strings.Join(patterns, ", ")
+
+	current, err := currentKubeContext()
+	if err != nil {
+		return "", "", fmt.Errorf("script '%s' expects kube context %s, but the current context could not be read: %w", scriptName, expected, err)
+	}
+	if matchesAny(patterns, current) {
+		return "", current, nil
+	}
+	return fmt.Sprintf("script '%s' expects kube context %s, but the current context is '%s'", scriptName, expected, current), current, nil
+}
+
+// requireKubeContext fails when the current kubectl context does not fit the
+// script, for runs nobody can be asked about
+func requireKubeContext(scriptName, scriptPath string) error {
+	mismatch, _, err := kubeContextMismatch(scriptName, scriptPath)
+	if err != nil {
+		return err
+	}
+	if mismatch != "" {
+		return fmt.Errorf("%s; switch with 'kubectl config use-context'", mismatch)
+	}
+	return nil
+}
+
+// checkKubeContext makes sure the current kubectl context fits the script
+// before it runs, so that it does not run against the wrong cluster. On a
+// mismatch it asks on a terminal and fails otherwise; --assume-yes does not
+// answer for it.
+func checkKubeContext(scriptName, scriptPath string) error {
+	if !stdinIsTerminal() {
+		return requireKubeContext(scriptName, scriptPath)
+	}
+	mismatch, current, err := kubeContextMismatch(scriptName, scriptPath)
+	if err != nil || mismatch == "" {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("%s %s", ui.Icon("⚠️ ", "!"), mismatch)))
+	fmt.Fprintf(os.Stderr, "%s %s: ", i18n.T("prompt.run_against_context", current), i18n.T("prompt.hint_default_no"))
+	if response, _ := readLine(); !i18n.IsYes(response) {
+		return fmt.Errorf("cancelled; script '%s' was not run", scriptName)
+	}
+	return nil
+}
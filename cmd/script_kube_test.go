@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// setKubeContext pretends kubectl's current context is context, or that it
+// can't be read when context is ""
+func setKubeContext(t *testing.T, context string) {
+	t.Helper()
+	orig := currentKubeContext
+	currentKubeContext = func() (string, error) {
+		if context == "" {
+			return "", errors.New("kubectl not found")
+		}
+		return context, nil
+	}
+	t.Cleanup(func() { currentKubeContext = orig })
+}
+
+func TestCheckKubeContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\n# berga:kube_context: prod-*, staging\n")
+	plain := writeTestScript(t, "plain.sh", "#!/bin/sh\n")
+
+	setKubeContext(t, "prod-eu")
+	if err := checkKubeContext("deploy.sh", path); err != nil {
+		t.Errorf("Expected prod-eu to match prod-*, got %v", err)
+	}
+
+	setKubeContext(t, "dev")
+	err := checkKubeContext("deploy.sh", path)
+	if err == nil || !strings.Contains(err.Error(), "expects kube context prod-*, staging, but the current context is 'dev'") {
+		t.Errorf("Expected a mismatch error, got %v", err)
+	}
+	if err := checkKubeContext("plain.sh", plain); err != nil {
+		t.Errorf("Expected scripts without kube_context to run anywhere, got %v", err)
+	}
+
+	setKubeContext(t, "")
+	if err := checkKubeContext("deploy.sh", path); err == nil || !strings.Contains(err.Error(), "could not be read") {
+		t.Errorf("Expected an error when the context can't be read, got %v", err)
+	}
+}
+
+func TestCheckKubeContextPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, true)
+	setKubeContext(t, "dev")
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\n# berga:kube_context: prod-*\n")
+	origReader := stdinReader
+	assumeYes = true
+	defer func() { stdinReader, assumeYes = origReader, false }()
+
+	stdinReader = bufio.NewReader(strings.NewReader("\n"))
+	if err := checkKubeContext("deploy.sh", path); err == nil {
+		t.Error("Expected the prompt to default to no, even with --assume-yes")
+	}
+	stdinReader = bufio.NewReader(strings.NewReader("y\n"))
+	if err := checkKubeContext("deploy.sh", path); err != nil {
+		t.Errorf("Expected yes to run against the wrong context, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	scriptMaxMem string
+	scriptNice   int
+)
+
+// resourceLimits caps what a script run may use. Zero values mean no limit.
+type resourceLimits struct {
+	MaxMem uint64 // bytes
+	Nice   int
+}
+
+func (l resourceLimits) none() bool {
+	return l.MaxMem == 0 && l.Nice == 0
+}
+
+// scriptResourceLimits returns the limits from --max-mem and --nice, or
+// scripts.max_mem and scripts.nice
+func scriptResourceLimits() (resourceLimits, error) {
+	var limits resourceLimits
+	if raw := viper.GetString("scripts.max_mem"); raw != "" {
+		size, err := config.ParseSize(raw)
+		if err != nil {
+			return limits, fmt.Errorf("max-mem %v", err)
+		}
+		limits.MaxMem = size
+	}
+	limits.Nice = viper.GetInt("scripts.nice")
+	if limits.Nice < -20 || limits.Nice > 19 {
+		return limits, fmt.Errorf("nice must be between -20 and 19, got %d", limits.Nice)
+	}
+	return limits, nil
+}
+
+// checkLimitFlags rejects limits that cannot be applied before the run starts
+func checkLimitFlags() error {
+	if (scriptMaxMem != "" || scriptNice != 0) && len(scriptHosts) > 0 {
+		return fmt.Errorf("--max-mem and --nice cannot be combined with --hosts")
+	}
+	_, err := scriptResourceLimits()
+	return err
+}
+
+// runLimited runs cmd with limits applied to it and to the processes it
+// starts. Where the limits are applied to the process once it has started,
+// a failure to apply them stops it.
+func runLimited(cmd *exec.Cmd, scriptPath string, limits resourceLimits) error {
+	if limits.none() {
+		return cmd.Run()
+	}
+	if containerImage(scriptPath) != "" {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: resource limits do not apply to container runs; use the container runtime's own limits"))
+		return cmd.Run()
+	}
+
+	limitCommand(cmd, limits)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	release, err := limitProcess(cmd.Process, limits)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("failed to apply resource limits: %w", err)
+	}
+	defer release()
+	return cmd.Wait()
+}
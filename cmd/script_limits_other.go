@@ -0,0 +1,35 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// limitCommand makes cmd start through sh, which sets the memory limit with
+// ulimit (setrlimit on the address space) and the niceness with nice, and
+// then execs the script. The limits are in place before the script runs its
+// first instruction, and the processes it starts inherit them.
+func limitCommand(cmd *exec.Cmd, limits resourceLimits) {
+	var steps []string
+	if limits.MaxMem > 0 {
+		// ulimit -v counts KiB
+		steps = append(steps, fmt.Sprintf("ulimit -v %d", (limits.MaxMem+1023)/1024))
+	}
+	run := `exec "$0" "$@"`
+	if limits.Nice != 0 {
+		run = fmt.Sprintf(`exec nice -n %d "$0" "$@"`, limits.Nice)
+	}
+	script := strings.Join(append(steps, run), " && ")
+
+	cmd.Args = append([]string{"sh", "-c", script, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+}
+
+// limitProcess has nothing left to do on Unix
+func limitProcess(p *os.Process, limits resourceLimits) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestScriptResourceLimits(t *testing.T) {
+	defer viper.Set("scripts.max_mem", nil)
+	defer viper.Set("scripts.nice", nil)
+
+	viper.Set("scripts.max_mem", "512M")
+	viper.Set("scripts.nice", 10)
+	limits, err := scriptResourceLimits()
+	if err != nil || limits.MaxMem != 512<<20 || limits.Nice != 10 {
+		t.Errorf("Unexpected limits %+v, %v", limits, err)
+	}
+
+	viper.Set("scripts.max_mem", "lots")
+	if _, err := scriptResourceLimits(); err == nil {
+		t.Error("Expected an invalid size to fail")
+	}
+	viper.Set("scripts.max_mem", nil)
+	viper.Set("scripts.nice", 25)
+	if _, err := scriptResourceLimits(); err == nil {
+		t.Error("Expected a niceness out of range to fail")
+	}
+}
+
+func TestExecuteScriptWithLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	out := filepath.Join(t.TempDir(), "out")
+	script := writeTestScript(t, "limits.sh", "#!/bin/sh\n{ ulimit -v; nice; echo \"$@\"; } > "+out+"\n")
+	viper.Set("scripts.max_mem", "256M")
+	viper.Set("scripts.nice", 4)
+	defer viper.Set("scripts.max_mem", nil)
+	defer viper.Set("scripts.nice", nil)
+
+	if err := executeScript(context.Background(), script, []string{"a", "b c"}, nil, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "262144" || lines[2] != "a b c" {
+		t.Errorf("Expected the memory limit and the arguments to reach the script, got %q", lines)
+	}
+	// nice adds to the niceness berga itself runs at
+	if lines[1] == "0" {
+		t.Errorf("Expected the script to run niced, got %q", lines[1])
+	}
+}
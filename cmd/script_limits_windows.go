@@ -0,0 +1,105 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	jobObjectExtendedLimitInformation = 9
+	jobObjectLimitPriorityClass       = 0x0020
+	jobObjectLimitJobMemory           = 0x0200
+	processSetQuota                   = 0x0100
+	processTerminate                  = 0x0001
+
+	idlePriorityClass        = 0x0040
+	belowNormalPriorityClass = 0x4000
+	aboveNormalPriorityClass = 0x8000
+	highPriorityClass        = 0x0080
+)
+
+var (
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+)
+
+// jobObjectExtendedLimits mirrors the Win32 JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+// structure
+type jobObjectExtendedLimits struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+	IoCounters              [6]uint64
+	ProcessMemoryLimit      uintptr
+	JobMemoryLimit          uintptr
+	PeakProcessMemoryUsed   uintptr
+	PeakJobMemoryUsed       uintptr
+}
+
+// priorityClass maps a Unix niceness to the nearest Windows priority class
+func priorityClass(nice int) uint32 {
+	switch {
+	case nice >= 15:
+		return idlePriorityClass
+	case nice > 0:
+		return belowNormalPriorityClass
+	case nice <= -15:
+		return highPriorityClass
+	default:
+		return aboveNormalPriorityClass
+	}
+}
+
+// limitCommand has nothing to prepare on Windows; the limits are set on a
+// Job Object once the script has started
+func limitCommand(cmd *exec.Cmd, limits resourceLimits) {}
+
+// limitProcess puts a started script into a Job Object holding the limits.
+// Processes the script starts afterwards join the job too, so the memory
+// limit covers all of them together.
+func limitProcess(p *os.Process, limits resourceLimits) (func(), error) {
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return nil, err
+	}
+	release := func() { syscall.CloseHandle(syscall.Handle(job)) }
+
+	var info jobObjectExtendedLimits
+	if limits.MaxMem > 0 {
+		info.LimitFlags |= jobObjectLimitJobMemory
+		info.JobMemoryLimit = uintptr(limits.MaxMem)
+	}
+	if limits.Nice != 0 {
+		info.LimitFlags |= jobObjectLimitPriorityClass
+		info.PriorityClass = priorityClass(limits.Nice)
+	}
+	r, _, err := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	if r == 0 {
+		release()
+		return nil, err
+	}
+
+	process, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(p.Pid))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	defer syscall.CloseHandle(process)
+	if r, _, err := procAssignProcessToJobObject.Call(job, uintptr(process)); r == 0 {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintStrict       bool
+	lintNoShellcheck bool
+)
+
+// Lint finding severities, most severe first
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// lintFinding is a single problem found in a script
+type lintFinding struct {
+	Script   string
+	Line     int
+	Severity string
+	Check    string
+	Message  string
+}
+
+// scriptLintCmd checks stored scripts for common problems
+var scriptLintCmd = &cobra.Command{
+	Use:   "lint [script-name...]",
+	Short: "Check scripts for common problems",
+	Long: `Run basic static checks on stored scripts: missing shebang, missing
+executable bit, CRLF line endings on Unix, and unquoted shell variables.
+Shell scripts are checked with shellcheck instead when it is installed.
+
+With no arguments every script is checked. The command exits nonzero when an
+error is found, or any warning with --strict, so it can be used in CI.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return lintScripts(args)
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptLintCmd)
+
+	// Flags
+	scriptLintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Exit nonzero on warnings as well as errors")
+	scriptLintCmd.Flags().BoolVar(&lintNoShellcheck, "no-shellcheck", false, "Use the built-in checks even if shellcheck is installed")
+}
+
+// lintTargets returns the scripts to lint, by name and path
+func lintTargets(names []string) (map[string]string, error) {
+	targets := make(map[string]string)
+	if len(names) > 0 {
+		for _, name := range names {
+			path := resolveScriptPath(name)
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("script '%s' not found in %s", name, GetScriptsDir())
+			}
+			targets[name] = path
+		}
+		return targets, nil
+	}
+
+	// Later search paths first, then earlier ones, then the project, so the
+	// script that 'run' would pick wins
+	scriptDirs := GetScriptsDirs()
+	var dirs []string
+	for i := len(scriptDirs) - 1; i >= 0; i-- {
+		dirs = append(dirs, scriptDirs[i])
+	}
+	for _, dir := range append(dirs, GetProjectScriptsDir()) {
+		if dir == "" {
+			continue
+		}
+		files, err := readListing(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() && !isScriptSpecFile(file.Name()) && !isEncryptedScript(file.Name()) {
+				targets[file.Name()] = filepath.Join(dir, file.Name())
+			}
+		}
+	}
+	return targets, nil
+}
+
+// isWindowsScript reports whether a script is run by a Windows interpreter
+func isWindowsScript(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".bat", ".cmd", ".ps1":
+		return true
+	}
+	return false
+}
+
+// shellShebang matches a shebang for a POSIX-style shell
+var shellShebang = regexp.MustCompile(`^#!.*\b(sh|bash|zsh|ksh|dash)\b`)
+
+// isShellScript reports whether a script is a POSIX shell script
+func isShellScript(name string, content []byte) bool {
+	if strings.ToLower(filepath.Ext(name)) == ".sh" {
+		return true
+	}
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	return shellShebang.Match(firstLine)
+}
+
+// lintScriptContent runs the built-in checks against a script's content
+func lintScriptContent(name string, content []byte, executable bool, goos string, shellChecks bool) []lintFinding {
+	var findings []lintFinding
+	add := func(line int, severity, check, message string) {
+		findings = append(findings, lintFinding{name, line, severity, check, message})
+	}
+
+	if isWindowsScript(name) {
+		return nil
+	}
+
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		add(1, severityWarning, "shebang", "missing shebang line; berga falls back to sh")
+	}
+	if goos != "windows" {
+		if !executable {
+			add(0, severityWarning, "executable", "file is not executable (chmod +x)")
+		}
+		if bytes.Contains(content, []byte("\r\n")) {
+			line := bytes.Count(content[:bytes.Index(content, []byte("\r\n"))], []byte("\n")) + 1
+			add(line, severityError, "crlf", "CRLF line endings break scripts on Unix")
+		}
+	}
+
+	if shellChecks && isShellScript(name, content) {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for n := 1; scanner.Scan(); n++ {
+			for _, v := range unquotedVariables(scanner.Text()) {
+				add(n, severityInfo, "quoting", fmt.Sprintf("unquoted variable %s may be split or globbed", v))
+			}
+		}
+	}
+	return findings
+}
+
+// shellVarPattern matches a variable expansion at the start of a string
+var shellVarPattern = regexp.MustCompile(`^\$(\{[A-Za-z_][A-Za-z0-9_]*[^}]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// unquotedVariables returns the variable expansions in a shell line that are
+// outside quotes. Assignments and [[ ]] tests, where splitting does not
+// happen, are skipped.
+func unquotedVariables(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") || strings.Contains(trimmed, "[[") {
+		return nil
+	}
+
+	var found []string
+	inSingle, inDouble := false, false
+	wordStart := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && !inSingle:
+			i++
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '#' && !inSingle && !inDouble && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return found
+		case (c == ' ' || c == '\t' || c == ';' || c == '|' || c == '&') && !inSingle && !inDouble:
+			wordStart = i + 1
+		case c == '$' && !inSingle && !inDouble:
+			// Right-hand side of an assignment is not split
+			if strings.Contains(line[wordStart:i], "=") {
+				continue
+			}
+			if m := shellVarPattern.FindString(line[i:]); m != "" {
+				found = append(found, m)
+				i += len(m) - 1
+			}
+		}
+	}
+	return found
+}
+
+// shellcheckLine matches shellcheck's gcc output format
+var shellcheckLine = regexp.MustCompile(`^.*?:(\d+):\d+: (error|warning|note): (.*?)(?: \[(SC\d+)\])?$`)
+
+// runShellcheck lints a shell script with shellcheck
+func runShellcheck(name, path string) ([]lintFinding, error) {
+	out, err := exec.Command("shellcheck", "-f", "gcc", path).Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to run shellcheck: %w", err)
+		}
+	}
+
+	var findings []lintFinding
+	for _, line := range strings.Split(string(out), "\n") {
+		m := shellcheckLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		severity := m[2]
+		if severity == "note" {
+			severity = severityInfo
+		}
+		check := "shellcheck"
+		if m[4] != "" {
+			check = m[4]
+		}
+		findings = append(findings, lintFinding{name, n, severity, check, m[3]})
+	}
+	return findings, nil
+}
+
+// lintScript runs every applicable check on one script
+func lintScript(name, path string, useShellcheck bool) ([]lintFinding, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	delegate := useShellcheck && isShellScript(name, content)
+	findings := lintScriptContent(name, content, isExecutable(path), runtime.GOOS, !delegate)
+	if delegate {
+		extra, err := runShellcheck(name, path)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, extra...)
+	}
+	return findings, nil
+}
+
+// severityLabel renders a severity for the findings table
+func severityLabel(severity string) string {
+	label := fmt.Sprintf("%-7s", strings.ToUpper(severity))
+	switch severity {
+	case severityError:
+		return ui.Red(label)
+	case severityWarning:
+		return ui.Yellow(label)
+	default:
+		return ui.Dim(label)
+	}
+}
+
+func lintScripts(names []string) error {
+	targets, err := lintTargets(names)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No scripts found.")
+		return nil
+	}
+
+	useShellcheck := false
+	if !lintNoShellcheck {
+		if _, err := exec.LookPath("shellcheck"); err == nil {
+			useShellcheck = true
+		}
+	}
+
+	sorted := make([]string, 0, len(targets))
+	for name := range targets {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	counts := make(map[string]int)
+	for _, name := range sorted {
+		findings, err := lintScript(name, targets[name], useShellcheck)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, f := range findings {
+			counts[f.Severity]++
+			location := f.Script
+			if f.Line > 0 {
+				location = fmt.Sprintf("%s:%d", f.Script, f.Line)
+			}
+			fmt.Printf("  %s %s %s %s\n", severityLabel(f.Severity), ui.Bold(location), ui.Dim(f.Check+":"), f.Message)
+		}
+	}
+
+	fmt.Printf("\n%d script(s) checked: %d error(s), %d warning(s), %d info\n",
+		len(sorted), counts[severityError], counts[severityWarning], counts[severityInfo])
+
+	if counts[severityError] > 0 || (lintStrict && counts[severityWarning] > 0) {
+		return fmt.Errorf("lint found problems")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnquotedVariables(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`rm -rf $TARGET`, []string{"$TARGET"}},
+		{`rm -rf "$TARGET"`, nil},
+		{`echo '$literal'`, nil},
+		{`cp ${SRC} "$DEST"`, []string{"${SRC}"}},
+		{`name=$1`, nil},
+		{`if [[ -z $x ]]; then`, nil},
+		{`# echo $commented`, nil},
+		{`echo ok # $trailing comment`, nil},
+	}
+
+	for _, tt := range tests {
+		if got := unquotedVariables(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unquotedVariables(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLintScriptContent(t *testing.T) {
+	content := []byte("echo hi\r\necho $HOME\r\n")
+	findings := lintScriptContent("deploy.sh", content, false, "linux", true)
+
+	checks := make(map[string]string)
+	for _, f := range findings {
+		checks[f.Check] = f.Severity
+	}
+	want := map[string]string{
+		"shebang":    severityWarning,
+		"executable": severityWarning,
+		"crlf":       severityError,
+		"quoting":    severityInfo,
+	}
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("Got checks %v, want %v", checks, want)
+	}
+
+	if findings := lintScriptContent("setup.ps1", content, false, "linux", true); len(findings) != 0 {
+		t.Errorf("Expected Windows scripts to be skipped, got %v", findings)
+	}
+	if findings := lintScriptContent("ok.sh", []byte("#!/bin/sh\necho \"$1\"\n"), true, "linux", true); len(findings) != 0 {
+		t.Errorf("Expected a clean script to pass, got %v", findings)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// metadataLine matches a "berga:<key>: value" header line, e.g.
+// "# berga:container: python:3.12"
+var metadataLine = regexp.MustCompile(`berga:([a-z][a-z0-9_-]*):(.*)$`)
+
+// templateCommentEnd matches the end of a template comment: "*/}}" or " */ -}}"
+var templateCommentEnd = regexp.MustCompile(`\s*\*/\s*-?}}$`)
+
+// metadataEntry is a single berga:<key>: declaration
+type metadataEntry struct {
+	Key   string
+	Value string
+}
+
+// parseMetadata returns the berga:<key>: declarations in the first lines of a
+// script or template, in order
+func parseMetadata(r io.Reader) []metadataEntry {
+	var entries []metadataEntry
+	scanner := bufio.NewScanner(r)
+	for i := 0; i < tagHeaderLines && scanner.Scan(); i++ {
+		m := metadataLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		// Allow the marker inside template comments: {{/* berga:tags: x */}}
+		value := templateCommentEnd.ReplaceAllString(strings.TrimSpace(m[2]), "")
+		entries = append(entries, metadataEntry{m[1], strings.TrimSpace(value)})
+	}
+	return entries
+}
+
+// readMetadata returns the berga:<key>: declarations of a file. Keys are
+// lowercase; the first declaration of a key wins.
+func readMetadata(path string) map[string]string {
+	meta := make(map[string]string)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return meta
+	}
+	defer f.Close()
+
+	for _, entry := range parseMetadata(f) {
+		if _, seen := meta[entry.Key]; !seen {
+			meta[entry.Key] = entry.Value
+		}
+	}
+	return meta
+}
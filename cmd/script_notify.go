@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// notifyWebhookTimeout bounds the completion webhook request
+const notifyWebhookTimeout = 10 * time.Second
+
+var scriptNotify bool
+
+// notifyDesktop shows a desktop notification. Tests replace it.
+var notifyDesktop = desktopNotify
+
+// scriptOutcome describes how a script run ended, for notifications
+func scriptOutcome(err error) string {
+	var exitErr *ExitError
+	switch {
+	case err == nil:
+		return "succeeded"
+	case errors.As(err, &exitErr):
+		return fmt.Sprintf("failed with exit status %d", exitErr.Code)
+	}
+	return "failed: " + err.Error()
+}
+
+// notifyMessage is the text sent when a script finishes
+func notifyMessage(scriptName string, err error, elapsed time.Duration) string {
+	return fmt.Sprintf("%s %s after %s", scriptName, scriptOutcome(err), elapsed.Round(time.Second))
+}
+
+// notifyCompletion reports a finished script with --notify or scripts.notify,
+// as a desktop notification and to scripts.notify_webhook if set. Runs shorter
+// than scripts.notify_after are not reported. Notification problems are only
+// warnings; they never change the script's result.
+func notifyCompletion(scriptName string, err error, elapsed time.Duration) {
+	if !viper.GetBool("scripts.notify") || elapsed < viper.GetDuration("scripts.notify_after") {
+		return
+	}
+	title := "berga: " + scriptName
+	message := notifyMessage(scriptName, err, elapsed)
+
+	if nerr := notifyDesktop(title, message); nerr != nil && viper.GetBool("verbose") {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: desktop notification failed: %v", nerr)))
+	}
+	if webhook := viper.GetString("scripts.notify_webhook"); webhook != "" {
+		if nerr := postWebhook(webhook, message); nerr != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: notification webhook failed: %v", nerr)))
+		}
+	}
+}
+
+// desktopNotify shows a notification with osascript on macOS, a PowerShell
+// toast on Windows, and notify-send elsewhere
+func desktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "BERGA_NOTIFY_TITLE="+title, "BERGA_NOTIFY_MESSAGE="+message)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return fmt.Errorf("notify-send is not installed")
+		}
+		cmd = exec.Command("notify-send", "--app-name=berga", title, message)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// windowsToastScript raises a toast through the WinRT API. The text is passed
+// in the environment so it needs no escaping.
+const windowsToastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:BERGA_NOTIFY_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:BERGA_NOTIFY_MESSAGE)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('berga').Show($toast)
+`
+
+// webhookPayload builds the JSON body for a chat webhook. Discord reads
+// "content"; Slack and compatible services read "text".
+func webhookPayload(webhook, message string) ([]byte, error) {
+	field := "text"
+	if u, err := url.Parse(webhook); err == nil {
+		host := strings.ToLower(u.Hostname())
+		if host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			field = "content"
+		}
+	}
+	return json.Marshal(map[string]string{field: message})
+}
+
+// postWebhook sends message to a Slack or Discord incoming webhook
+func postWebhook(webhook, message string) error {
+	body, err := webhookPayload(webhook, message)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: notifyWebhookTimeout}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL is a credential; keep it out of the message
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestNotifyMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "deploy.sh succeeded after 1m5s"},
+		{&ExitError{Code: 3}, "deploy.sh failed with exit status 3 after 1m5s"},
+		{errors.New("script execution timed out after 1m0s"), "deploy.sh failed: script execution timed out after 1m0s after 1m5s"},
+	}
+	for _, tt := range tests {
+		if got := notifyMessage("deploy.sh", tt.err, 65*time.Second+300*time.Millisecond); got != tt.want {
+			t.Errorf("notifyMessage(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	tests := map[string]string{
+		"https://hooks.slack.com/services/T/B/X":  `{"text":"done"}`,
+		"https://discord.com/api/webhooks/1/abc":  `{"content":"done"}`,
+		"https://ptb.discord.com/api/webhooks/1/": `{"content":"done"}`,
+	}
+	for webhook, want := range tests {
+		body, err := webhookPayload(webhook, "done")
+		if err != nil || string(body) != want {
+			t.Errorf("webhookPayload(%s) = %s, %v; want %s", webhook, body, err, want)
+		}
+	}
+}
+
+func TestNotifyCompletion(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var payload map[string]string
+		json.Unmarshal(data, &payload)
+		received = append(received, payload["text"])
+	}))
+	defer server.Close()
+
+	var desktop []string
+	orig := notifyDesktop
+	notifyDesktop = func(title, message string) error {
+		desktop = append(desktop, message)
+		return nil
+	}
+	defer func() { notifyDesktop = orig }()
+
+	viper.Set("scripts.notify_webhook", server.URL)
+	viper.Set("scripts.notify_after", "10s")
+	defer func() {
+		viper.Set("scripts.notify", nil)
+		viper.Set("scripts.notify_webhook", nil)
+		viper.Set("scripts.notify_after", nil)
+	}()
+
+	viper.Set("scripts.notify", false)
+	notifyCompletion("build.sh", nil, time.Minute)
+	viper.Set("scripts.notify", true)
+	notifyCompletion("build.sh", nil, 5*time.Second)
+	if len(desktop) != 0 || len(received) != 0 {
+		t.Fatalf("Expected no notifications when disabled or short, got %v %v", desktop, received)
+	}
+
+	notifyCompletion("build.sh", &ExitError{Code: 1}, time.Minute)
+	want := "build.sh failed with exit status 1 after 1m0s"
+	if len(desktop) != 1 || desktop[0] != want || len(received) != 1 || received[0] != want {
+		t.Errorf("Expected one notification %q, got desktop %v, webhook %v", want, desktop, received)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/internal/ui"
+)
+
+// lineWriter decorates each complete line written to it before passing it
+// on. Writers sharing a mutex never interleave partial lines.
+type lineWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	label  string
+	stamps bool
+	buf    bytes.Buffer
+	now    func() time.Time
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf.Next(i + 1))
+		if err := w.emit(strings.TrimSuffix(line, "\n")); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes out a trailing line that did not end in a newline
+func (w *lineWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := w.buf.String()
+	w.buf.Reset()
+	return w.emit(line)
+}
+
+func (w *lineWriter) emit(line string) error {
+	var parts []string
+	if w.stamps {
+		parts = append(parts, ui.Dim(w.now().Format(time.RFC3339)))
+	}
+	if w.label != "" {
+		label := w.label
+		if label == "ERR" {
+			label = ui.Red(label)
+		}
+		parts = append(parts, label)
+	}
+	parts = append(parts, line)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := io.WriteString(w.out, strings.Join(parts, " ")+"\n")
+	return err
+}
+
+// scriptOutputWriters returns the writers for a script's stdout and stderr,
+// decorated according to --timestamps and --prefix, and a function that
+// flushes any trailing partial lines once the script has exited
+func scriptOutputWriters() (io.Writer, io.Writer, func()) {
+	if !scriptTimestamps && !scriptPrefix {
+		return os.Stdout, os.Stderr, func() {}
+	}
+
+	mu := &sync.Mutex{}
+	stdout := &lineWriter{mu: mu, out: os.Stdout, stamps: scriptTimestamps, now: time.Now}
+	stderr := &lineWriter{mu: mu, out: os.Stderr, stamps: scriptTimestamps, now: time.Now}
+	if scriptPrefix {
+		stdout.label = "OUT"
+		stderr.label = "ERR"
+	}
+	return stdout, stderr, func() {
+		stdout.Flush()
+		stderr.Flush()
+	}
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLineWriter(t *testing.T) {
+	var out bytes.Buffer
+	stamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	w := &lineWriter{mu: &sync.Mutex{}, out: &out, label: "OUT", stamps: true, now: func() time.Time { return stamp }}
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\npartial"))
+	if got, want := out.String(), "2024-05-01T12:00:00Z OUT first\n2024-05-01T12:00:00Z OUT second\n"; got != want {
+		t.Errorf("Before flush got %q, want %q", got, want)
+	}
+
+	w.Flush()
+	if got, want := out.String(), "2024-05-01T12:00:00Z OUT first\n2024-05-01T12:00:00Z OUT second\n2024-05-01T12:00:00Z OUT partial\n"; got != want {
+		t.Errorf("After flush got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// sensitiveEnvWords mark variables whose values the run plan masks, on top
+// of any variable holding one of the configured secrets
+var sensitiveEnvWords = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "PASSPHRASE", "CREDENTIAL", "APIKEY", "API_KEY", "PRIVATE_KEY", "ACCESS_KEY"}
+
+// runPlan is everything a script run resolved before starting, as verbose
+// mode prints it
+type runPlan struct {
+	Script      string
+	Command     []string
+	Interpreter string
+	Dir         string
+	Image       string
+	Hosts       []string
+	Input       string
+	EnvProfile  string
+	CleanEnv    bool
+	Timeout     time.Duration
+	Retry       retryPolicy
+	Env         []string // the variables the script gets, KEY=VALUE
+	Changed     map[string]bool
+}
+
+// buildRunPlan resolves how scriptPath will run with args. It is the same
+// command scriptCommand builds for the real run.
+func buildRunPlan(scriptName, scriptPath string, args []string, image string, hosts []string, timeout time.Duration) runPlan {
+	plan := runPlan{
+		Script:     storedScriptPath(scriptPath),
+		Image:      image,
+		Hosts:      hosts,
+		Input:      scriptInputFile,
+		EnvProfile: envProfileName(scriptEnvProfile),
+		CleanEnv:   scriptCleanEnv,
+		Timeout:    timeout,
+		Retry:      scriptRetryPolicy(scriptName, scriptRetriesSet, scriptRetryDelaySet),
+		Changed:    make(map[string]bool),
+	}
+	if len(hosts) > 0 {
+		return plan
+	}
+	plan.Interpreter = scriptInterpreter(scriptPath)
+
+	cmd := scriptCommand(context.Background(), scriptPath, args)
+	plan.Command = cmd.Args
+	plan.Dir = cmd.Dir
+	if plan.Dir == "" {
+		plan.Dir, _ = os.Getwd()
+	}
+
+	if image != "" {
+		env := containerEnv()
+		for k, v := range env {
+			plan.Env = append(plan.Env, k+"="+v)
+			plan.Changed[k] = true
+		}
+	} else {
+		environ := cmd.Env
+		if environ == nil {
+			environ = os.Environ()
+		}
+		// Later entries win, as they do for the process
+		merged := make(map[string]string)
+		for _, kv := range environ {
+			k, v, _ := strings.Cut(kv, "=")
+			merged[k] = v
+		}
+		for k, v := range merged {
+			plan.Env = append(plan.Env, k+"="+v)
+			if own, ok := os.LookupEnv(k); !ok || own != v {
+				plan.Changed[k] = true
+			}
+		}
+	}
+	sort.Strings(plan.Env)
+	return plan
+}
+
+// configuredSecrets returns the values of secrets.*, for masking
+func configuredSecrets() []string {
+	var values []string
+	for _, value := range viper.GetStringMap("secrets") {
+		// Very short values would mask unrelated text
+		if s := cast.ToString(value); len(s) >= 4 {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// maskSecrets replaces every configured secret in s
+func maskSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, maskedSecret)
+	}
+	return s
+}
+
+// sensitiveEnvName reports whether a variable's name says it holds a secret
+func sensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range sensitiveEnvWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// print writes the plan, with secrets masked in the command line and the
+// environment
+func (p runPlan) print(w io.Writer) {
+	secrets := configuredSecrets()
+	row := func(label, value string) {
+		fmt.Fprintf(w, "%-12s %s\n", label+":", value)
+	}
+
+	fmt.Fprintln(w, ui.Bold("Execution plan"))
+	row("Script", p.Script)
+	if len(p.Hosts) > 0 {
+		row("Hosts", strings.Join(p.Hosts, ", "))
+	}
+	if p.Image != "" {
+		row("Container", p.Image)
+	}
+	if p.Interpreter != "" {
+		row("Interpreter", p.Interpreter)
+	}
+	if len(p.Command) > 0 {
+		words := make([]string, len(p.Command))
+		for i, arg := range p.Command {
+			words[i] = maskSecrets(shellQuote(arg), secrets)
+		}
+		row("Command", strings.Join(words, " "))
+	}
+	if p.Dir != "" {
+		row("Directory", p.Dir)
+	}
+	if p.Input != "" {
+		row("Input", p.Input)
+	}
+	profile := p.EnvProfile
+	if profile == "" {
+		profile = "none"
+	}
+	if p.CleanEnv {
+		profile += " (clean environment)"
+	}
+	row("Env profile", profile)
+	row("Timeout", formatTimeout(p.Timeout))
+	retries := "none"
+	switch {
+	case p.Retry.Retries > 0 && p.Retry.Delay > 0:
+		retries = fmt.Sprintf("%d, waiting %s before the first and doubling", p.Retry.Retries, p.Retry.Delay)
+	case p.Retry.Retries > 0:
+		retries = fmt.Sprintf("%d, without waiting", p.Retry.Retries)
+	}
+	row("Retries", retries)
+
+	if len(p.Hosts) > 0 {
+		return
+	}
+	changed := 0
+	for _, kv := range p.Env {
+		if k, _, _ := strings.Cut(kv, "="); p.Changed[k] {
+			changed++
+		}
+	}
+	fmt.Fprintf(w, "Environment: %d variables, %d set for this run (*)\n", len(p.Env), changed)
+	for _, kv := range p.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		if sensitiveEnvName(k) && v != "" {
+			v = maskedSecret
+		}
+		line := k + "=" + maskSecrets(v, secrets)
+		if p.Changed[k] {
+			fmt.Fprintf(w, "  * %s\n", ui.Cyan(line))
+		} else {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRunPlan(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEPLOY_TOKEN", "tok-123")
+	viper.Set("secrets.api", "hunter22")
+	defer viper.Set("secrets", nil)
+	scriptEnvVars = []string{"API=Bearer hunter22", "STAGE=prod"}
+	scriptRetries, scriptRetryDelay, scriptRetriesSet, scriptRetryDelaySet = 2, time.Second, true, true
+	defer func() {
+		scriptEnvVars = nil
+		scriptRetries, scriptRetryDelay, scriptRetriesSet, scriptRetryDelaySet = 0, 0, false, false
+	}()
+
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\necho hi\n")
+	plan := buildRunPlan("deploy.sh", path, []string{"--key", "hunter22"}, "", nil, time.Minute)
+	if !plan.Changed["STAGE"] || plan.Changed["DEPLOY_TOKEN"] {
+		t.Errorf("Expected only the run's own variables marked, got %v", plan.Changed)
+	}
+
+	var out bytes.Buffer
+	plan.print(&out)
+	got := out.String()
+	for _, want := range []string{
+		"Command:     " + path + " --key ********",
+		"Timeout:     1m0s",
+		"Retries:     2, waiting 1s before the first and doubling",
+		"  * API=Bearer ********",
+		"  * STAGE=prod",
+		"    DEPLOY_TOKEN=********",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the plan:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "hunter22") || strings.Contains(got, "tok-123") {
+		t.Errorf("Expected secrets to be masked:\n%s", got)
+	}
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy actions. Deny refuses to run a script; confirm requires typing its
+// name, like danger: high.
+const (
+	policyDeny    = "deny"
+	policyConfirm = "confirm"
+)
+
+// defaultWorkingDays are the days outside_hours counts as working days when
+// working_days is not set
+var defaultWorkingDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+// Policy is the set of rules in policy.yaml
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule forbids or requires confirmation for the scripts it matches
+// while all of its conditions hold
+type PolicyRule struct {
+	Name    string           `yaml:"name,omitempty"`
+	Scripts []string         `yaml:"scripts,omitempty"` // glob patterns; none matches every script
+	Action  string           `yaml:"action"`
+	Message string           `yaml:"message,omitempty"`
+	When    PolicyConditions `yaml:"when,omitempty"`
+}
+
+// PolicyConditions are the circumstances a rule applies in. Patterns are
+// globs, and a list matches when any of its patterns does.
+type PolicyConditions struct {
+	Env          []string `yaml:"env,omitempty"`           // env profile of the run
+	Profile      []string `yaml:"profile,omitempty"`       // berga profile
+	Host         []string `yaml:"host,omitempty"`          // this machine's hostname
+	OutsideHours string   `yaml:"outside_hours,omitempty"` // working hours, e.g. 09:00-18:00
+	WorkingDays  []string `yaml:"working_days,omitempty"`  // default mon to fri
+}
+
+// policyContext is what rules are evaluated against
+type policyContext struct {
+	Env     string
+	Profile string
+	Host    string
+	Now     time.Time
+}
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Check the rules in policy.yaml",
+	Long: `Rules in ~/.berga/policy.yaml forbid scripts, or require typing their name
+before they run, while conditions hold: the env profile, the berga profile,
+this machine's hostname, or the time being outside working hours.`,
+}
+
+var policyCheckCmd = &cobra.Command{
+	Use:   "check [script-name]",
+	Short: "Validate policy.yaml and show what it says about a script",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		policy, err := loadPolicy()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			fmt.Printf("%s has %d rules.\n", GetPolicyFile(), len(policy.Rules))
+			return nil
+		}
+
+		ctx := currentPolicyContext()
+		rule := policy.evaluate(args[0], ctx)
+		switch {
+		case rule == nil:
+			fmt.Printf("No rule applies to '%s' right now.\n", args[0])
+		case rule.Action == policyDeny:
+			fmt.Println(ui.Red(rule.denial(args[0], ctx)))
+		default:
+			fmt.Println(ui.Yellow(fmt.Sprintf("'%s' %s", args[0], rule.requirement(ctx))))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyCheckCmd)
+	policyCheckCmd.ValidArgsFunction = completeScriptNames
+	policyCheckCmd.Flags().StringVar(&scriptEnvProfile, "env-profile", "", "Check as if running with this env profile")
+}
+
+// loadPolicy reads policy.yaml. A missing file has no rules; an invalid one
+// is an error, so that a typo never lifts a restriction.
+func loadPolicy() (*Policy, error) {
+	policy := &Policy{}
+	data, err := os.ReadFile(GetPolicyFile())
+	if os.IsNotExist(err) {
+		return policy, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy rule %s: %w", rule.Name, err)
+		}
+	}
+	return policy, nil
+}
+
+func (r *PolicyRule) validate() error {
+	r.Action = strings.ToLower(strings.TrimSpace(r.Action))
+	if r.Action != policyDeny && r.Action != policyConfirm {
+		return fmt.Errorf("action must be deny or confirm, got '%s'", r.Action)
+	}
+	for _, patterns := range [][]string{r.Scripts, r.When.Env, r.When.Profile, r.When.Host} {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern '%s'", pattern)
+			}
+		}
+	}
+	if r.When.OutsideHours != "" {
+		if _, _, err := parseWorkingHours(r.When.OutsideHours); err != nil {
+			return err
+		}
+	}
+	for _, day := range r.When.WorkingDays {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("unknown day '%s' in working_days", day)
+		}
+	}
+	return nil
+}
+
+// parseWorkingHours parses "09:00-18:00" into minutes after midnight. The end
+// may be before the start for hours spanning midnight.
+func parseWorkingHours(value string) (int, int, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if ok {
+		start, err1 := time.Parse("15:04", strings.TrimSpace(from))
+		end, err2 := time.Parse("15:04", strings.TrimSpace(to))
+		if err1 == nil && err2 == nil {
+			return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("outside_hours must look like 09:00-18:00, got '%s'", value)
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if len(value) >= 3 && strings.HasPrefix(name, value) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// currentPolicyContext describes the run about to start
+func currentPolicyContext() policyContext {
+	profile, _ := activeProfile()
+	host, _ := os.Hostname()
+	return policyContext{
+		Env:     envProfileName(scriptEnvProfile),
+		Profile: profile,
+		Host:    host,
+		Now:     time.Now(),
+	}
+}
+
+// matchesAny reports whether value matches one of the glob patterns,
+// ignoring case
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns the rule deciding a script's run: the first matching deny
+// rule, or else the first matching confirm rule, or nil when none match
+func (p *Policy) evaluate(scriptName string, ctx policyContext) *PolicyRule {
+	var confirm *PolicyRule
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.applies(scriptName, ctx) {
+			continue
+		}
+		if rule.Action == policyDeny {
+			return rule
+		}
+		if confirm == nil {
+			confirm = rule
+		}
+	}
+	return confirm
+}
+
+// applies reports whether the rule covers the script and all of its
+// conditions hold
+func (r *PolicyRule) applies(scriptName string, ctx policyContext) bool {
+	if len(r.Scripts) > 0 && !matchesAny(r.Scripts, scriptName) && !matchesAny(r.Scripts, filepath.Base(scriptName)) {
+		return false
+	}
+	if len(r.When.Env) > 0 && (ctx.Env == "" || !matchesAny(r.When.Env, ctx.Env)) {
+		return false
+	}
+	if len(r.When.Profile) > 0 && !matchesAny(r.When.Profile, ctx.Profile) {
+		return false
+	}
+	if len(r.When.Host) > 0 && !matchesAny(r.When.Host, ctx.Host) {
+		return false
+	}
+	if (r.When.OutsideHours != "" || len(r.When.WorkingDays) > 0) && !r.outsideWorkingHours(ctx.Now) {
+		return false
+	}
+	return true
+}
+
+// outsideWorkingHours reports whether now falls on a day off or outside the
+// rule's hours
+func (r *PolicyRule) outsideWorkingHours(now time.Time) bool {
+	days := r.When.WorkingDays
+	if len(days) == 0 {
+		days = defaultWorkingDays
+	}
+	working := false
+	for _, value := range days {
+		if day, _ := parseWeekday(value); day == now.Weekday() {
+			working = true
+		}
+	}
+	if !working || r.When.OutsideHours == "" {
+		return !working
+	}
+
+	start, end, _ := parseWorkingHours(r.When.OutsideHours)
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute < start || minute >= end
+	}
+	return minute < start && minute >= end
+}
+
+// reasons describes the conditions that made the rule apply
+func (r *PolicyRule) reasons(ctx policyContext) string {
+	var reasons []string
+	if len(r.When.Env) > 0 {
+		reasons = append(reasons, "env profile is "+ctx.Env)
+	}
+	if len(r.When.Profile) > 0 {
+		reasons = append(reasons, "profile is "+ctx.Profile)
+	}
+	if len(r.When.Host) > 0 {
+		reasons = append(reasons, "host is "+ctx.Host)
+	}
+	if r.When.OutsideHours != "" {
+		reasons = append(reasons, "outside working hours "+r.When.OutsideHours)
+	} else if len(r.When.WorkingDays) > 0 {
+		reasons = append(reasons, "outside working days")
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(reasons, ", ") + ")"
+}
+
+func (r *PolicyRule) withMessage(text string) string {
+	if r.Message != "" {
+		text += ": " + r.Message
+	}
+	return text
+}
+
+// denial is the error shown when a deny rule stops a script
+func (r *PolicyRule) denial(scriptName string, ctx policyContext) string {
+	return r.withMessage(fmt.Sprintf("policy rule %s forbids running '%s'%s", r.Name, scriptName, r.reasons(ctx)))
+}
+
+// requirement says why a confirm rule asks before a script runs
+func (r *PolicyRule) requirement(ctx policyContext) string {
+	return r.withMessage(fmt.Sprintf("requires confirmation under policy rule %s%s", r.Name, r.reasons(ctx)))
+}
+
+// checkScriptPolicy evaluates policy.yaml for a script about to run. A deny
+// rule, or a policy that can't be read, is returned as an error; a confirm
+// rule is returned for the caller to ask.
+func checkScriptPolicy(scriptName string) (*PolicyRule, policyContext, error) {
+	ctx := currentPolicyContext()
+	policy, err := loadPolicy()
+	if err != nil {
+		return nil, ctx, err
+	}
+	rule := policy.evaluate(scriptName, ctx)
+	if rule != nil && rule.Action == policyDeny {
+		return nil, ctx, errors.New(rule.denial(scriptName, ctx))
+	}
+	return rule, ctx, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestPolicy(t *testing.T, content string) {
+	t.Helper()
+	os.MkdirAll(GetConfigDir(), 0755)
+	if err := os.WriteFile(GetPolicyFile(), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestPolicy(t, `rules:
+  - name: prod-deploys
+    scripts: ["deploy*"]
+    action: confirm
+    when:
+      env: [prod]
+  - name: freeze
+    scripts: ["deploy*", "migrate.sh"]
+    action: deny
+    message: changes wait for working hours
+    when:
+      env: [prod]
+      outside_hours: "09:00-18:00"
+  - action: deny
+    when:
+      host: [build-*]
+`)
+	policy, err := loadPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-14 is a Wednesday
+	day := time.Date(2026, 10, 14, 11, 0, 0, 0, time.Local)
+	night := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	saturday := time.Date(2026, 10, 17, 11, 0, 0, 0, time.Local)
+	tests := []struct {
+		script string
+		ctx    policyContext
+		want   string
+	}{
+		{"deploy.sh", policyContext{Env: "staging", Now: night}, ""},
+		{"deploy.sh", policyContext{Env: "prod", Now: day}, "prod-deploys"},
+		{"deploy.sh", policyContext{Env: "prod", Now: night}, "freeze"},
+		{"ops/migrate.sh", policyContext{Env: "Prod", Now: saturday}, "freeze"},
+		{"backup.sh", policyContext{Env: "prod", Now: night}, ""},
+		{"backup.sh", policyContext{Host: "build-07", Now: day}, "#3"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if rule := policy.evaluate(tt.script, tt.ctx); rule != nil {
+			got = rule.Name
+		}
+		if got != tt.want {
+			t.Errorf("evaluate(%s, %+v) = %q, want %q", tt.script, tt.ctx, got, tt.want)
+		}
+	}
+
+	rule := policy.evaluate("deploy.sh", policyContext{Env: "prod", Now: night})
+	want := "policy rule freeze forbids running 'deploy.sh' (env profile is prod, outside working hours 09:00-18:00): changes wait for working hours"
+	if got := rule.denial("deploy.sh", policyContext{Env: "prod", Now: night}); got != want {
+		t.Errorf("Unexpected denial %q", got)
+	}
+}
+
+func TestOutsideWorkingHoursOvernight(t *testing.T) {
+	rule := PolicyRule{When: PolicyConditions{OutsideHours: "22:00-06:00", WorkingDays: []string{"monday", "wed"}}}
+	for hour, want := range map[int]bool{23: false, 3: false, 12: true} {
+		if got := rule.outsideWorkingHours(time.Date(2026, 10, 14, hour, 0, 0, 0, time.Local)); got != want {
+			t.Errorf("At %d:00 expected outside=%v", hour, want)
+		}
+	}
+	if !rule.outsideWorkingHours(time.Date(2026, 10, 13, 23, 0, 0, 0, time.Local)) {
+		t.Error("Expected Tuesday to be outside working days")
+	}
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, content := range []string{
+		"rules:\n  - action: block\n",
+		"rules:\n  - action: deny\n    when: {outside_hours: '9-5'}\n",
+		"rules:\n  - action: deny\n    when: {working_days: [someday]}\n",
+		"rules:\n  - action: deny\n    scripts: ['[']\n",
+	} {
+		writeTestPolicy(t, content)
+		if _, err := loadPolicy(); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestConfirmDangerousScriptPolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\n")
+	defer func() { scriptConfirm = "" }()
+
+	writeTestPolicy(t, "rules:\n  - scripts: [deploy.sh]\n    action: confirm\n")
+	if err := confirmDangerousScript("deploy.sh", path); err == nil || !strings.Contains(err.Error(), "requires confirmation under policy rule #1") {
+		t.Errorf("Expected a confirm rule to require --confirm, got %v", err)
+	}
+	scriptConfirm = "deploy.sh"
+	if err := confirmDangerousScript("deploy.sh", path); err != nil {
+		t.Errorf("Expected --confirm to satisfy a confirm rule, got %v", err)
+	}
+
+	writeTestPolicy(t, "rules:\n  - scripts: [deploy.sh]\n    action: deny\n    message: use the pipeline\n")
+	if err := confirmDangerousScript("deploy.sh", path); err == nil || !strings.Contains(err.Error(), "use the pipeline") {
+		t.Errorf("Expected a deny rule to refuse even with --confirm, got %v", err)
+	}
+}
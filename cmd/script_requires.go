@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionProbeTimeout bounds a single "<tool> --version" call
+const versionProbeTimeout = 5 * time.Second
+
+// requirement is a binary a script depends on, optionally with a version
+// constraint, e.g. "jq>=1.6"
+type requirement struct {
+	Name    string
+	Op      string
+	Version string
+}
+
+// requirementPattern splits "jq>=1.6" into the name, operator, and version
+var requirementPattern = regexp.MustCompile(`^([^<>=\s]+)\s*(?:(>=|<=|==|=|>|<)\s*(\d[\w.-]*))?$`)
+
+// versionPattern finds a dotted version number in a tool's --version output
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// parseRequirements parses a "berga:requires:" value such as
+// "kubectl, jq>=1.6" or "[kubectl, jq>=1.6]"
+func parseRequirements(value string) ([]requirement, error) {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+
+	var reqs []requirement
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		m := requirementPattern.FindStringSubmatch(field)
+		if m == nil {
+			return nil, fmt.Errorf("invalid requirement '%s'", field)
+		}
+		op := m[2]
+		if op == "==" {
+			op = "="
+		}
+		reqs = append(reqs, requirement{Name: m[1], Op: op, Version: m[3]})
+	}
+	return reqs, nil
+}
+
+// compareVersions compares dotted version numbers component by component
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionSatisfies reports whether version meets the requirement's constraint
+func versionSatisfies(version string, req requirement) bool {
+	c := compareVersions(version, req.Version)
+	switch req.Op {
+	case ">=":
+		return c >= 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	case "<":
+		return c < 0
+	case "=":
+		return c == 0
+	}
+	return true
+}
+
+// toolVersion asks a binary for its version, trying "--version" and then
+// "version". It returns "" when no version number can be found.
+func toolVersion(path string) string {
+	for _, arg := range []string{"--version", "version"} {
+		ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+		out, _ := exec.CommandContext(ctx, path, arg).CombinedOutput()
+		cancel()
+		if v := versionPattern.FindString(string(out)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// checkRequirements returns a description of every requirement that is not
+// met. Versions that cannot be determined are reported as warnings only.
+func checkRequirements(reqs []requirement) []string {
+	var problems []string
+	for _, req := range reqs {
+		path, err := exec.LookPath(req.Name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: not found in PATH", req.Name))
+			continue
+		}
+		if req.Op == "" {
+			continue
+		}
+
+		version := toolVersion(path)
+		if version == "" {
+			fmt.Fprintf(os.Stderr, "Warning: could not determine the version of %s; assuming %s%s\n", req.Name, req.Op, req.Version)
+			continue
+		}
+		if !versionSatisfies(version, req) {
+			problems = append(problems, fmt.Sprintf("%s: found version %s, need %s%s", req.Name, version, req.Op, req.Version))
+		}
+	}
+	return problems
+}
+
+// checkScriptRequirements verifies the binaries a script declares with
+// "berga:requires:" before it runs
+func checkScriptRequirements(scriptName, scriptPath string) error {
+	value := readMetadata(scriptPath)["requires"]
+	if value == "" {
+		return nil
+	}
+	reqs, err := parseRequirements(value)
+	if err != nil {
+		return fmt.Errorf("script '%s': %w", scriptName, err)
+	}
+
+	problems := checkRequirements(reqs)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("script '%s' has unmet dependencies (use --skip-checks to run anyway):\n  - %s",
+		scriptName, strings.Join(problems, "\n  - "))
+}
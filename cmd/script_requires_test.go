@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseRequirements(t *testing.T) {
+	got, err := parseRequirements("[kubectl, jq>=1.6, node == 20]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []requirement{
+		{Name: "kubectl"},
+		{Name: "jq", Op: ">=", Version: "1.6"},
+		{Name: "node", Op: "=", Version: "20"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := parseRequirements("jq>="); err == nil {
+		t.Error("Expected an error for a constraint without a version")
+	}
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		version string
+		req     requirement
+		want    bool
+	}{
+		{"1.6", requirement{Op: ">=", Version: "1.6"}, true},
+		{"1.10.2", requirement{Op: ">=", Version: "1.6"}, true},
+		{"1.5.9", requirement{Op: ">=", Version: "1.6"}, false},
+		{"2.0", requirement{Op: "<", Version: "2"}, false},
+		{"20.11.0", requirement{Op: "=", Version: "20.11"}, true},
+		{"3.1", requirement{Op: ">", Version: "3.1.0"}, false},
+	}
+	for _, tt := range tests {
+		if got := versionSatisfies(tt.version, tt.req); got != tt.want {
+			t.Errorf("versionSatisfies(%q, %s%s) = %v, want %v", tt.version, tt.req.Op, tt.req.Version, got, tt.want)
+		}
+	}
+}
+
+func TestCheckScriptRequirements(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools in this test are shell scripts")
+	}
+
+	// A fake tool on PATH that reports its version
+	bin := t.TempDir()
+	tool := filepath.Join(bin, "fakejq")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho fakejq-1.5.1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	script := filepath.Join(t.TempDir(), "report.sh")
+	write := func(requires string) {
+		content := "#!/bin/sh\n# berga:requires: " + requires + "\necho hi\n"
+		if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("fakejq>=1.5")
+	if err := checkScriptRequirements("report.sh", script); err != nil {
+		t.Errorf("Expected requirements to be met, got %v", err)
+	}
+
+	write("fakejq>=1.6, berga-missing-tool")
+	err := checkScriptRequirements("report.sh", script)
+	if err == nil {
+		t.Fatal("Expected unmet requirements to fail")
+	}
+	for _, want := range []string{"fakejq: found version 1.5.1, need >=1.6", "berga-missing-tool: not found in PATH", "--skip-checks"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// defaultResultLimit caps each captured stream in a --result-json result
+const defaultResultLimit = 1 << 20
+
+// errScriptTimedOut is returned when a script runs past its timeout
+var errScriptTimedOut = errors.New("script execution timed out")
+
+var (
+	scriptResultJSON  bool
+	scriptResultLimit int
+)
+
+// ScriptResult is what 'script run --result-json' prints once the run is over
+type ScriptResult struct {
+	Script          string   `json:"script"`
+	Command         []string `json:"command"`
+	ExitCode        int      `json:"exit_code"`
+	DurationMS      int64    `json:"duration_ms"`
+	TimedOut        bool     `json:"timed_out"`
+	Stdout          string   `json:"stdout"`
+	Stderr          string   `json:"stderr"`
+	StdoutTruncated bool     `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool     `json:"stderr_truncated,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the
+// rest. A limit of zero keeps everything.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && b.buf.Len()+len(p) > b.limit {
+		b.buf.Write(p[:b.limit-b.buf.Len()])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// resultCapture collects the output and command line of the script run
+// behind --result-json; nil when the flag is not set
+type resultCapture struct {
+	command        []string
+	stdout, stderr cappedBuffer
+}
+
+// activeResult is the capture for the current run, read by executeScript
+var activeResult *resultCapture
+
+// newResultCapture starts capturing a run, keeping up to limit bytes of each
+// stream
+func newResultCapture(limit int) *resultCapture {
+	return &resultCapture{stdout: cappedBuffer{limit: limit}, stderr: cappedBuffer{limit: limit}}
+}
+
+// attempt resets the capture for a new attempt of the run, so retries
+// report the output of the last one
+func (c *resultCapture) attempt(command []string) (io.Writer, io.Writer) {
+	c.command = command
+	c.stdout = cappedBuffer{limit: c.stdout.limit}
+	c.stderr = cappedBuffer{limit: c.stderr.limit}
+	return &c.stdout, &c.stderr
+}
+
+// result summarizes the run as a ScriptResult
+func (c *resultCapture) result(scriptName string, err error, elapsed time.Duration) ScriptResult {
+	r := ScriptResult{
+		Script:          scriptName,
+		Command:         c.command,
+		ExitCode:        ExitCode(err),
+		DurationMS:      elapsed.Milliseconds(),
+		TimedOut:        errors.Is(err, errScriptTimedOut),
+		Stdout:          c.stdout.buf.String(),
+		Stderr:          c.stderr.buf.String(),
+		StdoutTruncated: c.stdout.truncated,
+		StderrTruncated: c.stderr.truncated,
+	}
+	if r.Command == nil {
+		r.Command = []string{}
+	}
+	var exitErr *ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// checkResultFlags rejects flags that cannot be combined with --result-json
+func checkResultFlags() error {
+	if !scriptResultJSON {
+		return nil
+	}
+	if len(scriptWatch) > 0 {
+		return fmt.Errorf("--result-json cannot be used with --watch")
+	}
+	if len(scriptHosts) > 0 {
+		return fmt.Errorf("--result-json cannot be used with --hosts")
+	}
+	if scriptResultLimit < 0 {
+		return fmt.Errorf("--result-limit must not be negative")
+	}
+	return nil
+}
+
+// printScriptResult writes a run's result as JSON to stdout
+func printScriptResult(r ScriptResult) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	return nil
+}
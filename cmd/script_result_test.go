@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCappedBuffer(t *testing.T) {
+	b := cappedBuffer{limit: 5}
+	fmt.Fprint(&b, "abc")
+	if n, err := b.Write([]byte("defgh")); n != 5 || err != nil {
+		t.Errorf("Expected the whole write to be accepted, got %d, %v", n, err)
+	}
+	if b.buf.String() != "abcde" || !b.truncated {
+		t.Errorf("Expected the output to be cut at the limit, got %q (truncated %v)", b.buf.String(), b.truncated)
+	}
+
+	unlimited := cappedBuffer{}
+	fmt.Fprint(&unlimited, strings.Repeat("x", 100))
+	if unlimited.buf.Len() != 100 || unlimited.truncated {
+		t.Error("Expected a zero limit to keep everything")
+	}
+}
+
+func TestScriptResult(t *testing.T) {
+	c := newResultCapture(0)
+	timedOut := c.result("slow.sh", fmt.Errorf("%w after 1s", errScriptTimedOut), time.Second)
+	if !timedOut.TimedOut || timedOut.ExitCode != 1 || timedOut.Error == "" || timedOut.DurationMS != 1000 {
+		t.Errorf("Unexpected result for a timeout: %+v", timedOut)
+	}
+	failed := c.result("fail.sh", &ExitError{Code: 3}, 0)
+	if failed.ExitCode != 3 || failed.TimedOut || failed.Error != "" || failed.Command == nil {
+		t.Errorf("Unexpected result for a failed script: %+v", failed)
+	}
+}
+
+func TestExecuteScriptCapturesResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	script := writeTestScript(t, "noisy.sh", "#!/bin/sh\necho out\necho err >&2\nexit 2\n")
+
+	activeResult = newResultCapture(2)
+	defer func() { activeResult = nil }()
+	err := executeScript(context.Background(), script, []string{"a"}, nil, time.Minute)
+
+	r := activeResult.result("noisy.sh", err, time.Millisecond)
+	if r.ExitCode != 2 || r.Stdout != "ou" || !r.StdoutTruncated || r.Stderr != "er" {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	if len(r.Command) == 0 || r.Command[len(r.Command)-1] != "a" {
+		t.Errorf("Expected the resolved command line, got %q", r.Command)
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// retryPolicy controls how often a failing script is re-run
+type retryPolicy struct {
+	Retries int
+	Delay   time.Duration
+}
+
+// scriptOverrides returns the scripts.overrides.<name> settings for a script.
+// Script names usually contain dots, so the map is looked up directly rather
+// than through a dot-path key.
+func scriptOverrides(scriptName string) map[string]interface{} {
+	overrides := viper.GetStringMap("scripts.overrides")
+	for name, value := range overrides {
+		if strings.EqualFold(name, scriptName) {
+			return cast.ToStringMap(value)
+		}
+	}
+	return nil
+}
+
+// scriptRetryPolicy resolves the retry policy from explicit flags, then the
+// script's overrides, then the global scripts settings
+func scriptRetryPolicy(scriptName string, retriesSet, delaySet bool) retryPolicy {
+	policy := retryPolicy{Retries: scriptRetries, Delay: scriptRetryDelay}
+	overrides := scriptOverrides(scriptName)
+
+	if !retriesSet {
+		if v, ok := overrides["retries"]; ok {
+			policy.Retries = cast.ToInt(v)
+		} else if viper.IsSet("scripts.retries") {
+			policy.Retries = viper.GetInt("scripts.retries")
+		}
+	}
+	if !delaySet {
+		if v, ok := overrides["retry_delay"]; ok {
+			policy.Delay = cast.ToDuration(v)
+		} else if viper.IsSet("scripts.retry_delay") {
+			policy.Delay = viper.GetDuration("scripts.retry_delay")
+		}
+	}
+
+	if policy.Retries < 0 {
+		policy.Retries = 0
+	}
+	return policy
+}
+
+// maxRetryDelay caps the doubling between retries; a longer --retry-delay is
+// kept as given
+const maxRetryDelay = 10 * time.Minute
+
+// maxReplayStdin is the most piped input --replay-stdin keeps for retries
+const maxReplayStdin = 64 << 20
+
+// backoff returns the delay before the given retry (1-based), doubling each
+// time up to maxRetryDelay
+func (p retryPolicy) backoff(retry int) time.Duration {
+	limit := maxRetryDelay
+	if p.Delay > limit {
+		limit = p.Delay
+	}
+	wait := p.Delay
+	for i := 1; i < retry && wait > 0 && wait < limit; i++ {
+		wait *= 2
+	}
+	if wait > limit {
+		wait = limit
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// spoolRetryStdin copies piped standard input to a temporary file when
+// --replay-stdin asks for every retry to get the same input, and returns its
+// path. It returns "" when stdin is passed straight through: without the
+// flag or retries, with --input-file, which is reopened for each attempt, or
+// from a terminal. The caller removes the file.
+func spoolRetryStdin(policy retryPolicy) (string, error) {
+	if !scriptReplayStdin || policy.Retries == 0 || scriptInputFile != "" || stdinIsTerminal() {
+		return "", nil
+	}
+	f, err := os.CreateTemp("", "berga-stdin-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save input: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(os.Stdin, maxReplayStdin+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxReplayStdin {
+		err = fmt.Errorf("piped input is larger than %d MB; use --input-file", maxReplayStdin>>20)
+	} else if err != nil {
+		err = fmt.Errorf("failed to save input: %w", err)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// runWithRetries calls attempt until it succeeds or the policy is exhausted,
+// reporting each failed attempt
+func runWithRetries(policy retryPolicy, attempt func() error) error {
+	total := policy.Retries + 1
+
+	var err error
+	for i := 1; i <= total; i++ {
+		if err = attempt(); err == nil {
+			if i > 1 {
+				fmt.Fprintln(os.Stderr, ui.ErrGreen(fmt.Sprintf("Succeeded on attempt %d/%d", i, total)))
+			}
+			return nil
+		}
+		if i == total {
+			break
+		}
+
+		wait := policy.backoff(i)
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Attempt %d/%d failed: %v; retrying in %v", i, total, err, wait)))
+		time.Sleep(wait)
+	}
+
+	if total > 1 {
+		fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("Failed after %d attempts", total)))
+	}
+	return err
+}
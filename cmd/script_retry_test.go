@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	policy := retryPolicy{Retries: 3, Delay: time.Second}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// Large retry counts stay capped instead of overflowing
+	for _, retry := range []int{20, 64, 65, 1000} {
+		if got := policy.backoff(retry); got != maxRetryDelay {
+			t.Errorf("backoff(%d) = %v, want %v", retry, got, maxRetryDelay)
+		}
+	}
+	long := retryPolicy{Retries: 3, Delay: time.Hour}
+	if got := long.backoff(5); got != time.Hour {
+		t.Errorf("Expected a delay above the cap to be kept, got %v", got)
+	}
+	if got := (retryPolicy{Retries: 100}).backoff(100); got != 0 {
+		t.Errorf("Expected no delay to stay zero, got %v", got)
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	calls := 0
+	err := runWithRetries(retryPolicy{Retries: 2}, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success on second attempt, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+
+	calls = 0
+	exitErr := &ExitError{Code: 3}
+	err = runWithRetries(retryPolicy{Retries: 2}, func() error {
+		calls++
+		return exitErr
+	})
+	if err != exitErr {
+		t.Errorf("Expected the last error to be returned, got: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+// setStdin replaces os.Stdin with a pipe holding data
+func setStdin(t *testing.T, data string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(data)
+	w.Close()
+	origStdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = origStdin
+		r.Close()
+	})
+}
+
+func TestRetryStdinReplayed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	setStdin(t, "hello\n")
+	policy := retryPolicy{Retries: 1}
+
+	// Without --replay-stdin, stdin is passed straight through and never read
+	if path, err := spoolRetryStdin(policy); path != "" || err != nil {
+		t.Fatalf("Expected stdin to be passed through without --replay-stdin, got %q, %v", path, err)
+	}
+	scriptReplayStdin = true
+	defer func() { scriptReplayStdin = false }()
+	if path, _ := spoolRetryStdin(retryPolicy{}); path != "" {
+		t.Fatal("Expected stdin to be passed through without retries")
+	}
+
+	path, err := spoolRetryStdin(policy)
+	if err != nil || path == "" {
+		t.Fatalf("Expected stdin to be saved once, got %q, %v", path, err)
+	}
+	defer os.Remove(path)
+
+	// Fails the first time after reading its input, and needs the same
+	// input the second time
+	marker := filepath.Join(t.TempDir(), "attempted")
+	script := writeTestScript(t, "flaky.sh", "#!/bin/sh\nread line\n[ \"$line\" = hello ] || exit 3\n[ -e '"+marker+"' ] || { touch '"+marker+"'; exit 1; }\n")
+	err = runWithRetries(policy, func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return executeScript(context.Background(), script, nil, f, 0)
+	})
+	if err != nil {
+		t.Errorf("Expected the retry to get the same input, got %v", err)
+	}
+}
+
+func TestRetryStdinTooLarge(t *testing.T) {
+	setTerminal(t, false)
+	scriptReplayStdin = true
+	defer func() { scriptReplayStdin = false }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = origStdin }()
+	go func() {
+		chunk := make([]byte, 1<<20)
+		for i := 0; i <= maxReplayStdin>>20; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				break
+			}
+		}
+		w.Close()
+	}()
+	defer r.Close()
+
+	if _, err := spoolRetryStdin(retryPolicy{Retries: 1}); err == nil || !strings.Contains(err.Error(), "--input-file") {
+		t.Errorf("Expected oversized input to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"berga/internal/ui"
+	"berga/pkg/scripts"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSpecTimeout bounds each test case that does not set its own timeout
+const defaultSpecTimeout = 30 * time.Second
+
+// ScriptSpec is the sidecar <script>.test.yaml describing a script's tests
+type ScriptSpec struct {
+	Tests []ScriptTestCase `yaml:"tests"`
+}
+
+// ScriptTestCase is one run of a script and what it is expected to do
+type ScriptTestCase struct {
+	Name           string            `yaml:"name"`
+	Args           []string          `yaml:"args,omitempty"`
+	Stdin          string            `yaml:"stdin,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	Files          map[string]string `yaml:"files,omitempty"`
+	ExitCode       int               `yaml:"exit_code"`
+	Stdout         *string           `yaml:"stdout,omitempty"`
+	StdoutContains []string          `yaml:"stdout_contains,omitempty"`
+	StderrContains []string          `yaml:"stderr_contains,omitempty"`
+	Timeout        string            `yaml:"timeout,omitempty"`
+}
+
+// scriptTestCmd runs the test cases declared next to a script
+var scriptTestCmd = &cobra.Command{
+	Use:   "test [script-name...]",
+	Short: "Run a script's test cases",
+	Long: `Run the test cases in a script's sidecar spec, <script>.test.yaml, and check
+the exit code and output of each. Every case runs in a fresh temporary
+directory holding the fixture files it declares:
+
+  tests:
+    - name: greets by name
+      args: [World]
+      stdout: "Hello, World\n"
+    - name: reads config
+      files:
+        config.ini: "name=test\n"
+      stdout_contains: [test]
+    - name: fails without input
+      exit_code: 1
+      stderr_contains: [usage]
+
+With no arguments every script that has a spec is tested. The command exits
+nonzero when a case fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return testScripts(args)
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptTestCmd)
+}
+
+// isScriptSpecFile reports whether a file in a scripts directory is a test
+// spec or a JSON schema for script contracts, rather than a script
+func isScriptSpecFile(name string) bool {
+	return strings.HasSuffix(name, ".test.yaml") || strings.HasSuffix(name, schemaExt)
+}
+
+// specPathFor returns the path of the test spec for a script. Encrypted
+// scripts use the spec of their plain name.
+func specPathFor(scriptPath string) string {
+	return strings.TrimSuffix(scriptPath, encryptedExt) + ".test.yaml"
+}
+
+// loadScriptSpec reads a script's test spec, returning nil if it has none
+func loadScriptSpec(scriptPath string) (*ScriptSpec, error) {
+	data, err := os.ReadFile(specPathFor(scriptPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test spec: %w", err)
+	}
+
+	var spec ScriptSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(specPathFor(scriptPath)), err)
+	}
+	for i, tc := range spec.Tests {
+		if tc.Name == "" {
+			spec.Tests[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+		if tc.Timeout != "" {
+			if _, err := time.ParseDuration(tc.Timeout); err != nil {
+				return nil, fmt.Errorf("test '%s': invalid timeout '%s'", spec.Tests[i].Name, tc.Timeout)
+			}
+		}
+		for file := range tc.Files {
+			if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+				return nil, fmt.Errorf("test '%s': fixture '%s' must be a relative path inside the test directory", spec.Tests[i].Name, file)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// writeFixtures creates a test case's files in dir
+func writeFixtures(dir string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runScriptTest runs one case and returns why it failed, or nothing if it passed
+func runScriptTest(scriptPath string, tc ScriptTestCase) ([]string, error) {
+	dir, err := os.MkdirTemp("", "berga-test-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writeFixtures(dir, tc.Files); err != nil {
+		return nil, fmt.Errorf("failed to write fixtures: %w", err)
+	}
+
+	timeout := defaultSpecTimeout
+	if tc.Timeout != "" {
+		timeout, _ = time.ParseDuration(tc.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := scripts.LocalRunner{}.Command(ctx, scriptPath, tc.Args)
+	cmd.Dir = dir
+	cmd.Env = layerEnviron(os.Environ(), tc.Env)
+	cmd.Stdin = strings.NewReader(tc.Stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			return []string{fmt.Sprintf("timed out after %s", timeout)}, nil
+		case errors.As(err, &exitErr):
+			exitCode = exitErr.ExitCode()
+		default:
+			return nil, fmt.Errorf("failed to run script: %w", err)
+		}
+	}
+
+	var failures []string
+	if exitCode != tc.ExitCode {
+		failures = append(failures, fmt.Sprintf("exit code %d, expected %d", exitCode, tc.ExitCode))
+	}
+	if tc.Stdout != nil && stdout.String() != *tc.Stdout {
+		failures = append(failures, fmt.Sprintf("stdout was %q, expected %q", stdout.String(), *tc.Stdout))
+	}
+	for _, want := range tc.StdoutContains {
+		if !strings.Contains(stdout.String(), want) {
+			failures = append(failures, fmt.Sprintf("stdout does not contain %q", want))
+		}
+	}
+	for _, want := range tc.StderrContains {
+		if !strings.Contains(stderr.String(), want) {
+			failures = append(failures, fmt.Sprintf("stderr does not contain %q", want))
+		}
+	}
+	return failures, nil
+}
+
+// testTargets returns the scripts to test, by name and path. With no names,
+// every script that has a spec is returned.
+func testTargets(names []string) (map[string]string, error) {
+	if len(names) > 0 {
+		targets := make(map[string]string)
+		for _, name := range names {
+			path := resolveScriptPath(name)
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("script '%s' not found in %s", name, GetScriptsDir())
+			}
+			if _, err := os.Stat(specPathFor(path)); err != nil {
+				return nil, fmt.Errorf("script '%s' has no test spec (expected %s)", name, specPathFor(path))
+			}
+			targets[name] = path
+		}
+		return targets, nil
+	}
+
+	all, err := lintTargets(nil)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for name, path := range all {
+		if _, err := os.Stat(specPathFor(path)); err == nil {
+			targets[name] = path
+		}
+	}
+	return targets, nil
+}
+
+func testScripts(names []string) error {
+	targets, err := testTargets(names)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No scripts with a test spec found.")
+		return nil
+	}
+
+	sorted := make([]string, 0, len(targets))
+	for name := range targets {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	passed, failed := 0, 0
+	for _, name := range sorted {
+		spec, err := loadScriptSpec(targets[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		scriptPath, cleanup, err := plainScriptPath(targets[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer cleanup()
+		fmt.Println(ui.Bold(name))
+		for _, tc := range spec.Tests {
+			failures, err := runScriptTest(scriptPath, tc)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", name, tc.Name, err)
+			}
+			if len(failures) == 0 {
+				passed++
+				fmt.Printf("  %s %s\n", ui.Green("PASS"), tc.Name)
+				continue
+			}
+			failed++
+			fmt.Printf("  %s %s\n", ui.Red("FAIL"), tc.Name)
+			for _, failure := range failures {
+				fmt.Printf("       %s\n", ui.Dim(failure))
+			}
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d test(s) failed", failed)
+	}
+	return nil
+}
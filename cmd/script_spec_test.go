@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadScriptSpec(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "greet.sh", "#!/bin/sh\necho hi\n")
+
+	if spec, err := loadScriptSpec(path); err != nil || spec != nil {
+		t.Fatalf("no spec: got %v, %v", spec, err)
+	}
+
+	if err := os.WriteFile(specPathFor(path), []byte("tests:\n  - args: [a]\n  - name: escapes\n    files:\n      ../out: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScriptSpec(path); err == nil || !strings.Contains(err.Error(), "relative path") {
+		t.Errorf("fixture outside the test directory: got %v", err)
+	}
+
+	if err := os.WriteFile(specPathFor(path), []byte("tests:\n  - args: [a]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadScriptSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Tests) != 1 || spec.Tests[0].Name != "case 1" {
+		t.Errorf("unnamed case: got %+v", spec.Tests)
+	}
+}
+
+func TestRunScriptTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "check.sh", "#!/bin/sh\nread line\necho \"$1 $GREETING $line $(cat input.txt)\"\necho oops >&2\nexit 3\n")
+
+	stdout := "hello hi there fixture\n"
+	pass := ScriptTestCase{
+		Args:           []string{"hello"},
+		Stdin:          "there\n",
+		Env:            map[string]string{"GREETING": "hi"},
+		Files:          map[string]string{"input.txt": "fixture"},
+		ExitCode:       3,
+		Stdout:         &stdout,
+		StderrContains: []string{"oops"},
+	}
+	failures, err := runScriptTest(path, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("expected pass, got %v", failures)
+	}
+
+	fail := pass
+	fail.ExitCode = 0
+	fail.StdoutContains = []string{"missing"}
+	failures, err = runScriptTest(path, fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 2 {
+		t.Errorf("expected exit code and stdout failures, got %v", failures)
+	}
+}
+
+func TestIsScriptSpecFile(t *testing.T) {
+	if !isScriptSpecFile("deploy.sh.test.yaml") || isScriptSpecFile("deploy.sh") {
+		t.Error("isScriptSpecFile misclassified a file")
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"berga/internal/i18n"
+)
+
+// setInputFile sets --input-file for the duration of the test
+func setInputFile(t *testing.T, path string) {
+	t.Helper()
+	old := scriptInputFile
+	scriptInputFile = path
+	t.Cleanup(func() { scriptInputFile = old })
+}
+
+// writeStdinScript writes a script that copies its stdin to the returned file
+func writeStdinScript(t *testing.T) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "stdin.txt")
+	t.Setenv("BERGA_TEST_STDIN", out)
+	writeTestScript(t, "echo-stdin.sh", "#!/bin/sh\ncat > \"$BERGA_TEST_STDIN\"\n")
+	return out
+}
+
+func TestRunScriptInputFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	out := writeStdinScript(t)
+
+	input := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(input, []byte("line one\nline two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setInputFile(t, input)
+
+	if err := runScript("echo-stdin.sh", nil); err != nil {
+		t.Fatalf("runScript failed: %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "line one\nline two\n" {
+		t.Errorf("Expected the script to receive the input file on stdin, got %q", got)
+	}
+}
+
+func TestRunScriptInputFileRelative(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	out := writeStdinScript(t)
+
+	// A relative --input-file is resolved against the directory berga runs in
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "payload.json"), []byte(`{"ok":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	setInputFile(t, "payload.json")
+
+	if err := runScript("echo-stdin.sh", nil); err != nil {
+		t.Fatalf("runScript failed: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != `{"ok":true}` {
+		t.Errorf("Expected the relative input file on stdin, got %q", got)
+	}
+}
+
+func TestRunScriptInputFileMissing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	setTerminal(t, false)
+	out := writeStdinScript(t)
+	setInputFile(t, filepath.Join(t.TempDir(), "missing.txt"))
+
+	err := runScript("echo-stdin.sh", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to open input file") {
+		t.Fatalf("Expected a missing input file to fail, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("Expected the script not to run without its input file")
+	}
+}
+
+func TestScriptNotFoundMessage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	want := i18n.T("error.script_not_found", "missing.sh", GetScriptsDir())
+	if err := runScript("missing.sh", nil); err == nil || err.Error() != want {
+		t.Errorf("run: expected %q, got %v", want, err)
+	}
+	if err := showScript("missing.sh"); err == nil || err.Error() != want {
+		t.Errorf("show: expected %q, got %v", want, err)
+	}
+}
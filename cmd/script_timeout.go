@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"berga/internal/ui"
+	"berga/pkg/config"
+
+	"github.com/spf13/viper"
+)
+
+// defaultScriptTimeout applies when neither --timeout nor scripts.timeout is set
+const defaultScriptTimeout = 5 * time.Minute
+
+// defaultGracePeriod is how long a timed-out script has to exit after
+// SIGTERM before it is killed, when neither --grace nor scripts.grace is set
+const defaultGracePeriod = 5 * time.Second
+
+// timeoutNoticeInterval is how often verbose runs report the time left
+const timeoutNoticeInterval = time.Minute
+
+// timeoutValue is the --timeout flag: a duration such as 90s or 2m30s, or a
+// bare number of seconds as in earlier versions. Zero means no timeout.
+type timeoutValue time.Duration
+
+func (t *timeoutValue) String() string { return time.Duration(*t).String() }
+
+func (t *timeoutValue) Type() string { return "timeout" }
+
+func (t *timeoutValue) Set(raw string) error {
+	d, err := config.ParseTimeout(raw)
+	if err != nil {
+		return err
+	}
+	*t = timeoutValue(d)
+	return nil
+}
+
+// scriptRunTimeout returns the timeout from the --timeout flag or config.
+// The flag is bound to scripts.timeout, so an explicit flag wins over config.
+// Zero means no timeout.
+func scriptRunTimeout() time.Duration {
+	if !viper.IsSet("scripts.timeout") {
+		return defaultScriptTimeout
+	}
+	d, err := config.ParseTimeout(viper.GetString("scripts.timeout"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: scripts.timeout %v; using %s", err, defaultScriptTimeout)))
+		return defaultScriptTimeout
+	}
+	return d
+}
+
+// scriptGracePeriod returns the grace period from --grace or scripts.grace.
+// Zero kills a timed-out script at once, as always happens on Windows, which
+// has no SIGTERM.
+func scriptGracePeriod() time.Duration {
+	if runtime.GOOS == "windows" {
+		return 0
+	}
+	return max(viper.GetDuration("scripts.grace"), 0)
+}
+
+// killedBySignal reports whether a process was ended by SIGKILL
+func killedBySignal(state *os.ProcessState) bool {
+	if state == nil {
+		return false
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// runContext is context.WithTimeout, except that a zero timeout never expires
+func runContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// formatTimeout describes a timeout for verbose output
+func formatTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "none"
+	}
+	return timeout.String()
+}
+
+// reportTimeLeft prints how long a run has left before its deadline every
+// interval until ctx is done. Runs without a deadline report nothing.
+func reportTimeLeft(ctx context.Context, out io.Writer, interval time.Duration) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			left := deadline.Sub(now).Round(time.Second)
+			fmt.Fprintln(out, ui.Dim(fmt.Sprintf("[berga] %s left before the timeout", left)))
+		}
+	}
+}
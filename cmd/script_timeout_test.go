@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestScriptRunTimeout(t *testing.T) {
+	defer viper.Set("scripts.timeout", nil)
+
+	tests := map[interface{}]time.Duration{
+		120:     2 * time.Minute,
+		"90":    90 * time.Second,
+		"2m30s": 150 * time.Second,
+		0:       0,
+		"soon":  defaultScriptTimeout,
+	}
+	for value, want := range tests {
+		viper.Set("scripts.timeout", value)
+		if got := scriptRunTimeout(); got != want {
+			t.Errorf("scripts.timeout %v: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestTimeoutFlag(t *testing.T) {
+	var v timeoutValue
+	for raw, want := range map[string]string{"45": "45s", "1h": "1h0m0s", "0": "0s"} {
+		if err := v.Set(raw); err != nil || v.String() != want {
+			t.Errorf("Set(%q) gave %s, %v; want %s", raw, v.String(), err, want)
+		}
+	}
+	if err := v.Set("-3"); err == nil {
+		t.Error("Expected a negative timeout to be rejected")
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	ctx, cancel := runContext(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected a zero timeout to have no deadline")
+	}
+
+	ctx, cancel = runContext(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	var out strings.Builder
+	reportTimeLeft(ctx, &out, 30*time.Millisecond)
+	if n := strings.Count(out.String(), "left before the timeout"); n < 1 || n > 2 {
+		t.Errorf("Expected the time left to be reported while running, got %q", out.String())
+	}
+}
+
+func TestTimeoutGracePeriod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts and SIGTERM")
+	}
+	t.Setenv("HOME", t.TempDir())
+	defer viper.Set("scripts.grace", nil)
+	viper.Set("scripts.grace", "2s")
+
+	trap := writeTestScript(t, "trap.sh", "#!/bin/sh\ntrap 'exit 0' TERM\nwhile :; do sleep 0.05; done\n")
+	err := executeScript(context.Background(), trap, nil, nil, 100*time.Millisecond)
+	if !errors.Is(err, errScriptTimedOut) || !strings.Contains(err.Error(), "stopped by SIGTERM") {
+		t.Errorf("Expected a script that exits on SIGTERM to be reported as stopped, got %v", err)
+	}
+
+	viper.Set("scripts.grace", "200ms")
+	stubborn := writeTestScript(t, "stubborn.sh", "#!/bin/sh\ntrap '' TERM\nwhile :; do sleep 0.05; done\n")
+	start := time.Now()
+	err = executeScript(context.Background(), stubborn, nil, nil, 100*time.Millisecond)
+	if !errors.Is(err, errScriptTimedOut) || !strings.Contains(err.Error(), "killed after a 200ms grace period") {
+		t.Errorf("Expected a script ignoring SIGTERM to be killed after the grace period, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the grace period to be waited out, took %v", elapsed)
+	}
+}
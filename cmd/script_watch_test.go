@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/pkg/deep/file.go", true},
+		{"src/**/*.go", "other/main.go", false},
+		{"*.txt", "notes.txt", true},
+		{"*.txt", "dir/notes.txt", false},
+		{"file?.md", "file1.md", true},
+	}
+
+	for _, tt := range tests {
+		re, err := globToRegexp(tt.glob)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.match {
+			t.Errorf("globToRegexp(%q) match %q = %v, want %v", tt.glob, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	if got := globRoot("src/**/*.go"); got != "src" {
+		t.Errorf("Expected src, got %s", got)
+	}
+	if got := globRoot("*.go"); got != "." {
+		t.Errorf("Expected ., got %s", got)
+	}
+	if got := globRoot("config/app.yaml"); got != filepath.FromSlash("config") {
+		t.Errorf("Expected config, got %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchRegex      bool
+	searchIgnoreCase bool
+	searchContext    int
+	searchTypes      []string
+)
+
+// searchSource is the directories of searchable files of one type, in
+// lookup order
+type searchSource struct {
+	Type string
+	Dirs []string
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search across scripts, templates, snippets, and notes",
+	Long: `Search the content of everything stored in berga. The query is matched
+literally unless --regex is given.
+
+  berga search curl
+  berga search -r 'curl .*-H' --type script -C 2`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return search(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().BoolVarP(&searchRegex, "regex", "r", false, "Treat the query as a regular expression")
+	searchCmd.Flags().BoolVarP(&searchIgnoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	searchCmd.Flags().IntVarP(&searchContext, "context", "C", 0, "Lines of context around each match")
+	searchCmd.Flags().StringSliceVar(&searchTypes, "type", nil, "Only search these types: script, template, snippet, note, cheat")
+}
+
+// searchSources returns the directories searched by default
+func searchSources() []searchSource {
+	return []searchSource{
+		{"script", GetScriptsDirs()},
+		{"template", GetTemplatesDirs()},
+		{"snippet", []string{GetSnippetsDir()}},
+		{"note", []string{GetNotesDir()}},
+		{"cheat", []string{GetCheatsDir()}},
+	}
+}
+
+// compileSearchQuery builds the matcher for a query
+func compileSearchQuery(query string, useRegex, ignoreCase bool) (*regexp.Regexp, error) {
+	pattern := query
+	if !useRegex {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// highlightMatches wraps every match in a line with the match style
+func highlightMatches(re *regexp.Regexp, line string) string {
+	if !ui.ColorEnabled() {
+		return line
+	}
+	return re.ReplaceAllStringFunc(line, func(m string) string {
+		return ui.Red(ui.Bold(m))
+	})
+}
+
+func search(query string) error {
+	re, err := compileSearchQuery(query, searchRegex, searchIgnoreCase)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range searchTypes {
+		wanted[strings.TrimSuffix(strings.ToLower(t), "s")] = true
+	}
+
+	var sources []searchSource
+	var dirs []string
+	for _, source := range searchSources() {
+		if len(wanted) == 0 || wanted[source.Type] {
+			sources = append(sources, source)
+			dirs = append(dirs, source.Dirs...)
+		}
+	}
+	refreshListings(dirs)
+
+	total := 0
+	for _, source := range sources {
+		for _, dir := range source.Dirs {
+			walkListing(dir, func(path string) {
+				rel, _ := filepath.Rel(dir, path)
+				if n, err := searchFile(os.Stdout, re, source.Type, rel, path); err == nil {
+					total += n
+				}
+			})
+		}
+	}
+
+	if total == 0 {
+		fmt.Printf("No matches for '%s'.\n", query)
+	}
+	return nil
+}
+
+// searchFile writes the matches in one file to w and returns how many lines
+// matched
+func searchFile(w io.Writer, re *regexp.Regexp, kind, name, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	matches := 0
+	lastPrinted := -1
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		if matches == 0 {
+			fmt.Fprintf(w, "%s %s\n", ui.Dim(kind), ui.Bold(name))
+		}
+		matches++
+
+		from := i - searchContext
+		if from < 0 {
+			from = 0
+		}
+		if from <= lastPrinted {
+			from = lastPrinted + 1
+		} else if lastPrinted >= 0 && from > lastPrinted+1 && searchContext > 0 {
+			fmt.Fprintln(w, ui.Dim("  --"))
+		}
+		to := i + searchContext
+		if to >= len(lines) {
+			to = len(lines) - 1
+		}
+
+		for j := from; j <= to; j++ {
+			if j == i || re.MatchString(lines[j]) {
+				fmt.Fprintf(w, "  %s: %s\n", ui.Green(fmt.Sprintf("%4d", j+1)), highlightMatches(re, lines[j]))
+			} else {
+				fmt.Fprintf(w, "  %s  %s\n", ui.Dim(fmt.Sprintf("%4d", j+1)), lines[j])
+			}
+		}
+		lastPrinted = to
+	}
+
+	if matches > 0 {
+		fmt.Fprintln(w)
+	}
+	return matches, nil
+}
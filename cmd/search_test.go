@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSearchFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setSearchContext(t *testing.T, n int) {
+	t.Helper()
+	old := searchContext
+	searchContext = n
+	t.Cleanup(func() { searchContext = old })
+}
+
+func TestCompileSearchQuery(t *testing.T) {
+	tests := []struct {
+		query      string
+		useRegex   bool
+		ignoreCase bool
+		line       string
+		want       bool
+	}{
+		{"a.c", false, false, "a.c", true},
+		{"a.c", false, false, "abc", false},
+		{"a.c", true, false, "abc", true},
+		{"Curl", false, false, "curl -s", false},
+		{"Curl", false, true, "curl -s", true},
+		{"^curl .*-H", true, false, "curl -s -H x", true},
+	}
+	for _, tt := range tests {
+		re, err := compileSearchQuery(tt.query, tt.useRegex, tt.ignoreCase)
+		if err != nil {
+			t.Fatalf("compileSearchQuery(%q) failed: %v", tt.query, err)
+		}
+		if got := re.MatchString(tt.line); got != tt.want {
+			t.Errorf("%q (regex=%v, ignore-case=%v) on %q = %v, want %v", tt.query, tt.useRegex, tt.ignoreCase, tt.line, got, tt.want)
+		}
+	}
+
+	if _, err := compileSearchQuery("(", true, false); err == nil {
+		t.Error("Expected an invalid regular expression to be rejected")
+	}
+}
+
+func TestSearchFileContext(t *testing.T) {
+	setSearchContext(t, 1)
+	path := writeSearchFile(t, t.TempDir(), "deploy.sh", "one\ntwo\nmatch\nfour\nfive\nsix\nseven\nmatch\nnine\n")
+	re, _ := compileSearchQuery("match", false, false)
+
+	var out bytes.Buffer
+	n, err := searchFile(&out, re, "script", "deploy.sh", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 matches, got %d", n)
+	}
+	want := "script deploy.sh\n" +
+		"     2  two\n" +
+		"     3: match\n" +
+		"     4  four\n" +
+		"  --\n" +
+		"     7  seven\n" +
+		"     8: match\n" +
+		"     9  nine\n" +
+		"\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestSearchFileOverlappingContext(t *testing.T) {
+	setSearchContext(t, 2)
+	path := writeSearchFile(t, t.TempDir(), "notes.md", "a\nmatch\nb\nmatch\nc\nd\n")
+	re, _ := compileSearchQuery("match", false, false)
+
+	var out bytes.Buffer
+	n, err := searchFile(&out, re, "note", "notes.md", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 matches, got %d", n)
+	}
+	// The windows of both matches overlap, so every line is printed once,
+	// with no separator, and a match inside another's context is still
+	// marked as a match
+	want := "note notes.md\n" +
+		"     1  a\n" +
+		"     2: match\n" +
+		"     3  b\n" +
+		"     4: match\n" +
+		"     5  c\n" +
+		"     6  d\n" +
+		"\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestSearchFileNoMatch(t *testing.T) {
+	path := writeSearchFile(t, t.TempDir(), "empty.sh", "echo hi\n")
+	re, _ := compileSearchQuery("curl", false, false)
+
+	var out bytes.Buffer
+	n, err := searchFile(&out, re, "script", "empty.sh", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || out.Len() != 0 {
+		t.Errorf("Expected no output for a file without matches, got %d matches and %q", n, out.String())
+	}
+}
+
+func TestSearchMatchesContentNotNames(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeSearchFile(t, GetScriptsDir(), "deploy.sh", "echo hello\n")
+	writeSearchFile(t, GetNotesDir(), "servers.md", "run deploy.sh on the build host\n")
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	err := search("deploy")
+	os.Stdout = stdout
+	w.Close()
+	data, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	if !strings.Contains(out, "note servers.md") {
+		t.Errorf("Expected the note mentioning deploy to match, got %q", out)
+	}
+	if strings.Contains(out, "script deploy.sh") {
+		t.Errorf("Expected a file matching only by name to be skipped, got %q", out)
+	}
+}
+
+func TestSearchNoMatches(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeSearchFile(t, GetScriptsDir(), "deploy.sh", "echo hello\n")
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	err := search("missing")
+	os.Stdout = stdout
+	w.Close()
+	data, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "No matches for 'missing'.") {
+		t.Errorf("Expected a no-matches message, got %q", data)
+	}
+}
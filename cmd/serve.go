@@ -0,0 +1,453 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	serveAddr  string
+	serveToken string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a local HTTP API for editor and tool integrations",
+	Long: `Expose berga over a token-protected REST API on localhost.
+
+Every request must send "Authorization: Bearer <token>". The token comes from
+--token, the serve.token config key, or is generated and printed at startup.
+
+Endpoints:
+  GET  /api/scripts                 list scripts
+  POST /api/scripts/{name}/run      run a script, streaming output as
+                                    server-sent events ({"args": [...]})
+  GET  /api/templates               list templates
+  POST /api/templates/{name}/render render a template ({"vars": {...}})
+  GET  /api/config                  read the effective configuration`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve(serveAddr, serveToken)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token (default: serve.token from config, or a random token)")
+}
+
+func serve(addr, token string) error {
+	if _, ok := keychainRef(token); ok {
+		return fmt.Errorf("--token must be the token itself, not a keychain reference")
+	}
+	if token == "" && viper.IsSet("serve.token") {
+		var err error
+		if token, err = requireSecret("serve.token"); err != nil {
+			return fmt.Errorf("refusing to start: %w", err)
+		}
+	}
+	if token == "" {
+		var err error
+		if token, err = randomToken(16); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("berga API listening on http://%s\n", addr)
+	fmt.Printf("Token: %s\n", token)
+	return http.ListenAndServe(addr, newAPIHandler(token))
+}
+
+// randomToken returns n random bytes, hex-encoded
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newAPIHandler returns the API routes wrapped in token authentication
+func newAPIHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/scripts", handleListScripts)
+	mux.HandleFunc("/api/scripts/", handleRunScript)
+	mux.HandleFunc("/api/templates", handleListTemplates)
+	mux.HandleFunc("/api/templates/", handleRenderTemplate)
+	mux.HandleFunc("/api/config", handleConfig)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		// An empty token would let in requests without one
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// apiItem describes a script or template in API listings
+type apiItem struct {
+	Name string   `json:"name"`
+	Size int64    `json:"size"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func handleListScripts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	items, err := listItems(GetScriptsDirs(), "script")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	items, err := listItems(GetTemplatesDirs(), "template")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// listItems describes the scripts or templates in dirs. A name found in an
+// earlier directory hides the same name in later ones.
+func listItems(dirs []string, kind string) ([]apiItem, error) {
+	index, err := loadTagIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []apiItem{}
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		files, err := readListing(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() || isSchemaFile(file.Name()) || kind == "script" && isScriptSpecFile(file.Name()) {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			name := file.Name()
+			if kind == "template" {
+				name = strings.TrimSuffix(name, ".tmpl")
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			items = append(items, apiItem{
+				Name: name,
+				Size: info.Size(),
+				Tags: tagsFor(index, kind, name, filepath.Join(dir, file.Name())),
+			})
+		}
+	}
+	return items, nil
+}
+
+// splitAction splits "/api/scripts/deploy.sh/run" into the item name and action
+func splitAction(path, prefix string) (string, string) {
+	rest := strings.TrimPrefix(path, prefix)
+	i := strings.LastIndex(rest, "/")
+	if i <= 0 {
+		return "", ""
+	}
+	return rest[:i], rest[i+1:]
+}
+
+// sseWriter serializes server-sent events from concurrent producers
+type sseWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+	f  http.Flusher
+}
+
+func (s *sseWriter) send(event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.f.Flush()
+}
+
+// streamLines calls send with each line read from r, without the line
+// ending. Lines may be any length, and r is always read to the end so the
+// process writing it never blocks on a full pipe.
+func streamLines(r io.Reader, send func(string)) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			send(strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			io.Copy(io.Discard, br)
+			return
+		}
+	}
+}
+
+func handleRunScript(w http.ResponseWriter, r *http.Request) {
+	name, action := splitAction(r.URL.Path, "/api/scripts/")
+	if action != "run" || name == "" {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body struct {
+		Args []string `json:"args"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+
+	storedPath, err := locateScript(name)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	scriptPath, cleanup, err := plainScriptPath(storedPath)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer cleanup()
+	if level := scriptDangerLevel(name, scriptPath); level != dangerLow {
+		writeJSONError(w, http.StatusForbidden, fmt.Sprintf("script is marked danger: %s and must be run from the command line", level))
+		return
+	}
+	if rule, ctx, err := checkScriptPolicy(name); err != nil {
+		writeJSONError(w, http.StatusForbidden, err.Error())
+		return
+	} else if rule != nil {
+		writeJSONError(w, http.StatusForbidden, fmt.Sprintf("script %s and must be run from the command line", rule.requirement(ctx)))
+		return
+	}
+	if err := requireKubeContext(name, scriptPath); err != nil {
+		writeJSONError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	// Declared dependencies live inside the image for container runs
+	if containerImage(scriptPath) == "" {
+		if err := checkScriptRequirements(name, scriptPath); err != nil {
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	hookNames, hookEnv := scriptHookNames(storedPath), scriptHookEnv(storedPath, body.Args)
+	if err := runConfiguredHooks(hookPreScriptRun, "script", hookNames, hookEnv); err != nil {
+		writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	recordUsage("script", filepath.Base(storedPath))
+
+	ctx, cancel := runContext(r.Context(), scriptRunTimeout())
+	defer cancel()
+
+	cmd := scriptCommand(ctx, scriptPath, body.Args)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	events := &sseWriter{w: w, f: flusher}
+
+	output := newHistoryOutput()
+	var wg sync.WaitGroup
+	stream := func(event string, rd io.Reader) {
+		defer wg.Done()
+		streamLines(rd, func(line string) {
+			if output != nil {
+				output.Write([]byte(line + "\n"))
+			}
+			events.send(event, line)
+		})
+	}
+	wg.Add(2)
+	go stream("stdout", stdout)
+	go stream("stderr", stderr)
+	wg.Wait()
+
+	params := map[string]string{"via": "api"}
+	if len(body.Args) > 0 {
+		params["args"] = strings.Join(body.Args, " ")
+	}
+	code := 0
+	err = cmd.Wait()
+	elapsed := time.Since(started)
+	recordTimedAudit("script run", name, params, err, elapsed)
+	recordRunOutput(name, body.Args, err, elapsed, output)
+	warnHookFailure(runConfiguredHooks(hookPostScriptRun, "script", hookNames, scriptResultEnv(hookEnv, err, elapsed)))
+	if err != nil {
+		code = -1
+		if cmd.ProcessState != nil {
+			code = cmd.ProcessState.ExitCode()
+		}
+	}
+	events.send("exit", map[string]int{"code": code})
+}
+
+func handleRenderTemplate(w http.ResponseWriter, r *http.Request) {
+	name, action := splitAction(r.URL.Path, "/api/templates/")
+	if action != "render" || name == "" {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body struct {
+		Vars map[string]interface{} `json:"vars"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+
+	templatePath, err := resolveTemplatePath(name)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	schema, err := loadTemplateSchema(templatePath)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tmpl, err := parseTemplateFile(templatePath, name)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	vars, _ := collectTemplateVars(nil, true)
+	for k, v := range body.Vars {
+		vars[k] = v
+	}
+	if schema != nil {
+		if err := collectSchemaVars(schema, vars, true); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to execute template: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"name": name, "content": out.String()})
+}
+
+// deleteSetting removes a dot-path key from nested settings maps
+func deleteSetting(settings map[string]interface{}, key string) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := settings[part].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings = next
+	}
+	delete(settings, parts[len(parts)-1])
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	settings := viper.AllSettings()
+	// Never hand the API token or other sensitive values back out
+	for _, key := range viper.AllKeys() {
+		if k, ok := config.LookupKey(key); ok && k.Sensitive {
+			deleteSetting(settings, key)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"config_file": viper.ConfigFileUsed(),
+		"settings":    settings,
+	})
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAPIRequiresToken(t *testing.T) {
+	handler := newAPIHandler("secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scripts", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/scripts", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/scripts", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec = httptest.NewRecorder()
+	newAPIHandler("").ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an empty token, got %d", rec.Code)
+	}
+}
+
+func TestAPIRenderTemplate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(GetTemplatesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(GetTemplatesDir(), "greet.tmpl"), []byte("hi {{.Name}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := newAPIHandler("secret")
+	req := httptest.NewRequest(http.MethodPost, "/api/templates/greet/render", strings.NewReader(`{"vars": {"Name": "berga"}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp["content"] != "hi berga" {
+		t.Errorf("Expected rendered content 'hi berga', got %q", resp["content"])
+	}
+}
+
+func TestSplitAction(t *testing.T) {
+	name, action := splitAction("/api/scripts/deploy.sh/run", "/api/scripts/")
+	if name != "deploy.sh" || action != "run" {
+		t.Errorf("Expected deploy.sh/run, got %s/%s", name, action)
+	}
+	if name, _ := splitAction("/api/scripts/deploy.sh", "/api/scripts/"); name != "" {
+		t.Errorf("Expected no name without an action, got %s", name)
+	}
+}
+
+func TestStreamLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	var got []string
+	streamLines(strings.NewReader("first\r\n"+long+"\nlast"), func(line string) {
+		got = append(got, line)
+	})
+	if len(got) != 3 || got[0] != "first" || got[1] != long || got[2] != "last" {
+		t.Errorf("Expected 3 lines with the long one intact, got %d lines", len(got))
+	}
+}
+
+func TestAPIRunScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	// A line past bufio.Scanner's 64 KB limit, then enough output to fill
+	// the pipe if it were no longer read
+	writeTestScript(t, "big.sh", "#!/bin/sh\nhead -c 100000 /dev/zero | tr '\\0' x; echo\nseq 1 100000\necho done\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/scripts/big.sh/run", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	newAPIHandler("secret").ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, strings.Repeat("x", 100000)) {
+		t.Error("Expected the long line to be streamed whole")
+	}
+	if !strings.Contains(body, `data: "done"`) || !strings.Contains(body, `data: {"code":0}`) {
+		t.Errorf("Expected the run to finish, got the tail %q", body[max(0, len(body)-200):])
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Script != "big.sh" || entries[0].ExitCode != 0 {
+		t.Errorf("Expected the API run in the history, got %+v", entries)
+	}
+}
+
+func TestAPIRunScriptRequirements(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "report.sh", "#!/bin/sh\n# berga:requires: berga-missing-tool\necho hi\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/scripts/report.sh/run", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	newAPIHandler("secret").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPreconditionFailed || !strings.Contains(rec.Body.String(), "berga-missing-tool") {
+		t.Errorf("Expected unmet requirements to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if entries, _ := loadHistory(); len(entries) != 0 {
+		t.Errorf("Expected a refused run not to be recorded, got %+v", entries)
+	}
+}
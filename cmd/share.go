@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultShareAddr listens on every interface, on a free port, so the link
+// works for colleagues on the same network
+const defaultShareAddr = ":0"
+
+// sharePasswordEnv is the environment variable a share password can be set
+// in. Unlike a flag value it stays out of the process list and shell history.
+const sharePasswordEnv = "BERGA_SHARE_PASSWORD"
+
+// shareTunnelTimeout is how long a tunnel command gets to print its URL
+const shareTunnelTimeout = 30 * time.Second
+
+var (
+	shareAddr           string
+	shareExpires        time.Duration
+	shareDownloads      int
+	sharePasswordPrompt bool
+	sharePasswordFile   string
+	shareTunnel         string
+	shareNoTunnel       bool
+)
+
+// tunnelURLPattern finds the public URL in a tunnel command's output
+var tunnelURLPattern = regexp.MustCompile(`https://[A-Za-z0-9.-]+(:[0-9]+)?`)
+
+// shareCmd hands files to other people over a temporary link
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share a script over a temporary link",
+}
+
+// shareScriptCmd serves one script until it has been downloaded
+var shareScriptCmd = &cobra.Command{
+	Use:   "script [script-name]",
+	Short: "Serve a script from a short-lived link with a one-time token",
+	Long: `Start a temporary HTTP server that serves a script at a URL holding a random
+token, and print the URL to hand to a colleague:
+
+  berga share script deploy.sh
+  curl -fsSO http://192.168.1.20:40213/3f9a.../deploy.sh
+
+The server stops once the script has been downloaded (--downloads times), when
+--expires passes, or on Ctrl-C. A password can be required too, through HTTP
+basic auth (curl -u :<password>): --password asks for it without echoing it,
+--password-file reads it from a file, and otherwise BERGA_SHARE_PASSWORD is
+used when set.
+
+To reach someone outside your network, set a tunnel command with {port} where
+the local port goes; berga starts it, takes the first https:// URL it prints,
+and stops it with the server:
+
+  berga config set share.tunnel "cloudflared tunnel --url http://localhost:{port}"
+
+Encrypted scripts cannot be shared.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeScriptNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if shareDownloads < 1 {
+			return fmt.Errorf("--downloads must be at least 1")
+		}
+		if shareAddr == "" {
+			shareAddr = viper.GetString("share.addr")
+		}
+		if shareAddr == "" {
+			shareAddr = defaultShareAddr
+		}
+		if shareTunnel == "" && !shareNoTunnel {
+			shareTunnel = viper.GetString("share.tunnel")
+		}
+		return shareScript(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shareCmd)
+	shareCmd.AddCommand(shareScriptCmd)
+
+	// Flags
+	shareScriptCmd.Flags().StringVar(&shareAddr, "addr", "", "Address to listen on (default: share.addr from config, or every interface on a free port)")
+	shareScriptCmd.Flags().DurationVar(&shareExpires, "expires", 15*time.Minute, "Stop serving after this long")
+	shareScriptCmd.Flags().IntVar(&shareDownloads, "downloads", 1, "Stop serving after this many downloads")
+	shareScriptCmd.Flags().BoolVar(&sharePasswordPrompt, "password", false, "Ask for a password to require as well (HTTP basic auth)")
+	shareScriptCmd.Flags().StringVar(&sharePasswordFile, "password-file", "", "Require the password in this file as well (HTTP basic auth)")
+	shareScriptCmd.Flags().StringVar(&shareTunnel, "tunnel", "", "Command that exposes {port} publicly (default: share.tunnel from config)")
+	shareScriptCmd.Flags().BoolVar(&shareNoTunnel, "no-tunnel", false, "Do not start the share.tunnel command")
+}
+
+// shareHandler serves one file at /<token>/<name>. Once it has been
+// downloaded downloads times, done is closed and further requests fail.
+type shareHandler struct {
+	name      string
+	content   []byte
+	token     string
+	password  string
+	downloads int
+
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+func newShareHandler(name string, content []byte, token, password string, downloads int) *shareHandler {
+	return &shareHandler{
+		name:      name,
+		content:   content,
+		token:     token,
+		password:  password,
+		downloads: downloads,
+		done:      make(chan struct{}),
+	}
+}
+
+// path is the URL path the file is served at
+func (h *shareHandler) path() string {
+	return "/" + h.token + "/" + url.PathEscape(h.name)
+}
+
+func (h *shareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// A wrong token looks the same as a missing file
+	if subtle.ConstantTimeCompare([]byte(r.URL.EscapedPath()), []byte(h.path())) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	if h.password != "" {
+		_, given, _ := r.BasicAuth()
+		if subtle.ConstantTimeCompare([]byte(given), []byte(h.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="berga share"`)
+			http.Error(w, "password required", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	h.mu.Lock()
+	if h.downloads == 0 {
+		h.mu.Unlock()
+		http.Error(w, "this link has already been used", http.StatusGone)
+		return
+	}
+	if r.Method == http.MethodGet {
+		h.downloads--
+	}
+	last := h.downloads == 0
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", h.name))
+	w.Header().Set("Content-Length", strconv.Itoa(len(h.content)))
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodGet {
+		w.Write(h.content)
+		fmt.Fprintf(os.Stderr, "Downloaded by %s\n", r.RemoteAddr)
+	}
+	if last {
+		close(h.done)
+	}
+}
+
+// shareURLs returns the URLs the server can be reached at: the listen host
+// when one was given, otherwise each non-loopback IPv4 address of the machine
+func shareURLs(addr net.Addr, host, path string) []string {
+	port := strconv.Itoa(addr.(*net.TCPAddr).Port)
+	if host != "" && host != "0.0.0.0" && host != "::" {
+		return []string{"http://" + net.JoinHostPort(host, port) + path}
+	}
+
+	var urls []string
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+				continue
+			}
+			urls = append(urls, "http://"+net.JoinHostPort(ipNet.IP.String(), port)+path)
+		}
+	}
+	return append(urls, "http://"+net.JoinHostPort("localhost", port)+path)
+}
+
+// startTunnel runs a tunnel command for port and returns the public URL it
+// prints, and a function that stops it
+func startTunnel(command string, port int) (string, func(), error) {
+	command = strings.ReplaceAll(command, "{port}", strconv.Itoa(port))
+	ctx, cancel := context.WithCancel(context.Background())
+	tunnel := hookCommandContext(ctx, command)
+	out, err := tunnel.StdoutPipe()
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	// Tunnel tools differ on where they print the URL, so read both streams
+	tunnel.Stderr = tunnel.Stdout
+	if err := tunnel.Start(); err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("failed to start tunnel: %w", err)
+	}
+	stop := func() {
+		cancel()
+		tunnel.Wait()
+	}
+
+	found := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			if match := tunnelURLPattern.FindString(scanner.Text()); match != "" {
+				found <- match
+				break
+			}
+		}
+		// Keep draining so the tunnel never blocks on a full pipe
+		io.Copy(io.Discard, out)
+	}()
+
+	select {
+	case publicURL := <-found:
+		return publicURL, stop, nil
+	case <-time.After(shareTunnelTimeout):
+		stop()
+		return "", nil, fmt.Errorf("tunnel printed no https:// URL within %s", shareTunnelTimeout)
+	}
+}
+
+// readSharePassword returns the password downloads need, or "" for none: the
+// contents of --password-file, an answer to a prompt with --password, or
+// BERGA_SHARE_PASSWORD. It is never taken as a flag value, which other users
+// could read from the process list.
+func readSharePassword() (string, error) {
+	var password string
+	switch {
+	case sharePasswordFile != "":
+		data, err := os.ReadFile(expandHome(sharePasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	case sharePasswordPrompt:
+		if promptsDisabled() {
+			return "", fmt.Errorf("--password needs an interactive terminal; use --password-file or %s", sharePasswordEnv)
+		}
+		var err error
+		if password, err = promptLine("Password", "", true); err != nil {
+			return "", err
+		}
+	default:
+		return os.Getenv(sharePasswordEnv), nil
+	}
+	if password == "" {
+		return "", fmt.Errorf("the password is empty")
+	}
+	return password, nil
+}
+
+func shareScript(scriptName string) error {
+	scriptPath := resolveScriptPath(scriptName)
+	if isEncryptedScript(scriptPath) {
+		return fmt.Errorf("script '%s' is encrypted and cannot be shared", scriptName)
+	}
+	content, err := os.ReadFile(scriptPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("script '%s' not found in %s", scriptName, GetScriptsDir())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	password, err := readSharePassword()
+	if err != nil {
+		return err
+	}
+	token, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	handler := newShareHandler(filepath.Base(scriptPath), content, token, password, shareDownloads)
+
+	host, _, err := net.SplitHostPort(shareAddr)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", shareAddr, err)
+	}
+	listener, err := net.Listen("tcp", shareAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", shareAddr, err)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	urls := shareURLs(listener.Addr(), host, handler.path())
+	if shareTunnel != "" {
+		publicURL, stop, err := startTunnel(shareTunnel, listener.Addr().(*net.TCPAddr).Port)
+		if err != nil {
+			server.Close()
+			return err
+		}
+		defer stop()
+		urls = []string{strings.TrimSuffix(publicURL, "/") + handler.path()}
+	}
+
+	fmt.Printf("Sharing '%s' until it has been downloaded", scriptName)
+	if shareDownloads > 1 {
+		fmt.Printf(" %d times", shareDownloads)
+	}
+	fmt.Printf(" or %s has passed (Ctrl-C to stop)\n", shareExpires)
+	for _, u := range urls {
+		fmt.Printf("  %s\n", u)
+	}
+	download := "curl -fsSO " + urls[0]
+	if password != "" {
+		download = "curl -fsSO -u :<password> " + urls[0]
+	}
+	fmt.Println(ui.Dim("Download with: " + download))
+	if shareTunnel == "" && !isLoopbackAddr(shareAddr) {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow("Warning: the link is plain HTTP; anyone on the network path can read the script"))
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	var stopErr error
+	select {
+	case <-handler.done:
+		fmt.Println(ui.Green(ui.Icon("✓", "ok")) + " Downloaded; link closed")
+	case <-time.After(shareExpires):
+		stopErr = fmt.Errorf("link expired after %s without being used", shareExpires)
+	case <-interrupt:
+		fmt.Println("Stopped sharing")
+	case err := <-served:
+		return fmt.Errorf("share server failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	return stopErr
+}
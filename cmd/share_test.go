@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestShareHandler(t *testing.T) {
+	h := newShareHandler("deploy.sh", []byte("echo hi\n"), "abc123", "secret", 1)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	get := func(path, password string) int {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if password != "" {
+			req.SetBasicAuth("", password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("/wrong/deploy.sh", "secret"); code != http.StatusNotFound {
+		t.Errorf("Expected a wrong token to get 404, got %d", code)
+	}
+	if code := get(h.path(), ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected a missing password to get 401, got %d", code)
+	}
+	if code := get(h.path(), "secret"); code != http.StatusOK {
+		t.Errorf("Expected the download to succeed, got %d", code)
+	}
+	select {
+	case <-h.done:
+	default:
+		t.Error("Expected the handler to be done after the last download")
+	}
+	if code := get(h.path(), "secret"); code != http.StatusGone {
+		t.Errorf("Expected a used link to get 410, got %d", code)
+	}
+}
+
+func TestStartTunnel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell command")
+	}
+	publicURL, stop, err := startTunnel(`echo "starting on {port}"; echo "ready at https://abc.example.com/ ok" >&2; exec sleep 30`, 4242)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if publicURL != "https://abc.example.com" {
+		t.Errorf("Expected the tunnel's URL, got %q", publicURL)
+	}
+}
+
+// setSharePassword sets the share password flags for the duration of the test
+func setSharePassword(t *testing.T, prompt bool, file string) {
+	t.Helper()
+	oldPrompt, oldFile := sharePasswordPrompt, sharePasswordFile
+	sharePasswordPrompt, sharePasswordFile = prompt, file
+	t.Cleanup(func() { sharePasswordPrompt, sharePasswordFile = oldPrompt, oldFile })
+}
+
+func TestReadSharePassword(t *testing.T) {
+	setTerminal(t, false)
+	t.Setenv(sharePasswordEnv, "")
+
+	if password, err := readSharePassword(); err != nil || password != "" {
+		t.Errorf("Expected no password by default, got %q, %v", password, err)
+	}
+	t.Setenv(sharePasswordEnv, "from-env")
+	if password, _ := readSharePassword(); password != "from-env" {
+		t.Errorf("Expected the password from %s, got %q", sharePasswordEnv, password)
+	}
+
+	file := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(file, []byte("from-file\n"), 0600)
+	setSharePassword(t, false, file)
+	if password, err := readSharePassword(); err != nil || password != "from-file" {
+		t.Errorf("Expected the password file without its newline, got %q, %v", password, err)
+	}
+	os.WriteFile(file, []byte("\n"), 0600)
+	if _, err := readSharePassword(); err == nil {
+		t.Error("Expected an empty password file to be refused")
+	}
+
+	// Without a terminal there is nobody to ask
+	setSharePassword(t, true, "")
+	if _, err := readSharePassword(); err == nil {
+		t.Error("Expected --password to need a terminal")
+	}
+}
@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	shellInitInstall bool
+	shellInitPrompt  bool
+	cdList           bool
+)
+
+// supportedShells are the shells 'shell-init' generates code for
+var supportedShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// aliasNamePattern matches alias names that are safe to define in every shell
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// cdCmd prints the path of a berga-managed location
+var cdCmd = &cobra.Command{
+	Use:   "cd [location|bookmark]",
+	Short: "Print the path of a berga location or bookmark",
+	Long: `Print the directory of a berga location so a shell function can change into
+it. Locations are scripts (the default), templates, snippets, notes, cheats,
+dotfiles, config, data, and project; any other name is looked up as a bookmark.
+
+'berga shell-init' defines a bcd function that uses this:
+
+  bcd scripts
+  bcd my-bookmark`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if cdList {
+			return printCdTargets()
+		}
+		target := ""
+		if len(args) > 0 {
+			target = args[0]
+		}
+		dir, err := resolveCdTarget(target)
+		if err != nil {
+			return err
+		}
+		fmt.Println(dir)
+		return nil
+	},
+}
+
+// shellInitCmd prints shell integration code
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init [bash|zsh|fish|powershell]",
+	Short: "Print shell functions for navigation, aliases, and the prompt",
+	Long: `Print shell code that integrates berga with your shell:
+
+  - bcd, to change into berga locations and bookmarks (bcd scripts, bcd <bookmark>)
+  - an alias for each entry under aliases in the config (ll: "script list")
+  - a prompt hook that sets BERGA_PROMPT to the active profile and project,
+    for use in your own prompt, and BERGA_LAST_COMMAND to the command that
+    just ran, for 'berga grab'
+
+Load it from your shell's rc file, or let --install add the line for you:
+
+  eval "$(berga shell-init bash)"          # ~/.bashrc
+  eval "$(berga shell-init zsh)"           # ~/.zshrc
+  berga shell-init fish | source           # ~/.config/fish/config.fish
+  Invoke-Expression (& berga shell-init powershell | Out-String)   # $PROFILE
+
+The shell defaults to the one in $SHELL.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if shellInitPrompt {
+			if segment := promptSegment(); segment != "" {
+				fmt.Println(segment)
+			}
+			return nil
+		}
+
+		shell, err := initShell(args)
+		if err != nil {
+			return err
+		}
+		if shellInitInstall {
+			return installShellInit(shell)
+		}
+		fmt.Print(shellInitScript(shell, shellAliases()))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cdCmd)
+	rootCmd.AddCommand(shellInitCmd)
+
+	// Flags
+	cdCmd.Flags().BoolVar(&cdList, "list", false, "List location and bookmark names")
+	shellInitCmd.Flags().BoolVar(&shellInitInstall, "install", false, "Add the integration to your shell's rc file")
+	shellInitCmd.Flags().BoolVar(&shellInitPrompt, "prompt", false, "Print the prompt segment (used by the prompt hook)")
+	shellInitCmd.Flags().MarkHidden("prompt")
+}
+
+// cdLocations returns berga's own directories by name
+func cdLocations() map[string]string {
+	locations := map[string]string{
+		"config":    GetConfigDir(),
+		"data":      GetDataDir(),
+		"scripts":   GetScriptsDir(),
+		"templates": GetTemplatesDir(),
+		"snippets":  GetSnippetsDir(),
+		"notes":     GetNotesDir(),
+		"cheats":    GetCheatsDir(),
+		"dotfiles":  GetDotfilesDir(),
+	}
+	if project != nil {
+		locations["project"] = project.Root
+	}
+	return locations
+}
+
+// resolveCdTarget returns the directory for a location or bookmark name
+func resolveCdTarget(target string) (string, error) {
+	if target == "" {
+		target = "scripts"
+	}
+	if dir, ok := cdLocations()[target]; ok {
+		return dir, nil
+	}
+
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return "", err
+	}
+	bookmark, err := findBookmark(bookmarks, target)
+	if err != nil {
+		return "", fmt.Errorf("'%s' is neither a berga location nor a bookmark", target)
+	}
+	if bookmark.IsURL() {
+		return "", fmt.Errorf("bookmark '%s' is a URL, not a path", target)
+	}
+	return bookmark.Target, nil
+}
+
+// printCdTargets lists every name 'berga cd' accepts, for shell completion
+func printCdTargets() error {
+	var names []string
+	for name := range cdLocations() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if !b.IsURL() {
+			names = append(names, b.Name)
+		}
+	}
+	fmt.Println(strings.Join(names, "\n"))
+	return nil
+}
+
+// initShell picks the shell from the argument or $SHELL
+func initShell(args []string) (string, error) {
+	shell := ""
+	if len(args) > 0 {
+		shell = args[0]
+	} else if env := os.Getenv("SHELL"); env != "" {
+		shell = filepath.Base(env)
+	} else if runtime.GOOS == "windows" {
+		shell = "powershell"
+	}
+	if shell == "pwsh" {
+		shell = "powershell"
+	}
+	for _, s := range supportedShells {
+		if shell == s {
+			return shell, nil
+		}
+	}
+	if shell == "" {
+		return "", fmt.Errorf("cannot detect your shell; pass one of: %s", strings.Join(supportedShells, ", "))
+	}
+	return "", fmt.Errorf("unsupported shell '%s' (expected %s)", shell, strings.Join(supportedShells, ", "))
+}
+
+// shellAliases returns the aliases from the config, skipping names that are
+// not valid in every shell
+func shellAliases() map[string]string {
+	aliases := make(map[string]string)
+	for name, value := range viper.GetStringMapString("aliases") {
+		if aliasNamePattern.MatchString(name) && strings.TrimSpace(value) != "" {
+			aliases[name] = strings.TrimSpace(value)
+		} else if viper.GetBool("verbose") {
+			fmt.Fprintf(os.Stderr, "Skipping alias '%s'\n", name)
+		}
+	}
+	return aliases
+}
+
+// promptSegment is the text the prompt hook puts in BERGA_PROMPT: the active
+// profile unless it is the default, and the project directory's name
+func promptSegment() string {
+	var parts []string
+	if name, _ := activeProfile(); name != defaultProfile {
+		parts = append(parts, name)
+	}
+	if project != nil {
+		parts = append(parts, filepath.Base(project.Root))
+	}
+	return strings.Join(parts, ":")
+}
+
+// posixQuote single-quotes s for bash, zsh, and other POSIX shells
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// powershellQuote single-quotes s for PowerShell
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// shellInitScript generates the integration code for a shell
+func shellInitScript(shell string, aliases map[string]string) string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# berga shell integration for %s, generated by 'berga shell-init %s'\n", shell, shell)
+	switch shell {
+	case "bash", "zsh":
+		b.WriteString(`bcd() {
+  local dir
+  dir="$(command berga cd "$@")" || return
+  builtin cd -- "$dir"
+}
+__berga_prompt() {
+  export BERGA_LAST_COMMAND="$(fc -ln -1 2>/dev/null)"
+  BERGA_PROMPT="$(command berga shell-init --prompt 2>/dev/null)"
+}
+`)
+		if shell == "bash" {
+			b.WriteString(`_berga_bcd_complete() {
+  COMPREPLY=($(compgen -W "$(command berga cd --list 2>/dev/null)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -F _berga_bcd_complete bcd
+case ";${PROMPT_COMMAND:-};" in
+  *";__berga_prompt;"*) ;;
+  *) PROMPT_COMMAND="__berga_prompt${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`)
+		} else {
+			b.WriteString(`_berga_bcd() {
+  compadd -- ${(f)"$(command berga cd --list 2>/dev/null)"}
+}
+(( $+functions[compdef] )) && compdef _berga_bcd bcd
+autoload -Uz add-zsh-hook && add-zsh-hook precmd __berga_prompt
+`)
+		}
+		for _, name := range names {
+			fmt.Fprintf(&b, "alias %s=%s\n", name, posixQuote("berga "+aliases[name]))
+		}
+	case "fish":
+		b.WriteString(`function bcd
+    set -l dir (command berga cd $argv); or return
+    builtin cd -- $dir
+end
+complete -c bcd -f -a '(command berga cd --list 2>/dev/null)'
+function __berga_prompt --on-event fish_prompt
+    set -gx BERGA_LAST_COMMAND $history[1]
+    set -gx BERGA_PROMPT (command berga shell-init --prompt 2>/dev/null)
+end
+`)
+		for _, name := range names {
+			fmt.Fprintf(&b, "alias %s %s\n", name, fishQuote("berga "+aliases[name]))
+		}
+	case "powershell":
+		b.WriteString(`function bcd {
+    param([string]$Target)
+    $dir = & berga cd $Target
+    if ($LASTEXITCODE -eq 0) { Set-Location -LiteralPath $dir }
+}
+Register-ArgumentCompleter -CommandName bcd -ParameterName Target -ScriptBlock {
+    param($commandName, $parameterName, $word)
+    & berga cd --list 2>$null | Where-Object { $_ -like "$word*" }
+}
+if (-not (Test-Path Function:\__berga_original_prompt)) {
+    $function:__berga_original_prompt = $function:prompt
+}
+function prompt {
+    $env:BERGA_LAST_COMMAND = (Get-History -Count 1).CommandLine
+    $env:BERGA_PROMPT = (& berga shell-init --prompt 2>$null)
+    & $function:__berga_original_prompt
+}
+`)
+		for _, name := range names {
+			var words []string
+			for _, w := range strings.Fields(aliases[name]) {
+				words = append(words, powershellQuote(w))
+			}
+			fmt.Fprintf(&b, "function %s { & berga %s @args }\n", name, strings.Join(words, " "))
+		}
+	}
+	return b.String()
+}
+
+// shellRCFile returns the file --install adds the integration to
+func shellRCFile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			return filepath.Join(dir, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "fish":
+		return filepath.Join(xdgDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), "fish", "config.fish"), nil
+	default:
+		if runtime.GOOS == "windows" {
+			return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"), nil
+		}
+		return filepath.Join(xdgDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), "powershell", "Microsoft.PowerShell_profile.ps1"), nil
+	}
+}
+
+// shellInitLine is the rc file line that loads the integration
+func shellInitLine(shell string) string {
+	switch shell {
+	case "fish":
+		return "berga shell-init fish | source"
+	case "powershell":
+		return "Invoke-Expression (& berga shell-init powershell | Out-String)"
+	}
+	return fmt.Sprintf(`eval "$(berga shell-init %s)"`, shell)
+}
+
+// shellCompletionLine is the rc file line that loads berga's completion
+func shellCompletionLine(shell string) string {
+	switch shell {
+	case "fish":
+		return "berga completion fish | source"
+	case "powershell":
+		return "berga completion powershell | Out-String | Invoke-Expression"
+	}
+	return fmt.Sprintf("source <(berga completion %s)", shell)
+}
+
+// installShellInit adds the loading line to the shell's rc file inside a
+// marked block, so installing again leaves a single copy
+func installShellInit(shell string) error {
+	return installRCLine(shell, "shell-init", shellInitLine(shell), "berga shell integration")
+}
+
+// installShellCompletion adds the line loading completion to the shell's rc
+// file, like installShellInit
+func installShellCompletion(shell string) error {
+	return installRCLine(shell, "completion", shellCompletionLine(shell), "berga completion")
+}
+
+// installRCLine adds line to the shell's rc file in the block named block,
+// replacing an earlier copy of that block
+func installRCLine(shell, block, line, what string) error {
+	rcFile, err := shellRCFile(shell)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", rcFile, err)
+	}
+
+	merged, err := mergeContent(string(existing), line, mergeAppend, block, commentStyle{Prefix: "#"})
+	if err != nil {
+		return err
+	}
+	if merged == string(existing) {
+		fmt.Printf("%s is already in %s\n", what, rcFile)
+		return nil
+	}
+
+	// Write through symlinks, such as an rc file managed by 'berga dotfiles',
+	// and keep the existing file's permissions
+	target := rcFile
+	if resolved, err := filepath.EvalSymlinks(rcFile); err == nil {
+		target = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+	if err := writeFileAtomic(target, []byte(merged), perm); err != nil {
+		return fmt.Errorf("failed to update %s: %w", rcFile, err)
+	}
+	fmt.Printf("Added %s to %s; open a new shell to use it\n", what, rcFile)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShellInitScript(t *testing.T) {
+	aliases := map[string]string{"ll": "script list", "qt": "template apply it's"}
+	tests := map[string][]string{
+		"bash":       {"bcd() {", "complete -F _berga_bcd_complete bcd", `alias ll='berga script list'`, `alias qt='berga template apply it'\''s'`},
+		"zsh":        {"bcd() {", "add-zsh-hook precmd __berga_prompt", `alias ll='berga script list'`},
+		"fish":       {"function bcd", "--on-event fish_prompt", `alias qt 'berga template apply it\'s'`},
+		"powershell": {"function bcd {", "function prompt {", "function qt { & berga 'template' 'apply' 'it''s' @args }"},
+	}
+	for shell, wants := range tests {
+		script := shellInitScript(shell, aliases)
+		for _, want := range wants {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s script is missing %q:\n%s", shell, want, script)
+			}
+		}
+	}
+}
+
+func TestShellInitScriptSyntax(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, "-n")
+		cmd.Stdin = strings.NewReader(shellInitScript(shell, map[string]string{"ll": "script list"}))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s rejected the script: %v\n%s", shell, err, out)
+		}
+	}
+}
+
+func TestInitShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	if shell, err := initShell(nil); err != nil || shell != "zsh" {
+		t.Errorf("initShell() from $SHELL = %s, %v", shell, err)
+	}
+	if shell, err := initShell([]string{"pwsh"}); err != nil || shell != "powershell" {
+		t.Errorf("initShell(pwsh) = %s, %v", shell, err)
+	}
+	if _, err := initShell([]string{"tcsh"}); err == nil {
+		t.Error("Expected an unsupported shell to be rejected")
+	}
+}
+
+func TestResolveCdTarget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if dir, err := resolveCdTarget(""); err != nil || dir != GetScriptsDir() {
+		t.Errorf("resolveCdTarget(\"\") = %s, %v", dir, err)
+	}
+
+	target := t.TempDir()
+	if err := addBookmark("work", target, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := addBookmark("docs", "https://example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := resolveCdTarget("work"); err != nil || dir != target {
+		t.Errorf("resolveCdTarget(work) = %s, %v", dir, err)
+	}
+	if _, err := resolveCdTarget("docs"); err == nil {
+		t.Error("Expected a URL bookmark to be rejected")
+	}
+	if _, err := resolveCdTarget("nowhere"); err == nil {
+		t.Error("Expected an unknown name to be rejected")
+	}
+}
+
+func TestInstallShellInit(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	rc := filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(rc, []byte("export EDITOR=vim\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := installShellInit("bash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(rc)
+	if strings.Count(string(data), `eval "$(berga shell-init bash)"`) != 1 || !strings.HasPrefix(string(data), "export EDITOR=vim\n") {
+		t.Errorf("Unexpected rc file after installing twice:\n%s", data)
+	}
+	if info, _ := os.Stat(rc); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the rc file to keep its permissions, got %v", info.Mode().Perm())
+	}
+}
+
+func TestInstallShellInitSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ZDOTDIR", "")
+	managed := filepath.Join(home, "dotfiles", "zshrc")
+	os.MkdirAll(filepath.Dir(managed), 0755)
+	if err := os.WriteFile(managed, []byte("setopt autocd\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rc := filepath.Join(home, ".zshrc")
+	if err := os.Symlink(managed, rc); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := installShellInit("zsh"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(rc); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("Expected ~/.zshrc to stay a symlink")
+	}
+	data, _ := os.ReadFile(managed)
+	if !strings.Contains(string(data), "berga shell-init zsh") {
+		t.Errorf("Expected the line in the link's target, got:\n%s", data)
+	}
+	if info, _ := os.Stat(managed); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the target to keep its permissions, got %v", info.Mode().Perm())
+	}
+}
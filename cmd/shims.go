@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/scripts"
+
+	"github.com/spf13/cobra"
+)
+
+// shimMarker is on the second line of every shim, so 'shims install' only
+// ever replaces or removes files it wrote
+const shimMarker = "berga shim"
+
+// shimsCmd groups the commands managing PATH shims for scripts
+var shimsCmd = &cobra.Command{
+	Use:   "shims",
+	Short: "Run scripts by name from any shell",
+	Long: `Manage shims: small executables in ~/.berga/bin, one per script, that run
+'berga script run <script>' with the arguments they get. With that directory on
+your PATH, scripts can be run directly:
+
+  berga shims install
+  export PATH="$HOME/.berga/bin:$PATH"     # in ~/.bashrc or ~/.zshrc
+  deploy --dry-run                         # berga script run deploy.sh -- --dry-run`,
+}
+
+var shimsInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Write a shim for every script",
+	Long: `Write a shim for every script in the scripts search paths, named after the
+script without its extension. Shims of scripts that are gone are removed, and
+a name two scripts share goes to the one 'script run' finds first. Run it again
+after adding or renaming scripts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("shims", installShims)
+	},
+}
+
+var shimsRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove every shim",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return withLock("shims", func() error {
+			removed, err := removeShims(nil)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d shims from %s\n", removed, GetShimsDir())
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shimsCmd)
+	shimsCmd.AddCommand(shimsInstallCmd)
+	shimsCmd.AddCommand(shimsRemoveCmd)
+}
+
+// shimName is the command a script's shim is called: its name without the
+// extensions, so deploy.sh and deploy.sh.age both become deploy
+func shimName(scriptName string) string {
+	name := strings.TrimSuffix(scriptName, scripts.EncryptedExt)
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// shimFileName is the file a shim is written to
+func shimFileName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".cmd"
+	}
+	return name
+}
+
+// shimScripts maps each shim name to the script it runs. Scripts earlier in
+// the search path win, as they do for 'script run'.
+func shimScripts() map[string]string {
+	shims := make(map[string]string)
+	refreshListings(GetScriptsDirs())
+	for _, dir := range GetScriptsDirs() {
+		files, err := readListing(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || isScriptSpecFile(file.Name()) {
+				continue
+			}
+			// A shim called berga would hide berga itself
+			name := shimName(file.Name())
+			if name == "" || name == "berga" || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if script, ok := shims[name]; ok {
+				if script != strings.TrimSuffix(file.Name(), scripts.EncryptedExt) {
+					fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: shim '%s' runs %s; %s is skipped", name, script, filepath.Join(dir, file.Name()))))
+				}
+				continue
+			}
+			shims[name] = strings.TrimSuffix(file.Name(), scripts.EncryptedExt)
+		}
+	}
+	return shims
+}
+
+// shimContent is the shim running script through the berga at self
+func shimContent(self, script string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("@echo off\r\nrem %s for %s, written by 'berga shims install'\r\n\"%s\" script run \"%s\" -- %%*\r\n", shimMarker, script, self, script)
+	}
+	return fmt.Sprintf("#!/bin/sh\n# %s for %s, written by 'berga shims install'\nexec %s script run %s -- \"$@\"\n", shimMarker, script, posixQuote(self), posixQuote(script))
+}
+
+// isShim reports whether path is a file 'shims install' wrote
+func isShim(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	return scanner.Scan() && scanner.Scan() && strings.Contains(scanner.Text(), shimMarker)
+}
+
+// removeShims deletes the shims in the shims directory, except those in
+// keep, and returns how many it removed. Other files are left alone.
+func removeShims(keep map[string]bool) (int, error) {
+	entries, err := os.ReadDir(GetShimsDir())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read shims directory: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(GetShimsDir(), entry.Name())
+		if keep[entry.Name()] || entry.IsDir() || !isShim(path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove shim: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func installShims() error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the berga executable: %w", err)
+	}
+	if err := os.MkdirAll(GetShimsDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create shims directory: %w", err)
+	}
+
+	shims := shimScripts()
+	names := make([]string, 0, len(shims))
+	keep := make(map[string]bool)
+	for name := range shims {
+		names = append(names, name)
+		keep[shimFileName(name)] = true
+	}
+	sort.Strings(names)
+
+	written := 0
+	for _, name := range names {
+		path := filepath.Join(GetShimsDir(), shimFileName(name))
+		if _, err := os.Stat(path); err == nil && !isShim(path) {
+			fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: %s exists and is not a shim; leaving it alone", path)))
+			continue
+		}
+		if err := writeFileAtomic(path, []byte(shimContent(self, shims[name])), 0755); err != nil {
+			return fmt.Errorf("failed to write shim: %w", err)
+		}
+		written++
+	}
+	removed, err := removeShims(keep)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d shims to %s", written, GetShimsDir())
+	if removed > 0 {
+		fmt.Printf(" and removed %d stale ones", removed)
+	}
+	fmt.Println()
+	if !onPath(GetShimsDir()) {
+		fmt.Printf("Add %s to your PATH to run scripts by name.\n", GetShimsDir())
+	}
+	return nil
+}
+
+// onPath reports whether dir is one of the directories in $PATH
+func onPath(dir string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(entry) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShimName(t *testing.T) {
+	tests := map[string]string{
+		"deploy.sh":     "deploy",
+		"deploy.sh.age": "deploy",
+		"Makefile":      "Makefile",
+		"db.backup.py":  "db.backup",
+	}
+	for in, want := range tests {
+		if got := shimName(in); got != want {
+			t.Errorf("shimName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestInstallShims(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs a POSIX shim")
+	}
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "greet.sh", "#!/bin/sh\necho \"hi $1\"\n")
+	writeTestScript(t, "greet.py", "#!/usr/bin/env python3\n")
+	writeTestScript(t, "berga.sh", "#!/bin/sh\n")
+
+	os.MkdirAll(GetShimsDir(), 0755)
+	stale := filepath.Join(GetShimsDir(), "gone")
+	os.WriteFile(stale, []byte(shimContent("/bin/berga", "gone.sh")), 0755)
+	mine := filepath.Join(GetShimsDir(), "tool")
+	os.WriteFile(mine, []byte("#!/bin/sh\n"), 0755)
+
+	if err := installShims(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(GetShimsDir())
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "greet,tool" {
+		t.Errorf("Expected the greet shim and the untouched tool, got %v", names)
+	}
+	data, _ := os.ReadFile(filepath.Join(GetShimsDir(), "greet"))
+	if !strings.Contains(string(data), "script run 'greet.py' -- \"$@\"") {
+		t.Errorf("Expected the first script in listing order to win, got:\n%s", data)
+	}
+
+	// The shim passes its arguments on
+	shim := filepath.Join(t.TempDir(), "echo-shim")
+	os.WriteFile(shim, []byte(shimContent("/bin/echo", "greet.sh")), 0755)
+	out, err := exec.Command(shim, "a b", "--flag").Output()
+	if err != nil || string(out) != "script run greet.sh -- a b --flag\n" {
+		t.Errorf("Unexpected shim output %q %v", out, err)
+	}
+
+	if removed, err := removeShims(nil); err != nil || removed != 1 {
+		t.Errorf("Expected one shim to be removed, got %d %v", removed, err)
+	}
+	if _, err := os.Stat(mine); err != nil {
+		t.Error("Expected a file berga didn't write to be kept")
+	}
+}
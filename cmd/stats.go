@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// ScriptStats summarizes how one script has been used. Runs counts every run;
+// failures and durations only cover the runs recorded in the audit log.
+type ScriptStats struct {
+	Name               string    `json:"name"`
+	Runs               int       `json:"runs"`
+	Recorded           int       `json:"recorded_runs"`
+	Failures           int       `json:"failures"`
+	FailureRate        float64   `json:"failure_rate"`
+	AvgDurationSeconds float64   `json:"avg_duration_seconds"`
+	LastUsed           time.Time `json:"last_used"`
+}
+
+// MonthCount is a number of events in one month (2006-01)
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// StorageUsage is the disk space one kind of berga content takes
+type StorageUsage struct {
+	Category string `json:"category"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// UsageReport is the output of 'berga stats'
+type UsageReport struct {
+	Scripts           []ScriptStats  `json:"scripts"`
+	TemplatesPerMonth []MonthCount   `json:"templates_per_month"`
+	Storage           []StorageUsage `json:"storage"`
+}
+
+var (
+	statsJSON bool
+	statsTop  int
+)
+
+// statsCmd shows aggregate usage numbers
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show usage statistics",
+	Long: `Show the most-run scripts with their average run time and failure rate,
+the templates applied each month, and the disk space each kind of content
+takes. Run counts come from usage tracking; durations, failures, and template
+applies come from the audit log, so they only cover what it recorded.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		report, err := buildUsageReport(statsTop)
+		if err != nil {
+			return err
+		}
+		if statsJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+		printUsageReport(report)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	// Flags
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+	statsCmd.Flags().IntVarP(&statsTop, "top", "n", 10, "Show at most this many scripts (0 for all)")
+}
+
+// buildUsageReport gathers the statistics from the usage store, the audit
+// log, and the berga directories
+func buildUsageReport(top int) (*UsageReport, error) {
+	usage, err := loadUsage()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := loadAuditLog()
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := scriptStats(usage.Scripts, entries, auditScriptName)
+	if top > 0 && len(scripts) > top {
+		scripts = scripts[:top]
+	}
+	return &UsageReport{
+		Scripts:           scripts,
+		TemplatesPerMonth: templatesPerMonth(entries),
+		Storage:           storageUsage(),
+	}, nil
+}
+
+// auditScriptName maps the script name given on the command line to the file
+// name usage is tracked under
+func auditScriptName(target string) string {
+	if _, err := os.Stat(resolveScriptPath(target)); err == nil {
+		return filepath.Base(resolveScriptPath(target))
+	}
+	return target
+}
+
+// scriptStats combines usage counts with the script runs in the audit log,
+// most-run first
+func scriptStats(usage map[string]UsageEntry, entries []AuditEntry, normalize func(string) string) []ScriptStats {
+	byName := make(map[string]*ScriptStats)
+	get := func(name string) *ScriptStats {
+		if byName[name] == nil {
+			byName[name] = &ScriptStats{Name: name}
+		}
+		return byName[name]
+	}
+	for name, entry := range usage {
+		s := get(name)
+		s.Runs = entry.Count
+		s.LastUsed = entry.LastUsed
+	}
+
+	names := make(map[string]string)
+	totals := make(map[string]time.Duration)
+	for _, entry := range entries {
+		if entry.Action != "script run" || entry.Target == "" {
+			continue
+		}
+		name, ok := names[entry.Target]
+		if !ok {
+			name = normalize(entry.Target)
+			names[entry.Target] = name
+		}
+		s := get(name)
+		s.Recorded++
+		if entry.Error != "" {
+			s.Failures++
+		}
+		totals[name] += time.Duration(entry.DurationMS) * time.Millisecond
+		if entry.Time.After(s.LastUsed) {
+			s.LastUsed = entry.Time
+		}
+	}
+
+	stats := make([]ScriptStats, 0, len(byName))
+	for name, s := range byName {
+		// Runs from before usage tracking or outside it still count
+		s.Runs = max(s.Runs, s.Recorded)
+		if s.Recorded > 0 {
+			s.FailureRate = float64(s.Failures) / float64(s.Recorded)
+			s.AvgDurationSeconds = (totals[name] / time.Duration(s.Recorded)).Seconds()
+		}
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Runs != stats[j].Runs {
+			return stats[i].Runs > stats[j].Runs
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// templatesPerMonth counts successful template applies by month, oldest first
+func templatesPerMonth(entries []AuditEntry) []MonthCount {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		if entry.Action == "template apply" && entry.Error == "" {
+			counts[entry.Time.Local().Format("2006-01")]++
+		}
+	}
+	months := make([]MonthCount, 0, len(counts))
+	for month, count := range counts {
+		months = append(months, MonthCount{month, count})
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+	return months
+}
+
+// storageCategories lists the places berga keeps content, by category
+func storageCategories() [][2]string {
+	categories := [][2]string{}
+	for _, dir := range GetScriptsDirs() {
+		categories = append(categories, [2]string{"scripts", dir})
+	}
+	for _, dir := range GetTemplatesDirs() {
+		categories = append(categories, [2]string{"templates", dir})
+	}
+	return append(categories,
+		[2]string{"archive", filepath.Join(GetDataDir(), "archive")},
+		[2]string{"snippets", GetSnippetsDir()},
+		[2]string{"notes", GetNotesDir()},
+		[2]string{"cheats", GetCheatsDir()},
+		[2]string{"dotfiles", GetDotfilesDir()},
+		[2]string{"requests", GetRequestsDir()},
+		[2]string{"versions", GetVersionsDir()},
+		[2]string{"cache", GetCacheDir()},
+		[2]string{"records", GetStoreFile()},
+	)
+}
+
+// storageUsage adds up the size of each category. Paths that do not exist
+// count as empty.
+func storageUsage() []StorageUsage {
+	var usage []StorageUsage
+	index := make(map[string]int)
+	for _, c := range storageCategories() {
+		i, ok := index[c[0]]
+		if !ok {
+			i = len(usage)
+			index[c[0]] = i
+			usage = append(usage, StorageUsage{Category: c[0]})
+		}
+		filepath.WalkDir(c[1], func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				usage[i].Files++
+				usage[i].Bytes += info.Size()
+			}
+			return nil
+		})
+	}
+	return usage
+}
+
+// formatDuration prints a run time to a precision that suits its length
+func formatDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func printUsageReport(report *UsageReport) {
+	listHeader(i18n.T("header.usage_statistics"))
+
+	fmt.Println(ui.Bold("Most-run scripts"))
+	if len(report.Scripts) == 0 {
+		fmt.Println("  No script runs recorded yet.")
+	}
+	width := 0
+	for _, s := range report.Scripts {
+		width = max(width, len(s.Name))
+	}
+	for _, s := range report.Scripts {
+		runs := "runs"
+		if s.Runs == 1 {
+			runs = "run "
+		}
+		line := fmt.Sprintf("  %-*s %5d %s", width, s.Name, s.Runs, runs)
+		if s.Recorded > 0 {
+			line += fmt.Sprintf("  avg %-8s", formatDuration(s.AvgDurationSeconds))
+			failures := fmt.Sprintf("%d/%d failed (%.0f%%)", s.Failures, s.Recorded, 100*s.FailureRate)
+			if s.Failures > 0 {
+				failures = ui.Red(failures)
+			} else {
+				failures = ui.Dim(failures)
+			}
+			line += "  " + failures
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	fmt.Println(ui.Bold("Templates applied per month"))
+	if len(report.TemplatesPerMonth) == 0 {
+		fmt.Println("  No template applies recorded yet.")
+	}
+	for _, m := range report.TemplatesPerMonth {
+		fmt.Printf("  %s %5d\n", m.Month, m.Count)
+	}
+
+	fmt.Println()
+	fmt.Println(ui.Bold("Storage"))
+	var total int64
+	for _, s := range report.Storage {
+		total += s.Bytes
+		files := "files"
+		if s.Files == 1 {
+			files = "file"
+		}
+		fmt.Printf("  %-10s %10s %s\n", s.Category, humanizeSize(s.Bytes), ui.Dim(fmt.Sprintf("(%d %s)", s.Files, files)))
+	}
+	fmt.Printf("  %-10s %10s\n", "total", humanizeSize(total))
+	if !auditEnabled() {
+		fmt.Println()
+		fmt.Println(ui.Dim("audit.enabled is false, so durations and failures are not being recorded."))
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScriptStats(t *testing.T) {
+	now := time.Now()
+	usage := map[string]UsageEntry{
+		"deploy.sh": {Count: 10, LastUsed: now.Add(-time.Hour)},
+		"lint.sh":   {Count: 2, LastUsed: now.Add(-time.Hour)},
+	}
+	entries := []AuditEntry{
+		{Time: now, Action: "script run", Target: "deploy", DurationMS: 1000},
+		{Time: now, Action: "script run", Target: "deploy", DurationMS: 3000, Error: "exit status 1"},
+		{Time: now, Action: "script run", Target: "old.sh", DurationMS: 500},
+		{Time: now, Action: "script edit", Target: "lint.sh"},
+	}
+	normalize := func(name string) string {
+		if name == "deploy" {
+			return "deploy.sh"
+		}
+		return name
+	}
+
+	stats := scriptStats(usage, entries, normalize)
+	if len(stats) != 3 || stats[0].Name != "deploy.sh" || stats[1].Name != "lint.sh" {
+		t.Fatalf("Expected scripts ordered by runs, got %+v", stats)
+	}
+	deploy := stats[0]
+	if deploy.Runs != 10 || deploy.Recorded != 2 || deploy.Failures != 1 || deploy.FailureRate != 0.5 {
+		t.Errorf("Unexpected counts %+v", deploy)
+	}
+	if deploy.AvgDurationSeconds != 2 || !deploy.LastUsed.Equal(now) {
+		t.Errorf("Unexpected duration or last use %+v", deploy)
+	}
+	if stats[1].Recorded != 0 || stats[1].AvgDurationSeconds != 0 {
+		t.Errorf("Expected only script runs to count, got %+v", stats[1])
+	}
+	if stats[2].Name != "old.sh" || stats[2].Runs != 1 {
+		t.Errorf("Expected audited runs without usage to count, got %+v", stats[2])
+	}
+}
+
+func TestTemplatesPerMonth(t *testing.T) {
+	entries := []AuditEntry{
+		{Time: time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local), Action: "template apply"},
+		{Time: time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local), Action: "template apply"},
+		{Time: time.Date(2024, 3, 20, 12, 0, 0, 0, time.Local), Action: "template apply"},
+		{Time: time.Date(2024, 3, 21, 12, 0, 0, 0, time.Local), Action: "template apply", Error: "boom"},
+		{Time: time.Date(2024, 3, 22, 12, 0, 0, 0, time.Local), Action: "template new"},
+	}
+	got := templatesPerMonth(entries)
+	if len(got) != 2 || got[0] != (MonthCount{"2024-01", 1}) || got[1] != (MonthCount{"2024-03", 2}) {
+		t.Errorf("Unexpected months %v", got)
+	}
+}
+
+func TestStorageUsage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeTestScript(t, "a.sh", "#!/bin/sh\necho a\n")
+	writeTestScript(t, "b.sh", "#!/bin/sh\n")
+	if err := os.MkdirAll(GetNotesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(GetNotesDir(), "todo.md"), []byte("- x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := make(map[string]StorageUsage)
+	for _, u := range storageUsage() {
+		usage[u.Category] = u
+	}
+	if usage["scripts"].Files != 2 || usage["scripts"].Bytes != 27 {
+		t.Errorf("Unexpected script storage %+v", usage["scripts"])
+	}
+	if usage["notes"].Files != 1 || usage["templates"].Files != 0 {
+		t.Errorf("Unexpected storage %v", usage)
+	}
+}
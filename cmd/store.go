@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"berga/pkg/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// importedSuffix is added to the files records were kept in before the store
+// database once they have been imported
+const importedSuffix = ".imported"
+
+// withStore opens the store database and calls fn with it. Records left in
+// the files earlier versions kept them in are imported first.
+func withStore(fn func(s store.Store) error) error {
+	s, err := store.Open(GetStoreFile())
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := importLegacyRecords(s); err != nil {
+		return err
+	}
+	return fn(s)
+}
+
+// legacyRecordFiles are the files records were kept in before the store
+// database
+func legacyRecordFiles() []string {
+	return []string{GetHistoryFile(), GetHistoryDir(), GetAuditFile(), GetUsageFile()}
+}
+
+// importLegacyRecords moves the run history, audit log, and usage counts from
+// their old files into s, then renames the files so they are imported once
+func importLegacyRecords(s store.Store) error {
+	if !legacyRecordsExist() {
+		return nil
+	}
+	return withLock("store", func() error {
+		// Another berga may have imported them while this one waited
+		if !legacyRecordsExist() {
+			return nil
+		}
+		var records store.Records
+		var err error
+		if records.Runs, err = readLegacyHistory(); err != nil {
+			return err
+		}
+		if records.Audit, err = readLegacyAudit(); err != nil {
+			return err
+		}
+		if records.Usage, err = readLegacyUsage(); err != nil {
+			return err
+		}
+		if err := s.Import(records); err != nil {
+			return err
+		}
+		for _, path := range legacyRecordFiles() {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			os.RemoveAll(path + importedSuffix)
+			if err := os.Rename(path, path+importedSuffix); err != nil {
+				return fmt.Errorf("failed to set aside imported records: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func legacyRecordsExist() bool {
+	for _, path := range legacyRecordFiles() {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readJSONLines decodes each line of the file at path with decode. Lines that
+// cannot be parsed are skipped so a torn write never hides the rest.
+func readJSONLines(path string, decode func(line []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			decode(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLegacyHistory reads the runs of history.log with their recorded output
+func readLegacyHistory() ([]store.Run, error) {
+	var runs []store.Run
+	err := readJSONLines(GetHistoryFile(), func(line []byte) error {
+		var entry struct {
+			store.Run
+			// Output was the path of the file holding the output
+			OutputPath string `json:"output"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if entry.OutputPath != "" {
+			if output, err := os.ReadFile(entry.OutputPath); err == nil {
+				entry.Run.Output = output
+			}
+		}
+		runs = append(runs, entry.Run)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return runs, nil
+}
+
+// readLegacyAudit reads the entries of audit.log
+func readLegacyAudit() ([]store.AuditEntry, error) {
+	var entries []store.AuditEntry
+	err := readJSONLines(GetAuditFile(), func(line []byte) error {
+		var entry store.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// readLegacyUsage reads the usage counts of usage.yaml, by kind
+func readLegacyUsage() (map[string]map[string]store.Usage, error) {
+	data, err := os.ReadFile(GetUsageFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	type legacyEntry struct {
+		Count    int       `yaml:"count"`
+		LastUsed time.Time `yaml:"last_used"`
+	}
+	var legacy struct {
+		Scripts   map[string]legacyEntry `yaml:"scripts"`
+		Templates map[string]legacyEntry `yaml:"templates"`
+	}
+	if err := yaml.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	usage := make(map[string]map[string]store.Usage)
+	for kind, entries := range map[string]map[string]legacyEntry{"script": legacy.Scripts, "template": legacy.Templates} {
+		usage[kind] = make(map[string]store.Usage)
+		for name, entry := range entries {
+			usage[kind][name] = store.Usage{Count: entry.Count, LastUsed: entry.LastUsed}
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportLegacyRecords(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(GetHistoryDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(GetHistoryDir(), "7.log")
+	os.WriteFile(output, []byte("deployed\n"), 0600)
+	os.WriteFile(GetHistoryFile(), []byte(
+		`{"id":6,"time":"2024-03-10T12:00:00Z","script":"backup.sh","exit_code":1,"duration_ms":10}`+"\n"+
+			`{"id":7,"time":"2024-03-10T12:05:00Z","script":"deploy.sh","args":["prod"],"exit_code":0,"duration_ms":20,"output":"`+filepath.ToSlash(output)+`"}`+"\n"), 0600)
+	// A torn line is skipped rather than hiding the rest of the log
+	os.WriteFile(GetAuditFile(), []byte(
+		`{"time":"2024-03-10T12:00:00Z","action":"export"}`+"\n"+
+			`{"time":"20`+"\n"+
+			`{"time":"2024-03-10T12:01:00Z","action":"import","target":"a.tar.gz"}`+"\n"), 0600)
+	os.WriteFile(GetUsageFile(), []byte("scripts:\n  deploy.sh:\n    count: 3\n    last_used: 2024-03-10T12:05:00Z\ntemplates:\n  gitignore:\n    count: 1\n"), 0644)
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].ID != 7 || entries[1].command() != "deploy.sh prod" {
+		t.Fatalf("Expected both runs with their IDs, got %+v", entries)
+	}
+	entry, err := findHistoryEntry("7")
+	if err != nil || string(entry.Output) != "deployed\n" {
+		t.Errorf("Expected the recorded output to be imported, got %q, %v", entry.Output, err)
+	}
+	if audit, _ := loadAuditLog(); len(audit) != 2 || audit[1].Target != "a.tar.gz" {
+		t.Errorf("Expected 2 audit entries, got %+v", audit)
+	}
+	stats, _ := loadUsage()
+	if stats.Scripts["deploy.sh"].Count != 3 || stats.Templates["gitignore"].Count != 1 {
+		t.Errorf("Expected the usage counts to be imported, got %+v", stats)
+	}
+
+	for _, path := range legacyRecordFiles() {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be set aside", path)
+		}
+		if _, err := os.Stat(path + importedSuffix); err != nil {
+			t.Errorf("Expected %s to be kept as %s%s", path, path, importedSuffix)
+		}
+	}
+
+	// New runs follow the imported ones, and nothing is imported twice
+	recordRun("backup.sh", nil, nil, 0)
+	if entries, _ := loadHistory(); len(entries) != 3 || entries[2].ID != 8 {
+		t.Errorf("Expected run 8 after the imported runs, got %+v", entries)
+	}
+}
@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// tagHeaderLines is how many lines are scanned for header metadata such as
+// tags, e.g. "# berga:tags: deploy, aws"
+const tagHeaderLines = 20
+
+// TagIndex is the sidecar tag store, keyed by item kind and then item name
+type TagIndex struct {
+	Scripts   map[string][]string `yaml:"scripts,omitempty"`
+	Templates map[string][]string `yaml:"templates,omitempty"`
+}
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage tags on scripts and templates",
+	Long: `Add, remove, and list tags on scripts and templates.
+
+Tags can also be declared inside a file with a header line near the top:
+
+  # berga:tags: deploy, aws`,
+}
+
+// tagAddCmd adds tags to an item
+var tagAddCmd = &cobra.Command{
+	Use:   "add [script|template] [name] [tag...]",
+	Short: "Add tags to a script or template",
+	Args:  cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("tags", func() error {
+			return addTags(args[0], args[1], args[2:])
+		})
+	},
+}
+
+// tagRemoveCmd removes tags from an item
+var tagRemoveCmd = &cobra.Command{
+	Use:     "rm [script|template] [name] [tag...]",
+	Short:   "Remove tags from a script or template",
+	Aliases: []string{"remove"},
+	Args:    cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLock("tags", func() error {
+			return removeTags(args[0], args[1], args[2:])
+		})
+	},
+}
+
+// tagListCmd lists tags
+var tagListCmd = &cobra.Command{
+	Use:     "list [tag]",
+	Short:   "List tags, or the items with a tag",
+	Aliases: []string{"ls"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			return listTagged(args[0])
+		}
+		return listTags()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	tagCmd.AddCommand(tagListCmd)
+}
+
+func loadTagIndex() (*TagIndex, error) {
+	index := &TagIndex{}
+
+	data, err := os.ReadFile(GetTagsFile())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, index); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+	}
+
+	if index.Scripts == nil {
+		index.Scripts = make(map[string][]string)
+	}
+	if index.Templates == nil {
+		index.Templates = make(map[string][]string)
+	}
+	return index, nil
+}
+
+func saveTagIndex(index *TagIndex) error {
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(GetTagsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	return nil
+}
+
+// itemTags returns the index entries for a kind of item
+func (t *TagIndex) itemTags(kind string) (map[string][]string, error) {
+	switch kind {
+	case "script", "scripts":
+		return t.Scripts, nil
+	case "template", "templates":
+		return t.Templates, nil
+	default:
+		return nil, fmt.Errorf("unknown item type '%s' (expected script or template)", kind)
+	}
+}
+
+// itemPath returns the file for a script or template name
+func itemPath(kind, name string) (string, error) {
+	if kind == "template" || kind == "templates" {
+		return resolveTemplatePath(name)
+	}
+	for _, dir := range GetScriptsDirs() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("script '%s' not found in %s", name, strings.Join(GetScriptsDirs(), ", "))
+}
+
+// headerTags reads tags declared with a berga:tags: line near the top of a file
+func headerTags(path string) []string {
+	return splitTags(readMetadata(path)["tags"])
+}
+
+// splitTags parses a comma or space separated tag list
+func splitTags(s string) []string {
+	var tags []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if field != "" {
+			tags = append(tags, strings.ToLower(field))
+		}
+	}
+	return tags
+}
+
+// mergeTags returns the sorted union of tag lists
+func mergeTags(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, tag := range list {
+			tag = strings.ToLower(tag)
+			if !seen[tag] {
+				seen[tag] = true
+				merged = append(merged, tag)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// tagsFor returns the effective tags of an item from its header and the index
+func tagsFor(index *TagIndex, kind, name, path string) []string {
+	entries, _ := index.itemTags(kind)
+	return mergeTags(headerTags(path), entries[name])
+}
+
+// formatTags renders a tag list for listings
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + ui.Cyan("["+strings.Join(tags, ", ")+"]")
+}
+
+func addTags(kind, name string, tags []string) error {
+	index, err := loadTagIndex()
+	if err != nil {
+		return err
+	}
+	entries, err := index.itemTags(kind)
+	if err != nil {
+		return err
+	}
+	if _, err := itemPath(kind, name); err != nil {
+		return err
+	}
+
+	entries[name] = mergeTags(entries[name], tags)
+	if err := saveTagIndex(index); err != nil {
+		return err
+	}
+
+	fmt.Printf("Tags for %s '%s': %s\n", strings.TrimSuffix(kind, "s"), name, strings.Join(entries[name], ", "))
+	return nil
+}
+
+func removeTags(kind, name string, tags []string) error {
+	index, err := loadTagIndex()
+	if err != nil {
+		return err
+	}
+	entries, err := index.itemTags(kind)
+	if err != nil {
+		return err
+	}
+
+	drop := make(map[string]bool)
+	for _, tag := range tags {
+		drop[strings.ToLower(tag)] = true
+	}
+
+	var kept []string
+	for _, tag := range entries[name] {
+		if !drop[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == len(entries[name]) {
+		return fmt.Errorf("none of the given tags are set on '%s' (header tags must be edited in the file)", name)
+	}
+
+	if len(kept) == 0 {
+		delete(entries, name)
+	} else {
+		entries[name] = kept
+	}
+	if err := saveTagIndex(index); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed tags from '%s'\n", name)
+	return nil
+}
+
+// taggedItem is a script or template with its effective tags
+type taggedItem struct {
+	Kind string
+	Name string
+	Tags []string
+}
+
+// allTaggedItems collects every script and template along with its tags
+func allTaggedItems() ([]taggedItem, error) {
+	index, err := loadTagIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshListings(append(GetScriptsDirs(), GetTemplatesDirs()...))
+	var items []taggedItem
+	seen := make(map[string]bool)
+	for _, dir := range GetScriptsDirs() {
+		files, err := readListing(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || isScriptSpecFile(file.Name()) || seen["script/"+file.Name()] {
+				continue
+			}
+			seen["script/"+file.Name()] = true
+			path := filepath.Join(dir, file.Name())
+			items = append(items, taggedItem{"script", file.Name(), tagsFor(index, "script", file.Name(), path)})
+		}
+	}
+	for _, dir := range GetTemplatesDirs() {
+		files, err := readListing(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || isSchemaFile(file.Name()) {
+				continue
+			}
+			name := strings.TrimSuffix(file.Name(), ".tmpl")
+			if seen["template/"+name] {
+				continue
+			}
+			seen["template/"+name] = true
+			path := filepath.Join(dir, file.Name())
+			items = append(items, taggedItem{"template", name, tagsFor(index, "template", name, path)})
+		}
+	}
+	return items, nil
+}
+
+func listTags() error {
+	items, err := allTaggedItems()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, item := range items {
+		for _, tag := range item.Tags {
+			counts[tag]++
+		}
+	}
+	if len(counts) == 0 {
+		fmt.Println("No tags found.")
+		fmt.Println("Add one with: berga tag add script <name> <tag>")
+		return nil
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	ui.Header("Tags")
+	for _, tag := range tags {
+		fmt.Printf("  %s %s\n", ui.Cyan(tag), ui.Dim(fmt.Sprintf("(%d)", counts[tag])))
+	}
+	return nil
+}
+
+func listTagged(tag string) error {
+	items, err := allTaggedItems()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, item := range items {
+		if hasTag(item.Tags, tag) {
+			if !found {
+				ui.Header(fmt.Sprintf("Tagged '%s'", tag))
+				found = true
+			}
+			fmt.Printf("  %-9s %s\n", item.Kind, ui.Bold(item.Name))
+		}
+	}
+	if !found {
+		fmt.Printf("Nothing is tagged '%s'.\n", tag)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderTags(t *testing.T) {
+	dir := t.TempDir()
+
+	script := filepath.Join(dir, "deploy.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n# berga:tags: Deploy, aws\necho hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := headerTags(script); !reflect.DeepEqual(got, []string{"deploy", "aws"}) {
+		t.Errorf("Unexpected script tags: %v", got)
+	}
+
+	tmpl := filepath.Join(dir, "svc.tmpl")
+	if err := os.WriteFile(tmpl, []byte("{{/* berga:tags: k8s */}}\nkind: Service\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := headerTags(tmpl); !reflect.DeepEqual(got, []string{"k8s"}) {
+		t.Errorf("Unexpected template tags: %v", got)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	got := mergeTags([]string{"b", "A"}, []string{"a", "c"})
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Unexpected merged tags: %v", got)
+	}
+}
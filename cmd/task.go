@@ -0,0 +1,552 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Task is one target of the task graph. It runs a berga script or a shell
+// command once the tasks it depends on have finished.
+type Task struct {
+	Description string   `yaml:"description,omitempty"`
+	Deps        []string `yaml:"deps,omitempty"`
+	Script      string   `yaml:"script,omitempty"`
+	Args        []string `yaml:"args,omitempty"`
+	Run         string   `yaml:"run,omitempty"`
+	Dir         string   `yaml:"dir,omitempty"`
+	Inputs      []string `yaml:"inputs,omitempty"`
+	Outputs     []string `yaml:"outputs,omitempty"`
+}
+
+// TaskFile is the layout of tasks.yaml
+type TaskFile struct {
+	Tasks map[string]Task `yaml:"tasks"`
+}
+
+// taskStatus is how a task ended in a run
+type taskStatus int
+
+const (
+	taskDone taskStatus = iota
+	taskUpToDate
+	taskFailed
+	taskSkipped
+)
+
+// taskResult is the outcome of one task
+type taskResult struct {
+	Status   taskStatus
+	Err      error
+	Duration time.Duration
+}
+
+var (
+	taskJobs      int
+	taskForce     bool
+	taskKeepGoing bool
+	taskDryRun    bool
+)
+
+// taskCmd groups the task commands
+var taskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "Run tasks with dependencies, make-style",
+	Long: `Tasks are defined in tasks.yaml in the berga config directory. Each task runs
+a berga script or a shell command after the tasks it depends on:
+
+  tasks:
+    generate:
+      run: go generate ./...
+      dir: ~/src/app
+      inputs: [api.proto]
+      outputs: [api.pb.go]
+    build:
+      deps: [generate, lint.sh]
+      script: build.sh
+      args: [--release]
+      inputs: ["*.go", go.mod]
+      outputs: [bin/app]
+    all:
+      deps: [build, docs]
+
+A dependency that is not a task but names a script runs that script. A task
+with inputs and outputs is skipped when every output is newer than every input
+and none of its dependencies ran. Paths are relative to dir, the current
+directory by default; a directory stands for every file below it.`,
+}
+
+// taskRunCmd runs tasks and their dependencies
+var taskRunCmd = &cobra.Command{
+	Use:   "run [task...]",
+	Short: "Run tasks and everything they depend on",
+	Long: `Run tasks after their dependencies, with independent tasks running in
+parallel, --jobs at a time. Output lines are prefixed with the task name. When
+a task fails its dependents are skipped and no new tasks start unless
+--keep-going is set.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runTasks(args)
+	},
+	ValidArgsFunction: completeTaskNames,
+}
+
+// taskListCmd lists the defined tasks
+var taskListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List tasks and their dependencies",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return listTasks()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(taskCmd)
+	taskCmd.AddCommand(taskRunCmd, taskListCmd)
+
+	// Flags
+	taskRunCmd.Flags().IntVarP(&taskJobs, "jobs", "j", runtime.NumCPU(), "Tasks to run at once")
+	taskRunCmd.Flags().BoolVarP(&taskForce, "force", "f", false, "Run tasks even when their outputs are up to date")
+	taskRunCmd.Flags().BoolVar(&taskKeepGoing, "keep-going", false, "Keep running tasks that do not depend on a failed one")
+	taskRunCmd.Flags().BoolVar(&taskDryRun, "dry-run", false, "Print the tasks that would run, in order, without running them")
+}
+
+// loadTasks reads tasks.yaml. A missing file means no tasks.
+func loadTasks() (map[string]Task, error) {
+	data, err := os.ReadFile(GetTasksFile())
+	if os.IsNotExist(err) {
+		return map[string]Task{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	var file TaskFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", GetTasksFile(), err)
+	}
+	if file.Tasks == nil {
+		file.Tasks = map[string]Task{}
+	}
+	for name, task := range file.Tasks {
+		if task.Script != "" && task.Run != "" {
+			return nil, fmt.Errorf("task '%s' sets both script and run", name)
+		}
+	}
+	return file.Tasks, nil
+}
+
+// planTasks returns the targets and everything they depend on, each after its
+// dependencies. Dependencies that are not tasks but name a script become
+// tasks that run the script.
+func planTasks(tasks map[string]Task, targets []string, isScript func(string) bool) ([]string, map[string]Task, error) {
+	plan := make(map[string]Task)
+	state := make(map[string]int) // 1 while visiting, 2 once done
+	var order []string
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		case 2:
+			return nil
+		}
+
+		task, ok := tasks[name]
+		if !ok {
+			if !isScript(name) {
+				if len(path) == 0 {
+					return fmt.Errorf("task '%s' not found in %s", name, GetTasksFile())
+				}
+				return fmt.Errorf("task '%s' depends on '%s', which is neither a task nor a script", path[len(path)-1], name)
+			}
+			task = Task{Script: name}
+		}
+
+		state[name] = 1
+		for _, dep := range task.Deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		plan[name] = task
+		order = append(order, name)
+		return nil
+	}
+
+	for _, target := range targets {
+		if err := visit(target, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, plan, nil
+}
+
+// scheduleTasks runs the planned tasks, up to jobs at a time, each once all of
+// its dependencies have succeeded. run is told whether any dependency did
+// work, so up-to-date checks can account for it. After a failure, dependents
+// are skipped and, unless keepGoing, nothing new is started.
+func scheduleTasks(order []string, plan map[string]Task, jobs int, keepGoing bool, run func(name string, depsRan bool) taskResult) map[string]taskResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make(map[string]taskResult, len(order))
+	started := make(map[string]bool, len(order))
+
+	type finished struct {
+		name   string
+		result taskResult
+	}
+	done := make(chan finished)
+	running := 0
+	stopped := false
+
+	for {
+		// Start every task whose dependencies are all finished, in plan order
+		for _, name := range order {
+			if started[name] || running >= jobs {
+				continue
+			}
+			ready, depsRan, depFailed := true, false, false
+			for _, dep := range plan[name].Deps {
+				result, ok := results[dep]
+				if !ok {
+					ready = false
+					break
+				}
+				switch result.Status {
+				case taskDone:
+					depsRan = true
+				case taskFailed, taskSkipped:
+					depFailed = true
+				}
+			}
+			if !ready {
+				continue
+			}
+			started[name] = true
+			if depFailed || stopped {
+				results[name] = taskResult{Status: taskSkipped}
+				continue
+			}
+			running++
+			go func(name string, depsRan bool) {
+				done <- finished{name, run(name, depsRan)}
+			}(name, depsRan)
+		}
+
+		// Tasks are started in plan order, so once nothing is running every
+		// task has been started or skipped
+		if running == 0 {
+			return results
+		}
+
+		f := <-done
+		running--
+		results[f.name] = f.result
+		if f.result.Status == taskFailed && !keepGoing {
+			stopped = true
+		}
+	}
+}
+
+// taskDir returns the directory a task runs in
+func taskDir(task Task) string {
+	if task.Dir == "" {
+		return "."
+	}
+	return expandHome(task.Dir)
+}
+
+// taskFiles expands a task's input or output patterns to the files they
+// match, walking directories. ok is false when a pattern matches nothing.
+func taskFiles(dir string, patterns []string) (files []string, ok bool) {
+	for _, pattern := range patterns {
+		pattern = expandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			return nil, false
+		}
+		for _, match := range matches {
+			filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			})
+		}
+	}
+	return files, true
+}
+
+// taskOutputsCurrent reports whether every output of a task is newer than
+// every input. Tasks that do not declare both always run.
+func taskOutputsCurrent(task Task) bool {
+	if len(task.Inputs) == 0 || len(task.Outputs) == 0 {
+		return false
+	}
+	dir := taskDir(task)
+	inputs, ok := taskFiles(dir, task.Inputs)
+	if !ok {
+		return false
+	}
+	outputs, ok := taskFiles(dir, task.Outputs)
+	if !ok || len(outputs) == 0 {
+		return false
+	}
+
+	var newestInput time.Time
+	for _, path := range inputs {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(newestInput) {
+			newestInput = info.ModTime()
+		}
+	}
+	for _, path := range outputs {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Before(newestInput) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTaskScripts runs the checks 'script run' does before anything starts,
+// so a missing tool or a danger prompt does not interrupt the graph halfway
+func checkTaskScripts(order []string, plan map[string]Task) error {
+	for _, name := range order {
+		task := plan[name]
+		if task.Script == "" {
+			continue
+		}
+		storedPath, err := locateScript(task.Script)
+		if err != nil {
+			return fmt.Errorf("task '%s': %w", name, err)
+		}
+		if err := checkScriptRequirements(task.Script, storedPath); err != nil {
+			return fmt.Errorf("task '%s': %w", name, err)
+		}
+		if err := checkKubeContext(task.Script, storedPath); err != nil {
+			return fmt.Errorf("task '%s': %w", name, err)
+		}
+		if err := confirmDangerousScript(task.Script, storedPath); err != nil {
+			return fmt.Errorf("task '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// taskCommand returns the command a task runs, and a cleanup function
+func taskCommand(ctx context.Context, task Task) (*exec.Cmd, func(), error) {
+	if task.Script == "" {
+		cmd := hookCommandContext(ctx, task.Run)
+		cmd.Dir = taskDir(task)
+		return cmd, func() {}, nil
+	}
+
+	storedPath, err := locateScript(task.Script)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptPath, cleanup, err := plainScriptPath(storedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	recordUsage("script", filepath.Base(storedPath))
+	cmd := scriptCommand(ctx, scriptPath, task.Args)
+	if task.Dir != "" {
+		cmd.Dir = taskDir(task)
+	}
+	return cmd, cleanup, nil
+}
+
+// runTask runs one task, writing its output with the task name in front of
+// each line
+func runTask(name string, task Task, stdout, stderr io.Writer) error {
+	if task.Script == "" && task.Run == "" {
+		return nil
+	}
+	ctx, cancel := runContext(context.Background(), scriptRunTimeout())
+	defer cancel()
+
+	cmd, cleanup, err := taskCommand(ctx, task)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	mu := &sync.Mutex{}
+	out := &lineWriter{mu: mu, out: stdout, label: ui.Cyan("[" + name + "]"), now: time.Now}
+	errOut := &lineWriter{mu: mu, out: stderr, label: ui.ErrCyan("[" + name + "]"), now: time.Now}
+	defer out.Flush()
+	defer errOut.Flush()
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return cmd.Run()
+}
+
+// runTasks runs the given tasks and their dependencies
+func runTasks(targets []string) error {
+	tasks, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	isScript := func(name string) bool {
+		_, err := os.Stat(resolveScriptPath(name))
+		return err == nil
+	}
+	order, plan, err := planTasks(tasks, targets, isScript)
+	if err != nil {
+		return err
+	}
+
+	if taskDryRun {
+		for i, name := range order {
+			status := ""
+			if !taskForce && taskOutputsCurrent(plan[name]) {
+				status = ui.Dim(" (up to date unless a dependency runs)")
+			}
+			fmt.Printf("%d. %s%s\n", i+1, name, status)
+		}
+		return nil
+	}
+
+	if err := checkTaskScripts(order, plan); err != nil {
+		return err
+	}
+
+	var printMu sync.Mutex
+	status := func(line string) {
+		printMu.Lock()
+		defer printMu.Unlock()
+		fmt.Println(line)
+	}
+	results := scheduleTasks(order, plan, taskJobs, taskKeepGoing, func(name string, depsRan bool) taskResult {
+		task := plan[name]
+		if !taskForce && !depsRan && taskOutputsCurrent(task) {
+			status(ui.Dim(fmt.Sprintf("==> %s is up to date", name)))
+			return taskResult{Status: taskUpToDate}
+		}
+		status(ui.Bold("==> " + name))
+		started := time.Now()
+		if err := runTask(name, task, os.Stdout, os.Stderr); err != nil {
+			status(ui.Red(fmt.Sprintf("%s %s failed: %v", ui.Icon("❌", "x"), name, err)))
+			return taskResult{Status: taskFailed, Err: err, Duration: time.Since(started)}
+		}
+		return taskResult{Status: taskDone, Duration: time.Since(started)}
+	})
+
+	return reportTasks(order, results)
+}
+
+// reportTasks prints a summary of a run and returns the first failure in
+// plan order
+func reportTasks(order []string, results map[string]taskResult) error {
+	var ran, upToDate int
+	var failed, skipped []string
+	var firstErr error
+	for _, name := range order {
+		result := results[name]
+		switch result.Status {
+		case taskDone:
+			ran++
+		case taskUpToDate:
+			upToDate++
+		case taskFailed:
+			failed = append(failed, name)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("task '%s' failed: %w", name, result.Err)
+			}
+		case taskSkipped:
+			skipped = append(skipped, name)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d task(s) run, %d up to date", ran, upToDate)
+	if len(failed) > 0 {
+		fmt.Printf(", %d failed (%s)", len(failed), strings.Join(failed, ", "))
+	}
+	if len(skipped) > 0 {
+		fmt.Printf(", %d skipped (%s)", len(skipped), strings.Join(skipped, ", "))
+	}
+	fmt.Println()
+	return firstErr
+}
+
+func listTasks() error {
+	tasks, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		fmt.Printf("No tasks defined. Add them to %s (see 'berga task --help').\n", GetTasksFile())
+		return nil
+	}
+
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	listHeader(i18n.T("header.tasks"))
+	for _, name := range names {
+		task := tasks[name]
+		line := "  " + ui.Bold(name)
+		switch {
+		case task.Script != "":
+			line += " " + strings.Join(append([]string{task.Script}, task.Args...), " ")
+		case task.Run != "":
+			line += " " + ui.Dim(task.Run)
+		}
+		if len(task.Deps) > 0 {
+			line += ui.Cyan(" <- " + strings.Join(task.Deps, ", "))
+		}
+		fmt.Println(line)
+		if task.Description != "" {
+			fmt.Println("      " + task.Description)
+		}
+	}
+	fmt.Printf("\nTasks file: %s\n", GetTasksFile())
+	return nil
+}
+
+// completeTaskNames completes task names from tasks.yaml
+func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	tasks, err := loadTasks()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range tasks {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
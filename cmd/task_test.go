@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanTasks(t *testing.T) {
+	tasks := map[string]Task{
+		"all":      {Deps: []string{"build", "docs"}},
+		"build":    {Deps: []string{"generate", "lint.sh"}, Run: "make"},
+		"docs":     {Deps: []string{"generate"}, Run: "mkdocs build"},
+		"generate": {Run: "go generate"},
+	}
+	isScript := func(name string) bool { return name == "lint.sh" }
+
+	order, plan, err := planTasks(tasks, []string{"all"}, isScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, " "); got != "generate lint.sh build docs all" {
+		t.Errorf("Unexpected order %q", got)
+	}
+	if plan["lint.sh"].Script != "lint.sh" {
+		t.Errorf("Expected a script dependency to become a task, got %+v", plan["lint.sh"])
+	}
+
+	if _, _, err := planTasks(tasks, []string{"deploy"}, isScript); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown task to be an error, got %v", err)
+	}
+	tasks["docs"] = Task{Deps: []string{"missing"}}
+	if _, _, err := planTasks(tasks, []string{"docs"}, isScript); err == nil || !strings.Contains(err.Error(), "neither a task nor a script") {
+		t.Errorf("Expected an unknown dependency to be an error, got %v", err)
+	}
+	tasks["generate"] = Task{Deps: []string{"all"}}
+	if _, _, err := planTasks(tasks, []string{"all"}, isScript); err == nil || !strings.Contains(err.Error(), "all -> build -> generate -> all") {
+		t.Errorf("Expected the cycle to be reported, got %v", err)
+	}
+}
+
+func TestScheduleTasks(t *testing.T) {
+	plan := map[string]Task{
+		"a":    {},
+		"b":    {},
+		"c":    {Deps: []string{"a", "b"}},
+		"fail": {},
+		"d":    {Deps: []string{"fail"}},
+	}
+	order := []string{"a", "b", "c", "fail", "d"}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	depsRanFor := make(map[string]bool)
+	run := func(name string, depsRan bool) taskResult {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		depsRanFor[name] = depsRan
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if name == "fail" {
+			return taskResult{Status: taskFailed, Err: errors.New("boom")}
+		}
+		if name == "b" {
+			return taskResult{Status: taskUpToDate}
+		}
+		return taskResult{Status: taskDone}
+	}
+
+	results := scheduleTasks(order, plan, 2, true, run)
+	if peak != 2 {
+		t.Errorf("Expected 2 tasks at once, got %d", peak)
+	}
+	if results["c"].Status != taskDone || !depsRanFor["c"] {
+		t.Errorf("Expected c to run after a, got %+v", results["c"])
+	}
+	if results["d"].Status != taskSkipped {
+		t.Errorf("Expected d to be skipped after its dependency failed, got %+v", results["d"])
+	}
+
+	// Without --keep-going nothing new starts after a failure
+	results = scheduleTasks([]string{"fail", "a"}, plan, 1, false, run)
+	if results["a"].Status != taskSkipped {
+		t.Errorf("Expected a to be skipped after the failure, got %+v", results["a"])
+	}
+	if err := reportTasks([]string{"fail", "a"}, results); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the failure to be returned, got %v", err)
+	}
+}
+
+func TestTaskOutputsCurrent(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(src, "main.go")
+	output := filepath.Join(dir, "app")
+	os.WriteFile(input, []byte("package main"), 0644)
+	os.WriteFile(output, []byte("binary"), 0755)
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(input, old, old)
+
+	task := Task{Dir: dir, Inputs: []string{"src"}, Outputs: []string{"app"}}
+	if !taskOutputsCurrent(task) {
+		t.Error("Expected an output newer than its inputs to be current")
+	}
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(input, later, later)
+	if taskOutputsCurrent(task) {
+		t.Error("Expected a changed input to make the task stale")
+	}
+
+	if taskOutputsCurrent(Task{Dir: dir, Inputs: []string{"src"}, Outputs: []string{"missing"}}) {
+		t.Error("Expected a missing output to make the task stale")
+	}
+	if taskOutputsCurrent(Task{Dir: dir, Outputs: []string{"app"}}) {
+		t.Error("Expected a task without inputs to always run")
+	}
+}
+
+func TestRunTasks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	tasks := `tasks:
+  gen:
+    run: echo gen >> log && echo data > gen.txt
+    dir: ` + dir + `
+    inputs: [in.txt]
+    outputs: [gen.txt]
+  build:
+    deps: [gen]
+    run: echo build >> log
+    dir: ` + dir + `
+`
+	os.MkdirAll(GetConfigDir(), 0755)
+	if err := os.WriteFile(GetTasksFile(), []byte(tasks), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "in.txt"), old, old)
+
+	if err := runTasks([]string{"build"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runTasks([]string{"build"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "log"))
+	if got := strings.Fields(string(data)); strings.Join(got, " ") != "gen build build" {
+		t.Errorf("Expected gen to be skipped the second time, got %q", got)
+	}
+}
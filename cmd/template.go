@@ -1,17 +1,37 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+	"berga/pkg/templates"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var (
+	templateNoInput   bool
+	templateOutputDir string
+	templateManifest  string
+	templateListTag   string
+	templateListSort  string
+	templateBuiltin   bool
+	templateNoCache   bool
+	templateNoHooks   bool
+	templateForce     bool
+	templateBackup    bool
+	templateMerge     string
+	templateSection   string
+)
+
 // templateCmd represents the template command
 var templateCmd = &cobra.Command{
 	Use:   "template",
@@ -24,10 +44,14 @@ var templateCmd = &cobra.Command{
 var templateListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available templates",
-	Long:  `Display all available templates in your berga templates directory.`,
+	Long:  `Display all available templates in your berga templates directory.
+With --builtin, list the templates embedded in berga instead.`,
 	Aliases: []string{"ls"},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listTemplates()
+		if templateBuiltin {
+			return listBuiltinTemplates()
+		}
+		return listTemplates(templateListTag, templateListSort)
 	},
 }
 
@@ -35,12 +59,79 @@ var templateListCmd = &cobra.Command{
 var templateApplyCmd = &cobra.Command{
 	Use:   "apply [template-name] [output-file]",
 	Short: "Apply a template to create a file",
-	Long:  `Apply a template with variable substitution to create a new file.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Apply a template with variable substitution to create a new file.
+
+Built-in templates are applied with a "builtin/" prefix, e.g.
+"berga template apply builtin/gitignore .gitignore".
+
+Remote templates are downloaded and cached under ~/.berga/cache:
+
+  berga template apply https://example.com/foo.tmpl out.txt
+  berga template apply git::github.com/org/tmpls//k8s/deploy.tmpl?ref=main deploy.yaml
+
+Use "-" as the output file to render to stdout. With --output-dir, every
+argument is a template name or glob pattern and each matching template is
+rendered into the directory; --manifest reads the set from a YAML file:
+
+  vars:
+    Region: eu-west-1
+  templates:
+    - template: gitignore
+      output: .gitignore
+    - template: service
+      output: "deploy/{{.Env}}/service.yaml"
+      vars: {Env: prod}
+
+Manifest vars apply to every entry, an entry's vars override them, and neither
+is prompted for. Output paths are rendered with the entry's variables.
+
+--var NAME=VALUE sets a variable without asking for it, for every template
+and over any manifest vars. With shell completion set up, --var <TAB> lists
+the variables the template's schema declares:
+
+  berga template apply service svc.yaml --var Port=8080 --var Env=prod
+
+Templates can declare commands to run in the output directory after
+rendering, with template variables expanded; --no-hooks skips them:
+
+  {{/* berga:hook: go mod init {{.ProjectName}} */}}
+  {{/* berga:hook: chmod +x "$BERGA_OUTPUT" */}}
+
+Rendered files get 0644 or keep the permissions of the file they replace.
+--mode sets them, as do "berga:mode: 0600" or "berga:executable: true" in the
+template's header and "mode" in a manifest entry.
+
+--merge updates an existing file instead of replacing it. The rendered output
+is wrapped in marker comments named after the template (or --section), and
+applying the template again replaces the marked block in place:
+
+  berga template apply zsh-aliases ~/.zshrc --merge append
+  berga template apply hosts-dev /etc/hosts --merge replace-section
+
+--record-answers saves every prompt answer to a YAML file, and --answers
+replays them so the same run can be repeated without typing anything:
+
+  berga template apply service svc.yaml --record-answers svc-answers.yaml
+  berga template apply service svc.yaml --answers svc-answers.yaml`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if templateOutputDir != "" || templateManifest != "" {
+			return nil
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		templateName := args[0]
-		outputFile := args[1]
-		return applyTemplate(templateName, outputFile)
+		if err := validateMergeMode(templateMerge); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return withAnswers(func() error {
+			if templateOutputDir != "" || templateManifest != "" {
+				return applyTemplateSet(args, templateOutputDir, templateManifest)
+			}
+			templateName := args[0]
+			outputFile := args[1]
+			return applyTemplate(templateName, outputFile)
+		})
 	},
 }
 
@@ -48,7 +139,9 @@ var templateApplyCmd = &cobra.Command{
 var templateShowCmd = &cobra.Command{
 	Use:   "show [template-name]",
 	Short: "Show template content",
-	Long:  `Display the content of a template.`,
+	Long: `Display the content of a template, with syntax highlighting on a terminal.
+Output taller than the terminal opens in the pager from the pager setting or
+$PAGER (default less); --no-pager prints it directly.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return showTemplate(args[0])
@@ -72,33 +165,101 @@ func init() {
 	templateCmd.AddCommand(templateApplyCmd)
 	templateCmd.AddCommand(templateShowCmd)
 	templateCmd.AddCommand(templateEditCmd)
+
+	// Flags
+	templateListCmd.Flags().StringVarP(&templateListTag, "tag", "t", "", "Only show templates with this tag")
+	templateListCmd.Flags().StringVar(&templateListSort, "sort", sortName, "Sort order: name, frecency, mtime, or size")
+	templateListCmd.Flags().BoolVar(&templateBuiltin, "builtin", false, "List the built-in template gallery")
+	templateApplyCmd.Flags().BoolVar(&templateNoInput, "no-input", false, "Do not prompt; use defaults and fail on missing required variables")
+	templateApplyCmd.Flags().StringVar(&templateOutputDir, "output-dir", "", "Render every matching template into this directory")
+	templateApplyCmd.Flags().StringVar(&templateManifest, "manifest", "", "YAML manifest listing templates and output paths")
+	templateApplyCmd.Flags().BoolVar(&templateNoCache, "no-cache", false, "Download remote templates again instead of using the cache")
+	templateApplyCmd.Flags().BoolVarP(&templateForce, "force", "f", false, "Overwrite existing files without asking")
+	templateApplyCmd.Flags().BoolVar(&templateBackup, "backup", false, "Keep a copy of each overwritten file as <file>.bak")
+	templateApplyCmd.Flags().StringVar(&templateMerge, "merge", "", "Merge into an existing file: append, prepend, or replace-section")
+	templateApplyCmd.Flags().StringVar(&templateSection, "section", "", "Marker name for --merge (default is the template name)")
+	templateApplyCmd.Flags().StringVar(&templateRecordAnswers, "record-answers", "", "Save every prompt answer to this YAML file")
+	templateApplyCmd.Flags().StringVar(&templateAnswersFile, "answers", "", "Answer prompts from a file saved with --record-answers")
+	templateApplyCmd.Flags().BoolVar(&templateNoHooks, "no-hooks", false, "Do not run the template's post-render hooks")
+	templateApplyCmd.Flags().BoolVar(&templateAllowExec, "allow-exec", false, "Let the template run commands with the shell function")
+	templateApplyCmd.Flags().StringVar(&templateMode, "mode", "", "Permissions for the rendered files, e.g. 0755 (overrides the template's berga:mode:)")
+	templateApplyCmd.Flags().IntVarP(&templateJobs, "jobs", "j", defaultTemplateJobs, "Templates to render at once with --output-dir or --manifest")
+	templateApplyCmd.Flags().StringArrayVar(&templateVarFlags, "var", nil, "Set a template variable as NAME=VALUE instead of being asked (repeatable)")
+	templateApplyCmd.RegisterFlagCompletionFunc("var", completeTemplateVars)
+	templateApplyCmd.Flags().BoolVar(&templateNoRollback, "no-rollback", false, "Keep the files rendered before a failure with --output-dir or --manifest")
+	templateShowCmd.Flags().BoolVar(&templateNoCache, "no-cache", false, "Download remote templates again instead of using the cache")
+	templateShowCmd.Flags().BoolVar(&showNoPager, "no-pager", false, "Print the template instead of opening it in a pager")
 }
 
-func listTemplates() error {
+func listTemplates(tag, order string) error {
+	if err := validateSortOrder(order); err != nil {
+		return err
+	}
+	
 	templatesDir := GetTemplatesDir()
 	
 	if _, err := os.Stat(templatesDir); os.IsNotExist(err) {
-		fmt.Printf("Templates directory does not exist: %s\n", templatesDir)
-		fmt.Println("Run 'berga config init' to initialize your configuration.")
+		fmt.Println(i18n.T("list.templates_dir_missing", templatesDir))
+		fmt.Println(i18n.T("list.run_config_init"))
 		return nil
 	}
 
-	files, err := os.ReadDir(templatesDir)
+	refreshListings(GetTemplatesDirs())
+	files, err := readListing(templatesDir)
 	if err != nil {
 		return fmt.Errorf("failed to read templates directory: %w", err)
 	}
 
 	if len(files) == 0 {
-		fmt.Println("No templates found.")
-		fmt.Printf("Add templates to: %s\n", templatesDir)
+		fmt.Println(i18n.T("list.no_templates"))
+		fmt.Println(i18n.T("list.add_templates", templatesDir))
 		return nil
 	}
 
-	fmt.Println("Available Templates:")
-	fmt.Println("===================")
+	if err := sortListing(files, "template", order); err != nil {
+		return err
+	}
+	
+	index, err := loadTagIndex()
+	if err != nil {
+		return err
+	}
+	
+	listHeader(i18n.T("header.available_templates"))
+	
+	// shadowed maps each name listed so far to its directory
+	shadowed := make(map[string]string)
+	for _, name := range printTemplates(templatesDir, files, index, tag, shadowed) {
+		shadowed[name] = templatesDir
+	}
 	
+	fmt.Printf("\n%s\n", i18n.T("list.templates_dir", templatesDir))
+	
+	// Further search paths from paths.templates
+	for _, dir := range GetTemplatesDirs()[1:] {
+		files, err := readListing(dir)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		if err := sortListing(files, "template", order); err != nil {
+			return err
+		}
+		fmt.Println()
+		listHeader(i18n.T("header.templates_in", dir))
+		for _, name := range printTemplates(dir, files, index, tag, shadowed) {
+			shadowed[name] = dir
+		}
+	}
+	return nil
+}
+
+// printTemplates lists the templates in dir matching tag and returns the names
+// it found. Shadowed names are noted as hidden at the end, unless filtering by
+// tag.
+func printTemplates(dir string, files []os.DirEntry, index *TagIndex, tag string, shadowed map[string]string) []string {
+	var names, hidden []string
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || isSchemaFile(file.Name()) {
 			continue
 		}
 		
@@ -116,121 +277,232 @@ func listTemplates() error {
 			displayName = strings.TrimSuffix(name, ".tmpl")
 		}
 		
-		fmt.Printf("  📋 %s (%s, %s)\n", 
-			displayName, 
-			humanizeSize(info.Size()), 
-			info.ModTime().Format("2006-01-02 15:04"))
+		if shadowed[displayName] != "" {
+			hidden = append(hidden, displayName)
+			continue
+		}
+		names = append(names, displayName)
+		
+		tags := tagsFor(index, "template", displayName, filepath.Join(dir, name))
+		if tag != "" && !hasTag(tags, tag) {
+			continue
+		}
+		
+		fmt.Printf("  %s %s %s%s\n", 
+			ui.Icon("📋", "-"),
+			ui.Bold(displayName), 
+			ui.Dim(fmt.Sprintf("(%s, %s)", humanizeSize(info.Size()), info.ModTime().Format("2006-01-02 15:04"))),
+			formatTags(tags))
+	}
+	if tag == "" {
+		printShadowed(hidden, shadowed)
 	}
 	
-	fmt.Printf("\nTemplates directory: %s\n", templatesDir)
-	return nil
+	return names
 }
 
 func applyTemplate(templateName string, outputFile string) error {
-	templatesDir := GetTemplatesDir()
+	templatePath, err := resolveTemplatePath(templateName)
+	if err != nil {
+		return err
+	}
 	
-	// Try to find template file with or without .tmpl extension
-	templatePath := filepath.Join(templatesDir, templateName)
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		templatePath = filepath.Join(templatesDir, templateName+".tmpl")
-		if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-			return fmt.Errorf("template '%s' not found in %s", templateName, templatesDir)
+	// Rendering to stdout keeps prompts out of the rendered output
+	toStdout := outputFile == "-"
+	if toStdout {
+		if templateMerge != "" {
+			return fmt.Errorf("--merge needs an output file, not stdout")
 		}
+		promptOut = os.Stderr
+	}
+	
+	// Load the companion variable schema, if any
+	schema, err := loadTemplateSchema(templatePath)
+	if err != nil {
+		return err
+	}
+	mode, err := templateFileMode(templatePath, "")
+	if err != nil {
+		return err
 	}
 	
 	// Check if output file already exists
-	if _, err := os.Stat(outputFile); err == nil {
-		fmt.Printf("File %s already exists. Overwrite? (y/N): ", outputFile)
-		var response string
-		fmt.Scanln(&response)
-		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-			fmt.Println("Template application cancelled.")
-			return nil
+	if !toStdout {
+		if ok, err := confirmOverwrite(outputFile); err != nil || !ok {
+			return err
 		}
 	}
 	
-	// Read template content
-	templateContent, err := os.ReadFile(templatePath)
+	// Read and parse template before prompting
+	tmpl, err := parseTemplateFile(templatePath, templateName)
 	if err != nil {
-		return fmt.Errorf("failed to read template: %w", err)
+		return err
 	}
 	
-	// Parse template
-	tmpl, err := template.New(templateName).Parse(string(templateContent))
+	// Collect template variables; those given with --var are not prompted for
+	given, err := templateVarAssignments()
+	if err != nil {
+		return err
+	}
+	prompted := schema
+	if schema != nil {
+		prompted = schema.withoutVars(given)
+	}
+	vars, err := collectTemplateVars(prompted, promptsDisabled())
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
+	}
+	if vars, err = applyManifestVars(vars, given, schema); err != nil {
+		return err
 	}
 	
-	// Collect template variables
-	vars := collectTemplateVars()
+	hookNames, hookEnv := templateHookNames(templateName), templateHookEnv(templateName, templatePath, outputFile)
+	if err := runConfiguredHooks(hookPreTemplateApply, "template", hookNames, hookEnv); err != nil {
+		return err
+	}
 	
-	// Create output file
-	output, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	if toStdout {
+		if err := renderTemplateToStdout(tmpl, vars); err != nil {
+			return err
+		}
+		warnHookFailure(runConfiguredHooks(hookPostTemplateApply, "template", hookNames, hookEnv))
+		return nil
 	}
-	defer output.Close()
 	
-	// Execute template
-	if err := tmpl.Execute(output, vars); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	if err := renderTemplateToFile(tmpl, vars, outputFile, mode); err != nil {
+		return err
 	}
 	
 	fmt.Printf("Template '%s' applied successfully to '%s'\n", templateName, outputFile)
+	recordTemplateUsage(templateName)
+	if err := runTemplateHooks(templateName, templatePath, outputFile, vars); err != nil {
+		return err
+	}
+	warnHookFailure(runConfiguredHooks(hookPostTemplateApply, "template", hookNames, hookEnv))
 	return nil
 }
 
-func showTemplate(templateName string) error {
-	templatesDir := GetTemplatesDir()
+// resolveTemplatePath finds a template file with or without the .tmpl extension
+func resolveTemplatePath(templateName string) (string, error) {
+	if strings.HasPrefix(templateName, builtinPrefix) {
+		return resolveBuiltinPath(templateName)
+	}
+	if isRemoteTemplate(templateName) {
+		return fetchRemoteTemplate(templateName, templateNoCache)
+	}
 	
-	// Try to find template file with or without .tmpl extension
-	templatePath := filepath.Join(templatesDir, templateName)
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		templatePath = filepath.Join(templatesDir, templateName+".tmpl")
-		if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-			return fmt.Errorf("template '%s' not found in %s", templateName, templatesDir)
-		}
+	if templatePath, ok := findLocalTemplate(templateName); ok {
+		return templatePath, nil
+	}
+	return "", errors.New(i18n.T("error.template_not_found", templateName, strings.Join(GetTemplatesDirs(), ", ")))
+}
+
+// findLocalTemplate looks a template up in each templates directory in
+// order, with or without the .tmpl extension
+func findLocalTemplate(templateName string) (string, bool) {
+	return templates.DirStore{Dirs: GetTemplatesDirs()}.Find(templateName)
+}
+
+// parseTemplateFile reads and parses a template file
+func parseTemplateFile(templatePath, templateName string) (*template.Template, error) {
+	templateContent, err := readTemplateFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
 	}
 	
-	content, err := os.ReadFile(templatePath)
+	tmpl, err := parseTemplateSource(templateName, string(templateContent))
+	if err != nil {
+		return nil, err
+	}
+	// include reads files next to the template; built-in ones have no directory
+	baseDir := filepath.Dir(templatePath)
+	if isBuiltinPath(templatePath) {
+		baseDir = ""
+	}
+	return tmpl.Funcs(contentFuncs(baseDir, isRemoteTemplate(templateName))), nil
+}
+
+// parseTemplateSource parses template text
+func parseTemplateSource(templateName, content string) (*template.Template, error) {
+	tmpl, err := templates.TextRenderer{Funcs: templateFuncs()}.Parse(templateName, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplateToFile executes a parsed template into outputFile, showing
+// progress for large outputs. See writeRenderedFile.
+func renderTemplateToFile(tmpl *template.Template, vars map[string]interface{}, outputFile string, mode os.FileMode) error {
+	return writeRenderedFile(tmpl, vars, outputFile, mode, progressOut(false))
+}
+
+// backupFile copies an existing file to <file>.bak before it is replaced
+func backupFile(path string, perm os.FileMode) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s for backup: %w", path, err)
+	}
+	if err := writeFileAtomic(path+".bak", data, perm); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// confirmOverwrite asks before replacing an existing file. With --force,
+// --merge, or --assume-yes it never asks; when prompts are disabled otherwise
+// an existing file is an error.
+func confirmOverwrite(outputFile string) (bool, error) {
+	if _, err := os.Stat(outputFile); err != nil {
+		return true, nil
+	}
+	if templateForce || templateMerge != "" || assumeYes {
+		return true, nil
+	}
+	question := fmt.Sprintf("File %s already exists. Overwrite?", outputFile)
+	if _, replayed := replayedConfirmation(question); !replayed && promptsDisabled() {
+		return false, fmt.Errorf("output file %s already exists (use --force or --assume-yes to overwrite)", outputFile)
+	}
+	
+	if !confirm(os.Stdout, question, false) {
+		fmt.Println("Template application cancelled.")
+		return false, nil
+	}
+	return true, nil
+}
+
+func showTemplate(templateName string) error {
+	templatePath, err := resolveTemplatePath(templateName)
+	if err != nil {
+		return err
+	}
+	
+	content, err := readTemplateFile(templatePath)
 	if err != nil {
 		return fmt.Errorf("failed to read template: %w", err)
 	}
 	
-	fmt.Printf("Template: %s\n", templatePath)
-	fmt.Println("=" + strings.Repeat("=", len(templatePath)+10))
-	fmt.Print(string(content))
+	var out strings.Builder
+	fmt.Fprintf(&out, "Template: %s\n", templatePath)
+	fmt.Fprintln(&out, "="+strings.Repeat("=", len(templatePath)+10))
+	out.WriteString(highlight(filepath.Base(templatePath), string(content)))
 	
-	return nil
+	return pageOutput(out.String(), showNoPager)
 }
 
 func editTemplate(templateName string) error {
-	templatesDir := GetTemplatesDir()
+	if strings.HasPrefix(templateName, builtinPrefix) {
+		return fmt.Errorf("built-in templates are read-only; run 'berga template export-builtin %s' to customize it", strings.TrimPrefix(templateName, builtinPrefix))
+	}
 	
 	// Try to find template file with or without .tmpl extension
-	templatePath := filepath.Join(templatesDir, templateName)
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		templatePath = filepath.Join(templatesDir, templateName+".tmpl")
-	}
-	
-	// Get editor from config
-	editor := viper.GetString("editor")
-	if editor == "" {
-		// Try environment variables
-		editor = os.Getenv("EDITOR")
-		if editor == "" {
-			editor = os.Getenv("VISUAL")
-		}
-		if editor == "" {
-			// Default editors by platform
-			if strings.Contains(strings.ToLower(os.Getenv("OS")), "windows") {
-				editor = "notepad"
-			} else {
-				editor = "nano"
-			}
-		}
+	templatePath, ok := findLocalTemplate(templateName)
+	if !ok {
+		templatePath = filepath.Join(GetTemplatesDir(), templateName+".tmpl")
 	}
 	
+	editor := preferredEditor()
+	
 	fmt.Printf("Opening %s with %s...\n", templatePath, editor)
 	
 	// For new templates, ensure directory exists
@@ -238,15 +510,12 @@ func editTemplate(templateName string) error {
 		return fmt.Errorf("failed to create templates directory: %w", err)
 	}
 	
-	cmd := exec.Command(editor, templatePath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	
-	return cmd.Run()
+	return trackChange("template", templatePath, revisionEdit, func() error {
+		return editFile(editor, templatePath)
+	})
 }
 
-func collectTemplateVars() map[string]interface{} {
+func collectTemplateVars(schema *TemplateSchema, noInput bool) (map[string]interface{}, error) {
 	vars := make(map[string]interface{})
 	
 	// Get common variables from config
@@ -258,40 +527,80 @@ func collectTemplateVars() map[string]interface{} {
 		vars["CurrentDir"] = filepath.Base(cwd)
 		vars["ProjectName"] = filepath.Base(cwd)
 	}
+	now := time.Now()
+	vars["Year"] = now.Year()
+	vars["Date"] = now.Format("2006-01-02")
+	
+	// Details of the git repository we are in, if any
+	for k, v := range gitTemplateVars("") {
+		vars[k] = v
+	}
+	
+	// The OS, architecture, host, and user rendering the template
+	for k, v := range hostTemplatePlatform().vars() {
+		vars[k] = v
+	}
+	
+	// Variables defined in the global or project config
+	for k, v := range projectTemplateVars() {
+		vars[k] = v
+	}
+	
+	// Templates with a schema drive their prompts from it
+	if schema != nil {
+		if !noInput {
+			ui.HeaderTo(promptOut, "Template Variables")
+		}
+		if err := collectSchemaVars(schema, vars, noInput); err != nil {
+			return nil, err
+		}
+		return vars, nil
+	}
+	
+	// Answers from --answers stand in for the prompts below
+	if replayAnswers != nil {
+		for k, v := range replayAnswers.Vars {
+			vars[k] = v
+			recordVar(k, v)
+		}
+		return vars, nil
+	}
+	
+	if noInput {
+		return vars, nil
+	}
 	
 	// Interactive variable collection
-	fmt.Println("Template Variables:")
-	fmt.Println("==================")
+	ui.HeaderTo(promptOut, "Template Variables")
 	
 	// Prompt for project name if not set
 	if vars["ProjectName"] == "" || vars["ProjectName"] == "." {
-		fmt.Print("Project Name: ")
-		var projectName string
-		fmt.Scanln(&projectName)
+		projectName, _ := promptLine("Project Name", "", false)
+		projectName = strings.TrimSpace(projectName)
 		if projectName != "" {
 			vars["ProjectName"] = projectName
 		}
 	} else {
-		fmt.Printf("Project Name: %s\n", vars["ProjectName"])
+		fmt.Fprintf(promptOut, "Project Name: %s\n", vars["ProjectName"])
 	}
+	recordVar("ProjectName", vars["ProjectName"])
 	
 	// Prompt for author if not set
 	if vars["Author"] == "" {
-		fmt.Print("Author: ")
-		var author string
-		fmt.Scanln(&author)
+		author, _ := promptLine("Author", "", false)
+		author = strings.TrimSpace(author)
 		if author != "" {
 			vars["Author"] = author
 		}
 	} else {
-		fmt.Printf("Author: %s\n", vars["Author"])
+		fmt.Fprintf(promptOut, "Author: %s\n", vars["Author"])
 	}
+	recordVar("Author", vars["Author"])
 	
 	// Prompt for additional custom variables
-	fmt.Print("Additional variables (key=value, empty to finish): ")
 	for {
-		var input string
-		fmt.Scanln(&input)
+		input, _ := promptLine("Additional variables (key=value, empty to finish)", "", false)
+		input = strings.TrimSpace(input)
 		if input == "" {
 			break
 		}
@@ -299,10 +608,9 @@ func collectTemplateVars() map[string]interface{} {
 		parts := strings.SplitN(input, "=", 2)
 		if len(parts) == 2 {
 			vars[parts[0]] = parts[1]
+			recordVar(parts[0], parts[1])
 		}
-		
-		fmt.Print("Additional variables (key=value, empty to finish): ")
 	}
 	
-	return vars
+	return vars, nil
 }
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	templateAnswersFile   string
+	templateRecordAnswers string
+)
+
+// AnswerFile holds prompt responses saved with --record-answers and replayed
+// with --answers: variable values by name, and yes/no answers by question
+type AnswerFile struct {
+	Vars          map[string]string `yaml:"vars,omitempty"`
+	Confirmations map[string]bool   `yaml:"confirmations,omitempty"`
+}
+
+var (
+	// replayAnswers are the answers loaded with --answers, if any
+	replayAnswers *AnswerFile
+
+	// recordedAnswers collects responses for --record-answers, if set
+	recordedAnswers *AnswerFile
+)
+
+// loadAnswerFile reads an answers file
+func loadAnswerFile(path string) (*AnswerFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answers: %w", err)
+	}
+	var answers AnswerFile
+	if err := yaml.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("failed to parse answers file %s: %w", path, err)
+	}
+	return &answers, nil
+}
+
+// saveAnswerFile writes recorded answers to path
+func saveAnswerFile(path string, answers *AnswerFile) error {
+	data, err := yaml.Marshal(answers)
+	if err != nil {
+		return fmt.Errorf("failed to encode answers: %w", err)
+	}
+	header := "# Prompt answers recorded by berga; replay with --answers\n"
+	if err := writeFileAtomic(path, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write answers: %w", err)
+	}
+	return nil
+}
+
+// withAnswers sets up --answers and --record-answers around a run, saving the
+// recorded answers if it succeeds
+func withAnswers(run func() error) error {
+	if templateAnswersFile != "" {
+		answers, err := loadAnswerFile(templateAnswersFile)
+		if err != nil {
+			return err
+		}
+		replayAnswers = answers
+		defer func() { replayAnswers = nil }()
+	}
+	if templateRecordAnswers != "" {
+		recordedAnswers = &AnswerFile{Vars: make(map[string]string), Confirmations: make(map[string]bool)}
+		defer func() { recordedAnswers = nil }()
+	}
+
+	if err := run(); err != nil {
+		return err
+	}
+	if recordedAnswers != nil {
+		if err := saveAnswerFile(templateRecordAnswers, recordedAnswers); err != nil {
+			return err
+		}
+		fmt.Printf("Answers recorded to %s\n", templateRecordAnswers)
+	}
+	return nil
+}
+
+// replayedVar returns the value of a variable from --answers
+func replayedVar(name string) (string, bool) {
+	if replayAnswers == nil {
+		return "", false
+	}
+	value, ok := replayAnswers.Vars[name]
+	return value, ok
+}
+
+// replayedConfirmation returns the answer to a question from --answers
+func replayedConfirmation(question string) (bool, bool) {
+	if replayAnswers == nil {
+		return false, false
+	}
+	answer, ok := replayAnswers.Confirmations[question]
+	return answer, ok
+}
+
+// recordVar saves a variable's value for --record-answers
+func recordVar(name string, value interface{}) {
+	if recordedAnswers != nil {
+		recordedAnswers.Vars[name] = defaultString(value)
+	}
+}
+
+// recordConfirmation saves a yes/no answer for --record-answers
+func recordConfirmation(question string, answer bool) {
+	if recordedAnswers != nil {
+		recordedAnswers.Confirmations[question] = answer
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplayAnswers(t *testing.T) {
+	dir := t.TempDir()
+	answersPath := filepath.Join(dir, "answers.yaml")
+	output := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(output, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema := &TemplateSchema{Variables: []TemplateVar{
+		{Name: "Name", Required: true},
+		{Name: "Port", Type: "int", Default: 8080},
+	}}
+
+	// Record an interactive run
+	setTerminal(t, true)
+	origReader, origOut := stdinReader, promptOut
+	defer func() { stdinReader, promptOut = origReader, origOut }()
+	stdinReader = bufio.NewReader(strings.NewReader("api\n\ny\n"))
+	promptOut = io.Discard
+
+	templateRecordAnswers = answersPath
+	err := withAnswers(func() error {
+		vars := make(map[string]interface{})
+		if err := collectSchemaVars(schema, vars, false); err != nil {
+			return err
+		}
+		if ok, err := confirmOverwrite(output); !ok || err != nil {
+			t.Errorf("Expected the overwrite to be confirmed, got %v, %v", ok, err)
+		}
+		return nil
+	})
+	templateRecordAnswers = ""
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answers, err := loadAnswerFile(answersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers.Vars["Name"] != "api" || answers.Vars["Port"] != "8080" {
+		t.Errorf("Unexpected recorded vars %v", answers.Vars)
+	}
+	if !answers.Confirmations["File "+output+" already exists. Overwrite?"] {
+		t.Errorf("Expected the overwrite answer to be recorded, got %v", answers.Confirmations)
+	}
+
+	// Replay it without a terminal
+	setTerminal(t, false)
+	templateAnswersFile = answersPath
+	defer func() { templateAnswersFile = "" }()
+	err = withAnswers(func() error {
+		vars := make(map[string]interface{})
+		if err := collectSchemaVars(schema, vars, true); err != nil {
+			return err
+		}
+		if vars["Name"] != "api" || vars["Port"] != 8080 {
+			t.Errorf("Expected replayed values, got %v", vars)
+		}
+		if ok, err := confirmOverwrite(output); !ok || err != nil {
+			t.Errorf("Expected the replayed answer to allow the overwrite, got %v, %v", ok, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayAnswers != nil {
+		t.Error("Expected replayed answers to be cleared after the run")
+	}
+}
+
+func TestReplayInvalidAnswer(t *testing.T) {
+	replayAnswers = &AnswerFile{Vars: map[string]string{"Port": "http"}}
+	defer func() { replayAnswers = nil }()
+
+	schema := &TemplateSchema{Variables: []TemplateVar{{Name: "Port", Type: "int"}}}
+	if err := collectSchemaVars(schema, map[string]interface{}{}, true); err == nil {
+		t.Error("Expected an error for an answer of the wrong type")
+	}
+}
+
+func TestReplayWithoutSchema(t *testing.T) {
+	setTerminal(t, false)
+	replayAnswers = &AnswerFile{Vars: map[string]string{"ProjectName": "engine", "Extra": "1"}}
+	defer func() { replayAnswers = nil }()
+
+	vars, err := collectTemplateVars(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["ProjectName"] != "engine" || vars["Extra"] != "1" {
+		t.Errorf("Expected replayed variables, got %v", vars)
+	}
+}
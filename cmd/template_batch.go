@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/templates"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateManifest lists templates to render together and where each one goes.
+// Vars apply to every entry.
+type TemplateManifest struct {
+	Vars      map[string]interface{} `yaml:"vars"`
+	Templates []ManifestEntry        `yaml:"templates"`
+}
+
+// ManifestEntry maps a template to an output path relative to the output
+// directory. The output path is itself a template, rendered with the entry's
+// variables, and Vars override the manifest's and are not prompted for.
+type ManifestEntry struct {
+	Template string                 `yaml:"template"`
+	Output   string                 `yaml:"output"`
+	Vars     map[string]interface{} `yaml:"vars"`
+	Mode     string                 `yaml:"mode"`
+}
+
+// loadTemplateManifest reads a manifest file
+func loadTemplateManifest(path string) (*TemplateManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest TemplateManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	for i, entry := range manifest.Templates {
+		if entry.Template == "" {
+			return nil, fmt.Errorf("manifest entry #%d has no template", i+1)
+		}
+		// Entry variables are layered over the manifest's
+		vars := make(map[string]interface{}, len(manifest.Vars)+len(entry.Vars))
+		for k, v := range manifest.Vars {
+			vars[k] = v
+		}
+		for k, v := range entry.Vars {
+			vars[k] = v
+		}
+		manifest.Templates[i].Vars = vars
+	}
+	return &manifest, nil
+}
+
+// withoutVars returns a copy of schema without the variables in given, so
+// values the manifest supplies are not prompted for
+func (s *TemplateSchema) withoutVars(given map[string]interface{}) *TemplateSchema {
+	rest := *s
+	rest.Variables = nil
+	for _, v := range s.Variables {
+		if _, ok := given[v.Name]; !ok {
+			rest.Variables = append(rest.Variables, v)
+		}
+	}
+	return &rest
+}
+
+// applyManifestVars layers an entry's variables over the collected ones. Values
+// for schema variables are checked and converted like answers to a prompt.
+func applyManifestVars(vars, given map[string]interface{}, schema *TemplateSchema) (map[string]interface{}, error) {
+	if len(given) == 0 {
+		return vars, nil
+	}
+	merged := make(map[string]interface{}, len(vars)+len(given))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range given {
+		merged[k] = v
+	}
+	if schema != nil {
+		for _, v := range schema.Variables {
+			raw, ok := given[v.Name]
+			if !ok {
+				continue
+			}
+			value, err := convertVarValue(v, defaultString(raw))
+			if err != nil {
+				return nil, err
+			}
+			merged[v.Name] = value
+		}
+	}
+	return merged, nil
+}
+
+// renderOutputPath expands template expressions in a manifest output path
+func renderOutputPath(output string, vars map[string]interface{}) (string, error) {
+	if !strings.Contains(output, "{{") {
+		return output, nil
+	}
+	var buf strings.Builder
+	err := templates.TextRenderer{Funcs: hostTemplatePlatform().funcs(), Strict: true}.Render(&buf, "output", output, vars)
+	if err != nil {
+		return "", fmt.Errorf("failed to render output path '%s': %w", output, err)
+	}
+	path := strings.TrimSpace(buf.String())
+	if path == "" {
+		return "", fmt.Errorf("output path '%s' rendered empty", output)
+	}
+	return path, nil
+}
+
+// templateNames returns the display names of all templates in the templates
+// directories
+func templateNames() ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for i, dir := range GetTemplatesDirs() {
+		files, err := readListing(dir)
+		if err != nil {
+			// Only the primary directory has to exist
+			if i == 0 {
+				return nil, fmt.Errorf("failed to read templates directory: %w", err)
+			}
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || isSchemaFile(file.Name()) {
+				continue
+			}
+			name := strings.TrimSuffix(file.Name(), ".tmpl")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// expandTemplatePatterns resolves template names and glob patterns into manifest entries
+func expandTemplatePatterns(patterns []string) ([]ManifestEntry, error) {
+	names, err := templateNames()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var entries []ManifestEntry
+	for _, pattern := range patterns {
+		if isRemoteTemplate(pattern) {
+			entries = append(entries, ManifestEntry{Template: pattern, Output: defaultOutputName(pattern)})
+			continue
+		}
+		pattern = strings.TrimSuffix(pattern, ".tmpl")
+		candidates := names
+		if strings.HasPrefix(pattern, builtinPrefix) {
+			candidates = nil
+			for _, name := range builtinTemplateNames() {
+				candidates = append(candidates, builtinPrefix+name)
+			}
+		}
+		matched := false
+		for _, name := range candidates {
+			ok, err := filepath.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+			if !ok {
+				continue
+			}
+			matched = true
+			if !seen[name] {
+				seen[name] = true
+				entries = append(entries, ManifestEntry{Template: name, Output: defaultOutputName(name)})
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no templates match '%s'", pattern)
+		}
+	}
+	return entries, nil
+}
+
+// defaultOutputName is the file a template renders to when no output is given
+func defaultOutputName(templateName string) string {
+	if isRemoteTemplate(templateName) {
+		templateName = remoteTemplateName(templateName)
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(templateName, ".tmpl"), builtinPrefix)
+}
+
+// applyTemplateSet renders several templates into outputDir in one invocation
+func applyTemplateSet(patterns []string, outputDir, manifestPath string) error {
+	var entries []ManifestEntry
+	if manifestPath != "" {
+		manifest, err := loadTemplateManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		entries = manifest.Templates
+	}
+	if len(patterns) > 0 {
+		expanded, err := expandTemplatePatterns(patterns)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, expanded...)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no templates selected")
+	}
+	given, err := templateVarAssignments()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if len(given) > 0 && entries[i].Vars == nil {
+			entries[i].Vars = make(map[string]interface{}, len(given))
+		}
+		for k, v := range given {
+			entries[i].Vars[k] = v
+		}
+	}
+
+	if outputDir == "" {
+		outputDir = "."
+	}
+
+	// Prompts come first, one template at a time, so the renders can run
+	// concurrently afterwards
+	type pending struct {
+		name         string
+		templatePath string
+		job          renderJob
+	}
+	var queue []pending
+
+	// Templates without a schema share one round of prompts
+	var shared map[string]interface{}
+
+	// Manifest entries may render the same template to several files, but no
+	// two entries may write the same file
+	outputs := make(map[string]string)
+
+	for _, entry := range entries {
+		templatePath, err := resolveTemplatePath(entry.Template)
+		if err != nil {
+			return err
+		}
+
+		schema, err := loadTemplateSchema(templatePath)
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := parseTemplateFile(templatePath, entry.Template)
+		if err != nil {
+			return err
+		}
+
+		var vars map[string]interface{}
+		switch {
+		case schema != nil:
+			if !promptsDisabled() {
+				fmt.Printf("\n%s:\n", entry.Template)
+			}
+			if vars, err = collectTemplateVars(schema.withoutVars(entry.Vars), promptsDisabled()); err != nil {
+				return err
+			}
+		default:
+			if shared == nil {
+				if shared, err = collectTemplateVars(nil, promptsDisabled()); err != nil {
+					return err
+				}
+			}
+			vars = shared
+		}
+		if vars, err = applyManifestVars(vars, entry.Vars, schema); err != nil {
+			return fmt.Errorf("%s: %w", entry.Template, err)
+		}
+
+		output := entry.Output
+		if output == "" {
+			output = defaultOutputName(entry.Template)
+		}
+		if output, err = renderOutputPath(output, vars); err != nil {
+			return fmt.Errorf("%s: %w", entry.Template, err)
+		}
+		outputFile := filepath.Join(outputDir, output)
+		if other, ok := outputs[filepath.Clean(outputFile)]; ok {
+			return fmt.Errorf("'%s' and '%s' both render to %s", other, entry.Template, outputFile)
+		}
+		outputs[filepath.Clean(outputFile)] = entry.Template
+
+		ok, err := confirmOverwrite(outputFile)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mode, err := templateFileMode(templatePath, entry.Mode)
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Template, err)
+		}
+		env := templateHookEnv(entry.Template, templatePath, outputFile)
+		if err := runConfiguredHooks(hookPreTemplateApply, "template", templateHookNames(entry.Template), env); err != nil {
+			return fmt.Errorf("%s: %w", entry.Template, err)
+		}
+
+		queue = append(queue, pending{entry.Template, templatePath, renderJob{tmpl, vars, outputFile, mode}})
+	}
+
+	// Save what the renders replace, so a failure can undo the whole run
+	txn := newApplyTransaction()
+	defer txn.discard()
+	jobs := make([]renderJob, len(queue))
+	for i, p := range queue {
+		if err := txn.track(p.job.outputFile); err != nil {
+			return fmt.Errorf("failed to save %s before rendering: %w", p.job.outputFile, err)
+		}
+		jobs[i] = p.job
+	}
+	errs := renderConcurrently(jobs, templateJobs)
+
+	// Report in manifest order; hooks only run once every template rendered
+	var firstErr error
+	applied := 0
+	for i, p := range queue {
+		switch {
+		case errs[i] == errRenderSkipped:
+		case errs[i] != nil:
+			// The first failure is returned; report any others here
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", p.name, errs[i])
+			} else {
+				fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("%s: %v", p.name, errs[i])))
+			}
+		default:
+			fmt.Printf("Rendered '%s' -> %s\n", p.name, p.job.outputFile)
+			applied++
+		}
+	}
+	if firstErr != nil {
+		if templateNoRollback {
+			return firstErr
+		}
+		if err := txn.rollback(); err != nil {
+			fmt.Fprintln(os.Stderr, ui.ErrRed(fmt.Sprintf("Failed to roll back every file: %v", err)))
+		} else if applied > 0 {
+			fmt.Fprintf(os.Stderr, "Rolled back %d rendered file(s); nothing was changed\n", applied)
+		}
+		return firstErr
+	}
+
+	for _, p := range queue {
+		recordTemplateUsage(p.name)
+	}
+	for _, p := range queue {
+		if err := runTemplateHooks(p.name, p.templatePath, p.job.outputFile, p.job.vars); err != nil {
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+		env := templateHookEnv(p.name, p.templatePath, p.job.outputFile)
+		warnHookFailure(runConfiguredHooks(hookPostTemplateApply, "template", templateHookNames(p.name), env))
+	}
+
+	fmt.Printf("\n%d template(s) applied to %s\n", applied, outputDir)
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplateManifestVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gen.yaml")
+	content := `vars:
+  Region: eu
+  Replicas: 1
+templates:
+  - template: svc
+    output: "{{.Env}}/svc.yaml"
+    vars:
+      Env: prod
+      Replicas: 3
+  - template: svc
+    output: "{{.Env}}/svc.yaml"
+    vars:
+      Env: dev
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := loadTemplateManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, dev := manifest.Templates[0].Vars, manifest.Templates[1].Vars
+	if prod["Region"] != "eu" || prod["Replicas"] != 3 || prod["Env"] != "prod" {
+		t.Errorf("Expected entry vars over the manifest's, got %v", prod)
+	}
+	if dev["Replicas"] != 1 || dev["Env"] != "dev" {
+		t.Errorf("Expected the manifest's vars as defaults, got %v", dev)
+	}
+}
+
+func TestRenderOutputPath(t *testing.T) {
+	vars := map[string]interface{}{"Env": "prod", "Name": "api"}
+	if got, err := renderOutputPath("{{.Env}}/{{.Name}}.yaml", vars); err != nil || got != "prod/api.yaml" {
+		t.Errorf("Unexpected path %q, %v", got, err)
+	}
+	if got, _ := renderOutputPath("plain.txt", nil); got != "plain.txt" {
+		t.Errorf("Expected a plain path unchanged, got %q", got)
+	}
+	if _, err := renderOutputPath("{{.Missing}}/x", vars); err == nil {
+		t.Error("Expected an unknown variable to be an error")
+	}
+	if _, err := renderOutputPath(`{{if false}}x{{end}}`, vars); err == nil {
+		t.Error("Expected an empty path to be an error")
+	}
+}
+
+func TestApplyManifestVars(t *testing.T) {
+	schema := &TemplateSchema{Variables: []TemplateVar{
+		{Name: "Port", Type: "int"},
+		{Name: "Env", Enum: []string{"dev", "prod"}},
+	}}
+	rest := schema.withoutVars(map[string]interface{}{"Port": 8080})
+	if len(rest.Variables) != 1 || rest.Variables[0].Name != "Env" || len(schema.Variables) != 2 {
+		t.Errorf("Expected only Env left to prompt for, got %+v", rest.Variables)
+	}
+
+	vars, err := applyManifestVars(map[string]interface{}{"Env": "dev"}, map[string]interface{}{"Port": "8080"}, schema)
+	if err != nil || vars["Port"] != 8080 || vars["Env"] != "dev" {
+		t.Errorf("Expected Port converted to an int, got %v, %v", vars, err)
+	}
+	if _, err := applyManifestVars(nil, map[string]interface{}{"Env": "staging"}, schema); err == nil {
+		t.Error("Expected a value outside the enum to be an error")
+	}
+}
+
+func TestApplyTemplateManifest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	templateNoInput = true
+	defer func() { templateNoInput = false }()
+
+	if err := os.MkdirAll(GetTemplatesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(GetTemplatesDir(), "svc.tmpl"), []byte("env={{.Env}} port={{.Port}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema := "variables:\n  - name: Env\n    required: true\n  - name: Port\n    type: int\n    default: 80\n"
+	if err := os.WriteFile(filepath.Join(GetTemplatesDir(), "svc.vars.yaml"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "gen.yaml")
+	content := `templates:
+  - template: svc
+    output: "{{.Env}}/svc.conf"
+    vars: {Env: prod, Port: 443}
+  - template: svc
+    output: "{{.Env}}/svc.conf"
+    vars: {Env: dev}
+`
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := applyTemplateSet(nil, out, manifest); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"prod/svc.conf": "env=prod port=443\n",
+		"dev/svc.conf":  "env=dev port=80\n",
+	} {
+		if data, _ := os.ReadFile(filepath.Join(out, path)); string(data) != want {
+			t.Errorf("Expected %s to be %q, got %q", path, want, data)
+		}
+	}
+
+	clash := strings.Replace(content, "Env: dev", "Env: prod", 1)
+	if err := os.WriteFile(manifest, []byte(clash), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templateForce = true
+	defer func() { templateForce = false }()
+	if err := applyTemplateSet(nil, out, manifest); err == nil || !strings.Contains(err.Error(), "both render to") {
+		t.Errorf("Expected two entries writing one file to be an error, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// builtinPrefix selects a template from the embedded gallery, e.g. "builtin/gitignore"
+const builtinPrefix = "builtin/"
+
+//go:embed builtin
+var builtinTemplates embed.FS
+
+var templateExportForce bool
+
+// templateExportCmd copies built-in templates into the templates directory
+var templateExportCmd = &cobra.Command{
+	Use:   "export-builtin [name...]",
+	Short: "Copy built-in templates into your templates directory",
+	Long: `Copy one or more built-in templates into your templates directory so they
+can be customized. The copy is applied by its plain name, while
+"builtin/<name>" keeps referring to the original.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportBuiltinTemplates(args, templateExportForce)
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateExportCmd)
+
+	// Flags
+	templateExportCmd.Flags().BoolVarP(&templateExportForce, "force", "f", false, "Overwrite existing templates")
+}
+
+// isBuiltinPath reports whether a resolved template path points into the embedded gallery
+func isBuiltinPath(templatePath string) bool {
+	return strings.HasPrefix(filepath.ToSlash(templatePath), builtinPrefix)
+}
+
+// readTemplateFile reads a template or schema from disk or from the embedded gallery
+func readTemplateFile(templatePath string) ([]byte, error) {
+	if isBuiltinPath(templatePath) {
+		return builtinTemplates.ReadFile(filepath.ToSlash(templatePath))
+	}
+	return os.ReadFile(templatePath)
+}
+
+// resolveBuiltinPath finds an embedded template with or without the .tmpl extension
+func resolveBuiltinPath(templateName string) (string, error) {
+	name := strings.TrimPrefix(templateName, builtinPrefix)
+	for _, candidate := range []string{name, name + ".tmpl"} {
+		p := path.Join("builtin", candidate)
+		if _, err := fs.Stat(builtinTemplates, p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("built-in template '%s' not found (see 'berga template list --builtin')", name)
+}
+
+// builtinTemplateNames returns the names of the embedded templates, without prefix
+func builtinTemplateNames() []string {
+	entries, _ := builtinTemplates.ReadDir("builtin")
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || isSchemaFile(entry.Name()) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func listBuiltinTemplates() error {
+	ui.Header("Built-in Templates")
+
+	for _, name := range builtinTemplateNames() {
+		size := ""
+		if data, err := builtinTemplates.ReadFile(path.Join("builtin", name+".tmpl")); err == nil {
+			size = humanizeSize(int64(len(data)))
+		}
+		fmt.Printf("  %s %s %s\n", ui.Icon("📦", "-"), ui.Bold(builtinPrefix+name), ui.Dim("("+size+")"))
+	}
+
+	fmt.Println("\nApply one with: berga template apply builtin/<name> <output-file>")
+	fmt.Println("Customize one with: berga template export-builtin <name>")
+	return nil
+}
+
+func exportBuiltinTemplates(names []string, force bool) error {
+	templatesDir := GetTemplatesDir()
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	for _, name := range names {
+		src, err := resolveBuiltinPath(name)
+		if err != nil {
+			return err
+		}
+
+		// Copy the template along with its variable schema, if it has one
+		files := []string{src}
+		if _, err := fs.Stat(builtinTemplates, schemaPathFor(src)); err == nil {
+			files = append(files, schemaPathFor(src))
+		}
+
+		for _, file := range files {
+			dest := filepath.Join(templatesDir, path.Base(file))
+			if _, err := os.Stat(dest); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", dest)
+			}
+
+			data, err := builtinTemplates.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read built-in template: %w", err)
+			}
+			err = trackChange("template", dest, revisionExport, func() error {
+				return writeFileAtomic(dest, data, 0644)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write template: %w", err)
+			}
+		}
+		fmt.Printf("Exported '%s' to %s\n", builtinPrefix+strings.TrimPrefix(name, builtinPrefix), templatesDir)
+	}
+	return nil
+}
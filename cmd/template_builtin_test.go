@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestBuiltinTemplatesParse(t *testing.T) {
+	names := builtinTemplateNames()
+	if len(names) == 0 {
+		t.Fatal("Expected built-in templates to be embedded")
+	}
+
+	for _, name := range names {
+		templatePath, err := resolveTemplatePath(builtinPrefix + name)
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", name, err)
+		}
+		if _, err := parseTemplateFile(templatePath, name); err != nil {
+			t.Errorf("Built-in template %s does not parse: %v", name, err)
+		}
+	}
+}
+
+func TestResolveBuiltinPathMissing(t *testing.T) {
+	if _, err := resolveBuiltinPath("builtin/does-not-exist"); err == nil {
+		t.Error("Expected an error for an unknown built-in template")
+	}
+}
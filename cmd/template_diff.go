@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// isDriftTarget reports whether the second argument of 'template diff' names
+// a file to compare with rather than a revision. Revision numbers always
+// mean revisions; write ./3 for a file called 3.
+func isDriftTarget(arg string) bool {
+	if _, err := strconv.Atoi(arg); err == nil {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// renderTemplateString renders a template with the current variables, without
+// prompting: config and project variables, schema defaults, and --answers
+func renderTemplateString(templateName string) (string, error) {
+	templatePath, err := resolveTemplatePath(templateName)
+	if err != nil {
+		return "", err
+	}
+	schema, err := loadTemplateSchema(templatePath)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := parseTemplateFile(templatePath, templateName)
+	if err != nil {
+		return "", err
+	}
+	vars, err := collectTemplateVars(schema, true)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := executeTemplate(tmpl, vars, &out, nil); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// templateDrift returns a diff from file to what the template renders to
+// now, or "" if they match. A file holding the template's block from
+// 'template apply --merge' is compared on that block only.
+func templateDrift(templateName, file string) (string, error) {
+	current, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	rendered, err := renderTemplateString(templateName)
+	if err != nil {
+		return "", err
+	}
+
+	want := rendered
+	if merged, err := mergeContent(string(current), rendered, mergeReplaceSection, mergeSectionName(templateName), commentStyleFor(file)); err == nil {
+		want = merged
+	}
+	if want == string(current) {
+		return "", nil
+	}
+	return unifiedDiff(file, templateName+" (rendered)", string(current), want), nil
+}
+
+// diffTemplateFile shows how a file has drifted from its template
+func diffTemplateFile(templateName, file string) error {
+	diff, err := templateDrift(templateName, file)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("%s matches template '%s'\n", file, templateName)
+		return nil
+	}
+	fmt.Print(colorDiff(diff))
+	return nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestTemplateDrift(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetTemplatesDir(), 0755)
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "gitconfig.tmpl"), []byte("[user]\n\tname = {{.Author}}\n"), 0644)
+	viper.Set("templates.author", "Ada")
+	defer viper.Set("templates.author", nil)
+
+	dir := t.TempDir()
+	live := filepath.Join(dir, ".gitconfig")
+	os.WriteFile(live, []byte("[user]\n\tname = Ada\n"), 0644)
+	if diff, err := templateDrift("gitconfig", live); err != nil || diff != "" {
+		t.Errorf("Expected no drift, got %q, %v", diff, err)
+	}
+
+	os.WriteFile(live, []byte("[user]\n\tname = Someone\n"), 0644)
+	diff, err := templateDrift("gitconfig", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "-\tname = Someone") || !strings.Contains(diff, "+\tname = Ada") {
+		t.Errorf("Expected the drifted line in the diff, got:\n%s", diff)
+	}
+
+	// Only the merged block counts in a file that holds one
+	merged := filepath.Join(dir, "config")
+	os.WriteFile(merged, []byte("other = 1\n# >>> berga:gitconfig >>>\n[user]\n\tname = Ada\n# <<< berga:gitconfig <<<\n"), 0644)
+	if diff, err := templateDrift("gitconfig", merged); err != nil || diff != "" {
+		t.Errorf("Expected the merged block to match, got %q, %v", diff, err)
+	}
+}
+
+func TestIsDriftTarget(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "3")
+	os.WriteFile(file, nil, 0644)
+	if !isDriftTarget(file) {
+		t.Error("Expected an existing file to be a drift target")
+	}
+	if isDriftTarget("3") || isDriftTarget(dir) || isDriftTarget("abcd1234") {
+		t.Error("Expected revisions and directories not to be drift targets")
+	}
+}
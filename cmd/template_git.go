@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// gitTemplateVarNames are the variables gitTemplateVars sets
+var gitTemplateVarNames = []string{"GitBranch", "GitRemoteURL", "GitUserName", "GitUserEmail", "GitCommitShort"}
+
+// gitTemplateVars describes the git repository containing dir (the current
+// directory when empty). Every variable is set, to an empty string outside a
+// repository, when git is not installed, or when a value does not apply, such
+// as the branch of a detached HEAD or the commit of an empty repository.
+func gitTemplateVars(dir string) map[string]interface{} {
+	vars := make(map[string]interface{})
+	for _, name := range gitTemplateVarNames {
+		vars[name] = ""
+	}
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if git("rev-parse", "--is-inside-work-tree") != "true" {
+		return vars
+	}
+
+	vars["GitBranch"] = git("symbolic-ref", "--short", "-q", "HEAD")
+	vars["GitRemoteURL"] = redactRemoteURL(git("remote", "get-url", "origin"))
+	vars["GitUserName"] = git("config", "user.name")
+	vars["GitUserEmail"] = git("config", "user.email")
+	vars["GitCommitShort"] = git("rev-parse", "--short", "HEAD")
+	return vars
+}
+
+// redactRemoteURL drops credentials from an https remote so a token embedded
+// in the URL never ends up in rendered files
+func redactRemoteURL(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
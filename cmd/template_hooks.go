@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// templateHooks returns the post-render commands a template declares in its
+// header, e.g. {{/* berga:hook: go mod init {{.ProjectName}} */}}
+func templateHooks(templatePath string) ([]string, error) {
+	content, err := readTemplateFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	var hooks []string
+	for _, entry := range parseMetadata(bytes.NewReader(content)) {
+		if entry.Key == "hook" && entry.Value != "" {
+			hooks = append(hooks, entry.Value)
+		}
+	}
+	return hooks, nil
+}
+
+// renderHook expands template variables in a hook command. String values are
+// quoted for the hook shell, so a value can't add commands of its own.
+func renderHook(hook string, vars map[string]interface{}) (string, error) {
+	tmpl, err := parseTemplateSource("hook", hook)
+	if err != nil {
+		return "", err
+	}
+	quoted, err := quoteHookVars(vars)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, quoted); err != nil {
+		return "", fmt.Errorf("failed to render hook: %w", err)
+	}
+	return out.String(), nil
+}
+
+// quoteHookVars returns a copy of vars with every string value, including
+// those in lists, quoted for the hook shell
+func quoteHookVars(vars map[string]interface{}) (map[string]interface{}, error) {
+	shell := hookShellKind(hookShell())
+	quoted := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		var err error
+		switch v := value.(type) {
+		case string:
+			value, err = quoteHookValue(shell, v)
+		case []string:
+			list := make([]string, len(v))
+			for i := range v {
+				if list[i], err = quoteHookValue(shell, v[i]); err != nil {
+					break
+				}
+			}
+			value = list
+		case []interface{}:
+			list := append([]interface{}(nil), v...)
+			for i := range list {
+				if str, ok := list[i].(string); ok {
+					if list[i], err = quoteHookValue(shell, str); err != nil {
+						break
+					}
+				}
+			}
+			value = list
+		}
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %w", key, err)
+		}
+		quoted[key] = value
+	}
+	return quoted, nil
+}
+
+// quoteHookValue quotes s for the given hook shell when it needs quoting. cmd
+// has no quoting that stops every metacharacter, so such values are refused.
+func quoteHookValue(shell, s string) (string, error) {
+	switch shell {
+	case "cmd":
+		if strings.ContainsAny(s, "\"%^&|<>()!\r\n") {
+			return "", fmt.Errorf("value %q can't be passed safely to cmd", s)
+		}
+		if strings.ContainsAny(s, " \t") {
+			return `"` + s + `"`, nil
+		}
+		return s, nil
+	case "powershell", "pwsh":
+		if s != "" && !strings.ContainsAny(s, " \t\n'\"`$|&;<>(){}@#,") {
+			return s, nil
+		}
+		return powershellQuote(s), nil
+	}
+	return shellQuote(s), nil
+}
+
+// hookShell returns the configured hook shell, or sh (cmd on Windows) when
+// none is set
+func hookShell() string {
+	shell := viper.GetString("shell")
+	if shell == "" {
+		if runtime.GOOS == "windows" {
+			return "cmd"
+		}
+		return "sh"
+	}
+	return shell
+}
+
+// hookShellKind returns the name of a shell without directory or .exe, e.g.
+// "cmd" for C:\Windows\System32\cmd.exe
+func hookShellKind(shell string) string {
+	return strings.ToLower(strings.TrimSuffix(filepath.Base(shell), ".exe"))
+}
+
+// hookCommand runs a command line through the configured shell, or sh
+// (cmd on Windows) when none is set
+func hookCommand(command string) *exec.Cmd {
+	return hookCommandContext(context.Background(), command)
+}
+
+// hookCommandContext is hookCommand with a context that stops the command
+func hookCommandContext(ctx context.Context, command string) *exec.Cmd {
+	shell := hookShell()
+	switch hookShellKind(shell) {
+	case "cmd":
+		return exec.CommandContext(ctx, shell, "/C", command)
+	case "powershell", "pwsh":
+		return exec.CommandContext(ctx, shell, "-NoProfile", "-Command", command)
+	}
+	return exec.CommandContext(ctx, shell, "-c", command)
+}
+
+// confirmRemoteHooks asks before running commands that came from a remote
+// template. The commands are shown as they will run, with variables filled
+// in. --assume-yes runs them; without a prompt they are skipped.
+func confirmRemoteHooks(commands []string) bool {
+	fmt.Fprintln(promptOut, "This remote template wants to run:")
+	for _, command := range commands {
+		fmt.Fprintf(promptOut, "  %s\n", command)
+	}
+	return confirm(promptOut, "Run these hooks?", false)
+}
+
+// runTemplateHooks runs a template's post-render hooks in the directory of the
+// rendered file. BERGA_OUTPUT holds the rendered file's absolute path.
+func runTemplateHooks(templateName, templatePath, outputFile string, vars map[string]interface{}) error {
+	if templateNoHooks {
+		return nil
+	}
+	hooks, err := templateHooks(templatePath)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	// Render every hook before asking, so what is approved is what runs
+	commands := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		command, err := renderHook(hook, vars)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, command)
+	}
+
+	if isRemoteTemplate(templateName) && !confirmRemoteHooks(commands) {
+		fmt.Fprintln(promptOut, "Skipped hooks.")
+		return nil
+	}
+
+	output, err := filepath.Abs(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+
+	for _, command := range commands {
+		fmt.Printf("%s %s\n", ui.Dim(ui.Icon("🪝", ">")), command)
+
+		cmd := hookCommand(command)
+		cmd.Dir = filepath.Dir(output)
+		cmd.Env = append(os.Environ(), "BERGA_OUTPUT="+output)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook '%s' failed: %w", command, err)
+		}
+	}
+	return nil
+}
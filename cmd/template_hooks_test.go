@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const hookTemplate = `{{/* berga:hook: echo {{.Name}} > hook-{{.Name}}.txt */}}
+{{- /* berga:hook: cat "$BERGA_OUTPUT" >> hook-{{.Name}}.txt */ -}}
+Hello {{.Name}}
+`
+
+func TestTemplateHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greet.tmpl")
+	if err := os.WriteFile(path, []byte(hookTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks, err := templateHooks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"echo {{.Name}} > hook-{{.Name}}.txt",
+		`cat "$BERGA_OUTPUT" >> hook-{{.Name}}.txt`,
+	}
+	if !reflect.DeepEqual(hooks, want) {
+		t.Errorf("Expected %q, got %q", want, hooks)
+	}
+
+	command, err := renderHook(hooks[0], map[string]interface{}{"Name": "x"})
+	if err != nil || command != "echo x > hook-x.txt" {
+		t.Errorf("Unexpected rendered hook %q (err %v)", command, err)
+	}
+}
+
+func TestRunTemplateHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use POSIX shell syntax")
+	}
+
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "greet.tmpl")
+	if err := os.WriteFile(templatePath, []byte(hookTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(outDir, "greeting.txt")
+
+	tmpl, err := parseTemplateFile(templatePath, "greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{"Name": "world"}
+	if err := renderTemplateToFile(tmpl, vars, outputFile, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runTemplateHooks("greet", templatePath, outputFile, vars); err != nil {
+		t.Fatalf("Hooks failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "hook-world.txt"))
+	if err != nil {
+		t.Fatalf("Expected hook to run in the output directory: %v", err)
+	}
+	if got := string(data); got != "world\nHello world\n" {
+		t.Errorf("Unexpected hook output %q", got)
+	}
+
+	templateNoHooks = true
+	defer func() { templateNoHooks = false }()
+	os.Remove(filepath.Join(outDir, "hook-world.txt"))
+	if err := runTemplateHooks("greet", templatePath, outputFile, vars); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "hook-world.txt")); !os.IsNotExist(err) {
+		t.Error("Expected --no-hooks to skip hooks")
+	}
+}
+
+func TestRunTemplateHooksFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use POSIX shell syntax")
+	}
+
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "bad.tmpl")
+	if err := os.WriteFile(templatePath, []byte("# berga:hook: exit 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := runTemplateHooks("bad", templatePath, filepath.Join(dir, "bad.txt"), nil)
+	if err == nil || !strings.Contains(err.Error(), "exit 3") {
+		t.Errorf("Expected the failing hook to be reported, got %v", err)
+	}
+}
+
+func TestRenderHookQuotesValues(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("quoting in this test is for POSIX shells")
+	}
+	vars := map[string]interface{}{
+		"Name":  "x; rm -rf ~",
+		"Plain": "demo",
+		"Tags":  []interface{}{"a b", "c"},
+		"Port":  8080,
+	}
+	command, err := renderHook("echo {{.Name}} {{.Plain}} {{range .Tags}}{{.}} {{end}}{{.Port}}", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "echo 'x; rm -rf ~' demo 'a b' c 8080"; command != want {
+		t.Errorf("Expected %q, got %q", want, command)
+	}
+	if vars["Name"] != "x; rm -rf ~" {
+		t.Error("Expected the caller's vars to be left alone")
+	}
+}
+
+func TestQuoteHookValueCmd(t *testing.T) {
+	if got, err := quoteHookValue("cmd", "my app"); err != nil || got != `"my app"` {
+		t.Errorf("Expected a quoted value, got %q (err %v)", got, err)
+	}
+	if _, err := quoteHookValue("cmd", "x & del *"); err == nil {
+		t.Error("Expected cmd metacharacters to be refused")
+	}
+	if got, _ := quoteHookValue("pwsh", "it's"); got != "'it''s'" {
+		t.Errorf("Unexpected PowerShell quoting %q", got)
+	}
+}
+
+func TestRunTemplateHooksRemoteShowsRendered(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use POSIX shell syntax")
+	}
+	setTerminal(t, false)
+	var prompts bytes.Buffer
+	origOut := promptOut
+	promptOut = &prompts
+	defer func() { promptOut = origOut }()
+
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "greet.tmpl")
+	if err := os.WriteFile(templatePath, []byte(hookTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(dir, "greeting.txt")
+	vars := map[string]interface{}{"Name": "$(touch pwned)"}
+
+	if err := runTemplateHooks("https://example.com/greet.tmpl", templatePath, outputFile, vars); err != nil {
+		t.Fatal(err)
+	}
+	out := prompts.String()
+	if !strings.Contains(out, "echo '$(touch pwned)' > hook-'$(touch pwned)'.txt") {
+		t.Errorf("Expected the rendered commands in the confirmation, got %q", out)
+	}
+	if !strings.Contains(out, "Skipped hooks.") {
+		t.Errorf("Expected remote hooks to be skipped without a prompt, got %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Error("Expected no hook to run")
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// templateShellTimeout bounds a single shell call in a template
+const templateShellTimeout = 30 * time.Second
+
+var templateAllowExec bool
+
+// templateFuncs returns the functions available to templates. include
+// resolves relative paths against the current directory until a template
+// file binds it to its own; see parseTemplateFile.
+func templateFuncs() template.FuncMap {
+	funcs := hostTemplatePlatform().funcs()
+	for name, fn := range contentFuncs("", false) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// contentFuncs returns the include and shell template functions. include
+// reads a file inside baseDir, or the current directory when baseDir is
+// empty; remote templates need --allow-exec to include files at all. shell
+// runs a command in the current directory and returns its output without the
+// trailing newline; it needs --allow-exec, or templates.allow_exec for
+// templates that are not remote.
+func contentFuncs(baseDir string, remote bool) template.FuncMap {
+	return template.FuncMap{
+		"include": func(path string) (string, error) {
+			if remote && !templateAllowExec {
+				return "", fmt.Errorf("include %q: reading files from a remote template needs --allow-exec", path)
+			}
+			resolved, err := includePath(baseDir, path)
+			if err != nil {
+				return "", err
+			}
+			data, err := os.ReadFile(resolved)
+			if err != nil {
+				return "", fmt.Errorf("include: %w", err)
+			}
+			return string(data), nil
+		},
+		"shell": func(command string) (string, error) {
+			if !templateAllowExec && (remote || !viper.GetBool("templates.allow_exec")) {
+				return "", fmt.Errorf("shell %q: running commands needs --allow-exec", command)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), templateShellTimeout)
+			defer cancel()
+			cmd := hookCommandContext(ctx, command)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", fmt.Errorf("shell %q: timed out after %s", command, templateShellTimeout)
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return "", fmt.Errorf("shell %q: exit status %d: %s", command, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+			}
+			if err != nil {
+				return "", fmt.Errorf("shell %q: %w", command, err)
+			}
+			return strings.TrimRight(string(out), "\r\n"), nil
+		},
+	}
+}
+
+// includePath resolves an include path against baseDir and makes sure it,
+// and any symlink it goes through, stays inside baseDir
+func includePath(baseDir, path string) (string, error) {
+	if baseDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("include: %w", err)
+		}
+		baseDir = wd
+	}
+	target := expandHome(path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(baseDir, target)
+	}
+	if !pathWithin(baseDir, target) {
+		return "", fmt.Errorf("include %q: path is outside the template directory", path)
+	}
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", fmt.Errorf("include: %w", err)
+	}
+	resolvedBase, err := filepath.EvalSymlinks(baseDir)
+	if err != nil || !pathWithin(resolvedBase, resolved) {
+		return "", fmt.Errorf("include %q: path is outside the template directory", path)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIncludeTemplateFunc(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "parts"), 0755)
+	os.WriteFile(filepath.Join(dir, "parts", "common.yaml"), []byte("shared: true\n"), 0644)
+	path := filepath.Join(dir, "cfg.tmpl")
+	os.WriteFile(path, []byte(`name: {{.Name}}`+"\n"+`{{include "parts/common.yaml"}}`), 0644)
+
+	tmpl, err := parseTemplateFile(path, "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := executeTemplate(tmpl, map[string]interface{}{"Name": "api"}, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "name: api\nshared: true\n" {
+		t.Errorf("Expected the file to be included relative to the template, got %q", out.String())
+	}
+
+	os.WriteFile(path, []byte(`{{include "missing.yaml"}}`), 0644)
+	tmpl, _ = parseTemplateFile(path, "cfg")
+	if err := executeTemplate(tmpl, nil, &out, nil); err == nil {
+		t.Error("Expected a missing include to fail")
+	}
+}
+
+func TestShellTemplateFunc(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	defer func() { templateAllowExec = false }()
+	defer viper.Set("templates.allow_exec", nil)
+
+	shell := contentFuncs("", false)["shell"].(func(string) (string, error))
+	if _, err := shell("echo hi"); err == nil || !strings.Contains(err.Error(), "--allow-exec") {
+		t.Errorf("Expected shell to need --allow-exec, got %v", err)
+	}
+
+	viper.Set("templates.allow_exec", true)
+	if got, err := shell("echo v1.2.3"); err != nil || got != "v1.2.3" {
+		t.Errorf("Expected the command's output without the newline, got %q, %v", got, err)
+	}
+	if _, err := shell("echo oops >&2; exit 2"); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Expected a failing command to report its stderr, got %v", err)
+	}
+
+	remote := contentFuncs("", true)["shell"].(func(string) (string, error))
+	if _, err := remote("echo hi"); err == nil {
+		t.Error("Expected templates.allow_exec not to apply to remote templates")
+	}
+	templateAllowExec = true
+	if got, err := remote("echo hi"); err != nil || got != "hi" {
+		t.Errorf("Expected --allow-exec to apply to remote templates, got %q, %v", got, err)
+	}
+}
+
+func TestIncludeStaysInTemplateDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "id_ed25519")
+	os.WriteFile(secret, []byte("private"), 0600)
+	os.WriteFile(filepath.Join(os.Getenv("HOME"), "secret"), []byte("private"), 0600)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "part.txt"), []byte("ok"), 0644)
+	include := contentFuncs(dir, false)["include"].(func(string) (string, error))
+
+	if got, err := include("part.txt"); err != nil || got != "ok" {
+		t.Errorf("Expected a file next to the template to be included, got %q, %v", got, err)
+	}
+	if got, err := include(filepath.Join(dir, "part.txt")); err != nil || got != "ok" {
+		t.Errorf("Expected an absolute path inside the template directory to work, got %q, %v", got, err)
+	}
+	for _, path := range []string{secret, "../" + filepath.Base(outside) + "/id_ed25519", "~/secret"} {
+		if _, err := include(path); err == nil || !strings.Contains(err.Error(), "outside the template directory") {
+			t.Errorf("Expected %q to be refused, got %v", path, err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(secret, filepath.Join(dir, "link.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := include("link.txt"); err == nil || !strings.Contains(err.Error(), "outside the template directory") {
+			t.Errorf("Expected a symlink leaving the template directory to be refused, got %v", err)
+		}
+	}
+}
+
+func TestIncludeRemoteTemplate(t *testing.T) {
+	defer func() { templateAllowExec = false }()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "part.txt"), []byte("ok"), 0644)
+
+	include := contentFuncs(dir, true)["include"].(func(string) (string, error))
+	if _, err := include("part.txt"); err == nil || !strings.Contains(err.Error(), "--allow-exec") {
+		t.Errorf("Expected include in a remote template to need --allow-exec, got %v", err)
+	}
+	templateAllowExec = true
+	if got, err := include("part.txt"); err != nil || got != "ok" {
+		t.Errorf("Expected --allow-exec to allow includes inside the template, got %q, %v", got, err)
+	}
+	if _, err := include("~/.ssh/id_ed25519"); err == nil {
+		t.Error("Expected a remote template not to include files outside its directory")
+	}
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateLintAll    bool
+	templateLintStrict bool
+)
+
+// builtinTemplateFuncs are the functions text/template always provides
+var builtinTemplateFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf", "println",
+	"urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// templateLintCmd checks templates for mistakes before they are applied
+var templateLintCmd = &cobra.Command{
+	Use:   "lint [template-name-or-file...]",
+	Short: "Check templates for undefined variables, unknown functions, and unclosed blocks",
+	Long: `Run the checks of 'template validate' and report, with line numbers:
+
+  - variables that are not built in, set under templates.vars, or declared in
+    the template's .vars.yaml schema
+  - functions that are neither Go template functions nor berga's own
+  - blocks missing their {{end}}, an {{end}} too many, and other syntax errors
+  - stray "}}" in plain text, usually a typo for an action
+
+Arguments are template names or paths to template files, so the command can
+be run as a pre-commit hook on the staged files. --all lints every template.
+The command exits nonzero when an error is found, or any warning with --strict.`,
+	ValidArgsFunction: completeTemplateNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if len(args) == 0 && !templateLintAll {
+			return fmt.Errorf("give template names or files to lint, or --all")
+		}
+		if len(args) > 0 && templateLintAll {
+			return fmt.Errorf("--all does not take template names")
+		}
+		return lintTemplates(args)
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateLintCmd)
+
+	// Flags
+	templateLintCmd.Flags().BoolVar(&templateLintAll, "all", false, "Lint every template")
+	templateLintCmd.Flags().BoolVar(&templateLintStrict, "strict", false, "Exit nonzero on warnings as well as errors")
+}
+
+// knownTemplateFuncs returns the functions a template may call
+func knownTemplateFuncs() map[string]bool {
+	known := make(map[string]bool)
+	for _, name := range builtinTemplateFuncs {
+		known[name] = true
+	}
+	for name := range templateFuncs() {
+		known[name] = true
+	}
+	return known
+}
+
+// templateLintTargets returns the templates to lint, by name and path.
+// Arguments naming an existing file are linted as that file.
+func templateLintTargets(args []string) (map[string]string, error) {
+	var names []string
+	targets := make(map[string]string)
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+			targets[arg] = arg
+			continue
+		}
+		names = append(names, arg)
+	}
+	if len(names) == 0 && len(targets) > 0 {
+		return targets, nil
+	}
+	named, err := validateTargets(names)
+	if err != nil {
+		return nil, err
+	}
+	for name, path := range named {
+		targets[name] = path
+	}
+	return targets, nil
+}
+
+// parseErrorLine splits a text/template parse error into its line and message
+var parseErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+):\s*(.*)$`)
+
+// lintTemplate checks one template and returns its findings. Structural
+// problems are reported first; the variable checks and the sample render of
+// validateTemplate only run on templates that parse.
+func lintTemplate(templateName, templatePath string) []lintFinding {
+	finding := func(line int, severity, check, message string) lintFinding {
+		return lintFinding{Script: templateName, Line: line, Severity: severity, Check: check, Message: message}
+	}
+
+	content, err := readTemplateFile(templatePath)
+	if err != nil {
+		return []lintFinding{finding(0, severityError, "read", err.Error())}
+	}
+	source := string(content)
+
+	// Functions are checked separately below, so a misspelt one is reported
+	// by name along with every other problem instead of stopping the parse
+	tree := parse.New(templateName)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(source, "", "", trees); err != nil {
+		line, message := 0, err.Error()
+		if m := parseErrorLine.FindStringSubmatch(message); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			message = m[2]
+		}
+		check := "syntax"
+		switch {
+		case strings.Contains(message, "unexpected EOF"):
+			check, message = "unclosed", "a block is missing its {{end}}"
+		case strings.Contains(message, "unexpected {{end}}"):
+			check, message = "unclosed", "{{end}} without a matching block"
+		}
+		return []lintFinding{finding(line, severityError, check, message)}
+	}
+
+	var findings []lintFinding
+	known := knownTemplateFuncs()
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := trees[name]
+		if t.Root == nil {
+			continue
+		}
+		walkLintNode(t.Root, func(node parse.Node) {
+			line := lineOf(t, node)
+			switch n := node.(type) {
+			case *parse.IdentifierNode:
+				if !known[n.Ident] {
+					findings = append(findings, finding(line, severityError, "function", fmt.Sprintf("unknown function '%s'", n.Ident)))
+				}
+			case *parse.TextNode:
+				if i := strings.Index(string(n.Text), "}}"); i >= 0 {
+					line += strings.Count(string(n.Text[:i]), "\n")
+					findings = append(findings, finding(line, severityWarning, "delimiter", `stray "}}" in text; is an opening "{{" missing?`))
+				}
+			}
+		})
+	}
+	if hasErrorFinding(findings) {
+		return findings
+	}
+	return append(findings, validateTemplate(templateName, templatePath)...)
+}
+
+// hasErrorFinding reports whether any finding is an error
+func hasErrorFinding(findings []lintFinding) bool {
+	for _, f := range findings {
+		if f.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lineOf returns the line a node starts on
+func lineOf(t *parse.Tree, node parse.Node) int {
+	location, _ := t.ErrorContext(node)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+// walkLintNode calls fn for node and every node below it
+func walkLintNode(node parse.Node, fn func(parse.Node)) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			walkLintNode(child, fn)
+		}
+	case *parse.ActionNode:
+		walkLintNode(n.Pipe, fn)
+	case *parse.PipeNode:
+		for _, c := range n.Cmds {
+			walkLintNode(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkLintNode(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkLintNode(n.Node, fn)
+	case *parse.IfNode:
+		walkLintBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkLintBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkLintBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkLintNode(n.Pipe, fn)
+	}
+}
+
+func walkLintBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkLintNode(n.Pipe, fn)
+	walkLintNode(n.List, fn)
+	walkLintNode(n.ElseList, fn)
+}
+
+func lintTemplates(args []string) error {
+	targets, err := templateLintTargets(args)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No templates found.")
+		return nil
+	}
+
+	sorted := make([]string, 0, len(targets))
+	for name := range targets {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	counts := make(map[string]int)
+	for _, name := range sorted {
+		for _, f := range lintTemplate(name, targets[name]) {
+			counts[f.Severity]++
+			location := f.Script
+			if f.Line > 0 {
+				location = fmt.Sprintf("%s:%d", f.Script, f.Line)
+			}
+			fmt.Printf("  %s %s %s %s\n", severityLabel(f.Severity), ui.Bold(location), ui.Dim(f.Check+":"), f.Message)
+		}
+	}
+
+	fmt.Printf("\n%d template(s) checked: %d error(s), %d warning(s), %d info\n",
+		len(sorted), counts[severityError], counts[severityWarning], counts[severityInfo])
+	if counts[severityError] > 0 || (templateLintStrict && counts[severityWarning] > 0) {
+		return fmt.Errorf("lint found problems")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name, content string
+		check         string
+		severity      string
+		line          int
+	}{
+		{"ok", "{{.Author}}\n{{if isLinux}}{{printf \"%s\" .Year}}{{end}}\n", "", "", 0},
+		{"function", "{{.Author}}\n{{upper .Author}}\n", "function", severityError, 2},
+		{"missing-end", "{{if .Author}}\nx\n", "unclosed", severityError, 3},
+		{"extra-end", "x\n{{end}}\n", "unclosed", severityError, 2},
+		{"delimiter", "a\n{.Author}}\n", "delimiter", severityWarning, 2},
+		{"undefined", "{{.Missing}}\n", "undefined", severityWarning, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := lintTemplate(tt.name, write(tt.name+".tmpl", tt.content))
+			if tt.check == "" {
+				if len(findings) != 0 {
+					t.Errorf("Expected no findings, got %v", findings)
+				}
+				return
+			}
+			for _, f := range findings {
+				if f.Check == tt.check {
+					if f.Severity != tt.severity || f.Line != tt.line {
+						t.Errorf("Expected %s at line %d, got %+v", tt.severity, tt.line, f)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected a %s finding, got %v", tt.check, findings)
+		})
+	}
+}
+
+func TestLintTemplatesExitCode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.tmpl")
+	bad := filepath.Join(dir, "bad.tmpl")
+	os.WriteFile(good, []byte("{{.Author}}\n"), 0644)
+	os.WriteFile(bad, []byte("{{nope}}\n"), 0644)
+
+	if err := lintTemplates([]string{good}); err != nil {
+		t.Errorf("Expected a clean file to pass, got %v", err)
+	}
+	if err := lintTemplates([]string{good, bad}); err == nil {
+		t.Error("Expected an unknown function to fail the lint")
+	}
+}
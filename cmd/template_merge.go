@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Merge modes for 'template apply --merge'
+const (
+	mergeAppend         = "append"
+	mergePrepend        = "prepend"
+	mergeReplaceSection = "replace-section"
+)
+
+// commentStyle is how a file type writes a line comment
+type commentStyle struct {
+	Prefix string
+	Suffix string
+}
+
+// commentStyles maps file extensions to their comment syntax. Anything not
+// listed uses "#", which covers shell rc files, hosts, YAML, TOML, and most
+// config formats.
+var commentStyles = map[string]commentStyle{
+	".go":   {Prefix: "//"},
+	".js":   {Prefix: "//"},
+	".ts":   {Prefix: "//"},
+	".java": {Prefix: "//"},
+	".c":    {Prefix: "//"},
+	".h":    {Prefix: "//"},
+	".cpp":  {Prefix: "//"},
+	".rs":   {Prefix: "//"},
+	".css":  {Prefix: "/*", Suffix: " */"},
+	".sql":  {Prefix: "--"},
+	".lua":  {Prefix: "--"},
+	".ini":  {Prefix: ";"},
+	".vim":  {Prefix: `"`},
+	".html": {Prefix: "<!--", Suffix: " -->"},
+	".xml":  {Prefix: "<!--", Suffix: " -->"},
+	".md":   {Prefix: "<!--", Suffix: " -->"},
+}
+
+// commentStyleFor picks the comment syntax for a file
+func commentStyleFor(path string) commentStyle {
+	base := filepath.Base(path)
+	if base == ".vimrc" || base == ".gvimrc" {
+		return commentStyle{Prefix: `"`}
+	}
+	if style, ok := commentStyles[strings.ToLower(filepath.Ext(base))]; ok {
+		return style
+	}
+	return commentStyle{Prefix: "#"}
+}
+
+// validateMergeMode checks a --merge value
+func validateMergeMode(mode string) error {
+	switch mode {
+	case "", mergeAppend, mergePrepend, mergeReplaceSection:
+		return nil
+	}
+	return fmt.Errorf("invalid merge mode '%s' (expected append, prepend, or replace-section)", mode)
+}
+
+// mergeSectionName returns the marker name for a template's block: --section
+// if given, otherwise the template's file name without .tmpl
+func mergeSectionName(templateName string) string {
+	if templateSection != "" {
+		return templateSection
+	}
+	name := templateName
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "?"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSuffix(name, ".tmpl")
+}
+
+// sectionMarkers returns the lines that open and close a managed block
+func sectionMarkers(style commentStyle, section string) (string, string) {
+	begin := fmt.Sprintf("%s >>> berga:%s >>>%s", style.Prefix, section, style.Suffix)
+	end := fmt.Sprintf("%s <<< berga:%s <<<%s", style.Prefix, section, style.Suffix)
+	return begin, end
+}
+
+// mergeContent merges a rendered block into existing file content. The block
+// is wrapped in marker comments so that applying the template again replaces
+// it in place instead of adding a second copy. replace-section requires the
+// markers to be present already.
+func mergeContent(existing, rendered, mode, section string, style commentStyle) (string, error) {
+	begin, end := sectionMarkers(style, section)
+
+	rendered = strings.TrimRight(rendered, "\n")
+	block := begin + "\n"
+	if rendered != "" {
+		block += rendered + "\n"
+	}
+	block += end + "\n"
+
+	lines := strings.SplitAfter(existing, "\n")
+	start, stop := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 && trimmed == begin {
+			start = i
+		} else if start >= 0 && trimmed == end {
+			stop = i
+			break
+		}
+	}
+	if start >= 0 && stop < 0 {
+		return "", fmt.Errorf("section '%s' has no closing marker %q", section, end)
+	}
+
+	if start >= 0 {
+		return strings.Join(lines[:start], "") + block + strings.Join(lines[stop+1:], ""), nil
+	}
+
+	switch mode {
+	case mergeReplaceSection:
+		return "", fmt.Errorf("section '%s' not found (use --merge append or prepend to add it)", section)
+	case mergePrepend:
+		// Keep a shebang on the first line
+		if strings.HasPrefix(existing, "#!") {
+			first := lines[0]
+			if !strings.HasSuffix(first, "\n") {
+				first += "\n"
+			}
+			return first + block + strings.Join(lines[1:], ""), nil
+		}
+		return block + existing, nil
+	default:
+		if existing != "" && !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+		return existing + block, nil
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeContent(t *testing.T) {
+	hash := commentStyle{Prefix: "#"}
+	block := "# >>> berga:aliases >>>\nalias ll='ls -l'\n# <<< berga:aliases <<<\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		mode     string
+		want     string
+	}{
+		{"append to empty", "", mergeAppend, block},
+		{"append", "export A=1", mergeAppend, "export A=1\n" + block},
+		{"prepend", "export A=1\n", mergePrepend, block + "export A=1\n"},
+		{"prepend after shebang", "#!/bin/sh\necho hi\n", mergePrepend, "#!/bin/sh\n" + block + "echo hi\n"},
+		{"replace existing", "a\n# >>> berga:aliases >>>\nold\n# <<< berga:aliases <<<\nb\n", mergeReplaceSection, "a\n" + block + "b\n"},
+		{"append replaces existing", "a\n# >>> berga:aliases >>>\nold\n# <<< berga:aliases <<<\n", mergeAppend, "a\n" + block},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeContent(tt.existing, "alias ll='ls -l'\n", tt.mode, "aliases", hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := mergeContent("a\n", "x", mergeReplaceSection, "aliases", hash); err == nil {
+		t.Error("Expected replace-section to fail without markers")
+	}
+	if _, err := mergeContent("# >>> berga:aliases >>>\nx\n", "x", mergeAppend, "aliases", hash); err == nil {
+		t.Error("Expected an unclosed section to be an error")
+	}
+}
+
+func TestCommentStyleFor(t *testing.T) {
+	tests := map[string]string{
+		".zshrc":     "#",
+		"/etc/hosts": "#",
+		"main.go":    "//",
+		"index.html": "<!--",
+		".vimrc":     `"`,
+	}
+	for path, want := range tests {
+		if got := commentStyleFor(path).Prefix; got != want {
+			t.Errorf("commentStyleFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRenderTemplateToFileMerge(t *testing.T) {
+	output := filepath.Join(t.TempDir(), ".zshrc")
+	if err := os.WriteFile(output, []byte("export EDITOR=vim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templateMerge = mergeAppend
+	defer func() { templateMerge = "" }()
+
+	tmpl, err := parseTemplateSource("aliases.tmpl", "alias g={{.Git}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, git := range []string{"git", "hub"} {
+		if err := renderTemplateToFile(tmpl, map[string]interface{}{"Git": git}, output, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := os.ReadFile(output)
+	want := "export EDITOR=vim\n# >>> berga:aliases >>>\nalias g=hub\n# <<< berga:aliases <<<\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+	if strings.Count(string(data), "berga:aliases >>>") != 1 {
+		t.Error("Expected applying twice to keep a single block")
+	}
+}
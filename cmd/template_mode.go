@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var templateMode string
+
+// parseFileMode parses permissions written in octal, such as 0755 or 644
+func parseFileMode(raw string) (os.FileMode, error) {
+	raw = strings.TrimSpace(raw)
+	n, err := strconv.ParseUint(strings.TrimPrefix(raw, "0o"), 8, 32)
+	if err != nil || n == 0 || n > 0777 {
+		return 0, fmt.Errorf("invalid mode '%s' (expected octal permissions such as 0644 or 0755)", raw)
+	}
+	return os.FileMode(n), nil
+}
+
+// templateFileMode returns the permissions a rendered file gets: from --mode,
+// then declared (a manifest entry's mode), then the template's header:
+//
+//	{{/* berga:mode: 0750 */}}
+//	{{/* berga:executable: true */}}
+//
+// executable means 0755. Zero leaves the default: an existing file's own
+// permissions, or 0644.
+func templateFileMode(templatePath, declared string) (os.FileMode, error) {
+	if templateMode != "" {
+		return parseFileMode(templateMode)
+	}
+	if declared != "" {
+		return parseFileMode(declared)
+	}
+
+	content, err := readTemplateFile(templatePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read template: %w", err)
+	}
+	for _, entry := range parseMetadata(bytes.NewReader(content)) {
+		switch entry.Key {
+		case "mode":
+			return parseFileMode(entry.Value)
+		case "executable":
+			executable, err := strconv.ParseBool(entry.Value)
+			if err != nil {
+				return 0, fmt.Errorf("invalid berga:executable: value '%s' (expected true or false)", entry.Value)
+			}
+			if executable {
+				return 0755, nil
+			}
+		}
+	}
+	return 0, nil
+}
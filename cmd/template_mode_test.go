@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	for raw, want := range map[string]os.FileMode{"0755": 0755, "644": 0644, "0o600": 0600} {
+		if got, err := parseFileMode(raw); err != nil || got != want {
+			t.Errorf("parseFileMode(%q) = %o, %v; want %o", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"rwx", "0", "0999", "10000"} {
+		if _, err := parseFileMode(raw); err == nil {
+			t.Errorf("Expected parseFileMode(%q) to fail", raw)
+		}
+	}
+}
+
+func TestTemplateFileMode(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	script := write("run.sh.tmpl", "{{/* berga:executable: true */}}\n#!/bin/sh\n")
+	unit := write("app.service.tmpl", "{{/* berga:mode: 0600 */}}\n[Unit]\n")
+	plain := write("README.md.tmpl", "# {{.Name}}\n")
+
+	for path, want := range map[string]os.FileMode{script: 0755, unit: 0600, plain: 0} {
+		if got, err := templateFileMode(path, ""); err != nil || got != want {
+			t.Errorf("templateFileMode(%s) = %o, %v; want %o", filepath.Base(path), got, err, want)
+		}
+	}
+	if got, _ := templateFileMode(unit, "0640"); got != 0640 {
+		t.Errorf("Expected a manifest mode to win over the header, got %o", got)
+	}
+	templateMode = "0700"
+	defer func() { templateMode = "" }()
+	if got, _ := templateFileMode(unit, "0640"); got != 0700 {
+		t.Errorf("Expected --mode to win, got %o", got)
+	}
+}
+
+func TestRenderedFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not kept on Windows")
+	}
+	tmpl, err := parseTemplateSource("t", "#!/bin/sh\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(output, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderTemplateToFile(tmpl, nil, output, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(output); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the mode to replace the existing permissions, got %v", info.Mode())
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var templateNewFrom string
+
+// templateNewCmd creates a new template
+var templateNewCmd = &cobra.Command{
+	Use:   "new [template-name]",
+	Short: "Create a new template",
+	Long: `Create a new template in your templates directory.
+
+With --from, the template is seeded from an existing file. Values berga
+knows about (author, email, project name, date, and year) are detected in
+the file and you are asked whether to replace each one with its template
+variable. With --no-input, the file is copied without replacements.
+
+  berga template new license --from LICENSE`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return newTemplate(args[0], templateNewFrom)
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateNewCmd)
+
+	// Flags
+	templateNewCmd.Flags().StringVar(&templateNewFrom, "from", "", "Seed the template from an existing file")
+	templateNewCmd.Flags().BoolVar(&templateNoInput, "no-input", false, "Do not prompt for substitutions")
+}
+
+// substitution is a literal value that can be replaced by a template variable
+type substitution struct {
+	Var   string
+	Value string
+}
+
+// substitutionCandidates returns the known values worth replacing, most
+// specific first so that e.g. a date is replaced before the year inside it
+func substitutionCandidates() []substitution {
+	vars, _ := collectTemplateVars(nil, true)
+
+	var candidates []substitution
+	for _, name := range []string{"Date", "Email", "Author", "ProjectName", "Year"} {
+		value := strings.TrimSpace(fmt.Sprint(vars[name]))
+		// Very short values match too much to be useful
+		if len(value) < 3 {
+			continue
+		}
+		candidates = append(candidates, substitution{Var: name, Value: value})
+	}
+	return candidates
+}
+
+// escapeTemplateText makes literal text safe to parse as a Go template
+func escapeTemplateText(content string) string {
+	return strings.ReplaceAll(content, "{{", `{{"{{"}}`)
+}
+
+// templatizeContent turns file content into template source, asking confirm
+// for each detected substitution
+func templatizeContent(content string, candidates []substitution, confirm func(s substitution, count int) bool) string {
+	content = escapeTemplateText(content)
+	for _, s := range candidates {
+		count := strings.Count(content, s.Value)
+		if count == 0 || !confirm(s, count) {
+			continue
+		}
+		content = strings.ReplaceAll(content, s.Value, "{{."+s.Var+"}}")
+	}
+	return content
+}
+
+func newTemplate(templateName, from string) error {
+	if strings.HasPrefix(templateName, builtinPrefix) {
+		return fmt.Errorf("template names may not start with '%s'", builtinPrefix)
+	}
+
+	templatesDir := GetTemplatesDir()
+	templatePath := filepath.Join(templatesDir, strings.TrimSuffix(templateName, ".tmpl")+".tmpl")
+	if _, err := resolveTemplatePath(templateName); err == nil {
+		return fmt.Errorf("template '%s' already exists", templateName)
+	}
+
+	content := fmt.Sprintf("{{/* %s template - see 'berga template edit %s' */}}\n", templateName, templateName)
+	if from != "" {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return fmt.Errorf("failed to read source file: %w", err)
+		}
+
+		content = templatizeContent(string(data), substitutionCandidates(), func(s substitution, count int) bool {
+			if templateNoInput {
+				return false
+			}
+			return confirm(os.Stdout, fmt.Sprintf("Replace '%s' (%d occurrence(s)) with {{.%s}}?", s.Value, count, s.Var), true)
+		})
+	}
+
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	// Make sure the result still parses before saving it
+	if _, err := parseTemplateSource(templateName, content); err != nil {
+		return err
+	}
+	err := trackChange("template", templatePath, revisionCreate, func() error {
+		return writeFileAtomic(templatePath, []byte(content), 0644)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+
+	fmt.Printf("Template '%s' created: %s\n", templateName, templatePath)
+	return nil
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestTemplatizeContent(t *testing.T) {
+	candidates := []substitution{
+		{Var: "Date", Value: "2024-05-01"},
+		{Var: "Author", Value: "Jane Doe"},
+		{Var: "Year", Value: "2024"},
+	}
+	content := "Copyright 2024 Jane Doe\nReleased 2024-05-01\nUse {{ braces }}\n"
+
+	got := templatizeContent(content, candidates, func(s substitution, count int) bool {
+		return s.Var != "Author"
+	})
+	want := "Copyright {{.Year}} Jane Doe\nReleased {{.Date}}\nUse {{\"{{\"}} braces }}\n"
+	if got != want {
+		t.Errorf("templatizeContent() = %q, want %q", got, want)
+	}
+
+	if _, err := parseTemplateSource("test", got); err != nil {
+		t.Errorf("Result does not parse: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// platformTemplateVarNames are the variables templatePlatform sets
+var platformTemplateVarNames = []string{"OS", "Arch", "Hostname", "User"}
+
+// templatePlatform describes the machine a template is rendered on, so one
+// template can produce different content per OS or host
+type templatePlatform struct {
+	GOOS     string
+	GOARCH   string
+	Hostname string
+	User     string
+}
+
+// hostTemplatePlatform describes the machine berga is running on
+func hostTemplatePlatform() templatePlatform {
+	p := templatePlatform{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	p.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		// Windows names users DOMAIN\name
+		p.User = u.Username[strings.LastIndex(u.Username, `\`)+1:]
+	} else {
+		p.User = os.Getenv("USER")
+	}
+	return p
+}
+
+// vars returns the platform template variables
+func (p templatePlatform) vars() map[string]interface{} {
+	return map[string]interface{}{
+		"OS":       p.GOOS,
+		"Arch":     p.GOARCH,
+		"Hostname": p.Hostname,
+		"User":     p.User,
+	}
+}
+
+// funcs returns the platform template functions: isLinux, isDarwin, and
+// isWindows, and onHost, which is true when the hostname is one of its
+// arguments. Hostnames match case-insensitively, with or without the domain.
+func (p templatePlatform) funcs() template.FuncMap {
+	return template.FuncMap{
+		"isLinux":   func() bool { return p.GOOS == "linux" },
+		"isDarwin":  func() bool { return p.GOOS == "darwin" },
+		"isWindows": func() bool { return p.GOOS == "windows" },
+		"onHost": func(names ...string) bool {
+			short, _, _ := strings.Cut(p.Hostname, ".")
+			for _, name := range names {
+				if strings.EqualFold(name, p.Hostname) || strings.EqualFold(name, short) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
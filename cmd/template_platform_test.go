@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"berga/pkg/templates"
+)
+
+func TestTemplatePlatform(t *testing.T) {
+	p := templatePlatform{GOOS: "darwin", GOARCH: "arm64", Hostname: "Work-Laptop.local", User: "ada"}
+	source := `{{if isDarwin}}brew{{else if isLinux}}apt{{end}} {{.OS}}/{{.Arch}} {{.User}}` +
+		`{{if onHost "work-laptop"}} work{{end}}{{if onHost "home" "nas"}} home{{end}}{{if isWindows}} win{{end}}`
+
+	var out bytes.Buffer
+	err := templates.TextRenderer{Funcs: p.funcs()}.Render(&out, "t", source, p.vars())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "brew darwin/arm64 ada work"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestPlatformVarsAreBuiltin(t *testing.T) {
+	vars := hostTemplatePlatform().vars()
+	for _, name := range platformTemplateVarNames {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("Expected %s to be set", name)
+		}
+	}
+	if vars["OS"] == "" || vars["Arch"] == "" {
+		t.Errorf("Expected the OS and architecture, got %v", vars)
+	}
+	if _, err := parseTemplateSource("t", "{{if isLinux}}x{{end}}{{if onHost .Hostname}}y{{end}}"); err != nil {
+		t.Errorf("Expected the platform functions to be available: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitTemplatePrefix selects a template from a git repository, e.g.
+// "git::github.com/org/tmpls//k8s/deploy.tmpl?ref=main"
+const gitTemplatePrefix = "git::"
+
+// remoteFetchTimeout bounds a single template download
+const remoteFetchTimeout = 30 * time.Second
+
+// isRemoteTemplate reports whether a template name refers to a remote source
+func isRemoteTemplate(name string) bool {
+	return strings.HasPrefix(name, "http://") ||
+		strings.HasPrefix(name, "https://") ||
+		strings.HasPrefix(name, gitTemplatePrefix)
+}
+
+// remoteCacheKey returns a stable directory name for a remote source
+func remoteCacheKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:8])
+}
+
+// remoteTemplateName returns the file name a remote template is saved under
+func remoteTemplateName(ref string) string {
+	if strings.HasPrefix(ref, gitTemplatePrefix) {
+		_, subpath, _ := parseGitTemplateRef(ref)
+		return path.Base(subpath)
+	}
+	if u, err := url.Parse(ref); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return "template.tmpl"
+}
+
+// fetchRemoteTemplate downloads a remote template into the cache and returns
+// its local path. Cached copies are reused unless noCache is set.
+func fetchRemoteTemplate(ref string, noCache bool) (string, error) {
+	if strings.HasPrefix(ref, gitTemplatePrefix) {
+		return fetchGitTemplate(ref, noCache)
+	}
+	return fetchHTTPTemplate(ref, noCache)
+}
+
+func fetchHTTPTemplate(ref string, noCache bool) (string, error) {
+	dir := filepath.Join(GetCacheDir(), "templates", remoteCacheKey(ref))
+	dest := filepath.Join(dir, remoteTemplateName(ref))
+	if !noCache {
+		if _, err := os.Stat(dest); err == nil {
+			return dest, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := downloadFile(ref, dest); err != nil {
+		return "", err
+	}
+
+	// Pick up a companion variable schema if the server has one
+	schemaURL := strings.TrimSuffix(ref, ".tmpl") + ".vars.yaml"
+	if err := downloadFile(schemaURL, schemaPathFor(dest)); err != nil {
+		os.Remove(schemaPathFor(dest))
+	}
+	return dest, nil
+}
+
+// downloadFile fetches a URL into dest atomically
+func downloadFile(rawURL, dest string) error {
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	return writeFileAtomic(dest, data, 0644)
+}
+
+// parseGitTemplateRef splits "git::host/repo//sub/path?ref=x" into the
+// repository URL, the path inside it, and the optional ref
+func parseGitTemplateRef(ref string) (string, string, string) {
+	source := strings.TrimPrefix(ref, gitTemplatePrefix)
+
+	gitRef := ""
+	if i := strings.Index(source, "?"); i >= 0 {
+		if q, err := url.ParseQuery(source[i+1:]); err == nil {
+			gitRef = q.Get("ref")
+		}
+		source = source[:i]
+	}
+
+	// The "//" after the scheme separator belongs to the URL, not the subpath
+	schemeEnd := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		schemeEnd = i + 3
+	}
+	repo, subpath := source, ""
+	if i := strings.Index(source[schemeEnd:], "//"); i >= 0 {
+		repo = source[:schemeEnd+i]
+		subpath = source[schemeEnd+i+2:]
+	}
+
+	if !strings.Contains(repo, "://") && !strings.HasPrefix(repo, "git@") {
+		repo = "https://" + repo
+	}
+	return repo, subpath, gitRef
+}
+
+func fetchGitTemplate(ref string, noCache bool) (string, error) {
+	repo, subpath, gitRef := parseGitTemplateRef(ref)
+	if subpath == "" {
+		return "", fmt.Errorf("git template '%s' has no path; use git::<repo>//<path/to/template>", ref)
+	}
+	// Values starting with - would be read as git options
+	if strings.HasPrefix(gitRef, "-") {
+		return "", fmt.Errorf("invalid ref '%s' in git template '%s'", gitRef, ref)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is required for git:: templates")
+	}
+
+	clone := filepath.Join(GetCacheDir(), "git", remoteCacheKey(repo+"?ref="+gitRef))
+	if noCache {
+		os.RemoveAll(clone)
+	}
+	if _, err := os.Stat(clone); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(clone), 0755); err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if gitRef != "" {
+			args = append(args, "--branch", gitRef)
+		}
+		args = append(args, "--", repo, clone)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			os.RemoveAll(clone)
+			return "", fmt.Errorf("failed to clone %s: %s", repo, strings.TrimSpace(string(out)))
+		}
+	}
+
+	templatePath := filepath.Join(clone, filepath.FromSlash(subpath))
+	if !pathWithin(clone, templatePath) {
+		return "", fmt.Errorf("template path '%s' leaves the repository", subpath)
+	}
+	if _, err := os.Stat(templatePath); err != nil {
+		return "", fmt.Errorf("template '%s' not found in %s", subpath, repo)
+	}
+	// A symlink in the repository may point anywhere
+	resolvedClone, err1 := filepath.EvalSymlinks(clone)
+	resolved, err2 := filepath.EvalSymlinks(templatePath)
+	if err1 != nil || err2 != nil || !pathWithin(resolvedClone, resolved) {
+		return "", fmt.Errorf("template path '%s' leaves the repository", subpath)
+	}
+	return templatePath, nil
+}
+
+// pathWithin reports whether path is dir or lies below it
+func pathWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGitTemplateRef(t *testing.T) {
+	tests := []struct {
+		ref, repo, subpath, gitRef string
+	}{
+		{"git::github.com/org/tmpls//k8s/deploy.tmpl", "https://github.com/org/tmpls", "k8s/deploy.tmpl", ""},
+		{"git::https://example.com/r.git//a.tmpl?ref=v1", "https://example.com/r.git", "a.tmpl", "v1"},
+		{"git::git@github.com:org/r.git//x/y.tmpl", "git@github.com:org/r.git", "x/y.tmpl", ""},
+	}
+
+	for _, tt := range tests {
+		repo, subpath, gitRef := parseGitTemplateRef(tt.ref)
+		if repo != tt.repo || subpath != tt.subpath || gitRef != tt.gitRef {
+			t.Errorf("parseGitTemplateRef(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.ref, repo, subpath, gitRef, tt.repo, tt.subpath, tt.gitRef)
+		}
+	}
+}
+
+func TestFetchHTTPTemplateCaches(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foo.tmpl" {
+			http.NotFound(w, r)
+			return
+		}
+		hits++
+		fmt.Fprintf(w, "version %d", hits)
+	}))
+	defer server.Close()
+
+	ref := server.URL + "/foo.tmpl"
+	for i := 0; i < 2; i++ {
+		if _, err := fetchRemoteTemplate(ref, false); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Expected the cached copy to be reused, server was hit %d times", hits)
+	}
+
+	path, err := fetchRemoteTemplate(ref, true)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "version 2" {
+		t.Errorf("Expected --no-cache to download again, got %q", data)
+	}
+
+	if _, err := fetchRemoteTemplate(server.URL+"/missing.tmpl", false); err == nil {
+		t.Error("Expected an error for a missing remote template")
+	}
+}
+
+func TestFetchGitTemplateContained(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("config", "user.name", "Ada Lovelace")
+	git("config", "user.email", "ada@example.com")
+	os.MkdirAll(filepath.Join(repo, "k8s"), 0755)
+	os.WriteFile(filepath.Join(repo, "k8s", "deploy.tmpl"), []byte("{{.Name}}\n"), 0644)
+	os.Symlink("/etc/passwd", filepath.Join(repo, "k8s", "passwd.tmpl"))
+	git("add", "-A")
+	git("commit", "-q", "-m", "templates")
+
+	source := "git::file://" + filepath.ToSlash(repo)
+	if path, err := fetchGitTemplate(source+"//k8s/deploy.tmpl", false); err != nil || filepath.Base(path) != "deploy.tmpl" {
+		t.Fatalf("Expected the template from the clone, got %q, %v", path, err)
+	}
+	for _, ref := range []string{
+		source + "//../../../etc/passwd",
+		source + "//k8s/passwd.tmpl",
+		source + "//k8s/deploy.tmpl?ref=--upload-pack=touch%20/tmp/pwned",
+	} {
+		if _, err := fetchGitTemplate(ref, false); err == nil {
+			t.Errorf("Expected %s to be refused", ref)
+		}
+	}
+}
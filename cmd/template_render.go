@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	"berga/internal/ui"
+)
+
+const (
+	// defaultTemplateJobs is how many templates --output-dir renders at once
+	defaultTemplateJobs = 4
+
+	// progressThreshold is how much output a render produces before its
+	// progress is shown
+	progressThreshold = 1 << 20
+
+	// progressInterval limits how often the progress line is redrawn
+	progressInterval = 100 * time.Millisecond
+)
+
+var templateJobs int
+
+// renderProgress counts the bytes a render writes and, once the output is
+// large, keeps a line on out updated with the total
+type renderProgress struct {
+	w       io.Writer
+	out     io.Writer
+	label   string
+	written int64
+	last    time.Time
+	shown   bool
+}
+
+func (p *renderProgress) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.written >= progressThreshold && time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.shown = true
+		fmt.Fprintf(p.out, "\r%s %s", p.label, humanizeSize(p.written))
+	}
+	return n, err
+}
+
+// done finishes the progress line, if one was shown
+func (p *renderProgress) done() {
+	if p.shown {
+		fmt.Fprintf(p.out, "\r%s %s\n", p.label, humanizeSize(p.written))
+	}
+}
+
+// progressOut is where render progress goes: stderr when it is a terminal
+// that is not also receiving the rendered output
+func progressOut(toStdout bool) io.Writer {
+	if !ui.IsTerminal(os.Stderr) || (toStdout && ui.IsTerminal(os.Stdout)) {
+		return nil
+	}
+	return os.Stderr
+}
+
+// executeTemplate streams a template into w, reporting progress on progress
+// when it is not nil
+func executeTemplate(tmpl *template.Template, vars map[string]interface{}, w io.Writer, progress io.Writer) error {
+	if progress != nil {
+		p := &renderProgress{w: w, out: progress, label: "Rendering " + tmpl.Name() + ":"}
+		defer p.done()
+		w = p
+	}
+	if err := tmpl.Execute(w, vars); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
+
+// renderTemplateToStdout streams a template to stdout through a buffer
+func renderTemplateToStdout(tmpl *template.Template, vars map[string]interface{}) error {
+	out := bufio.NewWriterSize(os.Stdout, 64*1024)
+	if err := executeTemplate(tmpl, vars, out, progressOut(true)); err != nil {
+		out.Flush()
+		return err
+	}
+	return out.Flush()
+}
+
+// writeRenderedFile executes a parsed template into outputFile. The output is
+// streamed to a temporary file that replaces outputFile once the render
+// succeeds, so large outputs are never held in memory and a render error never
+// leaves a half-written file behind. --merge needs the whole output to merge
+// it into the existing file, so it renders in memory instead. A nonzero mode
+// sets the file's permissions; otherwise an existing file keeps its own.
+func writeRenderedFile(tmpl *template.Template, vars map[string]interface{}, outputFile string, mode os.FileMode, progress io.Writer) error {
+	// Write through symlinks and keep the existing file's permissions
+	target := outputFile
+	perm := os.FileMode(0644)
+	if resolved, err := filepath.EvalSymlinks(outputFile); err == nil {
+		target = resolved
+	}
+
+	var merged []byte
+	if templateMerge != "" {
+		var buf bytes.Buffer
+		if err := executeTemplate(tmpl, vars, &buf, progress); err != nil {
+			return err
+		}
+		existing, err := os.ReadFile(target)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read output file: %w", err)
+		}
+		content, err := mergeContent(string(existing), buf.String(), templateMerge, mergeSectionName(tmpl.Name()), commentStyleFor(target))
+		if err != nil {
+			return fmt.Errorf("failed to merge into %s: %w", outputFile, err)
+		}
+		merged = []byte(content)
+	}
+
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+		if templateBackup {
+			if err := backupFile(target, perm); err != nil {
+				return err
+			}
+		}
+	}
+	if mode != 0 {
+		perm = mode
+	}
+
+	var renderErr error
+	err := streamFileAtomic(target, perm, func(w io.Writer) error {
+		if merged != nil {
+			_, err := w.Write(merged)
+			return err
+		}
+		renderErr = executeTemplate(tmpl, vars, w, progress)
+		return renderErr
+	})
+	if renderErr != nil {
+		return renderErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// renderJob is one template to render in directory mode
+type renderJob struct {
+	tmpl       *template.Template
+	vars       map[string]interface{}
+	outputFile string
+	mode       os.FileMode
+}
+
+// renderConcurrently renders jobs with up to workers at a time and returns
+// each job's error, in order. After a failure no new jobs are started; those
+// left out get errRenderSkipped.
+func renderConcurrently(jobs []renderJob, workers int) []error {
+	errs := make([]error, len(jobs))
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Only a render that runs alone can own the progress line
+	var progress io.Writer
+	if workers == 1 || len(jobs) == 1 {
+		progress = progressOut(false)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	sem := make(chan struct{}, workers)
+	for i, job := range jobs {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			errs[i] = errRenderSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, job renderJob) {
+			defer func() { <-sem; wg.Done() }()
+			err := os.MkdirAll(filepath.Dir(job.outputFile), 0755)
+			if err != nil {
+				err = fmt.Errorf("failed to create output directory: %w", err)
+			} else {
+				err = writeRenderedFile(job.tmpl, job.vars, job.outputFile, job.mode, progress)
+			}
+			if err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+			errs[i] = err
+		}(i, job)
+	}
+	wg.Wait()
+	return errs
+}
+
+// errRenderSkipped marks a render not started because an earlier one failed
+var errRenderSkipped = fmt.Errorf("skipped after an earlier failure")
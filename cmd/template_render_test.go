@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderProgress(t *testing.T) {
+	var sink, out bytes.Buffer
+	p := &renderProgress{w: &sink, out: &out, label: "Rendering big:"}
+	p.Write(make([]byte, 1024))
+	if out.Len() != 0 {
+		t.Errorf("Expected no progress for small output, got %q", out.String())
+	}
+
+	p.Write(make([]byte, progressThreshold))
+	p.done()
+	if !strings.HasPrefix(out.String(), "\rRendering big: 1.0 MB") || !strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("Unexpected progress output %q", out.String())
+	}
+	if sink.Len() != 1024+progressThreshold {
+		t.Errorf("Expected every byte to reach the writer, got %d", sink.Len())
+	}
+}
+
+func TestWriteRenderedFileStreams(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "big.txt")
+	tmpl, err := parseTemplateSource("big", `{{range .Lines}}{{.}}{{"\n"}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]string, 100000)
+	for i := range lines {
+		lines[i] = "line"
+	}
+
+	var progress bytes.Buffer
+	if err := writeRenderedFile(tmpl, map[string]interface{}{"Lines": lines}, output, 0, &progress); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(output)
+	if len(data) != 500000 {
+		t.Errorf("Expected 500000 bytes, got %d", len(data))
+	}
+	if progress.Len() != 0 {
+		t.Errorf("Expected no progress below the threshold, got %q", progress.String())
+	}
+
+	// A failing render keeps the previous output
+	bad, _ := parseTemplateSource("big", "partial {{index .Lines 5}}")
+	if err := writeRenderedFile(bad, map[string]interface{}{"Lines": []string{}}, output, 0, nil); err == nil {
+		t.Fatal("Expected a render error")
+	}
+	if after, _ := os.ReadFile(output); !bytes.Equal(after, data) {
+		t.Error("Expected the failed render to leave the file alone")
+	}
+}
+
+func TestRenderConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	good, _ := parseTemplateSource("good", "hello {{.Name}}\n")
+	bad, _ := parseTemplateSource("bad", "{{index .List 3}}")
+	vars := map[string]interface{}{"Name": "ada", "List": []int{}}
+
+	var jobs []renderJob
+	for _, name := range []string{"a", "b", "c", "nested/d"} {
+		jobs = append(jobs, renderJob{good, vars, filepath.Join(dir, name), 0})
+	}
+	for i, err := range renderConcurrently(jobs, 3) {
+		if err != nil {
+			t.Errorf("Job %d failed: %v", i, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "nested", "d")); string(data) != "hello ada\n" {
+		t.Errorf("Unexpected output %q", data)
+	}
+
+	// One at a time, a failure stops the rest
+	jobs = []renderJob{{bad, vars, filepath.Join(dir, "x"), 0}, {good, vars, filepath.Join(dir, "y"), 0}}
+	errs := renderConcurrently(jobs, 1)
+	if errs[0] == nil || errs[1] != errRenderSkipped {
+		t.Errorf("Expected a failure then a skip, got %v", errs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "y")); !os.IsNotExist(err) {
+		t.Error("Expected the skipped template not to be rendered")
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// templateNoRollback keeps the files a failed multi-file run rendered
+var templateNoRollback bool
+
+// applyTransaction records the files and directories a multi-file template
+// run is about to change, so that a run failing partway can put them back
+// instead of leaving a half-generated project
+type applyTransaction struct {
+	dir   string            // copies of the files about to be replaced
+	saved map[string]string // file -> its copy, or "" when the run creates it
+	modes map[string]os.FileMode
+	files []string // tracked files, in order
+	dirs  []string // directories the run creates, outermost first
+}
+
+func newApplyTransaction() *applyTransaction {
+	return &applyTransaction{saved: make(map[string]string), modes: make(map[string]os.FileMode)}
+}
+
+// track records the state of outputFile, and of its backup with --backup,
+// before the run writes it. Symlinks are followed, as writes are.
+func (t *applyTransaction) track(outputFile string) error {
+	target := outputFile
+	if resolved, err := filepath.EvalSymlinks(outputFile); err == nil {
+		target = resolved
+	}
+	if err := t.trackFile(target); err != nil {
+		return err
+	}
+	if templateBackup {
+		return t.trackFile(target + ".bak")
+	}
+	return nil
+}
+
+func (t *applyTransaction) trackFile(path string) error {
+	if _, ok := t.saved[path]; ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		t.saved[path] = ""
+		t.files = append(t.files, path)
+		t.trackDirs(filepath.Dir(path))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if t.dir == "" {
+		if t.dir, err = os.MkdirTemp("", "berga-apply-*"); err != nil {
+			return err
+		}
+	}
+	saved := filepath.Join(t.dir, fmt.Sprint(len(t.files)))
+	if err := copyFile(path, saved); err != nil {
+		return err
+	}
+	t.saved[path] = saved
+	t.modes[path] = info.Mode().Perm()
+	t.files = append(t.files, path)
+	return nil
+}
+
+// trackDirs records the missing directories up to dir, which rendering
+// creates
+func (t *applyTransaction) trackDirs(dir string) {
+	var missing []string
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, dir := range missing {
+		known := false
+		for _, d := range t.dirs {
+			known = known || d == dir
+		}
+		if !known {
+			t.dirs = append(t.dirs, dir)
+		}
+	}
+}
+
+// rollback undoes the run: files it created are removed, files it replaced
+// get their old content and permissions back, and directories it created are
+// removed if nothing else was put in them. It keeps going past failures and
+// returns them all.
+func (t *applyTransaction) rollback() error {
+	var errs []error
+	for i := len(t.files) - 1; i >= 0; i-- {
+		path := t.files[i]
+		saved := t.saved[path]
+		if saved == "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := copyFile(saved, path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.Chmod(path, t.modes[path]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(t.dirs) - 1; i >= 0; i-- {
+		os.Remove(t.dirs[i])
+	}
+	return errors.Join(errs...)
+}
+
+// discard drops the saved copies once the run is over
+func (t *applyTransaction) discard() {
+	if t.dir != "" {
+		os.RemoveAll(t.dir)
+	}
+}
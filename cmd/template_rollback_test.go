@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestApplyTemplateSetRollsBack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	templateNoInput, templateForce, templateBackup = true, true, true
+	origJobs := templateJobs
+	templateJobs = 1
+	defer func() {
+		templateNoInput, templateForce, templateBackup = false, false, false
+		templateJobs = origJobs
+	}()
+
+	os.MkdirAll(GetTemplatesDir(), 0755)
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "good.tmpl"), []byte("new\n"), 0644)
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "bad.tmpl"), []byte("{{index .Missing 1}}\n"), 0644)
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	os.WriteFile(existing, []byte("old\n"), 0600)
+	manifest := filepath.Join(dir, "gen.yaml")
+	content := `templates:
+  - template: good
+    output: nested/deeper/created.txt
+  - template: good
+    output: existing.txt
+  - template: bad
+    output: bad.txt
+`
+	os.WriteFile(manifest, []byte(content), 0644)
+
+	if err := applyTemplateSet(nil, dir, manifest); err == nil {
+		t.Fatal("Expected the bad template to fail the run")
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old\n" {
+		t.Errorf("Expected the overwritten file to be restored, got %q", data)
+	}
+	if info, err := os.Stat(existing); err != nil || runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file's permissions to be restored, got %v", info.Mode())
+	}
+	for _, path := range []string{"nested", "existing.txt.bak", "bad.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be rolled back, got %v", path, err)
+		}
+	}
+
+	// --no-rollback keeps what was rendered
+	templateNoRollback = true
+	defer func() { templateNoRollback = false }()
+	applyTemplateSet(nil, dir, manifest)
+	if _, err := os.Stat(filepath.Join(dir, "nested", "deeper", "created.txt")); err != nil {
+		t.Errorf("Expected the rendered file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateToFileAtomic(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(output, []byte("original\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A template that fails halfway must not touch the existing file
+	tmpl, err := parseTemplateSource("bad", "start {{index .List 5}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderTemplateToFile(tmpl, map[string]interface{}{"List": []int{}}, output, 0); err == nil {
+		t.Fatal("Expected a render error")
+	}
+	if data, _ := os.ReadFile(output); string(data) != "original\n" {
+		t.Errorf("Expected existing file to be untouched, got %q", data)
+	}
+
+	tmpl, err = parseTemplateSource("good", "hello {{.Name}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderTemplateToFile(tmpl, map[string]interface{}{"Name": "x"}, output, 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "hello x\n" {
+		t.Errorf("Unexpected output %q", data)
+	}
+	if info, _ := os.Stat(output); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestRenderTemplateToFileBackup(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(output, []byte("original\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templateBackup = true
+	defer func() { templateBackup = false }()
+
+	tmpl, err := parseTemplateSource("good", "new\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderTemplateToFile(tmpl, nil, output, 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(output + ".bak"); string(data) != "original\n" {
+		t.Errorf("Expected backup of the original, got %q", data)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "new\n" {
+		t.Errorf("Unexpected output %q", data)
+	}
+}
+
+func TestConfirmOverwrite(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.txt")
+	if ok, err := confirmOverwrite(output); !ok || err != nil {
+		t.Errorf("Expected a new file to need no confirmation, got %v, %v", ok, err)
+	}
+	if err := os.WriteFile(output, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templateNoInput = true
+	defer func() { templateNoInput = false }()
+	if _, err := confirmOverwrite(output); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected an error suggesting --force, got %v", err)
+	}
+
+	templateForce = true
+	defer func() { templateForce = false }()
+	if ok, err := confirmOverwrite(output); !ok || err != nil {
+		t.Errorf("Expected --force to overwrite without asking, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"text/template/parse"
+
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// builtinTemplateVars are always set by collectTemplateVars
+var builtinTemplateVars = append(append([]string{"Author", "Email", "CurrentDir", "ProjectName", "Year", "Date"}, gitTemplateVarNames...), platformTemplateVarNames...)
+
+// templateValidateCmd checks templates without rendering them to a file
+var templateValidateCmd = &cobra.Command{
+	Use:   "validate [template-name...]",
+	Short: "Check templates for syntax errors and undefined variables",
+	Long: `Parse templates and check the variables they use. Variables must be
+built in (Author, Email, CurrentDir, ProjectName, Year, Date, OS, Arch,
+Hostname, User, and the Git* variables), set under templates.vars, or declared in the template's .vars.yaml
+schema. Each template is then rendered with sample data from the schema to
+catch runtime errors.
+
+Templates without a schema only get warnings for unknown variables, since they
+can be given interactively. With no arguments every template is checked. The
+command exits nonzero when an error is found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return validateTemplates(args)
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateValidateCmd)
+}
+
+// templateFields returns the top-level variables a template refers to, such
+// as "Name" for {{.Name}} or {{$.Name}}. References inside range and with
+// blocks are relative to another value and skipped, except through $.
+func templateFields(tmpl *template.Template) []string {
+	seen := make(map[string]bool)
+	if tmpl.Tree != nil {
+		walkTemplateNode(tmpl.Tree.Root, true, seen)
+	}
+	fields := make([]string, 0, len(seen))
+	for name := range seen {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func walkTemplateNode(node parse.Node, rootDot bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateNode(child, rootDot, seen)
+		}
+	case *parse.ActionNode:
+		walkTemplateNode(n.Pipe, rootDot, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			for _, arg := range c.Args {
+				walkTemplateNode(arg, rootDot, seen)
+			}
+		}
+	case *parse.FieldNode:
+		if rootDot {
+			seen[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			seen[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		walkTemplateNode(n.Node, rootDot, seen)
+	case *parse.IfNode:
+		walkTemplateNode(n.Pipe, rootDot, seen)
+		walkTemplateNode(n.List, rootDot, seen)
+		walkTemplateNode(n.ElseList, rootDot, seen)
+	case *parse.RangeNode:
+		walkTemplateNode(n.Pipe, rootDot, seen)
+		walkTemplateNode(n.List, false, seen)
+		walkTemplateNode(n.ElseList, rootDot, seen)
+	case *parse.WithNode:
+		walkTemplateNode(n.Pipe, rootDot, seen)
+		walkTemplateNode(n.List, false, seen)
+		walkTemplateNode(n.ElseList, rootDot, seen)
+	case *parse.TemplateNode:
+		walkTemplateNode(n.Pipe, rootDot, seen)
+	}
+}
+
+// sampleValue returns a value of a schema variable's type for test renders
+func sampleValue(v TemplateVar) interface{} {
+	if v.Default != nil {
+		if value, err := convertVarValue(v, defaultString(v.Default)); err == nil {
+			return value
+		}
+	}
+	if len(v.Enum) > 0 {
+		return v.Enum[0]
+	}
+	switch v.Type {
+	case "int":
+		return 1
+	case "float":
+		return 1.5
+	case "bool":
+		return true
+	}
+	return "sample"
+}
+
+// validateTemplate checks one template and returns its findings
+func validateTemplate(templateName, templatePath string) []lintFinding {
+	finding := func(severity, check, message string) lintFinding {
+		return lintFinding{Script: templateName, Severity: severity, Check: check, Message: message}
+	}
+
+	schema, err := loadTemplateSchema(templatePath)
+	if err != nil {
+		return []lintFinding{finding(severityError, "schema", err.Error())}
+	}
+	tmpl, err := parseTemplateFile(templatePath, templateName)
+	if err != nil {
+		return []lintFinding{finding(severityError, "syntax", err.Error())}
+	}
+
+	known := make(map[string]bool)
+	for _, name := range builtinTemplateVars {
+		known[name] = true
+	}
+	for name := range projectTemplateVars() {
+		known[name] = true
+	}
+	if schema != nil {
+		for _, v := range schema.Variables {
+			known[v.Name] = true
+		}
+	}
+
+	var findings []lintFinding
+	used := make(map[string]bool)
+	for _, name := range templateFields(tmpl) {
+		used[name] = true
+		if known[name] {
+			continue
+		}
+		if schema != nil {
+			findings = append(findings, finding(severityError, "undefined", fmt.Sprintf("'%s' is not declared in %s", name, filepath.Base(schemaPathFor(templatePath)))))
+		} else {
+			findings = append(findings, finding(severityWarning, "undefined", fmt.Sprintf("'%s' is not a built-in or configured variable", name)))
+		}
+	}
+	if schema != nil {
+		for _, v := range schema.Variables {
+			if !used[v.Name] {
+				findings = append(findings, finding(severityInfo, "unused", fmt.Sprintf("'%s' is declared but not used", v.Name)))
+			}
+		}
+	}
+
+	// Render with sample data; unknown variables get a placeholder so only
+	// real runtime errors are reported
+	vars := map[string]interface{}{"Author": "Author", "Email": "author@example.com", "CurrentDir": "project",
+		"ProjectName": "project", "Year": 2006, "Date": "2006-01-02"}
+	for k, v := range projectTemplateVars() {
+		vars[k] = v
+	}
+	if schema != nil {
+		for _, v := range schema.Variables {
+			vars[v.Name] = sampleValue(v)
+		}
+	}
+	for name := range used {
+		if _, ok := vars[name]; !ok {
+			vars[name] = "sample"
+		}
+	}
+	// Commands are never run while checking; shell stands in with a sample
+	tmpl.Funcs(template.FuncMap{"shell": func(string) string { return "sample" }})
+	if err := tmpl.Option("missingkey=error").Execute(io.Discard, vars); err != nil {
+		findings = append(findings, finding(severityError, "render", err.Error()))
+	}
+	return findings
+}
+
+// validateTargets returns the templates to validate, by name and path
+func validateTargets(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = templateNames(); err != nil {
+			return nil, err
+		}
+	}
+	targets := make(map[string]string)
+	for _, name := range names {
+		path, err := resolveTemplatePath(name)
+		if err != nil {
+			return nil, err
+		}
+		targets[name] = path
+	}
+	return targets, nil
+}
+
+func validateTemplates(names []string) error {
+	targets, err := validateTargets(names)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No templates found.")
+		return nil
+	}
+
+	sorted := make([]string, 0, len(targets))
+	for name := range targets {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	counts := make(map[string]int)
+	for _, name := range sorted {
+		findings := validateTemplate(name, targets[name])
+		if len(findings) == 0 {
+			fmt.Printf("  %s %s\n", ui.Green(fmt.Sprintf("%-7s", "OK")), ui.Bold(name))
+		}
+		for _, f := range findings {
+			counts[f.Severity]++
+			fmt.Printf("  %s %s %s %s\n", severityLabel(f.Severity), ui.Bold(f.Script), ui.Dim(f.Check+":"), f.Message)
+		}
+	}
+
+	fmt.Printf("\n%d template(s) checked: %d error(s), %d warning(s), %d info\n",
+		len(sorted), counts[severityError], counts[severityWarning], counts[severityInfo])
+	if counts[severityError] > 0 {
+		return fmt.Errorf("validation found problems")
+	}
+	return nil
+}
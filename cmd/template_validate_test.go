@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateFields(t *testing.T) {
+	tmpl := template.Must(template.New("t").Parse(
+		`{{.Name}} {{if .Debug}}{{.Level}}{{end}}{{range .Items}}{{.Skipped}}{{$.Owner}}{{end}}{{with .Config}}{{.Inner}}{{end}}{{printf "%s" .Arg}}`))
+	want := []string{"Arg", "Config", "Debug", "Items", "Level", "Name", "Owner"}
+	if got := templateFields(tmpl); !reflect.DeepEqual(got, want) {
+		t.Errorf("templateFields() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	checks := func(findings []lintFinding) map[string]string {
+		got := make(map[string]string)
+		for _, f := range findings {
+			got[f.Check] = f.Severity
+		}
+		return got
+	}
+
+	ok := write("ok.tmpl", "{{.Author}} {{.Port}}\n")
+	write("ok.vars.yaml", "variables:\n  - name: Port\n    type: int\n")
+	if findings := validateTemplate("ok", ok); len(findings) != 0 {
+		t.Errorf("valid template: got findings %v", findings)
+	}
+
+	undefined := write("undefined.tmpl", "{{.Port}} {{.Host}}\n")
+	write("undefined.vars.yaml", "variables:\n  - name: Port\n  - name: Unused\n")
+	got := checks(validateTemplate("undefined", undefined))
+	if got["undefined"] != severityError || got["unused"] != severityInfo {
+		t.Errorf("schema template: got %v", got)
+	}
+
+	loose := write("loose.tmpl", "{{.Host}}\n")
+	if got := checks(validateTemplate("loose", loose)); got["undefined"] != severityWarning {
+		t.Errorf("template without schema: got %v", got)
+	}
+
+	broken := write("broken.tmpl", "{{.Host\n")
+	if got := checks(validateTemplate("broken", broken)); got["syntax"] != severityError {
+		t.Errorf("broken template: got %v", got)
+	}
+
+	// Rendering with the default catches errors that parsing cannot
+	render := write("render.tmpl", "{{index .Name 5}}\n")
+	write("render.vars.yaml", "variables:\n  - name: Name\n    default: ab\n")
+	if got := checks(validateTemplate("render", render)); got["render"] != severityError {
+		t.Errorf("render failure: got %v", got)
+	}
+}
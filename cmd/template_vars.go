@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateVar describes a single variable declared in a template's .vars.yaml file
+type TemplateVar struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Type        string      `yaml:"type"`
+	Default     interface{} `yaml:"default"`
+	Required    bool        `yaml:"required"`
+	Enum        []string    `yaml:"enum"`
+	Pattern     string      `yaml:"pattern"`
+	Secret      bool        `yaml:"secret"`
+}
+
+// TemplateSchema is the companion variable schema for a template
+type TemplateSchema struct {
+	Variables []TemplateVar `yaml:"variables"`
+}
+
+var (
+	stdinReader = bufio.NewReader(os.Stdin)
+
+	// promptOut receives interactive prompts; it is switched to stderr when
+	// rendered output is written to stdout
+	promptOut io.Writer = os.Stdout
+)
+
+// schemaPathFor returns the path of the .vars.yaml companion for a template file
+func schemaPathFor(templatePath string) string {
+	base := strings.TrimSuffix(templatePath, ".tmpl")
+	return base + ".vars.yaml"
+}
+
+// isSchemaFile reports whether a file in the templates directory is a variable schema
+func isSchemaFile(name string) bool {
+	return strings.HasSuffix(name, ".vars.yaml")
+}
+
+// loadTemplateSchema reads the schema for a template, returning nil if none exists
+func loadTemplateSchema(templatePath string) (*TemplateSchema, error) {
+	schemaPath := schemaPathFor(templatePath)
+	data, err := readTemplateFile(schemaPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variable schema: %w", err)
+	}
+
+	var schema TemplateSchema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse variable schema %s: %w", filepath.Base(schemaPath), err)
+	}
+
+	for i, v := range schema.Variables {
+		if v.Name == "" {
+			return nil, fmt.Errorf("variable #%d in %s has no name", i+1, filepath.Base(schemaPath))
+		}
+		switch v.Type {
+		case "", "string", "int", "float", "bool":
+		default:
+			return nil, fmt.Errorf("variable '%s' has unsupported type '%s'", v.Name, v.Type)
+		}
+		if v.Pattern != "" {
+			if _, err := regexp.Compile(v.Pattern); err != nil {
+				return nil, fmt.Errorf("variable '%s' has invalid pattern: %w", v.Name, err)
+			}
+		}
+	}
+
+	return &schema, nil
+}
+
+// convertVarValue validates raw input against a variable declaration and converts it to its type
+func convertVarValue(v TemplateVar, raw string) (interface{}, error) {
+	if len(v.Enum) > 0 {
+		found := false
+		for _, option := range v.Enum {
+			if raw == option {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("'%s' must be one of: %s", v.Name, strings.Join(v.Enum, ", "))
+		}
+	}
+
+	if v.Pattern != "" {
+		if !regexp.MustCompile(v.Pattern).MatchString(raw) {
+			return nil, fmt.Errorf("'%s' does not match pattern %s", v.Name, v.Pattern)
+		}
+	}
+
+	switch v.Type {
+	case "int":
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be an integer", v.Name)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be a number", v.Name)
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be true or false", v.Name)
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// defaultString renders a schema default (or an already collected value) as prompt text
+func defaultString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// collectSchemaVars fills vars from a template schema, prompting unless noInput is set
+func collectSchemaVars(schema *TemplateSchema, vars map[string]interface{}, noInput bool) error {
+	for _, v := range schema.Variables {
+		if raw, ok := replayedVar(v.Name); ok {
+			value, err := convertVarValue(v, raw)
+			if err != nil {
+				return fmt.Errorf("invalid answer: %w", err)
+			}
+			vars[v.Name] = value
+			continue
+		}
+
+		def := defaultString(v.Default)
+		if existing, ok := vars[v.Name]; ok && defaultString(existing) != "" {
+			def = defaultString(existing)
+		}
+
+		if noInput {
+			if def == "" {
+				if v.Required {
+					return fmt.Errorf("missing required variable '%s' (set a default in the schema or templates.vars.%s in the config, or run interactively)", v.Name, v.Name)
+				}
+				vars[v.Name] = ""
+				continue
+			}
+			value, err := convertVarValue(v, def)
+			if err != nil {
+				return err
+			}
+			vars[v.Name] = value
+			continue
+		}
+
+		for {
+			input, err := promptVar(v, def)
+			if err != nil && input == "" {
+				return fmt.Errorf("failed to read value for '%s': %w", v.Name, err)
+			}
+			if input == "" {
+				if v.Required {
+					fmt.Fprintf(promptOut, "  '%s' is required\n", v.Name)
+					continue
+				}
+				vars[v.Name] = ""
+				break
+			}
+
+			value, err := convertVarValue(v, input)
+			if err != nil {
+				fmt.Fprintf(promptOut, "  %v\n", err)
+				continue
+			}
+			vars[v.Name] = value
+			break
+		}
+	}
+
+	// Secrets stay out of answer files
+	for _, v := range schema.Variables {
+		if !v.Secret {
+			recordVar(v.Name, vars[v.Name])
+		}
+	}
+	return nil
+}
+
+// promptVar asks for a schema variable: from a list when it is an enum, with
+// hidden input when it is a secret
+func promptVar(v TemplateVar, def string) (string, error) {
+	label := v.Name
+	if v.Description != "" {
+		label = fmt.Sprintf("%s (%s)", v.Name, v.Description)
+	}
+	if len(v.Enum) > 0 {
+		return promptSelect(label, v.Enum, def)
+	}
+	return promptLine(label, def, v.Secret)
+}
+
+// readLine reads a full line from stdin, trimming the trailing newline
+func readLine() (string, error) {
+	line, err := stdinReader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// templateVarFlags are the --var NAME=VALUE assignments of 'template apply'
+var templateVarFlags []string
+
+// templateVarAssignments parses --var into the variables it sets
+func templateVarAssignments() (map[string]interface{}, error) {
+	given := make(map[string]interface{}, len(templateVarFlags))
+	for _, assignment := range templateVarFlags {
+		key, value, found := strings.Cut(assignment, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --var '%s' (expected NAME=VALUE)", assignment)
+		}
+		given[key] = value
+	}
+	return given, nil
+}
+
+// completeTemplateVars completes --var with the variables declared in the
+// schemas of the templates named so far, and then with an enum variable's
+// choices. Remote templates are not downloaded to complete them.
+func completeTemplateVars(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var templateArgs []string
+	if templateOutputDir != "" || templateManifest != "" {
+		templateArgs = args
+	} else if len(args) > 0 {
+		templateArgs = args[:1]
+	}
+
+	seen := make(map[string]bool)
+	var completions []string
+	for _, name := range templateArgs {
+		if isRemoteTemplate(name) {
+			continue
+		}
+		templatePath, err := resolveTemplatePath(name)
+		if err != nil {
+			continue
+		}
+		schema, err := loadTemplateSchema(templatePath)
+		if err != nil || schema == nil {
+			continue
+		}
+		for _, v := range schema.Variables {
+			if key, value, found := strings.Cut(toComplete, "="); found {
+				if key != v.Name {
+					continue
+				}
+				for _, option := range v.Enum {
+					if strings.HasPrefix(option, value) && !seen[option] {
+						seen[option] = true
+						completions = append(completions, v.Name+"="+option)
+					}
+				}
+				continue
+			}
+			if seen[v.Name] || !strings.HasPrefix(v.Name, toComplete) {
+				continue
+			}
+			seen[v.Name] = true
+			completion := v.Name + "="
+			if v.Description != "" {
+				completion += "\t" + v.Description
+			}
+			completions = append(completions, completion)
+		}
+	}
+	if strings.Contains(toComplete, "=") {
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSchemaPathFor(t *testing.T) {
+	if got := schemaPathFor("/t/foo.tmpl"); got != "/t/foo.vars.yaml" {
+		t.Errorf("Expected /t/foo.vars.yaml, got %s", got)
+	}
+	if got := schemaPathFor("/t/foo"); got != "/t/foo.vars.yaml" {
+		t.Errorf("Expected /t/foo.vars.yaml, got %s", got)
+	}
+}
+
+func TestConvertVarValue(t *testing.T) {
+	port := TemplateVar{Name: "Port", Type: "int"}
+	if v, err := convertVarValue(port, "8080"); err != nil || v != 8080 {
+		t.Errorf("Expected 8080, got %v (%v)", v, err)
+	}
+	if _, err := convertVarValue(port, "eighty"); err == nil {
+		t.Error("Expected error for non-integer value")
+	}
+
+	env := TemplateVar{Name: "Env", Enum: []string{"dev", "prod"}}
+	if _, err := convertVarValue(env, "staging"); err == nil {
+		t.Error("Expected error for value outside enum")
+	}
+
+	name := TemplateVar{Name: "Name", Pattern: "^[a-z]+$"}
+	if _, err := convertVarValue(name, "Bad Name"); err == nil {
+		t.Error("Expected error for value not matching pattern")
+	}
+}
+
+func TestCollectSchemaVarsNoInput(t *testing.T) {
+	schema := &TemplateSchema{Variables: []TemplateVar{
+		{Name: "Debug", Type: "bool", Default: false},
+		{Name: "Owner", Required: true},
+	}}
+
+	vars := map[string]interface{}{}
+	if err := collectSchemaVars(schema, vars, true); err == nil {
+		t.Error("Expected error for missing required variable")
+	}
+
+	vars = map[string]interface{}{"Owner": "me"}
+	if err := collectSchemaVars(schema, vars, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vars["Debug"] != false {
+		t.Errorf("Expected Debug to be false, got %v", vars["Debug"])
+	}
+}
+
+func TestLoadTemplateSchema(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "svc.tmpl")
+
+	schema, err := loadTemplateSchema(tmpl)
+	if err != nil || schema != nil {
+		t.Fatalf("Expected no schema, got %v (%v)", schema, err)
+	}
+
+	content := "variables:\n  - name: Port\n    type: int\n    default: 80\n"
+	if err := os.WriteFile(filepath.Join(dir, "svc.vars.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err = loadTemplateSchema(tmpl)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(schema.Variables) != 1 || schema.Variables[0].Name != "Port" {
+		t.Errorf("Unexpected schema: %+v", schema)
+	}
+}
+
+func TestCompleteTemplateVars(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(GetTemplatesDir(), 0755)
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "svc.tmpl"), []byte("{{.Port}}\n"), 0644)
+	schema := "variables:\n  - name: Port\n    description: Listen port\n  - name: Env\n    enum: [dev, prod]\n"
+	os.WriteFile(filepath.Join(GetTemplatesDir(), "svc.vars.yaml"), []byte(schema), 0644)
+
+	got, directive := completeTemplateVars(templateApplyCmd, []string{"svc", "out.yaml"}, "")
+	if !reflect.DeepEqual(got, []string{"Port=\tListen port", "Env="}) || directive&cobra.ShellCompDirectiveNoSpace == 0 {
+		t.Errorf("Unexpected completions %q (%v)", got, directive)
+	}
+	if got, _ := completeTemplateVars(templateApplyCmd, []string{"svc"}, "Env=p"); !reflect.DeepEqual(got, []string{"Env=prod"}) {
+		t.Errorf("Expected the enum choice, got %q", got)
+	}
+	if got, _ := completeTemplateVars(templateApplyCmd, []string{"missing"}, ""); len(got) != 0 {
+		t.Errorf("Expected nothing for an unknown template, got %q", got)
+	}
+}
+
+func TestTemplateVarAssignments(t *testing.T) {
+	defer func() { templateVarFlags = nil }()
+	templateVarFlags = []string{"Port=8080", "Query=a=b"}
+	given, err := templateVarAssignments()
+	if err != nil || given["Port"] != "8080" || given["Query"] != "a=b" {
+		t.Errorf("Unexpected assignments %v (%v)", given, err)
+	}
+	templateVarFlags = []string{"Port"}
+	if _, err := templateVarAssignments(); err == nil {
+		t.Error("Expected an assignment without = to be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// stty runs stty against the terminal attached to f
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// makeRaw puts the terminal into raw mode and returns a function that
+// restores its previous state
+func makeRaw(f *os.File) (func(), error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal state: %w", err)
+	}
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+	return func() { stty(f, saved) }, nil
+}
+
+// terminalSize returns the width and height of the terminal attached to f
+func terminalSize(f *os.File) (int, int) {
+	out, err := stty(f, "size")
+	if err != nil {
+		return 80, 24
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 80, 24
+	}
+	rows, _ := strconv.Atoi(fields[0])
+	cols, _ := strconv.Atoi(fields[1])
+	if rows <= 0 || cols <= 0 {
+		return 80, 24
+	}
+	return cols, rows
+}
@@ -0,0 +1,85 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode             = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// consoleScreenBufferInfo mirrors the Win32 CONSOLE_SCREEN_BUFFER_INFO structure
+type consoleScreenBufferInfo struct {
+	Size              [2]int16
+	CursorPosition    [2]int16
+	Attributes        uint16
+	Window            [4]int16
+	MaximumWindowSize [2]int16
+}
+
+func getConsoleMode(f *os.File) (uint32, error) {
+	var mode uint32
+	r, _, err := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode)))
+	if r == 0 {
+		return 0, err
+	}
+	return mode, nil
+}
+
+func setConsoleMode(f *os.File, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// makeRaw puts the console into raw mode with VT sequences enabled on input
+// and output, and returns a function that restores its previous state
+func makeRaw(f *os.File) (func(), error) {
+	inMode, err := getConsoleMode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read console mode: %w", err)
+	}
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(f, raw); err != nil {
+		return nil, fmt.Errorf("failed to switch console to raw mode: %w", err)
+	}
+
+	outMode, outErr := getConsoleMode(os.Stdout)
+	if outErr == nil {
+		setConsoleMode(os.Stdout, outMode|enableVirtualTerminalProcessing)
+	}
+	return func() {
+		setConsoleMode(f, inMode)
+		if outErr == nil {
+			setConsoleMode(os.Stdout, outMode)
+		}
+	}, nil
+}
+
+// terminalSize returns the width and height of the console window
+func terminalSize(f *os.File) (int, int) {
+	var info consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 80, 24
+	}
+	return int(info.Window[2]-info.Window[0]) + 1, int(info.Window[3]-info.Window[1]) + 1
+}
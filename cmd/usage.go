@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+	"berga/pkg/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Sort orders for the list commands
+const (
+	sortName     = "name"
+	sortFrecency = "frecency"
+	sortMtime    = "mtime"
+	sortSize     = "size"
+)
+
+// UsageEntry records how often and how recently an item was used
+type UsageEntry store.Usage
+
+// UsageStats is the recorded usage, keyed by item kind and then item name
+type UsageStats struct {
+	Scripts   map[string]UsageEntry
+	Templates map[string]UsageEntry
+}
+
+var (
+	recentLimit int
+	recentType  string
+)
+
+// recentCmd lists recently used scripts and templates
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List recently used scripts and templates",
+	Long: `List the scripts you ran and the templates you applied most recently,
+with how often each was used.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listRecent(recentType, recentLimit)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recentCmd)
+
+	// Flags
+	recentCmd.Flags().IntVarP(&recentLimit, "limit", "n", 10, "Show at most this many items")
+	recentCmd.Flags().StringVar(&recentType, "type", "", "Only show this type: script or template")
+}
+
+func loadUsage() (*UsageStats, error) {
+	stats := &UsageStats{
+		Scripts:   make(map[string]UsageEntry),
+		Templates: make(map[string]UsageEntry),
+	}
+	err := withStore(func(s store.Store) error {
+		for _, kind := range []string{"script", "template"} {
+			usage, err := s.Usage(kind)
+			if err != nil {
+				return err
+			}
+			for name, u := range usage {
+				stats.entries(kind)[name] = UsageEntry(u)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func saveUsage(stats *UsageStats) error {
+	return withStore(func(s store.Store) error {
+		for _, kind := range []string{"script", "template"} {
+			usage := make(map[string]store.Usage)
+			for name, u := range stats.entries(kind) {
+				usage[name] = store.Usage(u)
+			}
+			if err := s.SetUsage(kind, usage); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// entries returns the usage map for a kind of item
+func (u *UsageStats) entries(kind string) map[string]UsageEntry {
+	if kind == "template" {
+		return u.Templates
+	}
+	return u.Scripts
+}
+
+// recordUsage counts a use of a script or template. Tracking is best-effort:
+// failures are only reported in verbose mode and never fail the command.
+func recordUsage(kind, name string) {
+	if kind == "template" {
+		name = strings.TrimSuffix(name, ".tmpl")
+	}
+	err := withStore(func(s store.Store) error {
+		return s.RecordUse(kind, name, time.Now().UTC())
+	})
+	if err != nil && viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+	}
+}
+
+// recordTemplateUsage counts a use of a template. Remote templates are not
+// tracked.
+func recordTemplateUsage(templateName string) {
+	if !isRemoteTemplate(templateName) {
+		recordUsage("template", templateName)
+	}
+}
+
+// frecency scores an item by use count, weighted by how recently it was used
+func frecency(entry UsageEntry, now time.Time) float64 {
+	if entry.Count == 0 {
+		return 0
+	}
+	age := now.Sub(entry.LastUsed)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(entry.Count) * weight
+}
+
+// validateSortOrder checks a --sort value
+func validateSortOrder(order string) error {
+	switch order {
+	case sortName, sortFrecency, sortMtime, sortSize:
+		return nil
+	}
+	return fmt.Errorf("invalid sort order '%s' (expected name, frecency, mtime, or size)", order)
+}
+
+// sortListing orders directory entries for a list command. Frecency uses the
+// usage store; mtime and size put the newest and largest files first.
+func sortListing(files []os.DirEntry, kind, order string) error {
+	if err := validateSortOrder(order); err != nil {
+		return err
+	}
+	if order == sortName {
+		return nil
+	}
+
+	var stats *UsageStats
+	if order == sortFrecency {
+		var err error
+		if stats, err = loadUsage(); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+
+	key := func(file os.DirEntry) float64 {
+		switch order {
+		case sortFrecency:
+			name := file.Name()
+			if kind == "template" {
+				name = strings.TrimSuffix(name, ".tmpl")
+			}
+			return frecency(stats.entries(kind)[name], now)
+		default:
+			info, err := file.Info()
+			if err != nil {
+				return 0
+			}
+			if order == sortMtime {
+				return float64(info.ModTime().UnixNano())
+			}
+			return float64(info.Size())
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return key(files[i]) > key(files[j]) })
+	return nil
+}
+
+// humanizeAge describes how long ago a time was, e.g. "3h ago"
+func humanizeAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// recentItem is one entry in the "berga recent" listing
+type recentItem struct {
+	Kind string
+	Name string
+	UsageEntry
+}
+
+func listRecent(kind string, limit int) error {
+	if kind != "" && kind != "script" && kind != "template" {
+		return fmt.Errorf("invalid type '%s' (expected script or template)", kind)
+	}
+
+	stats, err := loadUsage()
+	if err != nil {
+		return err
+	}
+
+	var items []recentItem
+	for _, k := range []string{"script", "template"} {
+		if kind != "" && k != kind {
+			continue
+		}
+		for name, entry := range stats.entries(k) {
+			items = append(items, recentItem{k, name, entry})
+		}
+	}
+	if len(items) == 0 {
+		fmt.Println("Nothing used yet. Scripts you run and templates you apply show up here.")
+		return nil
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].LastUsed.After(items[j].LastUsed) })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	listHeader(i18n.T("header.recently_used"))
+	now := time.Now()
+	for _, item := range items {
+		icon := ui.Icon("🚀", "*")
+		uses := "run"
+		if item.Kind == "template" {
+			icon = ui.Icon("📋", "-")
+			uses = "use"
+		}
+		if item.Count != 1 {
+			uses += "s"
+		}
+		fmt.Printf("  %s %s %s\n", icon, ui.Bold(item.Name),
+			ui.Dim(fmt.Sprintf("(%s, %d %s, %s)", item.Kind, item.Count, uses, humanizeAge(now.Sub(item.LastUsed)))))
+	}
+	return nil
+}
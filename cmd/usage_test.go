@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordUsage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	recordUsage("script", "deploy.sh")
+	recordUsage("script", "deploy.sh")
+	recordUsage("template", "gitignore.tmpl")
+
+	stats, err := loadUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats.Scripts["deploy.sh"].Count; got != 2 {
+		t.Errorf("Expected 2 uses of deploy.sh, got %d", got)
+	}
+	if entry, ok := stats.Templates["gitignore"]; !ok || entry.Count != 1 {
+		t.Errorf("Expected template usage keyed without .tmpl, got %v", stats.Templates)
+	}
+	if time.Since(stats.Scripts["deploy.sh"].LastUsed) > time.Minute {
+		t.Error("Expected last-used time to be recorded")
+	}
+}
+
+func TestFrecency(t *testing.T) {
+	now := time.Now()
+	recent := UsageEntry{Count: 2, LastUsed: now.Add(-10 * time.Minute)}
+	frequentButOld := UsageEntry{Count: 10, LastUsed: now.Add(-30 * 24 * time.Hour)}
+	frequentThisWeek := UsageEntry{Count: 20, LastUsed: now.Add(-3 * 24 * time.Hour)}
+
+	if frecency(recent, now) <= frecency(frequentButOld, now) {
+		t.Error("Expected a recent item to outrank an old one used slightly more")
+	}
+	if frecency(frequentThisWeek, now) <= frecency(recent, now) {
+		t.Error("Expected a much more frequent item this week to outrank a recent one")
+	}
+	if frecency(UsageEntry{}, now) != 0 {
+		t.Error("Expected unused items to score zero")
+	}
+}
+
+func TestSortListing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.sh": "1", "b.sh": "333", "c.sh": "22"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(order string) []string {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sortListing(files, "script", order); err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Name())
+		}
+		return out
+	}
+
+	if got := names(sortSize); got[0] != "b.sh" || got[1] != "c.sh" {
+		t.Errorf("Expected largest first, got %v", got)
+	}
+
+	recordUsage("script", "c.sh")
+	if got := names(sortFrecency); got[0] != "c.sh" || got[1] != "a.sh" {
+		t.Errorf("Expected used script first and the rest by name, got %v", got)
+	}
+
+	if err := sortListing(nil, "script", "random"); err == nil {
+		t.Error("Expected an error for an unknown sort order")
+	}
+}
+
+func TestHumanizeAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		10 * time.Second: "just now",
+		5 * time.Minute:  "5m ago",
+		3 * time.Hour:    "3h ago",
+		50 * time.Hour:   "2d ago",
+	}
+	for d, want := range tests {
+		if got := humanizeAge(d); got != want {
+			t.Errorf("humanizeAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"berga/internal/i18n"
+	"berga/internal/ui"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Revision actions recorded in the version history
+const (
+	revisionOriginal = "original"
+	revisionExternal = "external change"
+	revisionEdit     = "edit"
+	revisionCreate   = "create"
+	revisionImport   = "import"
+	revisionExport   = "export-builtin"
+	revisionRollback = "rollback"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// fileRevision is one saved version of a script or template
+type fileRevision struct {
+	Hash   string    `yaml:"hash"`
+	Time   time.Time `yaml:"time"`
+	Action string    `yaml:"action"`
+	Size   int       `yaml:"size"`
+}
+
+// revisionLog is the history of one script or template, oldest first
+type revisionLog struct {
+	Revisions []fileRevision `yaml:"revisions"`
+}
+
+// revisionKey returns the name a script or template's history is kept
+// under: its path relative to the scripts or templates directory holding it
+func revisionKey(kind, path string) string {
+	dirs := GetTemplatesDirs()
+	if kind == "script" {
+		dirs = GetScriptsDirs()
+	}
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// revisionLogPath returns the history file of a script or template
+func revisionLogPath(kind, name string) string {
+	return filepath.Join(GetVersionsDir(), kind, name+".yaml")
+}
+
+// revisionObjectPath returns where content with the given hash is stored.
+// Identical content is stored once, whichever file it came from.
+func revisionObjectPath(hash string) string {
+	return filepath.Join(GetVersionsDir(), "objects", hash[:2], hash[2:])
+}
+
+func loadRevisionLog(kind, name string) (*revisionLog, error) {
+	data, err := os.ReadFile(revisionLogPath(kind, name))
+	if os.IsNotExist(err) {
+		return &revisionLog{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version history: %w", err)
+	}
+	var log revisionLog
+	if err := yaml.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("failed to parse version history: %w", err)
+	}
+	return &log, nil
+}
+
+func saveRevisionLog(kind, name string, log *revisionLog) error {
+	data, err := yaml.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("failed to encode version history: %w", err)
+	}
+	path := revisionLogPath(kind, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// recordRevision saves the current content of a script or template to its
+// history, unless it matches the latest revision. A missing file records
+// nothing.
+func recordRevision(kind, name, path, action string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return withLock("versions", func() error {
+		log, err := loadRevisionLog(kind, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if n := len(log.Revisions); n > 0 && log.Revisions[n-1].Hash == hash {
+			return nil
+		}
+
+		object := revisionObjectPath(hash)
+		if _, err := os.Stat(object); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+				return fmt.Errorf("failed to create versions directory: %w", err)
+			}
+			if err := writeFileAtomic(object, content, 0600); err != nil {
+				return fmt.Errorf("failed to save revision: %w", err)
+			}
+		}
+
+		log.Revisions = append(log.Revisions, fileRevision{Hash: hash, Time: time.Now(), Action: action, Size: len(content)})
+		return saveRevisionLog(kind, name, log)
+	})
+}
+
+// snapshotBefore records a file's content before berga changes it. The first
+// snapshot is the original; later ones only differ from the latest revision if
+// the file was changed outside berga.
+func snapshotBefore(kind, name, path string) error {
+	log, err := loadRevisionLog(kind, name)
+	if err != nil {
+		return err
+	}
+	action := revisionExternal
+	if len(log.Revisions) == 0 {
+		action = revisionOriginal
+	}
+	return recordRevision(kind, name, path, action)
+}
+
+// trackChange records a file's content before and after berga changes it.
+// History problems are reported but never stop the change itself.
+func trackChange(kind, path, action string, change func() error) error {
+	name := revisionKey(kind, path)
+	if err := snapshotBefore(kind, name, path); err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: failed to save version of %s: %v", name, err)))
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	if err := recordRevision(kind, name, path, action); err != nil {
+		fmt.Fprintln(os.Stderr, ui.ErrYellow(fmt.Sprintf("Warning: failed to save version of %s: %v", name, err)))
+	}
+	return nil
+}
+
+// findRevision looks up a revision by number (1 is the oldest) or by a
+// prefix of its hash
+func findRevision(log *revisionLog, rev string) (int, error) {
+	if n, err := strconv.Atoi(rev); err == nil {
+		if n < 1 || n > len(log.Revisions) {
+			return 0, fmt.Errorf("revision %d does not exist (1-%d)", n, len(log.Revisions))
+		}
+		return n - 1, nil
+	}
+
+	found := -1
+	for i, r := range log.Revisions {
+		if len(rev) >= 4 && strings.HasPrefix(r.Hash, rev) {
+			if found >= 0 && log.Revisions[found].Hash != r.Hash {
+				return 0, fmt.Errorf("revision '%s' is ambiguous", rev)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("revision '%s' not found", rev)
+	}
+	return found, nil
+}
+
+// versionedItem is a script or template the version commands work on
+type versionedItem struct {
+	Kind string
+	Name string // the history key, see revisionKey
+	Path string
+}
+
+// lookupVersionedItem finds the file behind a script or template name
+func lookupVersionedItem(kind, name string) versionedItem {
+	var path string
+	if kind == "script" {
+		path = resolveScriptPath(name)
+	} else if found, ok := findLocalTemplate(name); ok {
+		path = found
+	} else {
+		path = filepath.Join(GetTemplatesDir(), name+".tmpl")
+	}
+	return versionedItem{Kind: kind, Name: revisionKey(kind, path), Path: path}
+}
+
+// revisionContent returns the content of a revision, decrypted for
+// encrypted scripts
+func revisionContent(item versionedItem, r fileRevision) ([]byte, error) {
+	object := revisionObjectPath(r.Hash)
+	if isEncryptedScript(item.Name) {
+		return decryptScript(object)
+	}
+	content, err := os.ReadFile(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision %s: %w", shortHash(r.Hash), err)
+	}
+	return content, nil
+}
+
+// currentContent returns a file's content, decrypted for encrypted scripts
+func currentContent(item versionedItem) ([]byte, error) {
+	if isEncryptedScript(item.Name) {
+		return decryptScript(item.Path)
+	}
+	return os.ReadFile(item.Path)
+}
+
+func shortHash(hash string) string {
+	return hash[:12]
+}
+
+func listVersions(kind, name string) error {
+	item := lookupVersionedItem(kind, name)
+	log, err := loadRevisionLog(kind, item.Name)
+	if err != nil {
+		return err
+	}
+	if len(log.Revisions) == 0 {
+		fmt.Printf("No saved versions of %s.\n", item.Name)
+		return nil
+	}
+
+	var current string
+	if content, err := os.ReadFile(item.Path); err == nil {
+		sum := sha256.Sum256(content)
+		current = hex.EncodeToString(sum[:])
+	}
+
+	listHeader(i18n.T("header.versions_of", item.Name))
+	for i, r := range log.Revisions {
+		marker := ""
+		if r.Hash == current && i == len(log.Revisions)-1 {
+			marker = " " + ui.Green("(current)")
+		}
+		fmt.Printf("  %3d  %s  %s  %-15s %s%s\n", i+1, ui.Yellow(shortHash(r.Hash)),
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Action, ui.Dim(humanizeSize(int64(r.Size))), marker)
+	}
+	return nil
+}
+
+func diffVersion(kind, name, rev string) error {
+	item := lookupVersionedItem(kind, name)
+	log, err := loadRevisionLog(kind, item.Name)
+	if err != nil {
+		return err
+	}
+	i, err := findRevision(log, rev)
+	if err != nil {
+		return err
+	}
+	old, err := revisionContent(item, log.Revisions[i])
+	if err != nil {
+		return err
+	}
+	current, err := currentContent(item)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", item.Name, err)
+	}
+
+	if bytes.Equal(old, current) {
+		fmt.Printf("%s is identical to revision %d\n", item.Name, i+1)
+		return nil
+	}
+	fmt.Print(colorDiff(unifiedDiff(fmt.Sprintf("%s@%d", item.Name, i+1), item.Name, string(old), string(current))))
+	return nil
+}
+
+func rollbackVersion(kind, name, rev string) error {
+	item := lookupVersionedItem(kind, name)
+	log, err := loadRevisionLog(kind, item.Name)
+	if err != nil {
+		return err
+	}
+	i, err := findRevision(log, rev)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(revisionObjectPath(log.Revisions[i].Hash))
+	if err != nil {
+		return fmt.Errorf("failed to read revision %d: %w", i+1, err)
+	}
+
+	perm := os.FileMode(0644)
+	if kind == "script" {
+		perm = 0755
+	}
+	if info, err := os.Stat(item.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	err = trackChange(kind, item.Path, revisionRollback, func() error {
+		if err := os.MkdirAll(filepath.Dir(item.Path), 0755); err != nil {
+			return err
+		}
+		return writeFileAtomic(item.Path, content, perm)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back %s: %w", item.Name, err)
+	}
+	fmt.Printf("Rolled back %s to revision %d (%s)\n", item.Name, i+1, shortHash(log.Revisions[i].Hash))
+	return nil
+}
+
+// unifiedDiff compares two texts line by line in unified diff format
+func unifiedDiff(fromName, toName, from, to string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table into a list of edits
+	type edit struct {
+		op   byte // ' ', '-', or '+'
+		line string
+		ai   int // line index in a before this edit
+		bi   int // line index in b before this edit
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(edits); {
+		// Find the next change and the extent of its hunk
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		first := max(start-diffContext, 0)
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k
+			} else if k-end > 2*diffContext {
+				break
+			}
+		}
+		last := min(end+diffContext, len(edits)-1)
+
+		aCount, bCount := 0, 0
+		for _, e := range edits[first : last+1] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", edits[first].ai+1, aCount, edits[first].bi+1, bCount)
+		for _, e := range edits[first : last+1] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		start = last + 1
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// colorDiff colors removed lines red, added lines green, and hunk headers cyan
+func colorDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(body, "---") || strings.HasPrefix(body, "+++"):
+			lines[i] = ui.Bold(body) + line[len(body):]
+		case strings.HasPrefix(body, "@@"):
+			lines[i] = ui.Cyan(body) + line[len(body):]
+		case strings.HasPrefix(body, "-"):
+			lines[i] = ui.Red(body) + line[len(body):]
+		case strings.HasPrefix(body, "+"):
+			lines[i] = ui.Green(body) + line[len(body):]
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// versionCommands builds the versions, diff, and rollback commands for
+// scripts or templates
+func versionCommands(kind string) []*cobra.Command {
+	return []*cobra.Command{
+		{
+			Use:   "versions [name]",
+			Short: fmt.Sprintf("List saved versions of a %s", kind),
+			Long: fmt.Sprintf(`List the revisions saved whenever berga edits, imports, or rolls back a
+%s. Revisions are numbered from 1, the oldest, and can also be given by hash.`, kind),
+			Args: cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return listVersions(kind, args[0])
+			},
+		},
+		versionDiffCommand(kind),
+		{
+			Use:   "rollback [name] [revision]",
+			Short: fmt.Sprintf("Restore a saved version of a %s", kind),
+			Long: fmt.Sprintf(`Restore a saved version of a %s. The content being replaced is saved as a
+new revision first, so a rollback can itself be undone.`, kind),
+			Args: cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cmd.SilenceUsage = true
+				return rollbackVersion(kind, args[0], args[1])
+			},
+		},
+	}
+}
+
+// versionDiffCommand builds the diff command. The template one also compares
+// a rendered template with a file; see diffTemplateFile.
+func versionDiffCommand(kind string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [name] [revision]",
+		Short: fmt.Sprintf("Show changes between a saved version and the current %s", kind),
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if kind == "template" && isDriftTarget(args[1]) {
+				return withAnswers(func() error {
+					return diffTemplateFile(args[0], args[1])
+				})
+			}
+			return diffVersion(kind, args[0], args[1])
+		},
+	}
+	if kind != "template" {
+		return cmd
+	}
+
+	cmd.Use = "diff [name] [revision-or-file]"
+	cmd.Short = "Show changes since a saved version of a template, or a file's drift from it"
+	cmd.Long = `Given a revision, show how the template changed since then. Given a file,
+render the template with the current variables and show how the file differs
+from the result, e.g. how far a live config has drifted from its template:
+
+  berga template diff gitconfig ~/.gitconfig
+
+Lines marked + are what applying the template again would bring back. If the
+file holds the template's block from 'template apply --merge', only that block
+is compared. Nothing is prompted for: variables come from the config, schema
+defaults, and --answers. A bare number is always a revision; write ./3 for a
+file called 3.`
+	cmd.Flags().StringVar(&templateAnswersFile, "answers", "", "Take variable values from a file saved with --record-answers")
+	cmd.Flags().BoolVar(&templateAllowExec, "allow-exec", false, "Let the template run commands with the shell function")
+	return cmd
+}
+
+func init() {
+	scriptCmd.AddCommand(versionCommands("script")...)
+	templateCmd.AddCommand(versionCommands("template")...)
+}
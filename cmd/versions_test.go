@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordRevision(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(GetScriptsDir(), "deploy.sh")
+	writeHomeFile(t, "scripts/deploy.sh", "echo one\n")
+
+	if err := snapshotBefore("script", "deploy.sh", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordRevision("script", "deploy.sh", path, revisionEdit); err != nil {
+		t.Fatal(err)
+	}
+	writeHomeFile(t, "scripts/deploy.sh", "echo two\n")
+	if err := recordRevision("script", "deploy.sh", path, revisionEdit); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := loadRevisionLog("script", "deploy.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Revisions) != 2 {
+		t.Fatalf("Expected unchanged content to be skipped, got %d revisions", len(log.Revisions))
+	}
+	if log.Revisions[0].Action != revisionOriginal || log.Revisions[1].Action != revisionEdit {
+		t.Errorf("Unexpected actions: %+v", log.Revisions)
+	}
+	content, err := os.ReadFile(revisionObjectPath(log.Revisions[0].Hash))
+	if err != nil || string(content) != "echo one\n" {
+		t.Errorf("Expected the first revision to be stored, got %q (err %v)", content, err)
+	}
+
+	// A change made outside berga is picked up before the next edit
+	writeHomeFile(t, "scripts/deploy.sh", "echo three\n")
+	if err := snapshotBefore("script", "deploy.sh", path); err != nil {
+		t.Fatal(err)
+	}
+	log, _ = loadRevisionLog("script", "deploy.sh")
+	if len(log.Revisions) != 3 || log.Revisions[2].Action != revisionExternal {
+		t.Errorf("Expected an external change revision, got %+v", log.Revisions)
+	}
+}
+
+func TestFindRevision(t *testing.T) {
+	log := &revisionLog{Revisions: []fileRevision{
+		{Hash: "abcd1111"}, {Hash: "abcd2222"}, {Hash: "ef003333"}, {Hash: "abcd1111"},
+	}}
+	tests := []struct {
+		rev     string
+		want    int
+		wantErr bool
+	}{
+		{"1", 0, false},
+		{"4", 3, false},
+		{"0", 0, true},
+		{"5", 0, true},
+		{"ef00", 2, false},
+		{"abcd2", 1, false},
+		{"abcd11", 3, false}, // same content twice resolves to the latest
+		{"abcd", 0, true},
+		{"abc", 0, true},
+		{"ffff", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := findRevision(log, tt.rev)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("findRevision(%q) = %d, %v; want %d, error %v", tt.rev, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("old", "new", from, to); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	got := unifiedDiff("old", "new", "", "x\n")
+	if !strings.Contains(got, "@@ -1,0 +1,1 @@\n+x\n") {
+		t.Errorf("Unexpected diff for new content:\n%s", got)
+	}
+}
+
+func TestRollbackVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(GetTemplatesDir(), "readme.tmpl")
+	writeHomeFile(t, "templates/readme.tmpl", "v1\n")
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := trackChange("template", path, revisionEdit, func() error {
+		return os.WriteFile(path, []byte("v2\n"), 0600)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rollbackVersion("template", "readme", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readHomeFile(t, "templates/readme.tmpl"); got != "v1\n" {
+		t.Errorf("Expected the first version back, got %q", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions to be kept, got %v (err %v)", info.Mode().Perm(), err)
+	}
+	log, _ := loadRevisionLog("template", "readme.tmpl")
+	if len(log.Revisions) != 3 || log.Revisions[2].Action != revisionRollback {
+		t.Errorf("Expected the rollback to be recorded, got %+v", log.Revisions)
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"berga/internal/ui"
+	"berga/pkg/scripts"
+	"berga/pkg/templates"
+
+	"github.com/spf13/cobra"
+)
+
+var whichAll bool
+
+// scriptWhichCmd shows which file a script name resolves to
+var scriptWhichCmd = &cobra.Command{
+	Use:   "which [script-name]",
+	Short: "Show which file a script name runs",
+	Long: `Print the path of the script that 'script run' would use. Scripts are looked
+up in the project's scripts directory, then in each of paths.scripts in order,
+and the first match wins. With --all, scripts of the same name further down the
+search path are listed too.
+
+On a terminal, the interpreter that runs the script follows the path: the one
+named by its shebang or extension, found on the PATH the script gets, or its
+container image. Piped, only the path is printed, so $(berga script which
+name) works.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		paths := scripts.DirStore{Dirs: scriptSearchDirs()}.FindAll(args[0])
+		if len(paths) == 0 {
+			return fmt.Errorf("script '%s' not found in %s", args[0], strings.Join(scriptSearchDirs(), ", "))
+		}
+		printWhich(paths, whichAll)
+		if ui.IsTerminal(os.Stdout) {
+			fmt.Println(ui.Dim("runs with: " + scriptInterpreter(paths[0])))
+		}
+		return nil
+	},
+	ValidArgsFunction: completeScriptNames,
+}
+
+// templateWhichCmd shows which file a template name resolves to
+var templateWhichCmd = &cobra.Command{
+	Use:   "which [template-name]",
+	Short: "Show which file a template name applies",
+	Long: `Print the path of the template that 'template apply' would use. Templates are
+looked up in each of paths.templates in order, with or without the .tmpl
+extension, and the first match wins. With --all, templates of the same name
+further down the search path are listed too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		name := args[0]
+		if strings.HasPrefix(name, builtinPrefix) {
+			path, err := resolveBuiltinPath(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", path, ui.Dim("(built in)"))
+			return nil
+		}
+		paths := templates.DirStore{Dirs: GetTemplatesDirs()}.FindAll(name)
+		if len(paths) == 0 {
+			return fmt.Errorf("template '%s' not found in %s", name, strings.Join(GetTemplatesDirs(), ", "))
+		}
+		printWhich(paths, whichAll)
+		return nil
+	},
+	ValidArgsFunction: completeTemplateNames,
+}
+
+func init() {
+	scriptCmd.AddCommand(scriptWhichCmd)
+	templateCmd.AddCommand(templateWhichCmd)
+
+	// Flags
+	for _, cmd := range []*cobra.Command{scriptWhichCmd, templateWhichCmd} {
+		cmd.Flags().BoolVarP(&whichAll, "all", "a", false, "Also list the files the first one hides")
+	}
+}
+
+// printWhich prints the winning path and, with all, the paths it hides
+func printWhich(paths []string, all bool) {
+	fmt.Println(paths[0])
+	if !all {
+		return
+	}
+	for _, path := range paths[1:] {
+		fmt.Printf("%s %s\n", path, ui.Dim("(hidden)"))
+	}
+}
+
+// scriptInterpreter describes what runs a script: its container image, or
+// the interpreter from its extension or shebang with the executable it
+// resolves to on the script's PATH, which includes an activated virtualenv
+func scriptInterpreter(scriptPath string) string {
+	if image := containerImage(scriptPath); image != "" {
+		return "container " + image
+	}
+	argv := scripts.Host.Argv(scriptPath, nil)
+	words := argv[:len(argv)-1]
+	if argv[0] == scriptPath {
+		if words = scripts.Shebang(scriptPath); len(words) == 0 {
+			return "the script itself (no shebang)"
+		}
+	}
+
+	// With /usr/bin/env, the program is the first word that is neither an
+	// option nor a variable assignment
+	name := words[0]
+	if filepath.Base(name) == "env" {
+		name = ""
+		for _, word := range words[1:] {
+			if !strings.HasPrefix(word, "-") && !strings.Contains(word, "=") {
+				name = word
+				break
+			}
+		}
+	}
+	described := strings.Join(words, " ")
+	if name == "" || filepath.IsAbs(name) {
+		return described
+	}
+
+	envs, _ := resolveScriptEnvs(scriptPath)
+	environ := envs.activate(scriptEnviron())
+	if environ == nil {
+		environ = os.Environ()
+	}
+	resolved, ok := lookPathIn(name, lookupEnv(environ, "PATH"))
+	if !ok {
+		return described + " " + ui.Yellow("(not found on PATH)")
+	}
+	return fmt.Sprintf("%s (%s)", described, resolved)
+}
+
+// lookPathIn finds an executable like exec.LookPath, but on the given PATH
+func lookPathIn(name, pathList string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		path, err := exec.LookPath(name)
+		return path, err == nil
+	}
+	for _, dir := range filepath.SplitList(pathList) {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// printShadowed lists entries of a search directory that an earlier
+// directory hides, with the directory that wins
+func printShadowed(names []string, shadowedBy map[string]string) {
+	for _, name := range names {
+		fmt.Printf("  %s\n", ui.Dim(fmt.Sprintf("%s (hidden by %s)", name, shadowedBy[name])))
+	}
+}
+
+// unshadowedEntries splits a directory listing into the entries no earlier
+// directory hides and the names of those it does
+func unshadowedEntries(files []os.DirEntry, shadowed map[string]string) ([]os.DirEntry, []string) {
+	var visible []os.DirEntry
+	var hidden []string
+	for _, file := range files {
+		if shadowed[file.Name()] == "" {
+			visible = append(visible, file)
+		} else if !file.IsDir() {
+			hidden = append(hidden, file.Name())
+		}
+	}
+	return visible, hidden
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScriptInterpreter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks shebangs")
+	}
+	t.Setenv("HOME", t.TempDir())
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "python3"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", bin)
+
+	path := writeTestScript(t, "tool.py", "#!/usr/bin/env -S python3 -u\nprint(1)\n")
+	if got := scriptInterpreter(path); got != "/usr/bin/env -S python3 -u ("+filepath.Join(bin, "python3")+")" {
+		t.Errorf("Unexpected interpreter %q", got)
+	}
+
+	path = writeTestScript(t, "tool.rb", "#!/usr/bin/env ruby\n")
+	if got := scriptInterpreter(path); !strings.Contains(got, "not found on PATH") {
+		t.Errorf("Expected a missing interpreter to be flagged, got %q", got)
+	}
+
+	path = writeTestScript(t, "run.sh", "#!/bin/bash\n")
+	if got := scriptInterpreter(path); got != "/bin/bash" {
+		t.Errorf("Expected the absolute shebang, got %q", got)
+	}
+
+	path = writeTestScript(t, "plain", "echo hi\n")
+	if got := scriptInterpreter(path); !strings.Contains(got, "no shebang") {
+		t.Errorf("Expected a script without a shebang to be noted, got %q", got)
+	}
+}
@@ -0,0 +1,55 @@
+package i18n
+
+// en is the English catalog, which every other catalog falls back to
+var en = map[string]string{
+	// List headers
+	"header.profile":             "%s [profile: %s]",
+	"header.project_scripts":     "Project Scripts",
+	"header.available_scripts":   "Available Scripts",
+	"header.scripts_in":          "Scripts in %s",
+	"header.archived_scripts":    "Archived Scripts",
+	"header.available_templates": "Available Templates",
+	"header.templates_in":        "Templates in %s",
+	"header.audit_log":           "Audit Log",
+	"header.http_requests":       "HTTP Requests",
+	"header.usage_statistics":    "Usage Statistics",
+	"header.tasks":               "Tasks",
+	"header.recently_used":       "Recently Used",
+	"header.versions_of":         "Versions of %s",
+	"header.cheats":              "Cheatsheets",
+
+	// Lists
+	"list.no_scripts":            "No scripts found.",
+	"list.add_scripts":           "Add scripts to: %s",
+	"list.scripts_dir":           "Scripts directory: %s",
+	"list.project_scripts_dir":   "Project scripts directory: %s",
+	"list.scripts_dir_missing":   "Scripts directory does not exist: %s",
+	"list.no_templates":          "No templates found.",
+	"list.add_templates":         "Add templates to: %s",
+	"list.templates_dir":         "Templates directory: %s",
+	"list.templates_dir_missing": "Templates directory does not exist: %s",
+	"list.run_config_init":       "Run 'berga config init' to initialize your configuration.",
+	"list.no_archived_scripts":   "No archived scripts.",
+	"list.no_audit_entries":      "No audit entries found.",
+	"list.no_cheats":             "No cheatsheets found.",
+	"list.add_cheats":            "Add Markdown files to: %s",
+
+	// Prompts
+	"prompt.hint_default_yes":    "(Y/n)",
+	"prompt.hint_default_no":     "(y/N)",
+	"prompt.yes_answers":         "y,yes",
+	"prompt.run_it":              "Run it?",
+	"prompt.type_script_name":    "Type the script name (%s) to run it: ",
+	"prompt.run_against_context": "Run it against '%s' anyway?",
+	"danger.marked":              "is marked danger: %s",
+	"danger.warning":             "Script '%s' %s",
+
+	// Errors
+	"error.prefix":                "Error:",
+	"error.script_not_found":      "script '%s' not found in %s",
+	"error.template_not_found":    "template '%s' not found in %s",
+	"error.cancelled":             "cancelled",
+	"error.confirm_required":      "script '%s' %s; pass --confirm %s to run it non-interactively",
+	"error.confirm_mismatch":      "--confirm %s does not match script '%s'",
+	"error.confirmation_mismatch": "confirmation did not match; script '%s' was not run",
+}
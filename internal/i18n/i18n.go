@@ -0,0 +1,120 @@
+// Package i18n translates berga's human-readable output.
+//
+// Messages are looked up by ID in one catalog per locale, falling back to
+// English for messages a catalog lacks. IDs are stable: a message's wording
+// may change, its ID does not. Only text meant for people goes through here;
+// JSON output (--json, --result-json, the serve API) is the same in every
+// locale and is what scripts should parse.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when nothing selects a locale berga has a catalog for
+const DefaultLocale = "en"
+
+// catalogs holds the messages of each locale by ID
+var catalogs = map[string]map[string]string{
+	"en": en,
+	"nb": nb,
+}
+
+// aliases maps language codes to the catalog that serves them
+var aliases = map[string]string{
+	"no": "nb",
+	"nn": "nb",
+}
+
+var current = DefaultLocale
+
+// Locales returns the locales with a catalog, sorted
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Normalize turns a locale name as found in LANG, such as nb_NO.UTF-8, into
+// the catalog serving it. It reports false when berga has no such catalog.
+func Normalize(name string) (string, bool) {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	lang, _, _ := strings.Cut(strings.ReplaceAll(name, "-", "_"), "_")
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := aliases[lang]; ok {
+		lang = alias
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang, true
+	}
+	return "", false
+}
+
+// Detect picks the locale: the configured one when set, otherwise the first
+// of LC_ALL, LC_MESSAGES, and LANG that is set, as gettext does. C, POSIX,
+// and locales without a catalog give English.
+func Detect(configured string) string {
+	name := configured
+	if name == "" {
+		for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if name = os.Getenv(key); name != "" {
+				break
+			}
+		}
+	}
+	if locale, ok := Normalize(name); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// SetLocale selects the catalog T uses. Unknown locales select English.
+func SetLocale(name string) {
+	locale, ok := Normalize(name)
+	if !ok {
+		locale = DefaultLocale
+	}
+	current = locale
+}
+
+// Locale returns the selected locale
+func Locale() string {
+	return current
+}
+
+// T returns the message with the given ID in the selected locale, formatted
+// with args as by fmt.Sprintf. A message missing from every catalog is
+// returned as its ID, so that a typo shows instead of vanishing.
+func T(id string, args ...any) string {
+	message, ok := catalogs[current][id]
+	if !ok {
+		if message, ok = en[id]; !ok {
+			message = id
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// IsYes reports whether answer is yes to a yes/no prompt in the selected
+// locale. The English answers are accepted in every locale.
+func IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, words := range []string{T("prompt.yes_answers"), en["prompt.yes_answers"]} {
+		for _, word := range strings.Split(words, ",") {
+			if answer == strings.TrimSpace(word) {
+				return true
+			}
+		}
+	}
+	return false
+}
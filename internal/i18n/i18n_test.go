@@ -0,0 +1,71 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verb = regexp.MustCompile(`%[a-z]`)
+
+// TestCatalogsMatchEnglish keeps every catalog's IDs and format verbs in step
+// with the English one, which is the reference for both
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for _, locale := range Locales() {
+		for id, message := range catalogs[locale] {
+			english, ok := en[id]
+			if !ok {
+				t.Errorf("%s: message %s is not in the English catalog", locale, id)
+				continue
+			}
+			if got, want := verb.FindAllString(message, -1), verb.FindAllString(english, -1); len(got) != len(want) {
+				t.Errorf("%s: message %s has verbs %v, English has %v", locale, id, got, want)
+			}
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "nb_NO.UTF-8")
+	tests := []struct {
+		configured string
+		want       string
+	}{
+		{"", "nb"},
+		{"en", "en"},
+		{"en_GB", "en"},
+		{"no", "nb"},
+		{"de_DE.UTF-8", "en"},
+		{"C", "en"},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.configured); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.configured, got, tt.want)
+		}
+	}
+
+	t.Setenv("LC_ALL", "C.UTF-8")
+	if got := Detect(""); got != "en" {
+		t.Errorf("Expected LC_ALL to win over LANG, got %q", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+	SetLocale("nb_NO")
+	if got := T("header.scripts_in", "/tmp"); got != "Skript i /tmp" {
+		t.Errorf("Unexpected translation %q", got)
+	}
+	en["test.only_english"] = "Only %s"
+	defer delete(en, "test.only_english")
+	if got := T("test.only_english", "English"); got != "Only English" {
+		t.Errorf("Expected the English fallback, got %q", got)
+	}
+	if got := T("test.missing"); got != "test.missing" {
+		t.Errorf("Expected a missing message to show its ID, got %q", got)
+	}
+	if !IsYes(" Ja ") || !IsYes("y") || IsYes("n") {
+		t.Error("Expected Norwegian and English yes answers to be accepted")
+	}
+}
@@ -0,0 +1,55 @@
+package i18n
+
+// nb is the Norwegian Bokmål catalog, also used for no and nn
+var nb = map[string]string{
+	// List headers
+	"header.profile":             "%s [profil: %s]",
+	"header.project_scripts":     "Prosjektskript",
+	"header.available_scripts":   "Tilgjengelige skript",
+	"header.scripts_in":          "Skript i %s",
+	"header.archived_scripts":    "Arkiverte skript",
+	"header.available_templates": "Tilgjengelige maler",
+	"header.templates_in":        "Maler i %s",
+	"header.audit_log":           "Revisjonslogg",
+	"header.http_requests":       "HTTP-forespørsler",
+	"header.usage_statistics":    "Bruksstatistikk",
+	"header.tasks":               "Oppgaver",
+	"header.recently_used":       "Nylig brukt",
+	"header.versions_of":         "Versjoner av %s",
+	"header.cheats":              "Jukselapper",
+
+	// Lists
+	"list.no_scripts":            "Fant ingen skript.",
+	"list.add_scripts":           "Legg skript i: %s",
+	"list.scripts_dir":           "Skriptmappe: %s",
+	"list.project_scripts_dir":   "Prosjektets skriptmappe: %s",
+	"list.scripts_dir_missing":   "Skriptmappen finnes ikke: %s",
+	"list.no_templates":          "Fant ingen maler.",
+	"list.add_templates":         "Legg maler i: %s",
+	"list.templates_dir":         "Malmappe: %s",
+	"list.templates_dir_missing": "Malmappen finnes ikke: %s",
+	"list.run_config_init":       "Kjør 'berga config init' for å sette opp konfigurasjonen.",
+	"list.no_archived_scripts":   "Ingen arkiverte skript.",
+	"list.no_audit_entries":      "Fant ingen oppføringer i revisjonsloggen.",
+	"list.no_cheats":             "Fant ingen jukselapper.",
+	"list.add_cheats":            "Legg Markdown-filer i: %s",
+
+	// Prompts
+	"prompt.hint_default_yes":    "(J/n)",
+	"prompt.hint_default_no":     "(j/N)",
+	"prompt.yes_answers":         "j,ja",
+	"prompt.run_it":              "Kjøre det?",
+	"prompt.type_script_name":    "Skriv navnet på skriptet (%s) for å kjøre det: ",
+	"prompt.run_against_context": "Kjøre det mot '%s' likevel?",
+	"danger.marked":              "er merket danger: %s",
+	"danger.warning":             "Skriptet '%s' %s",
+
+	// Errors
+	"error.prefix":                "Feil:",
+	"error.script_not_found":      "fant ikke skriptet '%s' i %s",
+	"error.template_not_found":    "fant ikke malen '%s' i %s",
+	"error.cancelled":             "avbrutt",
+	"error.confirm_required":      "skriptet '%s' %s; bruk --confirm %s for å kjøre det uten spørsmål",
+	"error.confirm_mismatch":      "--confirm %s stemmer ikke med skriptet '%s'",
+	"error.confirmation_mismatch": "bekreftelsen stemte ikke; skriptet '%s' ble ikke kjørt",
+}
@@ -0,0 +1,158 @@
+// Package ui provides terminal-aware styling for berga's output.
+//
+// Colors are only emitted when the target stream is a terminal, NO_COLOR is
+// unset, TERM is not "dumb", and color has not been disabled with --no-color.
+// CLICOLOR_FORCE forces color on streams that are not terminals. Stdout and
+// stderr are detected separately: the plain helpers style stdout and the Err
+// helpers style stderr.
+// Emoji icons degrade to ASCII on terminals that are unlikely to render them.
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"berga/internal/i18n"
+)
+
+const (
+	reset  = "\033[0m"
+	bold   = "\033[1m"
+	dim    = "\033[2m"
+	rev    = "\033[7m"
+	red    = "\033[31m"
+	green  = "\033[32m"
+	yellow = "\033[33m"
+	cyan   = "\033[36m"
+)
+
+var (
+	colorOut = detectColor(os.Stdout)
+	colorErr = detectColor(os.Stderr)
+	emojiOK  = detectEmoji()
+)
+
+// Configure applies user preferences. It should be called once flags and
+// config have been parsed.
+func Configure(noColor bool) {
+	if noColor {
+		colorOut = false
+		colorErr = false
+	}
+}
+
+// ColorEnabled reports whether styling is applied to stdout
+func ColorEnabled() bool {
+	return colorOut
+}
+
+// IsTerminal reports whether f is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func detectColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+func detectEmoji() bool {
+	if os.Getenv("BERGA_ASCII") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if runtime.GOOS != "windows" {
+		return true
+	}
+	// Windows Terminal and VS Code render emoji; the legacy console does not
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") == "vscode"
+}
+
+func style(enabled bool, code, s string) string {
+	if !enabled {
+		return s
+	}
+	return code + s + reset
+}
+
+// Bold renders s in bold
+func Bold(s string) string { return style(colorOut, bold, s) }
+
+// Dim renders s dimmed
+func Dim(s string) string { return style(colorOut, dim, s) }
+
+// Reverse renders s with foreground and background swapped
+func Reverse(s string) string { return style(colorOut, rev, s) }
+
+// Green renders s in green
+func Green(s string) string { return style(colorOut, green, s) }
+
+// Yellow renders s in yellow
+func Yellow(s string) string { return style(colorOut, yellow, s) }
+
+// Red renders s in red
+func Red(s string) string { return style(colorOut, red, s) }
+
+// Cyan renders s in cyan
+func Cyan(s string) string { return style(colorOut, cyan, s) }
+
+// ErrBold renders s in bold for stderr
+func ErrBold(s string) string { return style(colorErr, bold, s) }
+
+// ErrDim renders s dimmed for stderr
+func ErrDim(s string) string { return style(colorErr, dim, s) }
+
+// ErrGreen renders s in green for stderr
+func ErrGreen(s string) string { return style(colorErr, green, s) }
+
+// ErrYellow renders s in yellow for stderr
+func ErrYellow(s string) string { return style(colorErr, yellow, s) }
+
+// ErrRed renders s in red for stderr
+func ErrRed(s string) string { return style(colorErr, red, s) }
+
+// ErrCyan renders s in cyan for stderr
+func ErrCyan(s string) string { return style(colorErr, cyan, s) }
+
+// Icon returns the emoji when the terminal supports it and the ASCII fallback otherwise
+func Icon(emoji, ascii string) string {
+	if emojiOK {
+		return emoji
+	}
+	return ascii
+}
+
+// Header prints a title followed by an underline of matching width
+func Header(title string) {
+	HeaderTo(os.Stdout, title)
+}
+
+// HeaderTo prints a header to w
+func HeaderTo(w io.Writer, title string) {
+	fmt.Fprintln(w, Bold(title+":"))
+	fmt.Fprintln(w, strings.Repeat("=", utf8.RuneCountInString(title)+1))
+}
+
+// Error prints an error message to w, highlighting the prefix when w is a styled terminal
+func Error(w io.Writer, err error) {
+	prefix := i18n.T("error.prefix")
+	if w == os.Stderr && colorErr {
+		prefix = red + bold + prefix + reset
+	}
+	fmt.Fprintln(w, prefix, err)
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// unsetEnv clears key for the duration of the test
+func unsetEnv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+// setColor overrides the detected color state of both streams
+func setColor(t *testing.T, out, err bool) {
+	t.Helper()
+	oldOut, oldErr := colorOut, colorErr
+	colorOut, colorErr = out, err
+	t.Cleanup(func() { colorOut, colorErr = oldOut, oldErr })
+}
+
+// pipeFile returns the write end of a pipe, which is never a terminal
+func pipeFile(t *testing.T) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	return w
+}
+
+func TestDetectColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		noColor string
+		force   string
+		term    string
+		want    bool
+	}{
+		{"not a terminal", "", "", "xterm", false},
+		{"forced", "", "1", "xterm", true},
+		{"forced on dumb terminal", "", "1", "dumb", true},
+		{"force disabled", "", "0", "xterm", false},
+		{"NO_COLOR", "1", "", "xterm", false},
+		{"NO_COLOR wins over force", "1", "1", "xterm", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, "NO_COLOR")
+			unsetEnv(t, "CLICOLOR_FORCE")
+			if tt.noColor != "" {
+				t.Setenv("NO_COLOR", tt.noColor)
+			}
+			if tt.force != "" {
+				t.Setenv("CLICOLOR_FORCE", tt.force)
+			}
+			t.Setenv("TERM", tt.term)
+
+			if got := detectColor(pipeFile(t)); got != tt.want {
+				t.Errorf("detectColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTerminalPipe(t *testing.T) {
+	if IsTerminal(pipeFile(t)) {
+		t.Error("Expected a pipe not to be a terminal")
+	}
+}
+
+func TestStylePerStream(t *testing.T) {
+	setColor(t, true, false)
+	if got := Yellow("warn"); got != yellow+"warn"+reset {
+		t.Errorf("Expected stdout helpers to style, got %q", got)
+	}
+	if got := ErrYellow("warn"); got != "warn" {
+		t.Errorf("Expected stderr helpers to stay plain, got %q", got)
+	}
+
+	setColor(t, false, true)
+	if got := Red("fail"); got != "fail" {
+		t.Errorf("Expected stdout helpers to stay plain, got %q", got)
+	}
+	if got := ErrRed("fail"); got != red+"fail"+reset {
+		t.Errorf("Expected stderr helpers to style, got %q", got)
+	}
+}
+
+func TestConfigureNoColor(t *testing.T) {
+	setColor(t, true, true)
+	Configure(false)
+	if !colorOut || !colorErr {
+		t.Error("Expected Configure(false) to keep detected colors")
+	}
+	Configure(true)
+	if colorOut || colorErr || ColorEnabled() {
+		t.Error("Expected Configure(true) to disable colors on both streams")
+	}
+	if got := Bold("x") + ErrBold("x"); got != "xx" {
+		t.Errorf("Expected plain output with colors disabled, got %q", got)
+	}
+}
+
+func TestErrorPrefix(t *testing.T) {
+	setColor(t, true, true)
+	var buf bytes.Buffer
+	Error(&buf, errors.New("boom"))
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("Expected no escape codes when writing to a buffer, got %q", buf.String())
+	}
+	if !strings.HasSuffix(buf.String(), "boom\n") {
+		t.Errorf("Expected the error message, got %q", buf.String())
+	}
+}
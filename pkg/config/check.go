@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unchecked lists keys whose contents Check leaves alone: free-form sections
+// read by the command that owns them, and settings only project files use
+var Unchecked = []string{"listen.hooks", "scripts.overrides", "env.profiles", "templates.vars", "scripts.dir"}
+
+// listKeys may hold a YAML list as well as a comma-separated string
+var listKeys = map[string]bool{"listen.allow": true, "groups.*": true, "hosts.*": true}
+
+// Problem is a setting in a config file that berga ignores or cannot read
+type Problem struct {
+	Key     string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// Check compares a config document with Schema and reports unknown keys,
+// with the closest known key when one is near, and values of the wrong type.
+// Extra lists keys that are valid without being in Schema, such as keys
+// bound to global flags. Problems are in file order.
+func Check(doc *yaml.Node, extra ...string) []Problem {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	c := checker{extra: make(map[string]bool)}
+	for _, key := range extra {
+		c.extra[key] = true
+	}
+	c.mapping(doc.Content[0], "")
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems
+}
+
+type checker struct {
+	extra    map[string]bool
+	problems []Problem
+}
+
+func (c *checker) report(key string, line int, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Key: key, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) mapping(node *yaml.Node, prefix string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		key := prefix + keyNode.Value
+		switch {
+		case isUnchecked(key) || c.extra[key]:
+		case schemaKey(key) != "":
+			c.value(key, keyNode.Line, value)
+		case isSection(key):
+			if value.Kind == yaml.MappingNode {
+				c.mapping(value, key+".")
+			} else if value.Tag != "!!null" {
+				c.report(key, keyNode.Line, "'%s' must be a mapping of settings", key)
+			}
+		default:
+			if suggestion := closestKey(key); suggestion != "" {
+				c.report(key, keyNode.Line, "unknown key '%s' (did you mean '%s'?)", key, suggestion)
+			} else {
+				c.report(key, keyNode.Line, "unknown key '%s'", key)
+			}
+		}
+	}
+}
+
+func (c *checker) value(key string, line int, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return
+		}
+		if _, _, err := Validate(key, node.Value); err != nil {
+			c.report(key, line, "%v (got %q)", err, node.Value)
+		}
+	case yaml.SequenceNode:
+		if !listKeys[schemaKey(key)] {
+			c.report(key, line, "'%s' must be a single value, not a list", key)
+			return
+		}
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				c.report(key, item.Line, "'%s' must be a list of plain values", key)
+				return
+			}
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			c.value(key, line, node.Alias)
+		}
+	default:
+		c.report(key, line, "'%s' must be a single value, not a mapping", key)
+	}
+}
+
+// schemaKey returns the Schema entry that covers key, such as secrets.* for
+// secrets.token, or "" when there is none
+func schemaKey(key string) string {
+	if _, ok := Schema[key]; ok {
+		return key
+	}
+	if i := strings.LastIndex(key, "."); i > 0 {
+		if _, ok := Schema[key[:i]+".*"]; ok {
+			return key[:i] + ".*"
+		}
+	}
+	return ""
+}
+
+func isUnchecked(key string) bool {
+	for _, unchecked := range Unchecked {
+		if key == unchecked || strings.HasPrefix(key, unchecked+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSection reports whether key is a mapping that holds known keys
+func isSection(key string) bool {
+	for known := range Schema {
+		if strings.HasPrefix(known, key+".") {
+			return true
+		}
+	}
+	for _, unchecked := range Unchecked {
+		if strings.HasPrefix(unchecked, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey returns the known key nearest to a misspelled one, or the only
+// known key ending in it when it was written without its section, or ""
+func closestKey(key string) string {
+	best, bestDistance := "", 3
+	var nested []string
+	for _, known := range KnownKeys() {
+		if strings.HasSuffix(known, ".*") {
+			continue
+		}
+		if d := editDistance(key, known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+		if strings.HasSuffix(known, "."+key) {
+			nested = append(nested, known)
+		}
+	}
+	if best == "" && len(nested) == 1 {
+		return nested[0]
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	src := `version: 1
+scripts:
+  timout: 10m
+  verbose: nope
+  overrides:
+    deploy.sh: {retries: 3}
+timeout: 5
+listen:
+  allow: [127.0.0.1, 10.0.0.0/8]
+  hooks:
+    deploy: {script: deploy.sh, secret: hook}
+groups:
+  ci: [lint.sh, test.sh]
+secrets:
+  token: abc
+editor: [vim]
+templates: vim
+aliases: {}
+verbose: true
+`
+	doc, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := Check(doc, "verbose")
+	want := []struct {
+		line    int
+		message string
+	}{
+		{3, "unknown key 'scripts.timout' (did you mean 'scripts.timeout'?)"},
+		{4, "'scripts.verbose' must be true or false"},
+		{7, "unknown key 'timeout' (did you mean 'scripts.timeout'?)"},
+		{16, "'editor' must be a single value, not a list"},
+		{17, "'templates' must be a mapping of settings"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Expected %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		if problems[i].Line != w.line || !strings.HasPrefix(problems[i].Message, w.message) {
+			t.Errorf("Expected line %d: %s, got %s", w.line, w.message, problems[i])
+		}
+	}
+}
+
+func TestCheckValidConfig(t *testing.T) {
+	doc, err := Parse([]byte("scripts:\n  timeout: 300\n  verbose:\ntemplates:\n  author: Me\n  vars:\n    license: MIT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := Check(doc); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
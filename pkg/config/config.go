@@ -0,0 +1,153 @@
+// Package config reads, edits, and upgrades berga config files.
+//
+// Files are handled as YAML node trees rather than decoded values, so edits
+// keep the user's comments and key order. Keys are dot-paths such as
+// "scripts.timeout"; Schema describes the ones berga understands.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parse parses a config file into a document node holding a mapping. Empty
+// input gives an empty mapping.
+func Parse(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a YAML mapping")
+	}
+	return doc, nil
+}
+
+// Encode formats a document the way berga writes config files
+func Encode(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Set sets a dot-path key inside a mapping node, creating intermediate
+// mappings as needed
+func Set(mapping *yaml.Node, key, value, tag string) error {
+	parts := strings.Split(key, ".")
+	node := mapping
+	for i, part := range parts {
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == part {
+				child = node.Content[j+1]
+				break
+			}
+		}
+
+		last := i == len(parts)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = &yaml.Node{Kind: yaml.ScalarNode}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, child)
+		}
+
+		if last {
+			if child.Kind != yaml.ScalarNode {
+				return fmt.Errorf("'%s' is a section, not a value", key)
+			}
+			child.Value = value
+			child.Tag = tag
+			child.Style = 0
+			return nil
+		}
+
+		if child.Kind != yaml.MappingNode {
+			// Replace an empty placeholder such as "aliases: {}" or "~"
+			if child.Kind == yaml.ScalarNode && (child.Value == "" || child.Tag == "!!null") {
+				child.Kind = yaml.MappingNode
+				child.Tag = "!!map"
+				child.Value = ""
+			} else {
+				return fmt.Errorf("'%s' is not a section", strings.Join(parts[:i+1], "."))
+			}
+		}
+		child.Style = 0
+		node = child
+	}
+	return nil
+}
+
+// Remove deletes a dot-path key from a mapping node
+func Remove(mapping *yaml.Node, key string) bool {
+	parts := strings.Split(key, ".")
+	node := mapping
+	for i, part := range parts {
+		found := false
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value != part {
+				continue
+			}
+			if i == len(parts)-1 {
+				node.Content = append(node.Content[:j], node.Content[j+2:]...)
+				return true
+			}
+			node = node.Content[j+1]
+			found = node.Kind == yaml.MappingNode
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return false
+}
+
+// Lookup returns the node stored at a dot-path key, or nil
+func Lookup(mapping *yaml.Node, key string) *yaml.Node {
+	node := mapping
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == part {
+				next = node.Content[j+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// Value returns the scalar stored at a dot-path key, or nil
+func Value(mapping *yaml.Node, key string) interface{} {
+	node := Lookup(mapping, key)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil
+	}
+	return node.Value
+}
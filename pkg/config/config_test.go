@@ -0,0 +1,124 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetPreservesComments(t *testing.T) {
+	src := "# top comment\nscripts:\n  timeout: 300  # seconds\naliases: {}\n"
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(src), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Set(doc.Content[0], "scripts.timeout", "120", "!!int"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := Set(doc.Content[0], "aliases.ll", "script list", "!!str"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := Set(doc.Content[0], "templates.author", "Me", "!!str"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(out)
+	for _, want := range []string{"# top comment", "timeout: 120 # seconds", "ll: script list", "author: Me"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	if !Remove(doc.Content[0], "scripts.timeout") {
+		t.Error("Expected scripts.timeout to be removed")
+	}
+	if Remove(doc.Content[0], "scripts.missing") {
+		t.Error("Expected removing a missing key to report false")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if _, _, err := Validate("scripts.timeout", "soon"); err == nil {
+		t.Error("Expected error for an invalid timeout")
+	}
+	if v, tag, err := Validate("scripts.timeout", "120"); err != nil || v != "120" || tag != "!!int" {
+		t.Errorf("Expected seconds to stay a number, got %s %s %v", v, tag, err)
+	}
+	if v, tag, err := Validate("scripts.timeout", "2m30s"); err != nil || v != "2m30s" || tag != "!!str" {
+		t.Errorf("Unexpected result: %s %s %v", v, tag, err)
+	}
+	if v, tag, err := Validate("scripts.verbose", "yes"); err == nil {
+		t.Errorf("Expected error for invalid bool, got %s %s", v, tag)
+	}
+	if _, _, err := Validate("no.such.key", "x"); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+	if v, tag, err := Validate("aliases.ll", "script list"); err != nil || v != "script list" || tag != "!!str" {
+		t.Errorf("Unexpected result: %s %s %v", v, tag, err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("serve:\n  token: keychain:serve.token\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Value(doc.Content[0], "serve.token"); got != "keychain:serve.token" {
+		t.Errorf("Value(serve.token) = %v", got)
+	}
+	if got := Value(doc.Content[0], "serve"); got != nil {
+		t.Errorf("Expected nil for a mapping, got %v", got)
+	}
+	if got := Value(doc.Content[0], "missing.key"); got != nil {
+		t.Errorf("Expected nil for a missing key, got %v", got)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90":    90 * time.Second,
+		"0":     0,
+		"90s":   90 * time.Second,
+		"2m30s": 150 * time.Second,
+		" 1h ":  time.Hour,
+	}
+	for raw, want := range tests {
+		if got, err := ParseTimeout(raw); err != nil || got != want {
+			t.Errorf("ParseTimeout(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"-5", "-1m", "soon", ""} {
+		if _, err := ParseTimeout(raw); err == nil {
+			t.Errorf("Expected ParseTimeout(%q) to fail", raw)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]uint64{
+		"0":      0,
+		"4096":   4096,
+		"512M":   512 << 20,
+		"2g":     2 << 30,
+		"64KiB":  64 << 10,
+		" 1 GB ": 1 << 30,
+	}
+	for raw, want := range tests {
+		if got, err := ParseSize(raw); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"-5M", "M", "lots", "", "1.5G", "99999999999T"} {
+		if _, err := ParseSize(raw); err == nil {
+			t.Errorf("Expected ParseSize(%q) to fail", raw)
+		}
+	}
+}
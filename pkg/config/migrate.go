@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the layout of config files this berga writes. Files
+// without a version key predate versioning and count as version 0.
+const SchemaVersion = 1
+
+// migration upgrades a config document to a version. Apply edits the
+// top-level mapping in place and describes each change it made.
+type migration struct {
+	Version int
+	Apply   func(mapping *yaml.Node) []string
+}
+
+// migrations are applied in order to bring a file up to SchemaVersion
+var migrations = []migration{
+	// 1: directory and author settings moved under paths and templates
+	{1, migrateFlatKeys},
+}
+
+// flatKeys maps settings from the original flat layout to their current keys
+var flatKeys = [][2]string{
+	{"scripts_dir", "paths.scripts"},
+	{"templates_dir", "paths.templates"},
+	{"author", "templates.author"},
+	{"email", "templates.email"},
+}
+
+func migrateFlatKeys(mapping *yaml.Node) []string {
+	var changes []string
+	for _, rename := range flatKeys {
+		if change, ok := renameNode(mapping, rename[0], rename[1]); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// renameNode moves the value at from to to. When to is already set the old
+// key is dropped, since the current one is what berga reads.
+func renameNode(mapping *yaml.Node, from, to string) (string, bool) {
+	value := Lookup(mapping, from)
+	if value == nil {
+		return "", false
+	}
+	Remove(mapping, from)
+	if Lookup(mapping, to) != nil {
+		return fmt.Sprintf("removed %s (%s is already set)", from, to), true
+	}
+
+	// Create the key, then swap in the original node to keep lists and comments
+	if err := Set(mapping, to, "", "!!null"); err != nil {
+		return fmt.Sprintf("removed %s (%s)", from, err), true
+	}
+	*Lookup(mapping, to) = *value
+	return fmt.Sprintf("renamed %s to %s", from, to), true
+}
+
+// Version returns the schema version recorded in a config mapping
+func Version(mapping *yaml.Node) (int, error) {
+	node := Lookup(mapping, "version")
+	if node == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid config version %q", node.Value)
+	}
+	return version, nil
+}
+
+// setVersion records the schema version, as the first key when new
+func setVersion(mapping *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if node := Lookup(mapping, "version"); node != nil {
+		node.Value = value
+		node.Tag = "!!int"
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(mapping.Content) > 0 {
+		// Keep a comment at the top of the file above the new key
+		key.HeadComment, mapping.Content[0].HeadComment = mapping.Content[0].HeadComment, ""
+	}
+	mapping.Content = append([]*yaml.Node{key, {Kind: yaml.ScalarNode, Tag: "!!int", Value: value}}, mapping.Content...)
+}
+
+// Migrate applies the migrations a document is missing and stamps the current
+// version. It returns the version the document had and the changes made, in
+// order.
+func Migrate(doc *yaml.Node) (int, []string, error) {
+	mapping := doc.Content[0]
+	from, err := Version(mapping)
+	if err != nil {
+		return 0, nil, err
+	}
+	if from > SchemaVersion {
+		return from, nil, fmt.Errorf("config version %d is newer than this berga supports (%d); upgrade berga", from, SchemaVersion)
+	}
+
+	var changes []string
+	for _, migration := range migrations {
+		if migration.Version > from {
+			changes = append(changes, migration.Apply(mapping)...)
+		}
+	}
+	if from < SchemaVersion {
+		setVersion(mapping, SchemaVersion)
+	}
+	return from, changes, nil
+}
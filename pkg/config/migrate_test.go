@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrate(t *testing.T) {
+	src := "# my settings\neditor: vim\nauthor: Ada # me\nscripts_dir: [~/a, ~/b]\ntemplates:\n  email: new@example.com\nemail: old@example.com\n"
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(src), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	from, changes, err := Migrate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 0 || len(changes) != 3 {
+		t.Errorf("Expected 3 changes from version 0, got %d from %d: %v", len(changes), from, changes)
+	}
+
+	mapping := doc.Content[0]
+	if Value(mapping, "templates.author") != "Ada" || Value(mapping, "author") != nil {
+		t.Error("Expected author to move to templates.author")
+	}
+	if Value(mapping, "templates.email") != "new@example.com" || Value(mapping, "email") != nil {
+		t.Error("Expected the existing templates.email to win")
+	}
+	if scripts := Lookup(mapping, "paths.scripts"); scripts == nil || scripts.Kind != yaml.SequenceNode {
+		t.Error("Expected scripts_dir to move to paths.scripts as a list")
+	}
+
+	out, _ := yaml.Marshal(doc)
+	if !strings.HasPrefix(string(out), "# my settings\nversion: 1\n") {
+		t.Errorf("Expected the version first, below the top comment, got:\n%s", out)
+	}
+
+	// A migrated document has nothing left to do
+	if _, changes, _ := Migrate(doc); len(changes) != 0 {
+		t.Errorf("Expected no changes on a second run, got %v", changes)
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	doc := &yaml.Node{}
+	yaml.Unmarshal([]byte("version: 99\nauthor: Ada\n"), doc)
+	if _, _, err := Migrate(doc); err == nil {
+		t.Error("Expected an error for a config from a newer berga")
+	}
+}
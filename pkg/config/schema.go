@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key describes a known configuration key
+type Key struct {
+	Type        string // string, int, bool, duration, timeout, or size
+	Enum        []string
+	Description string
+	Sensitive   bool // stored in the OS keychain by 'config set'
+}
+
+// Schema lists every key berga understands. Keys under a prefix ending in
+// ".*" accept any name below that prefix.
+var Schema = map[string]Key{
+	"version":                   {Type: "int", Description: "Config layout version, upgraded by 'config migrate'"},
+	"editor":                    {Type: "string", Description: "Editor for scripts and templates"},
+	"locale":                    {Type: "string", Enum: []string{"en", "nb"}, Description: "Language of berga's messages (default from LC_ALL, LC_MESSAGES, or LANG)"},
+	"pager":                     {Type: "string", Description: "Pager for the show commands (default $PAGER or less)"},
+	"edit.review":               {Type: "bool", Description: "Ask whether to keep the changes after 'script edit' and 'template edit'"},
+	"shell":                     {Type: "string", Description: "Shell for script execution"},
+	"scripts.timeout":           {Type: "timeout", Description: "Script execution timeout, e.g. 90s or 1h (bare numbers are seconds, 0 for none)"},
+	"scripts.grace":             {Type: "duration", Description: "How long a timed-out script gets to exit after SIGTERM before it is killed"},
+	"scripts.verbose":           {Type: "bool", Description: "Verbose script execution"},
+	"scripts.require_trust":     {Type: "bool", Description: "Refuse untrusted or changed scripts"},
+	"scripts.retries":           {Type: "int", Description: "Times to retry a failing script"},
+	"scripts.container_runtime": {Type: "string", Enum: []string{"docker", "podman"}, Description: "Container CLI for --container"},
+	"scripts.retry_delay":       {Type: "duration", Description: "Delay before the first retry"},
+	"scripts.max_mem":           {Type: "size", Description: "Memory limit for script runs, e.g. 512M or 2G (0 for none)"},
+	"scripts.nice":              {Type: "int", Description: "Niceness script runs start with, -20 to 19 (lower needs privileges)"},
+	"scripts.notify":            {Type: "bool", Description: "Notify when a script run finishes"},
+	"scripts.notify_after":      {Type: "duration", Description: "Only notify for runs at least this long"},
+	"scripts.notify_webhook":    {Type: "string", Description: "Slack or Discord webhook URL for notifications", Sensitive: true},
+	"templates.allow_exec":      {Type: "bool", Description: "Let local templates run commands with the shell function, like --allow-exec"},
+	"templates.author":          {Type: "string", Description: "Default template author"},
+	"templates.email":           {Type: "string", Description: "Default template email"},
+	"templates.vars.*":          {Type: "string", Description: "Extra template variables"},
+	"paths.scripts":             {Type: "string", Description: "Script directories, separated by the OS path list separator"},
+	"paths.templates":           {Type: "string", Description: "Template directories, separated by the OS path list separator"},
+	"scripts.paths":             {Type: "string", Description: "Same as paths.scripts, which wins when both are set"},
+	"templates.paths":           {Type: "string", Description: "Same as paths.templates, which wins when both are set"},
+	"env.default":               {Type: "string", Description: "Env profile used when none is given"},
+	"dotfiles.mode":             {Type: "string", Enum: []string{"link", "copy"}, Description: "How dotfiles are placed"},
+	"serve.token":               {Type: "string", Description: "API token for 'berga serve'", Sensitive: true},
+	"secrets.*":                 {Type: "string", Description: "Secret values for scripts", Sensitive: true},
+	"registries.*":              {Type: "string", Description: "Git URLs of template and script registries, by name"},
+	"aliases.*":                 {Type: "string", Description: "Command aliases"},
+	"groups.*":                  {Type: "string", Description: "Script group for 'script run-group', comma-separated"},
+	"hosts.*":                   {Type: "string", Description: "Host group for 'script run --hosts @name', comma-separated"},
+	"listen.addr":               {Type: "string", Description: "Address for 'berga listen' (default 127.0.0.1:8788)"},
+	"listen.allow":              {Type: "string", Description: "Addresses and CIDR networks allowed to send webhooks, comma-separated"},
+	"share.addr":                {Type: "string", Description: "Address for 'berga share' (default every interface on a free port)"},
+	"share.tunnel":              {Type: "string", Description: "Command exposing {port} publicly for 'berga share', e.g. cloudflared"},
+	"history.enabled":           {Type: "bool", Description: "Record script runs for 'berga history' (default true)"},
+	"history.output":            {Type: "bool", Description: "Keep the last 64KB of each run's output in the history"},
+	"history.limit":             {Type: "int", Description: "How many runs the history keeps (default 1000, 0 for no limit)"},
+	"index.enabled":             {Type: "bool", Description: "Cache directory listings in index.json for list, search, and completion (default true)"},
+	"audit.enabled":             {Type: "bool", Description: "Record changes and script runs in the audit log (default true)"},
+	"hooks.pre_script_run":      {Type: "string", Description: "Command run before every script; failing cancels the run"},
+	"hooks.post_script_run":     {Type: "string", Description: "Command run after every script"},
+	"hooks.pre_template_apply":  {Type: "string", Description: "Command run before every template apply; failing cancels it"},
+	"hooks.post_template_apply": {Type: "string", Description: "Command run after every template apply"},
+}
+
+// LookupKey finds the schema entry for a dot-path key
+func LookupKey(key string) (Key, bool) {
+	if k, ok := Schema[key]; ok {
+		return k, true
+	}
+	if i := strings.LastIndex(key, "."); i > 0 {
+		if k, ok := Schema[key[:i]+".*"]; ok {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// KnownKeys returns the schema keys in sorted order
+func KnownKeys() []string {
+	keys := make([]string, 0, len(Schema))
+	for k := range Schema {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks raw against the schema and returns the normalized value and
+// its YAML tag
+func Validate(key, raw string) (string, string, error) {
+	k, ok := LookupKey(key)
+	if !ok {
+		return "", "", fmt.Errorf("unknown config key '%s' (known keys: %s)", key, strings.Join(KnownKeys(), ", "))
+	}
+
+	if len(k.Enum) > 0 {
+		valid := false
+		for _, option := range k.Enum {
+			if raw == option {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", "", fmt.Errorf("'%s' must be one of: %s", key, strings.Join(k.Enum, ", "))
+		}
+	}
+
+	switch k.Type {
+	case "int":
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("'%s' must be an integer", key)
+		}
+		return strconv.Itoa(n), "!!int", nil
+	case "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("'%s' must be true or false", key)
+		}
+		return strconv.FormatBool(b), "!!bool", nil
+	case "duration":
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("'%s' must be a duration such as 30s or 5m", key)
+		}
+		return d.String(), "!!str", nil
+	case "timeout":
+		if _, err := ParseTimeout(raw); err != nil {
+			return "", "", fmt.Errorf("'%s' %v", key, err)
+		}
+		if n, err := strconv.Atoi(raw); err == nil {
+			return strconv.Itoa(n), "!!int", nil
+		}
+		d, _ := time.ParseDuration(raw)
+		return d.String(), "!!str", nil
+	case "size":
+		if _, err := ParseSize(raw); err != nil {
+			return "", "", fmt.Errorf("'%s' %v", key, err)
+		}
+		return strings.TrimSpace(raw), "!!str", nil
+	default:
+		return raw, "!!str", nil
+	}
+}
+
+// ParseTimeout parses a timeout written as a duration such as 90s or 2m30s,
+// or as a bare number of seconds. Zero means no timeout.
+func ParseTimeout(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if n, err := strconv.Atoi(raw); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("must not be negative")
+		}
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("must be a duration such as 90s or 2m30s, or a number of seconds")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// sizeUnits are the suffixes ParseSize accepts, as powers of 1024
+var sizeUnits = map[string]uint{"": 0, "K": 1, "M": 2, "G": 3, "T": 4}
+
+// ParseSize parses a size written as a number of bytes or with a K, M, G, or
+// T suffix (powers of 1024, optionally followed by B or iB), such as 512M.
+// Zero means no limit.
+func ParseSize(raw string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') {
+		i--
+	}
+	shift, ok := sizeUnits[strings.TrimSpace(s[i:])]
+	n, err := strconv.ParseUint(s[:i], 10, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("must be a size such as 512M or 2G")
+	}
+	if n > (1<<64-1)>>(10*shift) {
+		return 0, fmt.Errorf("is too large")
+	}
+	return n << (10 * shift), nil
+}
@@ -0,0 +1,91 @@
+package scripts
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Platform describes the operating system scripts run on. Script execution
+// goes through it rather than runtime.GOOS so Windows behaviour can be tested
+// from any host.
+type Platform struct {
+	GOOS     string
+	LookPath func(file string) (string, error)
+}
+
+// Host is the platform the program is running on
+var Host = Platform{GOOS: runtime.GOOS, LookPath: exec.LookPath}
+
+// PowerShell returns the PowerShell to run .ps1 scripts with. PowerShell Core
+// (pwsh) is preferred when installed; Windows falls back to the bundled
+// Windows PowerShell.
+func (p Platform) PowerShell() string {
+	if _, err := p.LookPath("pwsh"); err == nil || p.GOOS != "windows" {
+		return "pwsh"
+	}
+	return "powershell"
+}
+
+// IsExecutable reports whether path can be run directly: by extension on
+// Windows, by permission bits elsewhere
+func (p Platform) IsExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if p.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".exe" || ext == ".bat" || ext == ".cmd" || ext == ".ps1"
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Argv returns the command line that runs scriptPath with args
+func (p Platform) Argv(scriptPath string, args []string) []string {
+	lower := strings.ToLower(scriptPath)
+	var argv []string
+	switch {
+	case strings.HasSuffix(lower, ".ps1"):
+		argv = []string{p.PowerShell(), "-File", scriptPath}
+	case p.GOOS == "windows" && (strings.HasSuffix(lower, ".bat") || strings.HasSuffix(lower, ".cmd")):
+		argv = []string{"cmd", "/C", scriptPath}
+	case p.GOOS == "windows" || p.IsExecutable(scriptPath):
+		argv = []string{scriptPath}
+	default:
+		// Not executable: use the shebang's interpreter, or sh
+		interpreter := shebangInterpreter(scriptPath)
+		if interpreter == "" {
+			interpreter = "sh"
+		}
+		argv = []string{interpreter, scriptPath}
+	}
+	return append(argv, args...)
+}
+
+// shebangInterpreter returns the interpreter named on a script's shebang line
+func shebangInterpreter(scriptPath string) string {
+	if parts := Shebang(scriptPath); len(parts) > 0 {
+		return parts[0]
+	}
+	return ""
+}
+
+// Shebang returns the words of a script's shebang line, or nil without one
+func Shebang(scriptPath string) []string {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() && strings.HasPrefix(scanner.Text(), "#!") {
+		return strings.Fields(scanner.Text()[2:])
+	}
+	return nil
+}
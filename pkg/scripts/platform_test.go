@@ -0,0 +1,113 @@
+package scripts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakePlatform returns a platform for goos where only the given commands are
+// installed
+func fakePlatform(goos string, installed ...string) Platform {
+	return Platform{GOOS: goos, LookPath: func(file string) (string, error) {
+		for _, name := range installed {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}}
+}
+
+func TestPowerShell(t *testing.T) {
+	tests := []struct {
+		platform Platform
+		want     string
+	}{
+		{fakePlatform("windows", "pwsh", "powershell"), "pwsh"},
+		{fakePlatform("windows", "powershell"), "powershell"},
+		{fakePlatform("linux", "pwsh"), "pwsh"},
+		{fakePlatform("darwin"), "pwsh"},
+	}
+	for _, tt := range tests {
+		if got := tt.platform.PowerShell(); got != tt.want {
+			t.Errorf("%s PowerShell() = %q, want %q", tt.platform.GOOS, got, tt.want)
+		}
+	}
+}
+
+func TestScriptArgvWindows(t *testing.T) {
+	dir := t.TempDir()
+	windows := fakePlatform("windows", "powershell")
+	tests := map[string][]string{
+		"deploy.ps1": {"powershell", "-File", filepath.Join(dir, "deploy.ps1"), "x"},
+		"build.BAT":  {"cmd", "/C", filepath.Join(dir, "build.BAT"), "x"},
+		"setup.cmd":  {"cmd", "/C", filepath.Join(dir, "setup.cmd"), "x"},
+		"tool.exe":   {filepath.Join(dir, "tool.exe"), "x"},
+	}
+	for name, want := range tests {
+		path := filepath.Join(dir, name)
+		if got := windows.Argv(path, []string{"x"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Argv(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	path := filepath.Join(dir, "deploy.ps1")
+	got := fakePlatform("windows", "pwsh", "powershell").Argv(path, nil)
+	if want := []string{"pwsh", "-File", path}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected pwsh to be preferred, got %v", got)
+	}
+}
+
+func TestScriptArgvUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs Unix permission bits")
+	}
+	dir := t.TempDir()
+	write := func(name, content string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		os.Chmod(path, perm)
+		return path
+	}
+	ps1 := write("deploy.ps1", "Write-Output hi\n", 0755)
+	exe := write("run.sh", "#!/bin/sh\n", 0755)
+	py := write("report.py", "#!/usr/bin/python3\n", 0644)
+	bare := write("plain", "echo hi\n", 0644)
+
+	linux := fakePlatform("linux", "pwsh")
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{ps1, []string{"pwsh", "-File", ps1}},
+		{exe, []string{exe}},
+		{py, []string{"/usr/bin/python3", py}},
+		{bare, []string{"sh", bare}},
+	}
+	for _, tt := range tests {
+		if got := linux.Argv(tt.path, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Argv(%s) = %v, want %v", filepath.Base(tt.path), got, tt.want)
+		}
+	}
+}
+
+func TestPlatformIsExecutable(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.ps1", "b.txt"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	windows := fakePlatform("windows")
+	if !windows.IsExecutable(filepath.Join(dir, "a.ps1")) || windows.IsExecutable(filepath.Join(dir, "b.txt")) {
+		t.Error("Expected Windows to decide by extension")
+	}
+	if windows.IsExecutable(filepath.Join(dir, "missing.exe")) {
+		t.Error("Expected a missing file not to be executable")
+	}
+}
@@ -0,0 +1,124 @@
+// Package scripts finds and runs berga scripts.
+//
+// A Store looks scripts up by name and a Runner executes them. DirStore and
+// LocalRunner are the implementations the berga CLI uses: scripts are files in
+// a list of directories, run as child processes with the interpreter their
+// extension or shebang asks for.
+package scripts
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// EncryptedExt is the extension of scripts encrypted with age. They are found
+// by their plain name as well.
+const EncryptedExt = ".age"
+
+// Store looks scripts up by name
+type Store interface {
+	// Find returns the path of the named script
+	Find(name string) (string, bool)
+	// List returns the names of every script
+	List() ([]string, error)
+}
+
+// Runner runs a script with arguments
+type Runner interface {
+	Run(ctx context.Context, scriptPath string, args []string) error
+}
+
+// DirStore keeps scripts in directories searched in order. A script in an
+// earlier directory hides one of the same name in a later directory.
+type DirStore struct {
+	Dirs []string
+}
+
+// Find looks name up in each directory, also as an encrypted script
+func (s DirStore) Find(name string) (string, bool) {
+	for _, dir := range s.Dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+		if _, err := os.Stat(path + EncryptedExt); err == nil {
+			return path + EncryptedExt, true
+		}
+	}
+	return "", false
+}
+
+// FindAll returns every script called name, in the order Find checks them.
+// The first one is the script Find returns; the rest are hidden by it.
+func (s DirStore) FindAll(name string) []string {
+	var paths []string
+	for _, dir := range s.Dirs {
+		for _, path := range []string{filepath.Join(dir, name), filepath.Join(dir, name+EncryptedExt)} {
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// List returns the sorted file names across all directories. Missing
+// directories are skipped.
+func (s DirStore) List() ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range s.Dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LocalRunner runs scripts as child processes. An unset Platform means Host;
+// the other fields behave as they do on exec.Cmd.
+type LocalRunner struct {
+	Platform Platform
+	Env      []string
+	Dir      string
+	Stdin    io.Reader
+	Stdout   io.Writer
+	Stderr   io.Writer
+}
+
+// Command builds the command that runs a script with the right interpreter
+func (r LocalRunner) Command(ctx context.Context, scriptPath string, args []string) *exec.Cmd {
+	platform := r.Platform
+	if platform.GOOS == "" {
+		platform = Host
+	}
+	argv := platform.Argv(scriptPath, args)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = r.Env
+	cmd.Dir = r.Dir
+	cmd.Stdin = r.Stdin
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	return cmd
+}
+
+// Run runs a script and waits for it to finish
+func (r LocalRunner) Run(ctx context.Context, scriptPath string, args []string) error {
+	return r.Command(ctx, scriptPath, args).Run()
+}
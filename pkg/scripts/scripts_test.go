@@ -0,0 +1,66 @@
+package scripts
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestDirStore(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, path := range []string{
+		filepath.Join(first, "build.sh"),
+		filepath.Join(second, "build.sh"),
+		filepath.Join(second, "deploy.sh.age"),
+	} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := DirStore{Dirs: []string{first, second, filepath.Join(first, "missing")}}
+
+	if path, ok := store.Find("build.sh"); !ok || path != filepath.Join(first, "build.sh") {
+		t.Errorf("Expected the first directory to win, got %q", path)
+	}
+	if path, ok := store.Find("deploy.sh"); !ok || path != filepath.Join(second, "deploy.sh.age") {
+		t.Errorf("Expected the encrypted script to be found by its plain name, got %q", path)
+	}
+	if _, ok := store.Find("nope.sh"); ok {
+		t.Error("Expected a missing script not to be found")
+	}
+	if paths := store.FindAll("build.sh"); !reflect.DeepEqual(paths, []string{filepath.Join(first, "build.sh"), filepath.Join(second, "build.sh")}) {
+		t.Errorf("Expected both scripts in precedence order, got %v", paths)
+	}
+
+	names, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"build.sh", "deploy.sh.age"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+}
+
+func TestLocalRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	path := filepath.Join(t.TempDir(), "greet")
+	if err := os.WriteFile(path, []byte("echo \"hi $1 from $PWD\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	var runner Runner = LocalRunner{Dir: dir, Stdout: &out}
+	if err := runner.Run(context.Background(), path, []string{"ada"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "hi ada from " + dir + "\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
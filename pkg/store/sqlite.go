@@ -0,0 +1,421 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// busyTimeout is how long a write waits for another process to finish its own
+const busyTimeout = 10 * time.Second
+
+// migrations bring a database up to the current schema. migrations[i] moves a
+// database at version i to version i+1; the version is kept in the
+// database's user_version. A released migration must never change: add
+// another one instead.
+var migrations = []string{
+	`CREATE TABLE runs (
+		id          INTEGER PRIMARY KEY,
+		time        INTEGER NOT NULL,
+		script      TEXT NOT NULL,
+		args        TEXT NOT NULL DEFAULT '[]',
+		flags       TEXT NOT NULL DEFAULT '[]',
+		dir         TEXT NOT NULL DEFAULT '',
+		exit_code   INTEGER NOT NULL DEFAULT 0,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		output      BLOB
+	);
+	CREATE INDEX runs_script ON runs (script COLLATE NOCASE);
+	CREATE INDEX runs_time ON runs (time);
+
+	CREATE TABLE audit (
+		id          INTEGER PRIMARY KEY,
+		time        INTEGER NOT NULL,
+		user        TEXT NOT NULL DEFAULT '',
+		action      TEXT NOT NULL,
+		target      TEXT NOT NULL DEFAULT '',
+		params      TEXT NOT NULL DEFAULT '{}',
+		error       TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX audit_time ON audit (time);
+
+	CREATE TABLE usage (
+		kind      TEXT NOT NULL,
+		name      TEXT NOT NULL,
+		count     INTEGER NOT NULL DEFAULT 0,
+		last_used INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (kind, name)
+	);`,
+}
+
+// SQLite is a Store in an SQLite database file. Writes take the database
+// lock up front and wait up to busyTimeout for it, so concurrent berga
+// processes queue up rather than fail.
+type SQLite struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it readable only by the user if
+// it does not exist, and migrates it to the current schema
+func Open(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	file.Close()
+
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate", path, busyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// One connection is plenty for the CLI and keeps writers in this
+	// process from contending with each other for the lock
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies the migrations the database is missing, all in one
+// transaction
+func (s *SQLite) migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read database version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database version %d is newer than this berga supports (%d); upgrade berga", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return nil
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("failed to migrate database to version %d: %w", i+1, err)
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Close closes the database
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// AddRun records run under the next ID
+func (s *SQLite) AddRun(run Run, keep int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO runs (time, script, args, flags, dir, exit_code, duration_ms, output)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		unixNano(run.Time), run.Script, encodeList(run.Args), encodeList(run.Flags), run.Dir, run.ExitCode, run.DurationMS, run.Output)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	added, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	if keep > 0 {
+		_, err := tx.Exec("DELETE FROM runs WHERE id NOT IN (SELECT id FROM runs ORDER BY id DESC LIMIT ?)", keep)
+		if err != nil {
+			return 0, fmt.Errorf("failed to drop old runs: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	return int(added), nil
+}
+
+// runColumns are the columns scanRun reads
+const runColumns = "id, time, script, args, flags, dir, exit_code, duration_ms, output IS NOT NULL"
+
+// Runs returns the runs filter selects
+func (s *SQLite) Runs(filter RunFilter) ([]Run, error) {
+	where := []string{"time >= ?"}
+	params := []interface{}{unixNano(filter.Since)}
+	if filter.Script != "" {
+		where = append(where, "script = ? COLLATE NOCASE")
+		params = append(params, filter.Script)
+	}
+	if filter.Failed {
+		where = append(where, "exit_code != 0")
+	}
+	limit := -1
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	query := fmt.Sprintf("SELECT * FROM (SELECT %s FROM runs WHERE %s ORDER BY id DESC LIMIT %d) ORDER BY id",
+		runColumns, strings.Join(where, " AND "), limit)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+	var runs []Run
+	for rows.Next() {
+		run, err := scanRun(rows, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return runs, nil
+}
+
+// FindRun returns the run with the given ID and its output
+func (s *SQLite) FindRun(id int) (Run, bool, error) {
+	row := s.db.QueryRow("SELECT "+runColumns+", output FROM runs WHERE id = ?", id)
+	run, err := scanRun(row, true)
+	if err == sql.ErrNoRows {
+		return Run{}, false, nil
+	}
+	if err != nil {
+		return Run{}, false, fmt.Errorf("failed to read history: %w", err)
+	}
+	return run, true, nil
+}
+
+// scanner is a row to scan, from QueryRow or Query
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRun reads the runColumns of a row, followed by the output when
+// withOutput is set
+func scanRun(row scanner, withOutput bool) (Run, error) {
+	var run Run
+	var at int64
+	var args, flags string
+	var out []byte
+	dest := []interface{}{&run.ID, &at, &run.Script, &args, &flags, &run.Dir, &run.ExitCode, &run.DurationMS, &run.HasOutput}
+	if withOutput {
+		dest = append(dest, &out)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return Run{}, err
+	}
+	run.Time = fromUnixNano(at)
+	run.Args = decodeList(args)
+	run.Flags = decodeList(flags)
+	run.Output = out
+	return run, nil
+}
+
+// ClearRuns forgets every run
+func (s *SQLite) ClearRuns() error {
+	if _, err := s.db.Exec("DELETE FROM runs"); err != nil {
+		return fmt.Errorf("failed to clear history: %w", err)
+	}
+	return nil
+}
+
+// AddAudit appends an entry to the audit log
+func (s *SQLite) AddAudit(entry AuditEntry) error {
+	params, err := json.Marshal(entry.Params)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO audit (time, user, action, target, params, error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		unixNano(entry.Time), entry.User, entry.Action, entry.Target, string(params), entry.Error, entry.DurationMS)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Audit returns the entries at or after since whose action starts with action
+func (s *SQLite) Audit(since time.Time, action string) ([]AuditEntry, error) {
+	rows, err := s.db.Query(`SELECT time, user, action, target, params, error, duration_ms FROM audit
+		WHERE time >= ? AND substr(action, 1, length(?)) = ? ORDER BY time, id`,
+		unixNano(since), action, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer rows.Close()
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var at int64
+		var params string
+		if err := rows.Scan(&at, &entry.User, &entry.Action, &entry.Target, &params, &entry.Error, &entry.DurationMS); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		entry.Time = fromUnixNano(at).UTC()
+		json.Unmarshal([]byte(params), &entry.Params)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordUse counts a use of an item
+func (s *SQLite) RecordUse(kind, name string, at time.Time) error {
+	_, err := s.db.Exec(`INSERT INTO usage (kind, name, count, last_used) VALUES (?, ?, 1, ?)
+		ON CONFLICT (kind, name) DO UPDATE SET count = count + 1, last_used = excluded.last_used`,
+		kind, name, unixNano(at))
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the usage of every item of a kind
+func (s *SQLite) Usage(kind string) (map[string]Usage, error) {
+	rows, err := s.db.Query("SELECT name, count, last_used FROM usage WHERE kind = ?", kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	defer rows.Close()
+	usage := make(map[string]Usage)
+	for rows.Next() {
+		var name string
+		var u Usage
+		var at int64
+		if err := rows.Scan(&name, &u.Count, &at); err != nil {
+			return nil, fmt.Errorf("failed to read usage: %w", err)
+		}
+		u.LastUsed = fromUnixNano(at).UTC()
+		usage[name] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	return usage, nil
+}
+
+// SetUsage replaces the usage of every item of a kind
+func (s *SQLite) SetUsage(kind string, usage map[string]Usage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM usage WHERE kind = ?", kind); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	for name, u := range usage {
+		_, err := tx.Exec("INSERT INTO usage (kind, name, count, last_used) VALUES (?, ?, ?, ?)",
+			kind, name, u.Count, unixNano(u.LastUsed))
+		if err != nil {
+			return fmt.Errorf("failed to write usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	return nil
+}
+
+// Import adds records in one transaction
+func (s *SQLite) Import(records Records) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, run := range records.Runs {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO runs (id, time, script, args, flags, dir, exit_code, duration_ms, output)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.ID, unixNano(run.Time), run.Script, encodeList(run.Args), encodeList(run.Flags), run.Dir, run.ExitCode, run.DurationMS, run.Output)
+		if err != nil {
+			return fmt.Errorf("failed to import run %d: %w", run.ID, err)
+		}
+	}
+	for _, entry := range records.Audit {
+		params, err := json.Marshal(entry.Params)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO audit (time, user, action, target, params, error, duration_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			unixNano(entry.Time), entry.User, entry.Action, entry.Target, string(params), entry.Error, entry.DurationMS)
+		if err != nil {
+			return fmt.Errorf("failed to import audit log: %w", err)
+		}
+	}
+	for kind, usage := range records.Usage {
+		for name, u := range usage {
+			_, err := tx.Exec("INSERT OR REPLACE INTO usage (kind, name, count, last_used) VALUES (?, ?, ?, ?)",
+				kind, name, u.Count, unixNano(u.LastUsed))
+			if err != nil {
+				return fmt.Errorf("failed to import usage: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	return nil
+}
+
+// unixNano is how times are stored. The zero time is stored as 0, so it
+// also works as "since the beginning".
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// encodeList stores a list of strings as a JSON array
+func encodeList(list []string) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func decodeList(s string) []string {
+	var list []string
+	json.Unmarshal([]byte(s), &list)
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTest opens a new database in a temporary directory
+func openTest(t *testing.T) (*SQLite, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "berga.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func runIDs(runs []Run) []int {
+	var ids []int
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	return ids
+}
+
+func TestOpenMigrates(t *testing.T) {
+	s, path := openTest(t)
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != len(migrations) {
+		t.Fatalf("Expected version %d, got %d, %v", len(migrations), version, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected a private database, got %v, %v", info, err)
+		}
+	}
+	s.RecordUse("script", "deploy.sh", time.Now())
+	s.Close()
+
+	// Opening again keeps the data and applies nothing twice
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if usage, _ := s.Usage("script"); usage["deploy.sh"].Count != 1 {
+		t.Errorf("Expected the usage to survive reopening, got %v", usage)
+	}
+}
+
+func TestOpenNewerVersion(t *testing.T) {
+	s, path := openTest(t)
+	if _, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations)+1)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := Open(path); err == nil {
+		t.Error("Expected a database from a newer berga to be refused")
+	}
+}
+
+func TestAddRunAndKeep(t *testing.T) {
+	s, _ := openTest(t)
+	for i := 1; i <= 3; i++ {
+		run := Run{Time: time.Now(), Script: "backup.sh", Args: []string{fmt.Sprint(i)}, Output: []byte(fmt.Sprintf("output %d\n", i))}
+		id, err := s.AddRun(run, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != i {
+			t.Errorf("Expected run %d, got ID %d", i, id)
+		}
+	}
+
+	runs, err := s.Runs(RunFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(runIDs(runs)) != "[2 3]" {
+		t.Fatalf("Expected runs 2 and 3 to be kept, got %v", runIDs(runs))
+	}
+	if !runs[0].HasOutput || runs[0].Output != nil || runs[0].Args[0] != "2" {
+		t.Errorf("Expected a listed run without its output loaded, got %+v", runs[0])
+	}
+
+	run, ok, err := s.FindRun(3)
+	if err != nil || !ok || string(run.Output) != "output 3\n" {
+		t.Errorf("Expected run 3 with its output, got %+v, %v, %v", run, ok, err)
+	}
+	if _, ok, _ := s.FindRun(1); ok {
+		t.Error("Expected the dropped run not to be found")
+	}
+
+	if err := s.ClearRuns(); err != nil {
+		t.Fatal(err)
+	}
+	if runs, _ := s.Runs(RunFilter{}); len(runs) != 0 {
+		t.Errorf("Expected no runs after clearing, got %v", runIDs(runs))
+	}
+}
+
+func TestRunsFilter(t *testing.T) {
+	s, _ := openTest(t)
+	now := time.Now()
+	for _, run := range []Run{
+		{Script: "deploy.sh", ExitCode: 1, Time: now.AddDate(0, 0, -40)},
+		{Script: "deploy.sh", ExitCode: 0, Time: now.AddDate(0, 0, -3)},
+		{Script: "Deploy.sh", ExitCode: 2, Time: now.AddDate(0, 0, -2)},
+		{Script: "backup.sh", ExitCode: 1, Time: now.AddDate(0, 0, -1)},
+	} {
+		if _, err := s.AddRun(run, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	since := now.AddDate(0, 0, -30)
+	tests := []struct {
+		filter RunFilter
+		want   string
+	}{
+		{RunFilter{}, "[1 2 3 4]"},
+		{RunFilter{Script: "deploy.sh"}, "[1 2 3]"},
+		{RunFilter{Failed: true}, "[1 3 4]"},
+		{RunFilter{Script: "deploy.sh", Failed: true, Since: since}, "[3]"},
+		{RunFilter{Limit: 2}, "[3 4]"},
+		{RunFilter{Failed: true, Limit: 2}, "[3 4]"},
+	}
+	for _, tt := range tests {
+		runs, err := s.Runs(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(runIDs(runs)); got != tt.want {
+			t.Errorf("Filter %+v matched %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	s, _ := openTest(t)
+	now := time.Now()
+	for _, entry := range []AuditEntry{
+		{Time: now.Add(-48 * time.Hour), Action: "script run", Target: "deploy.sh", Params: map[string]string{"args": "prod"}},
+		{Time: now.Add(-time.Hour), Action: "config set", Error: "boom"},
+		{Time: now.Add(-2 * time.Hour), Action: "script edit", DurationMS: 1500},
+	} {
+		if err := s.AddAudit(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := s.Audit(time.Time{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Action != "script run" || all[2].Action != "config set" {
+		t.Fatalf("Expected all entries oldest first, got %v", all)
+	}
+	if all[0].Params["args"] != "prod" || all[2].Error != "boom" || all[1].DurationMS != 1500 {
+		t.Errorf("Unexpected entries %+v", all)
+	}
+
+	got, err := s.Audit(now.Add(-24*time.Hour), "script")
+	if err != nil || len(got) != 1 || got[0].Action != "script edit" {
+		t.Errorf("Unexpected filtered entries %v, %v", got, err)
+	}
+	if got, _ := s.Audit(time.Time{}, "Script"); len(got) != 0 {
+		t.Errorf("Expected the action prefix to match case, got %v", got)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	s, _ := openTest(t)
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	s.RecordUse("script", "deploy.sh", at.Add(-time.Hour))
+	s.RecordUse("script", "deploy.sh", at)
+	s.RecordUse("template", "gitignore", at)
+
+	usage, err := s.Usage("script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := usage["deploy.sh"]; u.Count != 2 || !u.LastUsed.Equal(at) {
+		t.Errorf("Expected 2 uses, last at %v, got %+v", at, u)
+	}
+
+	if err := s.SetUsage("script", map[string]Usage{"release.sh": usage["deploy.sh"]}); err != nil {
+		t.Fatal(err)
+	}
+	usage, _ = s.Usage("script")
+	if _, ok := usage["deploy.sh"]; ok || usage["release.sh"].Count != 2 {
+		t.Errorf("Expected the usage to be replaced, got %v", usage)
+	}
+	if templates, _ := s.Usage("template"); templates["gitignore"].Count != 1 {
+		t.Errorf("Expected other kinds to be kept, got %v", templates)
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	_, path := openTest(t)
+
+	// Separate connections stand in for separate berga processes
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer s.Close()
+			for j := 0; j < 10; j++ {
+				if _, err := s.AddRun(Run{Time: time.Now(), Script: "a.sh"}, 0); err != nil {
+					errs <- err
+					return
+				}
+				if err := s.RecordUse("script", "a.sh", time.Now()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if runs, _ := s.Runs(RunFilter{}); len(runs) != 80 {
+		t.Errorf("Expected 80 runs, got %d", len(runs))
+	}
+	if usage, _ := s.Usage("script"); usage["a.sh"].Count != 80 {
+		t.Errorf("Expected 80 uses, got %d", usage["a.sh"].Count)
+	}
+}
+
+func TestImport(t *testing.T) {
+	s, _ := openTest(t)
+	s.RecordUse("script", "deploy.sh", time.Now())
+	records := Records{
+		Runs: []Run{
+			{ID: 41, Time: time.Now(), Script: "deploy.sh", Output: []byte("done\n")},
+			{ID: 42, Time: time.Now(), Script: "backup.sh", ExitCode: 1},
+		},
+		Audit: []AuditEntry{{Time: time.Now(), Action: "script run", Target: "deploy.sh"}},
+		Usage: map[string]map[string]Usage{"script": {"deploy.sh": {Count: 7}}},
+	}
+	if err := s.Import(records); err != nil {
+		t.Fatal(err)
+	}
+
+	if run, ok, _ := s.FindRun(41); !ok || string(run.Output) != "done\n" {
+		t.Errorf("Expected run 41 to keep its ID and output, got %+v", run)
+	}
+	if id, _ := s.AddRun(Run{Time: time.Now(), Script: "a.sh"}, 0); id != 43 {
+		t.Errorf("Expected new runs to follow the imported ones, got ID %d", id)
+	}
+	if entries, _ := s.Audit(time.Time{}, ""); len(entries) != 1 {
+		t.Errorf("Expected the audit entry to be imported, got %v", entries)
+	}
+	if usage, _ := s.Usage("script"); usage["deploy.sh"].Count != 7 {
+		t.Errorf("Expected the imported usage to replace the recorded one, got %v", usage)
+	}
+}
@@ -0,0 +1,95 @@
+// Package store keeps berga's records: the history of script runs, the audit
+// log, and how often scripts and templates are used.
+//
+// Store is what the berga CLI records through. SQLite is the implementation
+// it uses: a single database file, brought up to the current schema by
+// numbered migrations when it is opened, that several berga processes can
+// write to at once.
+package store
+
+import "time"
+
+// Run is one recorded script run
+type Run struct {
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Script     string    `json:"script"`
+	Args       []string  `json:"args,omitempty"`
+	Flags      []string  `json:"flags,omitempty"`
+	Dir        string    `json:"dir,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMS int64     `json:"duration_ms"`
+	// Output is the recorded output of the run. Only FindRun loads it;
+	// HasOutput tells whether there is any.
+	Output    []byte `json:"-"`
+	HasOutput bool   `json:"-"`
+}
+
+// RunFilter selects runs. The zero value selects every run.
+type RunFilter struct {
+	// Script keeps runs of this script, ignoring case
+	Script string
+	// Failed keeps runs that exited with an error
+	Failed bool
+	// Since keeps runs started at or after this time
+	Since time.Time
+	// Limit keeps only the newest this many matches when positive
+	Limit int
+}
+
+// AuditEntry is one entry of the audit log
+type AuditEntry struct {
+	Time       time.Time         `json:"time"`
+	User       string            `json:"user,omitempty"`
+	Action     string            `json:"action"`
+	Target     string            `json:"target,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	DurationMS int64             `json:"duration_ms,omitempty"`
+}
+
+// Usage records how often and how recently an item was used
+type Usage struct {
+	Count    int
+	LastUsed time.Time
+}
+
+// Records are everything a store holds, for moving them between stores
+type Records struct {
+	Runs  []Run
+	Audit []AuditEntry
+	// Usage is keyed by kind and then by name
+	Usage map[string]map[string]Usage
+}
+
+// Store records runs, audit entries, and usage
+type Store interface {
+	// AddRun records a run under the next ID, which it returns, and drops
+	// the oldest runs past keep when keep is positive
+	AddRun(run Run, keep int) (int, error)
+	// Runs returns the runs filter selects, oldest first
+	Runs(filter RunFilter) ([]Run, error)
+	// FindRun returns the run with the given ID and its output
+	FindRun(id int) (Run, bool, error)
+	// ClearRuns forgets every run
+	ClearRuns() error
+
+	// AddAudit appends an entry to the audit log
+	AddAudit(entry AuditEntry) error
+	// Audit returns the entries at or after since whose action starts with
+	// action, oldest first
+	Audit(since time.Time, action string) ([]AuditEntry, error)
+
+	// RecordUse counts a use of an item of a kind, such as a script
+	RecordUse(kind, name string, at time.Time) error
+	// Usage returns the usage of every item of a kind, by name
+	Usage(kind string) (map[string]Usage, error)
+	// SetUsage replaces the usage of every item of a kind
+	SetUsage(kind string, usage map[string]Usage) error
+
+	// Import adds records kept elsewhere, all or none of them. Runs keep
+	// their IDs and usage replaces what is recorded for the same item.
+	Import(records Records) error
+
+	Close() error
+}
@@ -0,0 +1,124 @@
+// Package templates finds and renders berga templates.
+//
+// A Store looks templates up by name and a Renderer turns a template's source
+// and variables into output. DirStore and TextRenderer are the
+// implementations the berga CLI uses: templates are text/template files in a
+// list of directories.
+package templates
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// Ext is the extension templates are usually saved with. It may be left out
+// when naming a template.
+const Ext = ".tmpl"
+
+// Store looks templates up by name
+type Store interface {
+	// Find returns the path of the named template
+	Find(name string) (string, bool)
+	// List returns the names of every template
+	List() ([]string, error)
+}
+
+// Renderer renders template source with variables
+type Renderer interface {
+	Render(w io.Writer, name, source string, vars map[string]interface{}) error
+}
+
+// DirStore keeps templates in directories searched in order. A template in an
+// earlier directory hides one of the same name in a later directory.
+type DirStore struct {
+	Dirs []string
+}
+
+// Find looks name up in each directory, with or without Ext
+func (s DirStore) Find(name string) (string, bool) {
+	for _, dir := range s.Dirs {
+		for _, candidate := range []string{name, name + Ext} {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// FindAll returns every template called name, in the order Find checks them.
+// The first one is the template Find returns; the rest are hidden by it.
+func (s DirStore) FindAll(name string) []string {
+	var paths []string
+	for _, dir := range s.Dirs {
+		for _, candidate := range []string{name, name + Ext} {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// List returns the sorted file names across all directories. Missing
+// directories are skipped.
+func (s DirStore) List() ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range s.Dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// TextRenderer renders text/template source. Strict makes a variable that is
+// not set an error rather than "<no value>".
+type TextRenderer struct {
+	Funcs  template.FuncMap
+	Strict bool
+}
+
+// Parse parses template source so it can be checked before any variables are
+// collected, then executed
+func (r TextRenderer) Parse(name, source string) (*template.Template, error) {
+	tmpl := template.New(name)
+	if r.Strict {
+		tmpl.Option("missingkey=error")
+	}
+	if r.Funcs != nil {
+		tmpl.Funcs(r.Funcs)
+	}
+	return tmpl.Parse(source)
+}
+
+// Render parses source and executes it with vars into w
+func (r TextRenderer) Render(w io.Writer, name, source string, vars map[string]interface{}) error {
+	tmpl, err := r.Parse(name, source)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	if err := tmpl.Execute(w, vars); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestDirStore(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, path := range []string{
+		filepath.Join(first, "gitignore.tmpl"),
+		filepath.Join(second, "gitignore.tmpl"),
+		filepath.Join(second, "Makefile"),
+	} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := DirStore{Dirs: []string{first, second}}
+
+	if path, ok := store.Find("gitignore"); !ok || path != filepath.Join(first, "gitignore.tmpl") {
+		t.Errorf("Expected the first directory to win without the extension, got %q", path)
+	}
+	if path, ok := store.Find("Makefile"); !ok || path != filepath.Join(second, "Makefile") {
+		t.Errorf("Expected a template without the extension to be found, got %q", path)
+	}
+	if _, ok := store.Find("missing"); ok {
+		t.Error("Expected a missing template not to be found")
+	}
+	if paths := store.FindAll("gitignore"); !reflect.DeepEqual(paths, []string{filepath.Join(first, "gitignore.tmpl"), filepath.Join(second, "gitignore.tmpl")}) {
+		t.Errorf("Expected both templates in precedence order, got %v", paths)
+	}
+
+	names, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Makefile", "gitignore.tmpl"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+}
+
+func TestTextRenderer(t *testing.T) {
+	var out strings.Builder
+	var renderer Renderer = TextRenderer{Funcs: template.FuncMap{"upper": strings.ToUpper}}
+	if err := renderer.Render(&out, "t", "{{upper .Name}} {{.Missing}}", map[string]interface{}{"Name": "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "API <no value>" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+
+	strict := TextRenderer{Strict: true}
+	if err := strict.Render(&out, "t", "{{.Missing}}", map[string]interface{}{}); err == nil {
+		t.Error("Expected a strict renderer to reject a missing variable")
+	}
+	if _, err := strict.Parse("t", "{{.Broken"); err == nil {
+		t.Error("Expected a parse error")
+	}
+}